@@ -1,27 +1,51 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	templatev1 "github.com/openshift/api/template/v1"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/acm"
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/config/layers"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/exemption"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/imageref"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/manifestdiff"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/olm"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/overlay"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/override"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/policy"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/rbac"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/sizing"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/summary"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/syncset"
 	webhooks "github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/podpriority"
 	utils "github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/ghodss/yaml"
 )
@@ -34,6 +58,9 @@ const (
 	repoName           string = "managed-cluster-validating-webhooks"
 	// Used to define what phase a resource should be deployed in by package-operator
 	pkoPhaseAnnotation string = "package-operator.run/phase"
+	// packageNamespace is replaced with the namespace of the package, the
+	// hosted control plane namespace, by package-operator
+	packageNamespace string = "{{.package.metadata.namespace}}"
 	// Defines the 'rbac' package-operator phase for any resources related to RBAC
 	rbacPhase string = "rbac"
 	// Defines the 'deploy' package-operator phase for any resources related to MCVW deployment
@@ -48,20 +75,58 @@ const (
 	hsControlPlaneLabel = "hypershift.openshift.io/hosted-control-plane"
 	// Defines the label for targeting hypershift control plane taints/tolerations
 	hsClusterLabel = "hypershift.openshift.io/cluster"
+	// hostedClusterLabel labels the resources of the webhooks of a hosted
+	// cluster with its name
+	hostedClusterLabel = "managed.openshift.io/hosted-cluster"
+	// metricsPort is the port of the metrics of the webhook pods, see
+	// cmd/main.go
+	metricsPort = 8080
+	// metricsServiceName is the Service of the metrics of the webhook pods,
+	// and metricsSecretName the Secret of its serving certificate
+	metricsServiceName = "validation-webhook-metrics"
+	metricsSecretName  = "validation-webhook-metrics-cert"
 	//caBundle annotation
 	caBundleAnnotation = "service.beta.openshift.io/inject-cabundle"
+	// imagePlaceholder is the image of the package, replaced by the pipeline
+	imagePlaceholder = "REPLACED_BY_PIPELINE"
 )
 
 var (
-	listenPort    = flag.Int("port", 5000, "On which port should the Webhook binary listen? (Not the Service port)")
-	secretName    = flag.String("secretname", "webhook-cert", "Secret where TLS certs are created")
-	caBundleName  = flag.String("cabundlename", "webhook-cert", "ConfigMap where CA cert is created")
-	templateFile  = flag.String("syncsetfile", "", "Path to where the SelectorSyncSet template should be written")
-	packageDir    = flag.String("packagedir", "", "Path to where the package manifest and resources should be written")
-	replicas      = flag.Int("replicas", 2, "Number of replicas for Hypershift-based MCVW deployment")
-	excludes      = flag.String("exclude", "debug-hook", "Comma-separated list of webhook names to skip")
-	only          = flag.String("only", "", "Only include these comma-separated webhooks")
-	showHookNames = flag.Bool("showhooks", false, "Print registered webhook names and exit")
+	listenPort        = flag.Int("port", 5000, "On which port should the Webhook binary listen? (Not the Service port)")
+	secretName        = flag.String("secretname", "webhook-cert", "Secret where TLS certs are created")
+	caBundleName      = flag.String("cabundlename", "webhook-cert", "ConfigMap where CA cert is created")
+	templateFile      = flag.String("syncsetfile", "", "Path to where the SelectorSyncSet template should be written")
+	packageDir        = flag.String("packagedir", "", "Path to where the package manifest and resources should be written")
+	replicas          = flag.Int("replicas", 2, "Number of replicas for Hypershift-based MCVW deployment")
+	maxReplicas       = flag.Int("max-replicas", 0, "Autoscale the Hypershift-based MCVW deployment from -replicas up to this number of replicas, not autoscaled if 0")
+	autoscaleCPU      = flag.String("autoscale-cpu", "200m", "Average CPU usage per pod the autoscaler of the Hypershift-based MCVW deployment targets")
+	excludes          = flag.String("exclude", "debug-hook", "Comma-separated list of webhook names to skip")
+	only              = flag.String("only", "", "Only include these comma-separated webhooks")
+	showHookNames     = flag.Bool("showhooks", false, "Print registered webhook names and exit")
+	productProfile    = flag.String("product-profile", "", fmt.Sprintf("Only include the webhooks and rules of this product profile in the SelectorSyncSet, one of %v", utils.Profiles))
+	complianceProfile = flag.String("compliance-profile", "", fmt.Sprintf("Build the manifests for this compliance profile, one of %v", utils.Compliances))
+	syncSetPerWebhook = flag.Bool("syncset-per-webhook", false, "Render a SelectorSyncSet per webhook instead of sharing them, so each webhook can be paused or rolled back on its own")
+	canary            = flag.String("canary", "", "Comma-separated webhooks whose configuration is rolled out to the canary clusters only, the other clusters keep their -canary-baseline configuration")
+	canaryBaseline    = flag.String("canary-baseline", "", "Path to the SelectorSyncSet template of the release the clusters outside the canary keep for the -canary webhooks. Without it they don't get the -canary webhooks.")
+	olmDir            = flag.String("olmdir", "", "Path to where the OLM bundle should be written")
+	olmVersion        = flag.String("olm-version", "", "Semantic version of the OLM bundle, required with -olmdir")
+	olmReplaces       = flag.String("olm-replaces", "", "Version of the OLM bundle this one upgrades from")
+	olmSkipRange      = flag.String("olm-skip-range", "", "Range of OLM bundle versions upgrading directly to this one, e.g. '>=0.1.0 <0.3.0'")
+	olmChannels       = flag.String("olm-channels", "stable", "Comma-separated OLM channels of the bundle, the first is the default channel")
+	webhookImage      = flag.String("image", "", "Image of the webhooks in the package and hosted cluster manifests, instead of the placeholder the pipeline replaces")
+	imageMirrors      = flag.String("image-mirrors", "", "Comma-separated source=mirror repository prefixes the images of the manifests are rewritten to, e.g. quay.io/app-sre=mirror.example.com/app-sre")
+	pinImages         = flag.Bool("pin-images", false, "Pin the images of the manifests to the digests of their linux/amd64 images, looked up with oc image info")
+	olmImage          = flag.String("olm-image", "quay.io/app-sre/managed-cluster-validating-webhooks:latest", "Image of the webhooks in the OLM bundle")
+	acmPolicies       = flag.String("acm-policies", "", "Path to where the ACM Policies reporting compliance with the webhooks' guardrails, in inform mode, should be written")
+	acmNamespace      = flag.String("acm-namespace", "policies", "Namespace of the ACM Policies on the hub")
+	acmPlacement      = flag.String("acm-placement", "", "Placement the ACM Policies are bound to. Without one no PlacementBinding is written.")
+	hostedClusterDir  = flag.String("hostedclusterdir", "", "Path to where the manifests of the webhooks of one HyperShift hosted cluster should be written, without package-operator")
+	hostedCluster     = flag.String("hosted-cluster", "", "Name of the HostedCluster of -hostedclusterdir")
+	hostedNamespace   = flag.String("hosted-namespace", "", "Hosted control plane namespace of -hosted-cluster on the management cluster, clusters-<hosted-cluster> by default")
+	hostedCABundle    = flag.String("hosted-ca-bundle", "", "Path to the service CA of the management cluster, verifying the serving certificate of the webhooks of -hostedclusterdir")
+	clusterSizeName   = flag.String("cluster-size", "", fmt.Sprintf("Scale the resource requests and replicas of the webhook pods and the webhook timeouts for clusters of this size, one of %v. Keeps the defaults if empty.", sizing.Names()))
+	environmentName   = flag.String("environment", "", fmt.Sprintf("Apply the built-in overlay of this environment, one of %v", overlay.Environments()))
+	diffSource        = flag.String("diff", "", "Print the changes from an earlier SelectorSyncSet template or package resources file, or package image, instead of writing the manifests")
 
 	namespace = flag.String("namespace", "openshift-validation-webhook", "In what namespace should resources exist?")
 
@@ -70,6 +135,14 @@ var (
 		"managed.openshift.io/gitRepoName": "${REPO_NAME}",
 		"managed.openshift.io/osd":         "true",
 	}
+
+	// environment is the overlay of -environment
+	environment overlay.Overlay
+	clusterSize sizing.Size
+	// mirrors are the mirrors of -image-mirrors, and pinnedImages the
+	// digests -pin-images looked up
+	mirrors      imageref.Mirrors
+	pinnedImages = map[string]string{}
 )
 
 func createNamespace() *corev1.Namespace {
@@ -122,6 +195,34 @@ func createRole() *rbacv1.Role {
 					"*",
 				},
 			},
+			{
+				APIGroups: []string{
+					"",
+				},
+				Resources: []string{
+					"configmaps",
+				},
+				ResourceNames: []string{
+					summary.ConfigMapName,
+					override.RedemptionsConfigMapName,
+				},
+				Verbs: []string{
+					"get",
+					"update",
+				},
+			},
+			{
+				// create can't be limited by resourceNames
+				APIGroups: []string{
+					"",
+				},
+				Resources: []string{
+					"configmaps",
+				},
+				Verbs: []string{
+					"create",
+				},
+			},
 			{
 				APIGroups: []string{
 					"monitoring.coreos.com",
@@ -162,7 +263,66 @@ func createRoleBinding() *rbacv1.RoleBinding {
 	}
 }
 
-func createClusterRole() *rbacv1.ClusterRole {
+// runtimePermissions are the cluster-wide rules the webhook server needs
+// whichever webhooks are deployed
+var runtimePermissions = []rbacv1.PolicyRule{
+	{
+		// The capabilities and the ID of the cluster
+		APIGroups: []string{"config.openshift.io"},
+		Resources: []string{"infrastructures", "networks", "dnses", "clusterversions"},
+		Verbs:     []string{"get"},
+	},
+	{
+		// The exemption labels and the break glass namespace
+		APIGroups: []string{""},
+		Resources: []string{"namespaces"},
+		Verbs:     []string{"get", "list"},
+	},
+	{
+		APIGroups: []string{""},
+		Resources: []string{"serviceaccounts"},
+		Verbs:     []string{"list"},
+	},
+	{
+		APIGroups: []string{""},
+		Resources: []string{"events"},
+		Verbs:     []string{"create"},
+	},
+	{
+		APIGroups: []string{exemption.Group},
+		Resources: []string{exemption.Plural},
+		Verbs:     []string{"list", "delete"},
+	},
+	{
+		APIGroups: []string{policy.Group},
+		Resources: []string{policy.Plural},
+		Verbs:     []string{"get"},
+	},
+	{
+		APIGroups: []string{policy.Group},
+		Resources: []string{policy.Plural + "/status"},
+		Verbs:     []string{"update"},
+	},
+	{
+		// The authentication and authorization of the debug endpoints
+		APIGroups: []string{"authentication.k8s.io"},
+		Resources: []string{"tokenreviews"},
+		Verbs:     []string{"create"},
+	},
+	{
+		APIGroups: []string{"authorization.k8s.io"},
+		Resources: []string{"subjectaccessreviews"},
+		Verbs:     []string{"create"},
+	},
+}
+
+// createClusterRole returns the ClusterRole of the webhook server, granting
+// the runtimePermissions and the permissions hooks declare they need
+func createClusterRole(hooks []webhooks.Webhook) *rbacv1.ClusterRole {
+	rules := [][]rbacv1.PolicyRule{runtimePermissions}
+	for _, hook := range hooks {
+		rules = append(rules, webhooks.RequiredPermissions(hook))
+	}
 	return &rbacv1.ClusterRole{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "ClusterRole",
@@ -171,20 +331,30 @@ func createClusterRole() *rbacv1.ClusterRole {
 		ObjectMeta: metav1.ObjectMeta{
 			Name: roleName,
 		},
-		Rules: []rbacv1.PolicyRule{
-			{
-				APIGroups: []string{
-					"imageregistry.operator.openshift.io",
-				},
-				Resources: []string{
-					"configs",
-				},
-				Verbs: []string{
-					"get",
-				},
-			},
-		},
+		Rules: rbac.Merge(rules...),
+	}
+}
+
+// classicHooks returns the webhooks deployed to classic clusters of profile
+// under compliance
+func classicHooks(skip, onlyInclude []string, profile utils.Profile, compliance utils.Compliance) []webhooks.Webhook {
+	hookNames := make([]string, 0, len(webhooks.Webhooks))
+	for name := range webhooks.Webhooks {
+		hookNames = append(hookNames, name)
+	}
+	sort.Strings(hookNames)
+	hooks := []webhooks.Webhook{}
+	for _, hookName := range hookNames {
+		hook := webhooks.Webhooks[hookName]()
+		if !hook.ClassicEnabled() || !webhooks.Enabled(hook, profile) || !webhooks.EnabledForCompliance(hook, compliance) || len(webhooks.Rules(hook, profile)) == 0 {
+			continue
+		}
+		if sliceContains(hookName, skip) || len(onlyInclude) > 0 && !sliceContains(hookName, onlyInclude) {
+			continue
+		}
+		hooks = append(hooks, hook)
 	}
+	return hooks
 }
 
 func createClusterRoleBinding() *rbacv1.ClusterRoleBinding {
@@ -266,6 +436,114 @@ func createPromethusRoleBinding() *rbacv1.RoleBinding {
 	}
 }
 
+// createNetworkPolicy restricts the traffic of the webhook pods to what they
+// need. Ingress is the API server calling the webhooks, from the host network
+// on OVN-Kubernetes, and Prometheus scraping the metrics. Egress is DNS, the
+// API server, whose Service is forwarded to port 6443 on the control plane
+// nodes, and HTTPS and OTLP to the optional denial record sinks, service logs
+// and trace collectors.
+func createNetworkPolicy() *networkingv1.NetworkPolicy {
+	port := func(protocol corev1.Protocol, port int) networkingv1.NetworkPolicyPort {
+		p := intstr.FromInt(port)
+		return networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &p}
+	}
+	fromNamespace := func(labels map[string]string) networkingv1.NetworkPolicyPeer {
+		return networkingv1.NetworkPolicyPeer{NamespaceSelector: &metav1.LabelSelector{MatchLabels: labels}}
+	}
+	return &networkingv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "NetworkPolicy",
+			APIVersion: "networking.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      serviceName,
+			Namespace: *namespace,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": "validation-webhook",
+				},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					From: []networkingv1.NetworkPolicyPeer{
+						fromNamespace(map[string]string{"policy-group.network.openshift.io/host-network": ""}),
+						fromNamespace(map[string]string{corev1.LabelMetadataName: "openshift-kube-apiserver"}),
+					},
+					Ports: []networkingv1.NetworkPolicyPort{port(corev1.ProtocolTCP, *listenPort)},
+				},
+				{
+					From: []networkingv1.NetworkPolicyPeer{
+						fromNamespace(map[string]string{corev1.LabelMetadataName: "openshift-monitoring"}),
+					},
+					Ports: []networkingv1.NetworkPolicyPort{port(corev1.ProtocolTCP, metricsPort)},
+				},
+			},
+			Egress: []networkingv1.NetworkPolicyEgressRule{
+				{
+					To: []networkingv1.NetworkPolicyPeer{
+						fromNamespace(map[string]string{corev1.LabelMetadataName: "openshift-dns"}),
+					},
+					Ports: []networkingv1.NetworkPolicyPort{
+						port(corev1.ProtocolUDP, 5353),
+						port(corev1.ProtocolTCP, 5353),
+						port(corev1.ProtocolUDP, 53),
+						port(corev1.ProtocolTCP, 53),
+					},
+				},
+				{
+					Ports: []networkingv1.NetworkPolicyPort{
+						port(corev1.ProtocolTCP, 6443),
+						port(corev1.ProtocolTCP, 443),
+						port(corev1.ProtocolTCP, 4317),
+						port(corev1.ProtocolTCP, 4318),
+					},
+				},
+			},
+		},
+	}
+}
+
+// createMetricsService returns the Service of the metrics of the webhook
+// pods. service-ca-operator generates its serving certificate, which the pods
+// serve the metrics with.
+func createMetricsService() *corev1.Service {
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"service.beta.openshift.io/serving-cert-secret-name": metricsSecretName,
+			},
+			Labels: map[string]string{
+				"app": serviceName,
+			},
+			Name:      metricsServiceName,
+			Namespace: *namespace,
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeClusterIP,
+			Selector: map[string]string{
+				"app": "validation-webhook",
+			},
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "metrics",
+					Port:       metricsPort,
+					TargetPort: intstr.FromInt(metricsPort),
+					Protocol:   corev1.ProtocolTCP,
+				},
+			},
+		},
+	}
+}
+
+// createServiceMonitor returns the ServiceMonitor scraping the metrics
+// Service over TLS, verifying the serving certificate with the service CA
 func createServiceMonitor() *monitoringv1.ServiceMonitor {
 	return &monitoringv1.ServiceMonitor{
 		TypeMeta: metav1.TypeMeta{
@@ -282,7 +560,20 @@ func createServiceMonitor() *monitoringv1.ServiceMonitor {
 					BearerTokenSecret: corev1.SecretKeySelector{
 						Key: "",
 					},
-					Port: "metrics",
+					Port:   "metrics",
+					Path:   "/metrics",
+					Scheme: "https",
+					TLSConfig: &monitoringv1.TLSConfig{
+						SafeTLSConfig: monitoringv1.SafeTLSConfig{
+							CA: monitoringv1.SecretOrConfigMap{
+								ConfigMap: &corev1.ConfigMapKeySelector{
+									LocalObjectReference: corev1.LocalObjectReference{Name: *caBundleName},
+									Key:                  "service-ca.crt",
+								},
+							},
+							ServerName: fmt.Sprintf("%s.%s.svc", metricsServiceName, *namespace),
+						},
+					},
 				},
 			},
 			NamespaceSelector: monitoringv1.NamespaceSelector{
@@ -299,6 +590,233 @@ func createServiceMonitor() *monitoringv1.ServiceMonitor {
 	}
 }
 
+// sloBurnRateWindow is a multiwindow burn rate alert: it fires when the error
+// budget burns faster than factor over both the long and short windows
+type sloBurnRateWindow struct {
+	long     string
+	short    string
+	factor   float64
+	severity string
+}
+
+var (
+	// sloBurnRateWindows page on a fast burn of 2% (1h) or 5% (6h) of a 30
+	// day budget, and warn on a slow burn of 10% (1d, 3d)
+	sloBurnRateWindows = []sloBurnRateWindow{
+		{long: "1h", short: "5m", factor: 14.4, severity: "critical"},
+		{long: "6h", short: "30m", factor: 6, severity: "critical"},
+		{long: "1d", short: "2h", factor: 3, severity: "warning"},
+		{long: "3d", short: "6h", factor: 1, severity: "warning"},
+	}
+	sloRatioWindows = []string{"5m", "30m", "1h", "2h", "6h", "1d", "3d"}
+)
+
+const (
+	// sloErrorBudget is the share of requests which may error, for a 99.9%
+	// availability SLO
+	sloErrorBudget = 0.001
+	// sloLatencyBudget is the share of requests which may be slower than
+	// sloLatencyThreshold, for a 99% latency SLO
+	sloLatencyBudget    = 0.01
+	sloLatencyThreshold = "1"
+)
+
+// createPrometheusRule returns the SLO burn rate alerts for the webhook's
+// availability and latency
+func createPrometheusRule() *monitoringv1.PrometheusRule {
+	recordingRules := []monitoringv1.Rule{}
+	for _, window := range sloRatioWindows {
+		recordingRules = append(recordingRules,
+			monitoringv1.Rule{
+				Record: fmt.Sprintf("managed_webhook:error_ratio:rate%s", window),
+				Expr: intstr.FromString(fmt.Sprintf(
+					`sum by (webhook) (rate(managed_webhook_requests_total{outcome="errored"}[%[1]s])) / sum by (webhook) (rate(managed_webhook_requests_total[%[1]s]))`, window)),
+			},
+			monitoringv1.Rule{
+				Record: fmt.Sprintf("managed_webhook:latency_slow_ratio:rate%s", window),
+				Expr: intstr.FromString(fmt.Sprintf(
+					`1 - (sum by (webhook) (rate(managed_webhook_request_duration_seconds_bucket{le="%[2]s"}[%[1]s])) / sum by (webhook) (rate(managed_webhook_request_duration_seconds_count[%[1]s])))`, window, sloLatencyThreshold)),
+			},
+		)
+	}
+
+	alertingRules := []monitoringv1.Rule{}
+	slos := []struct {
+		alert   string
+		ratio   string
+		budget  float64
+		summary string
+	}{
+		{
+			alert:   "ManagedWebhookErrorBudgetBurn",
+			ratio:   "managed_webhook:error_ratio",
+			budget:  sloErrorBudget,
+			summary: "Webhook {{ $labels.webhook }} is returning errors fast enough to exhaust its 99.9% availability error budget.",
+		},
+		{
+			alert:   "ManagedWebhookLatencyBudgetBurn",
+			ratio:   "managed_webhook:latency_slow_ratio",
+			budget:  sloLatencyBudget,
+			summary: "Webhook {{ $labels.webhook }} is answering slower than " + sloLatencyThreshold + "s often enough to exhaust its 99% latency error budget.",
+		},
+	}
+	for _, slo := range slos {
+		for _, window := range sloBurnRateWindows {
+			threshold := window.factor * slo.budget
+			alertingRules = append(alertingRules, monitoringv1.Rule{
+				Alert: slo.alert,
+				Expr: intstr.FromString(fmt.Sprintf("%[1]s:rate%[2]s > %.4[4]g and %[1]s:rate%[3]s > %.4[4]g",
+					slo.ratio, window.long, window.short, threshold)),
+				For: "2m",
+				Labels: map[string]string{
+					"severity":  window.severity,
+					"namespace": *namespace,
+					"window":    window.long,
+				},
+				Annotations: map[string]string{
+					"summary":     slo.summary,
+					"description": fmt.Sprintf("The error budget is burning %gx faster than sustainable over the last %s and %s. Webhook latency and errors slow down or fail API server requests.", window.factor, window.long, window.short),
+				},
+			})
+		}
+	}
+
+	return &monitoringv1.PrometheusRule{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PrometheusRule",
+			APIVersion: "monitoring.coreos.com/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "validation-webhook-slo",
+			Namespace: *namespace,
+		},
+		Spec: monitoringv1.PrometheusRuleSpec{
+			Groups: []monitoringv1.RuleGroup{
+				{
+					Name:  "validation-webhook-slo.rules",
+					Rules: recordingRules,
+				},
+				{
+					Name:  "validation-webhook-slo.alerts",
+					Rules: alertingRules,
+				},
+			},
+		},
+	}
+}
+
+const (
+	// certificateExpiryWarning and certificateExpiryCritical are how long
+	// before a certificate expires its alert fires at each severity
+	certificateExpiryWarning  = 7 * 24 * time.Hour
+	certificateExpiryCritical = 24 * time.Hour
+)
+
+// createCertificatePrometheusRule returns the alerts on the expiry of the
+// serving certificate and CA bundle the webhook loaded at startup
+func createCertificatePrometheusRule() *monitoringv1.PrometheusRule {
+	alertingRules := []monitoringv1.Rule{}
+	severities := []struct {
+		severity string
+		within   time.Duration
+		summary  string
+	}{
+		{severity: "warning", within: certificateExpiryWarning, summary: "7 days"},
+		{severity: "critical", within: certificateExpiryCritical, summary: "a day"},
+	}
+	for _, s := range severities {
+		alertingRules = append(alertingRules, monitoringv1.Rule{
+			Alert: "ManagedWebhookCertificateExpiring",
+			Expr: intstr.FromString(fmt.Sprintf("managed_webhook_certificate_expiry_timestamp_seconds - time() < %d",
+				int64(s.within.Seconds()))),
+			For: "10m",
+			Labels: map[string]string{
+				"severity":  s.severity,
+				"namespace": *namespace,
+			},
+			Annotations: map[string]string{
+				"summary":     fmt.Sprintf("The webhook {{ $labels.certificate }} certificate in pod {{ $labels.pod }} expires in less than %s.", s.summary),
+				"description": "The webhook loads its certificates at startup. Once they expire the API server can't call it, and requests it guards fail or skip the guardrail. If service-ca-operator has already rotated the certificate, restarting the pod loads the new one.",
+			},
+		})
+	}
+
+	return &monitoringv1.PrometheusRule{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PrometheusRule",
+			APIVersion: "monitoring.coreos.com/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "validation-webhook-certificates",
+			Namespace: *namespace,
+		},
+		Spec: monitoringv1.PrometheusRuleSpec{
+			Groups: []monitoringv1.RuleGroup{
+				{
+					Name:  "validation-webhook-certificates.alerts",
+					Rules: alertingRules,
+				},
+			},
+		},
+	}
+}
+
+// createSelfTestPrometheusRule returns the alert on webhooks failing their
+// canned self-test requests
+func createSelfTestPrometheusRule() *monitoringv1.PrometheusRule {
+	return &monitoringv1.PrometheusRule{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PrometheusRule",
+			APIVersion: "monitoring.coreos.com/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "validation-webhook-selftest",
+			Namespace: *namespace,
+		},
+		Spec: monitoringv1.PrometheusRuleSpec{
+			Groups: []monitoringv1.RuleGroup{
+				{
+					Name: "validation-webhook-selftest.alerts",
+					Rules: []monitoringv1.Rule{
+						{
+							Alert: "ManagedWebhookSelfTestFailing",
+							Expr:  intstr.FromString("managed_webhook_selftest_passed == 0"),
+							For:   "30m",
+							Labels: map[string]string{
+								"severity":  "warning",
+								"namespace": *namespace,
+							},
+							Annotations: map[string]string{
+								"summary":     "Webhook {{ $labels.webhook }} made the wrong decision on self-test {{ $labels.test }} in pod {{ $labels.pod }}.",
+								"description": "The webhook no longer allows or denies a canned request as expected, so it may be letting through what it guards against or blocking legitimate requests. GET /selftest on the pod returns the failing results.",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// createPriorityClass returns the PriorityClass assigned to customer
+// workloads by the podpriority-mutation webhook
+func createPriorityClass() *schedulingv1.PriorityClass {
+	preemptionPolicy := corev1.PreemptLowerPriority
+	return &schedulingv1.PriorityClass{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PriorityClass",
+			APIVersion: schedulingv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: podpriority.PriorityClassName,
+		},
+		Value:            podpriority.PriorityClassValue,
+		GlobalDefault:    false,
+		PreemptionPolicy: &preemptionPolicy,
+		Description:      "Default priority for customer workloads on Managed OpenShift clusters",
+	}
+}
+
 func createCACertConfigMap() *corev1.ConfigMap {
 	return &corev1.ConfigMap{
 		TypeMeta: metav1.TypeMeta{
@@ -319,6 +837,26 @@ func createCACertConfigMap() *corev1.ConfigMap {
 	}
 }
 
+// createClusterParametersConfigMap renders the per-cluster parameters from
+// the ClusterDeployment labels, see pkg/config/parameters.go
+func createClusterParametersConfigMap() *corev1.ConfigMap {
+	data := map[string]string{}
+	for envVar, label := range hookconfig.ClusterParameterLabels {
+		data[envVar] = fmt.Sprintf("{{ fromCDLabel %q }}", label)
+	}
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      hookconfig.ParametersConfigMap,
+			Namespace: *namespace,
+		},
+		Data: data,
+	}
+}
+
 func createPackagedCACertConfigMap(phase string) *corev1.ConfigMap {
 	cm := createCACertConfigMap()
 	cm.Annotations[pkoPhaseAnnotation] = phase
@@ -327,6 +865,17 @@ func createPackagedCACertConfigMap(phase string) *corev1.ConfigMap {
 }
 
 func createPackagedDeployment(replicas int32, phase string) *appsv1.Deployment {
+	deployment := createHostedDeployment(packageNamespace, replicas)
+	deployment.Annotations = map[string]string{
+		pkoPhaseAnnotation: phase,
+	}
+	return deployment
+}
+
+// createHostedDeployment returns the Deployment of the webhooks in the
+// hosted control plane namespace hcpNamespace, scheduled on the nodes of its
+// hosted cluster and next to its control plane
+func createHostedDeployment(hcpNamespace string, replicas int32) *appsv1.Deployment {
 	return &appsv1.Deployment{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Deployment",
@@ -337,9 +886,6 @@ func createPackagedDeployment(replicas int32, phase string) *appsv1.Deployment {
 				"app": "validation-webhook",
 			},
 			Name: "validation-webhook",
-			Annotations: map[string]string{
-				pkoPhaseAnnotation: phase,
-			},
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: &replicas,
@@ -348,10 +894,17 @@ func createPackagedDeployment(replicas int32, phase string) *appsv1.Deployment {
 					"app": "validation-webhook",
 				},
 			},
+			// A new pod is ready before an old one goes, so that the
+			// webhooks with FailurePolicy=Fail keep all their replicas
+			// during rollouts
 			Strategy: appsv1.DeploymentStrategy{
 				Type: appsv1.RollingUpdateDeploymentStrategyType,
 				RollingUpdate: &appsv1.RollingUpdateDeployment{
 					MaxUnavailable: &intstr.IntOrString{
+						Type:   intstr.Int,
+						IntVal: 0,
+					},
+					MaxSurge: &intstr.IntOrString{
 						Type:   intstr.Int,
 						IntVal: 1,
 					},
@@ -388,7 +941,7 @@ func createPackagedDeployment(replicas int32, phase string) *appsv1.Deployment {
 												Key:      hsClusterLabel,
 												Operator: corev1.NodeSelectorOpIn,
 												Values: []string{
-													"{{.package.metadata.namespace}}",
+													hcpNamespace,
 												},
 											},
 										},
@@ -404,7 +957,7 @@ func createPackagedDeployment(replicas int32, phase string) *appsv1.Deployment {
 									PodAffinityTerm: corev1.PodAffinityTerm{
 										LabelSelector: &metav1.LabelSelector{
 											MatchLabels: map[string]string{
-												hsControlPlaneLabel: "{{.package.metadata.namespace}}",
+												hsControlPlaneLabel: hcpNamespace,
 											},
 										},
 										TopologyKey: "kubernetes.io/hostname",
@@ -412,6 +965,8 @@ func createPackagedDeployment(replicas int32, phase string) *appsv1.Deployment {
 								},
 							},
 						},
+						// No two pods share a node, so a node failure
+						// takes at most one replica down
 						PodAntiAffinity: &corev1.PodAntiAffinity{
 							RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
 								{
@@ -420,7 +975,23 @@ func createPackagedDeployment(replicas int32, phase string) *appsv1.Deployment {
 											"app": "validation-webhook",
 										},
 									},
-									TopologyKey: "topology.kubernetes.io/zone",
+									TopologyKey: "kubernetes.io/hostname",
+								},
+							},
+						},
+					},
+					// The pods spread evenly across zones. Unlike one pod
+					// per zone, this leaves room for the surge pod of a
+					// rollout and for autoscaling past the number of
+					// zones, and schedules in single zone clusters.
+					TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+						{
+							MaxSkew:           1,
+							TopologyKey:       "topology.kubernetes.io/zone",
+							WhenUnsatisfiable: corev1.DoNotSchedule,
+							LabelSelector: &metav1.LabelSelector{
+								MatchLabels: map[string]string{
+									"app": "validation-webhook",
 								},
 							},
 						},
@@ -441,7 +1012,7 @@ func createPackagedDeployment(replicas int32, phase string) *appsv1.Deployment {
 						{
 							Key:      hsClusterLabel,
 							Operator: corev1.TolerationOpEqual,
-							Value:    "{{.package.metadata.namespace}}",
+							Value:    hcpNamespace,
 							Effect:   corev1.TaintEffectNoSchedule,
 						},
 					},
@@ -480,7 +1051,7 @@ func createPackagedDeployment(replicas int32, phase string) *appsv1.Deployment {
 							// have to worry about them changing underneath us.
 							ImagePullPolicy: corev1.PullIfNotPresent,
 							Name:            "webhooks",
-							Image:           "REPLACED_BY_PIPELINE",
+							Image:           image(imagePlaceholder),
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      "service-certs",
@@ -503,18 +1074,17 @@ func createPackagedDeployment(replicas int32, phase string) *appsv1.Deployment {
 									ContainerPort: int32(*listenPort),
 								},
 							},
-							Command: []string{
-								"webhooks",
-								"-tlskey", "/service-certs/tls.key",
-								"-tlscert", "/service-certs/tls.crt",
-								"-cacert", "/service-ca/service-ca.crt",
-								"-tls",
-							},
+							Command:   webhookCommand(),
+							Resources: clusterSize.Resources(),
+							// The configuration layers, merged by
+							// pkg/config/layers
+							EnvFrom: configLayersEnvFrom(),
 							Env: []corev1.EnvVar{
 								{
 									Name:  "KUBECONFIG",
 									Value: "/etc/hosted-kubernetes/kubeconfig",
 								},
+								overrideSigningKeyEnv(),
 							},
 						},
 					},
@@ -524,6 +1094,121 @@ func createPackagedDeployment(replicas int32, phase string) *appsv1.Deployment {
 	}
 }
 
+// createScalingResources returns the PodDisruptionBudget and, if it is
+// autoscaled, the HorizontalPodAutoscaler of deployment. The replicas of an
+// autoscaled deployment are left to the autoscaler, so reapplying the
+// deployment doesn't scale it back. Deployments of a single replica get no
+// PodDisruptionBudget, as it would block draining their node.
+func createScalingResources(deployment *appsv1.Deployment) []client.Object {
+	objects := []client.Object{}
+	minReplicas := replicaCount()
+	if minReplicas > 1 {
+		minAvailable := intstr.FromInt(1)
+		objects = append(objects, &policyv1.PodDisruptionBudget{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "PodDisruptionBudget",
+				APIVersion: "policy/v1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      deployment.Name,
+				Namespace: deployment.Namespace,
+			},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				MinAvailable: &minAvailable,
+				Selector:     deployment.Spec.Selector,
+			},
+		})
+	}
+	if maxReplicaCount() == 0 {
+		return objects
+	}
+	deployment.Spec.Replicas = nil
+	averageCPU := resource.MustParse(*autoscaleCPU)
+	objects = append(objects, &autoscalingv2.HorizontalPodAutoscaler{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "HorizontalPodAutoscaler",
+			APIVersion: "autoscaling/v2",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deployment.Name,
+			Namespace: deployment.Namespace,
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       deployment.Name,
+			},
+			MinReplicas: &minReplicas,
+			MaxReplicas: maxReplicaCount(),
+			Metrics: []autoscalingv2.MetricSpec{
+				{
+					// The webhook pods only request resources when
+					// rendered for a cluster size, so they are scaled on
+					// their CPU usage rather than utilization
+					Type: autoscalingv2.ResourceMetricSourceType,
+					Resource: &autoscalingv2.ResourceMetricSource{
+						Name: corev1.ResourceCPU,
+						Target: autoscalingv2.MetricTarget{
+							Type:         autoscalingv2.AverageValueMetricType,
+							AverageValue: &averageCPU,
+						},
+					},
+				},
+			},
+		},
+	})
+	return objects
+}
+
+// configLayersEnvFrom loads every configuration layer ConfigMap with its
+// prefix. The ConfigMaps are optional, and the cluster parameters are only
+// rendered on Classic clusters.
+func configLayersEnvFrom() []corev1.EnvFromSource {
+	envFrom := []corev1.EnvFromSource{}
+	for _, layer := range layers.Layers {
+		envFrom = append(envFrom, corev1.EnvFromSource{
+			Prefix: layer.Prefix,
+			ConfigMapRef: &corev1.ConfigMapEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: layer.ConfigMap},
+				Optional:             pointer.Bool(true),
+			},
+		})
+	}
+	return envFrom
+}
+
+// webhookCommand runs the webhooks with TLS, under the compliance profile
+// the manifests are built for
+func webhookCommand() []string {
+	command := []string{
+		"webhooks",
+		"-tlskey", "/service-certs/tls.key",
+		"-tlscert", "/service-certs/tls.crt",
+		"-cacert", "/service-ca/service-ca.crt",
+		"-tls",
+	}
+	if *complianceProfile != "" {
+		command = append(command, "-compliance-profile", *complianceProfile)
+	}
+	return command
+}
+
+// overrideSigningKeyEnv reads the optional signing key of override tokens,
+// see pkg/override
+func overrideSigningKeyEnv() corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: override.SigningKeyEnvVar,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: override.SigningKeySecret},
+				Key:                  override.SigningKeySecretKey,
+				Optional:             pointer.Bool(true),
+			},
+		},
+	}
+}
+
 func createDaemonSet() *appsv1.DaemonSet {
 	return &appsv1.DaemonSet{
 		TypeMeta: metav1.TypeMeta{
@@ -601,6 +1286,14 @@ func createDaemonSet() *appsv1.DaemonSet {
 								},
 							},
 						},
+						{
+							Name: "metrics-certs",
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{
+									SecretName: metricsSecretName,
+								},
+							},
+						},
 						{
 							Name: "service-ca",
 							VolumeSource: corev1.VolumeSource{
@@ -618,13 +1311,18 @@ func createDaemonSet() *appsv1.DaemonSet {
 							// have to worry about them changing underneath us.
 							ImagePullPolicy: corev1.PullIfNotPresent,
 							Name:            "webhooks",
-							Image:           "${REGISTRY_IMG}@${IMAGE_DIGEST}",
+							Image:           image("${REGISTRY_IMG}@${IMAGE_DIGEST}"),
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      "service-certs",
 									MountPath: "/service-certs",
 									ReadOnly:  true,
 								},
+								{
+									Name:      "metrics-certs",
+									MountPath: "/metrics-certs",
+									ReadOnly:  true,
+								},
 								{
 									Name:      "service-ca",
 									MountPath: "/service-ca",
@@ -635,13 +1333,23 @@ func createDaemonSet() *appsv1.DaemonSet {
 								{
 									ContainerPort: int32(*listenPort),
 								},
+								{
+									Name:          "metrics",
+									ContainerPort: metricsPort,
+								},
 							},
-							Command: []string{
-								"webhooks",
-								"-tlskey", "/service-certs/tls.key",
-								"-tlscert", "/service-certs/tls.crt",
-								"-cacert", "/service-ca/service-ca.crt",
-								"-tls",
+							// The metrics are served with the serving
+							// certificate of the metrics Service
+							Command: append(webhookCommand(),
+								"-metrics-tlskey", "/metrics-certs/tls.key",
+								"-metrics-tlscert", "/metrics-certs/tls.crt",
+							),
+							Resources: clusterSize.Resources(),
+							// The configuration layers, merged by
+							// pkg/config/layers
+							EnvFrom: configLayersEnvFrom(),
+							Env: []corev1.EnvVar{
+								overrideSigningKeyEnv(),
 							},
 						},
 					},
@@ -701,27 +1409,42 @@ func createPackagedService(phase string) *corev1.Service {
 }
 
 func createPackagedValidatingWebhookConfiguration(webhook webhooks.Webhook, phase string) admissionregv1.ValidatingWebhookConfiguration {
-	webhookConfiguration := createValidatingWebhookConfiguration(webhook)
-	uri := webhook.GetURI()
-	url := "https://" + serviceName + ".{{.package.metadata.namespace}}.svc.cluster.local" + uri
+	webhookConfiguration := createHostedValidatingWebhookConfiguration(webhook, packageNamespace, []byte("{{.config.serviceca | b64enc }}"))
 	webhookConfiguration.Annotations[pkoPhaseAnnotation] = phase
+	return webhookConfiguration
+}
+
+// hostedWebhookURL is the URL of webhook in the hosted control plane
+// namespace hcpNamespace. The kube-apiserver of the hosted cluster runs in
+// hcpNamespace, so it resolves the Service directly rather than through
+// konnectivity, which only reaches the Services of the hosted cluster.
+func hostedWebhookURL(webhook webhooks.Webhook, hcpNamespace string) string {
+	return "https://" + serviceName + "." + hcpNamespace + ".svc.cluster.local" + webhook.GetURI()
+}
+
+// createHostedValidatingWebhookConfiguration returns the configuration of
+// webhook in a hosted cluster, calling the webhooks in the hosted control
+// plane namespace hcpNamespace, whose serving certificate caBundle verifies
+func createHostedValidatingWebhookConfiguration(webhook webhooks.Webhook, hcpNamespace string, caBundle []byte) admissionregv1.ValidatingWebhookConfiguration {
+	webhookConfiguration := createValidatingWebhookConfiguration(webhook, utils.ProfileROSAHCP)
+	url := hostedWebhookURL(webhook, hcpNamespace)
 	webhookConfiguration.Annotations[caBundleAnnotation] = "false"
 	webhookConfiguration.Webhooks[0].ClientConfig = admissionregv1.WebhookClientConfig{
 		URL:      &url,
-		CABundle: []byte("{{.config.serviceca | b64enc }}"),
+		CABundle: caBundle,
 	}
 	return webhookConfiguration
 }
 
 // hookToResources turns a Webhook into a ValidatingWebhookConfiguration and Service.
 // The Webhook is expected to implement Rules() which will return a
-func createValidatingWebhookConfiguration(hook webhooks.Webhook) admissionregv1.ValidatingWebhookConfiguration {
-	failPolicy := hook.FailurePolicy()
-	timeout := hook.TimeoutSeconds()
+func createValidatingWebhookConfiguration(hook webhooks.Webhook, profile utils.Profile) admissionregv1.ValidatingWebhookConfiguration {
+	failPolicy := environment.FailurePolicy(hook.Name(), hook.FailurePolicy())
+	timeout := clusterSize.TimeoutSeconds(hook.TimeoutSeconds())
 	matchPolicy := hook.MatchPolicy()
 	sideEffects := hook.SideEffects()
 
-	return admissionregv1.ValidatingWebhookConfiguration{
+	webhookConfiguration := admissionregv1.ValidatingWebhookConfiguration{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "ValidatingWebhookConfiguration",
 			APIVersion: "admissionregistration.k8s.io/v1",
@@ -745,6 +1468,7 @@ func createValidatingWebhookConfiguration(hook webhooks.Webhook) admissionregv1.
 				MatchPolicy:             &matchPolicy,
 				Name:                    fmt.Sprintf("%s.managed.openshift.io", hook.Name()),
 				ObjectSelector:          hook.ObjectSelector(),
+				NamespaceSelector:       hook.NamespaceSelector(),
 				FailurePolicy:           &failPolicy,
 				ClientConfig: admissionregv1.WebhookClientConfig{
 					Service: &admissionregv1.ServiceReference{
@@ -753,32 +1477,41 @@ func createValidatingWebhookConfiguration(hook webhooks.Webhook) admissionregv1.
 						Name:      serviceName,
 					},
 				},
-				Rules: hook.Rules(),
+				Rules: webhooks.Rules(hook, profile),
 			},
 		},
 	}
+	annotateEnforcementMode(webhookConfiguration.Annotations, hook)
+	return webhookConfiguration
 }
 
 func createPackagedMutatingWebhookConfiguration(webhook webhooks.Webhook, phase string) admissionregv1.MutatingWebhookConfiguration {
-	webhookConfiguration := createMutatingWebhookConfiguration(webhook)
-	uri := webhook.GetURI()
-	url := "https://" + serviceName + ".{{.package.metadata.namespace}}.svc.cluster.local" + uri
+	webhookConfiguration := createHostedMutatingWebhookConfiguration(webhook, packageNamespace, []byte("{{.config.serviceca | b64enc }}"))
 	webhookConfiguration.Annotations[pkoPhaseAnnotation] = phase
+	return webhookConfiguration
+}
+
+// createHostedMutatingWebhookConfiguration returns the configuration of
+// webhook in a hosted cluster, calling the webhooks in the hosted control
+// plane namespace hcpNamespace, whose serving certificate caBundle verifies
+func createHostedMutatingWebhookConfiguration(webhook webhooks.Webhook, hcpNamespace string, caBundle []byte) admissionregv1.MutatingWebhookConfiguration {
+	webhookConfiguration := createMutatingWebhookConfiguration(webhook, utils.ProfileROSAHCP)
+	url := hostedWebhookURL(webhook, hcpNamespace)
 	webhookConfiguration.Annotations[caBundleAnnotation] = "false"
 	webhookConfiguration.Webhooks[0].ClientConfig = admissionregv1.WebhookClientConfig{
 		URL:      &url,
-		CABundle: []byte("{{.config.serviceca | b64enc }}"),
+		CABundle: caBundle,
 	}
 	return webhookConfiguration
 }
 
-func createMutatingWebhookConfiguration(hook webhooks.Webhook) admissionregv1.MutatingWebhookConfiguration {
-	failPolicy := hook.FailurePolicy()
-	timeout := hook.TimeoutSeconds()
+func createMutatingWebhookConfiguration(hook webhooks.Webhook, profile utils.Profile) admissionregv1.MutatingWebhookConfiguration {
+	failPolicy := environment.FailurePolicy(hook.Name(), hook.FailurePolicy())
+	timeout := clusterSize.TimeoutSeconds(hook.TimeoutSeconds())
 	matchPolicy := hook.MatchPolicy()
 	sideEffects := hook.SideEffects()
 
-	return admissionregv1.MutatingWebhookConfiguration{
+	webhookConfiguration := admissionregv1.MutatingWebhookConfiguration{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "MutatingWebhookConfiguration",
 			APIVersion: "admissionregistration.k8s.io/v1",
@@ -802,6 +1535,7 @@ func createMutatingWebhookConfiguration(hook webhooks.Webhook) admissionregv1.Mu
 				MatchPolicy:             &matchPolicy,
 				Name:                    fmt.Sprintf("%s.managed.openshift.io", hook.Name()),
 				ObjectSelector:          hook.ObjectSelector(),
+				NamespaceSelector:       hook.NamespaceSelector(),
 				FailurePolicy:           &failPolicy,
 				ClientConfig: admissionregv1.WebhookClientConfig{
 					Service: &admissionregv1.ServiceReference{
@@ -810,10 +1544,20 @@ func createMutatingWebhookConfiguration(hook webhooks.Webhook) admissionregv1.Mu
 						Name:      serviceName,
 					},
 				},
-				Rules: hook.Rules(),
+				Rules: webhooks.Rules(hook, profile),
 			},
 		},
 	}
+	annotateEnforcementMode(webhookConfiguration.Annotations, hook)
+	return webhookConfiguration
+}
+
+// annotateEnforcementMode records on the annotations of the configuration of
+// hook that it only audits requests. Enforcing webhooks aren't annotated.
+func annotateEnforcementMode(annotations map[string]string, hook webhooks.Webhook) {
+	if mode := webhooks.EnforcementMode(hook); mode != hookconfig.EnforcementEnforce {
+		annotations[webhooks.EnforcementModeAnnotation] = string(mode)
+	}
 }
 
 func sliceContains(needle string, haystack []string) bool {
@@ -829,8 +1573,78 @@ func main() {
 	flag.Parse()
 
 	skip := strings.Split(*excludes, ",")
+	profile, err := utils.ParseProfile(*productProfile)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	compliance, err := utils.ParseCompliance(*complianceProfile)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
 	onlyInclude := strings.Split(*only, "")
 
+	mirrors, err = imageref.ParseMirrors(*imageMirrors)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	clusterSize, err = sizing.Parse(*clusterSizeName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	environment, err = overlay.Load(*environmentName)
+	if err == nil {
+		hookNames := make([]string, 0, len(webhooks.Webhooks))
+		for name := range webhooks.Webhooks {
+			hookNames = append(hookNames, name)
+		}
+		err = environment.Validate(hookNames)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	skip = environment.Skip(skip)
+	if maxReplicaCount() != 0 && maxReplicaCount() < replicaCount() {
+		fmt.Printf("Error: -max-replicas %d is less than the %d replicas\n", maxReplicaCount(), replicaCount())
+		os.Exit(1)
+	}
+	if _, err := resource.ParseQuantity(*autoscaleCPU); err != nil {
+		fmt.Printf("Error: invalid -autoscale-cpu %q: %v\n", *autoscaleCPU, err)
+		os.Exit(1)
+	}
+
+	if *diffSource != "" {
+		old, err := loadRendering(*diffSource)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		// Render the same kind of output as the old one
+		var rendered []byte
+		if manifestdiff.IsTemplate(old) {
+			rendered = renderSelectorSyncSet(skip, onlyInclude, profile, compliance)
+		} else {
+			rendered = renderPackage(skip, onlyInclude, compliance)
+		}
+		diff, err := manifestdiff.Diff(old, rendered)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if diff == "" {
+			fmt.Printf("No changes from %s\n", *diffSource)
+			return
+		}
+		fmt.Print(diff)
+		// Like diff(1), exit 1 when the renderings differ
+		os.Exit(1)
+	}
+
 	buildSelectorSyncSet := false
 	if *templateFile != "" {
 		buildSelectorSyncSet = true
@@ -842,199 +1656,706 @@ func main() {
 	}
 
 	if buildSelectorSyncSet {
-		templateResources := syncset.SyncSetResourcesByLabelSelector{}
-		templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createNamespace()})
-		templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createServiceAccount()})
-		templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createRole()})
-		templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createRoleBinding()})
-		templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createClusterRole()})
-		templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createClusterRoleBinding()})
-		templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createPrometheusRole()})
-		templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createPromethusRoleBinding()})
-		templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createServiceMonitor()})
-		templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createCACertConfigMap()})
-		templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createService()})
-
-		encodedDaemonSet, err := syncset.EncodeAndFixDaemonset(createDaemonSet())
+		y := renderSelectorSyncSet(skip, onlyInclude, profile, compliance)
+		err = os.WriteFile(*templateFile, y, 0644)
 		if err != nil {
-			panic(fmt.Sprintf("couldn't marshal: %s\n", err.Error()))
+			panic(fmt.Sprintf("Failed to write to %s: %s\n", *templateFile, err.Error()))
 		}
-		templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Raw: encodedDaemonSet})
+	} else {
+		fmt.Printf("No -syncsetfile option supplied, will not generate selector sync set\n")
+	}
 
-		// Collect all of our webhook names and prepare to sort them all so the
-		// resulting SelectorSyncSet is always sorted.
-		hookNames := make([]string, 0)
-		for name := range webhooks.Webhooks {
-			hookNames = append(hookNames, name)
+	if buildPackage {
+		fname := filepath.Join(*packageDir, "resources.yaml.gotmpl")
+		err := os.WriteFile(fname, renderPackage(skip, onlyInclude, compliance), 0644)
+		if err != nil {
+			panic(fmt.Sprintf("Failed to write to %s: %s", fname, err.Error()))
 		}
-		sort.Strings(hookNames)
-		seen := make(map[string]bool)
-		for _, hookName := range hookNames {
-			hook := webhooks.Webhooks[hookName]
-			if seen[hook().GetURI()] {
-				panic(fmt.Sprintf("Duplicate hook URI: %s", hook().GetURI()))
-			}
-			seen[hook().GetURI()] = true
+	} else {
+		fmt.Printf("No -packagedir option supplied, will not generate package manifest\n")
+	}
 
-			if !hook().ClassicEnabled() {
-				continue
-			}
+	if *olmDir != "" {
+		if err := writeOLMBundle(*olmDir, skip, onlyInclude, profile, compliance); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
-			// no rules...?
-			if len(hook().Rules()) == 0 {
-				continue
-			}
+	if *hostedClusterDir != "" {
+		if err := writeHostedCluster(*hostedClusterDir, skip, onlyInclude, compliance); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
-			if *showHookNames {
-				fmt.Println(hook().Name())
-			}
-			if sliceContains(hook().Name(), skip) {
-				continue
-			}
-			if len(onlyInclude) > 0 && !sliceContains(hook().Name(), onlyInclude) {
-				continue
-			}
+	if *acmPolicies != "" {
+		if err := writeACMPolicies(*acmPolicies, skip, onlyInclude, profile, compliance); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
 
-			// MutatingWebhookConfigurations have special names (e.g., service-mutation)
-			if strings.HasSuffix(hookName, "-mutation") {
-				templateResources.Add(hook().SyncSetLabelSelector(), runtime.RawExtension{Raw: syncset.Encode(createMutatingWebhookConfiguration(hook()))})
-				continue
-			}
+// renderSelectorSyncSet returns the SelectorSyncSet template of the webhooks
+func renderSelectorSyncSet(skip, onlyInclude []string, profile utils.Profile, compliance utils.Compliance) []byte {
+	canaried, baseline, err := loadCanary()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	templateResources := syncset.SyncSetResourcesByLabelSelector{}
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createNamespace()})
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createServiceAccount()})
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createRole()})
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createRoleBinding()})
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createClusterRole(classicHooks(skip, onlyInclude, profile, compliance))})
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createClusterRoleBinding()})
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createPrometheusRole()})
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createPromethusRoleBinding()})
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createMetricsService()})
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createServiceMonitor()})
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createPrometheusRule()})
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createCertificatePrometheusRule()})
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createSelfTestPrometheusRule()})
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createCACertConfigMap()})
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createService()})
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createNetworkPolicy()})
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createPriorityClass()})
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: exemption.CustomResourceDefinition()})
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Object: policy.CustomResourceDefinition()})
+
+	encodedDaemonSet, err := syncset.EncodeAndFixDaemonset(createDaemonSet())
+	if err != nil {
+		panic(fmt.Sprintf("couldn't marshal: %s\n", err.Error()))
+	}
+	templateResources.Add(utils.DefaultLabelSelector(), runtime.RawExtension{Raw: encodedDaemonSet})
+
+	// Collect all of our webhook names and prepare to sort them all so the
+	// resulting SelectorSyncSet is always sorted.
+	hookNames := make([]string, 0)
+	for name := range webhooks.Webhooks {
+		hookNames = append(hookNames, name)
+	}
+	sort.Strings(hookNames)
+	seen := make(map[string]bool)
+	for _, hookName := range hookNames {
+		hook := webhooks.Webhooks[hookName]
+		if seen[hook().GetURI()] {
+			panic(fmt.Sprintf("Duplicate hook URI: %s", hook().GetURI()))
+		}
+		seen[hook().GetURI()] = true
 
-			// Now handle all Validating webhooks
-			templateResources.Add(hook().SyncSetLabelSelector(), runtime.RawExtension{Raw: syncset.Encode(createValidatingWebhookConfiguration(hook()))})
+		if !hook().ClassicEnabled() || !webhooks.Enabled(hook(), profile) || !webhooks.EnabledForCompliance(hook(), compliance) {
+			continue
+		}
+
+		// no rules...?
+		if len(webhooks.Rules(hook(), profile)) == 0 {
+			continue
 		}
 
 		if *showHookNames {
-			os.Exit(0)
+			fmt.Println(hook().Name())
+		}
+		if sliceContains(hook().Name(), skip) {
+			continue
+		}
+		if len(onlyInclude) > 0 && !sliceContains(hook().Name(), onlyInclude) {
+			continue
 		}
 
-		selectorSyncSets := templateResources.RenderSelectorSyncSets(sssLabels)
+		add := templateResources.Add
+		if *syncSetPerWebhook {
+			add = func(key metav1.LabelSelector, object runtime.RawExtension) {
+				templateResources.AddForWebhook(hookName, key, object)
+			}
+		}
+		if canaried[hookName] {
+			add = func(key metav1.LabelSelector, object runtime.RawExtension) {
+				templateResources.AddForCohort(hookName, syncset.CohortCanary, key, object)
+				kind := "ValidatingWebhookConfiguration"
+				if strings.HasSuffix(hookName, "-mutation") {
+					kind = "MutatingWebhookConfiguration"
+				}
+				if previous, ok := baseline[kind+"/sre-"+hookName]; ok {
+					templateResources.AddForCohort(hookName, syncset.CohortFleet, key, previous)
+				}
+			}
+		}
 
-		te := templatev1.Template{
-			TypeMeta: metav1.TypeMeta{
-				Kind:       "Template",
-				APIVersion: "template.openshift.io/v1",
+		// MutatingWebhookConfigurations have special names (e.g., service-mutation)
+		if strings.HasSuffix(hookName, "-mutation") {
+			add(webhooks.SyncSetLabelSelector(hook(), compliance), runtime.RawExtension{Raw: syncset.Encode(createMutatingWebhookConfiguration(hook(), profile))})
+			continue
+		}
+
+		// Now handle all Validating webhooks
+		add(webhooks.SyncSetLabelSelector(hook(), compliance), runtime.RawExtension{Raw: syncset.Encode(createValidatingWebhookConfiguration(hook(), profile))})
+	}
+
+	if *showHookNames {
+		os.Exit(0)
+	}
+
+	templateResources.AddTemplated(utils.DefaultLabelSelector(), runtime.RawExtension{Object: createClusterParametersConfigMap()})
+
+	selectorSyncSets := templateResources.RenderSelectorSyncSets(sssLabels)
+
+	te := templatev1.Template{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Template",
+			APIVersion: "template.openshift.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "selectorsyncset-template",
+		},
+		Parameters: []templatev1.Parameter{
+			// IMAGE_TAG is:
+			// - used to label the SSS
+			// - required to generate IMAGE_DIGEST
+			{
+				Name:     "IMAGE_TAG",
+				Required: true,
 			},
-			ObjectMeta: metav1.ObjectMeta{
-				Name: "selectorsyncset-template",
+			{
+				Name:     "REPO_NAME",
+				Required: true,
+				Value:    repoName,
 			},
-			Parameters: []templatev1.Parameter{
-				// IMAGE_TAG is:
-				// - used to label the SSS
-				// - required to generate IMAGE_DIGEST
-				{
-					Name:     "IMAGE_TAG",
-					Required: true,
-				},
-				{
-					Name:     "REPO_NAME",
-					Required: true,
-					Value:    repoName,
-				},
-				// REGISTRY_IMG must be supplied by the SaaS file
-				{
-					Name:     "REGISTRY_IMG",
-					Required: true,
-				},
-				// IMAGE_DIGEST is populated by app-sre based on probing the image at
-				// ${REGISTRY_IMG}:${IMAGE_TAG}. (${IMAGE_TAG} is generated under the covers
-				// based on the channel and git hash.)
-				{
-					Name:     "IMAGE_DIGEST",
-					Required: true,
-				},
+			// REGISTRY_IMG must be supplied by the SaaS file
+			{
+				Name:     "REGISTRY_IMG",
+				Required: true,
+			},
+			// IMAGE_DIGEST is populated by app-sre based on probing the image at
+			// ${REGISTRY_IMG}:${IMAGE_TAG}. (${IMAGE_TAG} is generated under the covers
+			// based on the channel and git hash.)
+			{
+				Name:     "IMAGE_DIGEST",
+				Required: true,
 			},
-			Objects: selectorSyncSets,
+		},
+		Objects: selectorSyncSets,
+	}
+
+	y, err := yaml.Marshal(te)
+	if err != nil {
+		panic(fmt.Sprintf("couldn't marshal: %s\n", err.Error()))
+	}
+	return y
+}
+
+// renderPackage returns the package-operator resources of the webhooks
+func renderPackage(skip, onlyInclude []string, compliance utils.Compliance) []byte {
+	// packageResources contains all resources intended for a package-operator package, with the key
+	// being the associated filename to generate
+	packageResources := make([]runtime.RawExtension, 0)
+	packageResources = append(packageResources, runtime.RawExtension{Object: createPackagedCACertConfigMap(configPhase)})
+	packageResources = append(packageResources, runtime.RawExtension{Object: createPackagedService(deployPhase)})
+	deployment := createPackagedDeployment(replicaCount(), deployPhase)
+	packageResources = append(packageResources, runtime.RawExtension{Object: deployment})
+	for _, obj := range createScalingResources(deployment) {
+		obj.SetNamespace("")
+		obj.SetAnnotations(map[string]string{pkoPhaseAnnotation: deployPhase})
+		packageResources = append(packageResources, runtime.RawExtension{Object: obj})
+	}
+
+	hookNames := make([]string, 0)
+	for name := range webhooks.Webhooks {
+		hookNames = append(hookNames, name)
+	}
+	sort.Strings(hookNames)
+	seen := make(map[string]bool)
+	for _, hookName := range hookNames {
+		hook := webhooks.Webhooks[hookName]
+		if seen[hook().GetURI()] {
+			panic(fmt.Sprintf("Duplicate hook URI: %s", hook().GetURI()))
+		}
+		seen[hook().GetURI()] = true
+
+		if !hook().HypershiftEnabled() || !webhooks.Enabled(hook(), utils.ProfileROSAHCP) || !webhooks.EnabledForCompliance(hook(), compliance) {
+			continue
+		}
+
+		// no rules...?
+		if len(webhooks.Rules(hook(), utils.ProfileROSAHCP)) == 0 {
+			continue
+		}
+
+		if *showHookNames {
+			fmt.Println(hook().Name())
+		}
+		if sliceContains(hook().Name(), skip) {
+			continue
+		}
+		if len(onlyInclude) > 0 && !sliceContains(hook().Name(), onlyInclude) {
+			continue
+		}
+
+		// MutatingWebhookConfigurations have special names (e.g., service-mutation)
+		if strings.HasSuffix(hookName, "-mutation") {
+			encodedWebhook, err := syncset.EncodeMutatingAndFixCA(createPackagedMutatingWebhookConfiguration(hook(), webhooksPhase))
+			if err != nil {
+				fmt.Printf("Error encoding packaged webhook: %v\n", err)
+				os.Exit(1)
+			}
+			packageResources = append(packageResources, runtime.RawExtension{Raw: encodedWebhook})
+			continue
 		}
 
-		y, err := yaml.Marshal(te)
+		// Now handle all Validating webhooks
+		encodedWebhook, err := syncset.EncodeValidatingAndFixCA(createPackagedValidatingWebhookConfiguration(hook(), webhooksPhase))
+		if err != nil {
+			fmt.Printf("Error encoding packaged webhook: %v\n", err)
+			os.Exit(1)
+		}
+		packageResources = append(packageResources, runtime.RawExtension{Raw: encodedWebhook})
+	}
+	var rb strings.Builder
+	for _, packageResource := range packageResources {
+		resourceYaml, err := yaml.Marshal(packageResource)
 		if err != nil {
-			panic(fmt.Sprintf("couldn't marshal: %s\n", err.Error()))
+			panic(fmt.Sprintf("Failed to marshal resource to string: %s", err.Error()))
 		}
+		rb.WriteString("---\n")
+		rb.Write(resourceYaml)
+	}
+	return []byte(rb.String())
+}
 
-		err = os.WriteFile(*templateFile, y, 0644)
+const (
+	// olmPackageName is the OLM package of the bundle
+	olmPackageName = repoName
+	// olmCertDir is where OLM mounts the serving certificate it generates
+	// for the webhooks of a CSV
+	olmCertDir = "/tmp/k8s-webhook-server/serving-certs"
+)
+
+// writeOLMBundle writes the OLM bundle of the webhooks to dir: the
+// ClusterServiceVersion and CRDs in manifests/, the bundle annotations in
+// metadata/ and the bundle.Dockerfile building its image
+func writeOLMBundle(dir string, skip, onlyInclude []string, profile utils.Profile, compliance utils.Compliance) error {
+	if *olmVersion == "" {
+		return fmt.Errorf("-olmdir requires -olm-version")
+	}
+	channels := strings.Split(*olmChannels, ",")
+	files := map[string]interface{}{
+		filepath.Join("manifests", olmPackageName+".clusterserviceversion.yaml"):     createClusterServiceVersion(skip, onlyInclude, profile, compliance),
+		filepath.Join("manifests", exemption.Plural+"."+exemption.Group+".crd.yaml"): exemption.CustomResourceDefinition(),
+		filepath.Join("manifests", policy.Plural+"."+policy.Group+".crd.yaml"):       policy.CustomResourceDefinition(),
+		filepath.Join("manifests", "priorityclass.yaml"):                             createPriorityClass(),
+		filepath.Join("metadata", "annotations.yaml"): map[string]interface{}{
+			"annotations": olmBundleAnnotations(channels),
+		},
+	}
+	for name, obj := range files {
+		y, err := yaml.Marshal(obj)
 		if err != nil {
-			panic(fmt.Sprintf("Failed to write to %s: %s\n", *templateFile, err.Error()))
+			return err
+		}
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, y, 0644); err != nil {
+			return err
 		}
-	} else {
-		fmt.Printf("No -syncsetfile option supplied, will not generate selector sync set\n")
 	}
 
-	if buildPackage {
-		// packageResources contains all resources intended for a package-operator package, with the key
-		// being the associated filename to generate
-		packageResources := make([]runtime.RawExtension, 0)
-		packageResources = append(packageResources, runtime.RawExtension{Object: createPackagedCACertConfigMap(configPhase)})
-		packageResources = append(packageResources, runtime.RawExtension{Object: createPackagedService(deployPhase)})
-		packageResources = append(packageResources, runtime.RawExtension{Object: createPackagedDeployment(int32(*replicas), deployPhase)})
-
-		hookNames := make([]string, 0)
-		for name := range webhooks.Webhooks {
-			hookNames = append(hookNames, name)
+	var dockerfile strings.Builder
+	dockerfile.WriteString("FROM scratch\n\n")
+	annotations := olmBundleAnnotations(channels)
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&dockerfile, "LABEL %s=%s\n", key, annotations[key])
+	}
+	dockerfile.WriteString("\nCOPY manifests /manifests/\nCOPY metadata /metadata/\n")
+	return os.WriteFile(filepath.Join(dir, "bundle.Dockerfile"), []byte(dockerfile.String()), 0644)
+}
+
+func olmBundleAnnotations(channels []string) map[string]string {
+	return map[string]string{
+		olm.MediaTypeAnnotation:      "registry+v1",
+		olm.ManifestsAnnotation:      "manifests/",
+		olm.MetadataAnnotation:       "metadata/",
+		olm.PackageAnnotation:        olmPackageName,
+		olm.ChannelsAnnotation:       strings.Join(channels, ","),
+		olm.DefaultChannelAnnotation: channels[0],
+	}
+}
+
+func createClusterServiceVersion(skip, onlyInclude []string, profile utils.Profile, compliance utils.Compliance) *olm.ClusterServiceVersion {
+	hookNames := make([]string, 0)
+	for name := range webhooks.Webhooks {
+		hookNames = append(hookNames, name)
+	}
+	sort.Strings(hookNames)
+	definitions := []olm.WebhookDescription{}
+	for _, hookName := range hookNames {
+		hook := webhooks.Webhooks[hookName]()
+		if !hook.ClassicEnabled() || !webhooks.Enabled(hook, profile) || !webhooks.EnabledForCompliance(hook, compliance) || len(webhooks.Rules(hook, profile)) == 0 {
+			continue
 		}
-		sort.Strings(hookNames)
-		seen := make(map[string]bool)
-		for _, hookName := range hookNames {
-			hook := webhooks.Webhooks[hookName]
-			if seen[hook().GetURI()] {
-				panic(fmt.Sprintf("Duplicate hook URI: %s", hook().GetURI()))
-			}
-			seen[hook().GetURI()] = true
+		if sliceContains(hookName, skip) || len(onlyInclude) > 0 && !sliceContains(hookName, onlyInclude) {
+			continue
+		}
+		definition, ok := olm.Webhook(hook, profile, serviceName, int32(*listenPort), strings.HasSuffix(hookName, "-mutation"))
+		if !ok {
+			fmt.Printf("Leaving %s out of the OLM bundle, OLM can't restrict it to its namespaces\n", hookName)
+			continue
+		}
+		failurePolicy := environment.FailurePolicy(hookName, *definition.FailurePolicy)
+		definition.FailurePolicy = &failurePolicy
+		timeout := clusterSize.TimeoutSeconds(*definition.TimeoutSeconds)
+		definition.TimeoutSeconds = &timeout
+		definitions = append(definitions, definition)
+	}
 
-			if !hook().HypershiftEnabled() {
-				continue
-			}
+	// The pods of the DaemonSet, run by a Deployment with the serving
+	// certificate OLM generates. OLM doesn't mount a CA file, and the CA is
+	// only used for the expiry metric, so the serving certificate stands in
+	// for it.
+	podSpec := createDaemonSet().Spec.Template.Spec
+	podSpec.Volumes = nil
+	container := &podSpec.Containers[0]
+	container.VolumeMounts = nil
+	container.Image = image(*olmImage)
+	container.Command = []string{
+		"webhooks",
+		"-tlskey", olmCertDir + "/tls.key",
+		"-tlscert", olmCertDir + "/tls.crt",
+		"-cacert", olmCertDir + "/tls.crt",
+		"-tls",
+	}
+	if *productProfile != "" {
+		container.Command = append(container.Command, "-product-profile", *productProfile)
+	}
+	if *complianceProfile != "" {
+		container.Command = append(container.Command, "-compliance-profile", *complianceProfile)
+	}
+	replicas := replicaCount()
 
-			// no rules...?
-			if len(hook().Rules()) == 0 {
-				continue
-			}
+	annotations := map[string]string{
+		"capabilities": "Basic Install",
+		"description":  "Validating and mutating admission webhooks guarding managed OpenShift clusters",
+	}
+	if *olmSkipRange != "" {
+		annotations[olm.SkipRangeAnnotation] = *olmSkipRange
+	}
+	replaces := ""
+	if *olmReplaces != "" {
+		replaces = olmPackageName + ".v" + *olmReplaces
+	}
+	return &olm.ClusterServiceVersion{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ClusterServiceVersion",
+			APIVersion: "operators.coreos.com/v1alpha1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        olmPackageName + ".v" + *olmVersion,
+			Annotations: annotations,
+		},
+		Spec: olm.ClusterServiceVersionSpec{
+			DisplayName: "Managed Cluster Validating Webhooks",
+			Description: "Admission webhooks enforcing the guardrails of managed OpenShift clusters. See docs/policy-catalog.md for the policies of each webhook.",
+			Version:     *olmVersion,
+			Replaces:    replaces,
+			Maturity:    "stable",
+			Provider:    olm.AppLink{Name: "Red Hat"},
+			Links:       []olm.AppLink{{Name: "Source Code", URL: "https://github.com/openshift/managed-cluster-validating-webhooks"}},
+			Keywords:    []string{"admission", "webhooks", "managed"},
+			// OLM only installs webhooks of operators watching all
+			// namespaces
+			InstallModes: []olm.InstallMode{
+				{Type: "OwnNamespace", Supported: false},
+				{Type: "SingleNamespace", Supported: false},
+				{Type: "MultiNamespace", Supported: false},
+				{Type: "AllNamespaces", Supported: true},
+			},
+			Install: olm.NamedInstallStrategy{
+				Strategy: "deployment",
+				Spec: olm.StrategyDetailsDeployment{
+					Deployments: []olm.StrategyDeploymentSpec{{
+						Name: serviceName,
+						Spec: appsv1.DeploymentSpec{
+							Replicas: &replicas,
+							Selector: createDaemonSet().Spec.Selector,
+							Template: corev1.PodTemplateSpec{
+								ObjectMeta: createDaemonSet().Spec.Template.ObjectMeta,
+								Spec:       podSpec,
+							},
+						},
+					}},
+					Permissions:        []olm.StrategyDeploymentPermissions{{ServiceAccountName: serviceAccountName, Rules: createRole().Rules}},
+					ClusterPermissions: []olm.StrategyDeploymentPermissions{{ServiceAccountName: serviceAccountName, Rules: createClusterRole(classicHooks(skip, onlyInclude, profile, compliance)).Rules}},
+				},
+			},
+			CustomResourceDefinitions: olm.CustomResourceDefinitions{Owned: []olm.CRDDescription{
+				{Name: exemption.Plural + "." + exemption.Group, Version: exemption.Version, Kind: exemption.Kind, DisplayName: "Webhook Exemption", Description: "Exempts users, groups, service accounts or object names from a webhook until it expires"},
+				{Name: policy.Plural + "." + policy.Group, Version: policy.Version, Kind: policy.Kind, DisplayName: "Validating Webhook Policy", Description: "Tunes the webhooks at runtime"},
+			}},
+			WebhookDefinitions: definitions,
+		},
+	}
+}
+
+// image returns the image of the webhook pods, def unless the -environment
+// overlay or -image sets one, pinned to its digest with -pin-images and
+// rewritten to its mirror with -image-mirrors. The images the pipeline sets
+// are left as they are.
+func image(def string) string {
+	ref := def
+	if environment.Image != "" {
+		ref = environment.Image
+	} else if def == imagePlaceholder && *webhookImage != "" {
+		ref = *webhookImage
+	}
+	if ref == imagePlaceholder || strings.Contains(ref, "${") {
+		return ref
+	}
+	if *pinImages && !imageref.IsPinned(ref) {
+		pinned, err := pinImage(ref)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		ref = pinned
+	}
+	return mirrors.Rewrite(ref)
+}
+
+// pinImage returns ref pinned to the digest of its image, looked up with oc
+// from the source registry, which the mirrors hold the same digest of
+func pinImage(ref string) (string, error) {
+	if pinned, ok := pinnedImages[ref]; ok {
+		return pinned, nil
+	}
+	out, err := exec.Command("oc", "image", "info", "--output", "json", "--filter-by-os", "linux/amd64", ref).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return "", fmt.Errorf("couldn't look up the digest of %s with oc: %v: %s", ref, err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	if err != nil {
+		return "", fmt.Errorf("couldn't look up the digest of %s with oc: %v", ref, err)
+	}
+	info := struct {
+		Digest string `json:"digest"`
+	}{}
+	if err := json.Unmarshal(out, &info); err != nil {
+		return "", fmt.Errorf("couldn't parse the image info of %s: %v", ref, err)
+	}
+	pinned, err := imageref.Pin(ref, info.Digest)
+	if err != nil {
+		return "", err
+	}
+	pinnedImages[ref] = pinned
+	return pinned, nil
+}
+
+// replicaCount returns the number of webhook pods of a Deployment, -replicas
+// unless the -environment overlay sets it
+func replicaCount() int32 {
+	if environment.Replicas != nil {
+		return *environment.Replicas
+	}
+	if clusterSize.Replicas != 0 {
+		return clusterSize.Replicas
+	}
+	return int32(*replicas)
+}
+
+// maxReplicaCount is the number of replicas the HyperShift deployment is
+// autoscaled up to, 0 if it isn't autoscaled
+func maxReplicaCount() int32 {
+	if environment.MaxReplicas != nil {
+		return *environment.MaxReplicas
+	}
+	return int32(*maxReplicas)
+}
+
+// loadCanary returns the -canary webhooks, and the resources of the
+// -canary-baseline template the other clusters keep
+func loadCanary() (map[string]bool, map[string]runtime.RawExtension, error) {
+	canaried := map[string]bool{}
+	for _, name := range strings.Split(*canary, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if _, ok := webhooks.Webhooks[name]; !ok {
+			return nil, nil, fmt.Errorf("unknown -canary webhook %q", name)
+		}
+		canaried[name] = true
+	}
+	if *canaryBaseline == "" {
+		return canaried, map[string]runtime.RawExtension{}, nil
+	}
+	if len(canaried) == 0 {
+		return nil, nil, fmt.Errorf("-canary-baseline requires -canary webhooks")
+	}
+	data, err := os.ReadFile(*canaryBaseline)
+	if err != nil {
+		return nil, nil, err
+	}
+	baseline, err := syncset.BaselineResources(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read -canary-baseline %s: %v", *canaryBaseline, err)
+	}
+	return canaried, baseline, nil
+}
+
+// loadRendering reads an earlier rendering to diff against: a SelectorSyncSet
+// template or package resources file, or a package image whose resources are
+// extracted with oc
+func loadRendering(source string) ([]byte, error) {
+	if _, err := os.Stat(source); err == nil {
+		return os.ReadFile(source)
+	}
+	dir, err := os.MkdirTemp("", "webhook-render-diff")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	out, err := exec.Command("oc", "image", "extract", source, "--confirm", "--path", "/package/resources.yaml.gotmpl:"+dir).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s is neither a file nor a package image oc can extract: %v: %s", source, err, strings.TrimSpace(string(out)))
+	}
+	return os.ReadFile(filepath.Join(dir, "resources.yaml.gotmpl"))
+}
+
+// writeHostedCluster writes the manifests of the webhooks of one hosted
+// cluster to dir, for HyperShift management clusters without
+// package-operator: management.yaml runs the webhooks in the hosted control
+// plane namespace and hosted-cluster.yaml configures them in the hosted
+// cluster. The webhooks and rules are the ones of the package.
+func writeHostedCluster(dir string, skip, onlyInclude []string, compliance utils.Compliance) error {
+	if *hostedCluster == "" || *hostedCABundle == "" {
+		return fmt.Errorf("-hostedclusterdir requires -hosted-cluster and -hosted-ca-bundle")
+	}
+	hcpNamespace := *hostedNamespace
+	if hcpNamespace == "" {
+		hcpNamespace = "clusters-" + *hostedCluster
+	}
+	caBundle, err := os.ReadFile(*hostedCABundle)
+	if err != nil {
+		return err
+	}
+	labels := map[string]string{hostedClusterLabel: *hostedCluster}
+
+	configMap := createCACertConfigMap()
+	configMap.Namespace = hcpNamespace
+	service := createService()
+	service.Namespace = hcpNamespace
+	deployment := createHostedDeployment(hcpNamespace, replicaCount())
+	deployment.Namespace = hcpNamespace
+	// Labelled like the control plane pods of the hosted cluster, so the
+	// hosted control plane policies apply to them
+	deployment.Spec.Template.Labels[hsControlPlaneLabel] = hcpNamespace
+	deployment.Spec.Template.Labels[hostedClusterLabel] = *hostedCluster
+	management := []metav1.Object{configMap, service, deployment}
+	for _, obj := range createScalingResources(deployment) {
+		management = append(management, obj)
+	}
 
-			if *showHookNames {
-				fmt.Println(hook().Name())
+	hookNames := make([]string, 0)
+	for name := range webhooks.Webhooks {
+		hookNames = append(hookNames, name)
+	}
+	sort.Strings(hookNames)
+	guest := []metav1.Object{}
+	for _, hookName := range hookNames {
+		hook := webhooks.Webhooks[hookName]()
+		if !hook.HypershiftEnabled() || !webhooks.Enabled(hook, utils.ProfileROSAHCP) || !webhooks.EnabledForCompliance(hook, compliance) || len(webhooks.Rules(hook, utils.ProfileROSAHCP)) == 0 {
+			continue
+		}
+		if sliceContains(hookName, skip) || len(onlyInclude) > 0 && !sliceContains(hookName, onlyInclude) {
+			continue
+		}
+		if strings.HasSuffix(hookName, "-mutation") {
+			configuration := createHostedMutatingWebhookConfiguration(hook, hcpNamespace, caBundle)
+			guest = append(guest, &configuration)
+			continue
+		}
+		configuration := createHostedValidatingWebhookConfiguration(hook, hcpNamespace, caBundle)
+		guest = append(guest, &configuration)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	files := map[string][]metav1.Object{"management.yaml": management, "hosted-cluster.yaml": guest}
+	for name, objects := range files {
+		var out bytes.Buffer
+		for i, obj := range objects {
+			objLabels := obj.GetLabels()
+			if objLabels == nil {
+				objLabels = map[string]string{}
 			}
-			if sliceContains(hook().Name(), skip) {
-				continue
+			for k, v := range labels {
+				objLabels[k] = v
 			}
-			if len(onlyInclude) > 0 && !sliceContains(hook().Name(), onlyInclude) {
-				continue
+			obj.SetLabels(objLabels)
+			y, err := yaml.Marshal(obj)
+			if err != nil {
+				return err
 			}
-
-			// MutatingWebhookConfigurations have special names (e.g., service-mutation)
-			if strings.HasSuffix(hookName, "-mutation") {
-				encodedWebhook, err := syncset.EncodeMutatingAndFixCA(createPackagedMutatingWebhookConfiguration(hook(), webhooksPhase))
-				if err != nil {
-					fmt.Printf("Error encoding packaged webhook: %v\n", err)
-					os.Exit(1)
-				}
-				packageResources = append(packageResources, runtime.RawExtension{Raw: encodedWebhook})
-				continue
+			if i > 0 {
+				out.WriteString("---\n")
 			}
+			out.Write(y)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), out.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-			// Now handle all Validating webhooks
-			encodedWebhook, err := syncset.EncodeValidatingAndFixCA(createPackagedValidatingWebhookConfiguration(hook(), webhooksPhase))
-			if err != nil {
-				fmt.Printf("Error encoding packaged webhook: %v\n", err)
-				os.Exit(1)
-			}
-			packageResources = append(packageResources, runtime.RawExtension{Raw: encodedWebhook})
+// writeACMPolicies writes the inform mode ACM Policies of the webhooks to
+// path, followed by their PlacementBinding if -acm-placement is set
+func writeACMPolicies(path string, skip, onlyInclude []string, profile utils.Profile, compliance utils.Compliance) error {
+	hookNames := make([]string, 0)
+	for name := range webhooks.Webhooks {
+		hookNames = append(hookNames, name)
+	}
+	sort.Strings(hookNames)
+	policies := []acm.Policy{}
+	for _, hookName := range hookNames {
+		hook := webhooks.Webhooks[hookName]()
+		if !hook.ClassicEnabled() || !webhooks.Enabled(hook, profile) || !webhooks.EnabledForCompliance(hook, compliance) || len(webhooks.Rules(hook, profile)) == 0 {
+			continue
 		}
-		var rb strings.Builder
-		for _, packageResource := range packageResources {
-			resourceYaml, err := yaml.Marshal(packageResource)
-			if err != nil {
-				panic(fmt.Sprintf("Failed to marshal resource to string: %s", err.Error()))
-			}
-			rb.WriteString("---\n")
-			rb.Write(resourceYaml)
+		if sliceContains(hookName, skip) || len(onlyInclude) > 0 && !sliceContains(hookName, onlyInclude) {
+			continue
 		}
-		fname := filepath.Join(*packageDir, "resources.yaml.gotmpl")
-		err := os.WriteFile(fname, []byte(rb.String()), 0644)
+		failurePolicy := environment.FailurePolicy(hookName, hook.FailurePolicy())
+		policies = append(policies, acm.ForWebhook(hook, profile, *acmNamespace, strings.HasSuffix(hookName, "-mutation"), failurePolicy))
+	}
+
+	objects := []interface{}{}
+	for _, p := range policies {
+		objects = append(objects, p)
+	}
+	if *acmPlacement != "" {
+		objects = append(objects, acm.Binding(repoName, *acmNamespace, *acmPlacement, policies))
+	}
+	var out bytes.Buffer
+	for i, obj := range objects {
+		y, err := yaml.Marshal(obj)
 		if err != nil {
-			panic(fmt.Sprintf("Failed to write to %s: %s", fname, err.Error()))
+			return err
 		}
-	} else {
-		fmt.Printf("No -packagedir option supplied, will not generate package manifest\n")
+		if i > 0 {
+			out.WriteString("---\n")
+		}
+		out.Write(y)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
 	}
+	return os.WriteFile(path, out.Bytes(), 0644)
 }
@@ -10,17 +10,31 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/openshift/operator-custom-metrics/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	klog "k8s.io/klog/v2"
 	"k8s.io/klog/v2/klogr"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/openshift/managed-cluster-validating-webhooks/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/audit"
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/config/layers"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/debug"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/dispatcher"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/k8sutil"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/policy"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/selftest"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/serving"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 )
 
 var log = logf.Log.WithName("handler")
@@ -35,24 +49,135 @@ var (
 	tlsCert = flag.String("tlscert", "", "TLS Certificate")
 	caCert  = flag.String("cacert", "", "CA Cert file")
 
+	disableSessionTickets = flag.Bool("tls-disable-session-tickets", false, "Stop clients from resuming TLS sessions with session tickets")
+	sessionTicketKeys     = flag.String("tls-session-ticket-keys", "", "File of base64-encoded 32 byte TLS session ticket keys, one per line, the first encrypting new tickets. Shared between the webhook pods, the API servers can resume sessions with any of them.")
+	http2MaxStreams       = flag.Uint("http2-max-concurrent-streams", 0, "Maximum number of concurrent HTTP/2 streams of each connection, 250 if 0")
+	http2MaxFrameSize     = flag.Uint("http2-max-read-frame-size", 0, "Largest HTTP/2 frame read from the API servers, between 16384 and 16777215 bytes, 1MiB if 0")
+
+	metricsTLSKey  = flag.String("metrics-tlskey", "", "TLS Key of the metrics endpoint. With -metrics-tlscert, serves the metrics over TLS and leaves the metrics Service and ServiceMonitor to the manifests")
+	metricsTLSCert = flag.String("metrics-tlscert", "", "TLS Certificate of the metrics endpoint")
+
+	productProfile    = flag.String("product-profile", os.Getenv(hookconfig.ProductProfileEnvVar), "Product profile selecting the served webhooks and their rules: osd, rosa-classic or rosa-hcp. Serves every webhook if empty. Defaults to "+hookconfig.ProductProfileEnvVar+".")
+	complianceProfile = flag.String("compliance-profile", os.Getenv(hookconfig.ComplianceProfileEnvVar), "Compliance profile enabling stricter webhooks and checks: fedramp. Defaults to "+hookconfig.ComplianceProfileEnvVar+".")
+
 	metricsPath = "/metrics"
 	metricsPort = "8080"
+
+	clusterIDTimeout = 10 * time.Second
+	// shutdownTimeout bounds how long the requests being answered are waited
+	// for when the pod is stopped, within its termination grace period
+	shutdownTimeout = 10 * time.Second
 )
 
+// loadClusterID resolves the cluster ID, bounding how long startup may wait
+// for the API server
+func loadClusterID() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), clusterIDTimeout)
+	defer cancel()
+	return k8sutil.LoadClusterID(ctx, nil)
+}
+
+// loadCapabilities detects the cluster capabilities, with the same bound as
+// loadClusterID
+func loadCapabilities() (k8sutil.Capabilities, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), clusterIDTimeout)
+	defer cancel()
+	return k8sutil.LoadCapabilities(ctx, nil)
+}
+
+// serveMetricsTLS serves the metrics of registry over TLS on addr. The
+// serving certificate is the one service-ca-operator generates for the
+// metrics Service of the manifests, which the ServiceMonitor verifies.
+func serveMetricsTLS(addr string, registry *prometheus.Registry) {
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, promhttp.InstrumentMetricHandler(registry, promhttp.HandlerFor(registry, promhttp.HandlerOpts{})))
+	server := &http.Server{
+		Addr:      addr,
+		Handler:   mux,
+		TLSConfig: &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+	log.Info("Serving metrics over TLS", "address", addr)
+	log.Error(server.ListenAndServeTLS(*metricsTLSCert, *metricsTLSKey), "Error serving metrics over TLS")
+}
+
 func main() {
 	var metricsAddr string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":"+metricsPort, "The address the metric endpoint binds to.")
 	flag.Parse()
 	klog.SetOutput(os.Stdout)
 
-	logf.SetLogger(klogr.New())
+	// The cluster ID is resolved before the logger is set, so that every log
+	// line carries it
+	var clusterID string
+	var clusterIDErr error
+	if !*testHooks {
+		clusterID, clusterIDErr = loadClusterID()
+	}
+	logger := klogr.New()
+	if clusterID != "" {
+		logger = logger.WithValues("clusterID", clusterID)
+	}
+	// The ValidatingWebhookPolicy may change the log level of a webhook
+	logf.SetLogger(logr.New(policy.LogSink(logger.GetSink())))
+	if clusterIDErr != nil {
+		log.Error(clusterIDErr, "Failed to look up the cluster ID, logs, audit records and metrics won't carry it")
+	}
+	// The configuration is parsed before the logger is set, so its invalid
+	// entries are logged now
+	for _, invalid := range layers.InvalidEntries() {
+		log.Error(errors.New(invalid.Error), "Ignoring invalid configuration entry", "key", invalid.Key, "entry", invalid.Entry)
+		localmetrics.IncrementInvalidConfigEntry(invalid.Key)
+	}
+	var caps k8sutil.Capabilities
+	capsLoaded := false
+	if !*testHooks {
+		var err error
+		if caps, err = loadCapabilities(); err != nil {
+			log.Error(err, "Failed to detect the cluster capabilities, webhooks will apply their default checks")
+		} else {
+			capsLoaded = true
+			log.Info("Detected cluster capabilities", "platform", caps.Platform, "region", caps.Region, "networkType", caps.NetworkType, "private", caps.Private, "fips", caps.FIPS)
+		}
+	}
 
 	if !*testHooks {
 		log.Info("HTTP server running at", "listen", net.JoinHostPort(*listenAddress, *listenPort))
 	}
-	dispatcher := dispatcher.NewDispatcher(webhooks.Webhooks)
+	denials, err := debug.NewDenialBufferFromEnv()
+	if err != nil {
+		log.Error(err, "Failed to configure the denial buffer")
+		os.Exit(1)
+	}
+	profile, err := utils.ParseProfile(*productProfile)
+	if err != nil {
+		log.Error(err, "Failed to select the product profile")
+		os.Exit(1)
+	}
+	compliance, err := utils.ParseCompliance(*complianceProfile)
+	if err != nil {
+		log.Error(err, "Failed to select the compliance profile")
+		os.Exit(1)
+	}
+	// Denials must not go unaudited under a compliance profile requiring a
+	// sink, so refuse to serve rather than drop them
+	if compliance.RequiresAuditSink() && os.Getenv(audit.SinkEnvVar) == "" && !*testHooks {
+		log.Error(fmt.Errorf("%s must be set under the %s compliance profile", audit.SinkEnvVar, compliance), "Failed to configure the audit sink")
+		os.Exit(1)
+	}
+	hooks := webhooks.Webhooks.ForProfile(profile).ForCompliance(compliance)
+	// Webhooks which don't apply on the cluster's cloud and region are
+	// evaluated once, since neither ever changes
+	if capsLoaded {
+		for name, hook := range hooks {
+			if !webhooks.EnabledOnPlatform(hook(), caps.Platform, caps.Region) {
+				log.Info("Webhook does not apply on this platform, it allows every request", "webhookName", name)
+			}
+		}
+		hooks = hooks.ForPlatform(caps.Platform, caps.Region)
+	}
+	dispatcher := dispatcher.NewDispatcher(hooks, denials)
 	seen := make(map[string]bool)
-	for name, hook := range webhooks.Webhooks {
+	for name, hook := range hooks {
 		realHook := hook()
 		if seen[realHook.GetURI()] {
 			panic(fmt.Errorf("Duplicate webhook trying to listen on %s", realHook.GetURI()))
@@ -66,17 +191,34 @@ func main() {
 	if *testHooks {
 		os.Exit(0)
 	}
+	http.Handle(debug.WebhooksPath, debug.NewHandler(hooks))
+	http.Handle(debug.DenialsPath, denials)
+	http.Handle(debug.ConfigPath, debug.NewConfigHandler())
+	selfTest := selftest.NewRunner(hooks)
+	http.Handle(selftest.Path, selfTest)
+	if interval, err := selftest.IntervalFromEnv(); err != nil {
+		log.Error(err, "Failed to configure the background self-test, it will only run on request")
+	} else if interval > 0 {
+		selfTest.Start(interval)
+	}
 
 	// start metrics server
+	registry, err := localmetrics.NewRegistry(clusterID)
+	if err != nil {
+		log.Error(err, "Failed to register metrics")
+		os.Exit(1)
+	}
 	metricsServer := metrics.NewBuilder(config.OperatorNamespace, fmt.Sprintf("%s-metrics", config.OperatorName)).
 		WithPort(metricsPort).
 		WithPath(metricsPath).
 		WithServiceLabel(map[string]string{"app": "validation-webhook"}).
-		WithCollectors(localmetrics.MetricsList).
+		WithRegistry(registry).
 		GetConfig()
 
-	// get the namespace we're running in to confirm if running in a cluster
-	if _, err := k8sutil.GetOperatorNamespace(); err != nil {
+	if *metricsTLSCert != "" && *metricsTLSKey != "" {
+		go serveMetricsTLS(metricsAddr, registry)
+		// get the namespace we're running in to confirm if running in a cluster
+	} else if _, err := k8sutil.GetOperatorNamespace(); err != nil {
 		if errors.Is(err, k8sutil.ErrRunLocal) {
 			log.Info("Skipping metrics server creation; not running in a cluster.")
 		} else {
@@ -93,6 +235,22 @@ func main() {
 	server := &http.Server{
 		Addr: net.JoinHostPort(*listenAddress, *listenPort),
 	}
+	// On SIGTERM the requests being answered are finished, then the denials
+	// still queued are recorded, before the pod exits
+	stopped := make(chan struct{})
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+		<-signals
+		log.Info("Shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Error(err, "Failed to finish the requests being answered")
+		}
+		close(stopped)
+	}()
+	var serveErr error
 	if *useTLS {
 		cafile, err := os.ReadFile(*caCert)
 		if err != nil {
@@ -101,12 +259,54 @@ func main() {
 		}
 		certpool := x509.NewCertPool()
 		certpool.AppendCertsFromPEM(cafile)
+		if err := localmetrics.ObserveCertificateExpiry(localmetrics.CertificateCABundle, cafile); err != nil {
+			log.Error(err, "Couldn't record CA bundle expiry")
+		}
+
+		// The key pair is loaded here rather than by ListenAndServeTLS, so the
+		// expiry metric describes the certificate actually served
+		certPEM, err := os.ReadFile(*tlsCert)
+		if err != nil {
+			log.Error(err, "Couldn't read TLS cert file")
+			os.Exit(1)
+		}
+		keyPEM, err := os.ReadFile(*tlsKey)
+		if err != nil {
+			log.Error(err, "Couldn't read TLS key file")
+			os.Exit(1)
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			log.Error(err, "Couldn't load TLS key pair")
+			os.Exit(1)
+		}
+		if err := localmetrics.ObserveCertificateExpiry(localmetrics.CertificateServing, certPEM); err != nil {
+			log.Error(err, "Couldn't record serving certificate expiry")
+		}
 
 		server.TLSConfig = &tls.Config{
-			RootCAs: certpool,
+			RootCAs:      certpool,
+			Certificates: []tls.Certificate{cert},
+		}
+		servingOptions := serving.Options{
+			DisableSessionTickets: *disableSessionTickets,
+			SessionTicketKeyFile:  *sessionTicketKeys,
+			MaxConcurrentStreams:  uint32(*http2MaxStreams),
+			MaxReadFrameSize:      uint32(*http2MaxFrameSize),
 		}
-		log.Error(server.ListenAndServeTLS(*tlsCert, *tlsKey), "Error serving TLS")
+		if err := servingOptions.Configure(server); err != nil {
+			log.Error(err, "Couldn't tune the TLS and HTTP/2 serving")
+			os.Exit(1)
+		}
+		serveErr = server.ListenAndServeTLS("", "")
 	} else {
-		log.Error(server.ListenAndServe(), "Error serving non-TLS connection")
+		serveErr = server.ListenAndServe()
+	}
+	if !errors.Is(serveErr, http.ErrServerClosed) {
+		log.Error(serveErr, "Error serving")
+		os.Exit(1)
 	}
+	<-stopped
+	dispatcher.Close()
+	log.Info("Recorded the queued denials")
 }
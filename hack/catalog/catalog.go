@@ -0,0 +1,239 @@
+package main
+
+// Generate the policy catalog of the webhooks from the registry, as JSON for
+// OCM policy displays or as Markdown for customer-facing documentation, so
+// the catalog can't drift from the code
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+var (
+	exclude = flag.String("exclude", "debug-hook", "Comma-separated list of webhook names to leave out of the catalog")
+	format  = flag.String("format", "json", "Format of the catalog: json or markdown")
+)
+
+// catalog is the schema of the JSON catalog. Fields are only ever added to
+// it, so its consumers keep working across releases.
+type catalog struct {
+	Webhooks []policy `json:"webhooks"`
+}
+
+type policy struct {
+	Name string `json:"name"`
+	// Type is validating or mutating
+	Type          string `json:"type"`
+	URI           string `json:"uri"`
+	Documentation string `json:"documentation"`
+	Rules         []rule `json:"rules"`
+	// Operations are the operations of all rules, for displays listing them
+	// without the rules
+	Operations        []string              `json:"operations"`
+	FailurePolicy     string                `json:"failurePolicy"`
+	ObjectSelector    *metav1.LabelSelector `json:"objectSelector,omitempty"`
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// Profiles are the product profiles the webhook is deployed to
+	Profiles []utils.Profile `json:"profiles"`
+	// Compliance are the compliance profiles the webhook is deployed under,
+	// empty if it is deployed under every profile including none
+	Compliance []utils.Compliance `json:"compliance,omitempty"`
+	Exemptions exemptions         `json:"exemptions"`
+}
+
+type rule struct {
+	APIGroups   []string `json:"apiGroups"`
+	APIVersions []string `json:"apiVersions"`
+	Resources   []string `json:"resources"`
+	Operations  []string `json:"operations"`
+	Scope       string   `json:"scope,omitempty"`
+}
+
+// exemptions are the identities and mechanisms the webhook allows beyond its
+// own checks, with the default configuration
+type exemptions struct {
+	// PrivilegedUsers are the platform users the webhook treats as
+	// privileged in addition to its own
+	PrivilegedUsers []string `json:"privilegedUsers,omitempty"`
+	// LabelExemption is whether namespaces and service accounts can be
+	// exempted from the webhook by label or WebhookExemption
+	LabelExemption bool `json:"labelExemption"`
+	// ServiceAccounts are the service accounts exempted from the webhook
+	ServiceAccounts []string `json:"serviceAccounts,omitempty"`
+}
+
+func buildCatalog(hookNames []string) catalog {
+	c := catalog{Webhooks: []policy{}}
+	for _, name := range hookNames {
+		hook := webhooks.Webhooks[name]()
+		p := policy{
+			Name:              hook.Name(),
+			Type:              "validating",
+			URI:               hook.GetURI(),
+			Documentation:     strings.TrimSpace(hook.Doc()),
+			Rules:             []rule{},
+			FailurePolicy:     string(hook.FailurePolicy()),
+			ObjectSelector:    hook.ObjectSelector(),
+			NamespaceSelector: hook.NamespaceSelector(),
+			Profiles:          []utils.Profile{},
+			Exemptions: exemptions{
+				PrivilegedUsers: hookconfig.Identities(hookconfig.PlatformAdminUsersFor(name), hookconfig.KubeAdminUsersFor(name)),
+				LabelExemption:  hookconfig.IsLabelExemptWebhook(name),
+				ServiceAccounts: hookconfig.ServiceAccountExemptions[name],
+			},
+		}
+		// MutatingWebhookConfigurations have special names, like in the
+		// SelectorSyncSet
+		if strings.HasSuffix(name, "-mutation") {
+			p.Type = "mutating"
+		}
+		operations := map[string]bool{}
+		for _, r := range hook.Rules() {
+			p.Rules = append(p.Rules, newRule(r))
+			for _, op := range r.Operations {
+				operations[string(op)] = true
+			}
+		}
+		for op := range operations {
+			p.Operations = append(p.Operations, op)
+		}
+		sort.Strings(p.Operations)
+		for _, profile := range utils.Profiles {
+			if webhooks.Enabled(hook, profile) {
+				p.Profiles = append(p.Profiles, profile)
+			}
+		}
+		if !webhooks.EnabledForCompliance(hook, utils.ComplianceNone) {
+			for _, compliance := range utils.Compliances {
+				if webhooks.EnabledForCompliance(hook, compliance) {
+					p.Compliance = append(p.Compliance, compliance)
+				}
+			}
+		}
+		c.Webhooks = append(c.Webhooks, p)
+	}
+	return c
+}
+
+func newRule(r admissionregv1.RuleWithOperations) rule {
+	converted := rule{APIGroups: r.APIGroups, APIVersions: r.APIVersions, Resources: r.Resources, Operations: []string{}}
+	for _, op := range r.Operations {
+		converted.Operations = append(converted.Operations, string(op))
+	}
+	if r.Scope != nil {
+		converted.Scope = string(*r.Scope)
+	}
+	return converted
+}
+
+// markdown renders c as a page per webhook section
+func markdown(c catalog) string {
+	var b strings.Builder
+	b.WriteString("# Webhook Policy Catalog\n\n")
+	b.WriteString("<!-- Generated by hack/catalog/catalog.go, do not edit. Run `make catalog` to update it. -->\n\n")
+	b.WriteString("| Webhook | Type | Operations | Profiles |\n|---|---|---|---|\n")
+	for _, p := range c.Webhooks {
+		fmt.Fprintf(&b, "| [%s](#%s) | %s | %s | %s |\n", p.Name, p.Name, p.Type, strings.Join(p.Operations, ", "), profileNames(p.Profiles))
+	}
+	for _, p := range c.Webhooks {
+		fmt.Fprintf(&b, "\n## %s\n\n%s\n\n", p.Name, p.Documentation)
+		b.WriteString("| API Groups | Resources | Operations | Scope |\n|---|---|---|---|\n")
+		for _, r := range p.Rules {
+			scope := r.Scope
+			if scope == "" {
+				scope = "*"
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", groupNames(r.APIGroups), strings.Join(r.Resources, ", "), strings.Join(r.Operations, ", "), scope)
+		}
+		b.WriteString("\n")
+		fmt.Fprintf(&b, "- Failure policy: %s\n", p.FailurePolicy)
+		fmt.Fprintf(&b, "- Profiles: %s\n", profileNames(p.Profiles))
+		if len(p.Compliance) > 0 {
+			names := []string{}
+			for _, compliance := range p.Compliance {
+				names = append(names, string(compliance))
+			}
+			fmt.Fprintf(&b, "- Compliance profiles: %s\n", strings.Join(names, ", "))
+		}
+		if len(p.Exemptions.PrivilegedUsers) > 0 {
+			fmt.Fprintf(&b, "- Privileged platform users: %s\n", strings.Join(p.Exemptions.PrivilegedUsers, ", "))
+		}
+		if len(p.Exemptions.ServiceAccounts) > 0 {
+			fmt.Fprintf(&b, "- Exempt service accounts: %s\n", strings.Join(p.Exemptions.ServiceAccounts, ", "))
+		}
+		if p.Exemptions.LabelExemption {
+			b.WriteString("- Namespaces and service accounts can be exempted by label or WebhookExemption\n")
+		}
+	}
+	return b.String()
+}
+
+func profileNames(profiles []utils.Profile) string {
+	if len(profiles) == 0 {
+		return "none"
+	}
+	names := []string{}
+	for _, profile := range profiles {
+		names = append(names, string(profile))
+	}
+	return strings.Join(names, ", ")
+}
+
+// groupNames names the core API group, which is empty in rules
+func groupNames(groups []string) string {
+	names := []string{}
+	for _, group := range groups {
+		if group == "" {
+			group = "core"
+		}
+		names = append(names, group)
+	}
+	return strings.Join(names, ", ")
+}
+
+func main() {
+	flag.Parse()
+	excluded := map[string]bool{}
+	for _, name := range strings.Split(*exclude, ",") {
+		excluded[name] = true
+	}
+	hookNames := make([]string, 0)
+	for name := range webhooks.Webhooks {
+		if !excluded[name] {
+			hookNames = append(hookNames, name)
+		}
+	}
+	sort.Strings(hookNames)
+	c := buildCatalog(hookNames)
+
+	var out []byte
+	switch *format {
+	case "json":
+		b, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			fmt.Printf("Error encoding: %s\n", err.Error())
+			os.Exit(1)
+		}
+		out = append(b, '\n')
+	case "markdown":
+		out = []byte(markdown(c))
+	default:
+		fmt.Printf("Error: -format must be json or markdown\n")
+		os.Exit(1)
+	}
+	if _, err := os.Stdout.Write(out); err != nil {
+		fmt.Printf("Error Writing: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+// Generate the Grafana dashboard of the webhooks from the registry, so every
+// registered webhook gets its panels
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+)
+
+var (
+	exclude = flag.String("exclude", "debug-hook", "Comma-separated list of webhook names to leave off the dashboard")
+)
+
+const (
+	panelWidth  = 8
+	panelHeight = 8
+	// selectors is added to every query, so the panels follow the cluster
+	// variable
+	selectors = `cluster_id=~"$cluster_id"`
+)
+
+type dashboard struct {
+	UID           string     `json:"uid"`
+	Title         string     `json:"title"`
+	Tags          []string   `json:"tags"`
+	SchemaVersion int        `json:"schemaVersion"`
+	Refresh       string     `json:"refresh"`
+	Time          timeRange  `json:"time"`
+	Templating    templating `json:"templating"`
+	Panels        []panel    `json:"panels"`
+}
+
+type timeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type templating struct {
+	List []variable `json:"list"`
+}
+
+type variable struct {
+	Name       string      `json:"name"`
+	Label      string      `json:"label"`
+	Type       string      `json:"type"`
+	Query      string      `json:"query"`
+	Datasource *datasource `json:"datasource,omitempty"`
+	IncludeAll bool        `json:"includeAll,omitempty"`
+	AllValue   string      `json:"allValue,omitempty"`
+	Multi      bool        `json:"multi,omitempty"`
+	Refresh    int         `json:"refresh,omitempty"`
+}
+
+type datasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type gridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type target struct {
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat"`
+	RefID        string `json:"refId"`
+}
+
+type fieldConfig struct {
+	Defaults fieldDefaults `json:"defaults"`
+}
+
+type fieldDefaults struct {
+	Unit string `json:"unit"`
+}
+
+type panel struct {
+	ID          int          `json:"id"`
+	Type        string       `json:"type"`
+	Title       string       `json:"title"`
+	GridPos     gridPos      `json:"gridPos"`
+	Datasource  *datasource  `json:"datasource,omitempty"`
+	Targets     []target     `json:"targets,omitempty"`
+	FieldConfig *fieldConfig `json:"fieldConfig,omitempty"`
+	Collapsed   bool         `json:"collapsed,omitempty"`
+	Panels      []panel      `json:"panels,omitempty"`
+}
+
+var prometheus = &datasource{Type: "prometheus", UID: "${datasource}"}
+
+// webhookPanels returns the denial rate, latency and error panels of a
+// webhook, starting at row y
+func webhookPanels(name string, id, y int) []panel {
+	labels := fmt.Sprintf(`webhook="%s",%s`, name, selectors)
+	timeseries := func(x int, title, unit string, targets ...target) panel {
+		id++
+		for i := range targets {
+			targets[i].RefID = string(rune('A' + i))
+		}
+		return panel{
+			ID:          id,
+			Type:        "timeseries",
+			Title:       title,
+			GridPos:     gridPos{H: panelHeight, W: panelWidth, X: x, Y: y},
+			Datasource:  prometheus,
+			Targets:     targets,
+			FieldConfig: &fieldConfig{Defaults: fieldDefaults{Unit: unit}},
+		}
+	}
+	return []panel{
+		timeseries(0, "Denial rate", "reqps",
+			target{
+				Expr:         fmt.Sprintf(`sum(rate(managed_webhook_requests_total{outcome="denied",%s}[5m]))`, labels),
+				LegendFormat: "denied",
+			},
+			target{
+				Expr:         fmt.Sprintf(`sum(rate(managed_webhook_requests_total{%s}[5m]))`, labels),
+				LegendFormat: "total",
+			},
+			target{
+				Expr:         fmt.Sprintf(`sum by (reason) (rate(managed_webhook_denied_requests_total{%s}[5m]))`, labels),
+				LegendFormat: "denied {{reason}}",
+			},
+		),
+		timeseries(panelWidth, "Latency", "s",
+			target{
+				Expr:         fmt.Sprintf(`histogram_quantile(0.5, sum by (le) (rate(managed_webhook_request_duration_seconds_bucket{%s}[5m])))`, labels),
+				LegendFormat: "p50",
+			},
+			target{
+				Expr:         fmt.Sprintf(`histogram_quantile(0.99, sum by (le) (rate(managed_webhook_request_duration_seconds_bucket{%s}[5m])))`, labels),
+				LegendFormat: "p99",
+			},
+			target{
+				Expr:         fmt.Sprintf(`histogram_quantile(0.99, sum by (le) (rate(managed_webhook_evaluation_duration_seconds_bucket{%s}[5m])))`, labels),
+				LegendFormat: "p99 evaluation",
+			},
+		),
+		timeseries(2*panelWidth, "Errors", "reqps",
+			target{
+				Expr:         fmt.Sprintf(`sum(rate(managed_webhook_requests_total{outcome="errored",%s}[5m]))`, labels),
+				LegendFormat: "errored",
+			},
+			target{
+				Expr:         fmt.Sprintf(`sum by (reason) (rate(managed_webhook_malformed_requests_total{%s}[5m]))`, labels),
+				LegendFormat: "malformed {{reason}}",
+			},
+			target{
+				Expr:         fmt.Sprintf(`sum(rate(managed_webhook_near_timeout_requests_total{%s}[5m]))`, labels),
+				LegendFormat: "near timeout",
+			},
+		),
+	}
+}
+
+// buildDashboard returns the dashboard with a row of panels for each of the
+// named webhooks
+func buildDashboard(hookNames []string) dashboard {
+	d := dashboard{
+		UID:           "managed-cluster-validating-webhooks",
+		Title:         "Managed Cluster Validating Webhooks",
+		Tags:          []string{"managed-cluster-validating-webhooks"},
+		SchemaVersion: 36,
+		Refresh:       "1m",
+		Time:          timeRange{From: "now-6h", To: "now"},
+		Templating: templating{List: []variable{
+			{
+				Name:  "datasource",
+				Label: "Datasource",
+				Type:  "datasource",
+				Query: "prometheus",
+			},
+			{
+				Name:       "cluster_id",
+				Label:      "Cluster",
+				Type:       "query",
+				Query:      "label_values(managed_webhook_requests_total, cluster_id)",
+				Datasource: prometheus,
+				IncludeAll: true,
+				// Also matches metrics of pods which couldn't look up the
+				// cluster ID
+				AllValue: ".*",
+				Multi:    true,
+				Refresh:  2,
+			},
+		}},
+		Panels: []panel{},
+	}
+	id, y := 0, 0
+	for _, name := range hookNames {
+		id++
+		d.Panels = append(d.Panels, panel{
+			ID:        id,
+			Type:      "row",
+			Title:     name,
+			GridPos:   gridPos{H: 1, W: 3 * panelWidth, X: 0, Y: y},
+			Collapsed: true,
+			Panels:    webhookPanels(name, id, y+1),
+		})
+		id += 3
+		y += 1 + panelHeight
+	}
+	return d
+}
+
+func main() {
+	flag.Parse()
+	excluded := map[string]bool{}
+	for _, name := range strings.Split(*exclude, ",") {
+		excluded[name] = true
+	}
+	hookNames := make([]string, 0)
+	for name := range webhooks.Webhooks {
+		if !excluded[name] {
+			hookNames = append(hookNames, name)
+		}
+	}
+	sort.Strings(hookNames)
+
+	b, err := json.MarshalIndent(buildDashboard(hookNames), "", "  ")
+	if err != nil {
+		fmt.Printf("Error encoding: %s\n", err.Error())
+		os.Exit(1)
+	}
+	if _, err = os.Stdout.Write(append(b, '\n')); err != nil {
+		fmt.Printf("Error Writing: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
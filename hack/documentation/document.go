@@ -22,6 +22,7 @@ type docuhook struct {
 	Name                string                              `json:"webhookName"`
 	Rules               []admissionregv1.RuleWithOperations `json:"rules,omitempty"`
 	ObjectSelector      *metav1.LabelSelector               `json:"webhookObjectSelector,omitempty"`
+	NamespaceSelector   *metav1.LabelSelector               `json:"webhookNamespaceSelector,omitempty"`
 	DocumentationString string                              `json:"documentString"`
 }
 
@@ -42,6 +43,7 @@ func WriteDocs() {
 		if !*hideRules {
 			dochooks[i].Rules = realHook.Rules()
 			dochooks[i].ObjectSelector = realHook.ObjectSelector()
+			dochooks[i].NamespaceSelector = realHook.NamespaceSelector()
 		}
 	}
 
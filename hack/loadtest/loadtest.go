@@ -0,0 +1,150 @@
+package main
+
+// Send synthetic AdmissionReview traffic to a running webhook server and
+// report its latency percentiles, e.g.
+//   go run hack/loadtest/loadtest.go -url http://localhost:8888 -mix scc-validation=3,namespace-validation -concurrency 20 -duration 1m
+// Exits 2 if any request failed or the webhooks errored on it.
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-logr/logr"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/loadtest"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+var (
+	url          = flag.String("url", "http://localhost:5000", "Base URL of the webhook server")
+	mix          = flag.String("mix", "", "Comma-separated webhook=weight traffic mix, e.g. scc-validation=3,namespace-validation. Every webhook of the profile has a weight of 1 by default.")
+	payloadSizes = flag.String("payload-sizes", "1024", "Comma-separated sizes in bytes the objects of the requests are padded to, sent in turn")
+	concurrency  = flag.Int("concurrency", 10, "Number of concurrent requests")
+	requests     = flag.Int("requests", 0, "Number of requests to send, unlimited if 0")
+	duration     = flag.Duration("duration", 30*time.Second, "How long to send requests for, unlimited if 0")
+	user         = flag.String("user", "loadtest-user", "Username of the requester of the requests")
+	groups       = flag.String("groups", "system:authenticated", "Comma-separated groups of the requester of the requests")
+	profile      = flag.String("product-profile", "", "Product profile of the webhooks of the default mix: osd, rosa-classic or rosa-hcp")
+	caCert       = flag.String("cacert", "", "CA certificate file of an https webhook server")
+	insecure     = flag.Bool("insecure-skip-verify", false, "Don't verify the certificate of an https webhook server")
+	output       = flag.String("o", "text", "Output format: text or json")
+)
+
+func main() {
+	flag.Parse()
+	if *output != "text" && *output != "json" {
+		fail(fmt.Errorf("-o must be text or json"))
+	}
+	if *requests == 0 && *duration == 0 {
+		fail(fmt.Errorf("-requests or -duration is required"))
+	}
+	p, err := utils.ParseProfile(*profile)
+	if err != nil {
+		fail(err)
+	}
+	// The webhooks are only built for their rules and URIs, they must not
+	// reach whatever cluster is configured
+	os.Setenv("KUBECONFIG", os.DevNull)
+	logf.SetLogger(logr.New(logf.NullLogSink{}))
+
+	hooks := webhooks.Webhooks.ForProfile(p)
+	weights, err := loadtest.ParseMix(*mix, hooks)
+	if err != nil {
+		fail(err)
+	}
+	opts := loadtest.Options{
+		URL:         *url,
+		Mix:         weights,
+		Concurrency: *concurrency,
+		Requests:    *requests,
+		Duration:    *duration,
+		Username:    *user,
+		Client:      httpClient(),
+	}
+	for _, size := range strings.Split(*payloadSizes, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(size))
+		if err != nil || n < 0 {
+			fail(fmt.Errorf("invalid payload size %q", size))
+		}
+		opts.PayloadSizes = append(opts.PayloadSizes, n)
+	}
+	for _, group := range strings.Split(*groups, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			opts.Groups = append(opts.Groups, group)
+		}
+	}
+
+	report, err := loadtest.Run(context.Background(), hooks, opts)
+	if err != nil {
+		fail(err)
+	}
+	if *output == "json" {
+		b, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fail(err)
+		}
+		fmt.Println(string(b))
+	} else {
+		printReport(report)
+	}
+	if report.Total.Errors > 0 {
+		os.Exit(2)
+	}
+}
+
+func httpClient() *http.Client {
+	tlsConfig := &tls.Config{InsecureSkipVerify: *insecure}
+	if *caCert != "" {
+		ca, err := os.ReadFile(*caCert)
+		if err != nil {
+			fail(err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			fail(fmt.Errorf("no certificates in %s", *caCert))
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	// Keep a connection per concurrent request, like the API server
+	transport.MaxIdleConnsPerHost = *concurrency
+	return &http.Client{Transport: transport, Timeout: 30 * time.Second}
+}
+
+func printReport(report *loadtest.Report) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "WEBHOOK\tREQUESTS\tERRORS\tDENIED\tP50\tP90\tP99\tMAX")
+	names := make([]string, 0, len(report.Webhooks))
+	for name := range report.Webhooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	row := func(name string, s loadtest.Stats) {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%v\t%v\t%v\t%v\n", name, s.Requests, s.Errors, s.Denied, s.P50, s.P90, s.P99, s.Max)
+	}
+	for _, name := range names {
+		row(name, report.Webhooks[name])
+	}
+	row("TOTAL", report.Total)
+	w.Flush()
+	fmt.Printf("\n%d requests in %v, %.1f requests per second\n", report.Total.Requests, report.Duration.Round(time.Millisecond), report.Throughput)
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(1)
+}
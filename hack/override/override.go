@@ -0,0 +1,65 @@
+package main
+
+// Mint a signed override token allowing one denied request, with one
+// operation and reason code, on a named object. The signing key is read from OVERRIDE_SIGNING_KEY, e.g.
+//   OVERRIDE_SIGNING_KEY=$(oc -n openshift-validation-webhook get secret webhook-override-key -o jsonpath='{.data.key}' | base64 -d)
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/override"
+)
+
+var (
+	webhook   = flag.String("webhook", "", "Name of the webhook whose denial is overridden, e.g. scc-validation")
+	group     = flag.String("group", "", "API group of the object, empty for the core group")
+	resource  = flag.String("resource", "", "Resource of the object, e.g. securitycontextconstraints")
+	namespace = flag.String("namespace", "", "Namespace of the object, empty for cluster-scoped objects")
+	name      = flag.String("name", "", "Name of the object")
+	operation = flag.String("operation", "", "Admission operation of the request, e.g. DELETE")
+	code      = flag.String("code", "", "Reason code of the denial, e.g. SCC002_DEFAULT_SCC_DELETE, empty for denials without one")
+	ttl       = flag.Duration("ttl", 15*time.Minute, fmt.Sprintf("How long the token is valid for, at most %s", override.MaxTTL))
+)
+
+func main() {
+	flag.Parse()
+	key := os.Getenv(override.SigningKeyEnvVar)
+	switch {
+	case key == "":
+		fail(fmt.Errorf("%s must be set to the signing key", override.SigningKeyEnvVar))
+	case *webhook == "" || *resource == "" || *name == "" || *operation == "":
+		fail(fmt.Errorf("-webhook, -resource, -name and -operation are required"))
+	case *ttl <= 0 || *ttl > override.MaxTTL:
+		fail(fmt.Errorf("-ttl must be positive and at most %s", override.MaxTTL))
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		fail(err)
+	}
+	token, err := override.Sign([]byte(key), override.Token{
+		Webhook:   *webhook,
+		Group:     *group,
+		Resource:  *resource,
+		Namespace: *namespace,
+		Name:      *name,
+		Operation: strings.ToUpper(*operation),
+		Code:      *code,
+		ExpiresAt: time.Now().Add(*ttl).Unix(),
+		Nonce:     hex.EncodeToString(nonce),
+	})
+	if err != nil {
+		fail(err)
+	}
+	fmt.Printf("%s=%s\n", override.Annotation, token)
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,135 @@
+package main
+
+// Report the drift of the webhook configurations of a cluster from the ones
+// the registry of this tree generates, e.g.
+//   go run hack/verify/verify.go -kubeconfig ~/.kube/config -product-profile rosa-classic -environment prod
+// Exits 2 if any webhook configuration drifted.
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/drift"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/overlay"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/sizing"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+var (
+	kubeconfig      = flag.String("kubeconfig", os.Getenv("KUBECONFIG"), "Kubeconfig of the cluster to verify")
+	profile         = flag.String("product-profile", "", "Product profile of the cluster: osd, rosa-classic or rosa-hcp")
+	compliance      = flag.String("compliance-profile", "", "Compliance profile of the cluster, e.g. fedramp")
+	environmentName = flag.String("environment", "", fmt.Sprintf("Expect the failure policies of the built-in overlay of this environment, one of %v", overlay.Environments()))
+	clusterSize     = flag.String("cluster-size", "", fmt.Sprintf("Cluster size the manifests were rendered for, one of %v", sizing.Names()))
+	excludes        = flag.String("exclude", "debug-hook", "Comma-separated list of webhook names which aren't deployed")
+	namespace       = flag.String("namespace", "openshift-validation-webhook", "Namespace of the webhook server")
+	service         = flag.String("service", "validation-webhook", "Service of the webhook server")
+	secret          = flag.String("secret", "webhook-cert", "Secret of the serving certificate of the webhook server")
+	output          = flag.String("o", "text", "Output format: text or json")
+)
+
+func main() {
+	flag.Parse()
+	if *output != "text" && *output != "json" {
+		fail(fmt.Errorf("-o must be text or json"))
+	}
+	if *kubeconfig == "" {
+		fail(fmt.Errorf("-kubeconfig or KUBECONFIG is required"))
+	}
+	p, err := utils.ParseProfile(*profile)
+	if err != nil {
+		fail(err)
+	}
+	c, err := utils.ParseCompliance(*compliance)
+	if err != nil {
+		fail(err)
+	}
+	environment, err := overlay.Load(*environmentName)
+	if err != nil {
+		fail(err)
+	}
+	size, err := sizing.Parse(*clusterSize)
+	if err != nil {
+		fail(err)
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		fail(err)
+	}
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		fail(err)
+	}
+	kubeClient, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		fail(err)
+	}
+	// The webhooks are only built for their configurations, they must not
+	// reach the cluster themselves
+	os.Setenv("KUBECONFIG", os.DevNull)
+	logf.SetLogger(logr.New(logf.NullLogSink{}))
+
+	opts := drift.Options{
+		Profile:       p,
+		Compliance:    c,
+		FailurePolicy: environment.FailurePolicy,
+		Size:          size,
+		Exclude:       environment.Skip(strings.Split(*excludes, ",")),
+		Namespace:     *namespace,
+		Service:       *service,
+		Secret:        *secret,
+	}
+	findings, err := drift.Verify(context.Background(), kubeClient, webhooks.Webhooks.ForProfile(p), opts)
+	if err != nil {
+		fail(err)
+	}
+	if *output == "json" {
+		b, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			fail(err)
+		}
+		fmt.Println(string(b))
+	} else {
+		printFindings(findings)
+	}
+	if len(findings) > 0 {
+		os.Exit(2)
+	}
+}
+
+func printFindings(findings []drift.Finding) {
+	if len(findings) == 0 {
+		fmt.Println("No drift from the registry")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CONFIGURATION\tKIND\tFIELD\tMESSAGE")
+	for _, f := range findings {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.Configuration, f.Kind, f.Field, f.Message)
+	}
+	w.Flush()
+	for _, f := range findings {
+		if f.Expected != "" || f.Actual != "" {
+			fmt.Printf("\n%s %s:\n  expected: %s\n  actual:   %s\n", f.Configuration, f.Field, f.Expected, f.Actual)
+		}
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,153 @@
+package main
+
+// Run local manifests through the webhooks offline and print which would deny
+// them and why, e.g.
+//   go run hack/webhook-eval/webhook-eval.go -user alice -groups system:authenticated -f manifests/
+// Exits 2 if any webhook would deny a manifest.
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-logr/logr"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/eval"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+var (
+	files     = flag.String("f", "-", "Comma-separated manifest files or directories of .yaml, .yml and .json files, - for stdin")
+	user      = flag.String("user", "", "Username of the assumed requester")
+	groups    = flag.String("groups", "system:authenticated", "Comma-separated groups of the assumed requester")
+	operation = flag.String("operation", string(admissionv1.Create), "Operation of the requests: CREATE, UPDATE or DELETE")
+	namespace = flag.String("namespace", "", "Namespace of the manifests without one")
+	profile   = flag.String("product-profile", "", "Product profile of the evaluated webhooks: osd, rosa-classic or rosa-hcp")
+	output    = flag.String("o", "text", "Output format: text or json")
+	verbose   = flag.Bool("v", false, "Also print the webhooks which allow each manifest")
+)
+
+func main() {
+	flag.Parse()
+	op := admissionv1.Operation(strings.ToUpper(*operation))
+	switch {
+	case *user == "":
+		fail(fmt.Errorf("-user is required"))
+	case op != admissionv1.Create && op != admissionv1.Update && op != admissionv1.Delete:
+		fail(fmt.Errorf("-operation must be CREATE, UPDATE or DELETE"))
+	case *output != "text" && *output != "json":
+		fail(fmt.Errorf("-o must be text or json"))
+	}
+	p, err := utils.ParseProfile(*profile)
+	if err != nil {
+		fail(err)
+	}
+	// Stay offline: webhooks which read the cluster fail to create a client
+	// and report an error instead of reading whatever cluster is configured
+	os.Setenv("KUBECONFIG", os.DevNull)
+	logf.SetLogger(logr.New(logf.NullLogSink{}))
+
+	opts := eval.Options{Username: *user, Operation: op, Namespace: *namespace}
+	for _, group := range strings.Split(*groups, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			opts.Groups = append(opts.Groups, group)
+		}
+	}
+	hooks := webhooks.Webhooks.ForProfile(p)
+	results := []eval.Result{}
+	for _, path := range manifestPaths(strings.Split(*files, ",")) {
+		objects, err := decodeFile(path)
+		if err != nil {
+			fail(fmt.Errorf("%s: %v", path, err))
+		}
+		for _, obj := range objects {
+			r, err := eval.Evaluate(hooks, obj, opts)
+			if err != nil {
+				fail(fmt.Errorf("%s: %v", path, err))
+			}
+			results = append(results, r...)
+		}
+	}
+
+	denied := false
+	if *output == "json" {
+		b, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			fail(err)
+		}
+		fmt.Println(string(b))
+	}
+	for _, r := range results {
+		denied = denied || r.Denied()
+		if *output == "json" {
+			continue
+		}
+		switch {
+		case r.Denied():
+			fmt.Printf("DENIED   %s by %s: %s %s\n", r.Object, r.Webhook, r.Code, r.Message)
+		case r.Errored:
+			fmt.Printf("UNKNOWN  %s by %s: %s\n", r.Object, r.Webhook, r.Message)
+		case *verbose:
+			fmt.Printf("ALLOWED  %s by %s\n", r.Object, r.Webhook)
+		}
+		for _, warning := range r.Warnings {
+			fmt.Printf("WARNING  %s by %s: %s\n", r.Object, r.Webhook, warning)
+		}
+	}
+	if denied {
+		os.Exit(2)
+	}
+}
+
+// manifestPaths expands directories to the manifest files they hold
+func manifestPaths(args []string) []string {
+	paths := []string{}
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if arg == "-" || err != nil || !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			switch filepath.Ext(path) {
+			case ".yaml", ".yml", ".json":
+				if !d.IsDir() {
+					paths = append(paths, path)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			fail(err)
+		}
+	}
+	return paths
+}
+
+func decodeFile(path string) ([]*unstructured.Unstructured, error) {
+	if path == "-" {
+		return eval.Decode(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return eval.Decode(f)
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(1)
+}
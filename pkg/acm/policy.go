@@ -0,0 +1,208 @@
+// Package acm holds the subset of the Open Cluster Management governance API
+// needed to express the protections of the webhooks as Policies, so fleets
+// managed with ACM report compliance with the same guardrails. The
+// governance API module isn't a dependency, so the types mirror its JSON
+// schema.
+package acm
+
+import (
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	Group                  = "policy.open-cluster-management.io"
+	PolicyAPIVersion       = Group + "/v1"
+	PlacementGroup         = "cluster.open-cluster-management.io"
+	PlacementBindingPrefix = "binding-"
+
+	// RemediationInform reports violations without fixing them. The
+	// webhooks enforce the guardrails, the policies only report on them.
+	RemediationInform  = "inform"
+	ComplianceMustHave = "musthave"
+
+	StandardsAnnotation  = Group + "/standards"
+	CategoriesAnnotation = Group + "/categories"
+	ControlsAnnotation   = Group + "/controls"
+	// DescriptionAnnotation holds the documentation of the webhook of a
+	// Policy
+	DescriptionAnnotation = "managed.openshift.io/description"
+)
+
+// Policy is a set of ConfigurationPolicies propagated to the clusters of its
+// Placement
+type Policy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              PolicySpec `json:"spec"`
+}
+
+// PolicySpec is the spec of a Policy
+type PolicySpec struct {
+	Disabled          bool             `json:"disabled"`
+	RemediationAction string           `json:"remediationAction"`
+	PolicyTemplates   []PolicyTemplate `json:"policy-templates"`
+}
+
+// PolicyTemplate is a policy of a Policy
+type PolicyTemplate struct {
+	ObjectDefinition ConfigurationPolicy `json:"objectDefinition"`
+}
+
+// ConfigurationPolicy checks that objects on a cluster match templates
+type ConfigurationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              ConfigurationPolicySpec `json:"spec"`
+}
+
+// ConfigurationPolicySpec is the spec of a ConfigurationPolicy
+type ConfigurationPolicySpec struct {
+	RemediationAction string           `json:"remediationAction"`
+	Severity          string           `json:"severity"`
+	ObjectTemplates   []ObjectTemplate `json:"object-templates"`
+}
+
+// ObjectTemplate is an object a ConfigurationPolicy checks. A musthave
+// template matches objects having at least its fields.
+type ObjectTemplate struct {
+	ComplianceType   string                 `json:"complianceType"`
+	ObjectDefinition map[string]interface{} `json:"objectDefinition"`
+}
+
+// PlacementBinding binds Policies to a Placement
+type PlacementBinding struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	PlacementRef      PlacementSubject   `json:"placementRef"`
+	Subjects          []PlacementSubject `json:"subjects"`
+}
+
+// PlacementSubject references a Placement or Policy
+type PlacementSubject struct {
+	APIGroup string `json:"apiGroup"`
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+}
+
+// ForWebhook returns the Policy of hook on profile, in namespace of the hub.
+// It checks that the webhook configuration of hook is on the cluster with
+// the rules and failurePolicy of hook, and that the objects hook protects
+// exist.
+func ForWebhook(hook webhooks.Webhook, profile utils.Profile, namespace string, mutating bool, failurePolicy admissionregv1.FailurePolicyType) Policy {
+	configurationName := "sre-" + hook.Name()
+	configurationKind := "ValidatingWebhookConfiguration"
+	if mutating {
+		configurationKind = "MutatingWebhookConfiguration"
+	}
+	rules := []interface{}{}
+	for _, rule := range webhooks.Rules(hook, profile) {
+		rules = append(rules, ruleObject(rule))
+	}
+	templates := []PolicyTemplate{
+		configurationPolicy(hook.Name()+"-configuration", severity(failurePolicy), map[string]interface{}{
+			"apiVersion": admissionregv1.SchemeGroupVersion.String(),
+			"kind":       configurationKind,
+			"metadata":   map[string]interface{}{"name": configurationName},
+			"webhooks": []interface{}{
+				map[string]interface{}{
+					"name":          hook.Name() + ".managed.openshift.io",
+					"failurePolicy": string(failurePolicy),
+					"rules":         rules,
+				},
+			},
+		}),
+	}
+	if protected := webhooks.ProtectedObjects(hook); len(protected) > 0 {
+		objects := make([]map[string]interface{}, 0, len(protected))
+		for _, obj := range protected {
+			objects = append(objects, obj.Object)
+		}
+		templates = append(templates, configurationPolicy(hook.Name()+"-protected-objects", severity(failurePolicy), objects...))
+	}
+	return Policy{
+		TypeMeta: metav1.TypeMeta{APIVersion: PolicyAPIVersion, Kind: "Policy"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configurationName,
+			Namespace: namespace,
+			Annotations: map[string]string{
+				StandardsAnnotation:   "NIST SP 800-53",
+				CategoriesAnnotation:  "CM Configuration Management",
+				ControlsAnnotation:    "CM-5 Access Restrictions for Change",
+				DescriptionAnnotation: hook.Doc(),
+			},
+		},
+		Spec: PolicySpec{
+			RemediationAction: RemediationInform,
+			PolicyTemplates:   templates,
+		},
+	}
+}
+
+// Binding returns the PlacementBinding of policies to placement
+func Binding(name, namespace, placement string, policies []Policy) PlacementBinding {
+	binding := PlacementBinding{
+		TypeMeta:     metav1.TypeMeta{APIVersion: PolicyAPIVersion, Kind: "PlacementBinding"},
+		ObjectMeta:   metav1.ObjectMeta{Name: PlacementBindingPrefix + name, Namespace: namespace},
+		PlacementRef: PlacementSubject{APIGroup: PlacementGroup, Kind: "Placement", Name: placement},
+		Subjects:     []PlacementSubject{},
+	}
+	for _, p := range policies {
+		binding.Subjects = append(binding.Subjects, PlacementSubject{APIGroup: Group, Kind: "Policy", Name: p.Name})
+	}
+	return binding
+}
+
+func configurationPolicy(name, severity string, objects ...map[string]interface{}) PolicyTemplate {
+	templates := make([]ObjectTemplate, 0, len(objects))
+	for _, obj := range objects {
+		templates = append(templates, ObjectTemplate{ComplianceType: ComplianceMustHave, ObjectDefinition: obj})
+	}
+	return PolicyTemplate{ObjectDefinition: ConfigurationPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: PolicyAPIVersion, Kind: "ConfigurationPolicy"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: ConfigurationPolicySpec{
+			RemediationAction: RemediationInform,
+			Severity:          severity,
+			ObjectTemplates:   templates,
+		},
+	}}
+}
+
+// severity is high for webhooks which deny requests when they are down,
+// which guard against the most harmful changes
+func severity(failurePolicy admissionregv1.FailurePolicyType) string {
+	if failurePolicy == admissionregv1.Fail {
+		return "high"
+	}
+	return "medium"
+}
+
+// ruleObject returns rule as the plain JSON of a template
+func ruleObject(rule admissionregv1.RuleWithOperations) map[string]interface{} {
+	obj := map[string]interface{}{
+		"apiGroups":   toList(rule.APIGroups),
+		"apiVersions": toList(rule.APIVersions),
+		"resources":   toList(rule.Resources),
+	}
+	operations := []interface{}{}
+	for _, op := range rule.Operations {
+		operations = append(operations, string(op))
+	}
+	obj["operations"] = operations
+	if rule.Scope != nil {
+		obj["scope"] = string(*rule.Scope)
+	}
+	return obj
+}
+
+func toList(values []string) []interface{} {
+	list := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		list = append(list, v)
+	}
+	return list
+}
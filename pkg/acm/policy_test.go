@@ -0,0 +1,71 @@
+package acm
+
+import (
+	"testing"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/namespace"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/scc"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+func TestForWebhook(t *testing.T) {
+	p := ForWebhook(scc.NewWebhook(), utils.ProfileAll, "policies", false, admissionregv1.Fail)
+	if p.Name != "sre-scc-validation" || p.Namespace != "policies" || p.Spec.RemediationAction != RemediationInform {
+		t.Fatalf("expected an inform Policy sre-scc-validation in policies, got %s/%s %s", p.Namespace, p.Name, p.Spec.RemediationAction)
+	}
+	if len(p.Spec.PolicyTemplates) != 2 {
+		t.Fatalf("expected a configuration and a protected objects template, got %d templates", len(p.Spec.PolicyTemplates))
+	}
+
+	configuration := p.Spec.PolicyTemplates[0].ObjectDefinition
+	if configuration.Spec.RemediationAction != RemediationInform || configuration.Spec.Severity != "high" {
+		t.Fatalf("expected an inform high severity ConfigurationPolicy, got %+v", configuration.Spec)
+	}
+	webhookConfiguration := configuration.Spec.ObjectTemplates[0].ObjectDefinition
+	if webhookConfiguration["kind"] != "ValidatingWebhookConfiguration" || webhookConfiguration["metadata"].(map[string]interface{})["name"] != "sre-scc-validation" {
+		t.Fatalf("expected the ValidatingWebhookConfiguration sre-scc-validation, got %v", webhookConfiguration)
+	}
+	webhook := webhookConfiguration["webhooks"].([]interface{})[0].(map[string]interface{})
+	rules := webhook["rules"].([]interface{})
+	if webhook["failurePolicy"] != "Fail" || len(rules) != 1 {
+		t.Fatalf("expected the failure policy and rule of the webhook, got %v", webhook)
+	}
+	if resources := rules[0].(map[string]interface{})["resources"].([]interface{}); resources[0] != "securitycontextconstraints" {
+		t.Fatalf("expected the rule to match securitycontextconstraints, got %v", resources)
+	}
+
+	protected := p.Spec.PolicyTemplates[1].ObjectDefinition.Spec.ObjectTemplates
+	found := false
+	for _, template := range protected {
+		if template.ComplianceType != ComplianceMustHave {
+			t.Fatalf("expected musthave templates of the protected objects, got %s", template.ComplianceType)
+		}
+		found = found || template.ObjectDefinition["kind"] == "SecurityContextConstraints" && template.ObjectDefinition["metadata"].(map[string]interface{})["name"] == "anyuid"
+	}
+	if !found {
+		t.Fatalf("expected the anyuid SCC to be a protected object, got %v", protected)
+	}
+}
+
+func TestForWebhookWithoutProtectedObjects(t *testing.T) {
+	p := ForWebhook(namespace.NewWebhook(), utils.ProfileAll, "policies", false, admissionregv1.Ignore)
+	if len(p.Spec.PolicyTemplates) != 1 {
+		t.Fatalf("expected only the configuration template, got %d templates", len(p.Spec.PolicyTemplates))
+	}
+	if severity := p.Spec.PolicyTemplates[0].ObjectDefinition.Spec.Severity; severity != "medium" {
+		t.Fatalf("expected a medium severity for a webhook ignoring failures, got %s", severity)
+	}
+}
+
+func TestBinding(t *testing.T) {
+	policies := []Policy{
+		ForWebhook(scc.NewWebhook(), utils.ProfileAll, "policies", false, admissionregv1.Fail),
+		ForWebhook(namespace.NewWebhook(), utils.ProfileAll, "policies", false, admissionregv1.Fail),
+	}
+	b := Binding("webhooks", "policies", "managed-clusters", policies)
+	if b.Name != "binding-webhooks" || b.PlacementRef.Name != "managed-clusters" || len(b.Subjects) != 2 || b.Subjects[1].Name != "sre-namespace-validation" {
+		t.Fatalf("expected a binding of both policies to managed-clusters, got %+v", b)
+	}
+}
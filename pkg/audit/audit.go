@@ -0,0 +1,288 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/events"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/k8sutil"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	// SinkEnvVar selects the audit sink, one of the Sink* constants. Denial
+	// records are not shipped when it is unset.
+	SinkEnvVar string = "AUDIT_SINK"
+	// SinkURLEnvVar is the endpoint for the splunk and http sinks
+	SinkURLEnvVar string = "AUDIT_SINK_URL"
+	// SinkTokenEnvVar is the Splunk HEC token, or the bearer token for the
+	// http sink
+	SinkTokenEnvVar string = "AUDIT_SINK_TOKEN"
+	// CloudWatchLogGroupEnvVar and CloudWatchLogStreamEnvVar name the
+	// existing log group and stream the cloudwatch sink writes to. AWS
+	// credentials and region are read from the standard AWS_* variables.
+	CloudWatchLogGroupEnvVar  string = "AUDIT_CLOUDWATCH_LOG_GROUP"
+	CloudWatchLogStreamEnvVar string = "AUDIT_CLOUDWATCH_LOG_STREAM"
+
+	SinkSplunk     string = "splunk"
+	SinkCloudWatch string = "cloudwatch"
+	SinkHTTP       string = "http"
+
+	defaultQueueSize     = 1000
+	defaultBatchSize     = 50
+	defaultFlushInterval = 5 * time.Second
+	defaultRetryBackoff  = time.Second
+	defaultMaxAttempts   = 3
+	sendTimeout          = 10 * time.Second
+)
+
+var log = logf.Log.WithName("audit")
+
+// Record is a structured record of a denied admission request, or of one a
+// webhook in audit mode would have denied
+type Record struct {
+	Timestamp     time.Time `json:"timestamp"`
+	ClusterID     string    `json:"clusterID,omitempty"`
+	Webhook       string    `json:"webhook"`
+	UID           string    `json:"uid"`
+	User          string    `json:"user"`
+	Groups        []string  `json:"groups,omitempty"`
+	Operation     string    `json:"operation"`
+	Group         string    `json:"group,omitempty"`
+	Version       string    `json:"version"`
+	Resource      string    `json:"resource"`
+	Kind          string    `json:"kind,omitempty"`
+	Namespace     string    `json:"namespace,omitempty"`
+	Name          string    `json:"name,omitempty"`
+	Code          string    `json:"code,omitempty"`
+	Reason        string    `json:"reason"`
+	CorrelationID string    `json:"correlationID,omitempty"`
+	// Audited is set for the requests a webhook in audit mode would have
+	// denied, which were allowed. AuditSource is what set it to audit mode.
+	Audited     bool   `json:"audited,omitempty"`
+	AuditSource string `json:"auditSource,omitempty"`
+	// Changes are the fields a denied UPDATE changes, and ChangesOmitted how
+	// many more it changes past maxChanges
+	Changes        []Change `json:"changes,omitempty"`
+	ChangesOmitted int      `json:"changesOmitted,omitempty"`
+}
+
+// Sink ships batches of Records to an external audit store
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, records []Record) error
+}
+
+// permanentError marks a Send failure which retrying won't fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+
+func (e permanentError) Unwrap() error { return e.err }
+
+// NewSinkFromEnv returns the Sink configured by the environment, or nil if
+// no sink is configured
+func NewSinkFromEnv() (Sink, error) {
+	var sink Sink
+	var err error
+	switch kind := os.Getenv(SinkEnvVar); kind {
+	case "":
+		return nil, nil
+	case SinkSplunk:
+		sink, err = newSplunkSink(os.Getenv(SinkURLEnvVar), os.Getenv(SinkTokenEnvVar))
+	case SinkHTTP:
+		sink, err = newHTTPSink(os.Getenv(SinkURLEnvVar), os.Getenv(SinkTokenEnvVar))
+	case SinkCloudWatch:
+		sink, err = newCloudWatchSinkFromEnv()
+	default:
+		err = fmt.Errorf("unknown audit sink %q in %s", kind, SinkEnvVar)
+	}
+	if err != nil {
+		// Don't return a typed nil Sink alongside the error
+		return nil, err
+	}
+	return sink, nil
+}
+
+// Pipeline asynchronously batches denial records and ships them to a Sink,
+// retrying failed batches. Records are dropped rather than blocking admission
+// when the queue is full.
+type Pipeline struct {
+	sink          Sink
+	records       chan Record
+	done          chan struct{}
+	batchSize     int
+	flushInterval time.Duration
+	retryBackoff  time.Duration
+	maxAttempts   int
+}
+
+// NewPipeline creates and starts a Pipeline shipping to sink
+func NewPipeline(sink Sink) *Pipeline {
+	p := newPipeline(sink, defaultBatchSize, defaultFlushInterval, defaultRetryBackoff)
+	go p.run()
+	return p
+}
+
+func newPipeline(sink Sink, batchSize int, flushInterval, retryBackoff time.Duration) *Pipeline {
+	return &Pipeline{
+		sink:          sink,
+		records:       make(chan Record, defaultQueueSize),
+		done:          make(chan struct{}),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		retryBackoff:  retryBackoff,
+		maxAttempts:   defaultMaxAttempts,
+	}
+}
+
+// RecordDenial implements events.Recorder
+func (p *Pipeline) RecordDenial(webhook string, request admissionctl.Request, resp admissionctl.Response) {
+	p.queue(NewRecord(webhook, request, resp))
+}
+
+// RecordDenials implements events.BatchRecorder
+func (p *Pipeline) RecordDenials(denials []events.Denial) {
+	for _, d := range denials {
+		p.queue(NewRecord(d.Webhook, d.Request, d.Response))
+	}
+}
+
+func (p *Pipeline) queue(record Record) {
+	select {
+	case p.records <- record:
+	default:
+		localmetrics.IncrementDroppedSideEffect(localmetrics.SideEffectAudit)
+		log.Info("Audit queue is full, dropping denial record", "sink", p.sink.Name(), "webhook", record.Webhook, "uid", record.UID)
+	}
+}
+
+// Close stops accepting records and waits for queued records to be shipped
+func (p *Pipeline) Close() {
+	close(p.records)
+	<-p.done
+}
+
+func (p *Pipeline) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Record, 0, p.batchSize)
+	for {
+		select {
+		case record, ok := <-p.records:
+			if !ok {
+				p.flush(batch)
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= p.batchSize {
+				p.flush(batch)
+				batch = make([]Record, 0, p.batchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				p.flush(batch)
+				batch = make([]Record, 0, p.batchSize)
+			}
+		}
+	}
+}
+
+// flush sends the batch, retrying with exponential backoff
+func (p *Pipeline) flush(batch []Record) {
+	if len(batch) == 0 {
+		return
+	}
+	backoff := p.retryBackoff
+	var err error
+	for attempt := 1; attempt <= p.maxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		err = p.sink.Send(ctx, batch)
+		cancel()
+		if err == nil {
+			return
+		}
+		if errors.As(err, &permanentError{}) {
+			break
+		}
+		if attempt < p.maxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	log.Error(err, "Failed to ship denial records", "sink", p.sink.Name(), "records", len(batch))
+}
+
+// NewRecord builds the Record for a denied request, or for one marked by
+// utils.WithAuditSource as audited. The Record of an UPDATE
+// carries the fields it changes, redacted like the logged requests.
+func NewRecord(webhook string, request admissionctl.Request, resp admissionctl.Response) Record {
+	code, reason := utils.DenialReason(resp)
+	record := Record{
+		Timestamp:     time.Now().UTC(),
+		ClusterID:     k8sutil.ClusterID(),
+		Webhook:       webhook,
+		UID:           string(request.UID),
+		User:          request.UserInfo.Username,
+		Groups:        request.UserInfo.Groups,
+		Operation:     string(request.Operation),
+		Group:         request.Resource.Group,
+		Version:       request.Resource.Version,
+		Resource:      request.Resource.Resource,
+		Kind:          request.Kind.Kind,
+		Namespace:     request.Namespace,
+		Name:          request.Name,
+		Code:          string(code),
+		Reason:        reason,
+		CorrelationID: utils.CorrelationID(resp),
+	}
+	if source := utils.AuditSource(resp); source != "" {
+		record.Audited = true
+		record.AuditSource = source
+	}
+	if request.Operation == admissionv1.Update {
+		record.Changes, record.ChangesOmitted = diffObjects(request.OldObject.Raw, request.Object.Raw)
+	}
+	return record
+}
+
+// LogRecorder writes the Record of every denial to the pod log as a line of
+// JSON, so that the records are kept whether or not a Sink is configured
+type LogRecorder struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewLogRecorder creates a LogRecorder writing to the standard output
+func NewLogRecorder() *LogRecorder {
+	return &LogRecorder{out: os.Stdout}
+}
+
+// RecordDenial implements events.Recorder
+func (l *LogRecorder) RecordDenial(webhook string, request admissionctl.Request, resp admissionctl.Response) {
+	data, err := json.Marshal(NewRecord(webhook, request, resp))
+	if err != nil {
+		log.Error(err, "Failed to encode the denial record", "webhook", webhook, "uid", request.UID)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.out.Write(append(data, '\n')); err != nil {
+		log.Error(err, "Failed to log the denial record", "webhook", webhook, "uid", request.UID)
+	}
+}
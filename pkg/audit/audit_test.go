@@ -0,0 +1,421 @@
+package audit
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/k8sutil"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+// fakeSink records the batches it is sent, failing the first `failures` sends
+type fakeSink struct {
+	mu       sync.Mutex
+	batches  [][]Record
+	attempts int
+	failures int
+	err      error
+}
+
+func (s *fakeSink) Name() string { return "fake" }
+
+func (s *fakeSink) Send(_ context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if s.failures > 0 {
+		s.failures--
+		return s.err
+	}
+	s.batches = append(s.batches, records)
+	return nil
+}
+
+func newRequest(uid string) admissionctl.Request {
+	return admissionctl.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			UID:       types.UID(uid),
+			Operation: admissionv1.Create,
+			Resource:  metav1.GroupVersionResource{Group: "security.openshift.io", Version: "v1", Resource: "securitycontextconstraints"},
+			Name:      "restricted",
+			UserInfo:  authenticationv1.UserInfo{Username: "my_user", Groups: []string{"system:authenticated"}},
+		},
+	}
+}
+
+func TestPipelineBatches(t *testing.T) {
+	sink := &fakeSink{}
+	p := newPipeline(sink, 2, time.Hour, time.Millisecond)
+	go p.run()
+	for i := 0; i < 5; i++ {
+		p.RecordDenial("scc-validation", newRequest(fmt.Sprintf("uid-%d", i)), admissionctl.Denied("Not allowed"))
+	}
+	p.Close()
+
+	if len(sink.batches) != 3 {
+		t.Fatalf("Expected 5 records in 3 batches, got %d batches", len(sink.batches))
+	}
+	if len(sink.batches[0]) != 2 || len(sink.batches[2]) != 1 {
+		t.Fatalf("Expected batches of 2, 2 and 1 records, got %v", sink.batches)
+	}
+	record := sink.batches[0][0]
+	if record.Webhook != "scc-validation" || record.UID != "uid-0" || record.User != "my_user" || record.Reason != "Not allowed" || record.Code != "" {
+		t.Fatalf("Unexpected record %+v", record)
+	}
+}
+
+func TestPipelineFlushesOnInterval(t *testing.T) {
+	sink := &fakeSink{}
+	p := newPipeline(sink, 100, 10*time.Millisecond, time.Millisecond)
+	go p.run()
+	p.RecordDenial("scc-validation", newRequest("uid-0"), admissionctl.Denied("Not allowed"))
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		sink.mu.Lock()
+		sent := len(sink.batches)
+		sink.mu.Unlock()
+		if sent == 1 {
+			p.Close()
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Expected the partial batch to be flushed on the interval")
+}
+
+func TestPipelineRetries(t *testing.T) {
+	tests := []struct {
+		testID           string
+		failures         int
+		err              error
+		expectedAttempts int
+		expectedBatches  int
+	}{
+		{
+			testID:           "transient-failure-retried",
+			failures:         2,
+			err:              fmt.Errorf("connection reset"),
+			expectedAttempts: 3,
+			expectedBatches:  1,
+		},
+		{
+			testID:           "persistent-failure-gives-up",
+			failures:         5,
+			err:              fmt.Errorf("connection reset"),
+			expectedAttempts: defaultMaxAttempts,
+			expectedBatches:  0,
+		},
+		{
+			testID:           "permanent-failure-not-retried",
+			failures:         5,
+			err:              permanentError{fmt.Errorf("401 unauthorized")},
+			expectedAttempts: 1,
+			expectedBatches:  0,
+		},
+	}
+	for _, test := range tests {
+		sink := &fakeSink{failures: test.failures, err: test.err}
+		p := newPipeline(sink, 1, time.Hour, time.Millisecond)
+		p.flush([]Record{NewRecord("scc-validation", newRequest("uid-0"), admissionctl.Denied("Not allowed"))})
+		if sink.attempts != test.expectedAttempts || len(sink.batches) != test.expectedBatches {
+			t.Fatalf("%s: Expected %d attempts and %d batches, got %d attempts and %d batches", test.testID, test.expectedAttempts, test.expectedBatches, sink.attempts, len(sink.batches))
+		}
+	}
+}
+
+func TestPipelineDropsWhenFull(t *testing.T) {
+	sink := &fakeSink{}
+	// Not started, so nothing drains the queue
+	p := newPipeline(sink, 1, time.Hour, time.Millisecond)
+	for i := 0; i < defaultQueueSize+10; i++ {
+		p.RecordDenial("scc-validation", newRequest("uid"), admissionctl.Denied("Not allowed"))
+	}
+	if len(p.records) != defaultQueueSize {
+		t.Fatalf("Expected the queue to hold %d records, got %d", defaultQueueSize, len(p.records))
+	}
+}
+
+func TestHTTPSinks(t *testing.T) {
+	var gotAuth, gotBody string
+	var status int
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	records := []Record{NewRecord("scc-validation", newRequest("uid-0"), admissionctl.Denied("Not allowed"))}
+
+	httpSink, err := newHTTPSink(server.URL, "secret")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	httpSink.httpClient = server.Client()
+	status = http.StatusOK
+	if err := httpSink.Send(context.Background(), records); err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	if gotAuth != "Bearer secret" {
+		t.Fatalf("Expected a bearer token, got %q", gotAuth)
+	}
+	var sent []Record
+	if err := json.Unmarshal([]byte(gotBody), &sent); err != nil || len(sent) != 1 {
+		t.Fatalf("Expected a JSON array of 1 record, got %q", gotBody)
+	}
+
+	splunk, err := newSplunkSink(server.URL, "hec-token")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	splunk.httpClient = server.Client()
+	if !strings.HasSuffix(splunk.endpoint, "/services/collector/event") {
+		t.Fatalf("Expected the HEC event endpoint, got %s", splunk.endpoint)
+	}
+	if err := splunk.Send(context.Background(), records); err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	if gotAuth != "Splunk hec-token" || !strings.Contains(gotBody, `"sourcetype":"_json"`) {
+		t.Fatalf("Unexpected HEC request: auth %q body %q", gotAuth, gotBody)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := splunk.Send(context.Background(), records); err == nil || isPermanent(err) {
+		t.Fatalf("Expected a retryable error for 503, got %v", err)
+	}
+	status = http.StatusForbidden
+	if err := splunk.Send(context.Background(), records); err == nil || !isPermanent(err) {
+		t.Fatalf("Expected a permanent error for 403, got %v", err)
+	}
+}
+
+func isPermanent(err error) bool {
+	_, ok := err.(permanentError)
+	return ok
+}
+
+func TestNewSinkFromEnv(t *testing.T) {
+	tests := []struct {
+		testID      string
+		env         map[string]string
+		expectSink  string
+		expectError bool
+	}{
+		{
+			testID:     "disabled",
+			env:        map[string]string{},
+			expectSink: "",
+		},
+		{
+			testID:     "splunk",
+			env:        map[string]string{SinkEnvVar: SinkSplunk, SinkURLEnvVar: "https://splunk.example.com:8088", SinkTokenEnvVar: "token"},
+			expectSink: SinkSplunk,
+		},
+		{
+			testID:      "http-requires-https",
+			env:         map[string]string{SinkEnvVar: SinkHTTP, SinkURLEnvVar: "http://audit.example.com"},
+			expectError: true,
+		},
+		{
+			testID: "cloudwatch",
+			env: map[string]string{
+				SinkEnvVar:                SinkCloudWatch,
+				"AWS_REGION":              "us-east-1",
+				"AWS_ACCESS_KEY_ID":       "AKIDEXAMPLE",
+				"AWS_SECRET_ACCESS_KEY":   "secret",
+				CloudWatchLogGroupEnvVar:  "webhook-audit",
+				CloudWatchLogStreamEnvVar: "cluster-1",
+			},
+			expectSink: SinkCloudWatch,
+		},
+		{
+			testID: "cloudwatch-rejects-sts",
+			env: map[string]string{
+				SinkEnvVar:                    SinkCloudWatch,
+				"AWS_REGION":                  "us-east-1",
+				"AWS_ROLE_ARN":                "arn:aws:iam::123456789012:role/webhook-audit",
+				"AWS_WEB_IDENTITY_TOKEN_FILE": "/var/run/secrets/openshift/serviceaccount/token",
+				CloudWatchLogGroupEnvVar:      "webhook-audit",
+				CloudWatchLogStreamEnvVar:     "cluster-1",
+			},
+			expectError: true,
+		},
+		{
+			testID:      "cloudwatch-requires-log-group",
+			env:         map[string]string{SinkEnvVar: SinkCloudWatch, "AWS_REGION": "us-east-1", "AWS_ACCESS_KEY_ID": "AKIDEXAMPLE", "AWS_SECRET_ACCESS_KEY": "secret"},
+			expectError: true,
+		},
+		{
+			testID:      "unknown",
+			env:         map[string]string{SinkEnvVar: "syslog"},
+			expectError: true,
+		},
+	}
+	for _, test := range tests {
+		for _, key := range []string{SinkEnvVar, SinkURLEnvVar, SinkTokenEnvVar, CloudWatchLogGroupEnvVar, CloudWatchLogStreamEnvVar, "AWS_REGION", "AWS_DEFAULT_REGION", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_ROLE_ARN", "AWS_WEB_IDENTITY_TOKEN_FILE"} {
+			t.Setenv(key, test.env[key])
+		}
+		sink, err := NewSinkFromEnv()
+		if (err != nil) != test.expectError {
+			t.Fatalf("%s: Expected error %t, got %v", test.testID, test.expectError, err)
+		}
+		name := ""
+		if sink != nil {
+			name = sink.Name()
+		}
+		if name != test.expectSink {
+			t.Fatalf("%s: Expected sink %q, got %q", test.testID, test.expectSink, name)
+		}
+	}
+}
+
+func TestDeriveSigningKey(t *testing.T) {
+	// Example from the AWS Signature Version 4 documentation
+	key := deriveSigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20150830", "us-east-1", "iam")
+	expected := "c4afb1cc5771d871763a393e44b703571b55cc28424d1a5e86da6ed3c154a4b9"
+	if got := hex.EncodeToString(key); got != expected {
+		t.Fatalf("Expected signing key %s, got %s", expected, got)
+	}
+}
+
+func TestSignRequest(t *testing.T) {
+	body := []byte(`{}`)
+	req, _ := http.NewRequest(http.MethodPost, "https://logs.us-east-1.amazonaws.com/", strings.NewReader(string(body)))
+	req.Header.Set("X-Amz-Target", "Logs_20140328.PutLogEvents")
+	signRequest(req, body, awsCredentials{accessKeyID: "AKIDEXAMPLE", secretAccessKey: "secret", sessionToken: "session"}, "us-east-1", "logs", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	if req.Header.Get("X-Amz-Date") != "20240102T030405Z" {
+		t.Fatalf("Expected X-Amz-Date to be set, got %q", req.Header.Get("X-Amz-Date"))
+	}
+	if req.Header.Get("X-Amz-Security-Token") != "session" {
+		t.Fatalf("Expected the session token to be sent")
+	}
+	auth := req.Header.Get("Authorization")
+	for _, want := range []string{
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240102/us-east-1/logs/aws4_request",
+		"SignedHeaders=host;x-amz-date;x-amz-security-token;x-amz-target",
+		"Signature=",
+	} {
+		if !strings.Contains(auth, want) {
+			t.Fatalf("Expected Authorization to contain %q, got %q", want, auth)
+		}
+	}
+}
+
+func TestRecordCarriesClusterID(t *testing.T) {
+	t.Setenv(k8sutil.ClusterIDEnvVar, "2c1d9a7e-5f0b-4a53-9c43-3d1bba1e0d6f")
+	if _, err := k8sutil.LoadClusterID(context.Background(), nil); err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	record := NewRecord("scc-validation", newRequest("uid-0"), admissionctl.Denied("Not allowed"))
+	if record.ClusterID != "2c1d9a7e-5f0b-4a53-9c43-3d1bba1e0d6f" {
+		t.Fatalf("Expected the record to carry the cluster ID, got %+v", record)
+	}
+}
+
+func TestRecordCarriesCorrelationID(t *testing.T) {
+	resp := utils.WithCorrelationID(utils.Denied(utils.ReasonSCCDefaultModify, "Not allowed"), "1a2b3c4d")
+	record := NewRecord("scc-validation", newRequest("uid-0"), resp)
+	if record.CorrelationID != "1a2b3c4d" || record.Reason != "Not allowed (support correlation ID: 1a2b3c4d)" {
+		t.Fatalf("Expected the record to carry the correlation ID, got %+v", record)
+	}
+}
+
+func TestRecordCarriesAuditSource(t *testing.T) {
+	record := NewRecord("scc-validation", newRequest("uid-0"), utils.Denied(utils.ReasonSCCDefaultModify, "Not allowed"))
+	if record.Audited || record.AuditSource != "" {
+		t.Fatalf("Expected a denial not to be audited, got %+v", record)
+	}
+	resp := utils.WithAuditSource(utils.Denied(utils.ReasonSCCDefaultModify, "Not allowed"), "enforcement-mode")
+	record = NewRecord("scc-validation", newRequest("uid-0"), resp)
+	if !record.Audited || record.AuditSource != "enforcement-mode" {
+		t.Fatalf("Expected the record to be audited by the enforcement mode, got %+v", record)
+	}
+}
+
+func TestRecordCarriesChanges(t *testing.T) {
+	request := newRequest("uid-0")
+	request.Operation = admissionv1.Update
+	request.Kind = metav1.GroupVersionKind{Group: "security.openshift.io", Version: "v1", Kind: "SecurityContextConstraints"}
+	request.OldObject.Raw = []byte(`{"kind":"SecurityContextConstraints","metadata":{"name":"restricted","resourceVersion":"1","managedFields":[{"manager":"oc"}]},"allowPrivilegedContainer":false,"users":["system:admin"],"priority":1}`)
+	request.Object.Raw = []byte(`{"kind":"SecurityContextConstraints","metadata":{"name":"restricted","resourceVersion":"2","managedFields":[{"manager":"kubectl"}]},"allowPrivilegedContainer":true,"users":["system:admin","my_user"],"allowHostPID":true}`)
+	record := NewRecord("scc-validation", request, admissionctl.Denied("Not allowed"))
+
+	expected := `[{"path":"allowHostPID","new":true},{"path":"allowPrivilegedContainer","old":false,"new":true},{"path":"priority","old":1},{"path":"users","old":["system:admin"],"new":["system:admin","my_user"]}]`
+	if changes, _ := json.Marshal(record.Changes); string(changes) != expected || record.ChangesOmitted != 0 {
+		t.Fatalf("Expected changes %s, got %s and %d omitted", expected, changes, record.ChangesOmitted)
+	}
+	if record.Kind != "SecurityContextConstraints" {
+		t.Fatalf("Expected the record to carry the kind, got %+v", record)
+	}
+
+	request.Operation = admissionv1.Create
+	if record := NewRecord("scc-validation", request, admissionctl.Denied("Not allowed")); record.Changes != nil {
+		t.Fatalf("Expected no changes for a CREATE, got %+v", record.Changes)
+	}
+}
+
+func TestRecordRedactsChanges(t *testing.T) {
+	request := newRequest("uid-0")
+	request.Operation = admissionv1.Update
+	request.OldObject.Raw = []byte(`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"pull-secret"},"data":{".dockerconfigjson":"b2xk"}}`)
+	request.Object.Raw = []byte(`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"pull-secret","labels":{"app":"x"}},"data":{".dockerconfigjson":"bmV3"}}`)
+	record := NewRecord("secret-validation", request, admissionctl.Denied("Not allowed"))
+	if changes, _ := json.Marshal(record.Changes); strings.Contains(string(changes), "bmV3") || strings.Contains(string(changes), "b2xk") || !strings.Contains(string(changes), "metadata.labels.app") {
+		t.Fatalf("Expected the label change without the secret data, got %s", changes)
+	}
+}
+
+func TestRecordTruncatesChanges(t *testing.T) {
+	old, new := map[string]string{}, map[string]string{}
+	for i := 0; i < maxChanges+5; i++ {
+		new[fmt.Sprintf("key%03d", i)] = strings.Repeat("x", 2*maxChangeValue)
+	}
+	request := newRequest("uid-0")
+	request.Operation = admissionv1.Update
+	request.OldObject.Raw, _ = json.Marshal(map[string]interface{}{"kind": "ConfigMap", "data": old})
+	request.Object.Raw, _ = json.Marshal(map[string]interface{}{"kind": "ConfigMap", "data": new})
+	record := NewRecord("configmap-validation", request, admissionctl.Denied("Not allowed"))
+	if len(record.Changes) != maxChanges || record.ChangesOmitted != 6 {
+		t.Fatalf("Expected %d changes and 6 omitted, got %d and %d", maxChanges, len(record.Changes), record.ChangesOmitted)
+	}
+	if len(record.Changes[1].New) > maxChangeValue+8 || !json.Valid(record.Changes[1].New) {
+		t.Fatalf("Expected values to be truncated to valid JSON, got %s", record.Changes[1].New)
+	}
+}
+
+func TestLogRecorder(t *testing.T) {
+	out := &strings.Builder{}
+	recorder := &LogRecorder{out: out}
+	recorder.RecordDenial("scc-validation", newRequest("uid-0"), utils.Denied(utils.ReasonSCCDefaultModify, "Not allowed"))
+	recorder.RecordDenial("scc-validation", newRequest("uid-1"), utils.Denied(utils.ReasonSCCDefaultModify, "Not allowed"))
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a line for each denial, got %q", out.String())
+	}
+	record := Record{}
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %q: %s", lines[1], err.Error())
+	}
+	if record.UID != "uid-1" || record.Code != string(utils.ReasonSCCDefaultModify) || len(record.Groups) != 1 {
+		t.Fatalf("Expected the record of the denial, got %+v", record)
+	}
+}
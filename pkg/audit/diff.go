@@ -0,0 +1,109 @@
+package audit
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/manifestdiff"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	// maxChanges is the most changes a Record carries. Sinks cap the size of
+	// their events, and an update replacing a whole object says little more
+	// with every field of it.
+	maxChanges = 100
+	// maxChangeValue is the longest encoded old or new value of a Change,
+	// after which the value is truncated
+	maxChangeValue = 256
+)
+
+// ignoredChanges are the fields the API server sets on every update, which
+// say nothing about what the user tried to change
+var ignoredChanges = []string{
+	"metadata.managedFields",
+	"metadata.resourceVersion",
+	"metadata.generation",
+}
+
+// Change is a field a denied UPDATE changes, e.g.
+// spec.containers[app].securityContext.privileged. Old and New are the JSON
+// encoded values, Old unset for an added field and New for a removed one.
+type Change struct {
+	Path string          `json:"path"`
+	Old  json.RawMessage `json:"old,omitempty"`
+	New  json.RawMessage `json:"new,omitempty"`
+}
+
+// diffObjects returns the changes from the JSON encoded object old to new,
+// sorted by path, with credential material redacted by utils.RedactObject,
+// and how many changes past maxChanges were left out. There are no changes if
+// either object can't be decoded.
+func diffObjects(old, new []byte) ([]Change, int) {
+	oldFields, ok := redactedFields(old)
+	if !ok {
+		return nil, 0
+	}
+	newFields, ok := redactedFields(new)
+	if !ok {
+		return nil, 0
+	}
+	paths := []string{}
+	for path, value := range oldFields {
+		if newValue, ok := newFields[path]; !ok || newValue != value {
+			paths = append(paths, path)
+		}
+	}
+	for path := range newFields {
+		if _, ok := oldFields[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	omitted := 0
+	if len(paths) > maxChanges {
+		omitted = len(paths) - maxChanges
+		paths = paths[:maxChanges]
+	}
+	changes := make([]Change, 0, len(paths))
+	for _, path := range paths {
+		changes = append(changes, Change{
+			Path: path,
+			Old:  changeValue(oldFields[path]),
+			New:  changeValue(newFields[path]),
+		})
+	}
+	return changes, omitted
+}
+
+// redactedFields returns the fields of the JSON encoded object raw, redacted,
+// without the ignoredChanges
+func redactedFields(raw []byte) (map[string]string, bool) {
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(utils.RedactObject(raw), &obj); err != nil {
+		return nil, false
+	}
+	fields := manifestdiff.Fields(obj)
+	for path := range fields {
+		for _, ignored := range ignoredChanges {
+			if path == ignored || strings.HasPrefix(path, ignored+".") || strings.HasPrefix(path, ignored+"[") {
+				delete(fields, path)
+			}
+		}
+	}
+	return fields, true
+}
+
+// changeValue returns the encoded value of a Change, truncated to a JSON
+// string of maxChangeValue bytes if it is longer
+func changeValue(value string) json.RawMessage {
+	if value == "" {
+		return nil
+	}
+	if len(value) > maxChangeValue {
+		truncated, _ := json.Marshal(value[:maxChangeValue] + "...")
+		return truncated
+	}
+	return json.RawMessage(value)
+}
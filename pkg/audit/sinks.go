@@ -0,0 +1,254 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// postJSON POSTs body to endpoint with the given headers. 5xx and 429
+// responses are retryable, other failures are permanent.
+func postJSON(ctx context.Context, httpClient *http.Client, endpoint string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("%s returned %d: %s", endpoint, resp.StatusCode, strings.TrimSpace(string(msg)))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return err
+	}
+	return permanentError{err}
+}
+
+// validateURL requires an absolute https URL, since records include user
+// identities
+func validateURL(endpoint string) error {
+	if endpoint == "" {
+		return fmt.Errorf("%s must be set", SinkURLEnvVar)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %v", SinkURLEnvVar, err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%s must be an https URL, got %q", SinkURLEnvVar, endpoint)
+	}
+	return nil
+}
+
+// httpSink POSTs each batch as a JSON array to a generic HTTPS endpoint
+type httpSink struct {
+	endpoint   string
+	token      string
+	httpClient *http.Client
+}
+
+func newHTTPSink(endpoint, token string) (*httpSink, error) {
+	if err := validateURL(endpoint); err != nil {
+		return nil, err
+	}
+	return &httpSink{
+		endpoint:   endpoint,
+		token:      token,
+		httpClient: &http.Client{},
+	}, nil
+}
+
+func (s *httpSink) Name() string { return SinkHTTP }
+
+func (s *httpSink) Send(ctx context.Context, records []Record) error {
+	body, err := json.Marshal(records)
+	if err != nil {
+		return permanentError{err}
+	}
+	headers := map[string]string{}
+	if s.token != "" {
+		headers["Authorization"] = "Bearer " + s.token
+	}
+	return postJSON(ctx, s.httpClient, s.endpoint, body, headers)
+}
+
+// splunkSink ships each batch to a Splunk HTTP Event Collector
+type splunkSink struct {
+	endpoint   string
+	token      string
+	httpClient *http.Client
+}
+
+// splunkEvent is the HEC event envelope
+type splunkEvent struct {
+	Time       float64 `json:"time"`
+	Source     string  `json:"source"`
+	SourceType string  `json:"sourcetype"`
+	Event      Record  `json:"event"`
+}
+
+func newSplunkSink(endpoint, token string) (*splunkSink, error) {
+	if err := validateURL(endpoint); err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, fmt.Errorf("%s must be set to the Splunk HEC token", SinkTokenEnvVar)
+	}
+	// Accept either the HEC base URL or the full event endpoint
+	if !strings.HasSuffix(endpoint, "/services/collector/event") {
+		endpoint = strings.TrimSuffix(endpoint, "/") + "/services/collector/event"
+	}
+	return &splunkSink{
+		endpoint:   endpoint,
+		token:      token,
+		httpClient: &http.Client{},
+	}, nil
+}
+
+func (s *splunkSink) Name() string { return SinkSplunk }
+
+func (s *splunkSink) Send(ctx context.Context, records []Record) error {
+	// HEC takes a batch as concatenated event objects
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, record := range records {
+		event := splunkEvent{
+			Time:       float64(record.Timestamp.UnixNano()) / float64(time.Second),
+			Source:     "managed-cluster-validating-webhooks",
+			SourceType: "_json",
+			Event:      record,
+		}
+		if err := encoder.Encode(event); err != nil {
+			return permanentError{err}
+		}
+	}
+	return postJSON(ctx, s.httpClient, s.endpoint, body.Bytes(), map[string]string{
+		"Authorization": "Splunk " + s.token,
+	})
+}
+
+// cloudWatchSink ships each batch to a CloudWatch Logs stream with PutLogEvents.
+// It signs its requests with static credentials, without the AWS SDK, so it
+// neither assumes roles nor refreshes credentials: STS clusters, whose pods
+// get web identity tokens rather than keys, are rejected.
+type cloudWatchSink struct {
+	endpoint    string
+	region      string
+	logGroup    string
+	logStream   string
+	credentials awsCredentials
+	httpClient  *http.Client
+	now         func() time.Time
+}
+
+type cloudWatchLogEvent struct {
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+type putLogEventsInput struct {
+	LogGroupName  string               `json:"logGroupName"`
+	LogStreamName string               `json:"logStreamName"`
+	LogEvents     []cloudWatchLogEvent `json:"logEvents"`
+}
+
+func newCloudWatchSinkFromEnv() (*cloudWatchSink, error) {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	credentials := awsCredentials{
+		accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	logGroup := os.Getenv(CloudWatchLogGroupEnvVar)
+	logStream := os.Getenv(CloudWatchLogStreamEnvVar)
+
+	switch {
+	case os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE") != "" || os.Getenv("AWS_ROLE_ARN") != "":
+		return nil, fmt.Errorf("the cloudwatch audit sink only supports static credentials, not the STS web identity of AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE")
+	case region == "":
+		return nil, fmt.Errorf("AWS_REGION must be set for the cloudwatch audit sink")
+	case credentials.accessKeyID == "" || credentials.secretAccessKey == "":
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set for the cloudwatch audit sink")
+	case logGroup == "" || logStream == "":
+		return nil, fmt.Errorf("%s and %s must be set for the cloudwatch audit sink", CloudWatchLogGroupEnvVar, CloudWatchLogStreamEnvVar)
+	}
+
+	return &cloudWatchSink{
+		endpoint:    fmt.Sprintf("https://logs.%s.amazonaws.com/", region),
+		region:      region,
+		logGroup:    logGroup,
+		logStream:   logStream,
+		credentials: credentials,
+		httpClient:  &http.Client{},
+		now:         time.Now,
+	}, nil
+}
+
+func (s *cloudWatchSink) Name() string { return SinkCloudWatch }
+
+func (s *cloudWatchSink) Send(ctx context.Context, records []Record) error {
+	input := putLogEventsInput{
+		LogGroupName:  s.logGroup,
+		LogStreamName: s.logStream,
+		LogEvents:     make([]cloudWatchLogEvent, 0, len(records)),
+	}
+	// PutLogEvents needs the events in chronological order, which is the
+	// order they were queued in
+	for _, record := range records {
+		message, err := json.Marshal(record)
+		if err != nil {
+			return permanentError{err}
+		}
+		input.LogEvents = append(input.LogEvents, cloudWatchLogEvent{
+			Timestamp: record.Timestamp.UnixMilli(),
+			Message:   string(message),
+		})
+	}
+	body, err := json.Marshal(input)
+	if err != nil {
+		return permanentError{err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "Logs_20140328.PutLogEvents")
+	signRequest(req, body, s.credentials, s.region, "logs", s.now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("PutLogEvents returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	// CloudWatch reports throttling as a 400
+	if resp.StatusCode >= 500 || strings.Contains(string(msg), "ThrottlingException") {
+		return err
+	}
+	return permanentError{err}
+}
@@ -0,0 +1,258 @@
+// Package capture records sanitized AdmissionReviews the webhooks receive to
+// files, so request shapes seen on real clusters can be turned into
+// regression test fixtures
+package capture
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	// DirEnvVar is the directory captured requests are written to. Capture
+	// is disabled when it is unset.
+	DirEnvVar string = "CAPTURE_DIR"
+	// FilterEnvVar selects the captured requests, e.g.
+	// webhook=scc-validation,decision=denied. Requests matching any value of
+	// every key given are captured, every request when it is unset.
+	FilterEnvVar string = "CAPTURE_FILTER"
+	// LimitEnvVar is how many requests are captured before capture stops,
+	// bounding the disk used
+	LimitEnvVar string = "CAPTURE_LIMIT"
+
+	defaultLimit = 100
+	// The decisions of the decision filter
+	DecisionAllowed string = "allowed"
+	DecisionDenied  string = "denied"
+
+	// hashedUserPrefix prefixes hashed usernames
+	hashedUserPrefix = "user-"
+)
+
+var log = logf.Log.WithName("capture")
+
+// filterKeys are the keys of a Filter
+var filterKeys = []string{"webhook", "kind", "operation", "namespace", "decision"}
+
+// Fixture is a captured request, and the decision the webhook made on it
+type Fixture struct {
+	Webhook  string    `json:"webhook"`
+	Captured time.Time `json:"captured"`
+	// Review holds the sanitized request
+	Review   admissionv1.AdmissionReview `json:"review"`
+	Allowed  bool                        `json:"allowed"`
+	Code     utils.ReasonCode            `json:"code,omitempty"`
+	Patched  bool                        `json:"patched,omitempty"`
+	Warnings []string                    `json:"warnings,omitempty"`
+}
+
+// Filter selects requests by key, each matching any of its values
+type Filter map[string][]string
+
+// ParseFilter parses a comma-separated list of key=value filters
+func ParseFilter(value string) (Filter, error) {
+	filter := Filter{}
+	for _, term := range strings.Split(value, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		key, v, ok := strings.Cut(term, "=")
+		if !ok || v == "" || !slices.Contains(filterKeys, key) {
+			return nil, fmt.Errorf("invalid capture filter %q, it must be one of %v with a value", term, filterKeys)
+		}
+		if key == "decision" && v != DecisionAllowed && v != DecisionDenied {
+			return nil, fmt.Errorf("invalid capture decision %q, it must be %s or %s", v, DecisionAllowed, DecisionDenied)
+		}
+		filter[key] = append(filter[key], v)
+	}
+	return filter, nil
+}
+
+// Match returns whether the request to webhook, on which it decided resp,
+// matches f
+func (f Filter) Match(webhook string, request admissionctl.Request, resp admissionctl.Response) bool {
+	decision := DecisionAllowed
+	if localmetrics.IsDenied(resp) {
+		decision = DecisionDenied
+	}
+	fields := map[string]string{
+		"webhook":   webhook,
+		"kind":      request.Kind.Kind,
+		"operation": string(request.Operation),
+		"namespace": request.Namespace,
+		"decision":  decision,
+	}
+	for key, values := range f {
+		if !slices.Contains(values, fields[key]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Capturer writes the requests matching its filter to a directory, until it
+// reaches its limit
+type Capturer struct {
+	dir    string
+	filter Filter
+	limit  int
+
+	mu       sync.Mutex
+	captured int
+	now      func() time.Time
+}
+
+// NewCapturerFromEnv returns the Capturer configured by DirEnvVar,
+// FilterEnvVar and LimitEnvVar, or nil if capture is disabled
+func NewCapturerFromEnv() (*Capturer, error) {
+	dir := os.Getenv(DirEnvVar)
+	if dir == "" {
+		return nil, nil
+	}
+	filter, err := ParseFilter(os.Getenv(FilterEnvVar))
+	if err != nil {
+		return nil, err
+	}
+	limit := defaultLimit
+	if value := os.Getenv(LimitEnvVar); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid %s %q, it must be a positive number", LimitEnvVar, value)
+		}
+	}
+	return NewCapturer(dir, filter, limit)
+}
+
+// NewCapturer returns a Capturer writing up to limit requests matching
+// filter to dir
+func NewCapturer(dir string, filter Filter, limit int) (*Capturer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &Capturer{dir: dir, filter: filter, limit: limit, now: time.Now}, nil
+}
+
+// Capture writes the sanitized request to webhook, on which it decided resp,
+// if it matches the filter. Capture never fails the request, errors are
+// logged. A nil Capturer captures nothing.
+func (c *Capturer) Capture(webhook string, request admissionctl.Request, resp admissionctl.Response) {
+	if c == nil || !c.filter.Match(webhook, request, resp) {
+		return
+	}
+	c.mu.Lock()
+	if c.captured >= c.limit {
+		c.mu.Unlock()
+		return
+	}
+	c.captured++
+	n := c.captured
+	c.mu.Unlock()
+	if n == c.limit {
+		log.Info("Reached the capture limit, no more requests will be captured", "limit", c.limit)
+	}
+
+	code, _ := utils.DenialReason(resp)
+	sanitized := Sanitize(request.AdmissionRequest)
+	fixture := Fixture{
+		Webhook:  webhook,
+		Captured: c.now().UTC(),
+		Review: admissionv1.AdmissionReview{
+			TypeMeta: metav1.TypeMeta{APIVersion: admissionv1.SchemeGroupVersion.String(), Kind: "AdmissionReview"},
+			Request:  &sanitized,
+		},
+		Allowed:  resp.Allowed,
+		Code:     code,
+		Patched:  len(resp.Patches) > 0,
+		Warnings: resp.Warnings,
+	}
+	if err := c.write(fixture); err != nil {
+		log.Error(err, "Failed to capture request", "webhook", webhook, "uid", request.UID)
+	}
+}
+
+// write writes fixture to a file of its own, renaming it into place so
+// readers never see a partial fixture
+func (c *Capturer) write(fixture Fixture) error {
+	b, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%d-%s.json", fixture.Webhook, fixture.Captured.UnixNano(), fixture.Review.Request.UID)
+	tmp, err := os.CreateTemp(c.dir, ".capture-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(c.dir, name))
+}
+
+// Sanitize returns a copy of request safe to keep as a fixture. Besides
+// the credential material utils.RedactRequest redacts, usernames are hashed
+// unless they name cluster components or privileged identities, and the UID
+// and extra fields of the requester, which hold session details, are
+// dropped. Groups are kept, the webhooks decide on them.
+func Sanitize(request admissionv1.AdmissionRequest) admissionv1.AdmissionRequest {
+	request = utils.RedactRequest(request)
+	request.UserInfo = authenticationv1.UserInfo{
+		Username: HashUsername(request.UserInfo.Username),
+		Groups:   request.UserInfo.Groups,
+	}
+	return request
+}
+
+// HashUsername returns a stable pseudonym of username, or username itself
+// if it names a cluster component or privileged identity, which the webhooks
+// decide on
+func HashUsername(username string) string {
+	if username == "" || strings.HasPrefix(username, "system:") ||
+		slices.Contains(hookconfig.PlatformAdminUsers, username) ||
+		slices.Contains(hookconfig.KubeAdminUsers, username) ||
+		slices.Contains(hookconfig.SREAdminUsers, username) {
+		return username
+	}
+	sum := sha256.Sum256([]byte(username))
+	return hashedUserPrefix + hex.EncodeToString(sum[:])[:12]
+}
+
+// Load reads the fixture captured to path
+func Load(path string) (Fixture, error) {
+	fixture := Fixture{}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fixture, err
+	}
+	if err := json.Unmarshal(b, &fixture); err != nil {
+		return fixture, fmt.Errorf("couldn't decode the fixture %s: %w", path, err)
+	}
+	if fixture.Review.Request == nil {
+		return fixture, fmt.Errorf("the fixture %s has no request", path)
+	}
+	return fixture, nil
+}
@@ -0,0 +1,157 @@
+package capture
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+func newRequest(uid, username string) admissionctl.Request {
+	return admissionctl.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			UID:       types.UID(uid),
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Secret"},
+			Resource:  metav1.GroupVersionResource{Version: "v1", Resource: "secrets"},
+			Operation: admissionv1.Create,
+			Namespace: "openshift-config",
+			Name:      "pull-secret",
+			UserInfo: authenticationv1.UserInfo{
+				Username: username,
+				UID:      "8d2c5a6e",
+				Groups:   []string{"dedicated-admins", "system:authenticated"},
+				Extra:    map[string]authenticationv1.ExtraValue{"scopes.authorization.openshift.io": {"user:full"}},
+			},
+			Object: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"pull-secret"},"data":{".dockerconfigjson":"c2VjcmV0"}}`)},
+		},
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	filter, err := ParseFilter("webhook=scc-validation, webhook=namespace-validation,decision=denied")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filter["webhook"]) != 2 || filter["decision"][0] != DecisionDenied {
+		t.Fatalf("expected two webhooks and the denied decision, got %v", filter)
+	}
+	for _, invalid := range []string{"webhook", "user=alice", "decision=maybe", "kind="} {
+		if _, err := ParseFilter(invalid); err == nil {
+			t.Fatalf("expected %q to be an invalid filter", invalid)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	request := newRequest("1", "alice")
+	denied := utils.Denied("SCC001_DEFAULT_SCC_MODIFY", "denied")
+	tests := []struct {
+		filter string
+		resp   admissionctl.Response
+		match  bool
+	}{
+		{"", admissionctl.Allowed(""), true},
+		{"webhook=scc-validation,webhook=namespace-validation", admissionctl.Allowed(""), true},
+		{"webhook=namespace-validation", admissionctl.Allowed(""), false},
+		{"kind=Secret,namespace=openshift-config,operation=CREATE", admissionctl.Allowed(""), true},
+		{"decision=denied", admissionctl.Allowed(""), false},
+		{"decision=denied", denied, true},
+	}
+	for _, test := range tests {
+		filter, err := ParseFilter(test.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := filter.Match("scc-validation", request, test.resp); got != test.match {
+			t.Errorf("expected filter %q to match %v, got %v", test.filter, test.match, got)
+		}
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	sanitized := Sanitize(newRequest("1", "alice@example.com").AdmissionRequest)
+	if sanitized.UserInfo.Username == "alice@example.com" || !strings.HasPrefix(sanitized.UserInfo.Username, hashedUserPrefix) {
+		t.Fatalf("expected the username to be hashed, got %s", sanitized.UserInfo.Username)
+	}
+	if sanitized.UserInfo.Username != HashUsername("alice@example.com") {
+		t.Fatal("expected usernames to hash to the same pseudonym")
+	}
+	if sanitized.UserInfo.UID != "" || sanitized.UserInfo.Extra != nil || len(sanitized.UserInfo.Groups) != 2 {
+		t.Fatalf("expected the UID and extra fields to be dropped and the groups kept, got %+v", sanitized.UserInfo)
+	}
+	if strings.Contains(string(sanitized.Object.Raw), "c2VjcmV0") {
+		t.Fatalf("expected the secret data to be redacted, got %s", sanitized.Object.Raw)
+	}
+	for _, username := range []string{"system:serviceaccount:openshift-monitoring:prometheus-k8s", "kube:admin", "backplane-cluster-admin"} {
+		if got := HashUsername(username); got != username {
+			t.Errorf("expected %s to be kept, got %s", username, got)
+		}
+	}
+}
+
+func TestCapture(t *testing.T) {
+	dir := t.TempDir()
+	filter, _ := ParseFilter("decision=denied")
+	c, err := NewCapturer(dir, filter, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	denied := utils.Denied("SCC001_DEFAULT_SCC_MODIFY", "denied")
+	c.Capture("scc-validation", newRequest("allowed", "alice"), admissionctl.Allowed(""))
+	for _, uid := range []string{"1", "2", "3"} {
+		c.Capture("scc-validation", newRequest(uid, "alice"), denied)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected the first two denials to be captured, got %v", files)
+	}
+	fixture, err := Load(filepath.Join(dir, "scc-validation-1700000000000000000-1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixture.Webhook != "scc-validation" || fixture.Allowed || fixture.Code != "SCC001_DEFAULT_SCC_MODIFY" {
+		t.Fatalf("expected the denial of scc-validation with its code, got %+v", fixture)
+	}
+	if fixture.Review.Kind != "AdmissionReview" || fixture.Review.Request.UserInfo.Username != HashUsername("alice") {
+		t.Fatalf("expected a sanitized AdmissionReview, got %+v", fixture.Review)
+	}
+
+	var disabled *Capturer
+	disabled.Capture("scc-validation", newRequest("4", "alice"), denied)
+}
+
+func TestNewCapturerFromEnv(t *testing.T) {
+	c, err := NewCapturerFromEnv()
+	if c != nil || err != nil {
+		t.Fatalf("expected capture to be disabled without %s, got %v, %v", DirEnvVar, c, err)
+	}
+	t.Setenv(DirEnvVar, t.TempDir())
+	t.Setenv(LimitEnvVar, "none")
+	if _, err := NewCapturerFromEnv(); err == nil {
+		t.Fatal("expected an invalid limit to fail")
+	}
+	t.Setenv(LimitEnvVar, "5")
+	c, err = NewCapturerFromEnv()
+	if err != nil || c == nil || c.limit != 5 {
+		t.Fatalf("expected a capturer limited to 5 requests, got %+v, %v", c, err)
+	}
+	if _, err := os.Stat(c.dir); err != nil {
+		t.Fatalf("expected the capture directory to exist: %v", err)
+	}
+}
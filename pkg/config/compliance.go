@@ -0,0 +1,29 @@
+package config
+
+// ComplianceProfileEnvVar is the default of the -compliance-profile flag
+const ComplianceProfileEnvVar = "COMPLIANCE_PROFILE"
+
+// AllowedImageRegistriesEnvVar overrides the comma-separated registries
+// customer Pods may pull images from under the FedRAMP compliance profile. It
+// is read from the OverridesConfigMap when it exists.
+const AllowedImageRegistriesEnvVar = "ALLOWED_IMAGE_REGISTRIES"
+
+// AllowedImageRegistries are the registries customer Pods may pull images
+// from under the FedRAMP compliance profile
+var AllowedImageRegistries = identitiesFromEnv(AllowedImageRegistriesEnvVar,
+	"registry.redhat.io",
+	"registry.access.redhat.com",
+	"quay.io",
+	"image-registry.openshift-image-registry.svc:5000",
+)
+
+// IsAllowedImageRegistry returns whether registry is one of the
+// AllowedImageRegistries
+func IsAllowedImageRegistry(registry string) bool {
+	for _, allowed := range AllowedImageRegistries {
+		if allowed == registry {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,17 @@
+package config
+
+import "testing"
+
+func TestIsAllowedImageRegistry(t *testing.T) {
+	for registry, expected := range map[string]bool{
+		"registry.redhat.io": true,
+		"quay.io":            true,
+		"image-registry.openshift-image-registry.svc:5000": true,
+		"docker.io":           false,
+		"quay.io.example.com": false,
+	} {
+		if IsAllowedImageRegistry(registry) != expected {
+			t.Errorf("Expected %s allowed to be %v", registry, expected)
+		}
+	}
+}
@@ -2,9 +2,66 @@ package config
 
 //go:generate go run ./generate/namespaces.go
 import (
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/config/layers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 )
 
+// ProtectedNamespacesEnvVar adds comma-separated regular expressions of
+// namespaces to protect to the PrivilegedNamespaces, e.g. for layered
+// products, without a code release. It is read from the OverridesConfigMap
+// when it exists.
+const ProtectedNamespacesEnvVar = "PROTECTED_NAMESPACES"
+
+// AdditionalProtectedNamespaces are the regular expressions added by
+// ProtectedNamespacesEnvVar. They are also part of PrivilegedNamespaces.
+var AdditionalProtectedNamespaces = protectedNamespacesFromEnv()
+
+// privilegedNamespaces and additionalProtectedNamespaces match the
+// PrivilegedNamespaces and AdditionalProtectedNamespaces
+var privilegedNamespaces, additionalProtectedNamespaces *utils.PatternMatcher
+
+func init() {
+	PrivilegedNamespaces = append(PrivilegedNamespaces, AdditionalProtectedNamespaces...)
+	// The configured patterns are checked by protectedNamespacesFromEnv
+	privilegedNamespaces, _ = utils.NewPatternMatcher(PrivilegedNamespaces)
+	additionalProtectedNamespaces, _ = utils.NewPatternMatcher(AdditionalProtectedNamespaces)
+}
+
+// protectedNamespacesFromEnv parses ProtectedNamespacesEnvVar and
+// ClusterProtectedNamespacesEnvVar. An invalid expression is reported with
+// layers.ReportInvalid and ignored, leaving the other namespaces protected,
+// rather than stopping every webhook from starting.
+func protectedNamespacesFromEnv() []string {
+	patterns := []string{}
+	for _, pattern := range strings.Split(os.Getenv(ProtectedNamespacesEnvVar), ",") {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			layers.ReportInvalid(ProtectedNamespacesEnvVar, pattern, err)
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	for _, name := range strings.Split(os.Getenv(ClusterProtectedNamespacesEnvVar), ".") {
+		if name = strings.TrimSpace(name); name != "" {
+			patterns = append(patterns, "^"+regexp.QuoteMeta(name)+"$")
+		}
+	}
+	return patterns
+}
+
 func IsPrivilegedNamespace(ns string) bool {
-	return utils.RegexSliceContains(ns, PrivilegedNamespaces)
+	return privilegedNamespaces.Matches(ns)
+}
+
+// IsAdditionalProtectedNamespace returns whether ns matches one of the
+// AdditionalProtectedNamespaces, for webhooks which protect their own set of
+// namespaces rather than the PrivilegedNamespaces
+func IsAdditionalProtectedNamespace(ns string) bool {
+	return additionalProtectedNamespaces.Matches(ns)
 }
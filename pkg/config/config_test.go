@@ -0,0 +1,72 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/config/layers"
+)
+
+func TestProtectedNamespacesFromEnv(t *testing.T) {
+	t.Setenv(ProtectedNamespacesEnvVar, "^redhat-rhoam-.*, ^acme-platform$")
+	if got := protectedNamespacesFromEnv(); !reflect.DeepEqual(got, []string{"^redhat-rhoam-.*", "^acme-platform$"}) {
+		t.Fatalf("Expected both patterns, got %v", got)
+	}
+	t.Setenv(ClusterProtectedNamespacesEnvVar, "acme-billing.acme-audit")
+	if got := protectedNamespacesFromEnv(); !reflect.DeepEqual(got, []string{"^redhat-rhoam-.*", "^acme-platform$", "^acme-billing$", "^acme-audit$"}) {
+		t.Fatalf("Expected the cluster namespaces to be added, got %v", got)
+	}
+	t.Setenv(ClusterProtectedNamespacesEnvVar, "")
+	t.Setenv(ProtectedNamespacesEnvVar, "^acme-(,^acme-platform$")
+	expectInvalid(t, ProtectedNamespacesEnvVar, "^acme-(", func() {
+		if got := protectedNamespacesFromEnv(); !reflect.DeepEqual(got, []string{"^acme-platform$"}) {
+			t.Fatalf("Expected the invalid pattern to be ignored, got %v", got)
+		}
+	})
+}
+
+// expectInvalid fails unless parse reports entry of key as invalid
+func expectInvalid(t *testing.T, key, entry string, parse func()) {
+	t.Helper()
+	reported := len(layers.InvalidEntries())
+	parse()
+	invalid := layers.InvalidEntries()[reported:]
+	if len(invalid) != 1 || invalid[0].Key != key || invalid[0].Entry != entry {
+		t.Fatalf("Expected %q to be reported as an invalid %s entry, got %+v", entry, key, invalid)
+	}
+}
+
+func TestServiceAccountExemptionsFromEnv(t *testing.T) {
+	t.Setenv(ServiceAccountExemptionsEnvVar, "scc-validation:openshift-gitops:argocd-application-controller, namespace-validation:acme:operator,scc-validation:acme:operator")
+	expected := map[string][]string{
+		"scc-validation":       {"system:serviceaccount:openshift-gitops:argocd-application-controller", "system:serviceaccount:acme:operator"},
+		"namespace-validation": {"system:serviceaccount:acme:operator"},
+	}
+	if got := serviceAccountExemptionsFromEnv(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	t.Setenv(ServiceAccountExemptionsEnvVar, "scc-validation:argocd-application-controller,scc-validation:acme:operator")
+	expectInvalid(t, ServiceAccountExemptionsEnvVar, "scc-validation:argocd-application-controller", func() {
+		expected := map[string][]string{"scc-validation": {"system:serviceaccount:acme:operator"}}
+		if got := serviceAccountExemptionsFromEnv(); !reflect.DeepEqual(got, expected) {
+			t.Fatalf("Expected the invalid entry to be ignored, got %v", got)
+		}
+	})
+}
+
+func TestDeclarativeManagersFromEnv(t *testing.T) {
+	t.Setenv(DeclarativeManagersEnvVar, "openshift-gitops:openshift-gitops-argocd-application-controller, flux-system:kustomize-controller")
+	expected := []string{
+		"system:serviceaccount:openshift-gitops:openshift-gitops-argocd-application-controller",
+		"system:serviceaccount:flux-system:kustomize-controller",
+	}
+	if got := declarativeManagersFromEnv(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	t.Setenv(DeclarativeManagersEnvVar, "scc-validation:flux-system:kustomize-controller")
+	expectInvalid(t, DeclarativeManagersEnvVar, "scc-validation:flux-system:kustomize-controller", func() {
+		if got := declarativeManagersFromEnv(); len(got) != 0 {
+			t.Fatalf("Expected the invalid entry to be ignored, got %v", got)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/config/layers"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+// DedicatedAdminCapabilitiesEnvVar replaces the DedicatedAdminCapabilities
+// with a JSON list of capabilities. It is read from the OverridesConfigMap
+// when it exists.
+const DedicatedAdminCapabilitiesEnvVar = "DEDICATED_ADMIN_CAPABILITIES"
+
+// DedicatedAdminCapability is an operation the DedicatedAdminGroups may
+// perform although a webhook protects it from regular users
+type DedicatedAdminCapability struct {
+	// Webhook is the name of the webhook allowing the operation
+	Webhook string `json:"webhook"`
+	// Group is the API group of the resources
+	Group string `json:"group"`
+	// Resources are the resources, or "*" for any resource of Group
+	Resources []string `json:"resources"`
+	// Operations are the admission operations, e.g. CREATE, or "*" for any
+	Operations []string `json:"operations"`
+	// Namespaces are regular expressions of the namespaces of the resources.
+	// The capability applies in any namespace, and to cluster-scoped
+	// resources, if it is empty.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// namespaces matches the Namespaces, built when the capabilities are
+	// loaded
+	namespaces *utils.PatternMatcher
+}
+
+// DedicatedAdminCapabilities are everything the DedicatedAdminGroups may do
+// beyond regular users. Webhooks check them with IsDedicatedAdminAllowed
+// rather than the group, so the policy for that persona is kept in one place.
+var DedicatedAdminCapabilities = dedicatedAdminCapabilitiesFromEnv()
+
+var defaultDedicatedAdminCapabilities = []DedicatedAdminCapability{
+	{
+		Webhook:    "regular-user-validation",
+		Group:      "managed.openshift.io",
+		Resources:  []string{"customdomains"},
+		Operations: []string{"*"},
+	},
+	{
+		// The NetNamespace must also not be a privileged namespace
+		Webhook:    "regular-user-validation",
+		Group:      "network.openshift.io",
+		Resources:  []string{"netnamespaces"},
+		Operations: []string{"*"},
+	},
+}
+
+// dedicatedAdminCapabilitiesFromEnv parses DedicatedAdminCapabilitiesEnvVar.
+// Invalid capabilities are reported with layers.ReportInvalid and ignored,
+// like invalid protected namespaces, and a value which isn't a JSON list
+// keeps the defaults.
+func dedicatedAdminCapabilitiesFromEnv() []DedicatedAdminCapability {
+	value := os.Getenv(DedicatedAdminCapabilitiesEnvVar)
+	if value == "" {
+		return compileCapabilities(defaultDedicatedAdminCapabilities)
+	}
+	parsed := []DedicatedAdminCapability{}
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		layers.ReportInvalid(DedicatedAdminCapabilitiesEnvVar, value, err)
+		return compileCapabilities(defaultDedicatedAdminCapabilities)
+	}
+	capabilities := []DedicatedAdminCapability{}
+	for _, c := range parsed {
+		entry, _ := json.Marshal(c)
+		if c.Webhook == "" || len(c.Resources) == 0 || len(c.Operations) == 0 {
+			layers.ReportInvalid(DedicatedAdminCapabilitiesEnvVar, string(entry), fmt.Errorf("it needs a webhook, resources and operations"))
+			continue
+		}
+		// A capability is dropped rather than its invalid patterns, which
+		// could leave it applying in any namespace
+		namespaces, err := utils.NewPatternMatcher(c.Namespaces)
+		if err != nil {
+			layers.ReportInvalid(DedicatedAdminCapabilitiesEnvVar, string(entry), err)
+			continue
+		}
+		c.namespaces = namespaces
+		capabilities = append(capabilities, c)
+	}
+	return capabilities
+}
+
+// compileCapabilities builds the namespace matchers of the capabilities of
+// the code, whose patterns are valid
+func compileCapabilities(capabilities []DedicatedAdminCapability) []DedicatedAdminCapability {
+	for i := range capabilities {
+		if len(capabilities[i].Namespaces) > 0 {
+			capabilities[i].namespaces, _ = utils.NewPatternMatcher(capabilities[i].Namespaces)
+		}
+	}
+	return capabilities
+}
+
+// IsDedicatedAdminAllowed returns whether request is made by a member of the
+// DedicatedAdminGroups and one of the DedicatedAdminCapabilities of webhook
+// allows it
+func IsDedicatedAdminAllowed(webhook string, request admissionctl.Request) bool {
+	if !IsMember(request.UserInfo.Groups, DedicatedAdminGroups) {
+		return false
+	}
+	for _, c := range DedicatedAdminCapabilities {
+		if c.allows(webhook, request) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c DedicatedAdminCapability) allows(webhook string, request admissionctl.Request) bool {
+	if c.Webhook != webhook || c.Group != request.Resource.Group {
+		return false
+	}
+	if !slices.Contains(c.Resources, "*") && !slices.Contains(c.Resources, request.Resource.Resource) {
+		return false
+	}
+	if !slices.Contains(c.Operations, "*") && !slices.Contains(c.Operations, string(request.Operation)) {
+		return false
+	}
+	return len(c.Namespaces) == 0 || c.namespaces.Matches(request.Namespace)
+}
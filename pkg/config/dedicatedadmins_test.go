@@ -0,0 +1,102 @@
+package config
+
+import (
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/config/layers"
+)
+
+func newDedicatedAdminRequest(groups []string, group, resource, namespace string, operation admissionv1.Operation) admissionctl.Request {
+	request := admissionctl.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Resource:  metav1.GroupVersionResource{Group: group, Resource: resource},
+		Namespace: namespace,
+		Operation: operation,
+	}}
+	request.UserInfo.Groups = groups
+	return request
+}
+
+func TestIsDedicatedAdminAllowed(t *testing.T) {
+	dedicatedAdmins := []string{"system:authenticated", "dedicated-admins"}
+	tests := []struct {
+		testID   string
+		webhook  string
+		request  admissionctl.Request
+		expected bool
+	}{
+		{
+			testID:   "customdomains",
+			webhook:  "regular-user-validation",
+			request:  newDedicatedAdminRequest(dedicatedAdmins, "managed.openshift.io", "customdomains", "", admissionv1.Delete),
+			expected: true,
+		},
+		{
+			testID:   "not a dedicated-admin",
+			webhook:  "regular-user-validation",
+			request:  newDedicatedAdminRequest([]string{"system:authenticated"}, "managed.openshift.io", "customdomains", "", admissionv1.Create),
+			expected: false,
+		},
+		{
+			testID:   "other resource",
+			webhook:  "regular-user-validation",
+			request:  newDedicatedAdminRequest(dedicatedAdmins, "managed.openshift.io", "subjectpermissions", "", admissionv1.Create),
+			expected: false,
+		},
+		{
+			testID:   "other webhook",
+			webhook:  "namespace-validation",
+			request:  newDedicatedAdminRequest(dedicatedAdmins, "managed.openshift.io", "customdomains", "", admissionv1.Create),
+			expected: false,
+		},
+	}
+	for _, test := range tests {
+		if got := IsDedicatedAdminAllowed(test.webhook, test.request); got != test.expected {
+			t.Fatalf("%s: Expected %v, got %v", test.testID, test.expected, got)
+		}
+	}
+}
+
+func TestDedicatedAdminCapabilitiesFromEnv(t *testing.T) {
+	t.Setenv(DedicatedAdminCapabilitiesEnvVar, `[{"webhook":"prometheusrule-validation","group":"monitoring.coreos.com","resources":["prometheusrules"],"operations":["CREATE","UPDATE"],"namespaces":["^openshift-customer-.*"]}]`)
+	capabilities := dedicatedAdminCapabilitiesFromEnv()
+	if len(capabilities) != 1 {
+		t.Fatalf("Expected the capabilities to be replaced, got %+v", capabilities)
+	}
+	c := capabilities[0]
+	request := newDedicatedAdminRequest(nil, "monitoring.coreos.com", "prometheusrules", "openshift-customer-monitoring", admissionv1.Update)
+	if !c.allows("prometheusrule-validation", request) {
+		t.Fatalf("Expected %+v to allow updates in openshift-customer-monitoring", c)
+	}
+	request.Namespace = "openshift-monitoring"
+	if c.allows("prometheusrule-validation", request) {
+		t.Fatalf("Expected %+v not to allow updates in openshift-monitoring", c)
+	}
+	request.Namespace = "openshift-customer-monitoring"
+	request.Operation = admissionv1.Delete
+	if c.allows("prometheusrule-validation", request) {
+		t.Fatalf("Expected %+v not to allow deletions", c)
+	}
+
+	for _, invalid := range []string{`{"webhook":"prometheusrule-validation"}`, `{"webhook":"prometheusrule-validation","group":"monitoring.coreos.com","resources":["prometheusrules"],"operations":["*"],"namespaces":["^openshift-(","^acme$"]}`} {
+		t.Setenv(DedicatedAdminCapabilitiesEnvVar, "["+invalid+`,{"webhook":"regular-user-validation","resources":["customdomains"],"operations":["*"]}]`)
+		reported := len(layers.InvalidEntries())
+		capabilities = dedicatedAdminCapabilitiesFromEnv()
+		if len(capabilities) != 1 || capabilities[0].Webhook != "regular-user-validation" {
+			t.Fatalf("Expected the invalid capability %s to be ignored, got %+v", invalid, capabilities)
+		}
+		if got := layers.InvalidEntries()[reported:]; len(got) != 1 || got[0].Key != DedicatedAdminCapabilitiesEnvVar {
+			t.Fatalf("Expected the invalid capability %s to be reported, got %+v", invalid, got)
+		}
+	}
+
+	t.Setenv(DedicatedAdminCapabilitiesEnvVar, "not json")
+	expectInvalid(t, DedicatedAdminCapabilitiesEnvVar, "not json", func() {
+		if got := dedicatedAdminCapabilitiesFromEnv(); len(got) != len(defaultDedicatedAdminCapabilities) {
+			t.Fatalf("Expected the defaults to be kept, got %+v", got)
+		}
+	})
+}
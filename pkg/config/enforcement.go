@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/config/layers"
+)
+
+// WebhookEnforcementEnvVar sets the EnforcementMode of webhooks, as
+// comma-separated <webhook>:<mode> entries, e.g.
+// "scc-validation:Audit,pod-validation:Enforce". A listed webhook uses the
+// listed mode, overriding its own default. It is read from the
+// OverridesConfigMap when it exists.
+const WebhookEnforcementEnvVar = "WEBHOOK_ENFORCEMENT"
+
+// EnforcementMode is whether a webhook denies the requests it rejects or only
+// reports them
+type EnforcementMode string
+
+const (
+	// EnforcementEnforce denies requests, the default
+	EnforcementEnforce EnforcementMode = "Enforce"
+	// EnforcementAudit allows the requests the webhook would deny with a
+	// warning, after logging and recording them as requests it would have
+	// denied
+	EnforcementAudit EnforcementMode = "Audit"
+)
+
+// WebhookEnforcement are the EnforcementModes configured by
+// WebhookEnforcementEnvVar, by webhook name
+var WebhookEnforcement = webhookEnforcementFromEnv()
+
+// webhookEnforcementFromEnv parses WebhookEnforcementEnvVar. An invalid entry
+// is reported with layers.ReportInvalid and ignored, leaving its webhook in
+// its default mode, rather than stopping every webhook from starting.
+func webhookEnforcementFromEnv() map[string]EnforcementMode {
+	modes := map[string]EnforcementMode{}
+	for _, entry := range strings.Split(os.Getenv(WebhookEnforcementEnvVar), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		webhook, mode, found := strings.Cut(entry, ":")
+		if !found || webhook == "" {
+			layers.ReportInvalid(WebhookEnforcementEnvVar, entry, fmt.Errorf("it must be <webhook>:<mode>"))
+			continue
+		}
+		switch EnforcementMode(mode) {
+		case EnforcementEnforce, EnforcementAudit:
+			modes[webhook] = EnforcementMode(mode)
+		default:
+			layers.ReportInvalid(WebhookEnforcementEnvVar, entry, fmt.Errorf("the mode must be %s or %s", EnforcementEnforce, EnforcementAudit))
+		}
+	}
+	return modes
+}
+
+// WebhookEnforcementMode returns the EnforcementMode configured for webhook,
+// and whether one is
+func WebhookEnforcementMode(webhook string) (mode EnforcementMode, configured bool) {
+	mode, configured = WebhookEnforcement[webhook]
+	return mode, configured
+}
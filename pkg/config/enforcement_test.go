@@ -0,0 +1,33 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/config/layers"
+)
+
+func TestWebhookEnforcementFromEnv(t *testing.T) {
+	t.Setenv(WebhookEnforcementEnvVar, "scc-validation:Audit, pod-validation:Enforce")
+	expected := map[string]EnforcementMode{
+		"scc-validation": EnforcementAudit,
+		"pod-validation": EnforcementEnforce,
+	}
+	if got := webhookEnforcementFromEnv(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for _, value := range []string{"scc-validation", ":Audit", "scc-validation:audit", "scc-validation:"} {
+		t.Run(value, func(t *testing.T) {
+			t.Setenv(WebhookEnforcementEnvVar, value+",pod-validation:Audit")
+			reported := len(layers.InvalidEntries())
+			expected := map[string]EnforcementMode{"pod-validation": EnforcementAudit}
+			if got := webhookEnforcementFromEnv(); !reflect.DeepEqual(got, expected) {
+				t.Fatalf("Expected %q to be ignored, got %v", value, got)
+			}
+			invalid := layers.InvalidEntries()
+			if len(invalid) != reported+1 || invalid[reported].Key != WebhookEnforcementEnvVar || invalid[reported].Entry != value {
+				t.Fatalf("Expected %q to be reported as invalid, got %+v", value, invalid[reported:])
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/config/layers"
+)
+
+// ServiceAccountExemptionsEnvVar exempts service accounts from individual
+// webhooks, e.g. a customer's approved GitOps controller, as comma-separated
+// <webhook>:<namespace>:<name> entries. Unlike the privileged identities, an
+// exempted service account is only exempted from the webhooks it is listed
+// for. It is read from the OverridesConfigMap when it exists.
+const ServiceAccountExemptionsEnvVar = "SERVICE_ACCOUNT_EXEMPTIONS"
+
+// ServiceAccountExemptions are the usernames of the service accounts
+// exempted by ServiceAccountExemptionsEnvVar, by webhook name
+var ServiceAccountExemptions = serviceAccountExemptionsFromEnv()
+
+// serviceAccountExemptionsFromEnv parses ServiceAccountExemptionsEnvVar. An
+// invalid entry is reported with layers.ReportInvalid and ignored, exempting
+// nobody, rather than stopping every webhook from starting.
+func serviceAccountExemptionsFromEnv() map[string][]string {
+	exemptions := map[string][]string{}
+	for _, entry := range strings.Split(os.Getenv(ServiceAccountExemptionsEnvVar), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			layers.ReportInvalid(ServiceAccountExemptionsEnvVar, entry, fmt.Errorf("it must be <webhook>:<namespace>:<name>"))
+			continue
+		}
+		webhook := parts[0]
+		exemptions[webhook] = append(exemptions[webhook], fmt.Sprintf("system:serviceaccount:%s:%s", parts[1], parts[2]))
+	}
+	return exemptions
+}
+
+// IsExemptServiceAccount returns whether username is a service account
+// exempted from webhook
+func IsExemptServiceAccount(webhook, username string) bool {
+	for _, exempt := range ServiceAccountExemptions[webhook] {
+		if exempt == username {
+			return true
+		}
+	}
+	return false
+}
+
+// LabelExemptionWebhooksEnvVar overrides the comma-separated webhooks which
+// honor the webhook exemption label on Namespaces and ServiceAccounts, see
+// pkg/exemption. It is read from the OverridesConfigMap when it exists.
+const LabelExemptionWebhooksEnvVar = "LABEL_EXEMPTION_WEBHOOKS"
+
+// LabelExemptionWebhooks are the webhooks which certified add-on namespaces
+// and operator service accounts may be exempted from by label. Webhooks
+// protecting the platform itself are deliberately not part of it.
+var LabelExemptionWebhooks = identitiesFromEnv(LabelExemptionWebhooksEnvVar,
+	"pod-validation",
+	"podimageregistry-validation",
+	"scc-validation",
+)
+
+// IsLabelExemptWebhook returns whether webhook honors the exemption label
+func IsLabelExemptWebhook(webhook string) bool {
+	return slices.Contains(LabelExemptionWebhooks, webhook)
+}
+
+// DeclarativeManagersEnvVar registers a customer's GitOps controllers, e.g.
+// Argo CD or Flux, as comma-separated <namespace>:<name> service accounts.
+// It is read from the OverridesConfigMap when it exists.
+const DeclarativeManagersEnvVar = "DECLARATIVE_MANAGERS"
+
+// DeclarativeManagers are the usernames of the service accounts registered by
+// DeclarativeManagersEnvVar. Every webhook allows them to re-apply objects
+// unchanged, e.g. a default SCC kept in a GitOps repository, while still
+// denying them actual changes.
+var DeclarativeManagers = declarativeManagersFromEnv()
+
+// declarativeManagersFromEnv parses DeclarativeManagersEnvVar. An invalid
+// entry is reported and ignored, like ServiceAccountExemptionsEnvVar.
+func declarativeManagersFromEnv() []string {
+	managers := []string{}
+	for _, entry := range strings.Split(os.Getenv(DeclarativeManagersEnvVar), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			layers.ReportInvalid(DeclarativeManagersEnvVar, entry, fmt.Errorf("it must be <namespace>:<name>"))
+			continue
+		}
+		managers = append(managers, fmt.Sprintf("system:serviceaccount:%s:%s", parts[0], parts[1]))
+	}
+	return managers
+}
+
+// IsDeclarativeManager returns whether username is a registered declarative
+// manager
+func IsDeclarativeManager(username string) bool {
+	return slices.Contains(DeclarativeManagers, username)
+}
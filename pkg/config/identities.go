@@ -0,0 +1,156 @@
+package config
+
+import (
+	"os"
+	"strings"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/config/layers"
+)
+
+// Environment variables overriding the privileged identities, as
+// comma-separated lists. They are read from the OverridesConfigMap when it
+// exists, so forks and other managed offerings can use their own identity
+// naming without changing the webhooks.
+const (
+	PlatformAdminUsersEnvVar             = "PLATFORM_ADMIN_USERS"
+	KubeAdminUsersEnvVar                 = "KUBE_ADMIN_USERS"
+	SREAdminUsersEnvVar                  = "SRE_ADMIN_USERS"
+	SREAdminGroupsEnvVar                 = "SRE_ADMIN_GROUPS"
+	CEEGroupsEnvVar                      = "CEE_GROUPS"
+	CustomerClusterAdminGroupsEnvVar     = "CUSTOMER_CLUSTER_ADMIN_GROUPS"
+	DedicatedAdminGroupsEnvVar           = "DEDICATED_ADMIN_GROUPS"
+	LayeredProductAdminGroupsEnvVar      = "LAYERED_PRODUCT_ADMIN_GROUPS"
+	PrivilegedServiceAccountGroupsEnvVar = "PRIVILEGED_SERVICE_ACCOUNT_GROUPS"
+
+	// PlatformAdminWebhooksEnvVar and KubeAdminWebhooksEnvVar override the
+	// comma-separated names of the webhooks treating the PlatformAdminUsers
+	// and KubeAdminUsers as privileged. Other webhooks handle them like any
+	// other user.
+	PlatformAdminWebhooksEnvVar = "PLATFORM_ADMIN_WEBHOOKS"
+	KubeAdminWebhooksEnvVar     = "KUBE_ADMIN_WEBHOOKS"
+
+	// OverridesConfigMap is the optional ConfigMap in the webhook namespace
+	// whose keys are loaded as the environment variables above, and
+	// ProtectedNamespacesEnvVar. It takes precedence over the other
+	// configuration layers, see pkg/config/layers.
+	OverridesConfigMap = layers.OverridesConfigMap
+)
+
+var (
+	// PlatformAdminUsers apply platform resources. Hive authenticates as
+	// system:admin with the admin kubeconfig.
+	PlatformAdminUsers = identitiesFromEnv(PlatformAdminUsersEnvVar, "system:admin")
+	// KubeAdminUsers are the installer-created break-glass administrators
+	KubeAdminUsers = identitiesFromEnv(KubeAdminUsersEnvVar, "kube:admin")
+	// SREAdminUsers are the users SRE acts as through backplane
+	SREAdminUsers = identitiesFromEnv(SREAdminUsersEnvVar, "backplane-cluster-admin")
+	// SREAdminGroups are the groups of the service accounts SRE acts as
+	// through backplane
+	SREAdminGroups = identitiesFromEnv(SREAdminGroupsEnvVar, "system:serviceaccounts:openshift-backplane-srep")
+	// CEEGroups are the groups of the service accounts support engineers act
+	// as through backplane
+	CEEGroups = identitiesFromEnv(CEEGroupsEnvVar, "system:serviceaccounts:openshift-backplane-cee")
+	// CustomerClusterAdminGroups are the groups of customers granted
+	// cluster-admin
+	CustomerClusterAdminGroups = identitiesFromEnv(CustomerClusterAdminGroupsEnvVar, "cluster-admins")
+	// DedicatedAdminGroups are the groups of the customer administrators
+	DedicatedAdminGroups = identitiesFromEnv(DedicatedAdminGroupsEnvVar, "dedicated-admins")
+	// LayeredProductAdminGroups are the groups of the SRE teams running
+	// layered products
+	LayeredProductAdminGroups = identitiesFromEnv(LayeredProductAdminGroupsEnvVar, "layered-sre-cluster-admins")
+	// PrivilegedServiceAccountGroups is a regex string of serviceaccounts that our webhooks should commonly allow to
+	// perform restricted actions.
+	// Centralized osde2e tests have a serviceaccount like "system:serviceaccounts:osde2e-abcde"
+	// Decentralized osde2e tests have a serviceaccount like "system:serviceaccounts:osde2e-h-abcde"
+	PrivilegedServiceAccountGroups = stringFromEnv(PrivilegedServiceAccountGroupsEnvVar, `^system:serviceaccounts:(kube-.*|openshift|openshift-.*|default|redhat-.*|osde2e-(h-)?[a-z0-9]{5})`)
+
+	// PlatformAdminWebhooks are the webhooks treating the PlatformAdminUsers
+	// as privileged
+	PlatformAdminWebhooks = identitiesFromEnv(PlatformAdminWebhooksEnvVar,
+		"customresourcedefinitions-validation",
+		"hiveownership-validation",
+		"ingress-config-validation",
+		"namespace-validation",
+		"networkpolicies-validation",
+		"oauthclient-validation",
+		"ownershiplabel-mutation",
+		"prometheusrule-validation",
+		"scc-validation",
+		"sccpriority-mutation",
+	)
+	// KubeAdminWebhooks are the webhooks treating the KubeAdminUsers as
+	// privileged
+	KubeAdminWebhooks = identitiesFromEnv(KubeAdminWebhooksEnvVar,
+		"hiveownership-validation",
+		"namespace-validation",
+		"oauthclient-validation",
+		"prometheusrule-validation",
+		"sccpriority-mutation",
+	)
+)
+
+// PlatformAdminUsersFor returns the PlatformAdminUsers if webhook treats them
+// as privileged, and no users otherwise
+func PlatformAdminUsersFor(webhook string) []string {
+	return usersFor(webhook, PlatformAdminWebhooks, PlatformAdminUsers)
+}
+
+// KubeAdminUsersFor returns the KubeAdminUsers if webhook treats them as
+// privileged, and no users otherwise
+func KubeAdminUsersFor(webhook string) []string {
+	return usersFor(webhook, KubeAdminWebhooks, KubeAdminUsers)
+}
+
+func usersFor(webhook string, webhooks, users []string) []string {
+	for _, name := range webhooks {
+		if name == webhook {
+			return users
+		}
+	}
+	return []string{}
+}
+
+// identitiesFromEnv returns the comma-separated identities set by envVar, or
+// defaults if it is unset. Setting it to an empty string clears the list.
+func identitiesFromEnv(envVar string, defaults ...string) []string {
+	value, ok := os.LookupEnv(envVar)
+	if !ok {
+		return defaults
+	}
+	identities := []string{}
+	for _, identity := range strings.Split(value, ",") {
+		if identity = strings.TrimSpace(identity); identity != "" {
+			identities = append(identities, identity)
+		}
+	}
+	return identities
+}
+
+func stringFromEnv(envVar, def string) string {
+	if value := os.Getenv(envVar); value != "" {
+		return value
+	}
+	return def
+}
+
+// Identities returns the concatenation of the identity lists, for hooks
+// allowing several kinds of privileged identities
+func Identities(lists ...[]string) []string {
+	identities := []string{}
+	for _, list := range lists {
+		identities = append(identities, list...)
+	}
+	return identities
+}
+
+// IsMember returns whether any of userGroups is one of groups
+func IsMember(userGroups, groups []string) bool {
+	for _, group := range userGroups {
+		for _, check := range groups {
+			if group == check {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIdentitiesFromEnv(t *testing.T) {
+	tests := []struct {
+		testID   string
+		set      bool
+		value    string
+		expected []string
+	}{
+		{testID: "unset", expected: []string{"backplane-cluster-admin"}},
+		{testID: "override", set: true, value: "sre-admin, break-glass-admin,", expected: []string{"sre-admin", "break-glass-admin"}},
+		{testID: "cleared", set: true, value: "", expected: []string{}},
+	}
+	for _, test := range tests {
+		if test.set {
+			t.Setenv(SREAdminUsersEnvVar, test.value)
+		}
+		if got := identitiesFromEnv(SREAdminUsersEnvVar, "backplane-cluster-admin"); !reflect.DeepEqual(got, test.expected) {
+			t.Fatalf("%s: Expected %v, got %v", test.testID, test.expected, got)
+		}
+	}
+}
+
+func TestIsMember(t *testing.T) {
+	if !IsMember([]string{"system:authenticated", "cluster-admins"}, Identities(CustomerClusterAdminGroups, DedicatedAdminGroups)) {
+		t.Fatalf("Expected cluster-admins to be a customer admin group")
+	}
+	if IsMember([]string{"system:authenticated"}, SREAdminGroups) {
+		t.Fatalf("Expected system:authenticated not to be an SRE group")
+	}
+}
+
+func TestAdminUsersFor(t *testing.T) {
+	if got := KubeAdminUsersFor("namespace-validation"); !reflect.DeepEqual(got, KubeAdminUsers) {
+		t.Fatalf("Expected namespace-validation to treat kube:admin as privileged, got %v", got)
+	}
+	if got := KubeAdminUsersFor("scc-validation"); len(got) != 0 {
+		t.Fatalf("Expected scc-validation not to treat kube:admin as privileged, got %v", got)
+	}
+	if got := PlatformAdminUsersFor("scc-validation"); !reflect.DeepEqual(got, PlatformAdminUsers) {
+		t.Fatalf("Expected scc-validation to treat system:admin as privileged, got %v", got)
+	}
+}
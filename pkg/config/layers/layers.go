@@ -0,0 +1,151 @@
+// Package layers merges the configuration ConfigMaps of the webhooks into
+// their environment, before any package reads it. Each ConfigMap is loaded
+// into the pod environment with its own prefix, so the precedence is decided
+// here rather than by the order of the pod's envFrom, and the source of every
+// effective value can be reported.
+package layers
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The ConfigMaps in the webhook namespace, in increasing precedence
+const (
+	// OrgConfigMap holds the defaults of an organization, overriding those
+	// shipped in the image
+	OrgConfigMap = "webhook-org-config"
+	// ParametersConfigMap is rendered per cluster by Hive from the
+	// ClusterDeployment labels
+	ParametersConfigMap = "webhook-cluster-parameters"
+	// OverridesConfigMap holds the exceptions of a single cluster
+	OverridesConfigMap = "webhook-overrides"
+
+	// ImageSource is the source of values set by the image or the pod spec
+	ImageSource = "image"
+)
+
+// Layer is a ConfigMap loaded into the environment with Prefix
+type Layer struct {
+	ConfigMap string `json:"configMap"`
+	Prefix    string `json:"prefix"`
+	// skipEmpty ignores empty values, which Hive renders for unset labels,
+	// so they don't clear the value of a lower layer
+	skipEmpty bool
+}
+
+// Layers are the configuration layers, in increasing precedence
+var Layers = []Layer{
+	{ConfigMap: OrgConfigMap, Prefix: "MCVW_ORG_"},
+	{ConfigMap: ParametersConfigMap, Prefix: "MCVW_PARAMETERS_", skipEmpty: true},
+	{ConfigMap: OverridesConfigMap, Prefix: "MCVW_OVERRIDES_"},
+}
+
+// Value is the value of a setting in one source
+type Value struct {
+	Source string `json:"source"`
+	Value  string `json:"value"`
+}
+
+// Setting is the effective value of a configuration key and where it comes
+// from. Shadowed are the values of lower layers it takes precedence over.
+type Setting struct {
+	Key      string  `json:"key"`
+	Value    string  `json:"value"`
+	Source   string  `json:"source"`
+	Shadowed []Value `json:"shadowed,omitempty"`
+}
+
+// Invalid is an entry of a setting which was ignored as invalid
+type Invalid struct {
+	Key   string `json:"key"`
+	Entry string `json:"entry"`
+	Error string `json:"error"`
+}
+
+var (
+	effective []Setting
+
+	invalidMu sync.Mutex
+	invalid   []Invalid
+)
+
+func init() {
+	effective = merge(os.Environ())
+	for _, setting := range effective {
+		os.Setenv(setting.Key, setting.Value)
+	}
+}
+
+// Effective returns the settings set by the layers, sorted by key
+func Effective() []Setting {
+	return effective
+}
+
+// ReportInvalid records that entry of the setting key was ignored, being
+// invalid for err. The settings are mostly parsed while the packages are
+// initialized, before the logger is set, so the webhooks log the invalid
+// entries on startup and report them with the effective configuration.
+func ReportInvalid(key, entry string, err error) {
+	invalidMu.Lock()
+	defer invalidMu.Unlock()
+	invalid = append(invalid, Invalid{Key: key, Entry: entry, Error: err.Error()})
+}
+
+// InvalidEntries returns the entries reported by ReportInvalid
+func InvalidEntries() []Invalid {
+	invalidMu.Lock()
+	defer invalidMu.Unlock()
+	return append([]Invalid{}, invalid...)
+}
+
+// merge returns the settings environ sets through the layers
+func merge(environ []string) []Setting {
+	base := map[string]string{}
+	layered := map[string][]Value{}
+	for _, entry := range environ {
+		key, value, _ := strings.Cut(entry, "=")
+		layer, found := layerOf(key)
+		if !found {
+			base[key] = value
+			continue
+		}
+		if value == "" && layer.skipEmpty {
+			continue
+		}
+		key = strings.TrimPrefix(key, layer.Prefix)
+		layered[key] = append(layered[key], Value{Source: layer.ConfigMap, Value: value})
+	}
+
+	settings := []Setting{}
+	for key, values := range layered {
+		sort.Slice(values, func(i, j int) bool { return precedence(values[i].Source) < precedence(values[j].Source) })
+		if value, found := base[key]; found {
+			values = append([]Value{{Source: ImageSource, Value: value}}, values...)
+		}
+		top := values[len(values)-1]
+		settings = append(settings, Setting{Key: key, Value: top.Value, Source: top.Source, Shadowed: values[:len(values)-1]})
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings
+}
+
+func layerOf(key string) (Layer, bool) {
+	for _, layer := range Layers {
+		if strings.HasPrefix(key, layer.Prefix) {
+			return layer, true
+		}
+	}
+	return Layer{}, false
+}
+
+func precedence(configMap string) int {
+	for i, layer := range Layers {
+		if layer.ConfigMap == configMap {
+			return i
+		}
+	}
+	return -1
+}
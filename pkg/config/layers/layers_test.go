@@ -0,0 +1,31 @@
+package layers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	settings := merge([]string{
+		"PATH=/usr/bin",
+		"SRE_ADMIN_USERS=backplane-cluster-admin",
+		"MCVW_OVERRIDES_SRE_ADMIN_USERS=break-glass-admin",
+		"MCVW_ORG_SRE_ADMIN_USERS=org-admin",
+		"MCVW_ORG_DEFAULT_CPU_REQUEST=50m",
+		"MCVW_PARAMETERS_DEFAULT_CPU_REQUEST=100m",
+		"MCVW_PARAMETERS_PRODUCT_PROFILE=",
+		"MCVW_OVERRIDES_KUBE_ADMIN_USERS=",
+	})
+	expected := []Setting{
+		{Key: "DEFAULT_CPU_REQUEST", Value: "100m", Source: ParametersConfigMap, Shadowed: []Value{{Source: OrgConfigMap, Value: "50m"}}},
+		// Overrides may clear a value, unlike unset parameter labels
+		{Key: "KUBE_ADMIN_USERS", Value: "", Source: OverridesConfigMap, Shadowed: []Value{}},
+		{Key: "SRE_ADMIN_USERS", Value: "break-glass-admin", Source: OverridesConfigMap, Shadowed: []Value{
+			{Source: ImageSource, Value: "backplane-cluster-admin"},
+			{Source: OrgConfigMap, Value: "org-admin"},
+		}},
+	}
+	if !reflect.DeepEqual(settings, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, settings)
+	}
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/template"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/config/layers"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+// DenialMessagesEnvVar customizes the messages of denials, as a JSON object
+// of DenialMessage by webhook name, so each managed offering can point
+// customers to its own documentation. The DefaultDenialMessageKey entry
+// applies to webhooks without one. It is read from the OverridesConfigMap
+// when it exists.
+const DenialMessagesEnvVar = "DENIAL_MESSAGES"
+
+// DefaultDenialMessageKey is the DenialMessagesEnvVar entry of webhooks
+// without their own
+const DefaultDenialMessageKey = "default"
+
+// defaultDenialMessageTemplate is used by entries which only set a DocsURL
+const defaultDenialMessageTemplate = `{{ .Message }}{{ if .DocsURL }} See {{ .DocsURL }}{{ end }}`
+
+// DenialMessage customizes the message of a webhook's denials
+type DenialMessage struct {
+	// Template is a text/template rendering DenialMessageData. It defaults
+	// to the webhook's message followed by DocsURL.
+	Template string `json:"template,omitempty"`
+	// DocsURL is the documentation of the webhook, e.g. a knowledge-base
+	// article
+	DocsURL string `json:"docsURL,omitempty"`
+
+	tmpl *template.Template
+}
+
+// DenialMessageData is what a DenialMessage Template renders
+type DenialMessageData struct {
+	// Webhook is the name of the denying webhook
+	Webhook string
+	// Code is the reason code of the denial, if it has one
+	Code utils.ReasonCode
+	// Message is the webhook's own denial message
+	Message string
+	// DocsURL is the DocsURL of the DenialMessage
+	DocsURL string
+}
+
+// DenialMessages are the customized denial messages set by
+// DenialMessagesEnvVar, by webhook name
+var DenialMessages = denialMessagesFromEnv()
+
+// denialMessagesFromEnv parses DenialMessagesEnvVar. An invalid template is
+// reported with layers.ReportInvalid at startup rather than when a request is
+// denied, and its webhook keeps its own message. A value which isn't a JSON
+// object is reported and customizes no message.
+func denialMessagesFromEnv() map[string]DenialMessage {
+	value := os.Getenv(DenialMessagesEnvVar)
+	messages := map[string]DenialMessage{}
+	if value == "" {
+		return messages
+	}
+	if err := json.Unmarshal([]byte(value), &messages); err != nil {
+		layers.ReportInvalid(DenialMessagesEnvVar, value, err)
+		return map[string]DenialMessage{}
+	}
+	for webhook, message := range messages {
+		compiled, err := compileDenialMessage(webhook, message)
+		if err != nil {
+			layers.ReportInvalid(DenialMessagesEnvVar, webhook, err)
+			delete(messages, webhook)
+			continue
+		}
+		messages[webhook] = compiled
+	}
+	return messages
+}
+
+// ParseDenialMessages parses the JSON object of DenialMessage by webhook name
+// in value, which may be empty, failing on any invalid template
+func ParseDenialMessages(value string) (map[string]DenialMessage, error) {
+	messages := map[string]DenialMessage{}
+	if value == "" {
+		return messages, nil
+	}
+	if err := json.Unmarshal([]byte(value), &messages); err != nil {
+		return nil, err
+	}
+	for webhook, message := range messages {
+		compiled, err := compileDenialMessage(webhook, message)
+		if err != nil {
+			return nil, fmt.Errorf("template of %s: %v", webhook, err)
+		}
+		messages[webhook] = compiled
+	}
+	return messages, nil
+}
+
+// compileDenialMessage parses the template of the DenialMessage of webhook
+func compileDenialMessage(webhook string, message DenialMessage) (DenialMessage, error) {
+	if message.Template == "" {
+		message.Template = defaultDenialMessageTemplate
+	}
+	tmpl, err := template.New(webhook).Option("missingkey=error").Parse(message.Template)
+	if err != nil {
+		return message, err
+	}
+	message.tmpl = tmpl
+	return message, nil
+}
+
+// RenderDenialMessage returns the customized message of a denial by webhook
+// with code and message, and false if its message isn't customized. It
+// errors if the template fails to render, e.g. on an unknown field.
+func RenderDenialMessage(webhook string, code utils.ReasonCode, message string) (string, bool, error) {
+	custom, ok := DenialMessages[webhook]
+	if !ok {
+		custom, ok = DenialMessages[DefaultDenialMessageKey]
+	}
+	if !ok {
+		return "", false, nil
+	}
+	out := &bytes.Buffer{}
+	err := custom.tmpl.Execute(out, DenialMessageData{
+		Webhook: webhook,
+		Code:    code,
+		Message: message,
+		DocsURL: custom.DocsURL,
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return out.String(), true, nil
+}
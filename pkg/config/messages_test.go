@@ -0,0 +1,79 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+func setDenialMessages(t *testing.T, value string) {
+	t.Helper()
+	messages, err := ParseDenialMessages(value)
+	if err != nil {
+		t.Fatalf("Expected the denial messages to parse, got %v", err)
+	}
+	previous := DenialMessages
+	t.Cleanup(func() { DenialMessages = previous })
+	DenialMessages = messages
+}
+
+func TestRenderDenialMessage(t *testing.T) {
+	setDenialMessages(t, `{
+		"scc-validation": {"template": "{{ .Code }}: {{ .Message }}. Read {{ .DocsURL }} before contacting support.", "docsURL": "https://example.com/kb/scc"},
+		"default": {"docsURL": "https://example.com/kb/webhooks"}
+	}`)
+
+	message, ok, err := RenderDenialMessage("scc-validation", utils.ReasonSCCDefaultModify, "Modifying default SCCs is not allowed")
+	if err != nil || !ok {
+		t.Fatalf("Expected a customized message, got %v, %v", ok, err)
+	}
+	if expected := "SCC001_DEFAULT_SCC_MODIFY: Modifying default SCCs is not allowed. Read https://example.com/kb/scc before contacting support."; message != expected {
+		t.Errorf("Expected %q, got %q", expected, message)
+	}
+
+	message, ok, err = RenderDenialMessage("node-validation", utils.ReasonNodeDelete, "Deleting nodes is not allowed.")
+	if err != nil || !ok {
+		t.Fatalf("Expected the default message, got %v, %v", ok, err)
+	}
+	if expected := "Deleting nodes is not allowed. See https://example.com/kb/webhooks"; message != expected {
+		t.Errorf("Expected %q, got %q", expected, message)
+	}
+}
+
+func TestRenderDenialMessageUncustomized(t *testing.T) {
+	setDenialMessages(t, `{"scc-validation": {"docsURL": "https://example.com/kb/scc"}}`)
+	if _, ok, err := RenderDenialMessage("node-validation", utils.ReasonNodeDelete, "Deleting nodes is not allowed."); ok || err != nil {
+		t.Errorf("Expected no customized message, got %v, %v", ok, err)
+	}
+}
+
+func TestInvalidDenialMessages(t *testing.T) {
+	for _, value := range []string{`not json`, `{"scc-validation": {"template": "{{ .Message"}}`} {
+		if _, err := ParseDenialMessages(value); err == nil {
+			t.Errorf("Expected %q to fail to parse", value)
+		}
+	}
+}
+
+func TestDenialMessagesFromEnv(t *testing.T) {
+	t.Setenv(DenialMessagesEnvVar, `{"scc-validation": {"template": "{{ .Message"}, "default": {"docsURL": "https://example.com/kb/webhooks"}}`)
+	expectInvalid(t, DenialMessagesEnvVar, "scc-validation", func() {
+		messages := denialMessagesFromEnv()
+		if _, ok := messages["scc-validation"]; ok || len(messages) != 1 {
+			t.Fatalf("Expected the invalid template to be ignored, got %v", messages)
+		}
+	})
+	t.Setenv(DenialMessagesEnvVar, `not json`)
+	expectInvalid(t, DenialMessagesEnvVar, "not json", func() {
+		if messages := denialMessagesFromEnv(); len(messages) != 0 {
+			t.Fatalf("Expected no customized message, got %v", messages)
+		}
+	})
+}
+
+func TestRenderDenialMessageUnknownField(t *testing.T) {
+	setDenialMessages(t, `{"default": {"template": "{{ .Reason }}"}}`)
+	if _, ok, err := RenderDenialMessage("node-validation", utils.ReasonNodeDelete, "Deleting nodes is not allowed."); ok || err == nil {
+		t.Errorf("Expected an unknown field to fail to render, got %v, %v", ok, err)
+	}
+}
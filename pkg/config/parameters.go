@@ -0,0 +1,36 @@
+package config
+
+import "github.com/openshift/managed-cluster-validating-webhooks/pkg/config/layers"
+
+// Per-cluster parameters, rendered by Hive into the ParametersConfigMap from
+// ClusterDeployment labels, so fleet operators can tune the webhooks per
+// cluster tier. An unset label renders an empty value, which keeps the value
+// of the lower layers. Values set in the OverridesConfigMap take precedence.
+const (
+	// ParametersConfigMap is the ConfigMap in the webhook namespace rendered
+	// from the ClusterParameterLabels
+	ParametersConfigMap = layers.ParametersConfigMap
+
+	// ProductProfileEnvVar is the default of the -product-profile flag
+	ProductProfileEnvVar = "PRODUCT_PROFILE"
+	// ClusterProtectedNamespacesEnvVar adds namespaces to protect, like
+	// ProtectedNamespacesEnvVar. Label values can't hold regular expressions
+	// or commas, so it is a list of exact namespace names separated by dots,
+	// which namespace names can't contain.
+	ClusterProtectedNamespacesEnvVar = "CLUSTER_PROTECTED_NAMESPACES"
+	// LegalEntityIDEnvVar is the legal entity ID of the cluster owner, which
+	// namespacelabel-mutation labels customer namespaces with
+	LegalEntityIDEnvVar = "LEGAL_ENTITY_ID"
+)
+
+// ClusterParameterLabels are the ClusterDeployment labels rendered into the
+// ParametersConfigMap, by the environment variable they set
+var ClusterParameterLabels = map[string]string{
+	ProductProfileEnvVar:             "ext-managed.openshift.io/webhook-product-profile",
+	ClusterProtectedNamespacesEnvVar: "ext-managed.openshift.io/webhook-protected-namespaces",
+	// OCM labels every ClusterDeployment with the legal entity of its owner
+	LegalEntityIDEnvVar: "api.openshift.com/legal-entity-id",
+	// The default requests of the podresources-mutation webhook
+	"DEFAULT_CPU_REQUEST":    "ext-managed.openshift.io/webhook-default-cpu-request",
+	"DEFAULT_MEMORY_REQUEST": "ext-managed.openshift.io/webhook-default-memory-request",
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/config/layers"
+)
+
+// WebhookPlatformsEnvVar restricts webhooks to some clouds and regions, as
+// comma-separated <webhook>:<platforms> entries. Platforms are separated by
+// |, each an Infrastructure platform type optionally followed by a region
+// pattern, e.g. "podimageregistry-validation:AWS/us-gov-*|Azure". A listed
+// webhook only applies on the listed platforms, overriding its own default.
+// It is read from the OverridesConfigMap when it exists.
+const WebhookPlatformsEnvVar = "WEBHOOK_PLATFORMS"
+
+// PlatformRule is a cloud, and optionally its regions, a webhook applies on
+type PlatformRule struct {
+	// Platform is the Infrastructure platform type, e.g. AWS, matched
+	// case-insensitively
+	Platform string
+	// Region is a path.Match pattern of the regions, e.g. us-gov-*. Every
+	// region matches if it is empty.
+	Region string
+}
+
+// WebhookPlatforms are the PlatformRules configured by
+// WebhookPlatformsEnvVar, by webhook name
+var WebhookPlatforms = webhookPlatformsFromEnv()
+
+// webhookPlatformsFromEnv parses WebhookPlatformsEnvVar. An invalid entry is
+// reported with layers.ReportInvalid and ignored as a whole, leaving its
+// webhook on its default platforms, rather than stopping every webhook from
+// starting.
+func webhookPlatformsFromEnv() map[string][]PlatformRule {
+	platforms := map[string][]PlatformRule{}
+	for _, entry := range strings.Split(os.Getenv(WebhookPlatformsEnvVar), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		webhook, rules, err := parsePlatformEntry(entry)
+		if err != nil {
+			layers.ReportInvalid(WebhookPlatformsEnvVar, entry, err)
+			continue
+		}
+		platforms[webhook] = append(platforms[webhook], rules...)
+	}
+	return platforms
+}
+
+// parsePlatformEntry parses a <webhook>:<platforms> entry of
+// WebhookPlatformsEnvVar
+func parsePlatformEntry(entry string) (string, []PlatformRule, error) {
+	webhook, value, found := strings.Cut(entry, ":")
+	if !found || webhook == "" || value == "" {
+		return "", nil, fmt.Errorf("it must be <webhook>:<platform>[/<region>][|...]")
+	}
+	rules := []PlatformRule{}
+	for _, rule := range strings.Split(value, "|") {
+		platform, region, _ := strings.Cut(rule, "/")
+		if platform == "" {
+			return "", nil, fmt.Errorf("a platform is empty")
+		}
+		if _, err := path.Match(region, ""); err != nil {
+			return "", nil, fmt.Errorf("region pattern %q: %v", region, err)
+		}
+		rules = append(rules, PlatformRule{Platform: platform, Region: region})
+	}
+	return webhook, rules, nil
+}
+
+// Matches returns whether the rule applies on platform in region
+func (r PlatformRule) Matches(platform, region string) bool {
+	if !strings.EqualFold(r.Platform, platform) {
+		return false
+	}
+	if r.Region == "" {
+		return true
+	}
+	matched, _ := path.Match(r.Region, region)
+	return matched
+}
+
+// EnabledOnPlatform returns whether WebhookPlatforms enables webhook on
+// platform in region, and false for configured if it doesn't list webhook,
+// so the webhook's own default applies
+func EnabledOnPlatform(webhook, platform, region string) (enabled, configured bool) {
+	rules, configured := WebhookPlatforms[webhook]
+	for _, rule := range rules {
+		if rule.Matches(platform, region) {
+			return true, true
+		}
+	}
+	return false, configured
+}
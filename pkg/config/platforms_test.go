@@ -0,0 +1,54 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWebhookPlatformsFromEnv(t *testing.T) {
+	t.Setenv(WebhookPlatformsEnvVar, "podimageregistry-validation:AWS/us-gov-*|Azure, serviceinternallb-mutation:GCP")
+	expected := map[string][]PlatformRule{
+		"podimageregistry-validation": {{Platform: "AWS", Region: "us-gov-*"}, {Platform: "Azure"}},
+		"serviceinternallb-mutation":  {{Platform: "GCP"}},
+	}
+	if got := webhookPlatformsFromEnv(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for _, value := range []string{"podimageregistry-validation", "podimageregistry-validation:AWS|", "podimageregistry-validation:AWS/us-gov-["} {
+		t.Run(value, func(t *testing.T) {
+			t.Setenv(WebhookPlatformsEnvVar, value+",serviceinternallb-mutation:GCP")
+			expectInvalid(t, WebhookPlatformsEnvVar, value, func() {
+				expected := map[string][]PlatformRule{"serviceinternallb-mutation": {{Platform: "GCP"}}}
+				if got := webhookPlatformsFromEnv(); !reflect.DeepEqual(got, expected) {
+					t.Fatalf("Expected %q to be ignored, got %v", value, got)
+				}
+			})
+		})
+	}
+}
+
+func TestEnabledOnPlatform(t *testing.T) {
+	previous := WebhookPlatforms
+	t.Cleanup(func() { WebhookPlatforms = previous })
+	WebhookPlatforms = map[string][]PlatformRule{
+		"podimageregistry-validation": {{Platform: "AWS", Region: "us-gov-*"}, {Platform: "Azure"}},
+	}
+
+	tests := []struct {
+		webhook, platform, region string
+		enabled, configured       bool
+	}{
+		{webhook: "podimageregistry-validation", platform: "AWS", region: "us-gov-west-1", enabled: true, configured: true},
+		{webhook: "podimageregistry-validation", platform: "aws", region: "us-gov-east-1", enabled: true, configured: true},
+		{webhook: "podimageregistry-validation", platform: "AWS", region: "us-east-1", configured: true},
+		{webhook: "podimageregistry-validation", platform: "Azure", enabled: true, configured: true},
+		{webhook: "podimageregistry-validation", platform: "GCP", region: "us-east1", configured: true},
+		{webhook: "scc-validation", platform: "AWS", region: "us-east-1"},
+	}
+	for _, test := range tests {
+		enabled, configured := EnabledOnPlatform(test.webhook, test.platform, test.region)
+		if enabled != test.enabled || configured != test.configured {
+			t.Errorf("%s on %s/%s: Expected %v, %v, got %v, %v", test.webhook, test.platform, test.region, test.enabled, test.configured, enabled, configured)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/config/layers"
+)
+
+// ConfigPath is the URI reporting the effective configuration set by the
+// configuration layers. Callers must present a bearer token for a user
+// allowed to get this non-resource URL.
+const ConfigPath string = "/debug/config"
+
+// ConfigReport is the effective configuration and the layers it is merged
+// from, in increasing precedence. Invalid are the entries of settings which
+// were ignored.
+type ConfigReport struct {
+	Layers   []layers.Layer   `json:"layers"`
+	Settings []layers.Setting `json:"settings"`
+	Invalid  []layers.Invalid `json:"invalid,omitempty"`
+}
+
+// ConfigHandler serves the ConfigReport
+type ConfigHandler struct {
+	authorizer authorizer
+}
+
+// NewConfigHandler creates a ConfigHandler which authorizes callers against
+// the API server
+func NewConfigHandler() *ConfigHandler {
+	return &ConfigHandler{authorizer: newReviewAuthorizer()}
+}
+
+// ServeHTTP implements http.Handler
+func (h *ConfigHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !authorizeRequest(w, r, h.authorizer, ConfigPath) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(ConfigReport{Layers: layers.Layers, Settings: layers.Effective(), Invalid: layers.InvalidEntries()}); err != nil {
+		log.Error(err, "Failed to encode the effective configuration")
+	}
+}
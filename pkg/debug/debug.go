@@ -0,0 +1,247 @@
+package debug
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/k8sutil"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/ttlcache"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+)
+
+const (
+	// WebhooksPath is the URI listing the registered webhooks. Callers must
+	// present a bearer token for a user allowed to get this non-resource URL.
+	WebhooksPath string = "/debug/webhooks"
+
+	reviewTimeout = 5 * time.Second
+	// reviewCacheTTL is how long the TokenReviews and SubjectAccessReviews
+	// of a token are reused, so that a script polling the endpoints doesn't
+	// review its token on every request
+	reviewCacheTTL  = 10 * time.Second
+	reviewCacheSize = 1024
+)
+
+var log = logf.Log.WithName("debug")
+
+// WebhookInfo is the effective configuration of a registered webhook
+type WebhookInfo struct {
+	Name                 string                              `json:"name"`
+	Type                 string                              `json:"type"`
+	URI                  string                              `json:"uri"`
+	Rules                []admissionregv1.RuleWithOperations `json:"rules"`
+	FailurePolicy        admissionregv1.FailurePolicyType    `json:"failurePolicy"`
+	MatchPolicy          admissionregv1.MatchPolicyType      `json:"matchPolicy"`
+	SideEffects          admissionregv1.SideEffectClass      `json:"sideEffects"`
+	TimeoutSeconds       int32                               `json:"timeoutSeconds"`
+	ObjectSelector       *metav1.LabelSelector               `json:"objectSelector,omitempty"`
+	NamespaceSelector    *metav1.LabelSelector               `json:"namespaceSelector,omitempty"`
+	SyncSetLabelSelector metav1.LabelSelector                `json:"syncSetLabelSelector"`
+	ClassicEnabled       bool                                `json:"classicEnabled"`
+	HypershiftEnabled    bool                                `json:"hypershiftEnabled"`
+	Doc                  string                              `json:"doc"`
+}
+
+// authorizer decides whether a bearer token may read the debug endpoint at
+// path
+type authorizer interface {
+	authorize(ctx context.Context, token, path string) (bool, error)
+}
+
+// Handler serves the effective configuration of the registered webhooks
+type Handler struct {
+	hooks      webhooks.RegisteredWebhooks
+	authorizer authorizer
+}
+
+// NewHandler creates a Handler for hooks which authorizes callers against
+// the API server
+func NewHandler(hooks webhooks.RegisteredWebhooks) *Handler {
+	return &Handler{
+		hooks:      hooks,
+		authorizer: newReviewAuthorizer(),
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !authorizeRequest(w, r, h.authorizer, WebhooksPath) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(Webhooks(h.hooks)); err != nil {
+		log.Error(err, "Failed to encode registered webhooks")
+	}
+}
+
+// authorizeRequest checks r is a GET with a bearer token allowed to get
+// path, and writes the error response if it isn't
+func authorizeRequest(w http.ResponseWriter, r *http.Request, a authorizer, path string) bool {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		http.Error(w, "a bearer token is required", http.StatusUnauthorized)
+		return false
+	}
+	allowed, err := a.authorize(r.Context(), token, path)
+	if err != nil {
+		log.Error(err, "Failed to authorize debug request", "path", path)
+		http.Error(w, "failed to authorize the request", http.StatusInternalServerError)
+		return false
+	}
+	if !allowed {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// Webhooks returns the effective configuration of hooks, sorted by name
+func Webhooks(hooks webhooks.RegisteredWebhooks) []WebhookInfo {
+	infos := make([]WebhookInfo, 0, len(hooks))
+	for _, factory := range hooks {
+		hook := factory()
+		hookType := "Validating"
+		if strings.HasSuffix(hook.Name(), "-mutation") {
+			hookType = "Mutating"
+		}
+		infos = append(infos, WebhookInfo{
+			Name:                 hook.Name(),
+			Type:                 hookType,
+			URI:                  hook.GetURI(),
+			Rules:                hook.Rules(),
+			FailurePolicy:        hook.FailurePolicy(),
+			MatchPolicy:          hook.MatchPolicy(),
+			SideEffects:          hook.SideEffects(),
+			TimeoutSeconds:       hook.TimeoutSeconds(),
+			ObjectSelector:       hook.ObjectSelector(),
+			NamespaceSelector:    hook.NamespaceSelector(),
+			SyncSetLabelSelector: hook.SyncSetLabelSelector(),
+			ClassicEnabled:       hook.ClassicEnabled(),
+			HypershiftEnabled:    hook.HypershiftEnabled(),
+			Doc:                  hook.Doc(),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// reviewAuthorizer authenticates the token with a TokenReview and authorizes
+// the user with a SubjectAccessReview to get the requested non-resource URL.
+// The reviews are cached by the SHA-256 of the token when the caches are set.
+type reviewAuthorizer struct {
+	once       sync.Once
+	kubeClient client.Client
+	clientErr  error
+	users      *ttlcache.Cache[*authenticationv1.UserInfo]
+	decisions  *ttlcache.Cache[bool]
+}
+
+func newReviewAuthorizer() *reviewAuthorizer {
+	return &reviewAuthorizer{
+		users:     ttlcache.New[*authenticationv1.UserInfo](reviewCacheTTL, reviewCacheSize),
+		decisions: ttlcache.New[bool](reviewCacheTTL, reviewCacheSize),
+	}
+}
+
+func (a *reviewAuthorizer) client() (client.Client, error) {
+	a.once.Do(func() {
+		if a.kubeClient != nil {
+			return
+		}
+		scheme := runtime.NewScheme()
+		if err := authenticationv1.AddToScheme(scheme); err != nil {
+			a.clientErr = err
+			return
+		}
+		if err := authorizationv1.AddToScheme(scheme); err != nil {
+			a.clientErr = err
+			return
+		}
+		a.kubeClient, a.clientErr = k8sutil.KubeClient(scheme)
+	})
+	return a.kubeClient, a.clientErr
+}
+
+func (a *reviewAuthorizer) authorize(ctx context.Context, token, path string) (bool, error) {
+	kubeClient, err := a.client()
+	if err != nil {
+		return false, fmt.Errorf("fail creating KubeClient for debug endpoint: %v", err)
+	}
+	// Concurrent requests with the token share the reviews of the first, so
+	// they must not be canceled with it
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), reviewTimeout)
+	defer cancel()
+
+	sum := sha256.Sum256([]byte(token))
+	tokenKey := hex.EncodeToString(sum[:])
+	user, err := a.users.Get(tokenKey, func() (*authenticationv1.UserInfo, error) {
+		return reviewToken(ctx, kubeClient, token)
+	})
+	if err != nil || user == nil {
+		return false, err
+	}
+	return a.decisions.Get(tokenKey+" "+path, func() (bool, error) {
+		return reviewAccess(ctx, kubeClient, user, path)
+	})
+}
+
+// reviewToken returns the user of token, or nil if it isn't authenticated
+func reviewToken(ctx context.Context, kubeClient client.Client, token string) (*authenticationv1.UserInfo, error) {
+	tokenReview := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}
+	if err := kubeClient.Create(ctx, tokenReview); err != nil {
+		return nil, fmt.Errorf("failed to review token: %v", err)
+	}
+	if !tokenReview.Status.Authenticated {
+		return nil, nil
+	}
+	return &tokenReview.Status.User, nil
+}
+
+// reviewAccess returns whether user may get the non-resource URL path
+func reviewAccess(ctx context.Context, kubeClient client.Client, user *authenticationv1.UserInfo, path string) (bool, error) {
+	extra := map[string]authorizationv1.ExtraValue{}
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	accessReview := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+				Path: path,
+				Verb: "get",
+			},
+		},
+	}
+	if err := kubeClient.Create(ctx, accessReview); err != nil {
+		return false, fmt.Errorf("failed to review access: %v", err)
+	}
+	return accessReview.Status.Allowed, nil
+}
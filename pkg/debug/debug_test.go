@@ -0,0 +1,197 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/config/layers"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/pdbrelax"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/scc"
+)
+
+// reviewClient answers TokenReviews and SubjectAccessReviews the way the API
+// server would for a fixed set of tokens and users
+type reviewClient struct {
+	client.Client
+	users   map[string]string
+	allowed map[string]map[string]bool
+	// tokenReviews and accessReviews count the reviews
+	tokenReviews  int
+	accessReviews int
+}
+
+func (c *reviewClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	switch review := obj.(type) {
+	case *authenticationv1.TokenReview:
+		c.tokenReviews++
+		if user, ok := c.users[review.Spec.Token]; ok {
+			review.Status.Authenticated = true
+			review.Status.User = authenticationv1.UserInfo{Username: user}
+		}
+	case *authorizationv1.SubjectAccessReview:
+		c.accessReviews++
+		review.Status.Allowed = review.Spec.NonResourceAttributes != nil &&
+			c.allowed[review.Spec.User][review.Spec.NonResourceAttributes.Path] &&
+			review.Spec.NonResourceAttributes.Verb == "get"
+	}
+	return nil
+}
+
+func newTestHandler() *Handler {
+	hooks := webhooks.RegisteredWebhooks{
+		scc.WebhookName:      func() webhooks.Webhook { return scc.NewWebhook() },
+		pdbrelax.WebhookName: func() webhooks.Webhook { return pdbrelax.NewWebhook() },
+	}
+	handler := NewHandler(hooks)
+	handler.authorizer = newTestAuthorizer()
+	return handler
+}
+
+// newTestAuthorizer allows sre, but not dev, to get the debug endpoints
+func newTestAuthorizer() authorizer {
+	return &reviewAuthorizer{
+		kubeClient: &reviewClient{
+			Client: fake.NewClientBuilder().WithScheme(runtime.NewScheme()).Build(),
+			users:  map[string]string{"sre-token": "sre", "dev-token": "dev"},
+			allowed: map[string]map[string]bool{
+				"sre": {WebhooksPath: true, DenialsPath: true, ConfigPath: true},
+			},
+		},
+	}
+}
+
+func TestWebhooksEndpointAuthorization(t *testing.T) {
+	tests := []struct {
+		testID         string
+		method         string
+		token          string
+		expectedStatus int
+	}{
+		{
+			testID:         "authorized-user",
+			method:         http.MethodGet,
+			token:          "sre-token",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			testID:         "unauthorized-user",
+			method:         http.MethodGet,
+			token:          "dev-token",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			testID:         "unknown-token",
+			method:         http.MethodGet,
+			token:          "bogus",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			testID:         "no-token",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testID:         "post-not-allowed",
+			method:         http.MethodPost,
+			token:          "sre-token",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	handler := newTestHandler()
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, WebhooksPath, nil)
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.expectedStatus {
+			t.Fatalf("%s: Expected status %d, got %d: %s", test.testID, test.expectedStatus, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestWebhooksEndpointContent(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, WebhooksPath, nil)
+	req.Header.Set("Authorization", "Bearer sre-token")
+	rec := httptest.NewRecorder()
+	newTestHandler().ServeHTTP(rec, req)
+
+	infos := []WebhookInfo{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &infos); err != nil {
+		t.Fatalf("Expected a JSON list of webhooks, got %s: %v", rec.Body.String(), err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("Expected 2 webhooks, got %d", len(infos))
+	}
+	// Sorted by name
+	pdb, sccInfo := infos[0], infos[1]
+	if pdb.Name != pdbrelax.WebhookName || pdb.Type != "Mutating" || pdb.URI != "/"+pdbrelax.WebhookName {
+		t.Fatalf("Unexpected pdbrelax entry %+v", pdb)
+	}
+	if sccInfo.Name != scc.WebhookName || sccInfo.Type != "Validating" || len(sccInfo.Rules) == 0 || sccInfo.Doc == "" || sccInfo.TimeoutSeconds == 0 {
+		t.Fatalf("Unexpected scc entry %+v", sccInfo)
+	}
+	if !sccInfo.ClassicEnabled {
+		t.Fatalf("Expected scc to be enabled on Classic, got %+v", sccInfo)
+	}
+}
+
+func TestConfigEndpoint(t *testing.T) {
+	layers.ReportInvalid("WEBHOOK_ENFORCEMENT", "scc-validation:audit", errors.New("the mode must be Enforce or Audit"))
+	handler := &ConfigHandler{authorizer: newTestAuthorizer()}
+	for token, expected := range map[string]int{"sre-token": http.StatusOK, "dev-token": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodGet, ConfigPath, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != expected {
+			t.Fatalf("%s: Expected status %d, got %d", token, expected, rec.Code)
+		}
+		if expected != http.StatusOK {
+			continue
+		}
+		report := ConfigReport{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Expected a JSON config report, got %s: %v", rec.Body.String(), err)
+		}
+		if len(report.Layers) != 3 {
+			t.Fatalf("Expected the 3 configuration layers, got %+v", report.Layers)
+		}
+		if len(report.Invalid) != 1 || report.Invalid[0].Entry != "scc-validation:audit" {
+			t.Fatalf("Expected the invalid entry to be reported, got %+v", report.Invalid)
+		}
+	}
+}
+
+func TestReviewCache(t *testing.T) {
+	a := newReviewAuthorizer()
+	reviews := newTestAuthorizer().(*reviewAuthorizer).kubeClient.(*reviewClient)
+	a.kubeClient = reviews
+	for i := 0; i < 3; i++ {
+		for _, path := range []string{WebhooksPath, DenialsPath} {
+			if allowed, err := a.authorize(context.Background(), "sre-token", path); err != nil || !allowed {
+				t.Fatalf("expected sre to get %s, got %v, %v", path, allowed, err)
+			}
+		}
+		if allowed, err := a.authorize(context.Background(), "unknown-token", WebhooksPath); err != nil || allowed {
+			t.Fatalf("expected an unknown token to be forbidden, got %v, %v", allowed, err)
+		}
+	}
+	// One TokenReview of each token, and one SubjectAccessReview of each path
+	// of the authenticated one
+	if reviews.tokenReviews != 2 || reviews.accessReviews != 2 {
+		t.Errorf("expected the reviews to be cached, got %d TokenReviews and %d SubjectAccessReviews", reviews.tokenReviews, reviews.accessReviews)
+	}
+}
@@ -0,0 +1,152 @@
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/audit"
+)
+
+const (
+	// DenialsPath is the URI returning the most recent denials as JSON lines.
+	// Adding ?follow=true keeps the connection open and streams new denials.
+	// Callers must present a bearer token for a user allowed to get this
+	// non-resource URL.
+	DenialsPath string = "/debug/denials"
+	// DenialBufferSizeEnvVar is how many recent denials are kept in memory
+	DenialBufferSizeEnvVar string = "DEBUG_DENIAL_BUFFER_SIZE"
+
+	defaultDenialBufferSize = 200
+	// subscriberBuffer is how many denials a slow follower may fall behind
+	// before further denials are dropped for it
+	subscriberBuffer = 64
+)
+
+// DenialBuffer keeps the most recent denials in a ring buffer and serves
+// them at DenialsPath. It implements events.Recorder.
+type DenialBuffer struct {
+	mu          sync.Mutex
+	records     []audit.Record
+	next        int
+	full        bool
+	subscribers map[chan audit.Record]struct{}
+	authorizer  authorizer
+}
+
+// NewDenialBufferFromEnv creates a DenialBuffer sized by
+// DenialBufferSizeEnvVar which authorizes callers against the API server
+func NewDenialBufferFromEnv() (*DenialBuffer, error) {
+	size := defaultDenialBufferSize
+	if value := os.Getenv(DenialBufferSizeEnvVar); value != "" {
+		var err error
+		size, err = strconv.Atoi(value)
+		if err != nil || size < 1 {
+			return nil, fmt.Errorf("invalid %s %q, it must be a positive integer", DenialBufferSizeEnvVar, value)
+		}
+	}
+	return newDenialBuffer(size, newReviewAuthorizer()), nil
+}
+
+func newDenialBuffer(size int, a authorizer) *DenialBuffer {
+	return &DenialBuffer{
+		records:     make([]audit.Record, size),
+		subscribers: map[chan audit.Record]struct{}{},
+		authorizer:  a,
+	}
+}
+
+// RecordDenial implements events.Recorder
+func (b *DenialBuffer) RecordDenial(webhook string, request admissionctl.Request, resp admissionctl.Response) {
+	record := audit.NewRecord(webhook, request, resp)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.records[b.next] = record
+	b.next = (b.next + 1) % len(b.records)
+	if b.next == 0 {
+		b.full = true
+	}
+	for ch := range b.subscribers {
+		select {
+		case ch <- record:
+		default:
+		}
+	}
+}
+
+// Denials returns the buffered denials, oldest first
+func (b *DenialBuffer) Denials() []audit.Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.snapshot()
+}
+
+// snapshot must be called with mu held
+func (b *DenialBuffer) snapshot() []audit.Record {
+	if !b.full {
+		return append([]audit.Record{}, b.records[:b.next]...)
+	}
+	return append(append([]audit.Record{}, b.records[b.next:]...), b.records[:b.next]...)
+}
+
+// subscribe returns the buffered denials and a channel receiving every later
+// one, so no denial is missed or repeated in between
+func (b *DenialBuffer) subscribe() ([]audit.Record, chan audit.Record) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch := make(chan audit.Record, subscriberBuffer)
+	b.subscribers[ch] = struct{}{}
+	return b.snapshot(), ch
+}
+
+func (b *DenialBuffer) unsubscribe(ch chan audit.Record) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subscribers, ch)
+}
+
+// ServeHTTP implements http.Handler
+func (b *DenialBuffer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !authorizeRequest(w, r, b.authorizer, DenialsPath) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+
+	follow, _ := strconv.ParseBool(r.URL.Query().Get("follow"))
+	flusher, canFlush := w.(http.Flusher)
+	if !follow || !canFlush {
+		for _, record := range b.Denials() {
+			if err := encoder.Encode(record); err != nil {
+				log.Error(err, "Failed to encode denial")
+				return
+			}
+		}
+		return
+	}
+
+	records, ch := b.subscribe()
+	defer b.unsubscribe(ch)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case record := <-ch:
+			if err := encoder.Encode(record); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package debug
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/audit"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/scc"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+func recordDenial(b *DenialBuffer, user string) {
+	request := admissionctl.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Update,
+			UserInfo:  authenticationv1.UserInfo{Username: user},
+		},
+	}
+	b.RecordDenial(scc.WebhookName, request, utils.Denied(utils.ReasonSCCDefaultModify, "Modifying default SCCs is not allowed"))
+}
+
+func users(records []audit.Record) string {
+	names := []string{}
+	for _, record := range records {
+		names = append(names, record.User)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestDenialBufferWraps(t *testing.T) {
+	b := newDenialBuffer(3, newTestAuthorizer())
+	recordDenial(b, "a")
+	recordDenial(b, "b")
+	if got := users(b.Denials()); got != "a,b" {
+		t.Fatalf("Expected a,b, got %s", got)
+	}
+	recordDenial(b, "c")
+	recordDenial(b, "d")
+	recordDenial(b, "e")
+	if got := users(b.Denials()); got != "c,d,e" {
+		t.Fatalf("Expected the 3 most recent denials oldest first, got %s", got)
+	}
+}
+
+func TestDenialsEndpointDump(t *testing.T) {
+	b := newDenialBuffer(10, newTestAuthorizer())
+	recordDenial(b, "alice")
+	recordDenial(b, "bob")
+
+	for token, expectedStatus := range map[string]int{"sre-token": http.StatusOK, "dev-token": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodGet, DenialsPath, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		b.ServeHTTP(rec, req)
+		if rec.Code != expectedStatus {
+			t.Fatalf("%s: Expected status %d, got %d: %s", token, expectedStatus, rec.Code, rec.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, DenialsPath, nil)
+	req.Header.Set("Authorization", "Bearer sre-token")
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, req)
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 JSON lines, got %q", rec.Body.String())
+	}
+	record := audit.Record{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %s: %v", lines[0], err)
+	}
+	if record.User != "alice" || record.Webhook != scc.WebhookName || record.Code != string(utils.ReasonSCCDefaultModify) {
+		t.Fatalf("Unexpected record %+v", record)
+	}
+}
+
+func TestDenialsEndpointFollow(t *testing.T) {
+	b := newDenialBuffer(10, newTestAuthorizer())
+	recordDenial(b, "alice")
+	server := httptest.NewServer(b)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+DenialsPath+"?follow=true", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	req.Header.Set("Authorization", "Bearer sre-token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	next := func() audit.Record {
+		if !scanner.Scan() {
+			t.Fatalf("Expected another denial, got %v", scanner.Err())
+		}
+		record := audit.Record{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Expected a JSON record, got %s: %v", scanner.Text(), err)
+		}
+		return record
+	}
+	if record := next(); record.User != "alice" {
+		t.Fatalf("Expected the buffered denial first, got %+v", record)
+	}
+	recordDenial(b, "bob")
+	if record := next(); record.User != "bob" {
+		t.Fatalf("Expected the new denial to be streamed, got %+v", record)
+	}
+}
+
+func TestNewDenialBufferFromEnv(t *testing.T) {
+	t.Setenv(DenialBufferSizeEnvVar, "")
+	if b, err := NewDenialBufferFromEnv(); err != nil || len(b.records) != defaultDenialBufferSize {
+		t.Fatalf("Expected the default size, got %v", err)
+	}
+	t.Setenv(DenialBufferSizeEnvVar, "0")
+	if _, err := NewDenialBufferFromEnv(); err == nil {
+		t.Fatalf("Expected a zero %s to be an error", DenialBufferSizeEnvVar)
+	}
+}
@@ -0,0 +1,28 @@
+package dispatcher
+
+import (
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+)
+
+// dedupe returns the response decide makes to request of webhook. Requests
+// of webhook with the UID and operation of request received while it is
+// being decided, e.g. as the API server retries it, share its response
+// rather than evaluating it again, so that its events, audit records and
+// override redemptions happen once. It returns whether the response is that
+// of another request. Requests without a UID are always decided.
+func (d *Dispatcher) dedupe(webhook string, request admissionctl.Request, decide func() admissionctl.Response) (admissionctl.Response, bool) {
+	if request.UID == "" {
+		return decide(), false
+	}
+	led := false
+	v, _, _ := d.flights.Do(webhook+"/"+string(request.UID)+"/"+string(request.Operation), func() (interface{}, error) {
+		led = true
+		return decide(), nil
+	})
+	if !led {
+		localmetrics.IncrementDeduplicatedRequest(webhook)
+	}
+	return v.(admissionctl.Response), !led
+}
@@ -1,35 +1,453 @@
 package dispatcher
 
 import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand"
 	"net/http"
 	"net/url"
-	"sync"
+	"os"
+	"strconv"
+	"time"
 
+	"golang.org/x/sync/singleflight"
+	admissionv1 "k8s.io/api/admission/v1"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/audit"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/capture"
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/events"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/exemption"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/gvk"
 	responsehelper "github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/override"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/policy"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/servicelog"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/summary"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/tracing"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 )
 
+// AllowedLogSampleRateEnvVar is the fraction, between 0 and 1, of allowed
+// requests which are logged. Allowed requests aren't logged when it is unset.
+const AllowedLogSampleRateEnvVar string = "ALLOWED_REQUEST_LOG_SAMPLE_RATE"
+
 var log = logf.Log.WithName("dispatcher")
 
+// redeemer redeems the override token of a denied request, it is
+// implemented by *override.Verifier
+type redeemer interface {
+	Redeem(ctx context.Context, webhook string, request admissionctl.Request, code utils.ReasonCode) (*override.Token, error)
+}
+
 // Dispatcher struct
 type Dispatcher struct {
-	hooks *map[string]webhooks.WebhookFactory // uri -> hookfactory
-	mu    sync.Mutex
+	hooks     *map[string]webhooks.WebhookFactory // uri -> hookfactory
+	recorders []events.Recorder
+	// async records the denials off the admission path, it is one of the
+	// recorders
+	async  *events.AsyncRecorder
+	tracer *tracing.Tracer
+	// exemptions are the break-glass WebhookExemptions
+	exemptions *exemption.Store
+	// overrides redeems signed one-off override tokens, it is a nil
+	// *override.Verifier when they are disabled
+	overrides redeemer
+	// policies is the ValidatingWebhookPolicy of the cluster
+	policies *policy.Store
+	// allowedSampleRate is the fraction of allowed requests to log
+	allowedSampleRate float64
+	// capturer records sanitized requests as test fixtures, it is nil when
+	// capture is disabled
+	capturer *capture.Capturer
+	// limits bound the memory decoding a request takes, the zero limits
+	// don't
+	limits utils.DecodeLimits
+	// shedder bounds the requests in flight, it is nil when load shedding
+	// is disabled
+	shedder *loadShedder
+	// workers evaluate the requests of each webhook, it is nil when every
+	// request is evaluated as it is received
+	workers *workerPool
+	// flights evaluate identical requests received concurrently once
+	flights singleflight.Group
 }
 
-// NewDispatcher new dispatcher
-func NewDispatcher(hooks webhooks.RegisteredWebhooks) *Dispatcher {
+// NewDispatcher new dispatcher. Denials are recorded by the configured
+// recorders, off the admission path, and any extra recorders given, which
+// must be cheap as they are called before the response is sent.
+func NewDispatcher(hooks webhooks.RegisteredWebhooks, extraRecorders ...events.Recorder) *Dispatcher {
 	hookMap := make(map[string]webhooks.WebhookFactory)
-	for _, hook := range hooks {
+	hookNames := make([]string, 0, len(hooks))
+	for name, hook := range hooks {
 		hookMap[hook().GetURI()] = hook
+		hookNames = append(hookNames, name)
+	}
+	async := events.NewAsyncRecorder()
+	async.Add(localmetrics.SideEffectEvents, events.NewRecorder())
+	async.Add(localmetrics.SideEffectAuditLog, audit.NewLogRecorder())
+	recorders := append([]events.Recorder{async}, extraRecorders...)
+	sink, err := audit.NewSinkFromEnv()
+	if err != nil {
+		log.Error(err, "Failed to configure the audit sink, denial records will not be shipped")
+	} else if sink != nil {
+		log.Info("Shipping denial records", "sink", sink.Name())
+		async.Add(localmetrics.SideEffectAudit, audit.NewPipeline(sink))
+	}
+	reporter, err := summary.NewReporterFromEnv()
+	if err != nil {
+		log.Error(err, "Failed to configure the denial summary, it will not be published")
+	} else if reporter != nil {
+		async.Add(localmetrics.SideEffectSummary, reporter)
+	}
+	notifier, err := servicelog.NewNotifierFromEnv(hooks)
+	if err != nil {
+		log.Error(err, "Failed to configure service log notifications, repeated denials will not be notified")
+	} else if notifier != nil {
+		log.Info("Posting service logs for repeated denials")
+		async.Add(localmetrics.SideEffectServiceLog, notifier)
+	}
+	tracer, err := tracing.NewTracerFromEnv()
+	if err != nil {
+		log.Error(err, "Failed to configure tracing, admission requests will not be traced")
+	} else if tracer != nil {
+		log.Info("Exporting admission request traces")
+	}
+	capturer, err := capture.NewCapturerFromEnv()
+	if err != nil {
+		log.Error(err, "Failed to configure request capture, requests will not be captured")
+	} else if capturer != nil {
+		log.Info("Capturing sanitized requests", "dir", os.Getenv(capture.DirEnvVar), "filter", os.Getenv(capture.FilterEnvVar))
+	}
+	limits, err := utils.DecodeLimitsFromEnv()
+	if err != nil {
+		log.Error(err, "Failed to configure the decode limits, using the defaults", "limits", limits)
 	}
 	return &Dispatcher{
-		hooks: &hookMap,
+		hooks:             &hookMap,
+		recorders:         recorders,
+		async:             async,
+		tracer:            tracer,
+		exemptions:        exemption.NewStore(),
+		overrides:         override.NewVerifierFromEnv(),
+		policies:          policy.Start(hookNames),
+		allowedSampleRate: allowedSampleRateFromEnv(),
+		capturer:          capturer,
+		limits:            limits,
+		shedder:           newLoadShedderFromEnv(),
+		workers:           newWorkerPoolFromEnv(hooks),
+	}
+}
+
+// Close records the denials still queued, once the server stopped sending
+// requests to the Dispatcher, so they aren't lost when the pod shuts down
+func (d *Dispatcher) Close() {
+	if d.async != nil {
+		d.async.Close()
+	}
+}
+
+// allowedSampleRateFromEnv reads AllowedLogSampleRateEnvVar, disabling
+// sampling if it isn't a valid fraction
+func allowedSampleRateFromEnv() float64 {
+	value := os.Getenv(AllowedLogSampleRateEnvVar)
+	if value == "" {
+		return 0
+	}
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 || rate > 1 {
+		log.Info(fmt.Sprintf("Ignoring invalid %s, it must be between 0 and 1", AllowedLogSampleRateEnvVar), "value", value)
+		return 0
+	}
+	log.Info("Sampling allowed requests", "rate", rate)
+	return rate
+}
+
+// newCorrelationID returns a short random ID for a denial, short enough for
+// customers to paste to support
+func newCorrelationID() string {
+	b := make([]byte, 4)
+	if _, err := cryptorand.Read(b); err != nil {
+		return fmt.Sprintf("%08x", rand.Uint32())
+	}
+	return hex.EncodeToString(b)
+}
+
+// countingReader counts the bytes read from a request body
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// observeRequest records the outcome and duration of a request in the SLI
+// metrics, and whether it came close to the webhook's timeout
+func observeRequest(hook webhooks.Webhook, resp admissionctl.Response, start time.Time) {
+	duration := time.Since(start)
+	localmetrics.ObserveRequest(hook.Name(), resp, duration)
+	localmetrics.ObserveNearTimeout(hook.Name(), hook.TimeoutSeconds(), duration)
+}
+
+// recordMissingObjects counts requests missing the object or old object their
+// operation should carry
+func recordMissingObjects(webhook string, request admissionctl.Request) {
+	switch request.Operation {
+	case admissionv1.Create, admissionv1.Update:
+		if len(request.Object.Raw) == 0 {
+			localmetrics.IncrementMalformedRequest(webhook, localmetrics.MalformedMissingObject)
+		}
+	}
+	switch request.Operation {
+	case admissionv1.Update, admissionv1.Delete:
+		if len(request.OldObject.Raw) == 0 {
+			localmetrics.IncrementMalformedRequest(webhook, localmetrics.MalformedMissingOldObject)
+		}
+	}
+}
+
+// annotateDecision attaches the webhook name and decision to the audit
+// annotations of resp, so the cluster audit log records every guardrail
+// decision, including allowed requests with warnings
+func annotateDecision(webhook string, resp admissionctl.Response) admissionctl.Response {
+	var decision string
+	switch {
+	case resp.Allowed && len(resp.Warnings) > 0:
+		decision = utils.DecisionAllowedWithWarnings
+	case resp.Allowed:
+		decision = utils.DecisionAllowed
+	case localmetrics.IsDenied(resp):
+		decision = utils.DecisionDenied
+	default:
+		decision = utils.DecisionErrored
+	}
+	annotations := map[string]string{
+		utils.WebhookAuditAnnotation:  webhook,
+		utils.DecisionAuditAnnotation: decision,
+	}
+	for k, v := range resp.AuditAnnotations {
+		annotations[k] = v
+	}
+	resp.AuditAnnotations = annotations
+	return resp
+}
+
+// applyExemption allows request, which matched exemption e, without the
+// webhook evaluating it, so it is neither denied nor mutated. Every matched
+// request is logged.
+func applyExemption(webhook string, e *exemption.WebhookExemption, request admissionctl.Request) admissionctl.Response {
+	log.Info("Request matched webhook exemption",
+		"webhook", webhook,
+		"exemption", e.Name,
+		"exemptionReason", e.Spec.Reason,
+		"expiresAt", e.Spec.ExpiresAt,
+		"uid", request.UID,
+		"user", request.UserInfo.Username,
+		"groups", request.UserInfo.Groups,
+		"kind", request.Kind.Kind,
+		"operation", request.Operation,
+		"namespace", request.Namespace,
+		"name", request.Name,
+	)
+	exempted := utils.AllowWithWarning(request, fmt.Sprintf("Exempted from %s by WebhookExemption %s", webhook, e.Name),
+		fmt.Sprintf("%s doesn't check this request, it is exempted by WebhookExemption %s until %s", webhook, e.Name, e.Spec.ExpiresAt.UTC().Format(time.RFC3339)))
+	exempted.AuditAnnotations = map[string]string{utils.ExemptionAuditAnnotation: e.Name}
+	return exempted
+}
+
+// customizeDenialMessage replaces the message of the denial resp by webhook
+// with its configured DenialMessage, if it has one
+func customizeDenialMessage(webhook string, resp admissionctl.Response) admissionctl.Response {
+	if resp.Result == nil {
+		return resp
+	}
+	code, message := utils.DenialReason(resp)
+	custom, ok, err := hookconfig.RenderDenialMessage(webhook, code, message)
+	if err != nil {
+		log.Error(err, "Failed to render the customized denial message, keeping the webhook's message", "webhook", webhook)
+		return resp
+	}
+	if !ok {
+		return resp
+	}
+	result := *resp.Result
+	result.Message = custom
+	resp.Result = &result
+	return resp
+}
+
+// applyBreakGlass allows a denied request while the break-glass is active.
+// The denial has already been logged and recorded, so the audit trail keeps
+// every request the webhooks would have denied.
+func applyBreakGlass(webhook string, until time.Time, correlationID string, resp admissionctl.Response) admissionctl.Response {
+	_, reason := utils.DenialReason(resp)
+	expiry := until.UTC().Format(time.RFC3339)
+	log.Info("Allowing denied request during break-glass", "webhook", webhook, "correlationID", correlationID, "until", expiry)
+	allowed := utils.AllowDenied(resp, fmt.Sprintf("%s is audit-only during break-glass", webhook), utils.BreakGlassAuditAnnotation, expiry)
+	allowed.Warnings = append(allowed.Warnings, fmt.Sprintf("%s would have denied this request (%s), it is allowed by break-glass until %s", webhook, reason, expiry))
+	allowed.AuditAnnotations[utils.CorrelationIDAuditAnnotation] = correlationID
+	return allowed
+}
+
+// enforcementModeSource is the audit source of webhooks whose EnforcementMode
+// is hookconfig.EnforcementAudit
+const enforcementModeSource = "enforcement-mode"
+
+// auditSource returns what audits hook at now rather than enforcing it: the
+// ValidatingWebhookPolicy or its maintenance window, see
+// policy.Spec.AuditSource, else enforcementModeSource if the EnforcementMode
+// of hook is audit. It returns an empty string if hook is enforced.
+func auditSource(spec policy.Spec, hook webhooks.Webhook, now time.Time) string {
+	if source := spec.AuditSource(hook.Name(), now); source != "" {
+		return source
+	}
+	if webhooks.EnforcementMode(hook) == hookconfig.EnforcementAudit {
+		return enforcementModeSource
+	}
+	return ""
+}
+
+// applyAuditMode allows a denied request of a webhook in audit mode, source
+// being the policy, maintenance window or enforcement mode auditing it. The
+// request has already been logged, counted and recorded as one the webhook
+// would have denied.
+func applyAuditMode(webhook, source, correlationID string, resp admissionctl.Response) admissionctl.Response {
+	_, reason := utils.DenialReason(resp)
+	log.Info("Allowing denied request of audit mode webhook", "webhook", webhook, "source", source, "correlationID", correlationID)
+	allowed := utils.AllowDenied(resp, fmt.Sprintf("%s is in audit mode", webhook), utils.AuditModeAuditAnnotation, source)
+	allowed.Warnings = append(allowed.Warnings, fmt.Sprintf("%s would have denied this request (%s), it is allowed as the webhook is in audit mode (%s)", webhook, reason, source))
+	allowed.AuditAnnotations[utils.CorrelationIDAuditAnnotation] = correlationID
+	return allowed
+}
+
+// applyOverride allows request, which a webhook denied, with the redeemed
+// override token t
+func applyOverride(webhook string, t *override.Token, request admissionctl.Request, resp admissionctl.Response) admissionctl.Response {
+	code, reason := utils.DenialReason(resp)
+	log.Info("Allowing denied request with override token",
+		"webhook", webhook,
+		"code", code,
+		"nonce", t.Nonce,
+		"expiresAt", time.Unix(t.ExpiresAt, 0).UTC().Format(time.RFC3339),
+		"uid", request.UID,
+		"user", request.UserInfo.Username,
+		"groups", request.UserInfo.Groups,
+		"kind", request.Kind.Kind,
+		"operation", request.Operation,
+		"namespace", request.Namespace,
+		"name", request.Name,
+	)
+	allowed := utils.AllowDenied(resp, fmt.Sprintf("Overridden %s denial", webhook), utils.OverrideAuditAnnotation, t.Nonce)
+	allowed.Warnings = append(allowed.Warnings, fmt.Sprintf("%s would have denied this request (%s), it is allowed once by an override token", webhook, reason))
+	return allowed
+}
+
+// applyDeclarativeManager allows request, an unchanged re-apply by a
+// declarative manager, if the webhook denied it. Its actual changes are still
+// denied.
+func applyDeclarativeManager(webhook string, request admissionctl.Request, resp admissionctl.Response) admissionctl.Response {
+	if !localmetrics.IsDenied(resp) {
+		return resp
+	}
+	code, _ := utils.DenialReason(resp)
+	log.Info("Allowing unchanged re-apply from declarative manager",
+		"webhook", webhook,
+		"code", code,
+		"uid", request.UID,
+		"user", request.UserInfo.Username,
+		"kind", request.Kind.Kind,
+		"namespace", request.Namespace,
+		"name", request.Name,
+	)
+	return utils.AllowDenied(resp, fmt.Sprintf("%s re-applied %s unchanged", request.UserInfo.Username, request.Name), utils.DeclarativeManagerAuditAnnotation, request.UserInfo.Username)
+}
+
+// applyServiceAccountExemption allows request, made by a service account
+// exempted from the webhook by configuration, if the webhook denied it
+func applyServiceAccountExemption(webhook string, request admissionctl.Request, resp admissionctl.Response) admissionctl.Response {
+	if !localmetrics.IsDenied(resp) {
+		return resp
+	}
+	code, _ := utils.DenialReason(resp)
+	log.Info("Allowing request from exempted service account",
+		"webhook", webhook,
+		"code", code,
+		"uid", request.UID,
+		"user", request.UserInfo.Username,
+		"kind", request.Kind.Kind,
+		"operation", request.Operation,
+		"namespace", request.Namespace,
+		"name", request.Name,
+	)
+	return utils.AllowDenied(resp, fmt.Sprintf("%s is exempted from %s", request.UserInfo.Username, webhook), utils.ServiceAccountExemptionAuditAnnotation, request.UserInfo.Username)
+}
+
+// applyLabelExemption allows request, if it was denied, as source carries
+// the exemption label for webhook
+func applyLabelExemption(webhook, source string, request admissionctl.Request, resp admissionctl.Response) admissionctl.Response {
+	if !localmetrics.IsDenied(resp) {
+		return resp
+	}
+	code, _ := utils.DenialReason(resp)
+	log.Info("Allowing request exempted by label",
+		"webhook", webhook,
+		"code", code,
+		"source", source,
+		"uid", request.UID,
+		"user", request.UserInfo.Username,
+		"kind", request.Kind.Kind,
+		"operation", request.Operation,
+		"namespace", request.Namespace,
+		"name", request.Name,
+	)
+	return utils.AllowDenied(resp, fmt.Sprintf("%s is exempted from %s by label", source, webhook), utils.LabelExemptionAuditAnnotation, source)
+}
+
+// logAllowedSample logs a sample of allowed requests, so the traffic reaching
+// each webhook can be compared to what its rules and selectors should match.
+// Webhooks at the Debug log level of the ValidatingWebhookPolicy log every
+// request they allow.
+func (d *Dispatcher) logAllowedSample(webhook string, request admissionctl.Request, resp admissionctl.Response) {
+	if !resp.Allowed {
+		return
+	}
+	if hookLog := logf.Log.WithName(webhook).V(1); hookLog.Enabled() {
+		hookLog.Info("Allowed request", allowedRequestFields(webhook, request, resp)...)
+		return
+	}
+	if d.allowedSampleRate <= 0 || rand.Float64() >= d.allowedSampleRate {
+		return
+	}
+	log.Info("Sampled allowed request", allowedRequestFields(webhook, request, resp)...)
+}
+
+// allowedRequestFields are the fields logged for an allowed request
+func allowedRequestFields(webhook string, request admissionctl.Request, resp admissionctl.Response) []interface{} {
+	return []interface{}{
+		"webhook", webhook,
+		"uid", request.UID,
+		"user", request.UserInfo.Username,
+		"groups", request.UserInfo.Groups,
+		"group", request.Kind.Group,
+		"version", request.Kind.Version,
+		"kind", request.Kind.Kind,
+		"subResource", request.SubResource,
+		"operation", request.Operation,
+		"namespace", request.Namespace,
+		"name", request.Name,
+		"patched", len(resp.Patches) > 0,
 	}
 }
 
@@ -41,8 +459,16 @@ func NewDispatcher(hooks webhooks.RegisteredWebhooks) *Dispatcher {
 // request, or some internal problem) it is appropriate to use the HTTP status
 // code to communicate.
 func (d *Dispatcher) HandleRequest(w http.ResponseWriter, r *http.Request) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
+	// Time from before queuing, since waiting for a worker adds to the
+	// latency seen by the API server
+	start := time.Now()
+	// Requests past the ceiling are shed before they are queued, which is
+	// where an overload stalls them
+	if !d.shedder.acquire() {
+		d.shed(w, r, start)
+		return
+	}
+	defer d.shedder.release()
 	log.Info("Handling request", "request", r.RequestURI)
 	url, err := url.Parse(r.RequestURI)
 	if err != nil {
@@ -54,27 +480,9 @@ func (d *Dispatcher) HandleRequest(w http.ResponseWriter, r *http.Request) {
 
 	// is it one of ours?
 	if hook, ok := (*d.hooks)[url.Path]; ok {
-		// it's one of ours, so let's attempt to parse the request
-		request, _, err := utils.ParseHTTPRequest(r)
-		// Problem even parsing an AdmissionReview, so use HTTP status code
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			log.Error(err, "Error parsing HTTP Request Body")
-			responsehelper.SendResponse(w, admissionctl.Errored(http.StatusBadRequest, err))
-			return
+		if !d.workers.run(r.Context(), hook().Name(), func() { d.handle(w, r, hook, start) }) {
+			d.shedHook(w, r, hook(), start)
 		}
-		// Valid AdmissionReview, but we can't do anything with it because we do not
-		// think the request inside is valid.
-		if !hook().Validate(request) {
-			err = fmt.Errorf("not a valid webhook request")
-			log.Error(err, "Error validaing HTTP Request Body")
-			responsehelper.SendResponse(w,
-				admissionctl.Errored(http.StatusBadRequest, err))
-			return
-		}
-
-		// Dispatch
-		responsehelper.SendResponse(w, hook().Authorized(request))
 		return
 	}
 	log.Info("Request is not for a registered webhook.", "known_hooks", *d.hooks, "parsed_url", url, "lookup", (*d.hooks)[url.Path])
@@ -86,3 +494,180 @@ func (d *Dispatcher) HandleRequest(w http.ResponseWriter, r *http.Request) {
 		admissionctl.Errored(http.StatusBadRequest,
 			fmt.Errorf("request is not for a registered webhook")))
 }
+
+// handle evaluates r with hook, start being when r was received
+func (d *Dispatcher) handle(w http.ResponseWriter, r *http.Request, hook webhooks.WebhookFactory, start time.Time) {
+	ctx, span := d.tracer.Start(tracing.Extract(r.Context(), r.Header), "admission "+hook().Name(), tracing.SpanKindServer)
+	defer span.End()
+	span.SetAttribute("webhook", hook().Name())
+
+	// it's one of ours, so let's attempt to parse the request
+	_, decodeSpan := d.tracer.Start(ctx, "decode", tracing.SpanKindInternal)
+	var body *countingReader
+	if r.Body != nil {
+		body = &countingReader{ReadCloser: r.Body}
+		r.Body = body
+	}
+	request, _, err := utils.ParseHTTPRequestWithLimits(r, d.limits)
+	if body != nil {
+		localmetrics.ObserveRequestSize(hook().Name(), body.n)
+	}
+	if err != nil {
+		decodeSpan.SetError(err.Error())
+	}
+	decodeSpan.End()
+	// Problem even parsing an AdmissionReview, so use HTTP status code
+	if err != nil {
+		span.SetError(err.Error())
+		code, reason := int32(http.StatusBadRequest), localmetrics.MalformedReviewDecode
+		var limitErr *utils.DecodeLimitError
+		if errors.As(err, &limitErr) {
+			code, reason = http.StatusRequestEntityTooLarge, localmetrics.MalformedDecodeLimit
+		}
+		w.WriteHeader(int(code))
+		log.Error(err, "Error parsing HTTP Request Body")
+		resp := admissionctl.Errored(code, err)
+		localmetrics.IncrementMalformedRequest(hook().Name(), reason)
+		observeRequest(hook(), resp, start)
+		responsehelper.SendResponse(w, annotateDecision(hook().Name(), resp))
+		return
+	}
+	recordMissingObjects(hook().Name(), request)
+	// Webhooks matching every version of a kind decode its objects into
+	// the types of the preferred version
+	if normalized, converted, err := gvk.Normalize(request.AdmissionRequest, hook().Rules()); err != nil {
+		span.SetError(err.Error())
+		log.Error(err, "Error normalizing the version of the request", "webhook", hook().Name(), "kind", request.Kind)
+		resp := admissionctl.Errored(http.StatusBadRequest, err)
+		localmetrics.IncrementMalformedRequest(hook().Name(), localmetrics.MalformedConversion)
+		observeRequest(hook(), resp, start)
+		responsehelper.SendResponse(w, annotateDecision(hook().Name(), resp))
+		return
+	} else if converted {
+		span.SetAttribute("request_kind", request.Kind.String())
+		request.AdmissionRequest = normalized
+	}
+	span.SetAttribute("uid", string(request.UID))
+	span.SetAttribute("operation", string(request.Operation))
+	span.SetAttribute("resource", request.Resource.Resource)
+	span.SetAttribute("namespace", request.Namespace)
+
+	// Valid AdmissionReview, but we can't do anything with it because we do not
+	// think the request inside is valid.
+	_, validateSpan := d.tracer.Start(ctx, "validate", tracing.SpanKindInternal)
+	valid := hook().Validate(request)
+	validateSpan.End()
+	if !valid {
+		err = fmt.Errorf("not a valid webhook request")
+		span.SetError(err.Error())
+		log.Error(err, "Error validaing HTTP Request Body")
+		resp := admissionctl.Errored(http.StatusBadRequest, err)
+		localmetrics.IncrementMalformedRequest(hook().Name(), localmetrics.MalformedInvalid)
+		observeRequest(hook(), resp, start)
+		responsehelper.SendResponse(w, annotateDecision(hook().Name(), resp))
+		return
+	}
+
+	// Dispatch, once for identical requests received concurrently
+	resp, deduplicated := d.dedupe(hook().Name(), request, func() admissionctl.Response {
+		return d.decide(ctx, span, hook, request)
+	})
+	if deduplicated {
+		span.SetAttribute("deduplicated", true)
+	}
+	observeRequest(hook(), resp, start)
+	responsehelper.SendResponse(w, annotateDecision(hook().Name(), resp))
+}
+
+// decide evaluates request with hook, applying the exemptions, overrides and
+// policies to its response, and records the decision. The requests matching
+// a WebhookExemption aren't evaluated, so that mutating webhooks don't change
+// them either.
+func (d *Dispatcher) decide(ctx context.Context, span *tracing.Span, hook webhooks.WebhookFactory, request admissionctl.Request) admissionctl.Response {
+	if e := d.exemptions.Match(hook().Name(), request); e != nil {
+		span.SetAttribute("exemption", e.Name)
+		resp := applyExemption(hook().Name(), e, request)
+		d.logAllowedSample(hook().Name(), request, resp)
+		d.capturer.Capture(hook().Name(), request, resp)
+		return resp
+	}
+	_, authorizeSpan := d.tracer.Start(ctx, "authorize", tracing.SpanKindInternal)
+	start := time.Now()
+	resp := hook().Authorized(request)
+	localmetrics.ObserveEvaluation(hook().Name(), time.Since(start))
+	authorizeSpan.End()
+	span.SetAttribute("allowed", resp.Allowed)
+	if resp.Result != nil && resp.Result.Code >= http.StatusInternalServerError {
+		span.SetError(resp.Result.Message)
+	}
+	if !resp.Allowed && resp.Result != nil && resp.Result.Code == http.StatusBadRequest {
+		localmetrics.IncrementMalformedRequest(hook().Name(), localmetrics.MalformedObjectDecode)
+	}
+	if hookconfig.IsExemptServiceAccount(hook().Name(), request.UserInfo.Username) {
+		resp = applyServiceAccountExemption(hook().Name(), request, resp)
+	} else if hookconfig.IsDeclarativeManager(request.UserInfo.Username) && utils.IsUnchangedUpdate(request.AdmissionRequest) {
+		resp = applyDeclarativeManager(hook().Name(), request, resp)
+	} else if source := d.exemptions.MatchLabel(hook().Name(), request); source != "" {
+		span.SetAttribute("label_exemption", source)
+		resp = applyLabelExemption(hook().Name(), source, request, resp)
+	}
+	// The requests of a webhook in audit mode are never denied, so they are
+	// counted and recorded as requests it would have denied rather than as
+	// denials, and don't redeem override tokens. Nor do dry runs, which don't
+	// apply the change the token was minted for.
+	var source string
+	if localmetrics.IsDenied(resp) {
+		source = auditSource(d.policies.Spec(), hook(), time.Now())
+	}
+	if d.overrides != nil && localmetrics.IsDenied(resp) && source == "" && (request.DryRun == nil || !*request.DryRun) {
+		code, _ := utils.DenialReason(resp)
+		if t, err := d.overrides.Redeem(ctx, hook().Name(), request, code); err != nil {
+			log.Info("Ignoring override token", "webhook", hook().Name(), "uid", request.UID, "user", request.UserInfo.Username, "reason", err.Error())
+		} else if t != nil {
+			span.SetAttribute("override", t.Nonce)
+			resp = applyOverride(hook().Name(), t, request, resp)
+		}
+	}
+	if localmetrics.IsDenied(resp) {
+		resp = customizeDenialMessage(hook().Name(), resp)
+		correlationID := newCorrelationID()
+		resp = utils.WithCorrelationID(resp, correlationID)
+		code, _ := utils.DenialReason(resp)
+		message := "Denied request"
+		if source != "" {
+			message = "Would have denied request"
+			resp = utils.WithAuditSource(resp, source)
+		}
+		log.Info(message,
+			"webhook", hook().Name(),
+			"correlationID", correlationID,
+			"code", code,
+			"auditSource", source,
+			"uid", request.UID,
+			"user", request.UserInfo.Username,
+			"kind", request.Kind.Kind,
+			"operation", request.Operation,
+			"namespace", request.Namespace,
+			"name", request.Name,
+		)
+		span.SetAttribute("correlation_id", correlationID)
+		if source != "" {
+			span.SetAttribute("audit_mode", source)
+			localmetrics.IncrementWouldHaveDeniedRequest(hook().Name(), source)
+		} else {
+			localmetrics.IncrementDeniedRequest(hook().Name(), request, string(code))
+		}
+		for _, recorder := range d.recorders {
+			recorder.RecordDenial(hook().Name(), request, resp)
+		}
+		if source != "" {
+			resp = applyAuditMode(hook().Name(), source, correlationID, resp)
+		} else if until, active := d.exemptions.BreakGlass(); active {
+			span.SetAttribute("break_glass", true)
+			resp = applyBreakGlass(hook().Name(), until, correlationID, resp)
+		}
+	}
+	d.logAllowedSample(hook().Name(), request, resp)
+	d.capturer.Capture(hook().Name(), request, resp)
+	return resp
+}
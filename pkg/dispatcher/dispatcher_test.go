@@ -0,0 +1,811 @@
+package dispatcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/events"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/exemption"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/override"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/policy"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/customresourcedefinitions"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/imagecontentpolicies"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/namespace"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/pod"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/scc"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+func TestAllowedSampleRateFromEnv(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected float64
+	}{
+		{value: "", expected: 0},
+		{value: "0.01", expected: 0.01},
+		{value: "1", expected: 1},
+		{value: "1.5", expected: 0},
+		{value: "-0.1", expected: 0},
+		{value: "one-percent", expected: 0},
+	}
+	for _, test := range tests {
+		t.Setenv(AllowedLogSampleRateEnvVar, test.value)
+		if rate := allowedSampleRateFromEnv(); rate != test.expected {
+			t.Fatalf("Expected %q to give a sample rate of %v, got %v", test.value, test.expected, rate)
+		}
+	}
+}
+
+func TestRecordMissingObjects(t *testing.T) {
+	raw := runtime.RawExtension{Raw: []byte(`{}`)}
+	tests := []struct {
+		testID             string
+		request            admissionv1.AdmissionRequest
+		expectedMissing    float64
+		expectedMissingOld float64
+	}{
+		{
+			testID:  "create-with-object",
+			request: admissionv1.AdmissionRequest{Operation: admissionv1.Create, Object: raw},
+		},
+		{
+			testID:          "create-without-object",
+			request:         admissionv1.AdmissionRequest{Operation: admissionv1.Create},
+			expectedMissing: 1,
+		},
+		{
+			testID:             "update-without-old-object",
+			request:            admissionv1.AdmissionRequest{Operation: admissionv1.Update, Object: raw},
+			expectedMissingOld: 1,
+		},
+		{
+			testID:             "delete-without-old-object",
+			request:            admissionv1.AdmissionRequest{Operation: admissionv1.Delete},
+			expectedMissingOld: 1,
+		},
+		{
+			testID:  "connect-without-objects",
+			request: admissionv1.AdmissionRequest{Operation: admissionv1.Connect},
+		},
+	}
+	for _, test := range tests {
+		webhook := "malformed-" + test.testID
+		recordMissingObjects(webhook, admissionctl.Request{AdmissionRequest: test.request})
+		if got := testutil.ToFloat64(localmetrics.MetricMalformedRequests.WithLabelValues(webhook, localmetrics.MalformedMissingObject)); got != test.expectedMissing {
+			t.Fatalf("%s: Expected %v missing objects, got %v", test.testID, test.expectedMissing, got)
+		}
+		if got := testutil.ToFloat64(localmetrics.MetricMalformedRequests.WithLabelValues(webhook, localmetrics.MalformedMissingOldObject)); got != test.expectedMissingOld {
+			t.Fatalf("%s: Expected %v missing old objects, got %v", test.testID, test.expectedMissingOld, got)
+		}
+	}
+}
+
+func TestAnnotateDecision(t *testing.T) {
+	tests := []struct {
+		testID           string
+		resp             admissionctl.Response
+		expectedDecision string
+		expectedCode     string
+	}{
+		{
+			testID:           "allowed",
+			resp:             admissionctl.Allowed("ok"),
+			expectedDecision: utils.DecisionAllowed,
+		},
+		{
+			testID:           "allowed-with-warnings",
+			resp:             admissionctl.Allowed("ok").WithWarnings("tolerationSeconds was capped"),
+			expectedDecision: utils.DecisionAllowedWithWarnings,
+		},
+		{
+			testID:           "denied",
+			resp:             utils.Denied(utils.ReasonSCCDefaultModify, "Modifying default SCCs is not allowed"),
+			expectedDecision: utils.DecisionDenied,
+			expectedCode:     string(utils.ReasonSCCDefaultModify),
+		},
+		{
+			testID:           "errored",
+			resp:             admissionctl.Errored(http.StatusBadRequest, fmt.Errorf("bad object")),
+			expectedDecision: utils.DecisionErrored,
+		},
+	}
+	for _, test := range tests {
+		resp := annotateDecision("scc-validation", test.resp)
+		if got := resp.AuditAnnotations[utils.WebhookAuditAnnotation]; got != "scc-validation" {
+			t.Fatalf("%s: Expected the webhook annotation to be scc-validation, got %q", test.testID, got)
+		}
+		if got := resp.AuditAnnotations[utils.DecisionAuditAnnotation]; got != test.expectedDecision {
+			t.Fatalf("%s: Expected decision %s, got %q", test.testID, test.expectedDecision, got)
+		}
+		if got := resp.AuditAnnotations[utils.ReasonCodeAuditAnnotation]; got != test.expectedCode {
+			t.Fatalf("%s: Expected reason code %q, got %q", test.testID, test.expectedCode, got)
+		}
+	}
+}
+
+func TestApplyExemption(t *testing.T) {
+	e := &exemption.WebhookExemption{
+		ObjectMeta: metav1.ObjectMeta{Name: "break-glass"},
+		Spec: exemption.WebhookExemptionSpec{
+			Webhook:   "scc-validation",
+			Users:     []string{"alice"},
+			ExpiresAt: metav1.NewTime(time.Now().Add(time.Hour)),
+			Reason:    "OHSS-1234",
+		},
+	}
+	request := admissionctl.Request{AdmissionRequest: admissionv1.AdmissionRequest{UID: "exempted"}}
+
+	resp := applyExemption("scc-validation", e, request)
+	if !resp.Allowed || resp.UID != "exempted" || len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], "break-glass") {
+		t.Fatalf("Expected the request to be allowed with a warning, got %+v", resp)
+	}
+	if len(resp.Patches) != 0 || resp.PatchType != nil {
+		t.Fatalf("Expected the exempted request not to be mutated, got %+v", resp.Patches)
+	}
+	if resp.AuditAnnotations[utils.ExemptionAuditAnnotation] != "break-glass" {
+		t.Fatalf("Expected the exemption to be annotated, got %v", resp.AuditAnnotations)
+	}
+	if got := annotateDecision("scc-validation", resp).AuditAnnotations[utils.DecisionAuditAnnotation]; got != utils.DecisionAllowedWithWarnings {
+		t.Fatalf("Expected decision %s, got %q", utils.DecisionAllowedWithWarnings, got)
+	}
+}
+
+func TestApplyServiceAccountExemption(t *testing.T) {
+	request := admissionctl.Request{}
+	request.UserInfo.Username = "system:serviceaccount:openshift-gitops:argocd-application-controller"
+
+	resp := applyServiceAccountExemption("scc-validation", request, utils.Denied(utils.ReasonSCCDefaultModify, "Modifying default SCCs is not allowed"))
+	if !resp.Allowed || len(resp.Warnings) != 0 {
+		t.Fatalf("Expected the denial to be allowed without a warning, got %+v", resp)
+	}
+	if resp.AuditAnnotations[utils.ServiceAccountExemptionAuditAnnotation] != request.UserInfo.Username || resp.AuditAnnotations[utils.ReasonCodeAuditAnnotation] != string(utils.ReasonSCCDefaultModify) {
+		t.Fatalf("Expected the service account and reason code to be annotated, got %v", resp.AuditAnnotations)
+	}
+
+	errored := admissionctl.Errored(http.StatusBadRequest, fmt.Errorf("bad object"))
+	if resp := applyServiceAccountExemption("scc-validation", request, errored); resp.Allowed {
+		t.Fatalf("Expected errors not to be exempted, got %+v", resp)
+	}
+}
+
+func TestApplyDeclarativeManager(t *testing.T) {
+	request := admissionctl.Request{}
+	request.UserInfo.Username = "system:serviceaccount:openshift-gitops:openshift-gitops-argocd-application-controller"
+	request.Name = "anyuid"
+
+	resp := applyDeclarativeManager("scc-validation", request, utils.Denied(utils.ReasonSCCDefaultModify, "Modifying default SCCs is not allowed"))
+	if !resp.Allowed {
+		t.Fatalf("Expected the denial to be allowed, got %+v", resp)
+	}
+	if resp.AuditAnnotations[utils.DeclarativeManagerAuditAnnotation] != request.UserInfo.Username || resp.AuditAnnotations[utils.ReasonCodeAuditAnnotation] != string(utils.ReasonSCCDefaultModify) {
+		t.Fatalf("Expected the declarative manager and reason code to be annotated, got %v", resp.AuditAnnotations)
+	}
+
+	errored := admissionctl.Errored(http.StatusBadRequest, fmt.Errorf("bad object"))
+	if resp := applyDeclarativeManager("scc-validation", request, errored); resp.Allowed {
+		t.Fatalf("Expected errors not to be allowed, got %+v", resp)
+	}
+}
+
+func TestApplyLabelExemption(t *testing.T) {
+	request := admissionctl.Request{}
+	request.Namespace = "addon-certified"
+
+	resp := applyLabelExemption("pod-validation", "namespace/addon-certified", request, utils.Denied(utils.ReasonPodInfraNoScheduleToleration, "Not allowed to schedule a pod with NoSchedule taint on infra node"))
+	if !resp.Allowed {
+		t.Fatalf("Expected the denial to be allowed, got %+v", resp)
+	}
+	if resp.AuditAnnotations[utils.LabelExemptionAuditAnnotation] != "namespace/addon-certified" || resp.AuditAnnotations[utils.ReasonCodeAuditAnnotation] != string(utils.ReasonPodInfraNoScheduleToleration) {
+		t.Fatalf("Expected the label source and reason code to be annotated, got %v", resp.AuditAnnotations)
+	}
+
+	allowed := admissionctl.Allowed("")
+	if resp := applyLabelExemption("pod-validation", "namespace/addon-certified", request, allowed); len(resp.AuditAnnotations) != 0 {
+		t.Fatalf("Expected allowed requests to be left alone, got %+v", resp)
+	}
+}
+
+func TestApplyBreakGlass(t *testing.T) {
+	until := time.Date(2023, 5, 1, 18, 0, 0, 0, time.UTC)
+	resp := applyBreakGlass("scc-validation", until, "a1b2c3d4", utils.Denied(utils.ReasonSCCDefaultModify, "Modifying default SCCs is not allowed"))
+	if !resp.Allowed || len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], "2023-05-01T18:00:00Z") {
+		t.Fatalf("Expected the denial to be allowed with a warning, got %+v", resp)
+	}
+	expected := map[string]string{
+		utils.BreakGlassAuditAnnotation:    "2023-05-01T18:00:00Z",
+		utils.CorrelationIDAuditAnnotation: "a1b2c3d4",
+		utils.ReasonCodeAuditAnnotation:    string(utils.ReasonSCCDefaultModify),
+	}
+	if !reflect.DeepEqual(resp.AuditAnnotations, expected) {
+		t.Fatalf("Expected audit annotations %v, got %v", expected, resp.AuditAnnotations)
+	}
+}
+
+func TestApplyAuditMode(t *testing.T) {
+	resp := applyAuditMode("scc-validation", "maintenance/upgrades", "a1b2c3d4", utils.Denied(utils.ReasonSCCDefaultModify, "Modifying default SCCs is not allowed"))
+	if !resp.Allowed || len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], "audit mode") {
+		t.Fatalf("Expected the denial to be allowed with a warning, got %+v", resp)
+	}
+	expected := map[string]string{
+		utils.AuditModeAuditAnnotation:     "maintenance/upgrades",
+		utils.CorrelationIDAuditAnnotation: "a1b2c3d4",
+		utils.ReasonCodeAuditAnnotation:    string(utils.ReasonSCCDefaultModify),
+	}
+	if !reflect.DeepEqual(resp.AuditAnnotations, expected) {
+		t.Fatalf("Expected audit annotations %v, got %v", expected, resp.AuditAnnotations)
+	}
+}
+
+// auditedHook is the scc webhook with an EnforcementMode
+type auditedHook struct {
+	webhooks.Webhook
+	mode hookconfig.EnforcementMode
+}
+
+func (a auditedHook) EnforcementMode() hookconfig.EnforcementMode { return a.mode }
+
+func TestAuditSource(t *testing.T) {
+	previous := hookconfig.WebhookEnforcement
+	t.Cleanup(func() { hookconfig.WebhookEnforcement = previous })
+	hookconfig.WebhookEnforcement = map[string]hookconfig.EnforcementMode{}
+
+	audited := auditedHook{Webhook: scc.NewWebhook(), mode: hookconfig.EnforcementAudit}
+	auditPolicy := policy.Spec{Webhooks: []policy.WebhookPolicy{{Name: scc.WebhookName, Mode: policy.ModeAudit}}}
+	tests := []struct {
+		name       string
+		spec       policy.Spec
+		hook       webhooks.Webhook
+		configured hookconfig.EnforcementMode
+		expected   string
+	}{
+		{name: "enforced", hook: scc.NewWebhook()},
+		{name: "policy", spec: auditPolicy, hook: scc.NewWebhook(), expected: policy.Name},
+		{name: "policy before enforcement mode", spec: auditPolicy, hook: audited, expected: policy.Name},
+		{name: "enforcement mode", hook: audited, expected: enforcementModeSource},
+		{name: "configured enforcement mode", hook: scc.NewWebhook(), configured: hookconfig.EnforcementAudit, expected: enforcementModeSource},
+		{name: "configured over enforcement mode", hook: audited, configured: hookconfig.EnforcementEnforce},
+	}
+	for _, test := range tests {
+		delete(hookconfig.WebhookEnforcement, scc.WebhookName)
+		if test.configured != "" {
+			hookconfig.WebhookEnforcement[scc.WebhookName] = test.configured
+		}
+		if got := auditSource(test.spec, test.hook, time.Now()); got != test.expected {
+			t.Errorf("%s: Expected audit source %q, got %q", test.name, test.expected, got)
+		}
+	}
+}
+
+// responseRecorder keeps the responses of the denials it records
+type responseRecorder struct {
+	responses []admissionctl.Response
+}
+
+func (r *responseRecorder) RecordDenial(_ string, _ admissionctl.Request, resp admissionctl.Response) {
+	r.responses = append(r.responses, resp)
+}
+
+func TestHandleRequestAuditMode(t *testing.T) {
+	previous := hookconfig.WebhookEnforcement
+	t.Cleanup(func() { hookconfig.WebhookEnforcement = previous })
+	hookconfig.WebhookEnforcement = map[string]hookconfig.EnforcementMode{scc.WebhookName: hookconfig.EnforcementAudit}
+
+	factory := webhooks.Webhooks[scc.WebhookName]
+	uri := factory().GetURI()
+	recorder := &responseRecorder{}
+	d := &Dispatcher{hooks: &map[string]webhooks.WebhookFactory{uri: factory}, recorders: []events.Recorder{recorder}}
+	denied := localmetrics.MetricDeniedRequests.WithLabelValues(scc.WebhookName, string(utils.ReasonSCCDefaultDelete))
+	deniedBefore := testutil.ToFloat64(denied)
+	audited := localmetrics.MetricWouldHaveDeniedRequests.WithLabelValues(scc.WebhookName, enforcementModeSource)
+	auditedBefore := testutil.ToFloat64(audited)
+
+	kind := metav1.GroupVersionKind{Group: "security.openshift.io", Version: "v1", Kind: "SecurityContextConstraints"}
+	resource := metav1.GroupVersionResource{Group: "security.openshift.io", Version: "v1", Resource: "securitycontextconstraints"}
+	object := &runtime.RawExtension{Raw: []byte(`{"metadata": {"name": "anyuid"}}`)}
+	body, err := testutils.CreateFakeRequestJSON("audited", kind, resource, admissionv1.Delete, "alice", []string{"system:authenticated"}, "", object, object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, uri, bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	d.HandleRequest(w, r)
+	review := admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(w.Body.Bytes(), &review); err != nil || review.Response == nil {
+		t.Fatalf("expected an AdmissionReview, got %s, %v", w.Body.String(), err)
+	}
+	if !review.Response.Allowed {
+		t.Fatalf("expected the request to be allowed in audit mode, got %+v", review.Response)
+	}
+	if got := testutil.ToFloat64(denied); got != deniedBefore {
+		t.Errorf("expected the request not to be counted as denied, got %v denials", got-deniedBefore)
+	}
+	if got := testutil.ToFloat64(audited); got != auditedBefore+1 {
+		t.Errorf("expected the request to be counted as one which would have been denied, got %v", got-auditedBefore)
+	}
+	if len(recorder.responses) != 1 || utils.AuditSource(recorder.responses[0]) != enforcementModeSource {
+		t.Errorf("expected the request to be recorded as audited by %s, got %+v", enforcementModeSource, recorder.responses)
+	}
+}
+
+func TestApplyOverride(t *testing.T) {
+	token := &override.Token{Webhook: "scc-validation", Nonce: "0123456789abcdef", ExpiresAt: time.Now().Add(time.Minute).Unix()}
+	resp := applyOverride("scc-validation", token, admissionctl.Request{}, utils.Denied(utils.ReasonSCCDefaultModify, "Modifying default SCCs is not allowed"))
+	if !resp.Allowed || len(resp.Warnings) != 1 {
+		t.Fatalf("Expected the denial to be allowed with a warning, got %+v", resp)
+	}
+	if resp.AuditAnnotations[utils.OverrideAuditAnnotation] != token.Nonce || resp.AuditAnnotations[utils.ReasonCodeAuditAnnotation] != string(utils.ReasonSCCDefaultModify) {
+		t.Fatalf("Expected the override and reason code to be annotated, got %v", resp.AuditAnnotations)
+	}
+}
+
+// fakeRedeemer redeems every override token, counting the redemptions
+type fakeRedeemer struct {
+	redeemed int
+}
+
+func (f *fakeRedeemer) Redeem(_ context.Context, webhook string, _ admissionctl.Request, _ utils.ReasonCode) (*override.Token, error) {
+	f.redeemed++
+	return &override.Token{Webhook: webhook, Nonce: "0123456789abcdef", ExpiresAt: time.Now().Add(time.Minute).Unix()}, nil
+}
+
+func TestDecideRedeemsOverrides(t *testing.T) {
+	previous := hookconfig.WebhookEnforcement
+	t.Cleanup(func() { hookconfig.WebhookEnforcement = previous })
+
+	dryRun := true
+	tests := []struct {
+		name         string
+		dryRun       *bool
+		audit        bool
+		wantRedeemed int
+	}{
+		{name: "denied request", wantRedeemed: 1},
+		{name: "dry run", dryRun: &dryRun},
+		{name: "audit mode", audit: true},
+	}
+	for _, test := range tests {
+		hookconfig.WebhookEnforcement = map[string]hookconfig.EnforcementMode{}
+		if test.audit {
+			hookconfig.WebhookEnforcement[scc.WebhookName] = hookconfig.EnforcementAudit
+		}
+		redeemer := &fakeRedeemer{}
+		d := &Dispatcher{overrides: redeemer}
+		object := runtime.RawExtension{Raw: []byte(`{"metadata": {"name": "anyuid"}}`)}
+		request := admissionctl.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			UID:       "override",
+			Kind:      metav1.GroupVersionKind{Group: "security.openshift.io", Version: "v1", Kind: "SecurityContextConstraints"},
+			Resource:  metav1.GroupVersionResource{Group: "security.openshift.io", Version: "v1", Resource: "securitycontextconstraints"},
+			Name:      "anyuid",
+			Operation: admissionv1.Delete,
+			UserInfo:  authenticationv1.UserInfo{Username: "alice", Groups: []string{"system:authenticated"}},
+			Object:    object,
+			OldObject: object,
+			DryRun:    test.dryRun,
+		}}
+		resp := d.decide(context.Background(), nil, webhooks.Webhooks[scc.WebhookName], request)
+		if redeemer.redeemed != test.wantRedeemed {
+			t.Errorf("%s: Expected %d redemptions, got %d", test.name, test.wantRedeemed, redeemer.redeemed)
+		}
+		if overridden := resp.AuditAnnotations[utils.OverrideAuditAnnotation] != ""; overridden != (test.wantRedeemed > 0) {
+			t.Errorf("%s: Expected the request to be overridden %t, got %+v", test.name, test.wantRedeemed > 0, resp)
+		}
+	}
+}
+
+func TestCustomizeDenialMessage(t *testing.T) {
+	messages, err := hookconfig.ParseDenialMessages(`{"scc-validation": {"docsURL": "https://example.com/kb/scc"}}`)
+	if err != nil {
+		t.Fatalf("Expected the denial messages to parse, got %v", err)
+	}
+	previous := hookconfig.DenialMessages
+	defer func() { hookconfig.DenialMessages = previous }()
+	hookconfig.DenialMessages = messages
+
+	denied := utils.Denied(utils.ReasonSCCDefaultModify, "Modifying default SCCs is not allowed.")
+	resp := customizeDenialMessage("scc-validation", denied)
+	if expected := "Modifying default SCCs is not allowed. See https://example.com/kb/scc"; resp.Result.Message != expected {
+		t.Fatalf("Expected message %q, got %q", expected, resp.Result.Message)
+	}
+	if code, _ := utils.DenialReason(resp); code != utils.ReasonSCCDefaultModify {
+		t.Fatalf("Expected the reason code to be kept, got %q", code)
+	}
+	if denied.Result.Message != "Modifying default SCCs is not allowed." {
+		t.Fatalf("Expected the original denial not to be modified, got %q", denied.Result.Message)
+	}
+	if resp := customizeDenialMessage("node-validation", denied); resp.Result.Message != denied.Result.Message {
+		t.Fatalf("Expected webhooks without a customized message to keep theirs, got %q", resp.Result.Message)
+	}
+}
+
+func TestCountingReader(t *testing.T) {
+	body := &countingReader{ReadCloser: io.NopCloser(strings.NewReader(`{"kind":"AdmissionReview"}`))}
+	if _, err := io.ReadAll(body); err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	if body.n != 26 {
+		t.Fatalf("Expected 26 bytes to be counted, got %d", body.n)
+	}
+}
+
+func TestNewCorrelationID(t *testing.T) {
+	id := newCorrelationID()
+	if len(id) != 8 || id == newCorrelationID() {
+		t.Fatalf("Expected a random 8 character ID, got %q", id)
+	}
+}
+
+func TestHandleRequestNormalizesVersions(t *testing.T) {
+	factory := webhooks.Webhooks[customresourcedefinitions.WebhookName]
+	uri := factory().GetURI()
+	d := &Dispatcher{hooks: &map[string]webhooks.WebhookFactory{uri: factory}}
+	kind := metav1.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1beta1", Kind: "CustomResourceDefinition"}
+	resource := metav1.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1beta1", Resource: "customresourcedefinitions"}
+	tests := []struct {
+		name    string
+		object  string
+		allowed bool
+		code    int32
+	}{
+		{
+			name:   "protected",
+			object: `{"apiVersion": "apiextensions.k8s.io/v1beta1", "kind": "CustomResourceDefinition", "metadata": {"name": "prometheusrules.monitoring.coreos.com"}, "spec": {"version": "v1", "validation": {"openAPIV3Schema": {"type": "object"}}}}`,
+			code:   http.StatusForbidden,
+		},
+		{
+			name:    "unprotected",
+			object:  `{"apiVersion": "apiextensions.k8s.io/v1beta1", "kind": "CustomResourceDefinition", "metadata": {"name": "foos.example.com"}, "spec": {"version": "v1"}}`,
+			allowed: true,
+			code:    http.StatusOK,
+		},
+		{
+			name:   "unconvertible",
+			object: `{"apiVersion": "apiextensions.k8s.io/v1beta1", "kind": "CustomResourceDefinition", "spec": {"versions": ["v1"]}}`,
+			code:   http.StatusBadRequest,
+		},
+	}
+	for _, test := range tests {
+		body, err := testutils.CreateFakeRequestJSON(test.name, kind, resource, admissionv1.Create, "alice", []string{"system:authenticated"}, "", &runtime.RawExtension{Raw: []byte(test.object)}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest(http.MethodPost, uri, bytes.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		d.HandleRequest(w, r)
+		review := admissionv1.AdmissionReview{}
+		if err := json.Unmarshal(w.Body.Bytes(), &review); err != nil || review.Response == nil {
+			t.Fatalf("%s: expected an AdmissionReview, got %s, %v", test.name, w.Body.String(), err)
+		}
+		if review.Response.Allowed != test.allowed || (review.Response.Result != nil && review.Response.Result.Code != test.code) {
+			t.Errorf("%s: expected allowed %v and code %d, got %+v", test.name, test.allowed, test.code, review.Response)
+		}
+	}
+}
+
+// discardResponseWriter is an http.ResponseWriter dropping the response, so
+// that benchmarks only measure the handling of the request
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
+
+func BenchmarkHandleRequest(b *testing.B) {
+	benchmarks := []struct {
+		name      string
+		webhook   string
+		gvk       metav1.GroupVersionKind
+		gvr       metav1.GroupVersionResource
+		operation admissionv1.Operation
+		namespace string
+		object    *runtime.RawExtension
+	}{
+		{
+			name:      "pod-create",
+			webhook:   pod.WebhookName,
+			gvk:       metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			gvr:       metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+			operation: admissionv1.Create,
+			namespace: "my-project",
+			object:    testutils.RepresentativeObject(testutils.RepresentativePod, "frontend-7c9d8b6f4d-x2x9z", "my-project"),
+		},
+		{
+			name:      "namespace-create",
+			webhook:   namespace.WebhookName,
+			gvk:       metav1.GroupVersionKind{Version: "v1", Kind: "Namespace"},
+			gvr:       metav1.GroupVersionResource{Version: "v1", Resource: "namespaces"},
+			operation: admissionv1.Create,
+			object:    testutils.RepresentativeObject(testutils.RepresentativeNamespace, "my-project", ""),
+		},
+		{
+			name:      "default-scc-update-denied",
+			webhook:   scc.WebhookName,
+			gvk:       metav1.GroupVersionKind{Group: "security.openshift.io", Version: "v1", Kind: "SecurityContextConstraints"},
+			gvr:       metav1.GroupVersionResource{Group: "security.openshift.io", Version: "v1", Resource: "securitycontextconstraints"},
+			operation: admissionv1.Update,
+			object:    testutils.RepresentativeObject(testutils.RepresentativeSCC, "anyuid", ""),
+		},
+	}
+	for _, bm := range benchmarks {
+		factory := webhooks.Webhooks[bm.webhook]
+		uri := factory().GetURI()
+		d := &Dispatcher{hooks: &map[string]webhooks.WebhookFactory{uri: factory}}
+		body, err := testutils.CreateFakeRequestJSON(bm.name, bm.gvk, bm.gvr, bm.operation, "alice", []string{"dedicated-admins", "system:authenticated"}, bm.namespace, bm.object, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		r := httptest.NewRequest(http.MethodPost, uri, nil)
+		r.Header.Set("Content-Type", "application/json")
+		w := &discardResponseWriter{header: http.Header{}}
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+			for i := 0; i < b.N; i++ {
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				d.HandleRequest(w, r)
+			}
+		})
+	}
+}
+
+func TestHandleRequestDecodeLimits(t *testing.T) {
+	factory := webhooks.Webhooks[customresourcedefinitions.WebhookName]
+	uri := factory().GetURI()
+	d := &Dispatcher{
+		hooks:  &map[string]webhooks.WebhookFactory{uri: factory},
+		limits: utils.DecodeLimits{MaxBodyBytes: 4096},
+	}
+	kind := metav1.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}
+	resource := metav1.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	object := fmt.Sprintf(`{"metadata": {"name": "foos.example.com", "annotations": {"a": %q}}}`, strings.Repeat("a", 4096))
+	body, err := testutils.CreateFakeRequestJSON("large", kind, resource, admissionv1.Create, "alice", []string{"system:authenticated"}, "", &runtime.RawExtension{Raw: []byte(object)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, uri, bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	d.HandleRequest(w, r)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected a 413, got %d: %s", w.Code, w.Body.String())
+	}
+	review := admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(w.Body.Bytes(), &review); err != nil || review.Response == nil || review.Response.Allowed {
+		t.Fatalf("expected a rejecting AdmissionReview, got %s, %v", w.Body.String(), err)
+	}
+}
+
+func TestHandleRequestShedsLoad(t *testing.T) {
+	sccHook := webhooks.Webhooks[scc.WebhookName]
+	icspHook := webhooks.Webhooks[imagecontentpolicies.WebhookName]
+	shedder := &loadShedder{max: 1}
+	d := &Dispatcher{
+		hooks:   &map[string]webhooks.WebhookFactory{sccHook().GetURI(): sccHook, icspHook().GetURI(): icspHook},
+		shedder: shedder,
+	}
+	if !shedder.acquire() {
+		t.Fatal("expected the first request to be acquired")
+	}
+	kind := metav1.GroupVersionKind{Group: "security.openshift.io", Version: "v1", Kind: "SecurityContextConstraints"}
+	resource := metav1.GroupVersionResource{Group: "security.openshift.io", Version: "v1", Resource: "securitycontextconstraints"}
+	body, err := testutils.CreateFakeRequestJSON("shed-uid", kind, resource, admissionv1.Update, "alice", []string{"system:authenticated"}, "", &runtime.RawExtension{Raw: []byte(`{"metadata": {"name": "anyuid"}}`)}, &runtime.RawExtension{Raw: []byte(`{"metadata": {"name": "anyuid"}}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// scc-validation ignores failures, so it is allowed without evaluation
+	allowedBefore := testutil.ToFloat64(localmetrics.MetricShedRequests.WithLabelValues(scc.WebhookName, localmetrics.ShedAllowed))
+	r := httptest.NewRequest(http.MethodPost, sccHook().GetURI(), bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	d.HandleRequest(w, r)
+	review := admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(w.Body.Bytes(), &review); err != nil || review.Response == nil {
+		t.Fatalf("expected an AdmissionReview, got %s, %v", w.Body.String(), err)
+	}
+	if w.Code != http.StatusOK || !review.Response.Allowed || review.Response.UID != "shed-uid" || review.Response.AuditAnnotations[utils.LoadShedAuditAnnotation] != "true" {
+		t.Errorf("expected the shed request to be allowed, got %d %+v", w.Code, review.Response)
+	}
+	if got := testutil.ToFloat64(localmetrics.MetricShedRequests.WithLabelValues(scc.WebhookName, localmetrics.ShedAllowed)); got != allowedBefore+1 {
+		t.Errorf("expected the shed request to be counted, got %v", got)
+	}
+
+	// imagecontentpolicies-validation fails closed, so it is rejected
+	r = httptest.NewRequest(http.MethodPost, icspHook().GetURI(), bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	d.HandleRequest(w, r)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the shed request to be rejected with a 429, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Once the load drops, requests are evaluated again
+	shedder.release()
+	r = httptest.NewRequest(http.MethodPost, sccHook().GetURI(), bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	d.HandleRequest(w, r)
+	review = admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(w.Body.Bytes(), &review); err != nil || review.Response == nil || review.Response.AuditAnnotations[utils.LoadShedAuditAnnotation] != "" {
+		t.Errorf("expected the request to be evaluated, got %s, %v", w.Body.String(), err)
+	}
+}
+
+func TestWorkerPool(t *testing.T) {
+	p := &workerPool{queues: map[string]chan *job{"slow": make(chan *job, 1), "fast": make(chan *job, 1)}}
+	for name, queue := range p.queues {
+		go work(name, queue)
+		defer close(queue)
+	}
+	ctx := context.Background()
+
+	// The worker of slow is busy and its queue full
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go p.run(ctx, "slow", func() { close(started); <-release })
+	<-started
+	queued := make(chan bool)
+	go func() { queued <- p.run(ctx, "slow", func() {}) }()
+	for testutil.ToFloat64(localmetrics.MetricQueueDepth.WithLabelValues("slow")) < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if p.run(ctx, "slow", func() {}) {
+		t.Error("expected a request to a full queue to be rejected")
+	}
+
+	// Other webhooks aren't delayed
+	ran := false
+	if !p.run(ctx, "fast", func() { ran = true }) || !ran {
+		t.Error("expected the request of another webhook to run")
+	}
+	// Webhooks without a queue run inline
+	ran = false
+	if !p.run(ctx, "unknown", func() { ran = true }) || !ran {
+		t.Error("expected the request of a webhook without a queue to run")
+	}
+	close(release)
+	if !<-queued {
+		t.Error("expected the queued request to run once the worker is free")
+	}
+
+	// Panics are raised in the handler rather than stopping the worker
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != "boom" {
+				t.Errorf("expected the panic to be raised in the handler, got %v", recovered)
+			}
+		}()
+		p.run(ctx, "fast", func() { panic("boom") })
+	}()
+	ran = false
+	if !p.run(ctx, "fast", func() { ran = true }) || !ran {
+		t.Error("expected the worker to keep running after a panic")
+	}
+
+	// Requests whose client is gone aren't run
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	ran = false
+	if !p.run(cancelled, "fast", func() { ran = true }) || ran {
+		t.Error("expected the request of a gone client not to run")
+	}
+}
+
+func TestHandleRequestConcurrently(t *testing.T) {
+	factory := webhooks.Webhooks[scc.WebhookName]
+	uri := factory().GetURI()
+	t.Setenv(WorkersEnvVar, "4")
+	d := &Dispatcher{
+		hooks:   &map[string]webhooks.WebhookFactory{uri: factory},
+		workers: newWorkerPoolFromEnv(webhooks.RegisteredWebhooks{scc.WebhookName: factory}),
+	}
+	kind := metav1.GroupVersionKind{Group: "security.openshift.io", Version: "v1", Kind: "SecurityContextConstraints"}
+	resource := metav1.GroupVersionResource{Group: "security.openshift.io", Version: "v1", Resource: "securitycontextconstraints"}
+	object := &runtime.RawExtension{Raw: []byte(`{"metadata": {"name": "anyuid"}}`)}
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			uid := fmt.Sprintf("uid-%d", i)
+			body, err := testutils.CreateFakeRequestJSON(uid, kind, resource, admissionv1.Delete, "alice", []string{"system:authenticated"}, "", object, object)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			r := httptest.NewRequest(http.MethodPost, uri, bytes.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			d.HandleRequest(w, r)
+			review := admissionv1.AdmissionReview{}
+			if err := json.Unmarshal(w.Body.Bytes(), &review); err != nil || review.Response == nil {
+				t.Errorf("expected an AdmissionReview, got %s, %v", w.Body.String(), err)
+				return
+			}
+			if review.Response.UID != types.UID(uid) || review.Response.Allowed {
+				t.Errorf("expected the deletion of a default SCC to be denied, got %+v", review.Response)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestDedupe(t *testing.T) {
+	d := &Dispatcher{}
+	request := admissionctl.Request{AdmissionRequest: admissionv1.AdmissionRequest{UID: "a", Operation: admissionv1.Delete}}
+	var calls atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	decide := func() admissionctl.Response {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		return admissionctl.Denied("denied")
+	}
+	var wg sync.WaitGroup
+	var led atomic.Int32
+	run := func() {
+		defer wg.Done()
+		resp, deduplicated := d.dedupe("scc-validation", request, decide)
+		if !deduplicated {
+			led.Add(1)
+		}
+		if resp.Allowed || resp.Result.Reason != "denied" {
+			t.Errorf("expected the decision to be shared, got %+v", resp)
+		}
+	}
+	wg.Add(1)
+	go run()
+	<-started
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go run()
+	}
+
+	// Other operations, webhooks and requests without a UID are decided
+	// while the first request is
+	for _, other := range []struct {
+		webhook string
+		request admissionctl.Request
+	}{
+		{webhook: "scc-validation", request: admissionctl.Request{AdmissionRequest: admissionv1.AdmissionRequest{UID: "a", Operation: admissionv1.Update}}},
+		{webhook: "namespace-validation", request: request},
+		{webhook: "scc-validation", request: admissionctl.Request{}},
+	} {
+		if _, deduplicated := d.dedupe(other.webhook, other.request, decide); deduplicated {
+			t.Fatalf("expected %s %+v to be decided", other.webhook, other.request)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if got := calls.Load() - 3; got != led.Load() || got >= 9 {
+		t.Fatalf("expected the concurrent requests to be decided once, got %d decisions", got)
+	}
+}
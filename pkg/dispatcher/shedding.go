@@ -0,0 +1,133 @@
+package dispatcher
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/types"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	responsehelper "github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	// MaxInFlightEnvVar is the number of requests being handled or waiting
+	// to be after which requests are shed, 0 disabling shedding
+	MaxInFlightEnvVar string = "MAX_IN_FLIGHT_REQUESTS"
+	// defaultMaxInFlight is the ceiling when MaxInFlightEnvVar is unset
+	defaultMaxInFlight = 256
+)
+
+// loadShedder bounds the requests in flight, i.e. being handled or waiting
+// for the dispatcher. A nil loadShedder doesn't.
+type loadShedder struct {
+	max      int64
+	inFlight atomic.Int64
+}
+
+// newLoadShedderFromEnv returns the loadShedder MaxInFlightEnvVar configures,
+// nil if it disables shedding
+func newLoadShedderFromEnv() *loadShedder {
+	max := int64(defaultMaxInFlight)
+	if value := os.Getenv(MaxInFlightEnvVar); value != "" {
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || n < 0 {
+			log.Info(fmt.Sprintf("Ignoring invalid %s, it must be a non-negative number", MaxInFlightEnvVar), "value", value, "default", max)
+		} else {
+			max = n
+		}
+	}
+	if max == 0 {
+		log.Info("Load shedding is disabled")
+		return nil
+	}
+	return &loadShedder{max: max}
+}
+
+// acquire counts a request in flight, unless the ceiling is reached. A
+// request acquired must be released.
+func (l *loadShedder) acquire() bool {
+	if l == nil {
+		return true
+	}
+	if n := l.inFlight.Add(1); n > l.max {
+		l.inFlight.Add(-1)
+		return false
+	}
+	localmetrics.MetricInFlightRequests.Inc()
+	return true
+}
+
+func (l *loadShedder) release() {
+	if l == nil {
+		return
+	}
+	l.inFlight.Add(-1)
+	localmetrics.MetricInFlightRequests.Dec()
+}
+
+// shed answers r, received while too many requests were in flight, without
+// evaluating it
+func (d *Dispatcher) shed(w http.ResponseWriter, r *http.Request, start time.Time) {
+	var hook webhooks.WebhookFactory
+	if u, err := url.Parse(r.RequestURI); err == nil {
+		hook = (*d.hooks)[u.Path]
+	}
+	if hook == nil {
+		w.WriteHeader(http.StatusTooManyRequests)
+		responsehelper.SendResponse(w, admissionctl.Errored(http.StatusTooManyRequests, fmt.Errorf("the webhooks are overloaded")))
+		return
+	}
+	d.shedHook(w, r, hook(), start)
+}
+
+// shedHook answers r of webhook without evaluating it. The API server ignores
+// the errors of webhooks with FailurePolicy=Ignore, so their requests are
+// allowed directly, sparing the API server a failed call. The requests of the
+// other webhooks are rejected with a 429, failing the call so that the API
+// server denies them rather than letting an overload bypass the webhook.
+func (d *Dispatcher) shedHook(w http.ResponseWriter, r *http.Request, webhook webhooks.Webhook, start time.Time) {
+	if webhook.FailurePolicy() != admissionregv1.Ignore {
+		log.Info("Rejecting request of overloaded webhook", "webhook", webhook.Name())
+		localmetrics.IncrementShedRequest(webhook.Name(), localmetrics.ShedRejected)
+		resp := admissionctl.Errored(http.StatusTooManyRequests, fmt.Errorf("%s is overloaded, retry later", webhook.Name()))
+		observeRequest(webhook, resp, start)
+		w.WriteHeader(http.StatusTooManyRequests)
+		responsehelper.SendResponse(w, annotateDecision(webhook.Name(), resp))
+		return
+	}
+	resp := admissionctl.Allowed(fmt.Sprintf("%s is overloaded", webhook.Name()))
+	resp.UID = types.UID(shedRequestUID(r, d.limits))
+	resp.AuditAnnotations = map[string]string{utils.LoadShedAuditAnnotation: "true"}
+	localmetrics.IncrementShedRequest(webhook.Name(), localmetrics.ShedAllowed)
+	observeRequest(webhook, resp, start)
+	responsehelper.SendResponse(w, annotateDecision(webhook.Name(), resp))
+}
+
+// shedRequestUID returns the UID of the AdmissionReview of r, read without
+// decoding it
+func shedRequestUID(r *http.Request, limits utils.DecodeLimits) string {
+	if r.Body == nil {
+		return ""
+	}
+	var body io.Reader = r.Body
+	if limits.MaxBodyBytes > 0 {
+		body = io.LimitReader(r.Body, limits.MaxBodyBytes)
+	}
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return ""
+	}
+	uid, _ := utils.StringField(raw, "request", "uid")
+	return uid
+}
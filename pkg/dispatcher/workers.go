@@ -0,0 +1,131 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+)
+
+const (
+	// WorkersEnvVar is the number of requests each webhook evaluates at
+	// once, 0 evaluating every request as it is received
+	WorkersEnvVar string = "WEBHOOK_WORKERS"
+	// QueueSizeEnvVar is the number of requests of each webhook waiting for
+	// a worker, after which its requests are shed
+	QueueSizeEnvVar string = "WEBHOOK_QUEUE_SIZE"
+	defaultWorkers         = 4
+	defaultQueue           = 64
+)
+
+// workerPool evaluates the requests of each webhook with its own workers and
+// queue, so that a slow webhook only delays its own requests, and the
+// goroutines evaluating requests are bounded. A nil workerPool runs every
+// request as it is received.
+type workerPool struct {
+	queues map[string]chan *job
+}
+
+// job is a request waiting for a worker
+type job struct {
+	ctx      context.Context
+	run      func()
+	enqueued time.Time
+	done     chan struct{}
+	// panicked is what run panicked with, re-raised by the handler so that
+	// the HTTP server handles it as before
+	panicked interface{}
+}
+
+// newWorkerPoolFromEnv returns the workerPool of hooks, which WorkersEnvVar
+// and QueueSizeEnvVar configure, nil if WorkersEnvVar disables it
+func newWorkerPoolFromEnv(hooks webhooks.RegisteredWebhooks) *workerPool {
+	workers := intFromEnv(WorkersEnvVar, defaultWorkers)
+	if workers == 0 {
+		log.Info("The worker pool is disabled, requests are evaluated as they are received")
+		return nil
+	}
+	size := intFromEnv(QueueSizeEnvVar, defaultQueue)
+	p := &workerPool{queues: make(map[string]chan *job, len(hooks))}
+	for _, hook := range hooks {
+		name := hook().Name()
+		queue := make(chan *job, size)
+		p.queues[name] = queue
+		for i := 0; i < workers; i++ {
+			go work(name, queue)
+		}
+	}
+	log.Info("Started the worker pool", "webhooks", len(hooks), "workers", workers, "queue", size)
+	return p
+}
+
+// intFromEnv reads the non-negative number envVar sets, def if it's unset or
+// invalid
+func intFromEnv(envVar string, def int) int {
+	value := os.Getenv(envVar)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Info(fmt.Sprintf("Ignoring invalid %s, it must be a non-negative number", envVar), "value", value, "default", def)
+		return def
+	}
+	return n
+}
+
+// run queues run for a worker of webhook and waits until it ran. It returns
+// false without running it if the queue of the webhook is full. A request
+// whose context is done while it waits isn't run, its client being gone.
+func (p *workerPool) run(ctx context.Context, webhook string, run func()) bool {
+	queue, ok := p.queue(webhook)
+	if !ok {
+		run()
+		return true
+	}
+	j := &job{ctx: ctx, run: run, enqueued: time.Now(), done: make(chan struct{})}
+	select {
+	case queue <- j:
+	default:
+		return false
+	}
+	localmetrics.MetricQueueDepth.WithLabelValues(webhook).Inc()
+	<-j.done
+	if j.panicked != nil {
+		panic(j.panicked)
+	}
+	return true
+}
+
+func (p *workerPool) queue(webhook string) (chan *job, bool) {
+	if p == nil {
+		return nil, false
+	}
+	queue, ok := p.queues[webhook]
+	return queue, ok
+}
+
+// work runs the jobs of queue, the queue of webhook
+func work(webhook string, queue chan *job) {
+	for j := range queue {
+		localmetrics.MetricQueueDepth.WithLabelValues(webhook).Dec()
+		localmetrics.ObserveQueueTime(webhook, time.Since(j.enqueued))
+		if j.ctx.Err() == nil {
+			runJob(j)
+		}
+		close(j.done)
+	}
+}
+
+// runJob runs j, recovering from its panics so that they don't stop the
+// worker
+func runJob(j *job) {
+	defer func() {
+		j.panicked = recover()
+	}()
+	j.run()
+}
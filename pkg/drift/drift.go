@@ -0,0 +1,347 @@
+// Package drift compares the webhook configurations on a cluster with the
+// ones the registry of this binary generates, for fleet health checks
+package drift
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/sizing"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+// configurationPrefix prefixes the names of the webhook configurations
+const configurationPrefix = "sre-"
+
+// Kind is a kind of drift
+type Kind string
+
+const (
+	// KindMissing is a registered webhook without a configuration
+	KindMissing Kind = "missing"
+	// KindUnexpected is a configuration of no registered webhook, e.g. one
+	// left behind by a removed webhook
+	KindUnexpected Kind = "unexpected"
+	// KindChanged is a field of a configuration differing from the registry
+	KindChanged Kind = "changed"
+	// KindCABundle is a CA bundle which doesn't verify the serving
+	// certificate of the webhooks
+	KindCABundle Kind = "ca-bundle"
+)
+
+// Finding is a drift of a webhook configuration
+type Finding struct {
+	Webhook       string `json:"webhook,omitempty"`
+	Configuration string `json:"configuration"`
+	Kind          Kind   `json:"kind"`
+	// Field is the changed field of the webhook, e.g. rules
+	Field    string `json:"field,omitempty"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Options select the expected webhook configurations
+type Options struct {
+	Profile    utils.Profile
+	Compliance utils.Compliance
+	// FailurePolicy returns the expected failure policy of a webhook,
+	// e.g. the one of an environment overlay. The webhook's own by default.
+	FailurePolicy func(webhook string, def admissionregv1.FailurePolicyType) admissionregv1.FailurePolicyType
+	// Size is the cluster size the webhooks were rendered for, scaling
+	// their timeouts
+	Size sizing.Size
+	// Exclude are webhooks which aren't deployed
+	Exclude []string
+	// Namespace, Service and Secret are where the webhooks are served and
+	// their serving certificate
+	Namespace string
+	Service   string
+	Secret    string
+}
+
+// spec is the part of a validating or mutating webhook the registry sets
+type spec struct {
+	Rules             []admissionregv1.RuleWithOperations `json:"rules"`
+	FailurePolicy     admissionregv1.FailurePolicyType    `json:"failurePolicy"`
+	MatchPolicy       admissionregv1.MatchPolicyType      `json:"matchPolicy"`
+	SideEffects       admissionregv1.SideEffectClass      `json:"sideEffects"`
+	TimeoutSeconds    int32                               `json:"timeoutSeconds"`
+	ObjectSelector    metav1.LabelSelector                `json:"objectSelector"`
+	NamespaceSelector metav1.LabelSelector                `json:"namespaceSelector"`
+}
+
+// live is a webhook of a configuration on the cluster
+type live struct {
+	name         string
+	spec         spec
+	clientConfig admissionregv1.WebhookClientConfig
+}
+
+// Verify returns the drift of the webhook configurations read with c from
+// the webhooks of hooks
+func Verify(ctx context.Context, c client.Client, hooks webhooks.RegisteredWebhooks, opts Options) ([]Finding, error) {
+	if opts.FailurePolicy == nil {
+		opts.FailurePolicy = func(_ string, def admissionregv1.FailurePolicyType) admissionregv1.FailurePolicyType { return def }
+	}
+	configurations, err := list(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	servingCert, certErr := servingCertificate(ctx, c, opts)
+
+	findings := []Finding{}
+	expected := map[string]bool{}
+	names := make([]string, 0, len(hooks))
+	for name := range hooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		hook := hooks[name]()
+		if slices.Contains(opts.Exclude, name) || !webhooks.Enabled(hook, opts.Profile) || !webhooks.EnabledForCompliance(hook, opts.Compliance) || len(webhooks.Rules(hook, opts.Profile)) == 0 {
+			continue
+		}
+		configurationName := configurationPrefix + name
+		expected[configurationName] = true
+		got, ok := configurations[configurationName]
+		if !ok {
+			// Webhooks selecting some clusters only may rightly be missing
+			if !reflect.DeepEqual(webhooks.SyncSetLabelSelector(hook, opts.Compliance), utils.DefaultLabelSelector()) {
+				continue
+			}
+			findings = append(findings, Finding{Webhook: name, Configuration: configurationName, Kind: KindMissing, Message: "the webhook has no configuration"})
+			continue
+		}
+		findings = append(findings, compare(hook, configurationName, got, opts, servingCert, certErr)...)
+	}
+	unexpected := []string{}
+	for configurationName := range configurations {
+		if !expected[configurationName] {
+			unexpected = append(unexpected, configurationName)
+		}
+	}
+	sort.Strings(unexpected)
+	for _, configurationName := range unexpected {
+		findings = append(findings, Finding{Configuration: configurationName, Kind: KindUnexpected, Message: "the configuration is of no registered webhook"})
+	}
+	return findings, nil
+}
+
+// list returns the webhooks of the managed webhook configurations on the
+// cluster by configuration name
+func list(ctx context.Context, c client.Client) (map[string][]live, error) {
+	configurations := map[string][]live{}
+	validating := &admissionregv1.ValidatingWebhookConfigurationList{}
+	if err := c.List(ctx, validating); err != nil {
+		return nil, err
+	}
+	for _, configuration := range validating.Items {
+		if !strings.HasPrefix(configuration.Name, configurationPrefix) {
+			continue
+		}
+		entries := []live{}
+		for _, w := range configuration.Webhooks {
+			entries = append(entries, newLive(w.Name, w.ClientConfig, w.Rules, w.FailurePolicy, w.MatchPolicy, w.SideEffects, w.TimeoutSeconds, w.ObjectSelector, w.NamespaceSelector))
+		}
+		configurations[configuration.Name] = entries
+	}
+	mutating := &admissionregv1.MutatingWebhookConfigurationList{}
+	if err := c.List(ctx, mutating); err != nil {
+		return nil, err
+	}
+	for _, configuration := range mutating.Items {
+		if !strings.HasPrefix(configuration.Name, configurationPrefix) {
+			continue
+		}
+		entries := []live{}
+		for _, w := range configuration.Webhooks {
+			entries = append(entries, newLive(w.Name, w.ClientConfig, w.Rules, w.FailurePolicy, w.MatchPolicy, w.SideEffects, w.TimeoutSeconds, w.ObjectSelector, w.NamespaceSelector))
+		}
+		configurations[configuration.Name] = entries
+	}
+	return configurations, nil
+}
+
+// newLive returns the fields of a validating or mutating webhook shared by
+// both
+func newLive(name string, clientConfig admissionregv1.WebhookClientConfig, rules []admissionregv1.RuleWithOperations, failurePolicy *admissionregv1.FailurePolicyType, matchPolicy *admissionregv1.MatchPolicyType, sideEffects *admissionregv1.SideEffectClass, timeout *int32, objectSelector, namespaceSelector *metav1.LabelSelector) live {
+	return live{name: name, clientConfig: clientConfig, spec: normalize(spec{
+		Rules:             rules,
+		FailurePolicy:     deref(failurePolicy),
+		MatchPolicy:       deref(matchPolicy),
+		SideEffects:       deref(sideEffects),
+		TimeoutSeconds:    deref(timeout),
+		ObjectSelector:    deref(objectSelector),
+		NamespaceSelector: deref(namespaceSelector),
+	})}
+}
+
+// compare returns the drift of the webhooks of a configuration from hook
+func compare(hook webhooks.Webhook, configurationName string, got []live, opts Options, servingCert *x509.Certificate, certErr error) []Finding {
+	webhookName := hook.Name() + ".managed.openshift.io"
+	finding := func(kind Kind, field, expected, actual, message string) Finding {
+		return Finding{Webhook: hook.Name(), Configuration: configurationName, Kind: kind, Field: field, Expected: expected, Actual: actual, Message: message}
+	}
+	var w *live
+	for i := range got {
+		if got[i].name == webhookName {
+			w = &got[i]
+		}
+	}
+	if w == nil {
+		return []Finding{finding(KindMissing, "", "", "", fmt.Sprintf("the configuration has no webhook %s", webhookName))}
+	}
+
+	want := expected(hook, opts)
+	findings := []Finding{}
+	fields := []struct {
+		name      string
+		want, got interface{}
+	}{
+		{"rules", want.Rules, w.spec.Rules},
+		{"failurePolicy", want.FailurePolicy, w.spec.FailurePolicy},
+		{"matchPolicy", want.MatchPolicy, w.spec.MatchPolicy},
+		{"sideEffects", want.SideEffects, w.spec.SideEffects},
+		{"timeoutSeconds", want.TimeoutSeconds, w.spec.TimeoutSeconds},
+		{"objectSelector", want.ObjectSelector, w.spec.ObjectSelector},
+		{"namespaceSelector", want.NamespaceSelector, w.spec.NamespaceSelector},
+	}
+	for _, f := range fields {
+		if !reflect.DeepEqual(f.want, f.got) {
+			findings = append(findings, finding(KindChanged, f.name, encode(f.want), encode(f.got), fmt.Sprintf("the %s of the webhook differ from the registry", f.name)))
+		}
+	}
+
+	// Only configurations served through the Service of the webhooks get
+	// the CA of its serving certificate injected. The hosted control plane
+	// package sets a URL and CA bundle itself.
+	service := w.clientConfig.Service
+	switch {
+	case service != nil:
+		if service.Namespace != opts.Namespace || service.Name != opts.Service || service.Path == nil || *service.Path != hook.GetURI() {
+			path := ""
+			if service.Path != nil {
+				path = *service.Path
+			}
+			findings = append(findings, finding(KindChanged, "clientConfig", opts.Namespace+"/"+opts.Service+hook.GetURI(), service.Namespace+"/"+service.Name+path, "the webhook calls another service or path"))
+		}
+		if message := verifyCABundle(w.clientConfig.CABundle, servingCert, certErr); message != "" {
+			findings = append(findings, finding(KindCABundle, "clientConfig.caBundle", "", "", message))
+		}
+	case w.clientConfig.URL != nil && !strings.HasSuffix(*w.clientConfig.URL, hook.GetURI()):
+		findings = append(findings, finding(KindChanged, "clientConfig", hook.GetURI(), *w.clientConfig.URL, "the webhook calls another path"))
+	}
+	return findings
+}
+
+// expected returns the spec of hook the registry generates
+func expected(hook webhooks.Webhook, opts Options) spec {
+	return normalize(spec{
+		Rules:             webhooks.Rules(hook, opts.Profile),
+		FailurePolicy:     opts.FailurePolicy(hook.Name(), hook.FailurePolicy()),
+		MatchPolicy:       hook.MatchPolicy(),
+		SideEffects:       hook.SideEffects(),
+		TimeoutSeconds:    opts.Size.TimeoutSeconds(hook.TimeoutSeconds()),
+		ObjectSelector:    deref(hook.ObjectSelector()),
+		NamespaceSelector: deref(hook.NamespaceSelector()),
+	})
+}
+
+// normalize applies the defaults of the API server to s, so specs compare
+// equal whether or not they were defaulted
+func normalize(s spec) spec {
+	rules := make([]admissionregv1.RuleWithOperations, 0, len(s.Rules))
+	for _, rule := range s.Rules {
+		if rule.Scope == nil {
+			scope := admissionregv1.AllScopes
+			rule.Scope = &scope
+		}
+		rules = append(rules, rule)
+	}
+	s.Rules = rules
+	if s.MatchPolicy == "" {
+		s.MatchPolicy = admissionregv1.Equivalent
+	}
+	if s.FailurePolicy == "" {
+		s.FailurePolicy = admissionregv1.Fail
+	}
+	if s.TimeoutSeconds == 0 {
+		s.TimeoutSeconds = 10
+	}
+	// An empty selector matches everything, like none
+	for _, selector := range []*metav1.LabelSelector{&s.ObjectSelector, &s.NamespaceSelector} {
+		if len(selector.MatchLabels) == 0 {
+			selector.MatchLabels = nil
+		}
+		if len(selector.MatchExpressions) == 0 {
+			selector.MatchExpressions = nil
+		}
+	}
+	return s
+}
+
+// servingCertificate returns the serving certificate of the webhooks
+func servingCertificate(ctx context.Context, c client.Client, opts Options) (*x509.Certificate, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: opts.Namespace, Name: opts.Secret}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("the secret %s/%s of the serving certificate doesn't exist", opts.Namespace, opts.Secret)
+		}
+		return nil, err
+	}
+	block, _ := pem.Decode(secret.Data[corev1.TLSCertKey])
+	if block == nil {
+		return nil, fmt.Errorf("the secret %s/%s has no %s certificate", opts.Namespace, opts.Secret, corev1.TLSCertKey)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// verifyCABundle returns why caBundle doesn't verify cert, or an empty
+// string if it does
+func verifyCABundle(caBundle []byte, cert *x509.Certificate, certErr error) string {
+	if len(caBundle) == 0 {
+		return "the webhook has no CA bundle, the API server can't call it"
+	}
+	if certErr != nil {
+		return fmt.Sprintf("couldn't verify the CA bundle: %v", certErr)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caBundle) {
+		return "the CA bundle of the webhook holds no certificate"
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: pool}); err != nil {
+		return fmt.Sprintf("the CA bundle of the webhook doesn't verify its serving certificate: %v", err)
+	}
+	return ""
+}
+
+func deref[T any](p *T) T {
+	if p == nil {
+		var zero T
+		return zero
+	}
+	return *p
+}
+
+func encode(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
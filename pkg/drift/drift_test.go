@@ -0,0 +1,198 @@
+package drift
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/namespacelabel"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/scc"
+)
+
+var (
+	testHooks = webhooks.RegisteredWebhooks{
+		scc.WebhookName:            func() webhooks.Webhook { return scc.NewWebhook() },
+		namespacelabel.WebhookName: func() webhooks.Webhook { return namespacelabel.NewWebhook() },
+	}
+	testOptions = Options{Namespace: "openshift-validation-webhook", Service: "validation-webhook", Secret: "webhook-cert"}
+)
+
+// newCA returns a CA certificate and a serving certificate it signs, both
+// PEM encoded
+func newCA(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "service-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serving := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "validation-webhook.openshift-validation-webhook.svc"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	servingDER, err := x509.CreateCertificate(rand.Reader, serving, ca, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: servingDER})
+}
+
+// deployed returns the webhook configurations of testHooks as the registry
+// generates them, with caBundle
+func deployed(caBundle []byte) []client.Object {
+	objects := []client.Object{}
+	for name, factory := range testHooks {
+		hook := factory()
+		want := expected(hook, Options{FailurePolicy: func(_ string, def admissionregv1.FailurePolicyType) admissionregv1.FailurePolicyType { return def }})
+		path := hook.GetURI()
+		clientConfig := admissionregv1.WebhookClientConfig{
+			Service:  &admissionregv1.ServiceReference{Namespace: testOptions.Namespace, Name: testOptions.Service, Path: &path},
+			CABundle: caBundle,
+		}
+		if name == namespacelabel.WebhookName {
+			objects = append(objects, &admissionregv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Name: "sre-" + name},
+				Webhooks: []admissionregv1.MutatingWebhook{{
+					Name:           name + ".managed.openshift.io",
+					ClientConfig:   clientConfig,
+					Rules:          want.Rules,
+					FailurePolicy:  &want.FailurePolicy,
+					MatchPolicy:    &want.MatchPolicy,
+					SideEffects:    &want.SideEffects,
+					TimeoutSeconds: &want.TimeoutSeconds,
+				}},
+			})
+			continue
+		}
+		objects = append(objects, &admissionregv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "sre-" + name},
+			Webhooks: []admissionregv1.ValidatingWebhook{{
+				Name:           name + ".managed.openshift.io",
+				ClientConfig:   clientConfig,
+				Rules:          want.Rules,
+				FailurePolicy:  &want.FailurePolicy,
+				MatchPolicy:    &want.MatchPolicy,
+				SideEffects:    &want.SideEffects,
+				TimeoutSeconds: &want.TimeoutSeconds,
+			}},
+		})
+	}
+	return objects
+}
+
+func verify(t *testing.T, objects ...client.Object) []Finding {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	findings, err := Verify(context.Background(), c, testHooks, testOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return findings
+}
+
+func servingSecret(cert []byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testOptions.Namespace, Name: testOptions.Secret},
+		Data:       map[string][]byte{corev1.TLSCertKey: cert},
+	}
+}
+
+func TestVerifyWithoutDrift(t *testing.T) {
+	ca, cert := newCA(t)
+	findings := verify(t, append(deployed(ca), servingSecret(cert))...)
+	if len(findings) != 0 {
+		t.Fatalf("expected no drift, got %+v", findings)
+	}
+}
+
+func TestVerifyDrift(t *testing.T) {
+	ca, cert := newCA(t)
+	objects := deployed(ca)
+	for _, obj := range objects {
+		if configuration, ok := obj.(*admissionregv1.ValidatingWebhookConfiguration); ok {
+			// Someone made the SCC webhook deny requests when it is down and
+			// stopped it guarding deletes
+			fail := admissionregv1.Fail
+			configuration.Webhooks[0].FailurePolicy = &fail
+			configuration.Webhooks[0].Rules[0].Operations = []admissionregv1.OperationType{admissionregv1.Update}
+		}
+	}
+	leftover := &admissionregv1.ValidatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: "sre-removed-validation"}}
+	findings := verify(t, append(objects, leftover, servingSecret(cert))...)
+
+	want := map[string]Kind{"rules": KindChanged, "failurePolicy": KindChanged, "": KindUnexpected}
+	if len(findings) != len(want) {
+		t.Fatalf("expected %d findings, got %+v", len(want), findings)
+	}
+	for _, f := range findings {
+		if kind, ok := want[f.Field]; !ok || kind != f.Kind {
+			t.Fatalf("unexpected finding %+v", f)
+		}
+	}
+}
+
+func TestVerifyMissing(t *testing.T) {
+	ca, cert := newCA(t)
+	objects := []client.Object{servingSecret(cert)}
+	for _, obj := range deployed(ca) {
+		if obj.GetName() != "sre-"+scc.WebhookName {
+			objects = append(objects, obj)
+		}
+	}
+	findings := verify(t, objects...)
+	if len(findings) != 1 || findings[0].Kind != KindMissing || findings[0].Webhook != scc.WebhookName {
+		t.Fatalf("expected the SCC webhook to be missing, got %+v", findings)
+	}
+}
+
+func TestVerifyCABundle(t *testing.T) {
+	ca, _ := newCA(t)
+	_, otherCert := newCA(t)
+	findings := verify(t, append(deployed(ca), servingSecret(otherCert))...)
+	if len(findings) != len(testHooks) {
+		t.Fatalf("expected a CA bundle finding per webhook, got %+v", findings)
+	}
+	for _, f := range findings {
+		if f.Kind != KindCABundle {
+			t.Fatalf("expected CA bundle findings, got %+v", f)
+		}
+	}
+
+	findings = verify(t, append(deployed(nil), servingSecret(otherCert))...)
+	if len(findings) != len(testHooks) || findings[0].Kind != KindCABundle {
+		t.Fatalf("expected webhooks without a CA bundle to be reported, got %+v", findings)
+	}
+}
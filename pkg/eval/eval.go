@@ -0,0 +1,240 @@
+// Package eval runs manifests through the registered webhooks offline, to
+// tell whether applying them would be denied and why
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+// Options are the assumed requester and operation of the evaluated requests
+type Options struct {
+	Username  string
+	Groups    []string
+	Operation admissionv1.Operation
+	// Namespace is the namespace of objects whose manifest has none, like
+	// `oc apply -n`. Objects without a namespace are otherwise evaluated as
+	// cluster-scoped.
+	Namespace string
+}
+
+// Result is the answer of one webhook to one object
+type Result struct {
+	Webhook string `json:"webhook"`
+	// Object identifies the object, e.g. SecurityContextConstraints/anyuid
+	Object  string `json:"object"`
+	Allowed bool   `json:"allowed"`
+	// Errored is whether the webhook failed to evaluate the request rather
+	// than deciding it, e.g. because it needs to read the cluster
+	Errored  bool             `json:"errored,omitempty"`
+	Code     utils.ReasonCode `json:"code,omitempty"`
+	Message  string           `json:"message,omitempty"`
+	Warnings []string         `json:"warnings,omitempty"`
+	Patched  bool             `json:"patched,omitempty"`
+}
+
+// Denied returns whether the webhook denied the request
+func (r Result) Denied() bool {
+	return !r.Allowed && !r.Errored
+}
+
+// Decode returns the objects of the YAML or JSON manifests in r, which may
+// hold several documents and Lists
+func Decode(r io.Reader) ([]*unstructured.Unstructured, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	objects := []*unstructured.Unstructured{}
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, err
+		}
+		if len(obj.Object) == 0 {
+			// An empty document, e.g. a trailing ---
+			continue
+		}
+		if obj.IsList() {
+			list, err := obj.ToList()
+			if err != nil {
+				return nil, err
+			}
+			for i := range list.Items {
+				objects = append(objects, &list.Items[i])
+			}
+			continue
+		}
+		if obj.GetKind() == "" || obj.GetAPIVersion() == "" {
+			return nil, fmt.Errorf("object %q has no apiVersion or kind", obj.GetName())
+		}
+		objects = append(objects, obj)
+	}
+}
+
+// Evaluate runs obj through the webhooks of hooks whose rules match it, in
+// name order. The namespace selectors of the webhooks can't be evaluated
+// offline, so they are assumed to match.
+func Evaluate(hooks webhooks.RegisteredWebhooks, obj *unstructured.Unstructured, opts Options) ([]Result, error) {
+	raw, err := obj.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	gvk := obj.GroupVersionKind()
+	gvr := guessResource(hooks, gvk)
+	namespace := obj.GetNamespace()
+	if namespace == "" {
+		namespace = opts.Namespace
+	}
+	request := admissionctl.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		UID:       types.UID("webhook-eval"),
+		Kind:      metav1.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind},
+		Resource:  metav1.GroupVersionResource{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource},
+		Name:      obj.GetName(),
+		Namespace: namespace,
+		Operation: opts.Operation,
+		UserInfo:  authenticationv1.UserInfo{Username: opts.Username, Groups: opts.Groups},
+	}}
+	switch opts.Operation {
+	case admissionv1.Delete:
+		request.OldObject = runtime.RawExtension{Raw: raw}
+	case admissionv1.Update:
+		request.Object = runtime.RawExtension{Raw: raw}
+		request.OldObject = runtime.RawExtension{Raw: raw}
+	default:
+		request.Object = runtime.RawExtension{Raw: raw}
+	}
+
+	names := make([]string, 0, len(hooks))
+	for name := range hooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	results := []Result{}
+	for _, name := range names {
+		hook := hooks[name]()
+		if !matches(hook, request, obj.GetLabels()) {
+			continue
+		}
+		result := evaluate(hook, request)
+		result.Object = objectName(obj, namespace)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// evaluate answers request with hook, like the dispatcher
+func evaluate(hook webhooks.Webhook, request admissionctl.Request) (result Result) {
+	result.Webhook = hook.Name()
+	defer func() {
+		// Webhooks which read the cluster may not cope without it
+		if r := recover(); r != nil {
+			result.Errored = true
+			result.Message = fmt.Sprintf("the webhook failed to evaluate the request offline: %v", r)
+		}
+	}()
+	if !hook.Validate(request) {
+		result.Errored = true
+		result.Message = "not a valid webhook request"
+		return result
+	}
+	resp := hook.Authorized(request)
+	result.Allowed = resp.Allowed
+	result.Warnings = resp.Warnings
+	result.Patched = len(resp.Patches) > 0 || len(resp.Patch) > 0
+	if resp.Result != nil {
+		result.Errored = !resp.Allowed && resp.Result.Code != 0 && resp.Result.Code != http.StatusForbidden
+	}
+	result.Code, result.Message = utils.DenialReason(resp)
+	return result
+}
+
+// matches returns whether the rules and object selector of hook match
+// request, for an object with objectLabels
+func matches(hook webhooks.Webhook, request admissionctl.Request, objectLabels map[string]string) bool {
+	if selector := hook.ObjectSelector(); selector != nil {
+		s, err := metav1.LabelSelectorAsSelector(selector)
+		if err != nil || !s.Matches(labels.Set(objectLabels)) {
+			return false
+		}
+	}
+	for _, rule := range hook.Rules() {
+		if ruleMatches(rule, request) {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleMatches(rule admissionregv1.RuleWithOperations, request admissionctl.Request) bool {
+	if !slices.Contains(rule.Operations, admissionregv1.OperationAll) && !slices.Contains(rule.Operations, admissionregv1.OperationType(request.Operation)) {
+		return false
+	}
+	if !matchesValue(rule.APIGroups, request.Kind.Group) || !matchesValue(rule.APIVersions, request.Kind.Version) || !matchesValue(rule.Resources, request.Resource.Resource) {
+		return false
+	}
+	if rule.Scope != nil {
+		switch *rule.Scope {
+		case admissionregv1.NamespacedScope:
+			return request.Namespace != ""
+		case admissionregv1.ClusterScope:
+			return request.Namespace == ""
+		}
+	}
+	return true
+}
+
+// matchesValue returns whether the values of a rule match value, ignoring
+// subresource rules such as pods/exec
+func matchesValue(values []string, value string) bool {
+	return slices.Contains(values, "*") || slices.Contains(values, value)
+}
+
+// guessResource returns the resource of gvk. Manifests don't say which
+// resource they are, so it is guessed like kubectl does without discovery,
+// unless the rules of hooks name a resource for the kind the guess misses,
+// e.g. securitycontextconstraints.
+func guessResource(hooks webhooks.RegisteredWebhooks, gvk schema.GroupVersionKind) schema.GroupVersionResource {
+	gvr, singular := meta.UnsafeGuessKindToResource(gvk)
+	for _, hook := range hooks {
+		for _, rule := range hook().Rules() {
+			if !matchesValue(rule.APIGroups, gvk.Group) {
+				continue
+			}
+			for _, resource := range rule.Resources {
+				if resource == singular.Resource {
+					gvr.Resource = resource
+					return gvr
+				}
+			}
+		}
+	}
+	return gvr
+}
+
+func objectName(obj *unstructured.Unstructured, namespace string) string {
+	if namespace == "" {
+		return obj.GetKind() + "/" + obj.GetName()
+	}
+	return obj.GetKind() + "/" + namespace + "/" + obj.GetName()
+}
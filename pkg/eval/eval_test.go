@@ -0,0 +1,95 @@
+package eval
+
+import (
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/scc"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const manifests = `
+apiVersion: security.openshift.io/v1
+kind: SecurityContextConstraints
+metadata:
+  name: anyuid
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: security.openshift.io/v1
+  kind: SecurityContextConstraints
+  metadata:
+    name: my-scc
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: settings
+---
+`
+
+func TestDecode(t *testing.T) {
+	objects, err := Decode(strings.NewReader(manifests))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	names := []string{}
+	for _, obj := range objects {
+		names = append(names, obj.GetName())
+	}
+	if strings.Join(names, ",") != "anyuid,my-scc,settings" {
+		t.Fatalf("Expected anyuid, my-scc and settings, got %v", names)
+	}
+
+	if _, err := Decode(strings.NewReader("metadata:\n  name: foo\n")); err == nil {
+		t.Fatalf("Expected an error decoding an object without a kind")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	hooks := webhooks.RegisteredWebhooks{scc.WebhookName: func() webhooks.Webhook { return scc.NewWebhook() }}
+	objects, err := Decode(strings.NewReader(manifests))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tests := []struct {
+		name      string
+		username  string
+		operation admissionv1.Operation
+		object    int
+		results   int
+		denied    bool
+	}{
+		{name: "regular user deleting a default SCC", username: "alice", operation: admissionv1.Delete, object: 0, results: 1, denied: true},
+		{name: "regular user updating a default SCC", username: "alice", operation: admissionv1.Update, object: 0, results: 1, denied: true},
+		{name: "regular user deleting another SCC", username: "alice", operation: admissionv1.Delete, object: 1, results: 1},
+		{name: "admin deleting a default SCC", username: "system:admin", operation: admissionv1.Delete, object: 0, results: 1},
+		{name: "creating an SCC doesn't match the rules", username: "alice", operation: admissionv1.Create, object: 0},
+		{name: "ConfigMaps don't match the rules", username: "alice", operation: admissionv1.Delete, object: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := Options{Username: test.username, Groups: []string{"system:authenticated"}, Operation: test.operation}
+			results, err := Evaluate(hooks, objects[test.object], opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(results) != test.results {
+				t.Fatalf("Expected %d results, got %+v", test.results, results)
+			}
+			if len(results) == 0 {
+				return
+			}
+			r := results[0]
+			if r.Denied() != test.denied || r.Errored {
+				t.Fatalf("Expected denied %v, got %+v", test.denied, r)
+			}
+			if test.denied && r.Code != utils.ReasonSCCDefaultDelete && r.Code != utils.ReasonSCCDefaultModify {
+				t.Fatalf("Expected an SCC reason code, got %+v", r)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+)
+
+const (
+	// asyncQueueSize is the number of denials waiting for each recorder of an
+	// AsyncRecorder, after which they are dropped
+	asyncQueueSize = 1024
+	// asyncBatchSize is the most denials a recorder is handed at once
+	asyncBatchSize = 64
+)
+
+// Denial is a denied admission request, as recorded by a Recorder
+type Denial struct {
+	Webhook  string
+	Request  admissionctl.Request
+	Response admissionctl.Response
+}
+
+// BatchRecorder is a Recorder also recording several denials at once, which
+// an AsyncRecorder hands it the denials queued since its last batch with. It
+// must not keep denials, which is reused for the next batch.
+type BatchRecorder interface {
+	Recorder
+	RecordDenials(denials []Denial)
+}
+
+// AsyncRecorder records denials with its recorders off the admission path.
+// Each recorder has its own queue and worker, so that a slow Event creation,
+// audit sink or service log never delays the decision, nor the other
+// recorders. A denial arriving while the queue of a recorder is full is
+// dropped for that recorder and counted in
+// managed_webhook_side_effects_dropped_total.
+type AsyncRecorder struct {
+	queues []*recorderQueue
+	wg     sync.WaitGroup
+}
+
+type recorderQueue struct {
+	name     string
+	recorder Recorder
+	denials  chan Denial
+}
+
+// NewAsyncRecorder returns an AsyncRecorder without recorders
+func NewAsyncRecorder() *AsyncRecorder {
+	return &AsyncRecorder{}
+}
+
+// Add starts the worker of recorder, named name in the logs and metrics. It
+// must be called before the first denial is recorded.
+func (a *AsyncRecorder) Add(name string, recorder Recorder) {
+	q := &recorderQueue{name: name, recorder: recorder, denials: make(chan Denial, asyncQueueSize)}
+	a.queues = append(a.queues, q)
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		q.run()
+	}()
+}
+
+// RecordDenial implements Recorder, queuing the denial for every recorder
+func (a *AsyncRecorder) RecordDenial(webhook string, request admissionctl.Request, resp admissionctl.Response) {
+	denial := Denial{Webhook: webhook, Request: request, Response: resp}
+	for _, q := range a.queues {
+		select {
+		case q.denials <- denial:
+		default:
+			localmetrics.IncrementDroppedSideEffect(q.name)
+			log.Info("Denial queue is full, dropping denial", "recorder", q.name, "webhook", webhook, "uid", request.UID)
+		}
+	}
+}
+
+// Close stops accepting denials and waits for the queued ones to be
+// recorded. The recorders with a Close method, e.g. the audit pipeline, are
+// then closed, so that they flush what they queue themselves. It must be
+// called once no more denials are recorded, e.g. once the server stopped.
+func (a *AsyncRecorder) Close() {
+	for _, q := range a.queues {
+		close(q.denials)
+	}
+	a.wg.Wait()
+	for _, q := range a.queues {
+		if closer, ok := q.recorder.(interface{ Close() }); ok {
+			closer.Close()
+		}
+	}
+}
+
+// run records the queued denials in batches until the queue is closed
+func (q *recorderQueue) run() {
+	batch := make([]Denial, 0, asyncBatchSize)
+	for denial := range q.denials {
+		batch = append(batch[:0], denial)
+	drain:
+		for len(batch) < asyncBatchSize {
+			select {
+			case denial, ok := <-q.denials:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, denial)
+			default:
+				break drain
+			}
+		}
+		q.record(batch)
+	}
+}
+
+// record hands batch to the recorder, recovering from its panics so that
+// they don't stop the worker
+func (q *recorderQueue) record(batch []Denial) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error(fmt.Errorf("%v", r), "Recorder panicked, dropping its denials", "recorder", q.name, "denials", len(batch))
+		}
+	}()
+	if b, ok := q.recorder.(BatchRecorder); ok {
+		b.RecordDenials(batch)
+		return
+	}
+	for _, d := range batch {
+		q.recorder.RecordDenial(d.Webhook, d.Request, d.Response)
+	}
+}
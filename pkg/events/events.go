@@ -0,0 +1,153 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/k8sutil"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	// ReportingNamespaceEnvVar names the environment variable overriding the
+	// namespace Events for cluster-scoped resources are created in
+	ReportingNamespaceEnvVar string = "DENIAL_EVENTS_NAMESPACE"
+	// DeniedReason is the reason of every denial Event
+	DeniedReason string = "AdmissionDenied"
+	// AuditedReason is the reason of the Events of requests a webhook in
+	// audit mode would have denied
+	AuditedReason string = "AdmissionAudited"
+	// ReasonCodeLabel carries the denial's reason code, so Events can be
+	// selected by code
+	ReasonCodeLabel string = "managed.openshift.io/reason-code"
+	// maxMessageLength is the longest Event message the API server accepts
+	maxMessageLength = 1024
+	// recordTimeout bounds how long creating an Event may take, so a slow API
+	// server doesn't hold up the later denials
+	recordTimeout = 5 * time.Second
+)
+
+var log = logf.Log.WithName("events")
+
+// Recorder records denied admission requests, and the requests webhooks in
+// audit mode would have denied, which utils.AuditSource tells apart
+type Recorder interface {
+	RecordDenial(webhook string, request admissionctl.Request, resp admissionctl.Response)
+}
+
+// DenialRecorder records denials as Kubernetes Events, so customers can see
+// why a request was denied with `oc get events`
+type DenialRecorder struct {
+	once               sync.Once
+	kubeClient         client.Client
+	reportingNamespace string
+}
+
+// NewRecorder creates a DenialRecorder. The kube client is created on first use.
+func NewRecorder() *DenialRecorder {
+	reportingNamespace := os.Getenv(ReportingNamespaceEnvVar)
+	if reportingNamespace == "" {
+		reportingNamespace = config.OperatorNamespace
+	}
+	return &DenialRecorder{
+		reportingNamespace: reportingNamespace,
+	}
+}
+
+// RecordDenial implements Recorder, creating the Event before it returns. The
+// dispatcher calls it from an AsyncRecorder, so it never adds to admission
+// latency.
+func (r *DenialRecorder) RecordDenial(webhook string, request admissionctl.Request, resp admissionctl.Response) {
+	r.once.Do(func() {
+		if r.kubeClient != nil {
+			return
+		}
+		scheme := runtime.NewScheme()
+		if err := corev1.AddToScheme(scheme); err != nil {
+			log.Error(err, "Fail adding corev1 scheme to DenialRecorder")
+			return
+		}
+		kubeClient, err := k8sutil.KubeClient(scheme)
+		if err != nil {
+			log.Error(err, "Fail creating KubeClient for DenialRecorder")
+			return
+		}
+		r.kubeClient = kubeClient
+	})
+	if r.kubeClient == nil {
+		return
+	}
+
+	event := r.buildEvent(webhook, request, resp)
+	ctx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+	defer cancel()
+	if err := r.kubeClient.Create(ctx, event); err != nil {
+		log.Error(err, "Failed to create denial Event", "webhook", webhook, "namespace", event.Namespace)
+	}
+}
+
+// buildEvent returns the Event recording the denial. Namespaced requests are
+// reported in the requester's namespace, and cluster-scoped requests in the
+// reporting namespace.
+func (r *DenialRecorder) buildEvent(webhook string, request admissionctl.Request, resp admissionctl.Response) *corev1.Event {
+	namespace := request.Namespace
+	if namespace == "" {
+		namespace = r.reportingNamespace
+	}
+	name := request.Name
+	if name == "" {
+		// Objects created with generateName have no name yet
+		name = webhook
+	}
+
+	code, reason := utils.DenialReason(resp)
+	eventReason, eventType := DeniedReason, corev1.EventTypeWarning
+	message := fmt.Sprintf("%s denied %s of %s %s by %s: %s", webhook, request.Operation, request.Kind.Kind, request.Name, request.UserInfo.Username, reason)
+	if source := utils.AuditSource(resp); source != "" {
+		eventReason, eventType = AuditedReason, corev1.EventTypeNormal
+		message = fmt.Sprintf("%s would have denied %s of %s %s by %s, it is in audit mode (%s): %s", webhook, request.Operation, request.Kind.Kind, request.Name, request.UserInfo.Username, source, reason)
+	}
+	if len(message) > maxMessageLength {
+		message = message[:maxMessageLength]
+	}
+
+	labels := map[string]string{
+		"managed.openshift.io/webhook": webhook,
+	}
+	if code != "" {
+		labels[ReasonCodeLabel] = string(code)
+	}
+
+	now := metav1.Now()
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: name + ".",
+			Namespace:    namespace,
+			Labels:       labels,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: metav1.GroupVersion{Group: request.Kind.Group, Version: request.Kind.Version}.String(),
+			Kind:       request.Kind.Kind,
+			Name:       request.Name,
+			Namespace:  request.Namespace,
+		},
+		Reason:         eventReason,
+		Message:        message,
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: config.OperatorName},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+}
@@ -0,0 +1,202 @@
+package events
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+func newRequest(namespace, name string, kind metav1.GroupVersionKind) admissionctl.Request {
+	return admissionctl.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:      kind,
+			Name:      name,
+			Namespace: namespace,
+			Operation: admissionv1.Create,
+			UserInfo:  authenticationv1.UserInfo{Username: "my_user"},
+		},
+	}
+}
+
+func TestBuildEvent(t *testing.T) {
+	tests := []struct {
+		testID            string
+		request           admissionctl.Request
+		reportingNs       string
+		expectedNamespace string
+	}{
+		{
+			testID:            "namespaced-request-reported-in-namespace",
+			request:           newRequest("my-namespace", "my-pod", metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}),
+			expectedNamespace: "my-namespace",
+		},
+		{
+			testID:            "cluster-scoped-request-reported-in-default-namespace",
+			request:           newRequest("", "restricted", metav1.GroupVersionKind{Group: "security.openshift.io", Version: "v1", Kind: "SecurityContextConstraints"}),
+			expectedNamespace: config.OperatorNamespace,
+		},
+		{
+			testID:            "cluster-scoped-request-reported-in-configured-namespace",
+			request:           newRequest("", "restricted", metav1.GroupVersionKind{Group: "security.openshift.io", Version: "v1", Kind: "SecurityContextConstraints"}),
+			reportingNs:       "openshift-customer-events",
+			expectedNamespace: "openshift-customer-events",
+		},
+	}
+	for _, test := range tests {
+		t.Setenv(ReportingNamespaceEnvVar, test.reportingNs)
+		recorder := NewRecorder()
+		event := recorder.buildEvent("test-validation", test.request, admissionctl.Denied("Not allowed"))
+
+		if event.Namespace != test.expectedNamespace {
+			t.Fatalf("%s: Expected event in namespace %s, got %s", test.testID, test.expectedNamespace, event.Namespace)
+		}
+		if event.Type != corev1.EventTypeWarning || event.Reason != DeniedReason {
+			t.Fatalf("%s: Expected a %s %s event, got %s %s", test.testID, corev1.EventTypeWarning, DeniedReason, event.Type, event.Reason)
+		}
+		if event.InvolvedObject.Name != test.request.Name || event.InvolvedObject.Kind != test.request.Kind.Kind {
+			t.Fatalf("%s: Expected the event to involve %s %s, got %v", test.testID, test.request.Kind.Kind, test.request.Name, event.InvolvedObject)
+		}
+		for _, want := range []string{"test-validation", "my_user", "Not allowed"} {
+			if !strings.Contains(event.Message, want) {
+				t.Fatalf("%s: Expected the event message to contain %q, got %q", test.testID, want, event.Message)
+			}
+		}
+	}
+}
+
+func TestBuildEventTruncatesMessage(t *testing.T) {
+	recorder := NewRecorder()
+	request := newRequest("my-namespace", "my-pod", metav1.GroupVersionKind{Version: "v1", Kind: "Pod"})
+	event := recorder.buildEvent("test-validation", request, admissionctl.Denied(strings.Repeat("x", 2*maxMessageLength)))
+	if len(event.Message) != maxMessageLength {
+		t.Fatalf("Expected the event message to be truncated to %d, got %d", maxMessageLength, len(event.Message))
+	}
+}
+
+func TestBuildEventReasonCode(t *testing.T) {
+	recorder := NewRecorder()
+	request := newRequest("", "restricted", metav1.GroupVersionKind{Group: "security.openshift.io", Version: "v1", Kind: "SecurityContextConstraints"})
+	event := recorder.buildEvent("scc-validation", request, utils.Denied(utils.ReasonSCCDefaultModify, "Modifying default SCCs is not allowed"))
+	if event.Labels[ReasonCodeLabel] != string(utils.ReasonSCCDefaultModify) {
+		t.Fatalf("Expected the %s label to be %s, got %v", ReasonCodeLabel, utils.ReasonSCCDefaultModify, event.Labels)
+	}
+	if !strings.Contains(event.Message, "Modifying default SCCs is not allowed") {
+		t.Fatalf("Expected the event message to contain the denial message, got %q", event.Message)
+	}
+}
+
+func TestBuildEventAudited(t *testing.T) {
+	recorder := NewRecorder()
+	request := newRequest("", "restricted", metav1.GroupVersionKind{Group: "security.openshift.io", Version: "v1", Kind: "SecurityContextConstraints"})
+	resp := utils.WithAuditSource(utils.Denied(utils.ReasonSCCDefaultModify, "Modifying default SCCs is not allowed"), "enforcement-mode")
+	event := recorder.buildEvent("scc-validation", request, resp)
+	if event.Reason != AuditedReason || event.Type != corev1.EventTypeNormal {
+		t.Fatalf("Expected a Normal %s event, got a %s %s event", AuditedReason, event.Type, event.Reason)
+	}
+	if !strings.Contains(event.Message, "would have denied") || !strings.Contains(event.Message, "enforcement-mode") {
+		t.Fatalf("Expected the event message to say the request would have been denied, got %q", event.Message)
+	}
+}
+
+// blockingRecorder counts its denials, waiting for release before recording
+// the first
+type blockingRecorder struct {
+	release chan struct{}
+	once    sync.Once
+	mu      sync.Mutex
+	batches []int
+}
+
+func (b *blockingRecorder) RecordDenial(string, admissionctl.Request, admissionctl.Response) {
+	b.RecordDenials(make([]Denial, 1))
+}
+
+func (b *blockingRecorder) RecordDenials(denials []Denial) {
+	b.once.Do(func() { <-b.release })
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.batches = append(b.batches, len(denials))
+}
+
+type panickingRecorder struct{ recorded int }
+
+func (p *panickingRecorder) RecordDenial(string, admissionctl.Request, admissionctl.Response) {
+	p.recorded++
+	if p.recorded == 1 {
+		panic("failed")
+	}
+}
+
+func TestAsyncRecorder(t *testing.T) {
+	blocking := &blockingRecorder{release: make(chan struct{})}
+	panicking := &panickingRecorder{}
+	a := NewAsyncRecorder()
+	a.Add("blocking", blocking)
+	a.Add("panicking", panicking)
+	request := newRequest("", "anyuid", metav1.GroupVersionKind{Kind: "SecurityContextConstraints"})
+	denials := asyncQueueSize + 10
+	for i := 0; i < denials; i++ {
+		// The blocked recorder neither delays the denials nor the other
+		// recorder, it drops what its queue can't hold
+		a.RecordDenial("scc-validation", request, admissionctl.Denied("Not allowed"))
+	}
+	dropped := testutil.ToFloat64(localmetrics.MetricDroppedSideEffects.WithLabelValues("blocking"))
+	if dropped == 0 {
+		t.Fatalf("Expected the denials past the queue of the blocked recorder to be dropped")
+	}
+	close(blocking.release)
+	a.Close()
+	recorded := 0
+	for _, n := range blocking.batches {
+		if n > asyncBatchSize {
+			t.Fatalf("Expected batches of at most %d denials, got %d", asyncBatchSize, n)
+		}
+		recorded += n
+	}
+	if recorded+int(dropped) != denials || len(blocking.batches) >= recorded {
+		t.Fatalf("Expected the denials to be recorded in batches or dropped, got %d recorded in %d batches and %v dropped", recorded, len(blocking.batches), dropped)
+	}
+	if panicking.recorded < 2 {
+		t.Fatalf("Expected a panic to drop the batch without stopping the worker, got %d denials", panicking.recorded)
+	}
+}
+
+// closingRecorder counts the denials it records before it is closed
+type closingRecorder struct {
+	recorded       int
+	closedRecorded int
+	closed         bool
+}
+
+func (c *closingRecorder) RecordDenial(string, admissionctl.Request, admissionctl.Response) {
+	c.recorded++
+}
+
+func (c *closingRecorder) Close() {
+	c.closedRecorded = c.recorded
+	c.closed = true
+}
+
+func TestAsyncRecorderClose(t *testing.T) {
+	a := NewAsyncRecorder()
+	closing := &closingRecorder{}
+	a.Add("closing", closing)
+	for i := 0; i < 10; i++ {
+		a.RecordDenial("scc-validation", admissionctl.Request{}, admissionctl.Denied("Not allowed"))
+	}
+	a.Close()
+	if !closing.closed || closing.closedRecorded != 10 {
+		t.Fatalf("Expected the recorder to be closed after recording the 10 queued denials, got closed %t after %d", closing.closed, closing.closedRecorded)
+	}
+}
@@ -0,0 +1,105 @@
+package exemption
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/k8sutil"
+)
+
+const (
+	// BreakGlassAnnotation on the webhook namespace switches every webhook to
+	// audit-only until the RFC 3339 time it is set to. Requests which would
+	// have been denied are allowed with a warning, and still recorded as
+	// denials. Only SRE can annotate the namespace, as it is protected.
+	BreakGlassAnnotation = "managed.openshift.io/webhook-break-glass-until"
+
+	// MaxBreakGlassDuration is how far ahead the break-glass may be set. A
+	// later expiry is ignored rather than shortened, like for exemptions.
+	MaxBreakGlassDuration = 4 * time.Hour
+)
+
+var namespaceGVK = schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+
+// BreakGlass returns when the break-glass expires, and whether it is active
+func (s *Store) BreakGlass() (time.Time, bool) {
+	if s == nil {
+		return time.Time{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.breakGlassUntil, s.now().Before(s.breakGlassUntil)
+}
+
+func (s *Store) refreshBreakGlass(ctx context.Context) {
+	value, err := s.getBreakGlass(ctx)
+	if err != nil {
+		if err.Error() != s.lastBreakGlassErr {
+			log.Error(err, "Failed to read the break-glass annotation, keeping the last known break-glass")
+			s.lastBreakGlassErr = err.Error()
+		}
+		return
+	}
+	s.lastBreakGlassErr = ""
+	s.setBreakGlass(value)
+}
+
+// setBreakGlass applies the value of the BreakGlassAnnotation, logging when
+// the break-glass starts and ends so it appears in the audit trail
+func (s *Store) setBreakGlass(value string) {
+	until := time.Time{}
+	ignored := ""
+	if value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		switch {
+		case err != nil:
+			if value != s.ignoredBreakGlass {
+				log.Error(err, "Ignoring invalid break-glass annotation", "annotation", BreakGlassAnnotation, "value", value)
+			}
+			ignored = value
+		case parsed.Sub(s.now()) > MaxBreakGlassDuration:
+			if value != s.ignoredBreakGlass {
+				log.Info("Ignoring break-glass which lasts longer than the maximum duration", "annotation", BreakGlassAnnotation, "until", value, "maxDuration", MaxBreakGlassDuration)
+			}
+			ignored = value
+		default:
+			until = parsed
+		}
+	}
+	s.ignoredBreakGlass = ignored
+	previous, wasActive := s.BreakGlass()
+	s.mu.Lock()
+	s.breakGlassUntil = until
+	s.mu.Unlock()
+	_, active := s.BreakGlass()
+	switch {
+	case active && (!wasActive || !previous.Equal(until)):
+		log.Info("Break-glass is active, every webhook is audit-only", "until", until.UTC().Format(time.RFC3339))
+	case !active && wasActive:
+		log.Info("Break-glass ended, webhooks are enforcing again")
+	}
+}
+
+// getBreakGlass returns the BreakGlassAnnotation of the webhook namespace
+func (s *Store) getBreakGlass(ctx context.Context) (string, error) {
+	if s.namespace == "" {
+		namespace, err := k8sutil.GetOperatorNamespace()
+		if err != nil {
+			return "", err
+		}
+		s.namespace = namespace
+	}
+	if err := s.ensureClient(); err != nil {
+		return "", err
+	}
+	ns := &unstructured.Unstructured{}
+	ns.SetGroupVersionKind(namespaceGVK)
+	if err := s.kubeClient.Get(ctx, client.ObjectKey{Name: s.namespace}, ns); err != nil {
+		return "", err
+	}
+	return ns.GetAnnotations()[BreakGlassAnnotation], nil
+}
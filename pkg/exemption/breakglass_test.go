@@ -0,0 +1,60 @@
+package exemption
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSetBreakGlass(t *testing.T) {
+	s := newStore()
+	now := created
+	s.now = func() time.Time { return now }
+	tests := []struct {
+		value    string
+		expected bool
+	}{
+		{value: "", expected: false},
+		{value: "not-a-time", expected: false},
+		{value: created.Add(MaxBreakGlassDuration + time.Minute).Format(time.RFC3339), expected: false},
+		{value: created.Add(time.Hour).Format(time.RFC3339), expected: true},
+		{value: created.Add(-time.Minute).Format(time.RFC3339), expected: false},
+	}
+	for _, test := range tests {
+		s.setBreakGlass(test.value)
+		if _, active := s.BreakGlass(); active != test.expected {
+			t.Fatalf("%q: Expected break-glass active %v, got %v", test.value, test.expected, active)
+		}
+	}
+
+	s.setBreakGlass(created.Add(time.Hour).Format(time.RFC3339))
+	now = created.Add(2 * time.Hour)
+	if _, active := s.BreakGlass(); active {
+		t.Fatalf("Expected the break-glass to revert once expired")
+	}
+	var nilStore *Store
+	if _, active := nilStore.BreakGlass(); active {
+		t.Fatalf("Expected a nil Store to have no break-glass")
+	}
+}
+
+func TestGetBreakGlass(t *testing.T) {
+	ns := &unstructured.Unstructured{}
+	ns.SetGroupVersionKind(namespaceGVK)
+	ns.SetName("openshift-validation-webhook")
+	ns.SetAnnotations(map[string]string{BreakGlassAnnotation: "2023-05-01T14:00:00Z"})
+	s := newStore()
+	s.namespace = "openshift-validation-webhook"
+	s.kubeClient = fake.NewClientBuilder().WithScheme(runtime.NewScheme()).WithObjects(ns).Build()
+	value, err := s.getBreakGlass(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	if value != "2023-05-01T14:00:00Z" {
+		t.Fatalf("Expected the annotation value, got %q", value)
+	}
+}
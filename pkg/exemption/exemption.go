@@ -0,0 +1,187 @@
+package exemption
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/k8sutil"
+)
+
+const (
+	// refreshInterval is how often the WebhookExemptions are listed, so how
+	// long a new exemption may take to apply
+	refreshInterval = 30 * time.Second
+	listTimeout     = 10 * time.Second
+)
+
+var log = logf.Log.WithName("exemption")
+
+// Store keeps the WebhookExemptions of the cluster, refreshed in the
+// background, so looking one up doesn't add an API call to admission
+type Store struct {
+	mu         sync.RWMutex
+	exemptions []WebhookExemption
+	now        func() time.Time
+	// ignored are the exemptions already logged as exceeding MaxTTL
+	ignored map[string]bool
+	lastErr string
+
+	// breakGlassUntil is when the break-glass set by BreakGlassAnnotation expires
+	breakGlassUntil time.Time
+	// ignoredBreakGlass is the annotation value already logged as ignored
+	ignoredBreakGlass string
+	lastBreakGlassErr string
+	namespace         string
+
+	// labelled are the exemptions set by ExemptionLabel
+	labelled     labelled
+	lastLabelErr string
+
+	kubeClient client.Client
+}
+
+// NewStore creates and starts a Store
+func NewStore() *Store {
+	s := newStore()
+	go s.run()
+	return s
+}
+
+func newStore() *Store {
+	return &Store{
+		now:     time.Now,
+		ignored: map[string]bool{},
+	}
+}
+
+// Match returns the active exemption of request from webhook, or nil if
+// there is none
+func (s *Store) Match(webhook string, request admissionctl.Request) *WebhookExemption {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := s.now()
+	for i := range s.exemptions {
+		e := &s.exemptions[i]
+		if e.Spec.Webhook == webhook && e.Active(now) && e.MatchesRequest(request) {
+			return e
+		}
+	}
+	return nil
+}
+
+func (s *Store) run() {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		s.refresh()
+		<-ticker.C
+	}
+}
+
+func (s *Store) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+	s.refreshBreakGlass(ctx)
+	s.refreshLabels(ctx)
+	exemptions, err := s.list(ctx)
+	if err != nil {
+		// Only log when the error changes, e.g. the CRD isn't installed on
+		// HyperShift
+		if err.Error() != s.lastErr {
+			log.Error(err, "Failed to list WebhookExemptions, keeping the last known exemptions")
+			s.lastErr = err.Error()
+		}
+		return
+	}
+	s.lastErr = ""
+	s.set(exemptions)
+	s.collect(ctx, exemptions)
+}
+
+// collect deletes the exemptions which expired more than GCAfter ago. Every
+// webhook pod collects them, so an exemption another pod deleted, or which
+// was recreated since it was listed, is left alone.
+func (s *Store) collect(ctx context.Context, exemptions []WebhookExemption) {
+	now := s.now()
+	for _, e := range exemptions {
+		if !e.collectable(now) {
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(schema.GroupVersionKind{Group: Group, Version: Version, Kind: Kind})
+		obj.SetName(e.Name)
+		uid := e.UID
+		err := s.kubeClient.Delete(ctx, obj, client.Preconditions{UID: &uid})
+		switch {
+		case err == nil:
+			log.Info("Deleted expired WebhookExemption", "exemption", e.Name, "webhook", e.Spec.Webhook, "expiresAt", e.Spec.ExpiresAt, "reason", e.Spec.Reason)
+		case apierrors.IsNotFound(err) || apierrors.IsConflict(err):
+		default:
+			log.Error(err, "Failed to delete expired WebhookExemption", "exemption", e.Name)
+		}
+	}
+}
+
+// set replaces the exemptions, logging those which are ignored for
+// exceeding MaxTTL once
+func (s *Store) set(exemptions []WebhookExemption) {
+	ignored := map[string]bool{}
+	for _, e := range exemptions {
+		if !e.exceedsMaxTTL() {
+			continue
+		}
+		ignored[string(e.UID)] = true
+		if !s.ignored[string(e.UID)] {
+			log.Info("Ignoring WebhookExemption which lasts longer than the maximum TTL", "exemption", e.Name, "webhook", e.Spec.Webhook, "expiresAt", e.Spec.ExpiresAt, "maxTTL", MaxTTL)
+		}
+	}
+	s.ignored = ignored
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exemptions = exemptions
+}
+
+func (s *Store) ensureClient() error {
+	if s.kubeClient != nil {
+		return nil
+	}
+	kubeClient, err := k8sutil.KubeClient(runtime.NewScheme())
+	if err != nil {
+		return err
+	}
+	s.kubeClient = kubeClient
+	return nil
+}
+
+func (s *Store) list(ctx context.Context) ([]WebhookExemption, error) {
+	if err := s.ensureClient(); err != nil {
+		return nil, err
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(listGVK)
+	if err := s.kubeClient.List(ctx, list); err != nil {
+		return nil, err
+	}
+	exemptions := make([]WebhookExemption, 0, len(list.Items))
+	for _, item := range list.Items {
+		e := WebhookExemption{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &e); err != nil {
+			log.Error(err, "Ignoring malformed WebhookExemption", "exemption", item.GetName())
+			continue
+		}
+		exemptions = append(exemptions, e)
+	}
+	return exemptions, nil
+}
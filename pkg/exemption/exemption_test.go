@@ -0,0 +1,148 @@
+package exemption
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var created = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func newExemption(name, webhook string, ttl time.Duration) WebhookExemption {
+	return WebhookExemption{
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID("uid-" + name), CreationTimestamp: metav1.NewTime(created)},
+		Spec: WebhookExemptionSpec{
+			Webhook:         webhook,
+			Users:           []string{"alice"},
+			Groups:          []string{"incident-responders"},
+			ServiceAccounts: []ServiceAccountReference{{Namespace: "openshift-gitops", Name: "argocd"}},
+			Names:           []string{"restricted-v2"},
+			ExpiresAt:       metav1.NewTime(created.Add(ttl)),
+			Reason:          "OHSS-1234",
+		},
+	}
+}
+
+func TestMatches(t *testing.T) {
+	e := newExemption("break-glass", "scc-validation", time.Hour)
+	tests := []struct {
+		user     authenticationv1.UserInfo
+		expected bool
+	}{
+		{authenticationv1.UserInfo{Username: "alice"}, true},
+		{authenticationv1.UserInfo{Username: "bob", Groups: []string{"system:authenticated", "incident-responders"}}, true},
+		{authenticationv1.UserInfo{Username: "system:serviceaccount:openshift-gitops:argocd"}, true},
+		{authenticationv1.UserInfo{Username: "system:serviceaccount:default:argocd"}, false},
+		{authenticationv1.UserInfo{Username: "bob", Groups: []string{"system:authenticated"}}, false},
+	}
+	for _, test := range tests {
+		if got := e.Matches(test.user); got != test.expected {
+			t.Fatalf("%+v: Expected match %v, got %v", test.user, test.expected, got)
+		}
+	}
+}
+
+func requestBy(username string) admissionctl.Request {
+	return admissionctl.Request{AdmissionRequest: admissionv1.AdmissionRequest{UserInfo: authenticationv1.UserInfo{Username: username}}}
+}
+
+func TestStoreMatch(t *testing.T) {
+	s := newStore()
+	now := created.Add(30 * time.Minute)
+	s.now = func() time.Time { return now }
+	s.set([]WebhookExemption{
+		newExemption("too-long", "namespace-validation", 7*24*time.Hour),
+		newExemption("scc", "scc-validation", time.Hour),
+	})
+	alice := requestBy("alice")
+
+	if e := s.Match("scc-validation", alice); e == nil || e.Name != "scc" {
+		t.Fatalf("Expected the scc exemption to match, got %v", e)
+	}
+	if e := s.Match("namespace-validation", alice); e != nil {
+		t.Fatalf("Expected an exemption exceeding %s to be ignored, got %v", MaxTTL, e.Name)
+	}
+	if e := s.Match("scc-validation", requestBy("bob")); e != nil {
+		t.Fatalf("Expected no exemption for bob, got %v", e.Name)
+	}
+	named := requestBy("bob")
+	named.Name = "restricted-v2"
+	if e := s.Match("scc-validation", named); e == nil || e.Name != "scc" {
+		t.Fatalf("Expected the scc exemption to match the exempted name, got %v", e)
+	}
+	now = created.Add(2 * time.Hour)
+	if e := s.Match("scc-validation", alice); e != nil {
+		t.Fatalf("Expected the expired exemption to no longer match, got %v", e.Name)
+	}
+	var nilStore *Store
+	if e := nilStore.Match("scc-validation", alice); e != nil {
+		t.Fatalf("Expected a nil Store to match nothing")
+	}
+}
+
+func TestList(t *testing.T) {
+	e := newExemption("scc", "scc-validation", time.Hour)
+	e.TypeMeta = metav1.TypeMeta{APIVersion: Group + "/" + Version, Kind: Kind}
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&e)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	s := newStore()
+	s.kubeClient = fake.NewClientBuilder().WithScheme(runtime.NewScheme()).WithObjects(&unstructured.Unstructured{Object: object}).Build()
+	exemptions, err := s.list(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	if len(exemptions) != 1 || exemptions[0].Spec.Webhook != "scc-validation" || !exemptions[0].Spec.ExpiresAt.Equal(&e.Spec.ExpiresAt) {
+		t.Fatalf("Unexpected exemptions %+v", exemptions)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	objects := []client.Object{}
+	for _, e := range []WebhookExemption{
+		newExemption("expired", "scc-validation", time.Hour),
+		newExemption("recently-expired", "scc-validation", 3*time.Hour),
+		newExemption("active", "scc-validation", 12*time.Hour),
+	} {
+		e.TypeMeta = metav1.TypeMeta{APIVersion: Group + "/" + Version, Kind: Kind}
+		object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, &unstructured.Unstructured{Object: object})
+	}
+	s := newStore()
+	s.now = func() time.Time { return created.Add(3*time.Hour + time.Minute) }
+	s.kubeClient = fake.NewClientBuilder().WithScheme(runtime.NewScheme()).WithObjects(objects...).Build()
+	exemptions, err := s.list(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.collect(context.Background(), exemptions)
+	// Collecting again ignores the exemptions already deleted
+	s.collect(context.Background(), exemptions)
+	exemptions, err = s.list(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, e := range exemptions {
+		names = append(names, e.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"active", "recently-expired"}) {
+		t.Fatalf("Expected the exemption expired for over %s to be deleted, got %v", GCAfter, names)
+	}
+}
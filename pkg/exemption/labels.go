@@ -0,0 +1,111 @@
+package exemption
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+)
+
+// ExemptionLabel exempts the workloads of a Namespace, or the requests of a
+// ServiceAccount, from the webhooks its value lists, e.g. certified add-on
+// namespaces and their operators. Webhook names are separated by dots, since
+// label values can't hold commas. Only the hookconfig.LabelExemptionWebhooks
+// honor it.
+//
+// namespace-validation protects the label from customers, and ServiceAccount
+// labels are only honored in privileged namespaces, which customers can't
+// modify, so only SRE and platform operators can apply it.
+const ExemptionLabel = "managed.openshift.io/webhook-exempt"
+
+var (
+	namespaceListGVK      = schema.GroupVersionKind{Version: "v1", Kind: "NamespaceList"}
+	serviceAccountListGVK = schema.GroupVersionKind{Version: "v1", Kind: "ServiceAccountList"}
+)
+
+// labelled are the webhooks each labelled namespace and service account is
+// exempted from
+type labelled struct {
+	// namespaces are keyed by name
+	namespaces map[string][]string
+	// serviceAccounts are keyed by username
+	serviceAccounts map[string][]string
+}
+
+// MatchLabel returns what exempts request from webhook by ExemptionLabel,
+// e.g. "namespace/my-addon", or an empty string if nothing does
+func (s *Store) MatchLabel(webhook string, request admissionctl.Request) string {
+	if s == nil || !hookconfig.IsLabelExemptWebhook(webhook) {
+		return ""
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if request.Namespace != "" && slices.Contains(s.labelled.namespaces[request.Namespace], webhook) {
+		return "namespace/" + request.Namespace
+	}
+	if slices.Contains(s.labelled.serviceAccounts[request.UserInfo.Username], webhook) {
+		return "serviceaccount/" + strings.TrimPrefix(request.UserInfo.Username, "system:serviceaccount:")
+	}
+	return ""
+}
+
+func (s *Store) refreshLabels(ctx context.Context) {
+	l, err := s.listLabelled(ctx)
+	if err != nil {
+		if err.Error() != s.lastLabelErr {
+			log.Error(err, "Failed to list the labelled namespaces and service accounts, keeping the last known label exemptions")
+			s.lastLabelErr = err.Error()
+		}
+		return
+	}
+	s.lastLabelErr = ""
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.labelled = l
+}
+
+func (s *Store) listLabelled(ctx context.Context) (labelled, error) {
+	l := labelled{namespaces: map[string][]string{}, serviceAccounts: map[string][]string{}}
+	if err := s.ensureClient(); err != nil {
+		return l, err
+	}
+	namespaces := &unstructured.UnstructuredList{}
+	namespaces.SetGroupVersionKind(namespaceListGVK)
+	if err := s.kubeClient.List(ctx, namespaces, client.HasLabels{ExemptionLabel}); err != nil {
+		return l, err
+	}
+	for _, ns := range namespaces.Items {
+		l.namespaces[ns.GetName()] = exemptedWebhooks(ns.GetLabels()[ExemptionLabel])
+	}
+	serviceAccounts := &unstructured.UnstructuredList{}
+	serviceAccounts.SetGroupVersionKind(serviceAccountListGVK)
+	if err := s.kubeClient.List(ctx, serviceAccounts, client.HasLabels{ExemptionLabel}); err != nil {
+		return l, err
+	}
+	for _, sa := range serviceAccounts.Items {
+		if !hookconfig.IsPrivilegedNamespace(sa.GetNamespace()) {
+			continue
+		}
+		username := fmt.Sprintf("system:serviceaccount:%s:%s", sa.GetNamespace(), sa.GetName())
+		l.serviceAccounts[username] = exemptedWebhooks(sa.GetLabels()[ExemptionLabel])
+	}
+	return l, nil
+}
+
+// exemptedWebhooks returns the webhook names of an ExemptionLabel value
+func exemptedWebhooks(value string) []string {
+	webhooks := []string{}
+	for _, webhook := range strings.Split(value, ".") {
+		if webhook != "" {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks
+}
@@ -0,0 +1,95 @@
+package exemption
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func newLabelled(gvk schema.GroupVersionKind, namespace, name, value string) client.Object {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	if value != "" {
+		obj.SetLabels(map[string]string{ExemptionLabel: value})
+	}
+	return obj
+}
+
+func newLabelRequest(namespace, username string) admissionctl.Request {
+	request := admissionctl.Request{}
+	request.Namespace = namespace
+	request.UserInfo.Username = username
+	return request
+}
+
+func TestMatchLabel(t *testing.T) {
+	serviceAccountGVK := schema.GroupVersionKind{Version: "v1", Kind: "ServiceAccount"}
+	s := newStore()
+	s.kubeClient = fake.NewClientBuilder().WithScheme(runtime.NewScheme()).WithObjects(
+		newLabelled(namespaceGVK, "", "addon-certified", "pod-validation.scc-validation"),
+		newLabelled(namespaceGVK, "", "customer-app", ""),
+		newLabelled(serviceAccountGVK, "redhat-addon-operator", "addon-operator", "scc-validation"),
+		// Customers may label their own service accounts
+		newLabelled(serviceAccountGVK, "customer-app", "deployer", "scc-validation"),
+	).Build()
+	s.refreshLabels(context.Background())
+
+	tests := []struct {
+		testID   string
+		webhook  string
+		request  admissionctl.Request
+		expected string
+	}{
+		{
+			testID:   "labelled namespace",
+			webhook:  "pod-validation",
+			request:  newLabelRequest("addon-certified", "system:serviceaccount:addon-certified:default"),
+			expected: "namespace/addon-certified",
+		},
+		{
+			testID:  "unlabelled namespace",
+			webhook: "pod-validation",
+			request: newLabelRequest("customer-app", "customer"),
+		},
+		{
+			testID:   "labelled privileged service account",
+			webhook:  "scc-validation",
+			request:  newLabelRequest("", "system:serviceaccount:redhat-addon-operator:addon-operator"),
+			expected: "serviceaccount/redhat-addon-operator:addon-operator",
+		},
+		{
+			testID:  "labelled customer service account",
+			webhook: "scc-validation",
+			request: newLabelRequest("", "system:serviceaccount:customer-app:deployer"),
+		},
+		{
+			testID:  "webhook not listed in the label",
+			webhook: "podimageregistry-validation",
+			request: newLabelRequest("addon-certified", "customer"),
+		},
+		{
+			// namespace-validation is not one of the LabelExemptionWebhooks
+			testID:  "webhook not honoring the label",
+			webhook: "namespace-validation",
+			request: newLabelRequest("addon-certified", "customer"),
+		},
+	}
+	for _, test := range tests {
+		if source := s.MatchLabel(test.webhook, test.request); source != test.expected {
+			t.Errorf("%s: Expected %q, got %q", test.testID, test.expected, source)
+		}
+	}
+
+	var nilStore *Store
+	if source := nilStore.MatchLabel("pod-validation", newLabelRequest("addon-certified", "customer")); source != "" {
+		t.Errorf("Expected a nil Store to exempt nothing, got %q", source)
+	}
+}
@@ -0,0 +1,186 @@
+package exemption
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/pointer"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	Group   string = "managed.openshift.io"
+	Version string = "v1alpha1"
+	Kind    string = "WebhookExemption"
+	Plural  string = "webhookexemptions"
+
+	// MaxTTL is the longest an exemption may last, from its creation to its
+	// expiry. Exemptions expiring later are ignored rather than shortened, so
+	// a typo can't leave a guardrail open for weeks.
+	MaxTTL = 24 * time.Hour
+	// GCAfter is how long after its expiry an exemption is deleted, so that
+	// it can still be looked at once it stopped applying
+	GCAfter = time.Hour
+)
+
+// listGVK is listed to load the WebhookExemptions
+var listGVK = schema.GroupVersionKind{Group: Group, Version: Version, Kind: Kind + "List"}
+
+// WebhookExemption exempts users, groups, service accounts or object names
+// from a named webhook until it expires
+type WebhookExemption struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec WebhookExemptionSpec `json:"spec"`
+}
+
+// WebhookExemptionSpec is who is exempted from which webhook, until when and
+// why
+type WebhookExemptionSpec struct {
+	// Webhook is the name of the exempted webhook, e.g. scc-validation
+	Webhook string `json:"webhook"`
+	// Users are exempted usernames
+	Users []string `json:"users,omitempty"`
+	// Groups are exempted groups
+	Groups []string `json:"groups,omitempty"`
+	// ServiceAccounts are exempted service accounts
+	ServiceAccounts []ServiceAccountReference `json:"serviceAccounts,omitempty"`
+	// Names are exempted names of the objects of the requests, in any
+	// namespace, whoever makes them
+	Names []string `json:"names,omitempty"`
+	// ExpiresAt is when the exemption stops applying. It must be within
+	// MaxTTL of the exemption's creation.
+	ExpiresAt metav1.Time `json:"expiresAt"`
+	// Reason is why the exemption exists, e.g. an incident ticket
+	Reason string `json:"reason"`
+}
+
+// ServiceAccountReference names a service account
+type ServiceAccountReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// Active returns whether e applies at now: it hasn't expired and it doesn't
+// last longer than MaxTTL
+func (e *WebhookExemption) Active(now time.Time) bool {
+	return now.Before(e.Spec.ExpiresAt.Time) && !e.exceedsMaxTTL()
+}
+
+func (e *WebhookExemption) exceedsMaxTTL() bool {
+	return e.Spec.ExpiresAt.Sub(e.CreationTimestamp.Time) > MaxTTL
+}
+
+// collectable returns whether e expired more than GCAfter before now
+func (e *WebhookExemption) collectable(now time.Time) bool {
+	return now.Sub(e.Spec.ExpiresAt.Time) > GCAfter
+}
+
+// MatchesRequest returns whether e exempts request: its user is exempted, or
+// the name of its object is
+func (e *WebhookExemption) MatchesRequest(request admissionctl.Request) bool {
+	return e.Matches(request.UserInfo) || (request.Name != "" && slices.Contains(e.Spec.Names, request.Name))
+}
+
+// Matches returns whether user is one of the exempted users, groups or
+// service accounts of e
+func (e *WebhookExemption) Matches(user authenticationv1.UserInfo) bool {
+	for _, name := range e.Spec.Users {
+		if name == user.Username {
+			return true
+		}
+	}
+	for _, sa := range e.Spec.ServiceAccounts {
+		if fmt.Sprintf("system:serviceaccount:%s:%s", sa.Namespace, sa.Name) == user.Username {
+			return true
+		}
+	}
+	for _, group := range e.Spec.Groups {
+		for _, userGroup := range user.Groups {
+			if group == userGroup {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CustomResourceDefinition returns the WebhookExemption CRD
+func CustomResourceDefinition() *apiextensionsv1.CustomResourceDefinition {
+	stringList := apiextensionsv1.JSONSchemaProps{
+		Type:  "array",
+		Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{Type: "string", MinLength: pointer.Int64(1)}},
+	}
+	nonEmpty := apiextensionsv1.JSONSchemaProps{Type: "string", MinLength: pointer.Int64(1)}
+	return &apiextensionsv1.CustomResourceDefinition{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "CustomResourceDefinition",
+			APIVersion: apiextensionsv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: Plural + "." + Group,
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: Group,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural:   Plural,
+				Singular: "webhookexemption",
+				Kind:     Kind,
+				ListKind: Kind + "List",
+			},
+			Scope: apiextensionsv1.ClusterScoped,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{
+					Name:    Version,
+					Served:  true,
+					Storage: true,
+					AdditionalPrinterColumns: []apiextensionsv1.CustomResourceColumnDefinition{
+						{Name: "Webhook", Type: "string", JSONPath: ".spec.webhook"},
+						{Name: "Expires", Type: "date", JSONPath: ".spec.expiresAt"},
+						{Name: "Reason", Type: "string", JSONPath: ".spec.reason"},
+					},
+					Schema: &apiextensionsv1.CustomResourceValidation{
+						OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+							Type:     "object",
+							Required: []string{"spec"},
+							Properties: map[string]apiextensionsv1.JSONSchemaProps{
+								"apiVersion": {Type: "string"},
+								"kind":       {Type: "string"},
+								"metadata":   {Type: "object"},
+								"spec": {
+									Type:     "object",
+									Required: []string{"webhook", "expiresAt", "reason"},
+									Properties: map[string]apiextensionsv1.JSONSchemaProps{
+										"webhook": nonEmpty,
+										"users":   stringList,
+										"groups":  stringList,
+										"names":   stringList,
+										"serviceAccounts": {
+											Type: "array",
+											Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{
+												Type:     "object",
+												Required: []string{"namespace", "name"},
+												Properties: map[string]apiextensionsv1.JSONSchemaProps{
+													"namespace": nonEmpty,
+													"name":      nonEmpty,
+												},
+											}},
+										},
+										"expiresAt": {Type: "string", Format: "date-time"},
+										"reason":    nonEmpty,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,130 @@
+package gvk
+
+import "fmt"
+
+// convertCustomResourceDefinition moves the fields apiextensions.k8s.io/v1beta1
+// sets for every version of a CustomResourceDefinition to each of its
+// versions, as apiextensions.k8s.io/v1 expects them
+func convertCustomResourceDefinition(obj map[string]interface{}) error {
+	spec, ok := obj["spec"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	versions, _ := spec["versions"].([]interface{})
+	if len(versions) == 0 {
+		if name, ok := spec["version"].(string); ok {
+			versions = []interface{}{map[string]interface{}{"name": name, "served": true, "storage": true}}
+		}
+	}
+	columns, _ := spec["additionalPrinterColumns"].([]interface{})
+	for _, v := range versions {
+		version, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected the versions of the CustomResourceDefinition to be objects")
+		}
+		if validation, ok := spec["validation"].(map[string]interface{}); ok {
+			setDefault(version, "schema", validation)
+		}
+		if subresources, ok := spec["subresources"]; ok {
+			setDefault(version, "subresources", subresources)
+		}
+		if columns != nil {
+			setDefault(version, "additionalPrinterColumns", columns)
+		}
+		if versionColumns, ok := version["additionalPrinterColumns"].([]interface{}); ok {
+			for _, c := range versionColumns {
+				if column, ok := c.(map[string]interface{}); ok {
+					rename(column, "JSONPath", "jsonPath")
+				}
+			}
+		}
+	}
+	if versions != nil {
+		spec["versions"] = versions
+	}
+	for _, field := range []string{"version", "validation", "subresources", "additionalPrinterColumns"} {
+		delete(spec, field)
+	}
+	if conversion, ok := spec["conversion"].(map[string]interface{}); ok {
+		webhook := map[string]interface{}{}
+		if clientConfig, ok := conversion["webhookClientConfig"]; ok {
+			webhook["clientConfig"] = clientConfig
+			delete(conversion, "webhookClientConfig")
+		}
+		if reviewVersions, ok := conversion["conversionReviewVersions"]; ok {
+			webhook["conversionReviewVersions"] = reviewVersions
+			delete(conversion, "conversionReviewVersions")
+		}
+		if len(webhook) > 0 {
+			conversion["webhook"] = webhook
+		}
+	}
+	return nil
+}
+
+// convertIngress converts the backends of a networking.k8s.io/v1beta1 or
+// extensions/v1beta1 Ingress, which name their service and port flatly, to
+// the ones of networking.k8s.io/v1
+func convertIngress(obj map[string]interface{}) error {
+	spec, ok := obj["spec"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	rename(spec, "backend", "defaultBackend")
+	if backend, ok := spec["defaultBackend"].(map[string]interface{}); ok {
+		convertIngressBackend(backend)
+	}
+	rules, _ := spec["rules"].([]interface{})
+	for _, r := range rules {
+		rule, _ := r.(map[string]interface{})
+		http, _ := rule["http"].(map[string]interface{})
+		paths, _ := http["paths"].([]interface{})
+		for _, p := range paths {
+			path, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			setDefault(path, "pathType", "ImplementationSpecific")
+			if backend, ok := path["backend"].(map[string]interface{}); ok {
+				convertIngressBackend(backend)
+			}
+		}
+	}
+	return nil
+}
+
+func convertIngressBackend(backend map[string]interface{}) {
+	name, hasName := backend["serviceName"]
+	port, hasPort := backend["servicePort"]
+	if !hasName && !hasPort {
+		return
+	}
+	service := map[string]interface{}{}
+	if hasName {
+		service["name"] = name
+	}
+	switch port := port.(type) {
+	case float64:
+		service["port"] = map[string]interface{}{"number": port}
+	case string:
+		service["port"] = map[string]interface{}{"name": port}
+	}
+	backend["service"] = service
+	delete(backend, "serviceName")
+	delete(backend, "servicePort")
+}
+
+// setDefault sets field of obj to value unless it is set
+func setDefault(obj map[string]interface{}, field string, value interface{}) {
+	if _, ok := obj[field]; !ok {
+		obj[field] = value
+	}
+}
+
+// rename moves field from of obj to to
+func rename(obj map[string]interface{}, from, to string) {
+	if value, ok := obj[from]; ok {
+		setDefault(obj, to, value)
+		delete(obj, from)
+	}
+}
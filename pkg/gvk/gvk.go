@@ -0,0 +1,150 @@
+// Package gvk normalizes the group, version and kind of admission requests.
+// Webhooks whose rules match every version of a group get each request in
+// the version it was made in, while they decode the object into the types of
+// the preferred version. The objects of older versions whose fields differ,
+// e.g. v1beta1 CustomResourceDefinitions, would then decode with empty
+// fields and silently skip the checks of the webhook.
+package gvk
+
+import (
+	"encoding/json"
+	"fmt"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// conversion converts an object of an older version to the preferred one. It
+// gets the object decoded, and needn't set its apiVersion.
+type conversion func(obj map[string]interface{}) error
+
+type target struct {
+	kind    metav1.GroupVersionKind
+	convert conversion
+}
+
+// sameFields converts objects whose fields didn't change between versions
+func sameFields(map[string]interface{}) error { return nil }
+
+// conversions maps the older versions of the kinds to their preferred
+// version. Only the kinds the webhooks may be sent are listed.
+var conversions = map[metav1.GroupVersionKind]target{
+	{Group: "apiextensions.k8s.io", Version: "v1beta1", Kind: "CustomResourceDefinition"}: {
+		kind:    metav1.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"},
+		convert: convertCustomResourceDefinition,
+	},
+	{Group: "networking.k8s.io", Version: "v1beta1", Kind: "Ingress"}: {
+		kind:    metav1.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"},
+		convert: convertIngress,
+	},
+	{Group: "extensions", Version: "v1beta1", Kind: "Ingress"}: {
+		kind:    metav1.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"},
+		convert: convertIngress,
+	},
+	{Group: "extensions", Version: "v1beta1", Kind: "NetworkPolicy"}: {
+		kind:    metav1.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"},
+		convert: sameFields,
+	},
+	{Group: "policy", Version: "v1beta1", Kind: "PodDisruptionBudget"}: {
+		kind:    metav1.GroupVersionKind{Group: "policy", Version: "v1", Kind: "PodDisruptionBudget"},
+		convert: sameFields,
+	},
+	{Group: "batch", Version: "v1beta1", Kind: "CronJob"}: {
+		kind:    metav1.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"},
+		convert: sameFields,
+	},
+	{Group: "autoscaling", Version: "v2beta2", Kind: "HorizontalPodAutoscaler"}: {
+		kind:    metav1.GroupVersionKind{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler"},
+		convert: sameFields,
+	},
+}
+
+func init() {
+	for _, kind := range []string{"Role", "RoleBinding", "ClusterRole", "ClusterRoleBinding"} {
+		conversions[metav1.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Kind: kind}] = target{
+			kind:    metav1.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: kind},
+			convert: sameFields,
+		}
+	}
+	for _, kind := range []string{"Deployment", "DaemonSet", "ReplicaSet", "StatefulSet"} {
+		for _, gv := range []metav1.GroupVersion{{Group: "apps", Version: "v1beta1"}, {Group: "apps", Version: "v1beta2"}, {Group: "extensions", Version: "v1beta1"}} {
+			conversions[metav1.GroupVersionKind{Group: gv.Group, Version: gv.Version, Kind: kind}] = target{
+				kind:    metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: kind},
+				convert: sameFields,
+			}
+		}
+	}
+}
+
+// Preferred returns the preferred version of kind, and whether kind is an
+// older version of it
+func Preferred(kind metav1.GroupVersionKind) (metav1.GroupVersionKind, bool) {
+	t, ok := conversions[kind]
+	if !ok {
+		return kind, false
+	}
+	return t.kind, true
+}
+
+// Normalize converts the objects of request to the preferred version of
+// their kind, unless a rule of rules names the version of the request, i.e.
+// the webhook expects it. The RequestKind keeps the kind the request was
+// made with. It returns whether the request was converted.
+func Normalize(request admissionv1.AdmissionRequest, rules []admissionregv1.RuleWithOperations) (admissionv1.AdmissionRequest, bool, error) {
+	t, ok := conversions[request.Kind]
+	if !ok || namesVersion(rules, request.Kind) {
+		return request, false, nil
+	}
+	object, err := convert(request.Object.Raw, t)
+	if err != nil {
+		return request, false, fmt.Errorf("failed to convert the %s object to %s: %w", request.Kind.String(), t.kind.String(), err)
+	}
+	oldObject, err := convert(request.OldObject.Raw, t)
+	if err != nil {
+		return request, false, fmt.Errorf("failed to convert the old %s object to %s: %w", request.Kind.String(), t.kind.String(), err)
+	}
+	request.Object.Raw = object
+	request.OldObject.Raw = oldObject
+	if request.RequestKind == nil {
+		kind := request.Kind
+		request.RequestKind = &kind
+	}
+	request.Kind = t.kind
+	request.Resource.Group = t.kind.Group
+	request.Resource.Version = t.kind.Version
+	return request, true, nil
+}
+
+// namesVersion returns whether a rule names the group and version of kind
+// rather than matching every version
+func namesVersion(rules []admissionregv1.RuleWithOperations, kind metav1.GroupVersionKind) bool {
+	for _, rule := range rules {
+		for _, group := range rule.APIGroups {
+			if group != kind.Group && group != "*" {
+				continue
+			}
+			for _, version := range rule.APIVersions {
+				if version == kind.Version {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func convert(raw []byte, t target) ([]byte, error) {
+	if len(raw) == 0 {
+		return raw, nil
+	}
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	if err := t.convert(obj); err != nil {
+		return nil, err
+	}
+	obj["apiVersion"] = metav1.GroupVersion{Group: t.kind.Group, Version: t.kind.Version}.String()
+	return json.Marshal(obj)
+}
@@ -0,0 +1,177 @@
+package gvk
+
+import (
+	"encoding/json"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var everyVersion = []admissionregv1.RuleWithOperations{{
+	Rule: admissionregv1.Rule{APIGroups: []string{"*"}, APIVersions: []string{"*"}, Resources: []string{"*"}},
+}}
+
+func request(kind metav1.GroupVersionKind, resource, object string) admissionv1.AdmissionRequest {
+	return admissionv1.AdmissionRequest{
+		Kind:      kind,
+		Resource:  metav1.GroupVersionResource{Group: kind.Group, Version: kind.Version, Resource: resource},
+		Operation: admissionv1.Update,
+		Object:    runtime.RawExtension{Raw: []byte(object)},
+		OldObject: runtime.RawExtension{Raw: []byte(object)},
+	}
+}
+
+func TestNormalizeCustomResourceDefinition(t *testing.T) {
+	kind := metav1.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1beta1", Kind: "CustomResourceDefinition"}
+	object := `{
+		"apiVersion": "apiextensions.k8s.io/v1beta1",
+		"kind": "CustomResourceDefinition",
+		"metadata": {"name": "foos.example.com"},
+		"spec": {
+			"group": "example.com",
+			"version": "v1",
+			"names": {"kind": "Foo", "plural": "foos"},
+			"scope": "Namespaced",
+			"validation": {"openAPIV3Schema": {"type": "object"}},
+			"subresources": {"status": {}},
+			"additionalPrinterColumns": [{"name": "Age", "type": "date", "JSONPath": ".metadata.creationTimestamp"}],
+			"conversion": {"strategy": "Webhook", "webhookClientConfig": {"url": "https://foo"}, "conversionReviewVersions": ["v1"]}
+		}
+	}`
+	normalized, converted, err := Normalize(request(kind, "customresourcedefinitions", object), everyVersion)
+	if err != nil || !converted {
+		t.Fatalf("expected the CustomResourceDefinition to be converted, got %v, %v", converted, err)
+	}
+	if normalized.Kind.Version != "v1" || normalized.Resource.Version != "v1" || normalized.RequestKind == nil || *normalized.RequestKind != kind {
+		t.Fatalf("expected the v1 kind and resource, and the v1beta1 request kind, got %v, %v, %v", normalized.Kind, normalized.Resource, normalized.RequestKind)
+	}
+	for _, raw := range [][]byte{normalized.Object.Raw, normalized.OldObject.Raw} {
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := json.Unmarshal(raw, crd); err != nil {
+			t.Fatal(err)
+		}
+		if crd.APIVersion != "apiextensions.k8s.io/v1" || len(crd.Spec.Versions) != 1 {
+			t.Fatalf("expected a v1 CustomResourceDefinition with one version, got %s", raw)
+		}
+		v := crd.Spec.Versions[0]
+		if v.Name != "v1" || !v.Served || !v.Storage || v.Schema == nil || v.Schema.OpenAPIV3Schema.Type != "object" || v.Subresources == nil || v.Subresources.Status == nil {
+			t.Fatalf("expected the version to carry the schema and subresources, got %+v", v)
+		}
+		if len(v.AdditionalPrinterColumns) != 1 || v.AdditionalPrinterColumns[0].JSONPath != ".metadata.creationTimestamp" {
+			t.Fatalf("expected the printer columns of the version, got %+v", v.AdditionalPrinterColumns)
+		}
+		if crd.Spec.Conversion == nil || crd.Spec.Conversion.Webhook == nil || crd.Spec.Conversion.Webhook.ClientConfig == nil || *crd.Spec.Conversion.Webhook.ClientConfig.URL != "https://foo" {
+			t.Fatalf("expected the conversion webhook, got %+v", crd.Spec.Conversion)
+		}
+	}
+}
+
+func TestNormalizeIngress(t *testing.T) {
+	kind := metav1.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Ingress"}
+	object := `{
+		"apiVersion": "extensions/v1beta1",
+		"kind": "Ingress",
+		"metadata": {"name": "web", "namespace": "my-project"},
+		"spec": {
+			"backend": {"serviceName": "default", "servicePort": 80},
+			"rules": [{"host": "example.com", "http": {"paths": [{"path": "/", "backend": {"serviceName": "web", "servicePort": "http"}}]}}]
+		}
+	}`
+	normalized, converted, err := Normalize(request(kind, "ingresses", object), everyVersion)
+	if err != nil || !converted {
+		t.Fatalf("expected the Ingress to be converted, got %v, %v", converted, err)
+	}
+	if normalized.Kind.Group != "networking.k8s.io" || normalized.Resource.Group != "networking.k8s.io" {
+		t.Fatalf("expected the networking.k8s.io group, got %v, %v", normalized.Kind, normalized.Resource)
+	}
+	ingress := &networkingv1.Ingress{}
+	if err := json.Unmarshal(normalized.Object.Raw, ingress); err != nil {
+		t.Fatal(err)
+	}
+	if b := ingress.Spec.DefaultBackend; b == nil || b.Service == nil || b.Service.Name != "default" || b.Service.Port.Number != 80 {
+		t.Fatalf("expected the default backend, got %+v", b)
+	}
+	path := ingress.Spec.Rules[0].HTTP.Paths[0]
+	if path.Backend.Service == nil || path.Backend.Service.Name != "web" || path.Backend.Service.Port.Name != "http" || path.PathType == nil || *path.PathType != networkingv1.PathTypeImplementationSpecific {
+		t.Fatalf("expected the backend and path type of the path, got %+v", path)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	pdb := metav1.GroupVersionKind{Group: "policy", Version: "v1beta1", Kind: "PodDisruptionBudget"}
+	tests := []struct {
+		name      string
+		request   admissionv1.AdmissionRequest
+		rules     []admissionregv1.RuleWithOperations
+		converted bool
+		err       bool
+	}{
+		{
+			name:      "same fields",
+			request:   request(pdb, "poddisruptionbudgets", `{"apiVersion": "policy/v1beta1", "kind": "PodDisruptionBudget", "spec": {"minAvailable": 1}}`),
+			rules:     everyVersion,
+			converted: true,
+		},
+		{
+			name:    "rule naming the version",
+			request: request(pdb, "poddisruptionbudgets", `{"apiVersion": "policy/v1beta1", "kind": "PodDisruptionBudget"}`),
+			rules: []admissionregv1.RuleWithOperations{{
+				Rule: admissionregv1.Rule{APIGroups: []string{"policy"}, APIVersions: []string{"v1", "v1beta1"}, Resources: []string{"poddisruptionbudgets"}},
+			}},
+		},
+		{
+			name:    "preferred version",
+			request: request(metav1.GroupVersionKind{Group: "policy", Version: "v1", Kind: "PodDisruptionBudget"}, "poddisruptionbudgets", `{}`),
+			rules:   everyVersion,
+		},
+		{
+			name:    "no object",
+			request: admissionv1.AdmissionRequest{Kind: pdb, Operation: admissionv1.Delete},
+			rules:   everyVersion,
+			// The kind is still the preferred one
+			converted: true,
+		},
+		{
+			name:    "undecodable",
+			request: request(pdb, "poddisruptionbudgets", `not json`),
+			rules:   everyVersion,
+			err:     true,
+		},
+	}
+	for _, test := range tests {
+		normalized, converted, err := Normalize(test.request, test.rules)
+		if (err != nil) != test.err || converted != test.converted {
+			t.Errorf("%s: expected converted %v and an error %v, got %v, %v", test.name, test.converted, test.err, converted, err)
+			continue
+		}
+		if !converted {
+			if normalized.Kind != test.request.Kind || string(normalized.Object.Raw) != string(test.request.Object.Raw) {
+				t.Errorf("%s: expected the request to be unchanged, got %v %s", test.name, normalized.Kind, normalized.Object.Raw)
+			}
+			continue
+		}
+		if normalized.Kind.Version != "v1" {
+			t.Errorf("%s: expected the v1 kind, got %v", test.name, normalized.Kind)
+		}
+		if len(normalized.Object.Raw) > 0 {
+			var obj map[string]interface{}
+			if err := json.Unmarshal(normalized.Object.Raw, &obj); err != nil || obj["apiVersion"] != "policy/v1" {
+				t.Errorf("%s: expected a policy/v1 object, got %s", test.name, normalized.Object.Raw)
+			}
+		}
+	}
+}
+
+func TestPreferred(t *testing.T) {
+	if kind, ok := Preferred(metav1.GroupVersionKind{Group: "apps", Version: "v1beta2", Kind: "Deployment"}); !ok || kind != (metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}) {
+		t.Errorf("expected apps/v1 to be preferred, got %v, %v", kind, ok)
+	}
+	if _, ok := Preferred(metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}); ok {
+		t.Error("expected v1 Pods not to be converted")
+	}
+}
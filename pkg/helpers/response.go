@@ -1,36 +1,73 @@
 package helpers
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 	"net/http"
+	"sync"
 
 	admissionapi "k8s.io/api/admission/v1"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
+// maxPooledBufferSize is the largest response buffer returned to the pool,
+// so that a rare large response doesn't stay allocated
+const maxPooledBufferSize = 64 << 10
+
 var log = logf.Log.WithName("response_helper")
 
+// The response buffers, annotations and AdmissionReviews are pooled, since
+// every request allocates them and the webhook pods of large clusters answer
+// hundreds of requests a second
+var (
+	bufferPool      = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+	annotationsPool = sync.Pool{New: func() interface{} { return map[string]string{} }}
+	reviewPool      = sync.Pool{New: func() interface{} { return new(admissionapi.AdmissionReview) }}
+)
+
 // SendResponse Send the AdmissionReview.
 func SendResponse(w io.Writer, resp admissionctl.Response) {
 
 	// Apply ownership annotation to allow for granular alerts for
 	// manipulation of SREP owned webhooks.
-	resp.AuditAnnotations = map[string]string{
-		"owner": "srep-managed-webhook",
+	annotations := annotationsPool.Get().(map[string]string)
+	defer func() {
+		clear(annotations)
+		annotationsPool.Put(annotations)
+	}()
+	annotations["owner"] = "srep-managed-webhook"
+	for k, v := range resp.AuditAnnotations {
+		annotations[k] = v
 	}
+	resp.AuditAnnotations = annotations
 
-	encoder := json.NewEncoder(w)
-	responseAdmissionReview := admissionapi.AdmissionReview{
-		Response: &resp.AdmissionResponse,
-	}
+	// The response is encoded in full before it is written, so that the API
+	// server doesn't get a truncated one on an encoding error
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBufferSize {
+			bufferPool.Put(buf)
+		}
+	}()
+	responseAdmissionReview := reviewPool.Get().(*admissionapi.AdmissionReview)
+	defer func() {
+		*responseAdmissionReview = admissionapi.AdmissionReview{}
+		reviewPool.Put(responseAdmissionReview)
+	}()
+	responseAdmissionReview.Response = &resp.AdmissionResponse
 	responseAdmissionReview.APIVersion = admissionapi.SchemeGroupVersion.String()
 	responseAdmissionReview.Kind = "AdmissionReview"
-	err := encoder.Encode(responseAdmissionReview)
+	err := json.NewEncoder(buf).Encode(responseAdmissionReview)
 	// TODO (lisa): handle this in a non-recursive way (why would the second one succeed)?
 	if err != nil {
 		log.Error(err, "Failed to encode Response", "response", resp)
 		SendResponse(w, admissionctl.Errored(http.StatusInternalServerError, err))
+		return
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		log.Error(err, "Failed to write Response", "uid", resp.UID)
 	}
 }
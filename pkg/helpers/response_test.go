@@ -93,3 +93,19 @@ func TestResponse(t *testing.T) {
 	}
 
 }
+
+func TestResponseKeepsAuditAnnotations(t *testing.T) {
+	buf := makeBuffer()
+	resp := admissionctl.Denied("SCC001_DEFAULT_SCC_MODIFY")
+	resp.AuditAnnotations = map[string]string{"reason-code": "SCC001_DEFAULT_SCC_MODIFY"}
+	SendResponse(buf, resp)
+
+	decodedResult := &admissionapi.AdmissionReview{}
+	if err := json.Unmarshal(buf.Bytes(), decodedResult); err != nil {
+		t.Fatalf("Couldn't unmarshal the JSON blob: %s", err.Error())
+	}
+	annotations := decodedResult.Response.AuditAnnotations
+	if annotations["owner"] != "srep-managed-webhook" || annotations["reason-code"] != "SCC001_DEFAULT_SCC_MODIFY" {
+		t.Fatalf("Expected the owner and reason-code audit annotations, got %v", annotations)
+	}
+}
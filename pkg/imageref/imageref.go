@@ -0,0 +1,92 @@
+// Package imageref pins the image references of the generated manifests to
+// digests and rewrites them to the mirrors of disconnected fleets
+package imageref
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Mirror rewrites the references of the images under Source, a registry or
+// a repository prefix, to the same images under Mirror
+type Mirror struct {
+	Source string
+	Mirror string
+}
+
+// Mirrors are the mirrors of the images of the manifests
+type Mirrors []Mirror
+
+// ParseMirrors parses comma-separated source=mirror pairs, e.g.
+// quay.io/app-sre=mirror.example.com/app-sre
+func ParseMirrors(spec string) (Mirrors, error) {
+	mirrors := Mirrors{}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		source, mirror, ok := strings.Cut(pair, "=")
+		source, mirror = strings.TrimSuffix(source, "/"), strings.TrimSuffix(mirror, "/")
+		if !ok || source == "" || mirror == "" {
+			return nil, fmt.Errorf("invalid image mirror %q, it must be source=mirror", pair)
+		}
+		if hasTag(source) || hasTag(mirror) {
+			return nil, fmt.Errorf("invalid image mirror %q, the source and mirror must be repositories without a tag or digest", pair)
+		}
+		mirrors = append(mirrors, Mirror{Source: source, Mirror: mirror})
+	}
+	// The longest source matches first, like the mirror sets of the cluster
+	sort.SliceStable(mirrors, func(i, j int) bool {
+		return len(mirrors[i].Source) > len(mirrors[j].Source)
+	})
+	return mirrors, nil
+}
+
+// Rewrite returns ref with the repository of the first mirror whose source
+// it is under, or ref if none is
+func (m Mirrors) Rewrite(ref string) string {
+	repository, suffix := Split(ref)
+	for _, mirror := range m {
+		if repository == mirror.Source || strings.HasPrefix(repository, mirror.Source+"/") {
+			return mirror.Mirror + strings.TrimPrefix(repository, mirror.Source) + suffix
+		}
+	}
+	return ref
+}
+
+// Split splits ref into its repository and its tag or digest, with the
+// separator, e.g. quay.io/app-sre/webhooks and @sha256:...
+func Split(ref string) (repository, suffix string) {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		return ref[:i], ref[i:]
+	}
+	// A colon after the last slash separates the tag, one before it the
+	// port of the registry
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i], ref[i:]
+	}
+	return ref, ""
+}
+
+// IsPinned returns whether ref refers to its image by digest
+func IsPinned(ref string) bool {
+	_, suffix := Split(ref)
+	return strings.HasPrefix(suffix, "@sha256:")
+}
+
+// Pin returns the reference to the image of ref by digest
+func Pin(ref, digest string) (string, error) {
+	if !strings.HasPrefix(digest, "sha256:") || len(digest) != len("sha256:")+64 {
+		return "", fmt.Errorf("invalid digest %q of %s", digest, ref)
+	}
+	repository, _ := Split(ref)
+	return repository + "@" + digest, nil
+}
+
+// hasTag returns whether ref carries a tag or digest
+func hasTag(ref string) bool {
+	_, suffix := Split(ref)
+	return suffix != ""
+}
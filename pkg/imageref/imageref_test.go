@@ -0,0 +1,79 @@
+package imageref
+
+import (
+	"strings"
+	"testing"
+)
+
+const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestParseMirrors(t *testing.T) {
+	mirrors, err := ParseMirrors("quay.io=mirror.example.com/quay, quay.io/app-sre=mirror.example.com:5000/app-sre/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mirrors) != 2 || mirrors[0].Source != "quay.io/app-sre" || mirrors[0].Mirror != "mirror.example.com:5000/app-sre" {
+		t.Fatalf("expected the longest source first without trailing slashes, got %+v", mirrors)
+	}
+	if mirrors, err := ParseMirrors(""); err != nil || len(mirrors) != 0 {
+		t.Fatalf("expected no mirrors, got %v, %v", mirrors, err)
+	}
+	for _, invalid := range []string{"quay.io", "=mirror.example.com", "quay.io/app-sre/webhooks:latest=mirror.example.com/webhooks", "quay.io=mirror.example.com@" + digest} {
+		if _, err := ParseMirrors(invalid); err == nil {
+			t.Errorf("expected %q to be an invalid mirror", invalid)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	mirrors, _ := ParseMirrors("quay.io=mirror.example.com/quay,quay.io/app-sre=mirror.example.com:5000/app-sre")
+	tests := []struct {
+		ref      string
+		expected string
+	}{
+		{"quay.io/app-sre/webhooks@" + digest, "mirror.example.com:5000/app-sre/webhooks@" + digest},
+		{"quay.io/app-sre/webhooks:v1", "mirror.example.com:5000/app-sre/webhooks:v1"},
+		{"quay.io/openshift/webhooks", "mirror.example.com/quay/openshift/webhooks"},
+		{"quay.io/app-sre-other/webhooks:v1", "mirror.example.com/quay/app-sre-other/webhooks:v1"},
+		{"registry.redhat.io/webhooks:v1", "registry.redhat.io/webhooks:v1"},
+	}
+	for _, test := range tests {
+		if got := mirrors.Rewrite(test.ref); got != test.expected {
+			t.Errorf("expected %s to be rewritten to %s, got %s", test.ref, test.expected, got)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		ref        string
+		repository string
+		suffix     string
+	}{
+		{"quay.io/app-sre/webhooks", "quay.io/app-sre/webhooks", ""},
+		{"quay.io/app-sre/webhooks:v1", "quay.io/app-sre/webhooks", ":v1"},
+		{"registry.example.com:5000/webhooks", "registry.example.com:5000/webhooks", ""},
+		{"registry.example.com:5000/webhooks:v1@" + digest, "registry.example.com:5000/webhooks:v1", "@" + digest},
+	}
+	for _, test := range tests {
+		if repository, suffix := Split(test.ref); repository != test.repository || suffix != test.suffix {
+			t.Errorf("expected %s to split into %s and %s, got %s and %s", test.ref, test.repository, test.suffix, repository, suffix)
+		}
+	}
+}
+
+func TestPin(t *testing.T) {
+	pinned, err := Pin("quay.io/app-sre/webhooks:v1", digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pinned != "quay.io/app-sre/webhooks@"+digest || !IsPinned(pinned) {
+		t.Fatalf("expected the image to be pinned by digest, got %s", pinned)
+	}
+	if IsPinned("quay.io/app-sre/webhooks:v1") {
+		t.Fatal("expected a tag not to pin the image")
+	}
+	if _, err := Pin("quay.io/app-sre/webhooks:v1", strings.TrimPrefix(digest, "sha256:")); err == nil {
+		t.Fatal("expected a digest without its algorithm to fail")
+	}
+}
@@ -0,0 +1,115 @@
+package k8sutil
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fipsEnabledPath is set to 1 by the kernel of FIPS mode nodes
+var fipsEnabledPath = "/proc/sys/crypto/fips_enabled"
+
+// Capabilities are the properties of the cluster webhooks may vary their
+// checks by. They are detected once at startup, since none of them changes
+// during a cluster's life.
+type Capabilities struct {
+	// Platform is the cloud provider, e.g. AWS or GCP
+	Platform configv1.PlatformType `json:"platform"`
+	// Region is the cloud region, e.g. us-gov-west-1, if the platform
+	// reports one
+	Region string `json:"region,omitempty"`
+	// NetworkType is the cluster network plugin, OVNKubernetes or
+	// OpenShiftSDN
+	NetworkType string `json:"networkType"`
+	// Private is whether the cluster has no public DNS zone, so its ingress
+	// isn't reachable from the internet
+	Private bool `json:"private"`
+	// FIPS is whether the nodes run in FIPS mode
+	FIPS bool `json:"fips"`
+}
+
+var (
+	capabilitiesMu sync.RWMutex
+	capabilities   *Capabilities
+)
+
+// ClusterCapabilities returns the capabilities detected by
+// LoadCapabilities, and false if they haven't been detected, in which case
+// webhooks should keep their default checks
+func ClusterCapabilities() (Capabilities, bool) {
+	capabilitiesMu.RLock()
+	defer capabilitiesMu.RUnlock()
+	if capabilities == nil {
+		return Capabilities{}, false
+	}
+	return *capabilities, true
+}
+
+// platformRegion returns the region of the platforms which report one
+func platformRegion(status *configv1.PlatformStatus) string {
+	switch {
+	case status.AWS != nil:
+		return status.AWS.Region
+	case status.GCP != nil:
+		return status.GCP.Region
+	case status.IBMCloud != nil:
+		return status.IBMCloud.Location
+	case status.PowerVS != nil:
+		return status.PowerVS.Region
+	}
+	return ""
+}
+
+// LoadCapabilities detects the capabilities of the cluster from its
+// Infrastructure, Network and DNS configs and the node's FIPS mode, and
+// stores them for ClusterCapabilities. kubeClient is created if nil.
+func LoadCapabilities(ctx context.Context, kubeClient client.Client) (Capabilities, error) {
+	if kubeClient == nil {
+		scheme := runtime.NewScheme()
+		if err := configv1.Install(scheme); err != nil {
+			return Capabilities{}, err
+		}
+		c, err := KubeClient(scheme)
+		if err != nil {
+			return Capabilities{}, fmt.Errorf("fail creating KubeClient to detect the cluster capabilities: %v", err)
+		}
+		kubeClient = c
+	}
+
+	caps := Capabilities{}
+	infra := &configv1.Infrastructure{}
+	if err := kubeClient.Get(ctx, client.ObjectKey{Name: "cluster"}, infra); err != nil {
+		return Capabilities{}, fmt.Errorf("failed to get the Infrastructure: %v", err)
+	}
+	if infra.Status.PlatformStatus != nil {
+		caps.Platform = infra.Status.PlatformStatus.Type
+		caps.Region = platformRegion(infra.Status.PlatformStatus)
+	}
+	network := &configv1.Network{}
+	if err := kubeClient.Get(ctx, client.ObjectKey{Name: "cluster"}, network); err != nil {
+		return Capabilities{}, fmt.Errorf("failed to get the Network config: %v", err)
+	}
+	caps.NetworkType = network.Status.NetworkType
+	if caps.NetworkType == "" {
+		caps.NetworkType = network.Spec.NetworkType
+	}
+	dns := &configv1.DNS{}
+	if err := kubeClient.Get(ctx, client.ObjectKey{Name: "cluster"}, dns); err != nil {
+		return Capabilities{}, fmt.Errorf("failed to get the DNS config: %v", err)
+	}
+	caps.Private = dns.Spec.PublicZone == nil
+	if fips, err := os.ReadFile(fipsEnabledPath); err == nil {
+		caps.FIPS = strings.TrimSpace(string(fips)) == "1"
+	}
+
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+	capabilities = &caps
+	return caps, nil
+}
@@ -0,0 +1,47 @@
+package k8sutil
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLoadCapabilities(t *testing.T) {
+	s := runtime.NewScheme()
+	_ = configv1.Install(s)
+	kubeClient := fake.NewClientBuilder().WithScheme(s).WithObjects(
+		&configv1.Infrastructure{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+			Status: configv1.InfrastructureStatus{PlatformStatus: &configv1.PlatformStatus{
+				Type: configv1.GCPPlatformType,
+				GCP:  &configv1.GCPPlatformStatus{Region: "us-east1"},
+			}},
+		},
+		&configv1.Network{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+			Status:     configv1.NetworkStatus{NetworkType: "OVNKubernetes"},
+		},
+		&configv1.DNS{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}},
+	).Build()
+	fipsEnabledPath = t.TempDir() + "/fips_enabled"
+	if err := os.WriteFile(fipsEnabledPath, []byte("1\n"), 0600); err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+
+	if _, loaded := ClusterCapabilities(); loaded {
+		t.Fatalf("Expected no capabilities before they are loaded")
+	}
+	caps, err := LoadCapabilities(context.Background(), kubeClient)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	expected := Capabilities{Platform: configv1.GCPPlatformType, Region: "us-east1", NetworkType: "OVNKubernetes", Private: true, FIPS: true}
+	if stored, loaded := ClusterCapabilities(); caps != expected || !loaded || stored != expected {
+		t.Fatalf("Expected %+v, got %+v and %+v", expected, caps, stored)
+	}
+}
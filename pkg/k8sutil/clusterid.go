@@ -0,0 +1,60 @@
+package k8sutil
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ClusterIDEnvVar overrides the cluster ID read from the ClusterVersion, e.g.
+// where the webhook doesn't run in the cluster it guards
+const ClusterIDEnvVar = "CLUSTER_ID"
+
+var (
+	clusterIDMu sync.RWMutex
+	clusterID   string
+)
+
+// ClusterID returns the cluster ID resolved by LoadClusterID, or an empty
+// string if it hasn't been resolved
+func ClusterID() string {
+	clusterIDMu.RLock()
+	defer clusterIDMu.RUnlock()
+	return clusterID
+}
+
+// LoadClusterID resolves the cluster's external ID, which is also its ID in
+// OCM, from ClusterIDEnvVar or the ClusterVersion, and stores it for
+// ClusterID. kubeClient is only used, and created if nil, when
+// ClusterIDEnvVar is unset.
+func LoadClusterID(ctx context.Context, kubeClient client.Client) (string, error) {
+	id := os.Getenv(ClusterIDEnvVar)
+	if id == "" {
+		if kubeClient == nil {
+			scheme := runtime.NewScheme()
+			if err := configv1.Install(scheme); err != nil {
+				return "", err
+			}
+			c, err := KubeClient(scheme)
+			if err != nil {
+				return "", fmt.Errorf("fail creating KubeClient to look up the cluster ID: %v", err)
+			}
+			kubeClient = c
+		}
+		clusterVersion := &configv1.ClusterVersion{}
+		if err := kubeClient.Get(ctx, client.ObjectKey{Name: "version"}, clusterVersion); err != nil {
+			return "", fmt.Errorf("failed to get the ClusterVersion: %v", err)
+		}
+		id = string(clusterVersion.Spec.ClusterID)
+	}
+
+	clusterIDMu.Lock()
+	defer clusterIDMu.Unlock()
+	clusterID = id
+	return id, nil
+}
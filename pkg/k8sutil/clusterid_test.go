@@ -0,0 +1,38 @@
+package k8sutil
+
+import (
+	"context"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLoadClusterID(t *testing.T) {
+	s := runtime.NewScheme()
+	_ = configv1.Install(s)
+	kubeClient := fake.NewClientBuilder().WithScheme(s).WithObjects(&configv1.ClusterVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "version"},
+		Spec:       configv1.ClusterVersionSpec{ClusterID: "2c1d9a7e-5f0b-4a53-9c43-3d1bba1e0d6f"},
+	}).Build()
+
+	t.Setenv(ClusterIDEnvVar, "")
+	id, err := LoadClusterID(context.Background(), kubeClient)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	if id != "2c1d9a7e-5f0b-4a53-9c43-3d1bba1e0d6f" || ClusterID() != id {
+		t.Fatalf("Expected the ClusterVersion cluster ID, got %s and %s", id, ClusterID())
+	}
+
+	t.Setenv(ClusterIDEnvVar, "from-env")
+	id, err = LoadClusterID(context.Background(), kubeClient)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	if id != "from-env" || ClusterID() != "from-env" {
+		t.Fatalf("Expected %s to override the cluster ID, got %s and %s", ClusterIDEnvVar, id, ClusterID())
+	}
+}
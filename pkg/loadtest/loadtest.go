@@ -0,0 +1,434 @@
+// Package loadtest sends synthetic AdmissionReview traffic to a running
+// webhook server and measures its latency, to plan its capacity on large
+// clusters
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+)
+
+// paddingAnnotation is the annotation padding the objects of the requests
+// to their payload size
+const paddingAnnotation = "loadtest.managed.openshift.io/padding"
+
+// kinds are the kinds of the resources matched by the webhooks' rules.
+// Other resources get a kind guessed from their name.
+var kinds = map[string]string{
+	"clusterloggings":            "ClusterLogging",
+	"clusterrolebindings":        "ClusterRoleBinding",
+	"customresourcedefinitions":  "CustomResourceDefinition",
+	"featuregates":               "FeatureGate",
+	"imagecontentsourcepolicies": "ImageContentSourcePolicy",
+	"imagedigestmirrorsets":      "ImageDigestMirrorSet",
+	"imagetagmirrorsets":         "ImageTagMirrorSet",
+	"ingresscontroller":          "IngressController",
+	"ingresscontrollers":         "IngressController",
+	"ingresses":                  "Ingress",
+	"namespaces":                 "Namespace",
+	"networkpolicies":            "NetworkPolicy",
+	"nodes":                      "Node",
+	"oauthclients":               "OAuthClient",
+	"poddisruptionbudgets":       "PodDisruptionBudget",
+	"pods":                       "Pod",
+	"prometheusrules":            "PrometheusRule",
+	"routes":                     "Route",
+	"securitycontextconstraints": "SecurityContextConstraints",
+	"serviceaccounts":            "ServiceAccount",
+	"services":                   "Service",
+}
+
+// Options configure a load test
+type Options struct {
+	// URL is the base URL of the webhook server, e.g. https://localhost:5000
+	URL string
+	// Mix is the relative weight of each webhook in the traffic
+	Mix map[string]int
+	// PayloadSizes are the sizes in bytes the objects of the requests are
+	// padded to, sent in turn
+	PayloadSizes []int
+	Concurrency  int
+	// Requests is how many requests to send, unlimited if 0
+	Requests int
+	// Duration is how long to send requests for, unlimited if 0
+	Duration time.Duration
+	// Username and Groups are the requester of the requests
+	Username string
+	Groups   []string
+	Client   *http.Client
+}
+
+// Stats are the outcomes and latencies of requests
+type Stats struct {
+	Requests int `json:"requests"`
+	// Errors are the requests which failed or which the webhook errored on
+	Errors int           `json:"errors"`
+	Denied int           `json:"denied"`
+	P50    time.Duration `json:"p50"`
+	P90    time.Duration `json:"p90"`
+	P99    time.Duration `json:"p99"`
+	Max    time.Duration `json:"max"`
+}
+
+// Report is the result of a load test
+type Report struct {
+	Duration time.Duration `json:"duration"`
+	// Throughput is the number of requests per second
+	Throughput float64          `json:"throughput"`
+	Total      Stats            `json:"total"`
+	Webhooks   map[string]Stats `json:"webhooks"`
+}
+
+// ParseMix parses a comma-separated list of webhook=weight, e.g.
+// "scc-validation=3,namespace-validation=1". A webhook without a weight has
+// a weight of 1. An empty mix weighs every webhook of hooks equally.
+func ParseMix(mix string, hooks webhooks.RegisteredWebhooks) (map[string]int, error) {
+	weights := map[string]int{}
+	if mix == "" {
+		for name := range hooks {
+			weights[name] = 1
+		}
+		return weights, nil
+	}
+	for _, entry := range strings.Split(mix, ",") {
+		name, weight, hasWeight := strings.Cut(strings.TrimSpace(entry), "=")
+		if _, ok := hooks[name]; !ok {
+			return nil, fmt.Errorf("unknown webhook %q", name)
+		}
+		weights[name] = 1
+		if hasWeight {
+			w, err := strconv.Atoi(weight)
+			if err != nil || w <= 0 {
+				return nil, fmt.Errorf("invalid weight %q of webhook %s", weight, name)
+			}
+			weights[name] = w
+		}
+	}
+	return weights, nil
+}
+
+// NewReview returns an AdmissionReview of a request matching the first rule
+// of hook, whose object is padded to about size bytes
+func NewReview(hook webhooks.Webhook, user string, groups []string, size int) ([]byte, error) {
+	request, err := newRequest(hook, user, groups)
+	if err != nil {
+		return nil, err
+	}
+	obj := map[string]interface{}{
+		"apiVersion": schemaVersion(request.Kind),
+		"kind":       request.Kind.Kind,
+		"metadata": map[string]interface{}{
+			"name":   request.Name,
+			"labels": map[string]interface{}{"app": "loadtest"},
+		},
+	}
+	if request.Namespace != "" {
+		obj["metadata"].(map[string]interface{})["namespace"] = request.Namespace
+	}
+	if request.Kind.Kind == "Pod" {
+		obj["spec"] = map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "loadtest", "image": "registry.access.redhat.com/ubi9/ubi-minimal:latest"},
+			},
+		}
+	}
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	if pad := size - len(raw); pad > 0 {
+		obj["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{paddingAnnotation: strings.Repeat("x", pad)}
+		if raw, err = json.Marshal(obj); err != nil {
+			return nil, err
+		}
+	}
+	// Like the API server, deletes only carry the old object and updates
+	// both
+	if request.Operation != admissionv1.Delete {
+		request.Object = runtime.RawExtension{Raw: raw}
+	}
+	if request.Operation != admissionv1.Create {
+		request.OldObject = runtime.RawExtension{Raw: raw}
+	}
+	return json.Marshal(admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request:  &request,
+	})
+}
+
+// newRequest returns a request matching the first rule of hook which names
+// a resource rather than a wildcard or subresource
+func newRequest(hook webhooks.Webhook, user string, groups []string) (admissionv1.AdmissionRequest, error) {
+	for _, rule := range hook.Rules() {
+		for _, resource := range rule.Resources {
+			if resource == "*" || strings.Contains(resource, "/") {
+				continue
+			}
+			request := admissionv1.AdmissionRequest{
+				UID:       types.UID("loadtest"),
+				Kind:      metav1.GroupVersionKind{Group: group(rule.APIGroups), Version: first(rule.APIVersions, "v1"), Kind: kind(resource)},
+				Operation: operation(rule.Operations),
+				Name:      "loadtest",
+				UserInfo:  authenticationv1.UserInfo{Username: user, Groups: groups},
+			}
+			request.Resource = metav1.GroupVersionResource{Group: request.Kind.Group, Version: request.Kind.Version, Resource: resource}
+			request.RequestKind = &request.Kind
+			request.RequestResource = &request.Resource
+			if rule.Scope == nil || *rule.Scope != admissionregv1.ClusterScope {
+				request.Namespace = "loadtest"
+			}
+			return request, nil
+		}
+	}
+	return admissionv1.AdmissionRequest{}, fmt.Errorf("webhook %s has no rule naming a resource", hook.Name())
+}
+
+// first returns the first of values which isn't a wildcard, or def
+func first(values []string, def string) string {
+	for _, v := range values {
+		if v != "*" {
+			return v
+		}
+	}
+	return def
+}
+
+// group returns the API group of a rule. Some rules name the core group by
+// its version, which the API server would never send.
+func group(groups []string) string {
+	g := first(groups, "")
+	if g == "v1" {
+		return ""
+	}
+	return g
+}
+
+func operation(operations []admissionregv1.OperationType) admissionv1.Operation {
+	for _, op := range operations {
+		switch op {
+		case admissionregv1.Create, admissionregv1.Update, admissionregv1.Delete:
+			return admissionv1.Operation(op)
+		}
+	}
+	return admissionv1.Create
+}
+
+func kind(resource string) string {
+	if k, ok := kinds[resource]; ok {
+		return k
+	}
+	singular := strings.TrimSuffix(resource, "s")
+	return strings.ToUpper(singular[:1]) + singular[1:]
+}
+
+func schemaVersion(kind metav1.GroupVersionKind) string {
+	if kind.Group == "" {
+		return kind.Version
+	}
+	return kind.Group + "/" + kind.Version
+}
+
+// target is a canned AdmissionReview of one webhook
+type target struct {
+	webhook string
+	url     string
+	body    []byte
+}
+
+type sample struct {
+	webhook string
+	latency time.Duration
+	errored bool
+	denied  bool
+}
+
+// Run sends the traffic of opts to the webhooks of hooks until it sent
+// opts.Requests, opts.Duration elapsed or ctx is done
+func Run(ctx context.Context, hooks webhooks.RegisteredWebhooks, opts Options) (*Report, error) {
+	if opts.Requests == 0 && opts.Duration == 0 {
+		return nil, fmt.Errorf("either a number of requests or a duration is required")
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	if len(opts.PayloadSizes) == 0 {
+		opts.PayloadSizes = []int{0}
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	// The requests are built beforehand, so building them isn't measured.
+	// Each webhook is weighed once per payload size.
+	targets := [][]target{}
+	names := make([]string, 0, len(opts.Mix))
+	for name := range opts.Mix {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		factory, ok := hooks[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown webhook %q", name)
+		}
+		hook := factory()
+		sized := []target{}
+		for _, size := range opts.PayloadSizes {
+			body, err := NewReview(hook, opts.Username, opts.Groups, size)
+			if err != nil {
+				return nil, err
+			}
+			sized = append(sized, target{webhook: name, url: strings.TrimSuffix(opts.URL, "/") + hook.GetURI(), body: body})
+		}
+		for i := 0; i < opts.Mix[name]; i++ {
+			targets = append(targets, sized)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no webhooks to send requests to")
+	}
+
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+	var sent int64
+	samples := make([][]sample, opts.Concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < opts.Concurrency; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			random := rand.New(rand.NewSource(start.UnixNano() + int64(w)))
+			for ctx.Err() == nil {
+				n := atomic.AddInt64(&sent, 1)
+				if opts.Requests > 0 && n > int64(opts.Requests) {
+					return
+				}
+				sized := targets[random.Intn(len(targets))]
+				t := sized[int(n)%len(sized)]
+				s, ok := send(ctx, opts.Client, t)
+				if ok {
+					samples[w] = append(samples[w], s)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	all := []sample{}
+	for _, s := range samples {
+		all = append(all, s...)
+	}
+	return newReport(all, elapsed), nil
+}
+
+// send sends the request of t. Requests interrupted by the end of the test
+// aren't a sample.
+func send(ctx context.Context, c *http.Client, t target) (sample, bool) {
+	s := sample{webhook: t.webhook}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(t.body))
+	if err != nil {
+		s.errored = true
+		return s, true
+	}
+	req.Header.Set("Content-Type", "application/json")
+	start := time.Now()
+	resp, err := c.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return s, false
+		}
+		s.latency = time.Since(start)
+		s.errored = true
+		return s, true
+	}
+	defer resp.Body.Close()
+	review := admissionv1.AdmissionReview{}
+	err = json.NewDecoder(resp.Body).Decode(&review)
+	s.latency = time.Since(start)
+	switch {
+	case err != nil || resp.StatusCode != http.StatusOK || review.Response == nil:
+		s.errored = true
+	case !review.Response.Allowed:
+		// Like the API server, a denial with a status code other than 403
+		// is the webhook failing to decide
+		result := review.Response.Result
+		if result != nil && result.Code != 0 && result.Code != http.StatusForbidden {
+			s.errored = true
+		} else {
+			s.denied = true
+		}
+	}
+	return s, true
+}
+
+func newReport(samples []sample, elapsed time.Duration) *Report {
+	report := &Report{Duration: elapsed, Webhooks: map[string]Stats{}}
+	if elapsed > 0 {
+		report.Throughput = float64(len(samples)) / elapsed.Seconds()
+	}
+	byWebhook := map[string][]sample{}
+	for _, s := range samples {
+		byWebhook[s.webhook] = append(byWebhook[s.webhook], s)
+	}
+	report.Total = stats(samples)
+	for name, s := range byWebhook {
+		report.Webhooks[name] = stats(s)
+	}
+	return report
+}
+
+func stats(samples []sample) Stats {
+	st := Stats{Requests: len(samples)}
+	latencies := make([]time.Duration, 0, len(samples))
+	for _, s := range samples {
+		if s.errored {
+			st.Errors++
+		}
+		if s.denied {
+			st.Denied++
+		}
+		latencies = append(latencies, s.latency)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	st.P50 = percentile(latencies, 50)
+	st.P90 = percentile(latencies, 90)
+	st.P99 = percentile(latencies, 99)
+	st.Max = percentile(latencies, 100)
+	return st
+}
+
+// percentile returns the nearest-rank p-th percentile of the sorted
+// latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,133 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/namespace"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/scc"
+)
+
+var testHooks = webhooks.RegisteredWebhooks{
+	scc.WebhookName:       func() webhooks.Webhook { return scc.NewWebhook() },
+	namespace.WebhookName: func() webhooks.Webhook { return namespace.NewWebhook() },
+}
+
+func TestParseMix(t *testing.T) {
+	tests := []struct {
+		mix     string
+		want    map[string]int
+		wantErr bool
+	}{
+		{mix: "", want: map[string]int{scc.WebhookName: 1, namespace.WebhookName: 1}},
+		{mix: "scc-validation=3, namespace-validation", want: map[string]int{scc.WebhookName: 3, namespace.WebhookName: 1}},
+		{mix: "scc-validation=0", wantErr: true},
+		{mix: "scc-validation=many", wantErr: true},
+		{mix: "pod-validation", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParseMix(test.mix, testHooks)
+		if (err != nil) != test.wantErr {
+			t.Fatalf("ParseMix(%q): expected error %v, got %v", test.mix, test.wantErr, err)
+		}
+		if err == nil && len(got) != len(test.want) {
+			t.Fatalf("ParseMix(%q): expected %v, got %v", test.mix, test.want, got)
+		}
+		for name, weight := range test.want {
+			if got[name] != weight {
+				t.Fatalf("ParseMix(%q): expected %v, got %v", test.mix, test.want, got)
+			}
+		}
+	}
+}
+
+func TestNewReview(t *testing.T) {
+	body, err := NewReview(scc.NewWebhook(), "alice", []string{"system:authenticated"}, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	review := admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(body, &review); err != nil {
+		t.Fatal(err)
+	}
+	request := review.Request
+	if request.Kind.Kind != "SecurityContextConstraints" || request.Resource.Resource != "securitycontextconstraints" || request.Namespace != "" {
+		t.Fatalf("expected a cluster-scoped SecurityContextConstraints request, got %v %v in %q", request.Kind, request.Resource, request.Namespace)
+	}
+	if request.Operation != admissionv1.Update || len(request.Object.Raw) == 0 || len(request.OldObject.Raw) == 0 {
+		t.Fatalf("expected an update with an object and old object, got %s", request.Operation)
+	}
+	if size := len(request.Object.Raw); size < 4096 || size > 4096+64 {
+		t.Fatalf("expected an object of about 4096 bytes, got %d", size)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := []time.Duration{}
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	for p, want := range map[int]time.Duration{50: 50 * time.Millisecond, 99: 99 * time.Millisecond, 100: 100 * time.Millisecond} {
+		if got := percentile(latencies, p); got != want {
+			t.Fatalf("expected p%d of %v, got %v", p, want, got)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Fatalf("expected no percentile of no latencies, got %v", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	// Deny SCC requests, allow namespace requests
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		review := admissionv1.AdmissionReview{}
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		review.Response = &admissionv1.AdmissionResponse{UID: review.Request.UID, Allowed: r.URL.Path != "/"+scc.WebhookName}
+		if !review.Response.Allowed {
+			review.Response.Result = &metav1.Status{Code: http.StatusForbidden}
+		}
+		review.Request = nil
+		_ = json.NewEncoder(w).Encode(review)
+	}))
+	defer server.Close()
+
+	report, err := Run(context.Background(), testHooks, Options{
+		URL:          server.URL,
+		Mix:          map[string]int{scc.WebhookName: 1, namespace.WebhookName: 1},
+		PayloadSizes: []int{512, 2048},
+		Concurrency:  4,
+		Requests:     200,
+		Username:     "alice",
+		Client:       server.Client(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total.Requests != 200 || report.Total.Errors != 0 {
+		t.Fatalf("expected 200 requests without errors, got %+v", report.Total)
+	}
+	sccStats, nsStats := report.Webhooks[scc.WebhookName], report.Webhooks[namespace.WebhookName]
+	if sccStats.Requests+nsStats.Requests != 200 || sccStats.Denied != sccStats.Requests || nsStats.Denied != 0 {
+		t.Fatalf("expected the SCC requests denied and the namespace requests allowed, got %+v and %+v", sccStats, nsStats)
+	}
+	if report.Total.P50 <= 0 || report.Total.P50 > report.Total.P99 || report.Total.P99 > report.Total.Max {
+		t.Fatalf("expected ordered percentiles, got %+v", report.Total)
+	}
+}
+
+func TestRunRequiresALimit(t *testing.T) {
+	if _, err := Run(context.Background(), testHooks, Options{Mix: map[string]int{scc.WebhookName: 1}}); err == nil {
+		t.Fatal("expected an error without a number of requests or a duration")
+	}
+}
@@ -1,7 +1,76 @@
 package localmetrics
 
 import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// maxLabelValues bounds how many distinct users or groups are tracked per
+	// denial breakdown metric. Values seen after the limit is reached are
+	// aggregated under overflowLabelValue.
+	maxLabelValues     = 100
+	overflowLabelValue = "other"
+	noGroupLabelValue  = "none"
+	noReasonLabelValue = "none"
+	// ClusterIDLabel is added to every exported metric, so fleet-wide
+	// aggregation can attribute them to a cluster
+	ClusterIDLabel = "cluster_id"
+
+	// nearTimeoutFraction is the share of a webhook's timeout after which a
+	// request counts towards MetricNearTimeoutRequests
+	nearTimeoutFraction = 0.9
+
+	// Request outcomes, as the outcome label of MetricRequests
+	OutcomeAllowed = "allowed"
+	OutcomeDenied  = "denied"
+	OutcomeErrored = "errored"
+
+	// MalformedReviewDecode is an AdmissionReview which couldn't be parsed.
+	// The Malformed* constants are the reason label of MetricMalformedRequests.
+	MalformedReviewDecode = "review_decode"
+	// MalformedObjectDecode is a request the webhook answered with a 400,
+	// which the webhooks only do when the object can't be decoded
+	MalformedObjectDecode = "object_decode"
+	// MalformedMissingObject and MalformedMissingOldObject are requests
+	// without the object or old object their operation should carry
+	MalformedMissingObject    = "missing_object"
+	MalformedMissingOldObject = "missing_old_object"
+	// MalformedInvalid is a request rejected by the webhook's Validate, e.g.
+	// for an unexpected kind
+	MalformedInvalid = "invalid"
+	// MalformedConversion is a request of an older version of a kind whose
+	// objects couldn't be converted to its preferred version
+	MalformedConversion = "conversion"
+	// MalformedDecodeLimit is an AdmissionReview exceeding the decode limits
+	// of the webhooks, e.g. of a huge or deeply nested object
+	MalformedDecodeLimit = "decode_limit"
+
+	// Shed requests, as the action label of MetricShedRequests. Requests of
+	// webhooks with FailurePolicy=Ignore are allowed, the others rejected.
+	ShedAllowed  = "allowed"
+	ShedRejected = "rejected"
+
+	// Recorders of denials, as the recorder label of
+	// MetricDroppedSideEffects
+	SideEffectEvents     = "events"
+	SideEffectAudit      = "audit"
+	SideEffectAuditLog   = "auditlog"
+	SideEffectSummary    = "summary"
+	SideEffectServiceLog = "servicelog"
+
+	// Certificates, as the certificate label of MetricCertificateExpiry
+	CertificateServing  = "serving"
+	CertificateCABundle = "ca_bundle"
 )
 
 var (
@@ -10,11 +79,429 @@ var (
 		Help: "Report how many times the managed node webhook has blocked requests",
 	}, []string{"user"})
 
+	// MetricDeniedRequests counts the denials of each webhook by reason code,
+	// e.g. SCC001_DEFAULT_SCC_MODIFY, so what a webhook denies can be told
+	// apart without its logs
+	MetricDeniedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_denied_requests_total",
+		Help: "Report how many requests each webhook has denied, by reason code",
+	}, []string{"webhook", "reason"})
+
+	MetricDeniedRequestsByUser = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_denied_requests_by_user",
+		Help: "Report how many requests each webhook has denied, by requesting user",
+	}, []string{"webhook", "user"})
+
+	MetricDeniedRequestsByGroup = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_denied_requests_by_group",
+		Help: "Report how many requests each webhook has denied, by requesting group",
+	}, []string{"webhook", "group"})
+
+	MetricDeniedRequestsByResource = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_denied_requests_by_resource",
+		Help: "Report how many requests each webhook has denied, by resource",
+	}, []string{"webhook", "resource"})
+
+	// MetricRequests and MetricRequestDuration are the availability and
+	// latency SLIs of each webhook
+	MetricRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_requests_total",
+		Help: "Report how many admission requests each webhook has handled, by outcome",
+	}, []string{"webhook", "outcome"})
+
+	MetricRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "managed_webhook_request_duration_seconds",
+		Help:    "Report how long each webhook takes to handle admission requests",
+		Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"webhook"})
+
+	// MetricEvaluationDuration is how long each webhook takes to decide, out
+	// of MetricRequestDuration, which also covers decoding the review,
+	// waiting for a worker and the dispatcher's own checks
+	MetricEvaluationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "managed_webhook_evaluation_duration_seconds",
+		Help:    "Report how long each webhook takes to evaluate admission requests",
+		Buckets: []float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	}, []string{"webhook"})
+
+	// MetricRequestSize is the size of the AdmissionReviews each webhook
+	// receives, which grows with the objects it matches
+	MetricRequestSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "managed_webhook_request_size_bytes",
+		Help:    "Report the size of the AdmissionReviews each webhook receives",
+		Buckets: prometheus.ExponentialBuckets(1024, 4, 8),
+	}, []string{"webhook"})
+
+	// MetricNearTimeoutRequests counts requests answered so close to the
+	// webhook's timeout that a little more latency would have tripped its
+	// FailurePolicy
+	MetricNearTimeoutRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_near_timeout_requests_total",
+		Help: "Report how many admission requests each webhook answered within 10% of its timeout",
+	}, []string{"webhook"})
+
+	// MetricMalformedRequests counts requests a webhook couldn't evaluate.
+	// Webhooks with FailurePolicy=Ignore hide these failures from users, so a
+	// spike is often the first sign of an API change breaking a guardrail.
+	MetricMalformedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_malformed_requests_total",
+		Help: "Report how many malformed admission requests each webhook has received, by reason",
+	}, []string{"webhook", "reason"})
+
+	// MetricShedRequests counts the requests answered without being
+	// evaluated while the webhooks were overloaded
+	MetricShedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_shed_requests_total",
+		Help: "Report how many admission requests each webhook has shed while overloaded, by action",
+	}, []string{"webhook", "action"})
+
+	// MetricDeduplicatedRequests counts the requests answered with the
+	// decision of an identical request evaluated concurrently
+	MetricDeduplicatedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_deduplicated_requests_total",
+		Help: "Report how many admission requests each webhook answered with the decision of an identical concurrent request",
+	}, []string{"webhook"})
+
+	// MetricDroppedSideEffects counts the denials a recorder dropped rather
+	// than delay admission while its queue was full
+	MetricDroppedSideEffects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_side_effects_dropped_total",
+		Help: "Report how many denials each recorder of Events, audit records, summaries or service logs dropped while its queue was full",
+	}, []string{"recorder"})
+
+	// MetricWouldHaveDeniedRequests counts the requests a webhook in audit
+	// mode allowed although it would have denied them, by what audits it
+	MetricWouldHaveDeniedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "managed_webhook_would_have_denied_requests_total",
+		Help: "Report how many admission requests each webhook in audit mode allowed although it would have denied them, by the policy, maintenance window or enforcement mode auditing it",
+	}, []string{"webhook", "source"})
+
+	// MetricInFlightRequests is the number of requests being handled or
+	// waiting to be
+	MetricInFlightRequests = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "managed_webhook_in_flight_requests",
+		Help: "Report how many admission requests are being handled or waiting to be",
+	})
+
+	// MetricQueueDepth and MetricQueueDuration are the requests of each
+	// webhook waiting for a worker, and how long they waited
+	MetricQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "managed_webhook_queue_depth",
+		Help: "Report how many admission requests of each webhook are waiting for a worker",
+	}, []string{"webhook"})
+
+	MetricQueueDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "managed_webhook_queue_duration_seconds",
+		Help:    "Report how long the admission requests of each webhook waited for a worker",
+		Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"webhook"})
+
+	// MetricCertificateExpiry is when the certificates the webhook loaded at
+	// startup expire. They aren't reloaded when rotated on disk.
+	MetricCertificateExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "managed_webhook_certificate_expiry_timestamp_seconds",
+		Help: "Report the notAfter time of the earliest expiring certificate the webhook serves or trusts, as a Unix timestamp",
+	}, []string{"certificate"})
+
+	// MetricSelfTestPassed is 1 when a webhook made the expected decision on
+	// a canned self-test request in the last run, and 0 when it didn't
+	MetricSelfTestPassed = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "managed_webhook_selftest_passed",
+		Help: "Report whether each webhook made the expected decision on each self-test request in the last run",
+	}, []string{"webhook", "test"})
+
+	// MetricInvalidConfigEntries is how many entries of each setting were
+	// ignored as invalid at startup
+	MetricInvalidConfigEntries = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "managed_webhook_invalid_config_entries",
+		Help: "Report how many entries of each configuration setting the webhooks ignored as invalid",
+	}, []string{"key"})
+
 	MetricsList = []prometheus.Collector{
 		MetricNodeWebhookBlockedReqeust,
+		MetricDeniedRequests,
+		MetricDeniedRequestsByUser,
+		MetricDeniedRequestsByGroup,
+		MetricDeniedRequestsByResource,
+		MetricRequests,
+		MetricRequestDuration,
+		MetricEvaluationDuration,
+		MetricRequestSize,
+		MetricNearTimeoutRequests,
+		MetricMalformedRequests,
+		MetricShedRequests,
+		MetricDeduplicatedRequests,
+		MetricDroppedSideEffects,
+		MetricWouldHaveDeniedRequests,
+		MetricInFlightRequests,
+		MetricQueueDepth,
+		MetricQueueDuration,
+		MetricCertificateExpiry,
+		MetricSelfTestPassed,
+		MetricInvalidConfigEntries,
+	}
+
+	userLimiter  = newLabelLimiter(maxLabelValues)
+	groupLimiter = newLabelLimiter(maxLabelValues)
+
+	// ignoredGroups are held by every user of their kind, so they say nothing
+	// about who is hitting a guardrail
+	ignoredGroups = []string{
+		"system:authenticated",
+		"system:authenticated:oauth",
+		"system:serviceaccounts",
 	}
 )
 
+// labelLimiter caps the number of distinct values a metric label takes. The
+// first max values seen are kept, and every later value maps to
+// overflowLabelValue.
+type labelLimiter struct {
+	mu     sync.Mutex
+	max    int
+	values map[string]bool
+}
+
+func newLabelLimiter(max int) *labelLimiter {
+	return &labelLimiter{
+		max:    max,
+		values: make(map[string]bool),
+	}
+}
+
+// limit returns value if it is tracked or there is room to track it, and
+// overflowLabelValue otherwise
+func (l *labelLimiter) limit(value string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.values[value] {
+		return value
+	}
+	if len(l.values) >= l.max {
+		return overflowLabelValue
+	}
+	l.values[value] = true
+	return value
+}
+
+// NewRegistry returns a registry with MetricsList and the Go and process
+// collectors registered, labelled with clusterID unless it is empty
+func NewRegistry(clusterID string) (*prometheus.Registry, error) {
+	registry := prometheus.NewRegistry()
+	var registerer prometheus.Registerer = registry
+	if clusterID != "" {
+		registerer = prometheus.WrapRegistererWith(prometheus.Labels{ClusterIDLabel: clusterID}, registry)
+	}
+	collectorList := append([]prometheus.Collector{
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	}, MetricsList...)
+	for _, collector := range collectorList {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// IncrementInvalidConfigEntry records an entry of the setting key ignored as
+// invalid
+func IncrementInvalidConfigEntry(key string) {
+	MetricInvalidConfigEntries.With(prometheus.Labels{"key": key}).Inc()
+}
+
 func IncrementNodeWebhookBlockedRequest(user string) {
 	MetricNodeWebhookBlockedReqeust.With(prometheus.Labels{"user": user}).Inc()
 }
+
+// IsDenied returns true if the response denies the request. Errored responses
+// are also not allowed, but they are failures rather than denials.
+func IsDenied(resp admissionctl.Response) bool {
+	if resp.Allowed {
+		return false
+	}
+	return resp.Result == nil || resp.Result.Code == http.StatusForbidden
+}
+
+// Outcome returns the outcome label of a response
+func Outcome(resp admissionctl.Response) string {
+	switch {
+	case resp.Allowed:
+		return OutcomeAllowed
+	case IsDenied(resp):
+		return OutcomeDenied
+	default:
+		return OutcomeErrored
+	}
+}
+
+// ObserveRequest records the outcome and duration of a request handled by the
+// named webhook in the SLI metrics
+func ObserveRequest(webhook string, resp admissionctl.Response, duration time.Duration) {
+	MetricRequests.With(prometheus.Labels{
+		"webhook": webhook,
+		"outcome": Outcome(resp),
+	}).Inc()
+	MetricRequestDuration.With(prometheus.Labels{"webhook": webhook}).Observe(duration.Seconds())
+}
+
+// ObserveEvaluation records how long the named webhook took to evaluate a
+// request
+func ObserveEvaluation(webhook string, duration time.Duration) {
+	MetricEvaluationDuration.With(prometheus.Labels{"webhook": webhook}).Observe(duration.Seconds())
+}
+
+// ObserveRequestSize records the size of an AdmissionReview received by the
+// named webhook
+func ObserveRequestSize(webhook string, bytes int64) {
+	MetricRequestSize.With(prometheus.Labels{"webhook": webhook}).Observe(float64(bytes))
+}
+
+// ObserveNearTimeout counts the request if the named webhook took within 10%
+// of its timeout of timeoutSeconds to answer it
+func ObserveNearTimeout(webhook string, timeoutSeconds int32, duration time.Duration) {
+	if timeoutSeconds <= 0 || duration.Seconds() < nearTimeoutFraction*float64(timeoutSeconds) {
+		return
+	}
+	MetricNearTimeoutRequests.With(prometheus.Labels{"webhook": webhook}).Inc()
+}
+
+// IncrementMalformedRequest records a malformed request received by the named
+// webhook
+func IncrementMalformedRequest(webhook, reason string) {
+	MetricMalformedRequests.With(prometheus.Labels{
+		"webhook": webhook,
+		"reason":  reason,
+	}).Inc()
+}
+
+// IncrementShedRequest records a request of the named webhook shed with
+// action
+func IncrementShedRequest(webhook, action string) {
+	MetricShedRequests.With(prometheus.Labels{
+		"webhook": webhook,
+		"action":  action,
+	}).Inc()
+}
+
+// IncrementDeduplicatedRequest records a request of the named webhook
+// answered with the decision of an identical concurrent request
+func IncrementDeduplicatedRequest(webhook string) {
+	MetricDeduplicatedRequests.With(prometheus.Labels{"webhook": webhook}).Inc()
+}
+
+// IncrementDroppedSideEffect records a denial the named recorder dropped
+func IncrementDroppedSideEffect(recorder string) {
+	MetricDroppedSideEffects.With(prometheus.Labels{"recorder": recorder}).Inc()
+}
+
+// IncrementWouldHaveDeniedRequest records a request the named webhook
+// allowed in audit mode, source being what audits it
+func IncrementWouldHaveDeniedRequest(webhook, source string) {
+	MetricWouldHaveDeniedRequests.With(prometheus.Labels{
+		"webhook": webhook,
+		"source":  source,
+	}).Inc()
+}
+
+// ObserveQueueTime records how long a request of the named webhook waited
+// for a worker
+func ObserveQueueTime(webhook string, duration time.Duration) {
+	MetricQueueDuration.With(prometheus.Labels{"webhook": webhook}).Observe(duration.Seconds())
+}
+
+// ObserveCertificateExpiry records the notAfter time of the earliest expiring
+// certificate in pemData, a PEM encoded certificate or bundle
+func ObserveCertificateExpiry(certificate string, pemData []byte) error {
+	var notAfter time.Time
+	for {
+		var block *pem.Block
+		block, pemData = pem.Decode(pemData)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return err
+		}
+		if notAfter.IsZero() || cert.NotAfter.Before(notAfter) {
+			notAfter = cert.NotAfter
+		}
+	}
+	if notAfter.IsZero() {
+		return errors.New("no certificates found")
+	}
+	MetricCertificateExpiry.With(prometheus.Labels{"certificate": certificate}).Set(float64(notAfter.Unix()))
+	return nil
+}
+
+// SetSelfTestResult records whether the named webhook passed a self-test
+func SetSelfTestResult(webhook, test string, passed bool) {
+	value := 0.0
+	if passed {
+		value = 1
+	}
+	MetricSelfTestPassed.With(prometheus.Labels{
+		"webhook": webhook,
+		"test":    test,
+	}).Set(value)
+}
+
+// IncrementDeniedRequest records a request denied by the named webhook with
+// the reason code reason, empty if the denial has none, in the denial
+// breakdown metrics
+func IncrementDeniedRequest(webhook string, request admissionctl.Request, reason string) {
+	if reason == "" {
+		reason = noReasonLabelValue
+	}
+	MetricDeniedRequests.With(prometheus.Labels{
+		"webhook": webhook,
+		"reason":  reason,
+	}).Inc()
+	MetricDeniedRequestsByUser.With(prometheus.Labels{
+		"webhook": webhook,
+		"user":    userLimiter.limit(request.UserInfo.Username),
+	}).Inc()
+	MetricDeniedRequestsByGroup.With(prometheus.Labels{
+		"webhook": webhook,
+		"group":   groupLimiter.limit(requestingGroup(request.UserInfo.Groups)),
+	}).Inc()
+	MetricDeniedRequestsByResource.With(prometheus.Labels{
+		"webhook":  webhook,
+		"resource": resourceName(request),
+	}).Inc()
+}
+
+// requestingGroup returns the first group which identifies who made the
+// request, skipping ignoredGroups
+func requestingGroup(groups []string) string {
+	for _, group := range groups {
+		ignored := false
+		for _, ignore := range ignoredGroups {
+			if group == ignore {
+				ignored = true
+				break
+			}
+		}
+		if !ignored {
+			return group
+		}
+	}
+	return noGroupLabelValue
+}
+
+// resourceName returns the resource of the request as resource.group, or just
+// the resource for the core group
+func resourceName(request admissionctl.Request) string {
+	resource := request.Resource.Resource
+	if request.SubResource != "" {
+		resource = strings.Join([]string{resource, request.SubResource}, "/")
+	}
+	if request.Resource.Group == "" {
+		return resource
+	}
+	return resource + "." + request.Resource.Group
+}
@@ -0,0 +1,203 @@
+package localmetrics
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestLabelLimiter(t *testing.T) {
+	limiter := newLabelLimiter(2)
+	for i := 0; i < 2; i++ {
+		value := fmt.Sprintf("user-%d", i)
+		if got := limiter.limit(value); got != value {
+			t.Fatalf("Expected %s to be tracked, got %s", value, got)
+		}
+	}
+	if got := limiter.limit("user-2"); got != overflowLabelValue {
+		t.Fatalf("Expected user-2 to overflow, got %s", got)
+	}
+	if got := limiter.limit("user-0"); got != "user-0" {
+		t.Fatalf("Expected already tracked user-0 to stay tracked, got %s", got)
+	}
+}
+
+func TestRequestingGroup(t *testing.T) {
+	tests := []struct {
+		groups   []string
+		expected string
+	}{
+		{
+			groups:   []string{"system:authenticated:oauth", "system:authenticated", "dedicated-admins"},
+			expected: "dedicated-admins",
+		},
+		{
+			groups:   []string{"system:serviceaccounts", "system:serviceaccounts:argocd", "system:authenticated"},
+			expected: "system:serviceaccounts:argocd",
+		},
+		{
+			groups:   []string{"system:authenticated"},
+			expected: noGroupLabelValue,
+		},
+	}
+	for _, test := range tests {
+		if got := requestingGroup(test.groups); got != test.expected {
+			t.Fatalf("Expected group %s for %v, got %s", test.expected, test.groups, got)
+		}
+	}
+}
+
+func TestIsDenied(t *testing.T) {
+	if IsDenied(admissionctl.Allowed("")) {
+		t.Fatalf("Allowed responses are not denials")
+	}
+	if !IsDenied(admissionctl.Denied("no")) {
+		t.Fatalf("Denied responses are denials")
+	}
+	if IsDenied(admissionctl.Errored(http.StatusBadRequest, fmt.Errorf("bad request"))) {
+		t.Fatalf("Errored responses are not denials")
+	}
+}
+
+func TestIncrementDeniedRequest(t *testing.T) {
+	request := admissionctl.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Resource: metav1.GroupVersionResource{Group: "security.openshift.io", Version: "v1", Resource: "securitycontextconstraints"},
+			UserInfo: authenticationv1.UserInfo{
+				Username: "my_user",
+				Groups:   []string{"system:authenticated", "dedicated-admins"},
+			},
+		},
+	}
+	IncrementDeniedRequest("scc-validation", request, "SCC001_DEFAULT_SCC_MODIFY")
+	IncrementDeniedRequest("scc-validation", request, "")
+
+	if got := testutil.ToFloat64(MetricDeniedRequests.WithLabelValues("scc-validation", "SCC001_DEFAULT_SCC_MODIFY")); got != 1 {
+		t.Fatalf("Expected 1 denial with reason SCC001_DEFAULT_SCC_MODIFY, got %v", got)
+	}
+	if got := testutil.ToFloat64(MetricDeniedRequests.WithLabelValues("scc-validation", noReasonLabelValue)); got != 1 {
+		t.Fatalf("Expected 1 denial without a reason code, got %v", got)
+	}
+
+	if got := testutil.ToFloat64(MetricDeniedRequestsByUser.WithLabelValues("scc-validation", "my_user")); got != 2 {
+		t.Fatalf("Expected 2 denials for my_user, got %v", got)
+	}
+	if got := testutil.ToFloat64(MetricDeniedRequestsByGroup.WithLabelValues("scc-validation", "dedicated-admins")); got != 2 {
+		t.Fatalf("Expected 2 denials for dedicated-admins, got %v", got)
+	}
+	if got := testutil.ToFloat64(MetricDeniedRequestsByResource.WithLabelValues("scc-validation", "securitycontextconstraints.security.openshift.io")); got != 2 {
+		t.Fatalf("Expected 2 denials for securitycontextconstraints, got %v", got)
+	}
+}
+
+func TestObserveRequest(t *testing.T) {
+	tests := []struct {
+		resp     admissionctl.Response
+		expected string
+	}{
+		{resp: admissionctl.Allowed("ok"), expected: OutcomeAllowed},
+		{resp: admissionctl.Denied("no"), expected: OutcomeDenied},
+		{resp: admissionctl.Errored(http.StatusBadRequest, fmt.Errorf("bad")), expected: OutcomeErrored},
+		{resp: admissionctl.Errored(http.StatusInternalServerError, fmt.Errorf("broken")), expected: OutcomeErrored},
+	}
+	for _, test := range tests {
+		if got := Outcome(test.resp); got != test.expected {
+			t.Fatalf("Expected outcome %s, got %s", test.expected, got)
+		}
+	}
+
+	ObserveRequest("slo-validation", admissionctl.Allowed("ok"), 20*time.Millisecond)
+	ObserveRequest("slo-validation", admissionctl.Errored(http.StatusInternalServerError, fmt.Errorf("broken")), 2*time.Second)
+
+	if got := testutil.ToFloat64(MetricRequests.WithLabelValues("slo-validation", OutcomeAllowed)); got != 1 {
+		t.Fatalf("Expected 1 allowed request, got %v", got)
+	}
+	if got := testutil.ToFloat64(MetricRequests.WithLabelValues("slo-validation", OutcomeErrored)); got != 1 {
+		t.Fatalf("Expected 1 errored request, got %v", got)
+	}
+	if got := testutil.CollectAndCount(MetricRequestDuration, "managed_webhook_request_duration_seconds"); got != 1 {
+		t.Fatalf("Expected one duration histogram, got %d", got)
+	}
+}
+
+func TestObserveNearTimeout(t *testing.T) {
+	ObserveNearTimeout("timeout-validation", 2, time.Second)
+	ObserveNearTimeout("timeout-validation", 2, 1900*time.Millisecond)
+	ObserveNearTimeout("timeout-validation", 2, 3*time.Second)
+	if got := testutil.ToFloat64(MetricNearTimeoutRequests.WithLabelValues("timeout-validation")); got != 2 {
+		t.Fatalf("Expected 2 requests within 10%% of the timeout, got %v", got)
+	}
+}
+
+func TestNewRegistryLabelsClusterID(t *testing.T) {
+	registry, err := NewRegistry("2c1d9a7e-5f0b-4a53-9c43-3d1bba1e0d6f")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	IncrementNodeWebhookBlockedRequest("cluster-id-user")
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Expected no error gathering metrics, got %s", err.Error())
+	}
+	if len(families) == 0 {
+		t.Fatalf("Expected metrics to be gathered")
+	}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			found := false
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == ClusterIDLabel && label.GetValue() == "2c1d9a7e-5f0b-4a53-9c43-3d1bba1e0d6f" {
+					found = true
+				}
+			}
+			if !found {
+				t.Fatalf("Expected %s to carry the %s label, got %v", family.GetName(), ClusterIDLabel, metric.GetLabel())
+			}
+		}
+	}
+}
+
+func testCertificatePEM(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Expected no error generating a key, got %s", err.Error())
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    notAfter.Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Expected no error creating a certificate, got %s", err.Error())
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestObserveCertificateExpiry(t *testing.T) {
+	earliest := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	bundle := append(testCertificatePEM(t, earliest.Add(time.Hour)), testCertificatePEM(t, earliest)...)
+	if err := ObserveCertificateExpiry(CertificateCABundle, bundle); err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	if got := testutil.ToFloat64(MetricCertificateExpiry.WithLabelValues(CertificateCABundle)); got != float64(earliest.Unix()) {
+		t.Fatalf("Expected the earliest expiry %d, got %g", earliest.Unix(), got)
+	}
+
+	if err := ObserveCertificateExpiry(CertificateServing, []byte("not a certificate")); err == nil {
+		t.Fatalf("Expected an error for PEM data without certificates")
+	}
+}
@@ -0,0 +1,310 @@
+// Package manifestdiff compares two renderings of the SelectorSyncSet template
+// or package resources, for reviewing which webhook rules and policies a
+// release changes
+package manifestdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// maxInlineList is the length of the longest lists whose changes are shown
+// as the whole old and new list
+const maxInlineList = 5
+
+// IsTemplate returns whether the rendering data is a SelectorSyncSet
+// template rather than package resources
+func IsTemplate(data []byte) bool {
+	objects, err := decode(data)
+	return err == nil && len(objects) > 0 && objects[0]["kind"] == "Template"
+}
+
+// Diff returns the objects added, removed and changed between the renderings
+// old and new, with the changed fields of each changed object, or "" if they
+// render the same objects. The resources of SelectorSyncSets are compared as
+// objects of their own, so moving a resource between SelectorSyncSets shows
+// as a change of their resource lists only.
+func Diff(old, new []byte) (string, error) {
+	oldObjects, err := flattenRendering(old)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the old rendering: %v", err)
+	}
+	newObjects, err := flattenRendering(new)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the new rendering: %v", err)
+	}
+
+	keys := []string{}
+	for key := range oldObjects {
+		keys = append(keys, key)
+	}
+	for key := range newObjects {
+		if _, ok := oldObjects[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	added, removed, changed := 0, 0, 0
+	for _, key := range keys {
+		oldFields, inOld := oldObjects[key]
+		newFields, inNew := newObjects[key]
+		switch {
+		case !inOld:
+			added++
+			fmt.Fprintf(&b, "+ %s\n", key)
+		case !inNew:
+			removed++
+			fmt.Fprintf(&b, "- %s\n", key)
+		default:
+			lines := diffFields(oldFields, newFields)
+			if len(lines) == 0 {
+				continue
+			}
+			changed++
+			fmt.Fprintf(&b, "~ %s\n", key)
+			for _, line := range lines {
+				fmt.Fprintf(&b, "    %s\n", line)
+			}
+		}
+	}
+	if added+removed+changed == 0 {
+		return "", nil
+	}
+	fmt.Fprintf(&b, "%d added, %d removed, %d changed\n", added, removed, changed)
+	return b.String(), nil
+}
+
+// diffFields returns a line per field added, removed or changed
+func diffFields(old, new map[string]string) []string {
+	paths := []string{}
+	for path := range old {
+		paths = append(paths, path)
+	}
+	for path := range new {
+		if _, ok := old[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	lines := []string{}
+	for _, path := range paths {
+		oldValue, inOld := old[path]
+		newValue, inNew := new[path]
+		switch {
+		case !inOld:
+			lines = append(lines, fmt.Sprintf("+ %s: %s", path, newValue))
+		case !inNew:
+			lines = append(lines, fmt.Sprintf("- %s: %s", path, oldValue))
+		case oldValue != newValue:
+			if added, removed, ok := listChanges(oldValue, newValue); ok {
+				// Long lists, like the resources of a SelectorSyncSet, are
+				// easier to review by item
+				for _, item := range added {
+					lines = append(lines, fmt.Sprintf("+ %s[]: %s", path, item))
+				}
+				for _, item := range removed {
+					lines = append(lines, fmt.Sprintf("- %s[]: %s", path, item))
+				}
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("%s: %s -> %s", path, oldValue, newValue))
+		}
+	}
+	return lines
+}
+
+// listChanges returns the items added to and removed from the lists of
+// scalars old and new, if both are lists longer than maxInlineList and the
+// change isn't only a reordering
+func listChanges(old, new string) (added, removed []string, ok bool) {
+	var oldItems, newItems []interface{}
+	if json.Unmarshal([]byte(old), &oldItems) != nil || json.Unmarshal([]byte(new), &newItems) != nil {
+		return nil, nil, false
+	}
+	if len(oldItems) <= maxInlineList && len(newItems) <= maxInlineList {
+		return nil, nil, false
+	}
+	counts := map[string]int{}
+	for _, item := range oldItems {
+		counts[encode(item)]++
+	}
+	for _, item := range newItems {
+		counts[encode(item)]--
+	}
+	for _, item := range newItems {
+		if key := encode(item); counts[key] < 0 {
+			added = append(added, key)
+			counts[key]++
+		}
+	}
+	for _, item := range oldItems {
+		if key := encode(item); counts[key] > 0 {
+			removed = append(removed, key)
+			counts[key]--
+		}
+	}
+	return added, removed, len(added)+len(removed) > 0
+}
+
+// flattenRendering returns the fields of each object of a rendering, keyed
+// by the kind, namespace and name of the object
+func flattenRendering(data []byte) (map[string]map[string]string, error) {
+	objects, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	flattened := map[string]map[string]string{}
+	var add func(obj map[string]interface{})
+	add = func(obj map[string]interface{}) {
+		switch obj["kind"] {
+		case "Template":
+			for _, o := range maps(obj["objects"]) {
+				add(o)
+			}
+			// Only the objects are compared, the parameters are the same
+			// for every release
+			return
+		case "SelectorSyncSet":
+			if spec, ok := obj["spec"].(map[string]interface{}); ok {
+				resources := maps(spec["resources"])
+				names := make([]interface{}, 0, len(resources))
+				for _, r := range resources {
+					add(r)
+					names = append(names, objectKey(r))
+				}
+				spec["resources"] = names
+			}
+		}
+		fields := map[string]string{}
+		flatten("", obj, fields)
+		key := objectKey(obj)
+		// The same object may be rendered into several SelectorSyncSets
+		for i := 2; flattened[key] != nil; i++ {
+			key = fmt.Sprintf("%s (%d)", objectKey(obj), i)
+		}
+		flattened[key] = fields
+	}
+	for _, obj := range objects {
+		add(obj)
+	}
+	return flattened, nil
+}
+
+// decode returns the objects of the YAML documents in data
+func decode(data []byte) ([]map[string]interface{}, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	objects := []map[string]interface{}{}
+	for {
+		obj := map[string]interface{}{}
+		if err := decoder.Decode(&obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, err
+		}
+		if len(obj) > 0 {
+			objects = append(objects, obj)
+		}
+	}
+}
+
+// objectKey identifies obj, e.g. ValidatingWebhookConfiguration/sre-scc-validation
+func objectKey(obj map[string]interface{}) string {
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	if namespace, _ := metadata["namespace"].(string); namespace != "" {
+		name = namespace + "/" + name
+	}
+	return fmt.Sprintf("%v/%s", obj["kind"], name)
+}
+
+// Fields returns the fields of obj by path, each a JSON encoded scalar or list
+// of scalars, as Diff names the fields it shows as changed, e.g.
+// spec.containers[app].image
+func Fields(obj map[string]interface{}) map[string]string {
+	fields := map[string]string{}
+	flatten("", obj, fields)
+	return fields
+}
+
+// flatten sets a field in fields for each scalar or list of scalars of v.
+// The items of lists of named objects, like the webhooks of a webhook
+// configuration, are identified by their name rather than their index, so
+// inserting an item only shows the inserted item.
+func flatten(path string, v interface{}, fields map[string]string) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if len(value) == 0 {
+			fields[path] = "{}"
+			return
+		}
+		for k, item := range value {
+			flatten(join(path, k), item, fields)
+		}
+	case []interface{}:
+		if len(value) == 0 {
+			fields[path] = "[]"
+			return
+		}
+		if scalars(value) {
+			fields[path] = encode(value)
+			return
+		}
+		for i, item := range value {
+			index := fmt.Sprint(i)
+			if obj, ok := item.(map[string]interface{}); ok {
+				if name, ok := obj["name"].(string); ok && name != "" {
+					index = name
+				}
+			}
+			flatten(fmt.Sprintf("%s[%s]", path, index), item, fields)
+		}
+	default:
+		fields[path] = encode(value)
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func scalars(values []interface{}) bool {
+	for _, v := range values {
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			return false
+		}
+	}
+	return true
+}
+
+func maps(v interface{}) []map[string]interface{} {
+	items, _ := v.([]interface{})
+	objects := []map[string]interface{}{}
+	for _, item := range items {
+		if obj, ok := item.(map[string]interface{}); ok {
+			objects = append(objects, obj)
+		}
+	}
+	return objects
+}
+
+func encode(v interface{}) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,97 @@
+package manifestdiff
+
+import (
+	"strings"
+	"testing"
+)
+
+const oldTemplate = `
+apiVersion: template.openshift.io/v1
+kind: Template
+metadata:
+  name: selectorsyncset-template
+objects:
+- apiVersion: hive.openshift.io/v1
+  kind: SelectorSyncSet
+  metadata:
+    name: managed-cluster-validating-webhooks-0
+  spec:
+    resources:
+    - apiVersion: admissionregistration.k8s.io/v1
+      kind: ValidatingWebhookConfiguration
+      metadata:
+        name: sre-scc-validation
+      webhooks:
+      - name: scc-validation.managed.openshift.io
+        rules:
+        - operations: ["UPDATE", "DELETE"]
+          resources: ["securitycontextconstraints"]
+    - apiVersion: admissionregistration.k8s.io/v1
+      kind: ValidatingWebhookConfiguration
+      metadata:
+        name: sre-old-validation
+      webhooks: []
+`
+
+const newTemplate = `
+apiVersion: template.openshift.io/v1
+kind: Template
+metadata:
+  name: selectorsyncset-template
+objects:
+- apiVersion: hive.openshift.io/v1
+  kind: SelectorSyncSet
+  metadata:
+    name: managed-cluster-validating-webhooks-0
+  spec:
+    resources:
+    - apiVersion: admissionregistration.k8s.io/v1
+      kind: ValidatingWebhookConfiguration
+      metadata:
+        name: sre-scc-validation
+      webhooks:
+      - name: scc-validation.managed.openshift.io
+        rules:
+        - operations: ["UPDATE"]
+          resources: ["securitycontextconstraints"]
+        timeoutSeconds: 2
+    - apiVersion: admissionregistration.k8s.io/v1
+      kind: ValidatingWebhookConfiguration
+      metadata:
+        name: sre-new-validation
+      webhooks: []
+`
+
+func TestDiff(t *testing.T) {
+	diff, err := Diff([]byte(oldTemplate), []byte(newTemplate))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, line := range []string{
+		"+ ValidatingWebhookConfiguration/sre-new-validation\n",
+		"- ValidatingWebhookConfiguration/sre-old-validation\n",
+		"~ ValidatingWebhookConfiguration/sre-scc-validation\n",
+		`    webhooks[scc-validation.managed.openshift.io].rules[0].operations: ["UPDATE","DELETE"] -> ["UPDATE"]` + "\n",
+		"    + webhooks[scc-validation.managed.openshift.io].timeoutSeconds: 2\n",
+		"~ SelectorSyncSet/managed-cluster-validating-webhooks-0\n",
+		"1 added, 1 removed, 2 changed\n",
+	} {
+		if !strings.Contains(diff, line) {
+			t.Errorf("Expected the diff to contain %q, got\n%s", line, diff)
+		}
+	}
+
+	diff, err = Diff([]byte(oldTemplate), []byte(oldTemplate))
+	if err != nil || diff != "" {
+		t.Fatalf("Expected no diff between identical renderings, got %q, %v", diff, err)
+	}
+}
+
+func TestIsTemplate(t *testing.T) {
+	if !IsTemplate([]byte(oldTemplate)) {
+		t.Errorf("Expected the SelectorSyncSet template to be a template")
+	}
+	if IsTemplate([]byte("---\napiVersion: v1\nkind: Service\nmetadata:\n  name: validation-webhook\n")) {
+		t.Errorf("Expected package resources not to be a template")
+	}
+}
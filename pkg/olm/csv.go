@@ -0,0 +1,160 @@
+// Package olm holds the subset of the Operator Lifecycle Manager bundle API
+// needed to package the webhooks as an operator. The operator-framework API
+// module isn't a dependency, so the types mirror its JSON schema.
+package olm
+
+import (
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	// SkipRangeAnnotation is the range of versions a CSV upgrades from
+	// directly, in addition to the one it replaces
+	SkipRangeAnnotation = "olm.skipRange"
+
+	// Annotations of the bundle metadata
+	MediaTypeAnnotation      = "operators.operatorframework.io.bundle.mediatype.v1"
+	ManifestsAnnotation      = "operators.operatorframework.io.bundle.manifests.v1"
+	MetadataAnnotation       = "operators.operatorframework.io.bundle.metadata.v1"
+	PackageAnnotation        = "operators.operatorframework.io.bundle.package.v1"
+	ChannelsAnnotation       = "operators.operatorframework.io.bundle.channels.v1"
+	DefaultChannelAnnotation = "operators.operatorframework.io.bundle.channel.default.v1"
+
+	ValidatingAdmissionWebhook = "ValidatingAdmissionWebhook"
+	MutatingAdmissionWebhook   = "MutatingAdmissionWebhook"
+)
+
+// ClusterServiceVersion describes a version of an operator to OLM
+type ClusterServiceVersion struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              ClusterServiceVersionSpec `json:"spec"`
+}
+
+// ClusterServiceVersionSpec is the spec of a ClusterServiceVersion
+type ClusterServiceVersionSpec struct {
+	DisplayName               string                    `json:"displayName"`
+	Description               string                    `json:"description,omitempty"`
+	Version                   string                    `json:"version"`
+	Replaces                  string                    `json:"replaces,omitempty"`
+	MinKubeVersion            string                    `json:"minKubeVersion,omitempty"`
+	Maturity                  string                    `json:"maturity,omitempty"`
+	Provider                  AppLink                   `json:"provider"`
+	Links                     []AppLink                 `json:"links,omitempty"`
+	Keywords                  []string                  `json:"keywords,omitempty"`
+	InstallModes              []InstallMode             `json:"installModes"`
+	Install                   NamedInstallStrategy      `json:"install"`
+	CustomResourceDefinitions CustomResourceDefinitions `json:"customresourcedefinitions,omitempty"`
+	WebhookDefinitions        []WebhookDescription      `json:"webhookdefinitions,omitempty"`
+}
+
+// AppLink names a provider or links to a page about the operator
+type AppLink struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// InstallMode is whether the operator supports an OperatorGroup type
+type InstallMode struct {
+	Type      string `json:"type"`
+	Supported bool   `json:"supported"`
+}
+
+// NamedInstallStrategy is how OLM installs the operator
+type NamedInstallStrategy struct {
+	Strategy string                    `json:"strategy"`
+	Spec     StrategyDetailsDeployment `json:"spec"`
+}
+
+// StrategyDetailsDeployment are the deployments and RBAC of the deployment
+// install strategy
+type StrategyDetailsDeployment struct {
+	Deployments        []StrategyDeploymentSpec        `json:"deployments"`
+	Permissions        []StrategyDeploymentPermissions `json:"permissions,omitempty"`
+	ClusterPermissions []StrategyDeploymentPermissions `json:"clusterPermissions,omitempty"`
+}
+
+// StrategyDeploymentSpec is a deployment OLM creates
+type StrategyDeploymentSpec struct {
+	Name  string                `json:"name"`
+	Label map[string]string     `json:"label,omitempty"`
+	Spec  appsv1.DeploymentSpec `json:"spec"`
+}
+
+// StrategyDeploymentPermissions are the RBAC rules OLM grants a service
+// account, in the operator namespace or cluster-wide
+type StrategyDeploymentPermissions struct {
+	ServiceAccountName string              `json:"serviceAccountName"`
+	Rules              []rbacv1.PolicyRule `json:"rules"`
+}
+
+// CustomResourceDefinitions are the CRDs of the operator
+type CustomResourceDefinitions struct {
+	Owned []CRDDescription `json:"owned,omitempty"`
+}
+
+// CRDDescription describes a CRD owned by the operator
+type CRDDescription struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Kind        string `json:"kind"`
+	DisplayName string `json:"displayName,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// WebhookDescription is a webhook configuration OLM creates, with its own
+// serving certificate and CA bundle. OLM sets the namespace selector itself
+// from the OperatorGroup.
+type WebhookDescription struct {
+	GenerateName            string                              `json:"generateName"`
+	Type                    string                              `json:"type"`
+	DeploymentName          string                              `json:"deploymentName"`
+	ContainerPort           int32                               `json:"containerPort"`
+	WebhookPath             *string                             `json:"webhookPath,omitempty"`
+	Rules                   []admissionregv1.RuleWithOperations `json:"rules,omitempty"`
+	FailurePolicy           *admissionregv1.FailurePolicyType   `json:"failurePolicy,omitempty"`
+	MatchPolicy             *admissionregv1.MatchPolicyType     `json:"matchPolicy,omitempty"`
+	ObjectSelector          *metav1.LabelSelector               `json:"objectSelector,omitempty"`
+	SideEffects             *admissionregv1.SideEffectClass     `json:"sideEffects"`
+	TimeoutSeconds          *int32                              `json:"timeoutSeconds,omitempty"`
+	AdmissionReviewVersions []string                            `json:"admissionReviewVersions"`
+}
+
+// Webhook returns the description of hook on profile, served by deployment
+// on port. It returns false for webhooks OLM can't install as they are,
+// those restricted to some namespaces, since OLM sets the namespace selector
+// of every webhook itself.
+func Webhook(hook webhooks.Webhook, profile utils.Profile, deployment string, port int32, mutating bool) (WebhookDescription, bool) {
+	if hook.NamespaceSelector() != nil {
+		return WebhookDescription{}, false
+	}
+	failurePolicy := hook.FailurePolicy()
+	matchPolicy := hook.MatchPolicy()
+	sideEffects := hook.SideEffects()
+	timeout := hook.TimeoutSeconds()
+	description := WebhookDescription{
+		GenerateName:            hook.Name() + ".managed.openshift.io",
+		Type:                    ValidatingAdmissionWebhook,
+		DeploymentName:          deployment,
+		ContainerPort:           port,
+		WebhookPath:             pointer.String(hook.GetURI()),
+		Rules:                   webhooks.Rules(hook, profile),
+		FailurePolicy:           &failurePolicy,
+		MatchPolicy:             &matchPolicy,
+		ObjectSelector:          hook.ObjectSelector(),
+		SideEffects:             &sideEffects,
+		TimeoutSeconds:          &timeout,
+		AdmissionReviewVersions: []string{"v1"},
+	}
+	if mutating {
+		description.Type = MutatingAdmissionWebhook
+	}
+	return description, true
+}
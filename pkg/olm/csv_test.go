@@ -0,0 +1,36 @@
+package olm
+
+import (
+	"testing"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/podpriority"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/pullsecretinjection"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/scc"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+func TestWebhook(t *testing.T) {
+	hook := scc.NewWebhook()
+	description, ok := Webhook(hook, utils.ProfileAll, "validation-webhook", 5000, false)
+	if !ok {
+		t.Fatalf("Expected %s to be installable by OLM", hook.Name())
+	}
+	if description.Type != ValidatingAdmissionWebhook || description.GenerateName != "scc-validation.managed.openshift.io" {
+		t.Errorf("Expected a validating scc-validation.managed.openshift.io webhook, got %s %s", description.Type, description.GenerateName)
+	}
+	if description.WebhookPath == nil || *description.WebhookPath != hook.GetURI() || description.ContainerPort != 5000 || description.DeploymentName != "validation-webhook" {
+		t.Errorf("Expected the webhook to be served at %s by validation-webhook:5000, got %+v", hook.GetURI(), description)
+	}
+	if len(description.Rules) != len(hook.Rules()) || *description.FailurePolicy != hook.FailurePolicy() {
+		t.Errorf("Expected the rules and failure policy of the webhook, got %+v", description)
+	}
+
+	description, ok = Webhook(podpriority.NewWebhook(), utils.ProfileAll, "validation-webhook", 5000, true)
+	if !ok || description.Type != MutatingAdmissionWebhook {
+		t.Errorf("Expected a mutating webhook, got %+v", description)
+	}
+
+	if _, ok := Webhook(pullsecretinjection.NewWebhook(), utils.ProfileAll, "validation-webhook", 5000, true); ok {
+		t.Errorf("Expected a webhook with a namespace selector not to be installable by OLM")
+	}
+}
@@ -0,0 +1,119 @@
+// Package overlay holds the built-in environment overlays of the manifest
+// rendering, the per environment values kustomize-style overlays would
+// otherwise patch outside the repo
+package overlay
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+)
+
+//go:embed environments/*.yaml
+var environments embed.FS
+
+// Overlay is what the rendering of an environment changes from the defaults.
+// Unset fields keep the defaults and the rendering flags.
+type Overlay struct {
+	// Image is the image of the webhook pods, replacing the image the
+	// deployment pipeline sets
+	Image string `json:"image,omitempty"`
+	// Replicas is the number of webhook pods of the HyperShift deployment
+	Replicas *int32 `json:"replicas,omitempty"`
+	// MaxReplicas enables the HorizontalPodAutoscaler of the HyperShift
+	// deployment, scaling it from its replicas up to MaxReplicas
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+	// FailurePolicies replace the failure policies of webhooks, by name
+	FailurePolicies map[string]admissionregv1.FailurePolicyType `json:"failurePolicies,omitempty"`
+	// EnabledWebhooks are rendered even if they are excluded, e.g. the
+	// debug-hook on integration
+	EnabledWebhooks []string `json:"enabledWebhooks,omitempty"`
+	// DisabledWebhooks aren't rendered
+	DisabledWebhooks []string `json:"disabledWebhooks,omitempty"`
+}
+
+// Environments returns the names of the built-in overlays
+func Environments() []string {
+	entries, _ := environments.ReadDir("environments")
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".yaml"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load returns the overlay of environment, or an empty overlay if
+// environment is empty. Unknown fields are rejected, so a typo can't
+// silently render the defaults.
+func Load(environment string) (Overlay, error) {
+	o := Overlay{}
+	if environment == "" {
+		return o, nil
+	}
+	data, err := environments.ReadFile(path.Join("environments", environment+".yaml"))
+	if err != nil {
+		return o, fmt.Errorf("unknown environment %q, it must be one of %v", environment, Environments())
+	}
+	if err := yaml.Unmarshal(data, &o, func(d *json.Decoder) *json.Decoder {
+		d.DisallowUnknownFields()
+		return d
+	}); err != nil {
+		return o, fmt.Errorf("invalid overlay of environment %s: %v", environment, err)
+	}
+	return o, nil
+}
+
+// Validate returns an error if the overlay references webhooks which aren't
+// registered, sets an unknown failure policy, or scales below its replicas
+func (o Overlay) Validate(webhooks []string) error {
+	if o.MaxReplicas != nil && o.Replicas != nil && *o.MaxReplicas < *o.Replicas {
+		return fmt.Errorf("maxReplicas %d is less than replicas %d", *o.MaxReplicas, *o.Replicas)
+	}
+	referenced := append(append([]string{}, o.EnabledWebhooks...), o.DisabledWebhooks...)
+	for name, policy := range o.FailurePolicies {
+		referenced = append(referenced, name)
+		if policy != admissionregv1.Fail && policy != admissionregv1.Ignore {
+			return fmt.Errorf("invalid failure policy %q of %s, it must be Fail or Ignore", policy, name)
+		}
+	}
+	for _, name := range referenced {
+		if !slices.Contains(webhooks, name) {
+			return fmt.Errorf("unknown webhook %q", name)
+		}
+	}
+	for _, name := range o.EnabledWebhooks {
+		if slices.Contains(o.DisabledWebhooks, name) {
+			return fmt.Errorf("webhook %q is both enabled and disabled", name)
+		}
+	}
+	return nil
+}
+
+// Skip returns the webhooks skipped with the overlay, when the flags skip
+// the webhooks of skip
+func (o Overlay) Skip(skip []string) []string {
+	skipped := []string{}
+	for _, name := range skip {
+		if !slices.Contains(o.EnabledWebhooks, name) {
+			skipped = append(skipped, name)
+		}
+	}
+	return append(skipped, o.DisabledWebhooks...)
+}
+
+// FailurePolicy returns the failure policy of webhook with the overlay, whose
+// default is def
+func (o Overlay) FailurePolicy(webhook string, def admissionregv1.FailurePolicyType) admissionregv1.FailurePolicyType {
+	if policy, ok := o.FailurePolicies[webhook]; ok {
+		return policy
+	}
+	return def
+}
@@ -0,0 +1,71 @@
+package overlay
+
+import (
+	"slices"
+	"testing"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestLoad(t *testing.T) {
+	webhooks := []string{"debug-hook", "scc-validation", "pod-validation"}
+	for _, environment := range Environments() {
+		o, err := Load(environment)
+		if err != nil {
+			t.Fatalf("Unexpected error loading %s: %v", environment, err)
+		}
+		if err := o.Validate(webhooks); err != nil {
+			t.Errorf("Expected the overlay of %s to be valid, got %v", environment, err)
+		}
+	}
+	if envs := Environments(); !slices.Equal(envs, []string{"int", "prod", "stage"}) {
+		t.Errorf("Expected the int, prod and stage environments, got %v", envs)
+	}
+	if o, err := Load(""); err != nil || o.Replicas != nil {
+		t.Errorf("Expected an empty overlay without an environment, got %+v, %v", o, err)
+	}
+	if _, err := Load("qa"); err == nil {
+		t.Errorf("Expected an error loading an unknown environment")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	webhooks := []string{"debug-hook", "scc-validation"}
+	tests := []struct {
+		name    string
+		overlay Overlay
+		valid   bool
+	}{
+		{name: "empty", valid: true},
+		{name: "known webhooks", overlay: Overlay{EnabledWebhooks: []string{"debug-hook"}, FailurePolicies: map[string]admissionregv1.FailurePolicyType{"scc-validation": admissionregv1.Ignore}}, valid: true},
+		{name: "unknown enabled webhook", overlay: Overlay{EnabledWebhooks: []string{"foo"}}},
+		{name: "unknown failure policy webhook", overlay: Overlay{FailurePolicies: map[string]admissionregv1.FailurePolicyType{"foo": admissionregv1.Fail}}},
+		{name: "invalid failure policy", overlay: Overlay{FailurePolicies: map[string]admissionregv1.FailurePolicyType{"scc-validation": "Sometimes"}}},
+		{name: "enabled and disabled", overlay: Overlay{EnabledWebhooks: []string{"debug-hook"}, DisabledWebhooks: []string{"debug-hook"}}},
+		{name: "autoscaled", overlay: Overlay{Replicas: pointer.Int32(2), MaxReplicas: pointer.Int32(4)}, valid: true},
+		{name: "max replicas below replicas", overlay: Overlay{Replicas: pointer.Int32(3), MaxReplicas: pointer.Int32(2)}},
+	}
+	for _, test := range tests {
+		if err := test.overlay.Validate(webhooks); (err == nil) != test.valid {
+			t.Errorf("%s: expected valid %v, got %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestSkipAndFailurePolicy(t *testing.T) {
+	o := Overlay{
+		EnabledWebhooks:  []string{"debug-hook"},
+		DisabledWebhooks: []string{"pod-validation"},
+		FailurePolicies:  map[string]admissionregv1.FailurePolicyType{"scc-validation": admissionregv1.Ignore},
+	}
+	if skipped := o.Skip([]string{"debug-hook", "node-validation-osd"}); !slices.Equal(skipped, []string{"node-validation-osd", "pod-validation"}) {
+		t.Errorf("Expected node-validation-osd and pod-validation to be skipped, got %v", skipped)
+	}
+	if policy := o.FailurePolicy("scc-validation", admissionregv1.Fail); policy != admissionregv1.Ignore {
+		t.Errorf("Expected the overlay failure policy, got %s", policy)
+	}
+	if policy := o.FailurePolicy("pod-validation", admissionregv1.Fail); policy != admissionregv1.Fail {
+		t.Errorf("Expected the default failure policy, got %s", policy)
+	}
+}
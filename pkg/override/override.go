@@ -0,0 +1,261 @@
+package override
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	// Annotation carries an override token on the object of the request it
+	// allows
+	Annotation string = "managed.openshift.io/webhook-override"
+	// SigningKeyEnvVar is the HMAC key tokens are signed with. It is read from
+	// the SigningKeySecret, and overrides are disabled when it is unset.
+	SigningKeyEnvVar string = "OVERRIDE_SIGNING_KEY"
+	// SigningKeySecret is the optional Secret in the webhook namespace holding
+	// the signing key under SigningKeySecretKey
+	SigningKeySecret    string = "webhook-override-key"
+	SigningKeySecretKey string = "key"
+
+	// RedemptionsConfigMapName is the ConfigMap in the operator namespace
+	// recording the nonces of the redeemed tokens, by nonce, until they
+	// expire, so every webhook pod redeems a token at most once
+	RedemptionsConfigMapName string = "webhook-override-redemptions"
+
+	// MaxTTL is the longest a token may be valid for. Tokens expiring later
+	// are rejected, so a leaked token can't be kept for later.
+	MaxTTL = time.Hour
+
+	redeemTimeout = 5 * time.Second
+)
+
+var log = logf.Log.WithName("override")
+
+// Token allows one request which a webhook would deny, with a single
+// operation and reason code on a single named object, until it expires
+type Token struct {
+	Webhook   string `json:"webhook"`
+	Group     string `json:"group"`
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Operation is the admission operation allowed, e.g. DELETE
+	Operation string `json:"operation"`
+	// Code is the reason code of the denial allowed, empty for denials
+	// without one
+	Code      string `json:"code,omitempty"`
+	ExpiresAt int64  `json:"expiresAt"`
+	// Nonce makes every token unique, so each can only be redeemed once
+	Nonce string `json:"nonce"`
+}
+
+// Sign returns the annotation value of t signed with key
+func Sign(key []byte, t Token) (string, error) {
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(sign(key, encoded)), nil
+}
+
+func sign(key []byte, encoded string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+// parse verifies the signature of value and decodes its token
+func parse(key []byte, value string) (*Token, error) {
+	encoded, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, fmt.Errorf("malformed override token")
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, sign(key, encoded)) {
+		return nil, fmt.Errorf("invalid override token signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("malformed override token: %w", err)
+	}
+	t := &Token{}
+	if err := json.Unmarshal(payload, t); err != nil {
+		return nil, fmt.Errorf("malformed override token: %w", err)
+	}
+	return t, nil
+}
+
+// Verifier redeems the override tokens of denied requests
+type Verifier struct {
+	key []byte
+	now func() time.Time
+
+	// kubeClient and namespace are where the RedemptionsConfigMapName is
+	kubeClient *utils.LazyClient
+	namespace  string
+}
+
+// NewVerifierFromEnv creates a Verifier with the key from SigningKeyEnvVar,
+// or returns nil if it is unset
+func NewVerifierFromEnv() *Verifier {
+	key := os.Getenv(SigningKeyEnvVar)
+	if key == "" {
+		return nil
+	}
+	log.Info("Accepting signed override tokens")
+	return newVerifier([]byte(key))
+}
+
+func newVerifier(key []byte) *Verifier {
+	return &Verifier{
+		key:        key,
+		now:        time.Now,
+		kubeClient: utils.CoreClient,
+		namespace:  config.OperatorNamespace,
+	}
+}
+
+// Redeem returns the token annotated on the object of request, if it is
+// valid for webhook, request and the reason code of its denial and hasn't
+// been redeemed yet by any webhook pod, marking it as redeemed. It returns
+// nil without an error if there is no token. The dispatcher doesn't redeem
+// tokens for dry runs or the requests of webhooks in audit mode.
+func (v *Verifier) Redeem(ctx context.Context, webhook string, request admissionctl.Request, code utils.ReasonCode) (*Token, error) {
+	if v == nil {
+		return nil, nil
+	}
+	value, err := annotation(request)
+	if err != nil || value == "" {
+		return nil, err
+	}
+	t, err := parse(v.key, value)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.matches(webhook, request, code); err != nil {
+		return nil, err
+	}
+	now := v.now()
+	expiresAt := time.Unix(t.ExpiresAt, 0)
+	if !now.Before(expiresAt) {
+		return nil, fmt.Errorf("override token expired at %s", expiresAt.UTC().Format(time.RFC3339))
+	}
+	if expiresAt.Sub(now) > MaxTTL {
+		return nil, fmt.Errorf("override token expires more than %s ahead", MaxTTL)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, redeemTimeout)
+	defer cancel()
+	if err := v.markRedeemed(ctx, t.Nonce, expiresAt, now); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// errRedeemed is returned for a nonce which was already redeemed
+var errRedeemed = fmt.Errorf("override token was already redeemed")
+
+// markRedeemed records nonce in the RedemptionsConfigMapName until
+// expiresAt, removing the expired nonces. The ConfigMap is created or
+// updated at the resourceVersion it was read at, so of the pods redeeming a
+// token concurrently only one succeeds and the others see it redeemed when
+// they retry. A token which can't be recorded isn't redeemed.
+func (v *Verifier) markRedeemed(ctx context.Context, nonce string, expiresAt, now time.Time) error {
+	kubeClient, err := v.kubeClient.Client()
+	if err != nil {
+		return fmt.Errorf("failed to record the override token redemption: %w", err)
+	}
+	err = retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}, func() error {
+		cm := &corev1.ConfigMap{}
+		err := kubeClient.Get(ctx, client.ObjectKey{Namespace: v.namespace, Name: RedemptionsConfigMapName}, cm)
+		if apierrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: v.namespace,
+					Name:      RedemptionsConfigMapName,
+				},
+				Data: map[string]string{nonce: expiresAt.UTC().Format(time.RFC3339)},
+			}
+			return kubeClient.Create(ctx, cm)
+		}
+		if err != nil {
+			return err
+		}
+		if _, redeemed := cm.Data[nonce]; redeemed {
+			return errRedeemed
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		for used, value := range cm.Data {
+			if expiry, err := time.Parse(time.RFC3339, value); err != nil || !now.Before(expiry) {
+				delete(cm.Data, used)
+			}
+		}
+		cm.Data[nonce] = expiresAt.UTC().Format(time.RFC3339)
+		return kubeClient.Update(ctx, cm)
+	})
+	if err != nil && err != errRedeemed {
+		return fmt.Errorf("failed to record the override token redemption: %w", err)
+	}
+	return err
+}
+
+// matches returns an error if t wasn't minted for webhook, the object and
+// operation of request and the reason code of its denial
+func (t *Token) matches(webhook string, request admissionctl.Request, code utils.ReasonCode) error {
+	switch {
+	case t.Webhook != webhook:
+		return fmt.Errorf("override token is for webhook %s", t.Webhook)
+	case t.Group != request.Resource.Group || t.Resource != request.Resource.Resource:
+		return fmt.Errorf("override token is for resource %s.%s", t.Resource, t.Group)
+	case t.Namespace != request.Namespace || t.Name != request.Name:
+		return fmt.Errorf("override token is for object %s/%s", t.Namespace, t.Name)
+	case t.Operation != string(request.Operation):
+		return fmt.Errorf("override token is for operation %s", t.Operation)
+	case t.Code != string(code):
+		return fmt.Errorf("override token is for reason code %q", t.Code)
+	case t.Nonce == "":
+		return fmt.Errorf("override token has no nonce")
+	}
+	return nil
+}
+
+// annotation returns the Annotation of the object of request, which is the
+// existing object for deletions
+func annotation(request admissionctl.Request) (string, error) {
+	raw := request.Object.Raw
+	if request.Operation == admissionv1.Delete {
+		raw = request.OldObject.Raw
+	}
+	if len(raw) == 0 {
+		return "", nil
+	}
+	object := &metav1.PartialObjectMetadata{}
+	if err := json.Unmarshal(raw, object); err != nil {
+		return "", fmt.Errorf("failed to decode the object for its override token: %w", err)
+	}
+	return object.Annotations[Annotation], nil
+}
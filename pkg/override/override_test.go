@@ -0,0 +1,165 @@
+package override
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+var (
+	key = []byte("test-signing-key")
+	now = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func newToken() Token {
+	return Token{
+		Webhook:   "scc-validation",
+		Group:     "security.openshift.io",
+		Resource:  "securitycontextconstraints",
+		Name:      "anyuid",
+		Operation: "UPDATE",
+		Code:      string(utils.ReasonSCCDefaultModify),
+		ExpiresAt: now.Add(15 * time.Minute).Unix(),
+		Nonce:     "0123456789abcdef",
+	}
+}
+
+func newRequest(operation admissionv1.Operation, value string) admissionctl.Request {
+	object := runtime.RawExtension{Raw: []byte(fmt.Sprintf(`{"metadata":{"name":"anyuid","annotations":{%q:%q}}}`, Annotation, value))}
+	request := admissionctl.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: operation,
+		Name:      "anyuid",
+		Resource:  metav1.GroupVersionResource{Group: "security.openshift.io", Version: "v1", Resource: "securitycontextconstraints"},
+	}}
+	if operation == admissionv1.Delete {
+		request.OldObject = object
+	} else {
+		request.Object = object
+	}
+	return request
+}
+
+// newTestVerifier returns a Verifier recording its redemptions with
+// kubeClient
+func newTestVerifier(kubeClient client.Client) *Verifier {
+	v := newVerifier(key)
+	v.now = func() time.Time { return now }
+	v.kubeClient = utils.StaticClient(kubeClient)
+	return v
+}
+
+func redeem(v *Verifier, request admissionctl.Request) (*Token, error) {
+	return v.Redeem(context.Background(), "scc-validation", request, utils.ReasonSCCDefaultModify)
+}
+
+func mustSign(t *testing.T, token Token) string {
+	value, err := Sign(key, token)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	return value
+}
+
+func TestRedeem(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().Build()
+	value := mustSign(t, newToken())
+
+	token, err := redeem(newTestVerifier(kubeClient), newRequest(admissionv1.Update, value))
+	if err != nil || token == nil || token.Nonce != "0123456789abcdef" {
+		t.Fatalf("Expected the token to be redeemed, got %+v, %v", token, err)
+	}
+	// Another pod shares the redemptions
+	if _, err := redeem(newTestVerifier(kubeClient), newRequest(admissionv1.Update, value)); err == nil || !strings.Contains(err.Error(), "already redeemed") {
+		t.Fatalf("Expected the token to only be redeemed once, got %v", err)
+	}
+}
+
+func TestRedeemRemovesExpired(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-validation-webhook", Name: RedemptionsConfigMapName},
+		Data:       map[string]string{"expired": now.Add(-time.Minute).Format(time.RFC3339)},
+	}).Build()
+	v := newTestVerifier(kubeClient)
+	v.namespace = "openshift-validation-webhook"
+	if token, err := redeem(v, newRequest(admissionv1.Update, mustSign(t, newToken()))); err != nil || token == nil {
+		t.Fatalf("Expected the token to be redeemed, got %+v, %v", token, err)
+	}
+	cm := &corev1.ConfigMap{}
+	if err := kubeClient.Get(context.Background(), client.ObjectKey{Namespace: "openshift-validation-webhook", Name: RedemptionsConfigMapName}, cm); err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	if _, ok := cm.Data["0123456789abcdef"]; !ok || len(cm.Data) != 1 {
+		t.Fatalf("Expected only the redeemed nonce to be kept, got %v", cm.Data)
+	}
+}
+
+func TestRedeemRejects(t *testing.T) {
+	expired := newToken()
+	expired.ExpiresAt = now.Add(-time.Minute).Unix()
+	tooLong := newToken()
+	tooLong.ExpiresAt = now.Add(2 * MaxTTL).Unix()
+	otherName := newToken()
+	otherName.Name = "privileged"
+	otherWebhook := newToken()
+	otherWebhook.Webhook = "namespace-validation"
+	otherOperation := newToken()
+	otherOperation.Operation = "DELETE"
+	otherCode := newToken()
+	otherCode.Code = string(utils.ReasonSCCDefaultDelete)
+	forged, err := Sign([]byte("another-key"), newToken())
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	tests := []struct {
+		testID string
+		value  string
+	}{
+		{testID: "expired", value: mustSign(t, expired)},
+		{testID: "too long", value: mustSign(t, tooLong)},
+		{testID: "other object", value: mustSign(t, otherName)},
+		{testID: "other webhook", value: mustSign(t, otherWebhook)},
+		{testID: "other operation", value: mustSign(t, otherOperation)},
+		{testID: "other reason code", value: mustSign(t, otherCode)},
+		{testID: "forged", value: forged},
+		{testID: "malformed", value: "not-a-token"},
+	}
+	for _, test := range tests {
+		v := newTestVerifier(fake.NewClientBuilder().Build())
+		if token, err := redeem(v, newRequest(admissionv1.Update, test.value)); err == nil {
+			t.Fatalf("%s: Expected the token to be rejected, got %+v", test.testID, token)
+		}
+	}
+}
+
+func TestRedeemDelete(t *testing.T) {
+	token := newToken()
+	token.Operation = "DELETE"
+	v := newTestVerifier(fake.NewClientBuilder().Build())
+	if token, err := redeem(v, newRequest(admissionv1.Delete, mustSign(t, token))); err != nil || token == nil {
+		t.Fatalf("Expected the token of the deleted object to be redeemed, got %+v, %v", token, err)
+	}
+}
+
+func TestRedeemWithoutToken(t *testing.T) {
+	var disabled *Verifier
+	if token, err := redeem(disabled, newRequest(admissionv1.Update, mustSign(t, newToken()))); token != nil || err != nil {
+		t.Fatalf("Expected a nil Verifier to redeem nothing, got %+v, %v", token, err)
+	}
+	v := newTestVerifier(fake.NewClientBuilder().Build())
+	request := admissionctl.Request{AdmissionRequest: admissionv1.AdmissionRequest{Object: runtime.RawExtension{Raw: []byte(`{"metadata":{"name":"anyuid"}}`)}}}
+	if token, err := redeem(v, request); token != nil || err != nil {
+		t.Fatalf("Expected no token, got %+v, %v", token, err)
+	}
+}
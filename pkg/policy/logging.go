@@ -0,0 +1,76 @@
+package policy
+
+import (
+	"github.com/go-logr/logr"
+)
+
+// debugVerbosity is the highest verbosity logged by webhooks at LogLevelDebug
+const debugVerbosity = 1
+
+// LogSink wraps sink so that the loggers named after a webhook log at the
+// LogLevel the ValidatingWebhookPolicy sets for it, e.g. the logger of
+// logf.Log.WithName("scc-validation"). Other loggers, and webhooks at
+// LogLevelInfo, keep the verbosity of sink.
+func LogSink(sink logr.LogSink) logr.LogSink {
+	return &logSink{sink: sink}
+}
+
+// logSink filters the log lines of a webhook by its LogLevel
+type logSink struct {
+	sink logr.LogSink
+	// webhook is the first name of the logger, which is the webhook name for
+	// the loggers of webhooks
+	webhook string
+}
+
+func (s *logSink) Init(info logr.RuntimeInfo) {
+	// Skip this sink's frame when reporting the caller
+	info.CallDepth++
+	s.sink.Init(info)
+}
+
+func (s *logSink) Enabled(level int) bool {
+	switch Current().WebhookLogLevel(s.webhook) {
+	case LogLevelError:
+		return false
+	case LogLevelDebug:
+		if level <= debugVerbosity {
+			return true
+		}
+	}
+	return s.sink.Enabled(level)
+}
+
+func (s *logSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	if level > 0 && level <= debugVerbosity && Current().WebhookLogLevel(s.webhook) == LogLevelDebug {
+		// sink checks its own verbosity again, so log the debug line at its
+		// default verbosity
+		keysAndValues = append(keysAndValues, "v", level)
+		level = 0
+	}
+	s.sink.Info(level, msg, keysAndValues...)
+}
+
+func (s *logSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.sink.Error(err, msg, keysAndValues...)
+}
+
+func (s *logSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &logSink{sink: s.sink.WithValues(keysAndValues...), webhook: s.webhook}
+}
+
+func (s *logSink) WithName(name string) logr.LogSink {
+	webhook := s.webhook
+	if webhook == "" {
+		webhook = name
+	}
+	return &logSink{sink: s.sink.WithName(name), webhook: webhook}
+}
+
+func (s *logSink) WithCallDepth(depth int) logr.LogSink {
+	withCallDepth, ok := s.sink.(logr.CallDepthLogSink)
+	if !ok {
+		return s
+	}
+	return &logSink{sink: withCallDepth.WithCallDepth(depth), webhook: s.webhook}
+}
@@ -0,0 +1,42 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+)
+
+func TestLogSink(t *testing.T) {
+	SetCurrent(Spec{Webhooks: []WebhookPolicy{
+		{Name: "scc-validation", LogLevel: LogLevelDebug},
+		{Name: "pdbrelax-mutation", LogLevel: LogLevelError},
+	}})
+	t.Cleanup(func() { SetCurrent(Spec{}) })
+
+	var lines []string
+	root := logr.New(LogSink(funcr.New(func(prefix, args string) {
+		lines = append(lines, prefix+" "+args)
+	}, funcr.Options{}).GetSink()))
+
+	root.WithName("scc-validation").V(1).Info("debug line")
+	root.WithName("scc-validation").V(2).Info("trace line")
+	root.WithName("pdbrelax-mutation").Info("info line")
+	root.WithName("pdbrelax-mutation").Error(nil, "error line")
+	root.WithName("dispatcher").V(1).Info("debug line")
+	root.WithName("dispatcher").Info("info line")
+
+	expected := []string{
+		`scc-validation "level"=0 "msg"="debug line" "v"=1`,
+		`pdbrelax-mutation "msg"="error line" "error"=null`,
+		`dispatcher "level"=0 "msg"="info line"`,
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d log lines, got %q", len(expected), lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], lines[i])
+		}
+	}
+}
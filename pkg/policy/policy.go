@@ -0,0 +1,236 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/k8sutil"
+)
+
+const (
+	// refreshInterval is how often the ValidatingWebhookPolicy is read, so
+	// how long a change may take to apply
+	refreshInterval = 30 * time.Second
+	getTimeout      = 10 * time.Second
+)
+
+var (
+	log = logf.Log.WithName("policy")
+
+	// current is the Store read by Current, set by Start
+	current   *Store
+	currentMu sync.RWMutex
+)
+
+// Store keeps the accepted spec of the ValidatingWebhookPolicy, refreshed in
+// the background, and reports in its status whether it was accepted
+type Store struct {
+	mu   sync.RWMutex
+	spec Spec
+	// webhooks are the names of the served webhooks, which the policy may
+	// reference
+	webhooks []string
+	lastErr  string
+	// lastInvalid is the validation error already logged
+	lastInvalid string
+	// openWindows are the maintenance windows already logged as open
+	openWindows map[string]bool
+
+	kubeClient client.Client
+}
+
+// Start creates and starts the Store of the webhooks, and makes its spec the
+// one returned by Current
+func Start(webhooks []string) *Store {
+	s := newStore(webhooks)
+	currentMu.Lock()
+	current = s
+	currentMu.Unlock()
+	go s.run()
+	return s
+}
+
+func newStore(webhooks []string) *Store {
+	return &Store{webhooks: webhooks, openWindows: map[string]bool{}}
+}
+
+// Current returns the accepted spec, which is empty until a policy is
+// accepted, so the webhooks apply their defaults
+func Current() Spec {
+	currentMu.RLock()
+	s := current
+	currentMu.RUnlock()
+	return s.Spec()
+}
+
+// SetCurrent replaces the Store read by Current with one holding spec, e.g.
+// in tests of the webhooks
+func SetCurrent(spec Spec) {
+	s := newStore(nil)
+	s.spec = spec
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = s
+}
+
+// Spec returns the accepted spec
+func (s *Store) Spec() Spec {
+	if s == nil {
+		return Spec{}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.spec
+}
+
+func (s *Store) run() {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		s.refresh()
+		<-ticker.C
+	}
+}
+
+func (s *Store) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), getTimeout)
+	defer cancel()
+	if err := s.reconcile(ctx); err != nil {
+		// Only log when the error changes, e.g. the CRD isn't installed on
+		// HyperShift
+		if err.Error() != s.lastErr {
+			log.Error(err, "Failed to reconcile the ValidatingWebhookPolicy, keeping the last accepted policy")
+			s.lastErr = err.Error()
+		}
+	} else {
+		s.lastErr = ""
+	}
+	s.logWindows(time.Now())
+}
+
+// logWindows logs the maintenance windows which opened or closed since the
+// last refresh. The webhooks check the windows on every request, so they
+// re-enforce when a window closes even if it isn't logged yet.
+func (s *Store) logWindows(now time.Time) {
+	open := map[string]bool{}
+	for _, w := range s.Spec().MaintenanceWindows {
+		if !w.OpenAt(now) {
+			continue
+		}
+		open[w.Name] = true
+		if !s.openWindows[w.Name] {
+			log.Info("Maintenance window opened, its webhooks are audit-only", "window", w.Name, "webhooks", w.Webhooks)
+		}
+	}
+	for name := range s.openWindows {
+		if !open[name] {
+			log.Info("Maintenance window closed, its webhooks are enforced", "window", name)
+		}
+	}
+	s.openWindows = open
+}
+
+// reconcile applies the ValidatingWebhookPolicy if it is valid, and reports
+// whether it was accepted in its status. Deleting the policy reverts the
+// webhooks to their defaults; an invalid policy keeps the last accepted one.
+func (s *Store) reconcile(ctx context.Context) error {
+	if err := s.ensureClient(); err != nil {
+		return err
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(policyGVK)
+	if err := s.kubeClient.Get(ctx, client.ObjectKey{Name: Name}, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			s.set(Spec{})
+			return nil
+		}
+		return err
+	}
+	p := &ValidatingWebhookPolicy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, p); err != nil {
+		return err
+	}
+
+	condition := metav1.Condition{
+		Type:               ConditionAccepted,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: p.Generation,
+		Reason:             "Applied",
+		Message:            "The webhooks apply the policy",
+	}
+	if err := p.Spec.Validate(s.webhooks); err != nil {
+		if err.Error() != s.lastInvalid {
+			log.Info("Ignoring invalid ValidatingWebhookPolicy", "reason", err.Error())
+			s.lastInvalid = err.Error()
+		}
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Invalid"
+		condition.Message = err.Error() + ", the webhooks keep the last accepted policy"
+	} else {
+		s.lastInvalid = ""
+		s.set(p.Spec)
+	}
+	return s.updateStatus(ctx, p, condition)
+}
+
+// set replaces the accepted spec, logging when it changes
+func (s *Store) set(spec Spec) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !reflect.DeepEqual(s.spec, spec) {
+		encoded, _ := json.Marshal(spec)
+		log.Info("Applying ValidatingWebhookPolicy", "spec", string(encoded))
+	}
+	s.spec = spec
+}
+
+// updateStatus sets condition on p, only writing the status when it changes
+// since every webhook pod reconciles the same policy
+func (s *Store) updateStatus(ctx context.Context, p *ValidatingWebhookPolicy, condition metav1.Condition) error {
+	existing := meta.FindStatusCondition(p.Status.Conditions, condition.Type)
+	if p.Status.ObservedGeneration == p.Generation && existing != nil &&
+		existing.Status == condition.Status && existing.Reason == condition.Reason &&
+		existing.Message == condition.Message && existing.ObservedGeneration == condition.ObservedGeneration {
+		return nil
+	}
+	p.Status.ObservedGeneration = p.Generation
+	meta.SetStatusCondition(&p.Status.Conditions, condition)
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(p)
+	if err != nil {
+		return err
+	}
+	obj := &unstructured.Unstructured{Object: content}
+	obj.SetGroupVersionKind(policyGVK)
+	if err := s.kubeClient.Status().Update(ctx, obj); err != nil {
+		if apierrors.IsConflict(err) {
+			// Another webhook pod updated it first, the next refresh
+			// compares against its status
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Store) ensureClient() error {
+	if s.kubeClient != nil {
+		return nil
+	}
+	kubeClient, err := k8sutil.KubeClient(runtime.NewScheme())
+	if err != nil {
+		return err
+	}
+	s.kubeClient = kubeClient
+	return nil
+}
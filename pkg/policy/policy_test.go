@@ -0,0 +1,121 @@
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var servedWebhooks = []string{"scc-validation", "pdbrelax-mutation"}
+
+func newPolicyObject(t *testing.T, generation int64, spec Spec) *unstructured.Unstructured {
+	t.Helper()
+	p := &ValidatingWebhookPolicy{ObjectMeta: metav1.ObjectMeta{Name: Name, Generation: generation}, Spec: spec}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(p)
+	if err != nil {
+		t.Fatalf("Couldn't convert the policy: %v", err)
+	}
+	obj := &unstructured.Unstructured{Object: content}
+	obj.SetGroupVersionKind(policyGVK)
+	return obj
+}
+
+func getPolicy(t *testing.T, c client.Client) *ValidatingWebhookPolicy {
+	t.Helper()
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(policyGVK)
+	if err := c.Get(context.Background(), client.ObjectKey{Name: Name}, obj); err != nil {
+		t.Fatalf("Couldn't get the policy: %v", err)
+	}
+	p := &ValidatingWebhookPolicy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, p); err != nil {
+		t.Fatalf("Couldn't convert the policy: %v", err)
+	}
+	return p
+}
+
+func TestReconcile(t *testing.T) {
+	spec := Spec{SCCPriorityCeiling: pointer.Int32(5), Webhooks: []WebhookPolicy{{Name: "scc-validation", Mode: ModeAudit}}}
+	c := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).WithObjects(newPolicyObject(t, 1, spec)).Build()
+	s := newStore(servedWebhooks)
+	s.kubeClient = c
+
+	if err := s.reconcile(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if s.Spec().SCCPriorityCeilingOr(MaxSCCPriorityCeiling) != 5 || s.Spec().WebhookMode("scc-validation") != ModeAudit {
+		t.Fatalf("Expected the policy to be applied, got %+v", s.Spec())
+	}
+	p := getPolicy(t, c)
+	accepted := meta.FindStatusCondition(p.Status.Conditions, ConditionAccepted)
+	if accepted == nil || accepted.Status != metav1.ConditionTrue || p.Status.ObservedGeneration != 1 {
+		t.Fatalf("Expected the policy to be reported accepted, got %+v", p.Status)
+	}
+
+	// An invalid policy keeps the last accepted one
+	invalid := newPolicyObject(t, 2, Spec{Webhooks: []WebhookPolicy{{Name: "unknown-validation", Mode: ModeAudit}}})
+	invalid.SetResourceVersion(p.ResourceVersion)
+	if err := c.Update(context.Background(), invalid); err != nil {
+		t.Fatalf("Couldn't update the policy: %v", err)
+	}
+	if err := s.reconcile(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if s.Spec().SCCPriorityCeilingOr(MaxSCCPriorityCeiling) != 5 {
+		t.Fatalf("Expected the last accepted policy to be kept, got %+v", s.Spec())
+	}
+	p = getPolicy(t, c)
+	accepted = meta.FindStatusCondition(p.Status.Conditions, ConditionAccepted)
+	if accepted == nil || accepted.Status != metav1.ConditionFalse || accepted.Reason != "Invalid" || p.Status.ObservedGeneration != 2 {
+		t.Fatalf("Expected the policy to be reported invalid, got %+v", p.Status)
+	}
+
+	// Deleting the policy reverts to the defaults
+	if err := c.Delete(context.Background(), invalid); err != nil {
+		t.Fatalf("Couldn't delete the policy: %v", err)
+	}
+	if err := s.reconcile(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if s.Spec().SCCPriorityCeiling != nil || s.Spec().WebhookMode("scc-validation") != ModeEnforce {
+		t.Fatalf("Expected the defaults once the policy is deleted, got %+v", s.Spec())
+	}
+}
+
+func TestCurrentWithoutStore(t *testing.T) {
+	if Current().WebhookMode("scc-validation") != ModeEnforce || Current().PDBMode() != PDBPolicyRelax {
+		t.Fatalf("Expected the defaults without a Store, got %+v", Current())
+	}
+}
+
+func TestReconcileMaintenanceWindows(t *testing.T) {
+	window := MaintenanceWindow{Name: "upgrades", Webhooks: []string{"pdbrelax-mutation"}, Schedule: "0 2 * * 6", Duration: &metav1.Duration{Duration: 4 * time.Hour}}
+	c := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).WithObjects(newPolicyObject(t, 1, Spec{MaintenanceWindows: []MaintenanceWindow{window}})).Build()
+	s := newStore(servedWebhooks)
+	s.kubeClient = c
+
+	if err := s.reconcile(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if windows := s.Spec().MaintenanceWindows; len(windows) != 1 || windows[0].Duration.Duration != 4*time.Hour {
+		t.Fatalf("Expected the maintenance window to be applied, got %+v", windows)
+	}
+
+	saturday, _ := time.Parse(time.RFC3339, "2023-05-06T03:00:00Z")
+	s.logWindows(saturday)
+	if !s.openWindows["upgrades"] {
+		t.Fatalf("Expected the window to be open, got %v", s.openWindows)
+	}
+	s.logWindows(saturday.Add(3 * time.Hour))
+	if len(s.openWindows) != 0 {
+		t.Fatalf("Expected the window to be closed, got %v", s.openWindows)
+	}
+}
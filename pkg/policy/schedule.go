@@ -0,0 +1,120 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule is a parsed cron expression: minute, hour, day of month, month
+// and day of week, evaluated in UTC
+type schedule struct {
+	minutes, hours, days, months, weekdays []bool
+	// anyDay and anyWeekday are whether the day of month and day of week
+	// fields are wildcards. Like cron, if both are restricted a time matches
+	// when either does.
+	anyDay, anyWeekday bool
+}
+
+// parseSchedule parses a standard five field cron expression, e.g.
+// "0 2 * * 6" for 02:00 UTC every Saturday. Fields accept *, values, ranges,
+// lists and steps, e.g. "1-5", "0,30" or "*/15".
+func parseSchedule(expr string) (*schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q must have 5 fields, minute hour day-of-month month day-of-week", expr)
+	}
+	s := &schedule{anyDay: fields[2] == "*", anyWeekday: fields[4] == "*"}
+	var err error
+	if s.minutes, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid minute of schedule %q: %v", expr, err)
+	}
+	if s.hours, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid hour of schedule %q: %v", expr, err)
+	}
+	if s.days, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid day of month of schedule %q: %v", expr, err)
+	}
+	if s.months, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid month of schedule %q: %v", expr, err)
+	}
+	// 7 is also Sunday
+	if s.weekdays, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid day of week of schedule %q: %v", expr, err)
+	}
+	s.weekdays[0] = s.weekdays[0] || s.weekdays[7]
+	return s, nil
+}
+
+// parseField returns which values between min and max a cron field matches
+func parseField(field string, min, max int) ([]bool, error) {
+	matches := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		r, s, hasStep := strings.Cut(part, "/")
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(s); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step %q", s)
+			}
+			part = r
+		}
+		low, high := min, max
+		if part != "*" {
+			l, h, isRange := strings.Cut(part, "-")
+			var err error
+			if low, err = strconv.Atoi(l); err != nil {
+				return nil, fmt.Errorf("invalid value %q", l)
+			}
+			high = low
+			if hasStep {
+				// e.g. 5/15 steps from 5 to the end of the range
+				high = max
+			}
+			if isRange {
+				if high, err = strconv.Atoi(h); err != nil {
+					return nil, fmt.Errorf("invalid value %q", h)
+				}
+			}
+		}
+		if low < min || high > max || low > high {
+			return nil, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for v := low; v <= high; v += step {
+			matches[v] = true
+		}
+	}
+	return matches, nil
+}
+
+// matches returns whether the minute of t is one the schedule fires at
+func (s *schedule) matches(t time.Time) bool {
+	t = t.UTC()
+	if !s.minutes[t.Minute()] || !s.hours[t.Hour()] || !s.months[int(t.Month())] {
+		return false
+	}
+	day, weekday := s.days[t.Day()], s.weekdays[int(t.Weekday())]
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// lastFiredWithin returns the last time at or before now the schedule fired,
+// if it fired less than within ago
+func (s *schedule) lastFiredWithin(now time.Time, within time.Duration) (time.Time, bool) {
+	start := now.UTC().Truncate(time.Minute)
+	for t := start; now.Sub(t) < within; t = t.Add(-time.Minute) {
+		if s.matches(t) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
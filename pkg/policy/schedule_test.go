@@ -0,0 +1,62 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	for _, expr := range []string{"0 2 * * 6", "*/15 * * * *", "0 0-6 1,15 * 1-5", "30 4 * 1-12/3 7", "5/20 * * * *"} {
+		if _, err := parseSchedule(expr); err != nil {
+			t.Errorf("Expected %q to parse, got %v", expr, err)
+		}
+	}
+	for _, expr := range []string{"", "0 2 * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := parseSchedule(expr); err == nil {
+			t.Errorf("Expected %q to fail to parse", expr)
+		}
+	}
+}
+
+func TestScheduleMatches(t *testing.T) {
+	tests := []struct {
+		expr     string
+		at       string
+		expected bool
+	}{
+		// 2023-05-06 is a Saturday
+		{expr: "0 2 * * 6", at: "2023-05-06T02:00:00Z", expected: true},
+		{expr: "0 2 * * 6", at: "2023-05-06T02:01:00Z", expected: false},
+		{expr: "0 2 * * 6", at: "2023-05-07T02:00:00Z", expected: false},
+		{expr: "0 2 * * 0", at: "2023-05-07T02:00:00Z", expected: true},
+		{expr: "0 2 * * 7", at: "2023-05-07T02:00:00Z", expected: true},
+		{expr: "*/15 * * * *", at: "2023-05-06T10:45:00Z", expected: true},
+		{expr: "5/20 * * * *", at: "2023-05-06T10:45:00Z", expected: true},
+		// Restricted day of month and day of week match either
+		{expr: "0 0 1 * 6", at: "2023-05-06T00:00:00Z", expected: true},
+		{expr: "0 0 1 * 6", at: "2023-05-01T00:00:00Z", expected: true},
+		{expr: "0 0 1 * 6", at: "2023-05-02T00:00:00Z", expected: false},
+	}
+	for _, test := range tests {
+		s, err := parseSchedule(test.expr)
+		if err != nil {
+			t.Fatalf("Expected %q to parse, got %v", test.expr, err)
+		}
+		at, _ := time.Parse(time.RFC3339, test.at)
+		if s.matches(at) != test.expected {
+			t.Errorf("Expected %q matching %s to be %v", test.expr, test.at, test.expected)
+		}
+	}
+}
+
+func TestLastFiredWithin(t *testing.T) {
+	s, _ := parseSchedule("0 2 * * 6")
+	now, _ := time.Parse(time.RFC3339, "2023-05-06T05:30:10Z")
+	fired, ok := s.lastFiredWithin(now, 4*time.Hour)
+	if !ok || fired.Format(time.RFC3339) != "2023-05-06T02:00:00Z" {
+		t.Fatalf("Expected the schedule to have fired at 02:00, got %v, %v", fired, ok)
+	}
+	if _, ok := s.lastFiredWithin(now, 3*time.Hour); ok {
+		t.Fatalf("Expected the schedule not to have fired within 3h")
+	}
+}
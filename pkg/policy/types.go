@@ -0,0 +1,394 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	Group   string = "managed.openshift.io"
+	Version string = "v1alpha1"
+	Kind    string = "ValidatingWebhookPolicy"
+	Plural  string = "validatingwebhookpolicies"
+
+	// Name is the name of the only ValidatingWebhookPolicy the webhooks read
+	Name string = "cluster"
+
+	// MaxSCCPriorityCeiling is the highest SCC priority ceiling. It keeps
+	// customer SCCs below the default anyuid SCC (priority 10).
+	MaxSCCPriorityCeiling int32 = 9
+
+	// ConditionAccepted reports whether the spec is in effect
+	ConditionAccepted string = "Accepted"
+
+	// MaxMaintenanceWindow is the longest a maintenance window may stay
+	// open, so a mistaken window can't disable a webhook for long
+	MaxMaintenanceWindow = 12 * time.Hour
+)
+
+// Mode is whether a webhook denies requests or only audits them
+type Mode string
+
+const (
+	// ModeEnforce denies requests, the default
+	ModeEnforce Mode = "Enforce"
+	// ModeAudit allows requests the webhook would deny with a warning, after
+	// logging and recording them as denials
+	ModeAudit Mode = "Audit"
+)
+
+// LogLevel is how much a webhook logs
+type LogLevel string
+
+const (
+	// LogLevelError only logs errors
+	LogLevelError LogLevel = "Error"
+	// LogLevelInfo logs at the verbosity of the webhook pods, the default
+	LogLevelInfo LogLevel = "Info"
+	// LogLevelDebug also logs debug lines, e.g. every request the webhook
+	// allows
+	LogLevelDebug LogLevel = "Debug"
+)
+
+// PDBPolicyMode is how pdbrelax-mutation handles PodDisruptionBudgets which
+// allow no disruptions
+type PDBPolicyMode string
+
+const (
+	// PDBPolicyRelax rewrites them to allow a disruption, the default
+	PDBPolicyRelax PDBPolicyMode = "Relax"
+	// PDBPolicyWarn only warns that they block node drains
+	PDBPolicyWarn PDBPolicyMode = "Warn"
+)
+
+var policyGVK = schema.GroupVersionKind{Group: Group, Version: Version, Kind: Kind}
+
+// ValidatingWebhookPolicy tunes the webhooks of a cluster at runtime
+type ValidatingWebhookPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   Spec   `json:"spec"`
+	Status Status `json:"status,omitempty"`
+}
+
+// Spec are the tunables of the webhooks. Unset tunables keep the webhooks'
+// defaults.
+type Spec struct {
+	// SCCPriorityCeiling is the highest priority of customer SCCs, at most
+	// MaxSCCPriorityCeiling
+	SCCPriorityCeiling *int32 `json:"sccPriorityCeiling,omitempty"`
+	// LoadBalancerQuota is how many LoadBalancer Services each customer
+	// namespace may have. It is unlimited if unset.
+	LoadBalancerQuota *int32 `json:"loadBalancerQuota,omitempty"`
+	// PDBPolicyMode is how PodDisruptionBudgets which allow no disruptions
+	// are handled
+	PDBPolicyMode PDBPolicyMode `json:"pdbPolicyMode,omitempty"`
+	// Webhooks are the modes and log levels of individual webhooks
+	Webhooks []WebhookPolicy `json:"webhooks,omitempty"`
+	// MaintenanceWindows switch webhooks to ModeAudit while they are open
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+}
+
+// WebhookPolicy is the mode and log level of a webhook
+type WebhookPolicy struct {
+	// Name is the name of the webhook, e.g. scc-validation
+	Name string `json:"name"`
+	// Mode is whether the webhook denies or audits requests
+	Mode Mode `json:"mode,omitempty"`
+	// LogLevel is how much the webhook logs
+	LogLevel LogLevel `json:"logLevel,omitempty"`
+}
+
+// MaintenanceWindow is when webhooks are audit-only, e.g. during upgrades.
+// It recurs by Schedule for Duration, or opens once from Start to End.
+type MaintenanceWindow struct {
+	// Name identifies the window in logs and audit annotations
+	Name string `json:"name"`
+	// Webhooks are the names of the webhooks audited during the window
+	Webhooks []string `json:"webhooks"`
+	// Schedule is a cron expression, in UTC, of when the window opens, e.g.
+	// "0 2 * * 6" for 02:00 every Saturday
+	Schedule string `json:"schedule,omitempty"`
+	// Duration is how long a scheduled window stays open, e.g. 4h
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	// Start and End bound a window which opens once
+	Start *metav1.Time `json:"start,omitempty"`
+	End   *metav1.Time `json:"end,omitempty"`
+}
+
+// Status reports whether the webhooks applied the spec
+type Status struct {
+	// ObservedGeneration is the generation of the spec the conditions
+	// describe
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions includes ConditionAccepted
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// SCCPriorityCeilingOr returns the SCCPriorityCeiling, or def if it's unset
+func (s Spec) SCCPriorityCeilingOr(def int32) int32 {
+	if s.SCCPriorityCeiling == nil {
+		return def
+	}
+	return *s.SCCPriorityCeiling
+}
+
+// PDBMode returns the PDBPolicyMode, defaulting to PDBPolicyRelax
+func (s Spec) PDBMode() PDBPolicyMode {
+	if s.PDBPolicyMode == "" {
+		return PDBPolicyRelax
+	}
+	return s.PDBPolicyMode
+}
+
+// WebhookMode returns the Mode of webhook, defaulting to ModeEnforce
+func (s Spec) WebhookMode(webhook string) Mode {
+	for _, w := range s.Webhooks {
+		if w.Name == webhook && w.Mode != "" {
+			return w.Mode
+		}
+	}
+	return ModeEnforce
+}
+
+// AuditSource returns what audits webhook at now rather than enforcing it:
+// the policy name if its Mode is ModeAudit, or the maintenance window open at
+// now, e.g. "maintenance/upgrades". It returns an empty string if webhook
+// is enforced.
+func (s Spec) AuditSource(webhook string, now time.Time) string {
+	if s.WebhookMode(webhook) == ModeAudit {
+		return Name
+	}
+	for _, w := range s.MaintenanceWindows {
+		if slices.Contains(w.Webhooks, webhook) && w.OpenAt(now) {
+			return "maintenance/" + w.Name
+		}
+	}
+	return ""
+}
+
+// OpenAt returns whether the window is open at now
+func (w MaintenanceWindow) OpenAt(now time.Time) bool {
+	if w.Schedule == "" {
+		return w.Start != nil && w.End != nil && !now.Before(w.Start.Time) && now.Before(w.End.Time)
+	}
+	sched, err := parseSchedule(w.Schedule)
+	if err != nil || w.Duration == nil {
+		// Validate rejects these windows
+		return false
+	}
+	_, open := sched.lastFiredWithin(now, w.Duration.Duration)
+	return open
+}
+
+// validate returns why the window can't be applied to webhooks
+func (w MaintenanceWindow) validate(webhooks []string) error {
+	if len(w.Webhooks) == 0 {
+		return fmt.Errorf("maintenance window %q lists no webhooks", w.Name)
+	}
+	for _, webhook := range w.Webhooks {
+		if !slices.Contains(webhooks, webhook) {
+			return fmt.Errorf("unknown webhook %q of maintenance window %q", webhook, w.Name)
+		}
+	}
+	var length time.Duration
+	switch {
+	case w.Schedule != "" && (w.Start != nil || w.End != nil):
+		return fmt.Errorf("maintenance window %q must set either a schedule and duration, or a start and end", w.Name)
+	case w.Schedule != "":
+		if _, err := parseSchedule(w.Schedule); err != nil {
+			return fmt.Errorf("maintenance window %q: %v", w.Name, err)
+		}
+		if w.Duration == nil {
+			return fmt.Errorf("maintenance window %q must set the duration of its schedule", w.Name)
+		}
+		length = w.Duration.Duration
+	case w.Start != nil && w.End != nil:
+		length = w.End.Sub(w.Start.Time)
+	default:
+		return fmt.Errorf("maintenance window %q must set either a schedule and duration, or a start and end", w.Name)
+	}
+	if length <= 0 || length > MaxMaintenanceWindow {
+		return fmt.Errorf("maintenance window %q must stay open for more than 0 and at most %s", w.Name, MaxMaintenanceWindow)
+	}
+	return nil
+}
+
+// WebhookLogLevel returns the LogLevel of webhook, defaulting to LogLevelInfo
+func (s Spec) WebhookLogLevel(webhook string) LogLevel {
+	for _, w := range s.Webhooks {
+		if w.Name == webhook && w.LogLevel != "" {
+			return w.LogLevel
+		}
+	}
+	return LogLevelInfo
+}
+
+// Validate returns why s can't be applied to webhooks, the names of the
+// served webhooks. The CRD schema already rejects most invalid values when
+// the policy is written; this catches what it can't, e.g. unknown webhooks.
+func (s Spec) Validate(webhooks []string) error {
+	if s.SCCPriorityCeiling != nil && (*s.SCCPriorityCeiling < 0 || *s.SCCPriorityCeiling > MaxSCCPriorityCeiling) {
+		return fmt.Errorf("sccPriorityCeiling %d must be between 0 and %d", *s.SCCPriorityCeiling, MaxSCCPriorityCeiling)
+	}
+	if s.LoadBalancerQuota != nil && *s.LoadBalancerQuota < 0 {
+		return fmt.Errorf("loadBalancerQuota %d must not be negative", *s.LoadBalancerQuota)
+	}
+	if s.PDBPolicyMode != "" && s.PDBPolicyMode != PDBPolicyRelax && s.PDBPolicyMode != PDBPolicyWarn {
+		return fmt.Errorf("unknown pdbPolicyMode %q", s.PDBPolicyMode)
+	}
+	seen := map[string]bool{}
+	for _, w := range s.Webhooks {
+		if !slices.Contains(webhooks, w.Name) {
+			return fmt.Errorf("unknown webhook %q", w.Name)
+		}
+		if seen[w.Name] {
+			return fmt.Errorf("webhook %q is listed more than once", w.Name)
+		}
+		seen[w.Name] = true
+		if w.Mode != "" && w.Mode != ModeEnforce && w.Mode != ModeAudit {
+			return fmt.Errorf("unknown mode %q of webhook %q", w.Mode, w.Name)
+		}
+		if w.LogLevel != "" && w.LogLevel != LogLevelError && w.LogLevel != LogLevelInfo && w.LogLevel != LogLevelDebug {
+			return fmt.Errorf("unknown logLevel %q of webhook %q", w.LogLevel, w.Name)
+		}
+	}
+	windows := map[string]bool{}
+	for _, w := range s.MaintenanceWindows {
+		if windows[w.Name] {
+			return fmt.Errorf("maintenance window %q is listed more than once", w.Name)
+		}
+		windows[w.Name] = true
+		if err := w.validate(webhooks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CustomResourceDefinition returns the ValidatingWebhookPolicy CRD
+func CustomResourceDefinition() *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "CustomResourceDefinition",
+			APIVersion: apiextensionsv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: Plural + "." + Group,
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: Group,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural:   Plural,
+				Singular: "validatingwebhookpolicy",
+				Kind:     Kind,
+				ListKind: Kind + "List",
+			},
+			Scope: apiextensionsv1.ClusterScoped,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{
+					Name:    Version,
+					Served:  true,
+					Storage: true,
+					Subresources: &apiextensionsv1.CustomResourceSubresources{
+						Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+					},
+					AdditionalPrinterColumns: []apiextensionsv1.CustomResourceColumnDefinition{
+						{Name: "Accepted", Type: "string", JSONPath: `.status.conditions[?(@.type=="Accepted")].status`},
+						{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+					},
+					Schema: &apiextensionsv1.CustomResourceValidation{
+						OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+							Type: "object",
+							Properties: map[string]apiextensionsv1.JSONSchemaProps{
+								"apiVersion": {Type: "string"},
+								"kind":       {Type: "string"},
+								"metadata":   {Type: "object"},
+								"spec": {
+									Type: "object",
+									Properties: map[string]apiextensionsv1.JSONSchemaProps{
+										"sccPriorityCeiling": {Type: "integer", Format: "int32", Minimum: pointer.Float64(0), Maximum: pointer.Float64(float64(MaxSCCPriorityCeiling))},
+										"loadBalancerQuota":  {Type: "integer", Format: "int32", Minimum: pointer.Float64(0)},
+										"pdbPolicyMode":      {Type: "string", Enum: enum(string(PDBPolicyRelax), string(PDBPolicyWarn))},
+										"webhooks": {
+											Type:         "array",
+											XListType:    pointer.String("map"),
+											XListMapKeys: []string{"name"},
+											Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{
+												Type:     "object",
+												Required: []string{"name"},
+												Properties: map[string]apiextensionsv1.JSONSchemaProps{
+													"name":     {Type: "string", MinLength: pointer.Int64(1)},
+													"mode":     {Type: "string", Enum: enum(string(ModeEnforce), string(ModeAudit))},
+													"logLevel": {Type: "string", Enum: enum(string(LogLevelError), string(LogLevelInfo), string(LogLevelDebug))},
+												},
+											}},
+										},
+										"maintenanceWindows": {
+											Type:         "array",
+											XListType:    pointer.String("map"),
+											XListMapKeys: []string{"name"},
+											Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{
+												Type:     "object",
+												Required: []string{"name", "webhooks"},
+												Properties: map[string]apiextensionsv1.JSONSchemaProps{
+													"name": {Type: "string", MinLength: pointer.Int64(1)},
+													"webhooks": {
+														Type:     "array",
+														MinItems: pointer.Int64(1),
+														Items:    &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{Type: "string"}},
+													},
+													"schedule": {Type: "string"},
+													"duration": {Type: "string"},
+													"start":    {Type: "string", Format: "date-time"},
+													"end":      {Type: "string", Format: "date-time"},
+												},
+											}},
+										},
+									},
+								},
+								"status": {
+									Type: "object",
+									Properties: map[string]apiextensionsv1.JSONSchemaProps{
+										"observedGeneration": {Type: "integer", Format: "int64"},
+										"conditions": {
+											Type: "array",
+											Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{
+												Type:     "object",
+												Required: []string{"type", "status", "lastTransitionTime", "reason", "message"},
+												Properties: map[string]apiextensionsv1.JSONSchemaProps{
+													"type":               {Type: "string"},
+													"status":             {Type: "string"},
+													"observedGeneration": {Type: "integer", Format: "int64"},
+													"lastTransitionTime": {Type: "string", Format: "date-time"},
+													"reason":             {Type: "string"},
+													"message":            {Type: "string"},
+												},
+											}},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// enum returns the JSON values of an enum schema
+func enum(values ...string) []apiextensionsv1.JSON {
+	out := make([]apiextensionsv1.JSON, 0, len(values))
+	for _, v := range values {
+		out = append(out, apiextensionsv1.JSON{Raw: []byte(`"` + v + `"`)})
+	}
+	return out
+}
@@ -0,0 +1,124 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func mustTime(t *testing.T, value string) *metav1.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("Couldn't parse %s: %v", value, err)
+	}
+	return &metav1.Time{Time: parsed}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		testID string
+		spec   Spec
+		valid  bool
+	}{
+		{testID: "empty", spec: Spec{}, valid: true},
+		{testID: "every tunable", spec: Spec{
+			SCCPriorityCeiling: pointer.Int32(0),
+			LoadBalancerQuota:  pointer.Int32(2),
+			PDBPolicyMode:      PDBPolicyWarn,
+			Webhooks:           []WebhookPolicy{{Name: "scc-validation", Mode: ModeAudit, LogLevel: LogLevelDebug}},
+		}, valid: true},
+		{testID: "log level only", spec: Spec{Webhooks: []WebhookPolicy{{Name: "scc-validation", LogLevel: LogLevelError}}}, valid: true},
+		{testID: "ceiling above anyuid", spec: Spec{SCCPriorityCeiling: pointer.Int32(10)}},
+		{testID: "negative quota", spec: Spec{LoadBalancerQuota: pointer.Int32(-1)}},
+		{testID: "unknown pdb mode", spec: Spec{PDBPolicyMode: "Delete"}},
+		{testID: "unknown webhook", spec: Spec{Webhooks: []WebhookPolicy{{Name: "unknown-validation", Mode: ModeAudit}}}},
+		{testID: "unknown mode", spec: Spec{Webhooks: []WebhookPolicy{{Name: "scc-validation", Mode: "Off"}}}},
+		{testID: "unknown log level", spec: Spec{Webhooks: []WebhookPolicy{{Name: "scc-validation", LogLevel: "Trace"}}}},
+		{testID: "scheduled window", spec: Spec{MaintenanceWindows: []MaintenanceWindow{
+			{Name: "upgrades", Webhooks: []string{"pdbrelax-mutation"}, Schedule: "0 2 * * 6", Duration: &metav1.Duration{Duration: 4 * time.Hour}},
+		}}, valid: true},
+		{testID: "one-off window", spec: Spec{MaintenanceWindows: []MaintenanceWindow{
+			{Name: "ohss-1234", Webhooks: []string{"scc-validation"}, Start: mustTime(t, "2023-05-06T02:00:00Z"), End: mustTime(t, "2023-05-06T06:00:00Z")},
+		}}, valid: true},
+		{testID: "window without webhooks", spec: Spec{MaintenanceWindows: []MaintenanceWindow{
+			{Name: "upgrades", Schedule: "0 2 * * 6", Duration: &metav1.Duration{Duration: time.Hour}},
+		}}},
+		{testID: "window of unknown webhook", spec: Spec{MaintenanceWindows: []MaintenanceWindow{
+			{Name: "upgrades", Webhooks: []string{"unknown-validation"}, Schedule: "0 2 * * 6", Duration: &metav1.Duration{Duration: time.Hour}},
+		}}},
+		{testID: "window with invalid schedule", spec: Spec{MaintenanceWindows: []MaintenanceWindow{
+			{Name: "upgrades", Webhooks: []string{"scc-validation"}, Schedule: "0 25 * * *", Duration: &metav1.Duration{Duration: time.Hour}},
+		}}},
+		{testID: "scheduled window without duration", spec: Spec{MaintenanceWindows: []MaintenanceWindow{
+			{Name: "upgrades", Webhooks: []string{"scc-validation"}, Schedule: "0 2 * * 6"},
+		}}},
+		{testID: "window too long", spec: Spec{MaintenanceWindows: []MaintenanceWindow{
+			{Name: "upgrades", Webhooks: []string{"scc-validation"}, Schedule: "0 2 * * 6", Duration: &metav1.Duration{Duration: 24 * time.Hour}},
+		}}},
+		{testID: "window ending before it starts", spec: Spec{MaintenanceWindows: []MaintenanceWindow{
+			{Name: "ohss-1234", Webhooks: []string{"scc-validation"}, Start: mustTime(t, "2023-05-06T06:00:00Z"), End: mustTime(t, "2023-05-06T02:00:00Z")},
+		}}},
+		{testID: "window with schedule and start", spec: Spec{MaintenanceWindows: []MaintenanceWindow{
+			{Name: "upgrades", Webhooks: []string{"scc-validation"}, Schedule: "0 2 * * 6", Duration: &metav1.Duration{Duration: time.Hour}, Start: mustTime(t, "2023-05-06T02:00:00Z")},
+		}}},
+		{testID: "duplicate window", spec: Spec{MaintenanceWindows: []MaintenanceWindow{
+			{Name: "upgrades", Webhooks: []string{"scc-validation"}, Schedule: "0 2 * * 6", Duration: &metav1.Duration{Duration: time.Hour}},
+			{Name: "upgrades", Webhooks: []string{"pdbrelax-mutation"}, Schedule: "0 2 * * 0", Duration: &metav1.Duration{Duration: time.Hour}},
+		}}},
+		{testID: "duplicate webhook", spec: Spec{Webhooks: []WebhookPolicy{{Name: "scc-validation", Mode: ModeAudit}, {Name: "scc-validation", Mode: ModeEnforce}}}},
+	}
+	for _, test := range tests {
+		if err := test.spec.Validate(servedWebhooks); (err == nil) != test.valid {
+			t.Errorf("%s: Expected valid %v, got %v", test.testID, test.valid, err)
+		}
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	spec := Spec{}
+	if spec.SCCPriorityCeilingOr(9) != 9 || spec.PDBMode() != PDBPolicyRelax || spec.WebhookMode("scc-validation") != ModeEnforce || spec.WebhookLogLevel("scc-validation") != LogLevelInfo {
+		t.Fatalf("Expected the defaults of an empty spec, got %+v", spec)
+	}
+}
+
+func TestAuditSource(t *testing.T) {
+	spec := Spec{
+		Webhooks: []WebhookPolicy{{Name: "scc-validation", Mode: ModeAudit}},
+		MaintenanceWindows: []MaintenanceWindow{
+			{Name: "upgrades", Webhooks: []string{"pdbrelax-mutation"}, Schedule: "0 2 * * 6", Duration: &metav1.Duration{Duration: 4 * time.Hour}},
+			{Name: "ohss-1234", Webhooks: []string{"node-validation"}, Start: mustTime(t, "2023-05-06T05:00:00Z"), End: mustTime(t, "2023-05-06T05:30:00Z")},
+		},
+	}
+	tests := []struct {
+		webhook  string
+		at       string
+		expected string
+	}{
+		{webhook: "scc-validation", at: "2023-05-01T12:00:00Z", expected: Name},
+		{webhook: "pdbrelax-mutation", at: "2023-05-06T03:59:00Z", expected: "maintenance/upgrades"},
+		{webhook: "pdbrelax-mutation", at: "2023-05-06T06:00:00Z", expected: ""},
+		{webhook: "pdbrelax-mutation", at: "2023-05-07T03:00:00Z", expected: ""},
+		{webhook: "node-validation", at: "2023-05-06T05:10:00Z", expected: "maintenance/ohss-1234"},
+		{webhook: "node-validation", at: "2023-05-06T05:30:00Z", expected: ""},
+		{webhook: "namespace-validation", at: "2023-05-06T05:10:00Z", expected: ""},
+	}
+	for _, test := range tests {
+		if source := spec.AuditSource(test.webhook, mustTime(t, test.at).Time); source != test.expected {
+			t.Errorf("Expected %s to be audited by %q at %s, got %q", test.webhook, test.expected, test.at, source)
+		}
+	}
+}
+
+func TestCustomResourceDefinition(t *testing.T) {
+	crd := CustomResourceDefinition()
+	if crd.Name != Plural+"."+Group || crd.Spec.Versions[0].Subresources.Status == nil {
+		t.Fatalf("Expected a CRD with a status subresource, got %+v", crd)
+	}
+	spec := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+	if *spec.Properties["sccPriorityCeiling"].Maximum != float64(MaxSCCPriorityCeiling) || len(spec.Properties["pdbPolicyMode"].Enum) != 2 {
+		t.Fatalf("Expected the schema to validate the tunables, got %+v", spec)
+	}
+}
@@ -0,0 +1,98 @@
+// Package rbac merges the RBAC rules the components of the webhook server
+// need into the smallest set of rules granting them
+package rbac
+
+import (
+	"sort"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// resourceKey identifies the objects a rule grants verbs on
+type resourceKey struct {
+	group         string
+	resource      string
+	resourceNames string
+}
+
+// ruleKey identifies the rules whose resources can be merged into one rule
+type ruleKey struct {
+	group         string
+	verbs         string
+	resourceNames string
+}
+
+// Merge returns the rules granting what all of rules grant, with one rule for
+// each API group, set of verbs and set of resource names. The rules are
+// sorted so that the same permissions always render the same rules.
+func Merge(rules ...[]rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	verbs := map[resourceKey]sets.String{}
+	nonResource := map[string]sets.String{}
+	for _, list := range rules {
+		for _, rule := range list {
+			for _, url := range rule.NonResourceURLs {
+				if nonResource[url] == nil {
+					nonResource[url] = sets.NewString()
+				}
+				nonResource[url].Insert(rule.Verbs...)
+			}
+			names := strings.Join(sets.NewString(rule.ResourceNames...).List(), ",")
+			for _, group := range rule.APIGroups {
+				for _, resource := range rule.Resources {
+					key := resourceKey{group: group, resource: resource, resourceNames: names}
+					if verbs[key] == nil {
+						verbs[key] = sets.NewString()
+					}
+					verbs[key].Insert(rule.Verbs...)
+				}
+			}
+		}
+	}
+
+	resources := map[ruleKey]sets.String{}
+	for key, v := range verbs {
+		r := ruleKey{group: key.group, verbs: joinVerbs(v), resourceNames: key.resourceNames}
+		if resources[r] == nil {
+			resources[r] = sets.NewString()
+		}
+		resources[r].Insert(key.resource)
+	}
+	merged := make([]rbacv1.PolicyRule, 0, len(resources)+len(nonResource))
+	for key, r := range resources {
+		rule := rbacv1.PolicyRule{
+			APIGroups: []string{key.group},
+			Resources: r.List(),
+			Verbs:     strings.Split(key.verbs, ","),
+		}
+		if key.resourceNames != "" {
+			rule.ResourceNames = strings.Split(key.resourceNames, ",")
+		}
+		merged = append(merged, rule)
+	}
+	urls := sets.StringKeySet(nonResource).List()
+	for _, url := range urls {
+		merged = append(merged, rbacv1.PolicyRule{NonResourceURLs: []string{url}, Verbs: strings.Split(joinVerbs(nonResource[url]), ",")})
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return ruleSortKey(merged[i]) < ruleSortKey(merged[j])
+	})
+	return merged
+}
+
+// joinVerbs joins the sorted verbs, or only the wildcard if they include it
+func joinVerbs(verbs sets.String) string {
+	if verbs.Has(rbacv1.VerbAll) {
+		return rbacv1.VerbAll
+	}
+	return strings.Join(verbs.List(), ",")
+}
+
+func ruleSortKey(rule rbacv1.PolicyRule) string {
+	if len(rule.NonResourceURLs) > 0 {
+		// After the resource rules
+		return "\xff" + rule.NonResourceURLs[0]
+	}
+	return strings.Join([]string{rule.APIGroups[0], strings.Join(rule.Resources, ","), strings.Join(rule.ResourceNames, ",")}, "\x00")
+}
@@ -0,0 +1,41 @@
+package rbac
+
+import (
+	"reflect"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestMerge(t *testing.T) {
+	merged := Merge(
+		[]rbacv1.PolicyRule{
+			{APIGroups: []string{"config.openshift.io"}, Resources: []string{"proxies"}, Verbs: []string{"get"}},
+			{APIGroups: []string{""}, Resources: []string{"namespaces"}, Verbs: []string{"get"}},
+			{APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"webhook-summary"}, Verbs: []string{"update", "get"}},
+		},
+		[]rbacv1.PolicyRule{
+			{APIGroups: []string{"config.openshift.io"}, Resources: []string{"infrastructures", "proxies"}, Verbs: []string{"get"}},
+			{APIGroups: []string{""}, Resources: []string{"namespaces", "serviceaccounts"}, Verbs: []string{"list"}},
+			{APIGroups: []string{""}, Resources: []string{"services"}, Verbs: []string{"list", "*"}},
+			{NonResourceURLs: []string{"/metrics"}, Verbs: []string{"get"}},
+		},
+	)
+	expected := []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"webhook-summary"}, Verbs: []string{"get", "update"}},
+		{APIGroups: []string{""}, Resources: []string{"namespaces"}, Verbs: []string{"get", "list"}},
+		{APIGroups: []string{""}, Resources: []string{"serviceaccounts"}, Verbs: []string{"list"}},
+		{APIGroups: []string{""}, Resources: []string{"services"}, Verbs: []string{"*"}},
+		{APIGroups: []string{"config.openshift.io"}, Resources: []string{"infrastructures", "proxies"}, Verbs: []string{"get"}},
+		{NonResourceURLs: []string{"/metrics"}, Verbs: []string{"get"}},
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Fatalf("expected\n%v\ngot\n%v", expected, merged)
+	}
+}
+
+func TestMergeEmpty(t *testing.T) {
+	if merged := Merge(); len(merged) != 0 {
+		t.Fatalf("expected no rules, got %v", merged)
+	}
+}
@@ -0,0 +1,151 @@
+package selftest
+
+import (
+	"encoding/json"
+
+	securityv1 "github.com/openshift/api/security/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/namespace"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/scc"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/serviceaccount"
+)
+
+// selfTestUser makes the requests of every Case, so the hooks' own logs of
+// self-test denials can be told apart from real ones
+const selfTestUser string = "managed-webhook-selftest"
+
+// Case is a canned admission request and the decision a webhook must make
+type Case struct {
+	Name    string
+	Webhook string
+	Request admissionv1.AdmissionRequest
+	Allowed bool
+}
+
+// rawObject returns obj as the raw object of an admission request
+func rawObject(obj runtime.Object) runtime.RawExtension {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		panic(err)
+	}
+	return runtime.RawExtension{Raw: raw}
+}
+
+func request(kind metav1.GroupVersionKind, resource string, operation admissionv1.Operation, namespace, name string, obj runtime.Object, user string, groups ...string) admissionv1.AdmissionRequest {
+	req := admissionv1.AdmissionRequest{
+		UID:       "selftest",
+		Kind:      kind,
+		Resource:  metav1.GroupVersionResource{Group: kind.Group, Version: kind.Version, Resource: resource},
+		Operation: operation,
+		Namespace: namespace,
+		Name:      name,
+		UserInfo:  authenticationv1.UserInfo{Username: user, Groups: groups},
+	}
+	// Like the API server, creates only carry the new object, deletes only
+	// the old one and updates both
+	if operation == admissionv1.Create || operation == admissionv1.Update {
+		req.Object = rawObject(obj)
+	}
+	if operation == admissionv1.Update || operation == admissionv1.Delete {
+		req.OldObject = rawObject(obj)
+	}
+	return req
+}
+
+func sccRequest(operation admissionv1.Operation, name, user string) admissionv1.AdmissionRequest {
+	obj := &securityv1.SecurityContextConstraints{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "security.openshift.io/v1", Kind: "SecurityContextConstraints"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}
+	kind := metav1.GroupVersionKind{Group: "security.openshift.io", Version: "v1", Kind: "SecurityContextConstraints"}
+	return request(kind, "securitycontextconstraints", operation, "", name, obj, user, "system:authenticated")
+}
+
+func namespaceRequest(operation admissionv1.Operation, name, user string) admissionv1.AdmissionRequest {
+	obj := &corev1.Namespace{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}
+	kind := metav1.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+	return request(kind, "namespaces", operation, "", name, obj, user, "system:authenticated")
+}
+
+func serviceAccountRequest(operation admissionv1.Operation, ns, name, user string) admissionv1.AdmissionRequest {
+	obj := &corev1.ServiceAccount{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+	}
+	kind := metav1.GroupVersionKind{Version: "v1", Kind: "ServiceAccount"}
+	return request(kind, "serviceaccounts", operation, ns, name, obj, user, "system:authenticated")
+}
+
+// Cases is the library of canned requests. It only covers webhooks whose
+// decision depends on the request alone, so running it has no side effects
+// and gives the same result on every cluster.
+var Cases = []Case{
+	{
+		Name:    "deny-default-scc-update",
+		Webhook: scc.WebhookName,
+		Request: sccRequest(admissionv1.Update, "restricted-v2", selfTestUser),
+		Allowed: false,
+	},
+	{
+		Name:    "deny-default-scc-delete",
+		Webhook: scc.WebhookName,
+		Request: sccRequest(admissionv1.Delete, "privileged", selfTestUser),
+		Allowed: false,
+	},
+	{
+		Name:    "allow-custom-scc-update",
+		Webhook: scc.WebhookName,
+		Request: sccRequest(admissionv1.Update, "selftest-custom", selfTestUser),
+		Allowed: true,
+	},
+	{
+		Name:    "allow-admin-default-scc-update",
+		Webhook: scc.WebhookName,
+		Request: sccRequest(admissionv1.Update, "restricted-v2", "system:admin"),
+		Allowed: true,
+	},
+	{
+		Name:    "deny-managed-namespace-create",
+		Webhook: namespace.WebhookName,
+		Request: namespaceRequest(admissionv1.Create, "kube-selftest", selfTestUser),
+		Allowed: false,
+	},
+	{
+		Name:    "deny-harmful-namespace-create",
+		Webhook: namespace.WebhookName,
+		Request: namespaceRequest(admissionv1.Create, "com", selfTestUser),
+		Allowed: false,
+	},
+	{
+		Name:    "allow-customer-namespace-create",
+		Webhook: namespace.WebhookName,
+		Request: namespaceRequest(admissionv1.Create, "selftest-customer", selfTestUser),
+		Allowed: true,
+	},
+	{
+		Name:    "allow-admin-managed-namespace-create",
+		Webhook: namespace.WebhookName,
+		Request: namespaceRequest(admissionv1.Create, "kube-selftest", "backplane-cluster-admin"),
+		Allowed: true,
+	},
+	{
+		Name:    "deny-managed-serviceaccount-delete",
+		Webhook: serviceaccount.WebhookName,
+		Request: serviceAccountRequest(admissionv1.Delete, "openshift-monitoring", "selftest", selfTestUser),
+		Allowed: false,
+	},
+	{
+		Name:    "allow-customer-serviceaccount-delete",
+		Webhook: serviceaccount.WebhookName,
+		Request: serviceAccountRequest(admissionv1.Delete, "selftest-customer", "selftest", selfTestUser),
+		Allowed: true,
+	},
+}
@@ -0,0 +1,153 @@
+package selftest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+)
+
+const (
+	// Path is the URI which runs the self-test and returns its results
+	Path string = "/selftest"
+	// IntervalEnvVar is how often the self-test runs in the background, as a
+	// duration such as 15m. Setting it to 0 disables background runs.
+	IntervalEnvVar string = "SELFTEST_INTERVAL"
+
+	defaultInterval = 15 * time.Minute
+)
+
+var log = logf.Log.WithName("selftest")
+
+// Result is the outcome of one Case
+type Result struct {
+	Webhook  string `json:"webhook"`
+	Test     string `json:"test"`
+	Passed   bool   `json:"passed"`
+	Expected bool   `json:"expectedAllowed"`
+	Allowed  bool   `json:"allowed"`
+	Message  string `json:"message,omitempty"`
+}
+
+// Runner replays the Cases through the registered webhooks and records
+// whether each made the expected decision
+type Runner struct {
+	mu    sync.Mutex
+	hooks webhooks.RegisteredWebhooks
+	cases []Case
+}
+
+// NewRunner creates a Runner for the Cases of hooks
+func NewRunner(hooks webhooks.RegisteredWebhooks) *Runner {
+	return &Runner{
+		hooks: hooks,
+		cases: Cases,
+	}
+}
+
+// IntervalFromEnv returns the background run interval set by IntervalEnvVar
+func IntervalFromEnv() (time.Duration, error) {
+	value := os.Getenv(IntervalEnvVar)
+	if value == "" {
+		return defaultInterval, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		return 0, fmt.Errorf("invalid %s %q, it must be a non-negative duration", IntervalEnvVar, value)
+	}
+	return interval, nil
+}
+
+// Start runs the self-test now and then every interval in the background
+func (r *Runner) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			r.Run()
+			<-ticker.C
+		}
+	}()
+}
+
+// Run replays every Case whose webhook is registered, records the results in
+// localmetrics and logs failures
+func (r *Runner) Run() []Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	results := []Result{}
+	for _, c := range r.cases {
+		factory, ok := r.hooks[c.Webhook]
+		if !ok {
+			continue
+		}
+		result := run(factory(), c)
+		localmetrics.SetSelfTestResult(result.Webhook, result.Test, result.Passed)
+		if !result.Passed {
+			log.Info("Self-test failed", "webhook", result.Webhook, "test", result.Test, "expectedAllowed", result.Expected, "allowed", result.Allowed, "message", result.Message)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// run sends the request of c through hook the way the dispatcher does,
+// without recording it as a real request
+func run(hook webhooks.Webhook, c Case) Result {
+	result := Result{
+		Webhook:  c.Webhook,
+		Test:     c.Name,
+		Expected: c.Allowed,
+	}
+	request := admissionctl.Request{AdmissionRequest: c.Request}
+	if !hook.Validate(request) {
+		result.Message = "request rejected by Validate"
+		return result
+	}
+	resp := hook.Authorized(request)
+	result.Allowed = resp.Allowed
+	if resp.Result != nil {
+		result.Message = resp.Result.Message
+		if result.Message == "" {
+			result.Message = string(resp.Result.Reason)
+		}
+	}
+	if c.Allowed {
+		result.Passed = resp.Allowed
+	} else {
+		// An errored response isn't the denial the case expects
+		result.Passed = localmetrics.IsDenied(resp)
+	}
+	return result
+}
+
+// ServeHTTP runs the self-test and returns its results, with a 500 status
+// if any case failed
+func (r *Runner) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	results := r.Run()
+	status := http.StatusOK
+	for _, result := range results {
+		if !result.Passed {
+			status = http.StatusInternalServerError
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(results); err != nil {
+		log.Error(err, "Failed to encode self-test results")
+	}
+}
@@ -0,0 +1,83 @@
+package selftest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/scc"
+)
+
+// TestCasesPass guards the library itself: every canned request must get the
+// expected decision from the webhooks as built
+func TestCasesPass(t *testing.T) {
+	results := NewRunner(webhooks.Webhooks).Run()
+	if len(results) != len(Cases) {
+		t.Fatalf("Expected every case's webhook to be registered, ran %d of %d cases", len(results), len(Cases))
+	}
+	for _, result := range results {
+		if !result.Passed {
+			t.Fatalf("Expected %s/%s to pass, got %+v", result.Webhook, result.Test, result)
+		}
+		if got := testutil.ToFloat64(localmetrics.MetricSelfTestPassed.WithLabelValues(result.Webhook, result.Test)); got != 1 {
+			t.Fatalf("Expected %s/%s to be recorded as passed, got %g", result.Webhook, result.Test, got)
+		}
+	}
+}
+
+// allowAll stands in for a regressed scc webhook which no longer denies
+type allowAll struct {
+	webhooks.Webhook
+}
+
+func (allowAll) Authorized(request admissionctl.Request) admissionctl.Response {
+	return admissionctl.Allowed("")
+}
+
+func TestRegressionFails(t *testing.T) {
+	runner := NewRunner(webhooks.RegisteredWebhooks{
+		scc.WebhookName: func() webhooks.Webhook { return allowAll{scc.NewWebhook()} },
+	})
+
+	rec := httptest.NewRecorder()
+	runner.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected a failing self-test to return %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+	results := []Result{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatalf("Expected JSON results, got %s: %v", rec.Body.String(), err)
+	}
+	failed := 0
+	for _, result := range results {
+		if result.Webhook != scc.WebhookName {
+			t.Fatalf("Expected only scc cases to run, got %+v", result)
+		}
+		if !result.Passed {
+			failed++
+			if got := testutil.ToFloat64(localmetrics.MetricSelfTestPassed.WithLabelValues(result.Webhook, result.Test)); got != 0 {
+				t.Fatalf("Expected %s to be recorded as failed, got %g", result.Test, got)
+			}
+		}
+	}
+	if failed != 2 {
+		t.Fatalf("Expected the 2 scc denial cases to fail, got %d failures in %+v", failed, results)
+	}
+}
+
+func TestIntervalFromEnv(t *testing.T) {
+	t.Setenv(IntervalEnvVar, "")
+	if interval, err := IntervalFromEnv(); err != nil || interval != defaultInterval {
+		t.Fatalf("Expected the default interval, got %v, %v", interval, err)
+	}
+	t.Setenv(IntervalEnvVar, "-1m")
+	if _, err := IntervalFromEnv(); err == nil {
+		t.Fatalf("Expected a negative %s to be an error", IntervalEnvVar)
+	}
+}
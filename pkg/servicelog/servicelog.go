@@ -0,0 +1,336 @@
+package servicelog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/k8sutil"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	// ClientIDEnvVar and ClientSecretEnvVar are the OCM service account
+	// credentials used to post service logs. The notifier is disabled unless
+	// both are set.
+	ClientIDEnvVar     string = "SERVICE_LOG_CLIENT_ID"
+	ClientSecretEnvVar string = "SERVICE_LOG_CLIENT_SECRET"
+	// OCMURLEnvVar overrides the OCM API URL
+	OCMURLEnvVar string = "SERVICE_LOG_OCM_URL"
+	// TokenURLEnvVar overrides the SSO token endpoint the credentials are
+	// exchanged at
+	TokenURLEnvVar string = "SERVICE_LOG_TOKEN_URL"
+	// ThresholdEnvVar is how many times a user must hit the same denial
+	// within a window before a service log is posted
+	ThresholdEnvVar string = "SERVICE_LOG_THRESHOLD"
+	// DocURLEnvVar overrides the documentation linked from service logs
+	DocURLEnvVar string = "SERVICE_LOG_DOC_URL"
+
+	defaultOCMURL    = "https://api.openshift.com"
+	defaultTokenURL  = "https://sso.redhat.com/auth/realms/redhat-external/protocol/openid-connect/token"
+	defaultDocURL    = "https://github.com/openshift/managed-cluster-validating-webhooks#denial-reason-codes"
+	defaultThreshold = 5
+	clusterLogsPath  = "/api/service_logs/v1/cluster_logs"
+	serviceName      = "ManagedClusterValidatingWebhooks"
+
+	// window is how long repeated denials are counted for
+	window = time.Hour
+	// cooldown is how long after a service log the same denial of the same
+	// user isn't notified again
+	cooldown = 24 * time.Hour
+	// maxTracked bounds how many distinct denials are counted. Denials seen
+	// after the limit is reached aren't notified.
+	maxTracked  = 1000
+	queueSize   = 16
+	sendTimeout = 10 * time.Second
+	// tokenExpiryMargin renews the access token before it expires
+	tokenExpiryMargin = 30 * time.Second
+)
+
+var log = logf.Log.WithName("servicelog")
+
+// denialKey identifies the same denial hit by the same user
+type denialKey struct {
+	webhook string
+	code    utils.ReasonCode
+	user    string
+}
+
+type denialCount struct {
+	count        int
+	windowStart  time.Time
+	lastNotified time.Time
+}
+
+// clusterLog is the OCM service log entry
+type clusterLog struct {
+	ClusterUUID   string   `json:"cluster_uuid"`
+	ServiceName   string   `json:"service_name"`
+	Severity      string   `json:"severity"`
+	LogType       string   `json:"log_type"`
+	Summary       string   `json:"summary"`
+	Description   string   `json:"description"`
+	DocReferences []string `json:"doc_references,omitempty"`
+	InternalOnly  bool     `json:"internal_only"`
+}
+
+// Notifier posts an OCM service log when a user repeatedly hits the same
+// denial, so guardrails silently failing GitOps pipelines become visible to
+// the customer
+type Notifier struct {
+	mu        sync.Mutex
+	denials   map[denialKey]*denialCount
+	threshold int
+	docs      map[string]string
+	docURL    string
+	now       func() time.Time
+	logs      chan clusterLog
+
+	ocmURL       string
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+	token        string
+	tokenExpiry  time.Time
+}
+
+// NewNotifierFromEnv creates and starts the Notifier configured by the
+// environment, or returns nil if it isn't configured. hooks provide the
+// guardrail explanation included in each service log.
+func NewNotifierFromEnv(hooks webhooks.RegisteredWebhooks) (*Notifier, error) {
+	clientID, clientSecret := os.Getenv(ClientIDEnvVar), os.Getenv(ClientSecretEnvVar)
+	if clientID == "" && clientSecret == "" {
+		return nil, nil
+	}
+	if clientID == "" || clientSecret == "" {
+		return nil, fmt.Errorf("both %s and %s must be set", ClientIDEnvVar, ClientSecretEnvVar)
+	}
+	ocmURL, err := envURL(OCMURLEnvVar, defaultOCMURL)
+	if err != nil {
+		return nil, err
+	}
+	tokenURL, err := envURL(TokenURLEnvVar, defaultTokenURL)
+	if err != nil {
+		return nil, err
+	}
+	threshold := defaultThreshold
+	if value := os.Getenv(ThresholdEnvVar); value != "" {
+		threshold, err = strconv.Atoi(value)
+		if err != nil || threshold < 1 {
+			return nil, fmt.Errorf("invalid %s %q, it must be a positive integer", ThresholdEnvVar, value)
+		}
+	}
+	docURL := os.Getenv(DocURLEnvVar)
+	if docURL == "" {
+		docURL = defaultDocURL
+	}
+
+	n := newNotifier(hooks, threshold, docURL)
+	n.ocmURL = ocmURL
+	n.tokenURL = tokenURL
+	n.clientID = clientID
+	n.clientSecret = clientSecret
+	go n.run()
+	return n, nil
+}
+
+func newNotifier(hooks webhooks.RegisteredWebhooks, threshold int, docURL string) *Notifier {
+	docs := map[string]string{}
+	for name, factory := range hooks {
+		docs[name] = factory().Doc()
+	}
+	return &Notifier{
+		denials:    map[denialKey]*denialCount{},
+		threshold:  threshold,
+		docs:       docs,
+		docURL:     docURL,
+		now:        time.Now,
+		logs:       make(chan clusterLog, queueSize),
+		httpClient: &http.Client{},
+	}
+}
+
+// envURL returns the https URL set by envVar, or def
+func envURL(envVar, def string) (string, error) {
+	value := os.Getenv(envVar)
+	if value == "" {
+		return def, nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("%s must be an https URL, got %q", envVar, value)
+	}
+	return strings.TrimSuffix(value, "/"), nil
+}
+
+// RecordDenial implements events.Recorder. The requests of webhooks in audit
+// mode were allowed, so customers aren't notified of them.
+func (n *Notifier) RecordDenial(webhook string, request admissionctl.Request, resp admissionctl.Response) {
+	if utils.AuditSource(resp) != "" {
+		return
+	}
+	code, reason := utils.DenialReason(resp)
+	key := denialKey{webhook: webhook, code: code, user: request.UserInfo.Username}
+	if !n.count(key) {
+		return
+	}
+	entry := n.buildLog(webhook, request, code, reason)
+	select {
+	case n.logs <- entry:
+	default:
+		localmetrics.IncrementDroppedSideEffect(localmetrics.SideEffectServiceLog)
+		log.Info("Service log queue is full, dropping notification", "webhook", webhook, "user", key.user)
+	}
+}
+
+// count records a denial and returns true when it reaches the threshold
+// within the window and hasn't been notified within the cooldown
+func (n *Notifier) count(key denialKey) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	now := n.now()
+	c, ok := n.denials[key]
+	if !ok {
+		if len(n.denials) >= maxTracked {
+			n.prune(now)
+			if len(n.denials) >= maxTracked {
+				return false
+			}
+		}
+		c = &denialCount{windowStart: now}
+		n.denials[key] = c
+	}
+	if now.Sub(c.windowStart) > window {
+		c.count = 0
+		c.windowStart = now
+	}
+	c.count++
+	if c.count < n.threshold || (!c.lastNotified.IsZero() && now.Sub(c.lastNotified) < cooldown) {
+		return false
+	}
+	c.lastNotified = now
+	c.count = 0
+	return true
+}
+
+// prune forgets denials which are outside both their window and cooldown
+func (n *Notifier) prune(now time.Time) {
+	for key, c := range n.denials {
+		if now.Sub(c.windowStart) > window && now.Sub(c.lastNotified) > cooldown {
+			delete(n.denials, key)
+		}
+	}
+}
+
+func (n *Notifier) buildLog(webhook string, request admissionctl.Request, code utils.ReasonCode, reason string) clusterLog {
+	denial := webhook
+	if code != "" {
+		denial = fmt.Sprintf("%s (%s)", webhook, code)
+	}
+	description := fmt.Sprintf("%s has been denied %s of %s at least %d times in the last hour by the %s guardrail: %s",
+		request.UserInfo.Username, strings.ToLower(string(request.Operation)), request.Kind.Kind, n.threshold, denial, reason)
+	if doc := n.docs[webhook]; doc != "" {
+		description += " " + doc
+	}
+	return clusterLog{
+		ClusterUUID:   k8sutil.ClusterID(),
+		ServiceName:   serviceName,
+		Severity:      "Warning",
+		LogType:       "cluster-configuration",
+		Summary:       "Requests are repeatedly denied by a Managed OpenShift guardrail",
+		Description:   description,
+		DocReferences: []string{n.docURL},
+		InternalOnly:  false,
+	}
+}
+
+func (n *Notifier) run() {
+	for entry := range n.logs {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		if err := n.send(ctx, entry); err != nil {
+			log.Error(err, "Failed to post service log")
+		}
+		cancel()
+	}
+}
+
+func (n *Notifier) send(ctx context.Context, entry clusterLog) error {
+	if entry.ClusterUUID == "" {
+		return fmt.Errorf("the cluster ID is unknown")
+	}
+	token, err := n.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.ocmURL+clusterLogsPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return n.do(req, nil)
+}
+
+// accessToken returns a cached access token, or exchanges the client
+// credentials for a new one
+func (n *Notifier) accessToken(ctx context.Context) (string, error) {
+	if n.token != "" && n.now().Before(n.tokenExpiry) {
+		return n.token, nil
+	}
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {n.clientID},
+		"client_secret": {n.clientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	token := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}{}
+	if err := n.do(req, &token); err != nil {
+		return "", fmt.Errorf("failed to get an OCM access token: %v", err)
+	}
+	n.token = token.AccessToken
+	n.tokenExpiry = n.now().Add(time.Duration(token.ExpiresIn)*time.Second - tokenExpiryMargin)
+	return n.token, nil
+}
+
+// do sends req and decodes the response into out, if set
+func (n *Notifier) do(req *http.Request, out interface{}) error {
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %d: %s", req.URL.Path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,175 @@
+package servicelog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/k8sutil"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/scc"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+func sccDenial(user string) (admissionctl.Request, admissionctl.Response) {
+	request := admissionctl.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Update,
+			Kind:      metav1.GroupVersionKind{Group: "security.openshift.io", Version: "v1", Kind: "SecurityContextConstraints"},
+			UserInfo:  authenticationv1.UserInfo{Username: user},
+		},
+	}
+	return request, utils.Denied(utils.ReasonSCCDefaultModify, "Modifying default SCCs is not allowed")
+}
+
+func TestCountThresholdAndCooldown(t *testing.T) {
+	n := newNotifier(webhooks.RegisteredWebhooks{}, 3, defaultDocURL)
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	n.now = func() time.Time { return now }
+	key := denialKey{webhook: scc.WebhookName, code: utils.ReasonSCCDefaultModify, user: "alice"}
+
+	for i := 1; i <= 3; i++ {
+		if notify := n.count(key); notify != (i == 3) {
+			t.Fatalf("Denial %d: expected notify=%v, got %v", i, i == 3, notify)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if n.count(key) {
+			t.Fatalf("Expected no notification within the cooldown")
+		}
+	}
+	if n.count(denialKey{webhook: scc.WebhookName, code: utils.ReasonSCCDefaultModify, user: "bob"}) {
+		t.Fatalf("Expected other users to be counted separately")
+	}
+
+	now = now.Add(cooldown + time.Minute)
+	for i := 1; i <= 3; i++ {
+		if notify := n.count(key); notify != (i == 3) {
+			t.Fatalf("Denial %d after the cooldown: expected notify=%v, got %v", i, i == 3, notify)
+		}
+	}
+}
+
+func TestCountResetsAfterWindow(t *testing.T) {
+	n := newNotifier(webhooks.RegisteredWebhooks{}, 2, defaultDocURL)
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	n.now = func() time.Time { return now }
+	key := denialKey{webhook: scc.WebhookName, user: "alice"}
+
+	n.count(key)
+	now = now.Add(window + time.Minute)
+	if n.count(key) {
+		t.Fatalf("Expected denials outside the window not to add up")
+	}
+}
+
+func TestRecordDenialIgnoresAudited(t *testing.T) {
+	n := newNotifier(webhooks.RegisteredWebhooks{}, 1, defaultDocURL)
+	request, resp := sccDenial("alice")
+	n.RecordDenial(scc.WebhookName, request, utils.WithAuditSource(resp, "enforcement-mode"))
+	if len(n.logs) != 0 || len(n.denials) != 0 {
+		t.Fatalf("Expected a request allowed in audit mode not to be notified, got %d logs", len(n.logs))
+	}
+	n.RecordDenial(scc.WebhookName, request, resp)
+	if len(n.logs) != 1 {
+		t.Fatalf("Expected the denial to be notified, got %d logs", len(n.logs))
+	}
+}
+
+func TestSend(t *testing.T) {
+	t.Setenv(k8sutil.ClusterIDEnvVar, "2c1d9a7e-5f0b-4a53-9c43-3d1bba1e0d6f")
+	if _, err := k8sutil.LoadClusterID(context.Background(), nil); err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+
+	tokenRequests := 0
+	received := []clusterLog{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			tokenRequests++
+			if err := r.ParseForm(); err != nil || r.Form.Get("client_secret") != "s3cr3t" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"access","expires_in":300}`))
+		case clusterLogsPath:
+			if r.Header.Get("Authorization") != "Bearer access" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			entry := clusterLog{}
+			_ = json.NewDecoder(r.Body).Decode(&entry)
+			received = append(received, entry)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	hooks := webhooks.RegisteredWebhooks{
+		scc.WebhookName: func() webhooks.Webhook { return scc.NewWebhook() },
+	}
+	n := newNotifier(hooks, 2, defaultDocURL)
+	n.ocmURL = server.URL
+	n.tokenURL = server.URL + "/token"
+	n.clientID = "client"
+	n.clientSecret = "s3cr3t"
+	n.httpClient = server.Client()
+
+	for i := 0; i < 2; i++ {
+		request, resp := sccDenial("alice")
+		n.RecordDenial(scc.WebhookName, request, resp)
+	}
+	if len(n.logs) != 1 {
+		t.Fatalf("Expected 1 queued service log, got %d", len(n.logs))
+	}
+	entry := <-n.logs
+	for i := 0; i < 2; i++ {
+		if err := n.send(context.Background(), entry); err != nil {
+			t.Fatalf("Expected no error, got %s", err.Error())
+		}
+	}
+	if tokenRequests != 1 {
+		t.Fatalf("Expected the access token to be cached, got %d token requests", tokenRequests)
+	}
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 service logs, got %d", len(received))
+	}
+	got := received[0]
+	if got.ClusterUUID != "2c1d9a7e-5f0b-4a53-9c43-3d1bba1e0d6f" || got.InternalOnly || len(got.DocReferences) != 1 {
+		t.Fatalf("Unexpected service log %+v", got)
+	}
+	for _, want := range []string{"alice", string(utils.ReasonSCCDefaultModify), "Modifying default SCCs is not allowed", scc.NewWebhook().Doc()} {
+		if !strings.Contains(got.Description, want) {
+			t.Fatalf("Expected the description to contain %q, got %q", want, got.Description)
+		}
+	}
+}
+
+func TestNewNotifierFromEnv(t *testing.T) {
+	t.Setenv(ClientIDEnvVar, "")
+	t.Setenv(ClientSecretEnvVar, "")
+	if n, err := NewNotifierFromEnv(webhooks.RegisteredWebhooks{}); n != nil || err != nil {
+		t.Fatalf("Expected the notifier to be disabled, got %v, %v", n, err)
+	}
+	t.Setenv(ClientIDEnvVar, "client")
+	if _, err := NewNotifierFromEnv(webhooks.RegisteredWebhooks{}); err == nil {
+		t.Fatalf("Expected an error without %s", ClientSecretEnvVar)
+	}
+	t.Setenv(ClientSecretEnvVar, "s3cr3t")
+	t.Setenv(OCMURLEnvVar, "http://api.openshift.com")
+	if _, err := NewNotifierFromEnv(webhooks.RegisteredWebhooks{}); err == nil {
+		t.Fatalf("Expected an error for a non-https %s", OCMURLEnvVar)
+	}
+}
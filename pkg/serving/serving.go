@@ -0,0 +1,138 @@
+// Package serving tunes the TLS and HTTP/2 serving of the webhooks. The API
+// servers open many short-lived connections to the webhooks, so on busy
+// clusters the TLS handshakes dominate the latency of the webhooks.
+package serving
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"golang.org/x/net/http2"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// minReadFrameSize and maxReadFrameSize bound the HTTP/2 frame sizes
+	// a server may advertise
+	minReadFrameSize = 16 << 10
+	maxReadFrameSize = 1<<24 - 1
+	// ticketKeyReloadInterval is how often the session ticket keys are
+	// read again, e.g. once their Secret rotated them
+	ticketKeyReloadInterval = time.Minute
+)
+
+var log = logf.Log.WithName("serving")
+
+// Options tune the serving of the webhooks. The zero Options keep the
+// defaults of Go.
+type Options struct {
+	// DisableSessionTickets stops clients from resuming TLS sessions
+	DisableSessionTickets bool
+	// SessionTicketKeyFile is a file of base64-encoded 32 byte keys, one per
+	// line, encrypting the TLS session tickets. The first key encrypts new
+	// tickets, every key decrypts them. Sharing the keys between the webhook
+	// pods lets the API servers resume sessions with any of them, and across
+	// restarts. Go generates and rotates keys of each pod without it.
+	SessionTicketKeyFile string
+	// MaxConcurrentStreams is the number of HTTP/2 streams of each connection,
+	// 250 if zero
+	MaxConcurrentStreams uint32
+	// MaxReadFrameSize is the largest HTTP/2 frame the webhooks read, 1MiB if
+	// zero
+	MaxReadFrameSize uint32
+}
+
+// Validate returns an error if o can't be served
+func (o Options) Validate() error {
+	if o.DisableSessionTickets && o.SessionTicketKeyFile != "" {
+		return fmt.Errorf("session ticket keys can't be set with session tickets disabled")
+	}
+	if o.MaxReadFrameSize != 0 && (o.MaxReadFrameSize < minReadFrameSize || o.MaxReadFrameSize > maxReadFrameSize) {
+		return fmt.Errorf("the HTTP/2 max read frame size must be between %d and %d, got %d", minReadFrameSize, maxReadFrameSize, o.MaxReadFrameSize)
+	}
+	return nil
+}
+
+// Configure applies o to server, whose TLSConfig must be set. With a
+// SessionTicketKeyFile, the keys are read again in the background so that
+// their rotation doesn't need a restart.
+func (o Options) Configure(server *http.Server) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	if server.TLSConfig == nil {
+		return fmt.Errorf("the server has no TLS configuration")
+	}
+	server.TLSConfig.SessionTicketsDisabled = o.DisableSessionTickets
+	if o.SessionTicketKeyFile != "" {
+		keys, err := readSessionTicketKeys(o.SessionTicketKeyFile)
+		if err != nil {
+			return err
+		}
+		server.TLSConfig.SetSessionTicketKeys(keys)
+		go reloadSessionTicketKeys(server.TLSConfig, o.SessionTicketKeyFile, keys)
+	}
+	// Go serves HTTP/2 with its defaults unless they're tuned
+	if o.MaxConcurrentStreams == 0 && o.MaxReadFrameSize == 0 {
+		return nil
+	}
+	return http2.ConfigureServer(server, &http2.Server{
+		MaxConcurrentStreams: o.MaxConcurrentStreams,
+		MaxReadFrameSize:     o.MaxReadFrameSize,
+	})
+}
+
+// reloadSessionTicketKeys sets the keys of path on config whenever they change
+func reloadSessionTicketKeys(config *tls.Config, path string, keys [][32]byte) {
+	for range time.Tick(ticketKeyReloadInterval) {
+		reloaded, err := readSessionTicketKeys(path)
+		if err != nil {
+			log.Error(err, "Failed to reload the TLS session ticket keys, keeping the previous ones", "path", path)
+			continue
+		}
+		if slices.Equal(keys, reloaded) {
+			continue
+		}
+		config.SetSessionTicketKeys(reloaded)
+		keys = reloaded
+		log.Info("Reloaded the TLS session ticket keys", "path", path, "keys", len(keys))
+	}
+}
+
+// readSessionTicketKeys reads a file of base64-encoded 32 byte keys, one per
+// line
+func readSessionTicketKeys(path string) ([][32]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys [][32]byte
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(string(text))
+		if err != nil {
+			return nil, fmt.Errorf("line %d of the session ticket keys %s: %w", line, path, err)
+		}
+		if len(decoded) != 32 {
+			return nil, fmt.Errorf("line %d of the session ticket keys %s: expected a 32 byte key, got %d bytes", line, path, len(decoded))
+		}
+		var key [32]byte
+		copy(key[:], decoded)
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("the session ticket keys %s have no key", path)
+	}
+	return keys, nil
+}
@@ -0,0 +1,94 @@
+package serving
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeKeys(t *testing.T, lines ...string) string {
+	path := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		err     bool
+	}{
+		{name: "defaults"},
+		{name: "tuned", options: Options{SessionTicketKeyFile: "keys", MaxConcurrentStreams: 1000, MaxReadFrameSize: 1 << 20}},
+		{name: "keys without tickets", options: Options{DisableSessionTickets: true, SessionTicketKeyFile: "keys"}, err: true},
+		{name: "small frames", options: Options{MaxReadFrameSize: 1024}, err: true},
+		{name: "large frames", options: Options{MaxReadFrameSize: 1 << 24}, err: true},
+	}
+	for _, test := range tests {
+		if err := test.options.Validate(); (err != nil) != test.err {
+			t.Errorf("%s: expected an error %v, got %v", test.name, test.err, err)
+		}
+	}
+}
+
+func TestReadSessionTicketKeys(t *testing.T) {
+	first := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	second := base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210"))
+	keys, err := readSessionTicketKeys(writeKeys(t, first, "", "  "+second+"  ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || string(keys[0][:]) != "0123456789abcdef0123456789abcdef" {
+		t.Fatalf("expected the two keys in order, got %q", keys)
+	}
+	for name, lines := range map[string][]string{
+		"empty":    {""},
+		"short":    {base64.StdEncoding.EncodeToString([]byte("short"))},
+		"not b64":  {"not base64!"},
+		"one good": {first, "!"},
+	} {
+		if _, err := readSessionTicketKeys(writeKeys(t, lines...)); err == nil {
+			t.Errorf("%s: expected the keys not to be read", name)
+		}
+	}
+	if _, err := readSessionTicketKeys(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected a missing file not to be read")
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	server := &http.Server{TLSConfig: &tls.Config{}}
+	if err := (Options{}).Configure(server); err != nil {
+		t.Fatal(err)
+	}
+	if server.TLSNextProto != nil || len(server.TLSConfig.NextProtos) != 0 {
+		t.Fatal("expected the defaults of Go to serve HTTP/2 without tuning")
+	}
+
+	server = &http.Server{TLSConfig: &tls.Config{}}
+	if err := (Options{DisableSessionTickets: true, MaxConcurrentStreams: 1000}).Configure(server); err != nil {
+		t.Fatal(err)
+	}
+	if !server.TLSConfig.SessionTicketsDisabled {
+		t.Error("expected session tickets to be disabled")
+	}
+	if !slices.Contains(server.TLSConfig.NextProtos, "h2") {
+		t.Errorf("expected HTTP/2 to be negotiated, got %v", server.TLSConfig.NextProtos)
+	}
+
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	server = &http.Server{TLSConfig: &tls.Config{}}
+	if err := (Options{SessionTicketKeyFile: writeKeys(t, key)}).Configure(server); err != nil {
+		t.Fatal(err)
+	}
+	if err := (Options{}).Configure(&http.Server{}); err == nil {
+		t.Error("expected a server without TLS not to be configured")
+	}
+}
@@ -0,0 +1,86 @@
+// Package sizing holds the cluster sizes the manifests can be rendered for,
+// scaling the resources and replicas of the webhook pods and the timeouts of
+// the webhooks with the size of the cluster
+package sizing
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// maxTimeoutSeconds is the longest timeout the API server allows a webhook
+const maxTimeoutSeconds int32 = 30
+
+// Size is the sizing of the webhooks for clusters of a size. The zero Size
+// keeps the defaults.
+type Size struct {
+	Name string
+	// CPU and Memory are the requests of the webhook containers
+	CPU    string
+	Memory string
+	// Replicas is the number of webhook pods of the HyperShift deployment
+	Replicas int32
+	// MinTimeoutSeconds raises the shorter timeouts of the webhooks. The
+	// API servers of larger clusters are busier, so the lookups some
+	// webhooks make take longer.
+	MinTimeoutSeconds int32
+}
+
+// Sizes are the cluster sizes, from the smallest
+var Sizes = []Size{
+	{Name: "small", CPU: "20m", Memory: "64Mi", Replicas: 2},
+	{Name: "medium", CPU: "50m", Memory: "128Mi", Replicas: 2, MinTimeoutSeconds: 3},
+	// Clusters of up to 250 nodes
+	{Name: "large", CPU: "200m", Memory: "256Mi", Replicas: 3, MinTimeoutSeconds: 5},
+}
+
+// Names returns the names of the Sizes
+func Names() []string {
+	names := make([]string, 0, len(Sizes))
+	for _, s := range Sizes {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// Parse returns the Size named name, or the zero Size if name is empty
+func Parse(name string) (Size, error) {
+	if name == "" {
+		return Size{}, nil
+	}
+	for _, s := range Sizes {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return Size{}, fmt.Errorf("unknown cluster size %q, it must be one of %v", name, Names())
+}
+
+// Resources returns the resource requirements of the webhook containers,
+// none for the zero Size
+func (s Size) Resources() corev1.ResourceRequirements {
+	requests := corev1.ResourceList{}
+	if s.CPU != "" {
+		requests[corev1.ResourceCPU] = resource.MustParse(s.CPU)
+	}
+	if s.Memory != "" {
+		requests[corev1.ResourceMemory] = resource.MustParse(s.Memory)
+	}
+	if len(requests) == 0 {
+		return corev1.ResourceRequirements{}
+	}
+	return corev1.ResourceRequirements{Requests: requests}
+}
+
+// TimeoutSeconds returns the timeout of a webhook whose default is def
+func (s Size) TimeoutSeconds(def int32) int32 {
+	if def >= s.MinTimeoutSeconds {
+		return def
+	}
+	if s.MinTimeoutSeconds > maxTimeoutSeconds {
+		return maxTimeoutSeconds
+	}
+	return s.MinTimeoutSeconds
+}
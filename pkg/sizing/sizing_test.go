@@ -0,0 +1,57 @@
+package sizing
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParse(t *testing.T) {
+	for _, name := range Names() {
+		s, err := Parse(name)
+		if err != nil || s.Name != name {
+			t.Fatalf("expected the %s size, got %+v, %v", name, s, err)
+		}
+		// The quantities must parse
+		s.Resources()
+	}
+	if s, err := Parse(""); err != nil || s != (Size{}) {
+		t.Fatalf("expected the zero size without a name, got %+v, %v", s, err)
+	}
+	if _, err := Parse("huge"); err == nil {
+		t.Fatal("expected an unknown size to fail")
+	}
+}
+
+func TestResources(t *testing.T) {
+	if r := (Size{}).Resources(); r.Requests != nil || r.Limits != nil {
+		t.Fatalf("expected no requirements of the zero size, got %v", r)
+	}
+	large, _ := Parse("large")
+	r := large.Resources()
+	if cpu := r.Requests[corev1.ResourceCPU]; cpu.String() != "200m" {
+		t.Fatalf("expected a 200m CPU request, got %s", cpu.String())
+	}
+	if memory := r.Requests[corev1.ResourceMemory]; memory.String() != "256Mi" {
+		t.Fatalf("expected a 256Mi memory request, got %s", memory.String())
+	}
+}
+
+func TestTimeoutSeconds(t *testing.T) {
+	large, _ := Parse("large")
+	tests := []struct {
+		size     Size
+		def      int32
+		expected int32
+	}{
+		{Size{}, 2, 2},
+		{large, 2, 5},
+		{large, 10, 10},
+		{Size{MinTimeoutSeconds: 60}, 2, 30},
+	}
+	for _, test := range tests {
+		if got := test.size.TimeoutSeconds(test.def); got != test.expected {
+			t.Errorf("expected a timeout of %d for %+v and %d, got %d", test.expected, test.size, test.def, got)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/k8sutil"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	// IntervalEnvVar is how often the summary is published, as a duration
+	// such as 10m. Setting it to 0 disables the summary.
+	IntervalEnvVar string = "DENIAL_SUMMARY_INTERVAL"
+	// ConfigMapName is the ConfigMap the summary is published to, in the
+	// operator namespace. Each pod publishes its own key.
+	ConfigMapName string = "webhook-denial-summary"
+
+	defaultInterval = 10 * time.Minute
+	// maxUsers bounds how many distinct users are counted per webhook. Users
+	// seen after the limit is reached are counted under overflowUser.
+	maxUsers       = 50
+	overflowUser   = "other"
+	publishTimeout = 10 * time.Second
+	// staleIntervals is how many intervals a pod's key may go without being
+	// published before another pod removes it, e.g. after a rollout
+	staleIntervals = 3
+)
+
+var log = logf.Log.WithName("summary")
+
+// Summary is the denials seen by one pod since it started
+type Summary struct {
+	Pod      string                     `json:"pod"`
+	Since    time.Time                  `json:"since"`
+	Updated  time.Time                  `json:"updated"`
+	Webhooks map[string]*WebhookSummary `json:"webhooks"`
+}
+
+// WebhookSummary is the denials of one webhook, in total and by user, and
+// the requests it would have denied in audit mode
+type WebhookSummary struct {
+	Denied          int64            `json:"denied"`
+	WouldHaveDenied int64            `json:"wouldHaveDenied,omitempty"`
+	Users           map[string]int64 `json:"users"`
+}
+
+// Reporter counts denials and periodically publishes them to ConfigMapName,
+// giving an in-cluster view of guardrail activity without Prometheus
+type Reporter struct {
+	mu        sync.Mutex
+	summary   Summary
+	interval  time.Duration
+	namespace string
+	now       func() time.Time
+
+	once       sync.Once
+	kubeClient client.Client
+}
+
+// NewReporterFromEnv creates and starts a Reporter publishing every
+// IntervalEnvVar, or returns nil if the summary is disabled
+func NewReporterFromEnv() (*Reporter, error) {
+	interval := defaultInterval
+	if value := os.Getenv(IntervalEnvVar); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid %s %q, it must be a non-negative duration", IntervalEnvVar, value)
+		}
+		interval = parsed
+	}
+	if interval == 0 {
+		return nil, nil
+	}
+	pod, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the pod name: %v", err)
+	}
+	r := newReporter(pod, config.OperatorNamespace, interval)
+	go r.run()
+	return r, nil
+}
+
+func newReporter(pod, namespace string, interval time.Duration) *Reporter {
+	r := &Reporter{
+		interval:  interval,
+		namespace: namespace,
+		now:       time.Now,
+	}
+	r.summary = Summary{
+		Pod:      pod,
+		Since:    r.now().UTC(),
+		Webhooks: map[string]*WebhookSummary{},
+	}
+	return r
+}
+
+// RecordDenial implements events.Recorder
+func (r *Reporter) RecordDenial(webhook string, request admissionctl.Request, resp admissionctl.Response) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	hook, ok := r.summary.Webhooks[webhook]
+	if !ok {
+		hook = &WebhookSummary{Users: map[string]int64{}}
+		r.summary.Webhooks[webhook] = hook
+	}
+	if utils.AuditSource(resp) != "" {
+		hook.WouldHaveDenied++
+		return
+	}
+	hook.Denied++
+	user := request.UserInfo.Username
+	if _, tracked := hook.Users[user]; !tracked && len(hook.Users) >= maxUsers {
+		user = overflowUser
+	}
+	hook.Users[user]++
+}
+
+func (r *Reporter) run() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+		if err := r.publish(ctx); err != nil {
+			log.Error(err, "Failed to publish the denial summary", "configMap", ConfigMapName)
+		}
+		cancel()
+	}
+}
+
+// snapshot returns the JSON encoded summary, stamped with the current time
+func (r *Reporter) snapshot() (string, time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.Updated = r.now().UTC()
+	data, err := json.Marshal(r.summary)
+	return string(data), r.summary.Updated, err
+}
+
+func (r *Reporter) client() client.Client {
+	r.once.Do(func() {
+		if r.kubeClient != nil {
+			return
+		}
+		scheme := runtime.NewScheme()
+		if err := corev1.AddToScheme(scheme); err != nil {
+			log.Error(err, "Fail adding corev1 scheme to denial summary Reporter")
+			return
+		}
+		kubeClient, err := k8sutil.KubeClient(scheme)
+		if err != nil {
+			log.Error(err, "Fail creating KubeClient for denial summary Reporter")
+			return
+		}
+		r.kubeClient = kubeClient
+	})
+	return r.kubeClient
+}
+
+// publish writes this pod's summary to its key of the ConfigMap, and removes
+// the keys of pods which stopped publishing. A conflicting update by another
+// pod is left for the next interval.
+func (r *Reporter) publish(ctx context.Context) error {
+	kubeClient := r.client()
+	if kubeClient == nil {
+		return fmt.Errorf("no KubeClient")
+	}
+	data, updated, err := r.snapshot()
+	if err != nil {
+		return err
+	}
+
+	cm := &corev1.ConfigMap{}
+	err = kubeClient.Get(ctx, client.ObjectKey{Namespace: r.namespace, Name: ConfigMapName}, cm)
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: r.namespace,
+				Name:      ConfigMapName,
+			},
+			Data: map[string]string{r.summary.Pod: data},
+		}
+		return kubeClient.Create(ctx, cm)
+	}
+	if err != nil {
+		return err
+	}
+
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[r.summary.Pod] = data
+	staleBefore := updated.Add(-staleIntervals * r.interval)
+	for _, pod := range stalePods(cm.Data, staleBefore) {
+		delete(cm.Data, pod)
+	}
+	return kubeClient.Update(ctx, cm)
+}
+
+// stalePods returns the keys of data whose summary was last updated before
+// staleBefore, or which don't hold a summary
+func stalePods(data map[string]string, staleBefore time.Time) []string {
+	stale := []string{}
+	for pod, value := range data {
+		s := Summary{}
+		if err := json.Unmarshal([]byte(value), &s); err != nil || s.Updated.Before(staleBefore) {
+			stale = append(stale, pod)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
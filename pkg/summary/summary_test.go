@@ -0,0 +1,120 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+func deniedRequest(user string) admissionctl.Request {
+	return admissionctl.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			UserInfo: authenticationv1.UserInfo{Username: user},
+		},
+	}
+}
+
+func newTestReporter(pod string, kubeClient client.Client, now time.Time) *Reporter {
+	r := newReporter(pod, "openshift-validation-webhook", 10*time.Minute)
+	r.kubeClient = kubeClient
+	r.now = func() time.Time { return now }
+	return r
+}
+
+func publishedSummary(t *testing.T, kubeClient client.Client, pod string) (Summary, bool) {
+	cm := &corev1.ConfigMap{}
+	if err := kubeClient.Get(context.Background(), client.ObjectKey{Namespace: "openshift-validation-webhook", Name: ConfigMapName}, cm); err != nil {
+		t.Fatalf("Expected the summary ConfigMap, got %s", err.Error())
+	}
+	value, ok := cm.Data[pod]
+	if !ok {
+		return Summary{}, false
+	}
+	s := Summary{}
+	if err := json.Unmarshal([]byte(value), &s); err != nil {
+		t.Fatalf("Expected a JSON summary for %s, got %s: %v", pod, value, err)
+	}
+	return s, true
+}
+
+func TestRecordDenialLimitsUsers(t *testing.T) {
+	r := newReporter("validation-webhook-abcde", "openshift-validation-webhook", time.Minute)
+	for i := 0; i < maxUsers+2; i++ {
+		r.RecordDenial("scc-validation", deniedRequest(fmt.Sprintf("user-%d", i)), admissionctl.Response{})
+	}
+	r.RecordDenial("scc-validation", deniedRequest("user-0"), admissionctl.Response{})
+	hook := r.summary.Webhooks["scc-validation"]
+	if hook.Denied != maxUsers+3 {
+		t.Fatalf("Expected %d denials, got %d", maxUsers+3, hook.Denied)
+	}
+	if len(hook.Users) != maxUsers+1 || hook.Users[overflowUser] != 2 || hook.Users["user-0"] != 2 {
+		t.Fatalf("Expected %d users with 2 overflowing, got %v", maxUsers, hook.Users)
+	}
+}
+
+func TestRecordDenialCountsAudited(t *testing.T) {
+	r := newReporter("validation-webhook-abcde", "openshift-validation-webhook", time.Minute)
+	r.RecordDenial("scc-validation", deniedRequest("user-0"), admissionctl.Response{})
+	r.RecordDenial("scc-validation", deniedRequest("user-1"), utils.WithAuditSource(admissionctl.Response{}, "enforcement-mode"))
+	hook := r.summary.Webhooks["scc-validation"]
+	if hook.Denied != 1 || hook.WouldHaveDenied != 1 {
+		t.Fatalf("Expected 1 denial and 1 audited request, got %+v", hook)
+	}
+	if _, found := hook.Users["user-1"]; found {
+		t.Fatalf("Expected the users to count denials only, got %v", hook.Users)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	s := runtime.NewScheme()
+	_ = corev1.AddToScheme(s)
+	kubeClient := fake.NewClientBuilder().WithScheme(s).Build()
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	old := newTestReporter("validation-webhook-old", kubeClient, now.Add(-time.Hour))
+	if err := old.publish(context.Background()); err != nil {
+		t.Fatalf("Expected no error creating the ConfigMap, got %s", err.Error())
+	}
+
+	r := newTestReporter("validation-webhook-abcde", kubeClient, now)
+	r.RecordDenial("scc-validation", deniedRequest("alice"), admissionctl.Response{})
+	r.RecordDenial("scc-validation", deniedRequest("alice"), admissionctl.Response{})
+	r.RecordDenial("namespace-validation", deniedRequest("bob"), admissionctl.Response{})
+	if err := r.publish(context.Background()); err != nil {
+		t.Fatalf("Expected no error updating the ConfigMap, got %s", err.Error())
+	}
+
+	published, ok := publishedSummary(t, kubeClient, "validation-webhook-abcde")
+	if !ok {
+		t.Fatalf("Expected the pod's summary to be published")
+	}
+	if !published.Updated.Equal(now) || published.Webhooks["scc-validation"].Users["alice"] != 2 || published.Webhooks["namespace-validation"].Denied != 1 {
+		t.Fatalf("Unexpected summary %+v", published)
+	}
+	if _, ok := publishedSummary(t, kubeClient, "validation-webhook-old"); ok {
+		t.Fatalf("Expected the stale pod's summary to be removed")
+	}
+}
+
+func TestNewReporterFromEnv(t *testing.T) {
+	t.Setenv(IntervalEnvVar, "0")
+	if r, err := NewReporterFromEnv(); r != nil || err != nil {
+		t.Fatalf("Expected %s=0 to disable the summary, got %v, %v", IntervalEnvVar, r, err)
+	}
+	t.Setenv(IntervalEnvVar, "ten minutes")
+	if _, err := NewReporterFromEnv(); err == nil {
+		t.Fatalf("Expected an invalid %s to be an error", IntervalEnvVar)
+	}
+}
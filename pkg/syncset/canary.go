@@ -0,0 +1,80 @@
+package syncset
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ghodss/yaml"
+	templatev1 "github.com/openshift/api/template/v1"
+	hivev1 "github.com/openshift/hive/apis/hive/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Cohort is a set of clusters a webhook is rolled out to in its own phase
+type Cohort string
+
+const (
+	// CohortCanary are the clusters labelled CanaryClusterLabel=true, which
+	// get the new release of a canaried webhook first
+	CohortCanary Cohort = "canary"
+	// CohortFleet are the other clusters, which keep the baseline release of
+	// a canaried webhook until it is promoted
+	CohortFleet Cohort = "fleet"
+
+	// CanaryClusterLabel is the ClusterDeployment label putting a cluster in
+	// CohortCanary
+	CanaryClusterLabel = "api.openshift.com/webhook-canary"
+	// CohortLabel labels the SelectorSyncSets of a canaried webhook with
+	// their cohort
+	CohortLabel = "managed.openshift.io/webhook-cohort"
+)
+
+// CohortSelector returns selector restricted to the clusters of cohort. The
+// selectors of the cohorts are disjoint, so a cluster never gets both
+// variants of a webhook.
+func CohortSelector(selector metav1.LabelSelector, cohort Cohort) metav1.LabelSelector {
+	restricted := *selector.DeepCopy()
+	operator := metav1.LabelSelectorOpIn
+	if cohort == CohortFleet {
+		// NotIn also matches the clusters without the label
+		operator = metav1.LabelSelectorOpNotIn
+	}
+	restricted.MatchExpressions = append(restricted.MatchExpressions, metav1.LabelSelectorRequirement{
+		Key:      CanaryClusterLabel,
+		Operator: operator,
+		Values:   []string{"true"},
+	})
+	return restricted
+}
+
+// BaselineResources returns the resources of the SelectorSyncSets of the
+// rendered SelectorSyncSet template data, e.g. of the previous release, by
+// their kind and name, e.g. ValidatingWebhookConfiguration/sre-scc-validation
+func BaselineResources(data []byte) (map[string]runtime.RawExtension, error) {
+	te := templatev1.Template{}
+	if err := yaml.Unmarshal(data, &te); err != nil {
+		return nil, err
+	}
+	resources := map[string]runtime.RawExtension{}
+	for _, object := range te.Objects {
+		sss := hivev1.SelectorSyncSet{}
+		if err := json.Unmarshal(object.Raw, &sss); err != nil {
+			return nil, err
+		}
+		if sss.Kind != "SelectorSyncSet" {
+			return nil, fmt.Errorf("the template holds a %s rather than SelectorSyncSets", sss.Kind)
+		}
+		for _, resource := range sss.Spec.Resources {
+			meta := struct {
+				Kind     string            `json:"kind"`
+				Metadata metav1.ObjectMeta `json:"metadata"`
+			}{}
+			if err := json.Unmarshal(resource.Raw, &meta); err != nil {
+				return nil, err
+			}
+			resources[meta.Kind+"/"+meta.Metadata.Name] = resource
+		}
+	}
+	return resources, nil
+}
@@ -0,0 +1,104 @@
+package syncset
+
+import (
+	"encoding/json"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestCohortSelector(t *testing.T) {
+	selector := metav1.LabelSelector{MatchLabels: map[string]string{"api.openshift.com/managed": "true"}}
+	canarySelector, fleetSelector := CohortSelector(selector, CohortCanary), CohortSelector(selector, CohortFleet)
+	canary, err := metav1.LabelSelectorAsSelector(&canarySelector)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	fleet, err := metav1.LabelSelectorAsSelector(&fleetSelector)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tests := []struct {
+		name          string
+		labels        map[string]string
+		canary, fleet bool
+	}{
+		{name: "canary cluster", labels: map[string]string{"api.openshift.com/managed": "true", CanaryClusterLabel: "true"}, canary: true},
+		{name: "fleet cluster", labels: map[string]string{"api.openshift.com/managed": "true"}, fleet: true},
+		{name: "cluster opted out of the canary", labels: map[string]string{"api.openshift.com/managed": "true", CanaryClusterLabel: "false"}, fleet: true},
+		{name: "unmanaged cluster", labels: map[string]string{CanaryClusterLabel: "true"}},
+	}
+	for _, test := range tests {
+		if got := canary.Matches(labels.Set(test.labels)); got != test.canary {
+			t.Errorf("%s: expected the canary selector to match %v, got %v", test.name, test.canary, got)
+		}
+		if got := fleet.Matches(labels.Set(test.labels)); got != test.fleet {
+			t.Errorf("%s: expected the fleet selector to match %v, got %v", test.name, test.fleet, got)
+		}
+	}
+	if len(selector.MatchExpressions) != 0 {
+		t.Errorf("Expected the original selector to be left alone, got %v", selector)
+	}
+}
+
+func TestRenderCohorts(t *testing.T) {
+	resources := SyncSetResourcesByLabelSelector{}
+	selector := metav1.LabelSelector{MatchLabels: map[string]string{"api.openshift.com/managed": "true"}}
+	resources.Add(selector, runtime.RawExtension{Raw: []byte(`{"kind":"Namespace"}`)})
+	resources.AddForCohort("scc-validation", CohortCanary, selector, runtime.RawExtension{Raw: []byte(`{"kind":"ValidatingWebhookConfiguration"}`)})
+	resources.AddForCohort("scc-validation", CohortFleet, selector, runtime.RawExtension{Raw: []byte(`{"kind":"ValidatingWebhookConfiguration"}`)})
+
+	names := []string{}
+	for _, raw := range resources.RenderSelectorSyncSets(map[string]string{"managed.openshift.io/osd": "true"}) {
+		sss := metav1.PartialObjectMetadata{}
+		if err := json.Unmarshal(raw.Raw, &sss); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		names = append(names, sss.Name)
+		if sss.Labels["managed.openshift.io/osd"] != "true" {
+			t.Errorf("Expected %s to keep the common labels, got %v", sss.Name, sss.Labels)
+		}
+		if sss.Name == "managed-cluster-validating-webhooks-scc-validation-canary" && (sss.Labels[WebhookLabel] != "scc-validation" || sss.Labels[CohortLabel] != "canary") {
+			t.Errorf("Expected the canary SelectorSyncSet to be labelled with its webhook and cohort, got %v", sss.Labels)
+		}
+	}
+	expected := []string{"managed-cluster-validating-webhooks-0", "managed-cluster-validating-webhooks-scc-validation-canary", "managed-cluster-validating-webhooks-scc-validation-fleet"}
+	if len(names) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, names)
+		}
+	}
+}
+
+func TestBaselineResources(t *testing.T) {
+	template := `
+apiVersion: template.openshift.io/v1
+kind: Template
+objects:
+- apiVersion: hive.openshift.io/v1
+  kind: SelectorSyncSet
+  metadata:
+    name: managed-cluster-validating-webhooks-0
+  spec:
+    resources:
+    - apiVersion: admissionregistration.k8s.io/v1
+      kind: ValidatingWebhookConfiguration
+      metadata:
+        name: sre-scc-validation
+`
+	resources, err := BaselineResources([]byte(template))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := resources["ValidatingWebhookConfiguration/sre-scc-validation"]; !ok || len(resources) != 1 {
+		t.Fatalf("Expected the scc-validation configuration, got %v", resources)
+	}
+	if _, err := BaselineResources([]byte("apiVersion: v1\nkind: Template\nobjects:\n- kind: Service\n")); err == nil {
+		t.Fatalf("Expected an error for a template without SelectorSyncSets")
+	}
+}
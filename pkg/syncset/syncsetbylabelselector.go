@@ -19,6 +19,9 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// WebhookLabel labels the SelectorSyncSet of a single webhook with its name
+const WebhookLabel = "managed.openshift.io/webhook"
+
 // SyncSetResourcesByLabelSelector is a mapping data structure.
 // It uses metav1.LabelSelector as key and runtime.RawExtension as value.
 // The builtin map type cannot be used because metav1.LabelSelector cannot be used as key.
@@ -29,24 +32,61 @@ type SyncSetResourcesByLabelSelector struct {
 type mapEntry struct {
 	key    metav1.LabelSelector
 	values []runtime.RawExtension
+	// templated entries are rendered with Hive resource templates enabled
+	templated bool
+	// webhook is the webhook of entries rendered in a SelectorSyncSet of
+	// their own
+	webhook string
+	// cohort is the rollout cohort of the entries of a canaried webhook
+	cohort Cohort
 }
 
 // Add adds a resources to a SyncSetResourcesByLabelSelector object
 func (s *SyncSetResourcesByLabelSelector) Add(key metav1.LabelSelector, object runtime.RawExtension) {
-	existingEntry := s.Get(key)
+	s.add(key, object, false, "", "")
+}
+
+// AddTemplated adds a resource using Hive resource template functions, e.g.
+// {{ fromCDLabel "label" }}. Templated resources are rendered in their own
+// SelectorSyncSets, so the template syntax of other resources, like
+// PrometheusRule annotations, is left alone.
+func (s *SyncSetResourcesByLabelSelector) AddTemplated(key metav1.LabelSelector, object runtime.RawExtension) {
+	s.add(key, object, true, "", "")
+}
+
+// AddForWebhook adds the resources of webhook, which are rendered in a
+// SelectorSyncSet of their own, so the webhook can be paused or rolled back
+// fleet-wide without touching the other webhooks
+func (s *SyncSetResourcesByLabelSelector) AddForWebhook(webhook string, key metav1.LabelSelector, object runtime.RawExtension) {
+	s.add(key, object, false, webhook, "")
+}
+
+// AddForCohort adds the resources of webhook for the clusters of cohort,
+// which are rendered in a SelectorSyncSet of their own and select only the
+// clusters of cohort, see CohortSelector
+func (s *SyncSetResourcesByLabelSelector) AddForCohort(webhook string, cohort Cohort, key metav1.LabelSelector, object runtime.RawExtension) {
+	s.add(CohortSelector(key, cohort), object, false, webhook, cohort)
+}
+
+func (s *SyncSetResourcesByLabelSelector) add(key metav1.LabelSelector, object runtime.RawExtension, templated bool, webhook string, cohort Cohort) {
+	existingEntry := s.get(key, templated, webhook, cohort)
 
 	if existingEntry != nil {
 		existingEntry.values = append(existingEntry.values, object)
 		return
 	}
 
-	s.entries = append(s.entries, mapEntry{key, []runtime.RawExtension{object}})
+	s.entries = append(s.entries, mapEntry{key, []runtime.RawExtension{object}, templated, webhook, cohort})
 }
 
 // Get returns a single entry based on the passed key. If none exists, it returns nil
 func (s *SyncSetResourcesByLabelSelector) Get(key metav1.LabelSelector) *mapEntry {
+	return s.get(key, false, "", "")
+}
+
+func (s *SyncSetResourcesByLabelSelector) get(key metav1.LabelSelector, templated bool, webhook string, cohort Cohort) *mapEntry {
 	for i, entry := range s.entries {
-		if reflect.DeepEqual(entry.key, key) {
+		if reflect.DeepEqual(entry.key, key) && entry.templated == templated && entry.webhook == webhook && entry.cohort == cohort {
 			return &s.entries[i]
 		}
 	}
@@ -54,23 +94,60 @@ func (s *SyncSetResourcesByLabelSelector) Get(key metav1.LabelSelector) *mapEntr
 }
 
 // RenderSelectorSyncSets renders a minimal set of SelectorSyncSets based on the LabelSelectors
-// existing in the SyncSetResourcesByLabelSelector object
+// existing in the SyncSetResourcesByLabelSelector object. The resources of a
+// webhook added with AddForWebhook are rendered in a SelectorSyncSet named
+// after the webhook and labelled with WebhookLabel, and those added with
+// AddForCohort in one named after the webhook and cohort and also labelled
+// with CohortLabel.
 func (s *SyncSetResourcesByLabelSelector) RenderSelectorSyncSets(labels map[string]string) []runtime.RawExtension {
 	sss := []runtime.RawExtension{}
-	for i, entry := range s.entries {
-		sss = append(sss, runtime.RawExtension{
-			Raw: Encode(createSelectorSyncSet(
-				fmt.Sprintf("managed-cluster-validating-webhooks-%d", i),
-				entry.values,
-				entry.key,
-				labels,
-			),
-			),
-		})
+	// shared numbers the SelectorSyncSets of resources shared by the
+	// webhooks, which keep their names whether or not the webhooks have
+	// their own
+	shared := 0
+	for _, entry := range s.entries {
+		name := fmt.Sprintf("managed-cluster-validating-webhooks-%d", shared)
+		sssLabels := labels
+		if entry.webhook != "" {
+			name = "managed-cluster-validating-webhooks-" + entry.webhook
+			sssLabels = map[string]string{WebhookLabel: entry.webhook}
+			if entry.cohort != "" {
+				name += "-" + string(entry.cohort)
+				sssLabels[CohortLabel] = string(entry.cohort)
+			}
+			for k, v := range labels {
+				sssLabels[k] = v
+			}
+		} else {
+			shared++
+		}
+		selectorSyncSet := createSelectorSyncSet(
+			name,
+			entry.values,
+			entry.key,
+			sssLabels,
+		)
+		raw := Encode(selectorSyncSet)
+		if entry.templated {
+			raw = encodeWithResourceTemplates(selectorSyncSet)
+		}
+		sss = append(sss, runtime.RawExtension{Raw: raw})
 	}
 	return sss
 }
 
+// encodeWithResourceTemplates sets enableResourceTemplates, which the
+// vendored Hive API doesn't have yet
+func encodeWithResourceTemplates(sss *hivev1.SelectorSyncSet) []byte {
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(Encode(sss), &decoded); err != nil {
+		fmt.Printf("Error decoding %+v\n", sss)
+		os.Exit(1)
+	}
+	decoded["spec"].(map[string]interface{})["enableResourceTemplates"] = true
+	return Encode(decoded)
+}
+
 func createSelectorSyncSet(name string, resources []runtime.RawExtension, selector metav1.LabelSelector, labels map[string]string) *hivev1.SelectorSyncSet {
 	return &hivev1.SelectorSyncSet{
 		TypeMeta: metav1.TypeMeta{
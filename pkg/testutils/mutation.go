@@ -0,0 +1,112 @@
+package testutils
+
+import (
+	"encoding/json"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// MutatingWebhook is a Webhook which mutates the objects it admits
+type MutatingWebhook interface {
+	Webhook
+	// GetURI returns the path the webhook is served on
+	GetURI() string
+}
+
+// MutationRequest is the admission request SendMutation sends a mutating
+// webhook. Operation defaults to CREATE, Username to "my_user" and
+// UserGroups to system:authenticated.
+type MutationRequest struct {
+	TestID     string
+	GVK        metav1.GroupVersionKind
+	GVR        metav1.GroupVersionResource
+	Operation  admissionv1.Operation
+	Username   string
+	UserGroups []string
+	Namespace  string
+	// Object is the raw JSON of the object in the request
+	Object []byte
+	// ExpectDenied is set for requests the webhook is expected to deny, in
+	// which case there is no mutated object to decode
+	ExpectDenied bool
+}
+
+// SendMutation sends the request to the webhook and fails the test unless the
+// response carries a tracking UID and is allowed, or denied for ExpectDenied.
+// The patch of an allowed response is applied to the request's Object and the
+// result decoded into mutated. The response is returned for the test to make
+// its own assertions.
+func SendMutation(t *testing.T, hook MutatingWebhook, request MutationRequest, mutated interface{}) *admissionv1.AdmissionResponse {
+	t.Helper()
+	operation := request.Operation
+	if operation == "" {
+		operation = admissionv1.Create
+	}
+	username := request.Username
+	if username == "" {
+		username = "my_user"
+	}
+	userGroups := request.UserGroups
+	if userGroups == nil {
+		userGroups = []string{"system:authenticated"}
+	}
+	obj := runtime.RawExtension{
+		Raw: request.Object,
+	}
+
+	httprequest, err := CreateHTTPRequest(hook.GetURI(),
+		request.TestID, request.GVK, request.GVR, operation, username, userGroups, request.Namespace, &obj, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	response, err := SendHTTPRequest(httprequest, hook)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	if response.UID == "" {
+		t.Fatalf("No tracking UID associated with the response.")
+	}
+	if request.ExpectDenied {
+		if response.Allowed {
+			t.Fatalf("%s: Expected the request to be denied", request.TestID)
+		}
+		return response
+	}
+	if !response.Allowed {
+		t.Fatalf("%s: Mutating webhook should allow the request, got %v", request.TestID, response.Result)
+	}
+
+	mutatedRaw, err := ApplyPatch(request.Object, response)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s while applying response.Patch", err.Error())
+	}
+	if err := json.Unmarshal(mutatedRaw, mutated); err != nil {
+		t.Fatalf("Expected no error, got %s while decoding the mutated %s", err.Error(), request.GVK.Kind)
+	}
+	return response
+}
+
+// KeepsNewerPodFields fails the test unless the Pod mutated from
+// PodWithNewerFields still sets the fields the vendored API types don't know,
+// and gained no status or creation timestamp from a re-encoding
+func KeepsNewerPodFields(t *testing.T, mutated map[string]interface{}) {
+	t.Helper()
+	spec, _ := mutated["spec"].(map[string]interface{})
+	initContainers, _ := spec["initContainers"].([]interface{})
+	containers, _ := spec["containers"].([]interface{})
+	if len(initContainers) != 1 || initContainers[0].(map[string]interface{})["restartPolicy"] != "Always" {
+		t.Fatalf("Expected the restartPolicy of the sidecar init container to be kept, got %v", initContainers)
+	}
+	if len(containers) != 1 || containers[0].(map[string]interface{})["resizePolicy"] == nil {
+		t.Fatalf("Expected the resizePolicy of the container to be kept, got %v", containers)
+	}
+	if _, found := mutated["status"]; found {
+		t.Fatalf("Expected no status to be added, got %v", mutated["status"])
+	}
+	if metadata, _ := mutated["metadata"].(map[string]interface{}); metadata["creationTimestamp"] != nil {
+		t.Fatalf("Expected no creationTimestamp to be added, got %v", metadata["creationTimestamp"])
+	}
+}
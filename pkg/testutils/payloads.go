@@ -0,0 +1,226 @@
+package testutils
+
+import (
+	"encoding/json"
+	"fmt"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// The representative payloads are objects the size and shape of the ones the
+// API server sends the busiest webhooks, with managed fields, status and the
+// sidecars of a typical workload, for benchmarks. Their name and namespace
+// are formatted in with RepresentativeObject.
+const (
+	// RepresentativePod is a Pod of a Deployment, with an application and a
+	// proxy sidecar container
+	RepresentativePod = `{
+  "apiVersion": "v1",
+  "kind": "Pod",
+  "metadata": {
+    "name": "%[1]s",
+    "namespace": "%[2]s",
+    "generateName": "frontend-7c9d8b6f4d-",
+    "uid": "5b3f2a1e-8c4d-4e6f-9a0b-1c2d3e4f5a6b",
+    "creationTimestamp": "2023-11-14T22:13:20Z",
+    "labels": {
+      "app": "frontend",
+      "pod-template-hash": "7c9d8b6f4d",
+      "app.kubernetes.io/part-of": "storefront",
+      "app.kubernetes.io/version": "1.42.0"
+    },
+    "annotations": {
+      "openshift.io/scc": "restricted-v2",
+      "seccomp.security.alpha.kubernetes.io/pod": "runtime/default",
+      "k8s.v1.cni.cncf.io/network-status": "[{\"name\":\"ovn-kubernetes\",\"interface\":\"eth0\",\"ips\":[\"10.128.2.17\"],\"default\":true}]"
+    },
+    "ownerReferences": [
+      {"apiVersion": "apps/v1", "kind": "ReplicaSet", "name": "frontend-7c9d8b6f4d", "uid": "0a1b2c3d-4e5f-6a7b-8c9d-0e1f2a3b4c5d", "controller": true, "blockOwnerDeletion": true}
+    ],
+    "managedFields": [
+      {"manager": "kube-controller-manager", "operation": "Update", "apiVersion": "v1", "time": "2023-11-14T22:13:20Z", "fieldsType": "FieldsV1", "fieldsV1": {"f:metadata": {"f:generateName": {}, "f:labels": {".": {}, "f:app": {}, "f:pod-template-hash": {}}, "f:ownerReferences": {".": {}, "k:{\"uid\":\"0a1b2c3d-4e5f-6a7b-8c9d-0e1f2a3b4c5d\"}": {}}}, "f:spec": {"f:containers": {"k:{\"name\":\"frontend\"}": {".": {}, "f:env": {}, "f:image": {}, "f:name": {}, "f:ports": {}, "f:resources": {}}, "k:{\"name\":\"proxy\"}": {".": {}, "f:image": {}, "f:name": {}}}}}}
+    ]
+  },
+  "spec": {
+    "serviceAccountName": "frontend",
+    "securityContext": {"runAsNonRoot": true, "seccompProfile": {"type": "RuntimeDefault"}, "fsGroup": 1000680000},
+    "containers": [
+      {
+        "name": "frontend",
+        "image": "quay.io/storefront/frontend@sha256:4b825dc642cb6eb9a060e54bf8d69288fbee4904e1f1a1c6b2a6e3f0d7d4e5c1",
+        "ports": [{"name": "http", "containerPort": 8080, "protocol": "TCP"}],
+        "env": [
+          {"name": "LOG_LEVEL", "value": "info"},
+          {"name": "BACKEND_URL", "value": "http://backend.storefront.svc:8080"},
+          {"name": "POD_NAME", "valueFrom": {"fieldRef": {"apiVersion": "v1", "fieldPath": "metadata.name"}}},
+          {"name": "DB_PASSWORD", "valueFrom": {"secretKeyRef": {"name": "frontend-db", "key": "password"}}}
+        ],
+        "resources": {"requests": {"cpu": "100m", "memory": "256Mi"}, "limits": {"memory": "512Mi"}},
+        "readinessProbe": {"httpGet": {"path": "/healthz", "port": 8080, "scheme": "HTTP"}, "periodSeconds": 10, "timeoutSeconds": 1, "successThreshold": 1, "failureThreshold": 3},
+        "volumeMounts": [
+          {"name": "config", "mountPath": "/etc/frontend"},
+          {"name": "kube-api-access-x7k2p", "readOnly": true, "mountPath": "/var/run/secrets/kubernetes.io/serviceaccount"}
+        ],
+        "securityContext": {"allowPrivilegeEscalation": false, "capabilities": {"drop": ["ALL"]}, "runAsUser": 1000680000},
+        "terminationMessagePath": "/dev/termination-log",
+        "terminationMessagePolicy": "File",
+        "imagePullPolicy": "IfNotPresent"
+      },
+      {
+        "name": "proxy",
+        "image": "registry.redhat.io/openshift4/ose-oauth-proxy@sha256:9d6f1b2c3a4e5f60718293a4b5c6d7e8f90123456789abcdef0123456789abcd",
+        "args": ["--https-address=:8443", "--provider=openshift", "--upstream=http://localhost:8080"],
+        "ports": [{"name": "https", "containerPort": 8443, "protocol": "TCP"}],
+        "resources": {"requests": {"cpu": "10m", "memory": "32Mi"}},
+        "volumeMounts": [
+          {"name": "kube-api-access-x7k2p", "readOnly": true, "mountPath": "/var/run/secrets/kubernetes.io/serviceaccount"}
+        ],
+        "securityContext": {"allowPrivilegeEscalation": false, "capabilities": {"drop": ["ALL"]}, "runAsUser": 1000680000},
+        "terminationMessagePath": "/dev/termination-log",
+        "terminationMessagePolicy": "File",
+        "imagePullPolicy": "IfNotPresent"
+      }
+    ],
+    "volumes": [
+      {"name": "config", "configMap": {"name": "frontend-config", "defaultMode": 420}},
+      {"name": "kube-api-access-x7k2p", "projected": {"defaultMode": 420, "sources": [{"serviceAccountToken": {"expirationSeconds": 3607, "path": "token"}}, {"configMap": {"name": "kube-root-ca.crt", "items": [{"key": "ca.crt", "path": "ca.crt"}]}}]}}
+    ],
+    "tolerations": [
+      {"key": "node.kubernetes.io/not-ready", "operator": "Exists", "effect": "NoExecute", "tolerationSeconds": 300},
+      {"key": "node.kubernetes.io/unreachable", "operator": "Exists", "effect": "NoExecute", "tolerationSeconds": 300},
+      {"key": "node.kubernetes.io/memory-pressure", "operator": "Exists", "effect": "NoSchedule"}
+    ],
+    "restartPolicy": "Always",
+    "terminationGracePeriodSeconds": 30,
+    "dnsPolicy": "ClusterFirst",
+    "schedulerName": "default-scheduler",
+    "priority": 0,
+    "enableServiceLinks": true,
+    "preemptionPolicy": "PreemptLowerPriority"
+  },
+  "status": {"phase": "Pending", "qosClass": "Burstable"}
+}`
+
+	// PodWithNewerFields is a customer Pod using fields newer than the
+	// vendored API types: a native sidecar init container and the resize
+	// policy of a container. It sets no priority class, resource requests or
+	// environment and tolerates the infra nodes, for mutating webhooks to
+	// show they patch it without dropping the fields they don't know.
+	PodWithNewerFields = `{
+  "apiVersion": "v1",
+  "kind": "Pod",
+  "metadata": {
+    "name": "%[1]s",
+    "namespace": "%[2]s",
+    "uid": "1234"
+  },
+  "spec": {
+    "initContainers": [
+      {"name": "log-shipper", "image": "quay.io/example/shipper:v1", "restartPolicy": "Always"}
+    ],
+    "containers": [
+      {"name": "app", "image": "quay.io/example/app:v1", "resizePolicy": [{"resourceName": "cpu", "restartPolicy": "NotRequired"}]}
+    ],
+    "tolerations": [
+      {"key": "node-role.kubernetes.io/infra", "operator": "Exists", "effect": "NoSchedule"}
+    ]
+  }
+}`
+
+	// RepresentativeNamespace is a project Namespace of a customer, with the
+	// annotations and labels OpenShift adds
+	RepresentativeNamespace = `{
+  "apiVersion": "v1",
+  "kind": "Namespace",
+  "metadata": {
+    "name": "%[1]s",
+    "uid": "7e6d5c4b-3a29-4817-a6f5-e4d3c2b1a098",
+    "creationTimestamp": "2023-11-14T22:13:20Z",
+    "labels": {
+      "kubernetes.io/metadata.name": "%[1]s",
+      "pod-security.kubernetes.io/audit": "restricted",
+      "pod-security.kubernetes.io/audit-version": "v1.24",
+      "pod-security.kubernetes.io/warn": "restricted",
+      "pod-security.kubernetes.io/warn-version": "v1.24"
+    },
+    "annotations": {
+      "openshift.io/description": "",
+      "openshift.io/display-name": "",
+      "openshift.io/requester": "alice",
+      "openshift.io/sa.scc.mcs": "s0:c26,c15",
+      "openshift.io/sa.scc.supplemental-groups": "1000680000/10000",
+      "openshift.io/sa.scc.uid-range": "1000680000/10000"
+    },
+    "managedFields": [
+      {"manager": "openshift-apiserver", "operation": "Update", "apiVersion": "v1", "time": "2023-11-14T22:13:20Z", "fieldsType": "FieldsV1", "fieldsV1": {"f:metadata": {"f:annotations": {".": {}, "f:openshift.io/description": {}, "f:openshift.io/display-name": {}, "f:openshift.io/requester": {}}, "f:labels": {".": {}, "f:kubernetes.io/metadata.name": {}}}}},
+      {"manager": "cluster-policy-controller", "operation": "Update", "apiVersion": "v1", "time": "2023-11-14T22:13:21Z", "fieldsType": "FieldsV1", "fieldsV1": {"f:metadata": {"f:annotations": {"f:openshift.io/sa.scc.mcs": {}, "f:openshift.io/sa.scc.supplemental-groups": {}, "f:openshift.io/sa.scc.uid-range": {}}}}}
+    ]
+  },
+  "spec": {"finalizers": ["kubernetes"]},
+  "status": {"phase": "Active"}
+}`
+
+	// RepresentativeSCC is a SecurityContextConstraints like the default
+	// ones. Its namespace is ignored.
+	RepresentativeSCC = `{
+  "apiVersion": "security.openshift.io/v1",
+  "kind": "SecurityContextConstraints",
+  "metadata": {
+    "name": "%[1]s",
+    "uid": "c0ffee00-1234-4abc-8def-0123456789ab",
+    "creationTimestamp": "2023-11-14T22:13:20Z",
+    "annotations": {
+      "include.release.openshift.io/ibm-cloud-managed": "true",
+      "include.release.openshift.io/self-managed-high-availability": "true",
+      "kubernetes.io/description": "anyuid provides all features of the restricted SCC but allows users to run with any UID and any GID.",
+      "release.openshift.io/create-only": "true"
+    }
+  },
+  "allowHostDirVolumePlugin": false,
+  "allowHostIPC": false,
+  "allowHostNetwork": false,
+  "allowHostPID": false,
+  "allowHostPorts": false,
+  "allowPrivilegeEscalation": true,
+  "allowPrivilegedContainer": false,
+  "allowedCapabilities": null,
+  "defaultAddCapabilities": null,
+  "fsGroup": {"type": "RunAsAny"},
+  "groups": ["system:cluster-admins"],
+  "priority": 10,
+  "readOnlyRootFilesystem": false,
+  "requiredDropCapabilities": ["MKNOD"],
+  "runAsUser": {"type": "RunAsAny"},
+  "seLinuxContext": {"type": "MustRunAs"},
+  "supplementalGroups": {"type": "RunAsAny"},
+  "users": [],
+  "volumes": ["configMap", "csi", "downwardAPI", "emptyDir", "ephemeral", "persistentVolumeClaim", "projected", "secret"]
+}`
+)
+
+// RepresentativeObject formats the name and namespace into the
+// representative payload
+func RepresentativeObject(payload, name, namespace string) *runtime.RawExtension {
+	return &runtime.RawExtension{Raw: []byte(fmt.Sprintf(payload, name, namespace))}
+}
+
+// CreateRequest returns the admission Request of CreateFakeRequestJSON, for
+// benchmarks calling a webhook without the HTTP round trip
+func CreateRequest(uid string,
+	gvk metav1.GroupVersionKind, gvr metav1.GroupVersionResource,
+	operation admissionv1.Operation,
+	username string, userGroups []string, namespace string,
+	obj, oldObject *runtime.RawExtension) (admissionctl.Request, error) {
+	b, err := CreateFakeRequestJSON(uid, gvk, gvr, operation, username, userGroups, namespace, obj, oldObject)
+	if err != nil {
+		return admissionctl.Request{}, err
+	}
+	review := admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(b, &review); err != nil {
+		return admissionctl.Request{}, err
+	}
+	return admissionctl.Request{AdmissionRequest: *review.Request}, nil
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 
+	jsonpatch "github.com/evanphx/json-patch"
 	admissionv1 "k8s.io/api/admission/v1"
 	authenticationv1 "k8s.io/api/authentication/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -13,6 +14,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/capture"
 	responsehelper "github.com/openshift/managed-cluster-validating-webhooks/pkg/helpers"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 )
@@ -127,3 +129,35 @@ func SendHTTPRequest(req *http.Request, s Webhook) (*admissionv1.AdmissionRespon
 	}
 	return ret.Response, nil
 }
+
+// ApplyPatch applies the JSONPatch in a mutating webhook's response to the
+// original object and returns the mutated object. When the response carries no
+// patch the original object is returned unchanged.
+func ApplyPatch(original []byte, response *admissionv1.AdmissionResponse) ([]byte, error) {
+	if len(response.Patch) == 0 {
+		return original, nil
+	}
+	patch, err := jsonpatch.DecodePatch(response.Patch)
+	if err != nil {
+		return nil, err
+	}
+	return patch.Apply(original)
+}
+
+// ReplayFixture sends the request of the fixture captured to path, see
+// pkg/capture, to the Webhook. The fixture holds the decision the webhook made
+// when it was captured, for the test to compare with the response.
+func ReplayFixture(path string, s Webhook) (*admissionv1.AdmissionResponse, capture.Fixture, error) {
+	fixture, err := capture.Load(path)
+	if err != nil {
+		return nil, fixture, err
+	}
+	b, err := json.Marshal(fixture.Review)
+	if err != nil {
+		return nil, fixture, err
+	}
+	httprequest := httptest.NewRequest("POST", "/", bytes.NewBuffer(b))
+	httprequest.Header["Content-Type"] = []string{"application/json"}
+	resp, err := SendHTTPRequest(httprequest, s)
+	return resp, fixture, err
+}
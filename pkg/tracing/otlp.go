@@ -0,0 +1,262 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultQueueSize     = 2048
+	defaultBatchSize     = 256
+	defaultFlushInterval = 5 * time.Second
+	exportTimeout        = 10 * time.Second
+	instrumentationScope = "github.com/openshift/managed-cluster-validating-webhooks/pkg/tracing"
+	// otlpStatusError is STATUS_CODE_ERROR
+	otlpStatusError = 2
+)
+
+// otlpExporter asynchronously batches finished Spans and POSTs them to an
+// OTLP/HTTP collector using the JSON encoding. Spans are dropped rather than
+// blocking admission when the queue is full.
+type otlpExporter struct {
+	endpoint      string
+	serviceName   string
+	headers       map[string]string
+	httpClient    *http.Client
+	spans         chan *Span
+	done          chan struct{}
+	batchSize     int
+	flushInterval time.Duration
+}
+
+func newOTLPExporter(endpoint, serviceName string, headers map[string]string) (*otlpExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP traces endpoint: %v", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("OTLP traces endpoint must be an http or https URL, got %q", endpoint)
+	}
+	return &otlpExporter{
+		endpoint:      endpoint,
+		serviceName:   serviceName,
+		headers:       headers,
+		httpClient:    &http.Client{Timeout: exportTimeout},
+		spans:         make(chan *Span, defaultQueueSize),
+		done:          make(chan struct{}),
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+	}, nil
+}
+
+func (e *otlpExporter) start() {
+	go e.run()
+}
+
+func (e *otlpExporter) export(span *Span) {
+	select {
+	case e.spans <- span:
+	default:
+		log.V(1).Info("Trace export queue is full, dropping span", "span", span.name)
+	}
+}
+
+// close stops accepting Spans and waits for queued Spans to be exported
+func (e *otlpExporter) close() {
+	close(e.spans)
+	<-e.done
+}
+
+func (e *otlpExporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, e.batchSize)
+	for {
+		select {
+		case span, ok := <-e.spans:
+			if !ok {
+				e.flush(batch)
+				return
+			}
+			batch = append(batch, span)
+			if len(batch) >= e.batchSize {
+				e.flush(batch)
+				batch = make([]*Span, 0, e.batchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				e.flush(batch)
+				batch = make([]*Span, 0, e.batchSize)
+			}
+		}
+	}
+}
+
+// flush exports the batch once. Traces are best effort, so failed batches
+// are logged and dropped.
+func (e *otlpExporter) flush(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+	body, err := json.Marshal(e.buildRequest(batch))
+	if err != nil {
+		log.Error(err, "Failed to encode spans")
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		log.Error(err, "Failed to build the trace export request")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		log.Error(err, "Failed to export spans", "spans", len(batch))
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		log.Error(fmt.Errorf("%s returned %d: %s", e.endpoint, resp.StatusCode, strings.TrimSpace(string(msg))), "Failed to export spans", "spans", len(batch))
+	}
+}
+
+// The types below are the subset of the OTLP ExportTraceServiceRequest JSON
+// encoding which the exporter emits
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func (e *otlpExporter) buildRequest(batch []*Span) otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		spans = append(spans, toOTLPSpan(s))
+	}
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: otlpResource{
+					Attributes: []otlpKeyValue{toOTLPKeyValue("service.name", e.serviceName)},
+				},
+				ScopeSpans: []otlpScopeSpans{
+					{
+						Scope: otlpScope{Name: instrumentationScope},
+						Spans: spans,
+					},
+				},
+			},
+		},
+	}
+}
+
+func toOTLPSpan(s *Span) otlpSpan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	span := otlpSpan{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+	}
+	if s.parentID != [8]byte{} {
+		span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	for k, v := range s.attributes {
+		span.Attributes = append(span.Attributes, toOTLPKeyValue(k, v))
+	}
+	if s.errMessage != "" {
+		span.Status = &otlpStatus{Code: otlpStatusError, Message: s.errMessage}
+	}
+	return span
+}
+
+func toOTLPKeyValue(key string, value interface{}) otlpKeyValue {
+	kv := otlpKeyValue{Key: key}
+	switch v := value.(type) {
+	case bool:
+		kv.Value.BoolValue = &v
+	case int:
+		i := strconv.Itoa(v)
+		kv.Value.IntValue = &i
+	case int32:
+		i := strconv.FormatInt(int64(v), 10)
+		kv.Value.IntValue = &i
+	case int64:
+		i := strconv.FormatInt(v, 10)
+		kv.Value.IntValue = &i
+	case float64:
+		kv.Value.DoubleValue = &v
+	case string:
+		kv.Value.StringValue = &v
+	default:
+		str := fmt.Sprintf("%v", v)
+		kv.Value.StringValue = &str
+	}
+	return kv
+}
@@ -0,0 +1,208 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// EndpointEnvVar is the base OTLP/HTTP collector URL, /v1/traces is
+	// appended to it. Tracing is disabled when neither it nor
+	// TracesEndpointEnvVar is set.
+	EndpointEnvVar string = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	// TracesEndpointEnvVar is the full OTLP/HTTP traces URL, and takes
+	// precedence over EndpointEnvVar
+	TracesEndpointEnvVar string = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	// HeadersEnvVar is a comma separated list of key=value headers sent with
+	// each export, e.g. for collector authentication
+	HeadersEnvVar string = "OTEL_EXPORTER_OTLP_HEADERS"
+	// ServiceNameEnvVar overrides the service.name resource attribute
+	ServiceNameEnvVar string = "OTEL_SERVICE_NAME"
+
+	defaultServiceName = "managed-cluster-validating-webhooks"
+	traceparentHeader  = "traceparent"
+)
+
+var log = logf.Log.WithName("tracing")
+
+// SpanKind is the OTLP span kind
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+)
+
+type spanContextKey struct{}
+
+// Tracer creates Spans and hands finished ones to its exporter. A nil
+// *Tracer is valid and creates no-op Spans, so callers needn't check whether
+// tracing is enabled.
+type Tracer struct {
+	exporter exporter
+}
+
+// exporter receives finished, sampled Spans
+type exporter interface {
+	export(span *Span)
+}
+
+// NewTracerFromEnv returns a Tracer exporting to the OTLP collector
+// configured by the environment, or nil if tracing isn't configured
+func NewTracerFromEnv() (*Tracer, error) {
+	endpoint := os.Getenv(TracesEndpointEnvVar)
+	if endpoint == "" {
+		base := os.Getenv(EndpointEnvVar)
+		if base == "" {
+			return nil, nil
+		}
+		endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+	serviceName := os.Getenv(ServiceNameEnvVar)
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	exp, err := newOTLPExporter(endpoint, serviceName, parseHeaders(os.Getenv(HeadersEnvVar)))
+	if err != nil {
+		return nil, err
+	}
+	exp.start()
+	return &Tracer{exporter: exp}, nil
+}
+
+// Span is a single timed operation within a trace
+type Span struct {
+	tracer     *Tracer
+	traceID    [16]byte
+	spanID     [8]byte
+	parentID   [8]byte
+	sampled    bool
+	name       string
+	kind       SpanKind
+	start      time.Time
+	end        time.Time
+	mu         sync.Mutex
+	attributes map[string]interface{}
+	errMessage string
+	ended      bool
+}
+
+// Start begins a Span which is a child of the Span in ctx, or of the remote
+// parent extracted by Extract, or a new root
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	span := &Span{
+		tracer:     t,
+		sampled:    true,
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: map[string]interface{}{},
+	}
+	if parent, ok := ctx.Value(spanContextKey{}).(*Span); ok && parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+		span.sampled = parent.sampled
+	} else {
+		_, _ = rand.Read(span.traceID[:])
+	}
+	_, _ = rand.Read(span.spanID[:])
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// Extract returns a context carrying the remote parent from a W3C
+// traceparent header, as sent by a kube-apiserver with tracing enabled
+func Extract(ctx context.Context, header http.Header) context.Context {
+	remote, ok := parseTraceparent(header.Get(traceparentHeader))
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, spanContextKey{}, remote)
+}
+
+// parseTraceparent parses a version 00 traceparent header of the form
+// 00-<trace-id>-<parent-id>-<flags>
+func parseTraceparent(value string) (*Span, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return nil, false
+	}
+	remote := &Span{}
+	if _, err := hex.Decode(remote.traceID[:], []byte(parts[1])); err != nil {
+		return nil, false
+	}
+	if _, err := hex.Decode(remote.spanID[:], []byte(parts[2])); err != nil {
+		return nil, false
+	}
+	if remote.traceID == [16]byte{} || remote.spanID == [8]byte{} {
+		return nil, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return nil, false
+	}
+	remote.sampled = flags[0]&0x01 == 0x01
+	return remote, true
+}
+
+// SetAttribute records a string, bool, int or float64 attribute on the Span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes[key] = value
+}
+
+// SetError marks the Span as failed
+func (s *Span) SetError(message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errMessage = message
+}
+
+// End finishes the Span and queues it for export if it is sampled
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+	if s.sampled && s.tracer.exporter != nil {
+		s.tracer.exporter.export(s)
+	}
+}
+
+// parseHeaders parses the key=value,key2=value2 form of HeadersEnvVar
+func parseHeaders(value string) map[string]string {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		k, v, found := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !found || k == "" {
+			continue
+		}
+		headers[k] = strings.TrimSpace(v)
+	}
+	return headers
+}
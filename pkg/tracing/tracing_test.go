@@ -0,0 +1,188 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// recordingExporter collects exported Spans for assertions
+type recordingExporter struct {
+	spans []*Span
+}
+
+func (r *recordingExporter) export(span *Span) {
+	r.spans = append(r.spans, span)
+}
+
+func TestNilTracerIsNoop(t *testing.T) {
+	var tracer *Tracer
+	ctx, span := tracer.Start(context.Background(), "noop", SpanKindServer)
+	span.SetAttribute("webhook", "test")
+	span.SetError("boom")
+	span.End()
+	if ctx.Value(spanContextKey{}) != nil {
+		t.Fatalf("Expected a nil Tracer not to store a Span in the context")
+	}
+}
+
+func TestChildSpansShareTrace(t *testing.T) {
+	exp := &recordingExporter{}
+	tracer := &Tracer{exporter: exp}
+
+	ctx, root := tracer.Start(context.Background(), "root", SpanKindServer)
+	_, child := tracer.Start(ctx, "child", SpanKindInternal)
+	child.End()
+	root.End()
+
+	if len(exp.spans) != 2 {
+		t.Fatalf("Expected 2 exported spans, got %d", len(exp.spans))
+	}
+	if child.traceID != root.traceID {
+		t.Fatalf("Expected child trace ID %x to match root %x", child.traceID, root.traceID)
+	}
+	if child.parentID != root.spanID {
+		t.Fatalf("Expected child parent ID %x to be the root span ID %x", child.parentID, root.spanID)
+	}
+	if root.parentID != [8]byte{} {
+		t.Fatalf("Expected the root span to have no parent, got %x", root.parentID)
+	}
+}
+
+func TestExtractTraceparent(t *testing.T) {
+	tests := []struct {
+		testID         string
+		traceparent    string
+		expectRemote   bool
+		expectExported bool
+	}{
+		{
+			testID:         "sampled-parent",
+			traceparent:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expectRemote:   true,
+			expectExported: true,
+		},
+		{
+			testID:         "unsampled-parent",
+			traceparent:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			expectRemote:   true,
+			expectExported: false,
+		},
+		{
+			testID:         "zero-trace-id",
+			traceparent:    "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			expectExported: true,
+		},
+		{
+			testID:         "malformed",
+			traceparent:    "not-a-traceparent",
+			expectExported: true,
+		},
+		{
+			testID:         "missing",
+			expectExported: true,
+		},
+	}
+	for _, test := range tests {
+		exp := &recordingExporter{}
+		tracer := &Tracer{exporter: exp}
+		header := http.Header{}
+		if test.traceparent != "" {
+			header.Set(traceparentHeader, test.traceparent)
+		}
+
+		_, span := tracer.Start(Extract(context.Background(), header), "admission", SpanKindServer)
+		span.End()
+
+		remote := hex.EncodeToString(span.traceID[:]) == "4bf92f3577b34da6a3ce929d0e0e4736" &&
+			hex.EncodeToString(span.parentID[:]) == "00f067aa0ba902b7"
+		if remote != test.expectRemote {
+			t.Fatalf("%s: Expected remote parent %t, got trace %x parent %x", test.testID, test.expectRemote, span.traceID, span.parentID)
+		}
+		if (len(exp.spans) == 1) != test.expectExported {
+			t.Fatalf("%s: Expected exported %t, got %d spans", test.testID, test.expectExported, len(exp.spans))
+		}
+	}
+}
+
+func TestOTLPExport(t *testing.T) {
+	received := make(chan otlpRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("Expected a POST to /v1/traces, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Expected the configured Authorization header, got %q", r.Header.Get("Authorization"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		req := otlpRequest{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("Expected an OTLP JSON body, got %s: %v", body, err)
+		}
+		received <- req
+	}))
+	defer server.Close()
+
+	t.Setenv(EndpointEnvVar, server.URL)
+	t.Setenv(HeadersEnvVar, "Authorization=Bearer secret, X-Ignored")
+	t.Setenv(ServiceNameEnvVar, "webhooks-test")
+	tracer, err := NewTracerFromEnv()
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+
+	ctx, root := tracer.Start(context.Background(), "admission test-hook", SpanKindServer)
+	root.SetAttribute("webhook", "test-hook")
+	root.SetAttribute("allowed", false)
+	_, child := tracer.Start(ctx, "authorize", SpanKindInternal)
+	child.SetError("failed")
+	child.End()
+	root.End()
+	tracer.exporter.(*otlpExporter).close()
+
+	var req otlpRequest
+	select {
+	case req = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for the OTLP export")
+	}
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("Expected one resource and scope, got %+v", req)
+	}
+	if name := *req.ResourceSpans[0].Resource.Attributes[0].Value.StringValue; name != "webhooks-test" {
+		t.Fatalf("Expected service.name webhooks-test, got %s", name)
+	}
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Name != "authorize" || spans[0].Status == nil || spans[0].Status.Code != otlpStatusError {
+		t.Fatalf("Expected a failed authorize span, got %+v", spans[0])
+	}
+	if spans[1].Kind != SpanKindServer || spans[1].ParentSpanID != "" || len(spans[1].Attributes) != 2 {
+		t.Fatalf("Expected a root server span with 2 attributes, got %+v", spans[1])
+	}
+	if spans[0].ParentSpanID != spans[1].SpanID || spans[0].TraceID != spans[1].TraceID {
+		t.Fatalf("Expected authorize to be a child of the root span, got %+v", spans)
+	}
+}
+
+func TestNewTracerFromEnv(t *testing.T) {
+	t.Setenv(EndpointEnvVar, "")
+	t.Setenv(TracesEndpointEnvVar, "")
+	tracer, err := NewTracerFromEnv()
+	if err != nil || tracer != nil {
+		t.Fatalf("Expected tracing to be disabled without an endpoint, got %v, %v", tracer, err)
+	}
+
+	t.Setenv(TracesEndpointEnvVar, "collector:4318")
+	tracer, err = NewTracerFromEnv()
+	if err == nil || tracer != nil {
+		t.Fatalf("Expected an error for an endpoint without a scheme, got %v, %v", tracer, err)
+	}
+}
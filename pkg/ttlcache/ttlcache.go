@@ -0,0 +1,97 @@
+// Package ttlcache caches the results of lookups against the API server, e.g.
+// TokenReviews and SubjectAccessReviews, for a short time. Bursts of requests
+// from the same automation then make a single lookup rather than one each.
+package ttlcache
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+type entry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// Cache caches the values of keys for a TTL. Concurrent lookups of a key
+// share a single load. A nil Cache loads every value.
+type Cache[V any] struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]entry[V]
+	group   singleflight.Group
+}
+
+// New returns a Cache keeping values for ttl, and at most maxEntries of them
+func New[V any](ttl time.Duration, maxEntries int) *Cache[V] {
+	return &Cache[V]{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    map[string]entry[V]{},
+	}
+}
+
+// Get returns the cached value of key, or loads it. Errors aren't cached, so
+// a failed load is retried by the next Get. The load of concurrent Gets of a
+// key is the one of the first, which must not depend on the context of its
+// caller.
+func (c *Cache[V]) Get(key string, load func() (V, error)) (V, error) {
+	if c == nil {
+		return load()
+	}
+	if value, ok := c.lookup(key); ok {
+		return value, nil
+	}
+	value, err, _ := c.group.Do(key, func() (interface{}, error) {
+		value, err := load()
+		if err == nil {
+			c.store(key, value)
+		}
+		return value, err
+	})
+	return value.(V), err
+}
+
+// Len returns the number of cached values, including the expired ones not
+// evicted yet
+func (c *Cache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *Cache[V]) lookup(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || !c.now().Before(e.expires) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// store caches value, evicting the expired values when the cache is full. A
+// cache full of fresh values doesn't cache more of them.
+func (c *Cache[V]) store(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= c.maxEntries {
+			return
+		}
+	}
+	c.entries[key] = entry[V]{value: value, expires: now.Add(c.ttl)}
+}
@@ -0,0 +1,87 @@
+package ttlcache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGet(t *testing.T) {
+	now := time.Now()
+	c := New[string](10*time.Second, 2)
+	c.now = func() time.Time { return now }
+	loads := 0
+	load := func(value string) func() (string, error) {
+		return func() (string, error) {
+			loads++
+			return value, nil
+		}
+	}
+
+	if v, err := c.Get("a", load("a1")); err != nil || v != "a1" {
+		t.Fatalf("expected a1 to be loaded, got %q, %v", v, err)
+	}
+	if v, _ := c.Get("a", load("a2")); v != "a1" || loads != 1 {
+		t.Fatalf("expected a1 to be cached, got %q after %d loads", v, loads)
+	}
+	now = now.Add(10 * time.Second)
+	if v, _ := c.Get("a", load("a2")); v != "a2" || loads != 2 {
+		t.Fatalf("expected a1 to expire, got %q after %d loads", v, loads)
+	}
+
+	if _, err := c.Get("b", func() (string, error) { return "", errors.New("unavailable") }); err == nil {
+		t.Fatal("expected the error of the load")
+	}
+	if v, _ := c.Get("b", load("b1")); v != "b1" {
+		t.Fatalf("expected the error not to be cached, got %q", v)
+	}
+
+	// The cache is full of fresh values
+	c.Get("c", load("c1"))
+	if c.Len() != 2 {
+		t.Fatalf("expected at most 2 values to be cached, got %d", c.Len())
+	}
+	// Until they expire
+	now = now.Add(10 * time.Second)
+	c.Get("c", load("c1"))
+	if c.Len() != 1 {
+		t.Fatalf("expected the expired values to be evicted, got %d values", c.Len())
+	}
+}
+
+func TestGetSingleFlight(t *testing.T) {
+	c := New[int](time.Minute, 10)
+	var loads atomic.Int32
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.Get("user", func() (int, error) {
+				loads.Add(1)
+				<-release
+				return 42, nil
+			})
+			if err != nil || v != 42 {
+				t.Errorf("expected the shared value, got %d, %v", v, err)
+			}
+		}()
+	}
+	// Let the Gets pile up on the first load
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := loads.Load(); n != 1 {
+		t.Errorf("expected a single load, got %d", n)
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache[int]
+	if v, err := c.Get("a", func() (int, error) { return 1, nil }); err != nil || v != 1 {
+		t.Fatalf("expected a nil cache to load, got %d, %v", v, err)
+	}
+}
@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/namespacelabel"
+)
+
+func init() {
+	Register(namespacelabel.WebhookName, func() Webhook { return namespacelabel.NewWebhook() })
+}
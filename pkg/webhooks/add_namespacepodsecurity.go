@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/namespacepodsecurity"
+)
+
+func init() {
+	Register(namespacepodsecurity.WebhookName, func() Webhook { return namespacepodsecurity.NewWebhook() })
+}
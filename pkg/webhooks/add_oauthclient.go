@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/oauthclient"
+)
+
+func init() {
+	Register(oauthclient.WebhookName, func() Webhook { return oauthclient.NewWebhook() })
+}
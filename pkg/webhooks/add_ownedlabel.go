@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/ownedlabel"
+)
+
+func init() {
+	Register(ownedlabel.WebhookName, func() Webhook { return ownedlabel.NewWebhook() })
+}
@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/pdbrelax"
+)
+
+func init() {
+	Register(pdbrelax.WebhookName, func() Webhook { return pdbrelax.NewWebhook() })
+}
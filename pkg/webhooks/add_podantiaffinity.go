@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/podantiaffinity"
+)
+
+func init() {
+	Register(podantiaffinity.WebhookName, func() Webhook { return podantiaffinity.NewWebhook() })
+}
@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/podcostlabels"
+)
+
+func init() {
+	Register(podcostlabels.WebhookName, func() Webhook { return podcostlabels.NewWebhook() })
+}
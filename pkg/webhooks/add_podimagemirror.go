@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/podimagemirror"
+)
+
+func init() {
+	Register(podimagemirror.WebhookName, func() Webhook { return podimagemirror.NewWebhook() })
+}
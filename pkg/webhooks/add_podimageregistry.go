@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/podimageregistry"
+)
+
+func init() {
+	Register(podimageregistry.WebhookName, func() Webhook { return podimageregistry.NewWebhook() })
+}
@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/podnodeselector"
+)
+
+func init() {
+	Register(podnodeselector.WebhookName, func() Webhook { return podnodeselector.NewWebhook() })
+}
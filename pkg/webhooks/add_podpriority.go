@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/podpriority"
+)
+
+func init() {
+	Register(podpriority.WebhookName, func() Webhook { return podpriority.NewWebhook() })
+}
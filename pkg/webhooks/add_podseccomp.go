@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/podseccomp"
+)
+
+func init() {
+	Register(podseccomp.WebhookName, func() Webhook { return podseccomp.NewWebhook() })
+}
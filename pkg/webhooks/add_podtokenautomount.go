@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/podtokenautomount"
+)
+
+func init() {
+	Register(podtokenautomount.WebhookName, func() Webhook { return podtokenautomount.NewWebhook() })
+}
@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/podtoleration"
+)
+
+func init() {
+	Register(podtoleration.WebhookName, func() Webhook { return podtoleration.NewWebhook() })
+}
@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/podtolerationseconds"
+)
+
+func init() {
+	Register(podtolerationseconds.WebhookName, func() Webhook { return podtolerationseconds.NewWebhook() })
+}
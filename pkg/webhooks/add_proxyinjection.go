@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/proxyinjection"
+)
+
+func init() {
+	Register(proxyinjection.WebhookName, func() Webhook { return proxyinjection.NewWebhook() })
+}
@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/pullsecretinjection"
+)
+
+func init() {
+	Register(pullsecretinjection.WebhookName, func() Webhook { return pullsecretinjection.NewWebhook() })
+}
@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/routetls"
+)
+
+func init() {
+	Register(routetls.WebhookName, func() Webhook { return routetls.NewWebhook() })
+}
@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/sccpriority"
+)
+
+func init() {
+	Register(sccpriority.WebhookName, func() Webhook { return sccpriority.NewWebhook() })
+}
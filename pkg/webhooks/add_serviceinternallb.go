@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/serviceinternallb"
+)
+
+func init() {
+	Register(serviceinternallb.WebhookName, func() Webhook { return serviceinternallb.NewWebhook() })
+}
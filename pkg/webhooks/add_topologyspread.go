@@ -0,0 +1,9 @@
+package webhooks
+
+import (
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/topologyspread"
+)
+
+func init() {
+	Register(topologyspread.WebhookName, func() Webhook { return topologyspread.NewWebhook() })
+}
@@ -3,7 +3,6 @@ package clusterlogging
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"regexp"
 	"strconv"
 
@@ -11,7 +10,6 @@ import (
 	utils "github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -41,12 +39,15 @@ var (
 )
 
 type ClusterloggingWebhook struct {
-	s runtime.Scheme
+	s *utils.LazyScheme
 }
 
 // ObjectSelector implements Webhook interface
 func (s *ClusterloggingWebhook) ObjectSelector() *metav1.LabelSelector { return nil }
 
+// NamespaceSelector implements Webhook interface
+func (s *ClusterloggingWebhook) NamespaceSelector() *metav1.LabelSelector { return nil }
+
 func (s *ClusterloggingWebhook) Doc() string {
 	return docString
 }
@@ -104,7 +105,7 @@ func (r *retentionPolicyValidator) checkPolicy(retentionPolicy *cl.RetentionPoli
 		return false, admissionctl.Errored(http.StatusBadRequest, err)
 	}
 	if !isAllowed {
-		return false, admissionctl.Denied(deniedMessage)
+		return false, utils.Denied(utils.ReasonCLORetentionPolicy, deniedMessage)
 	}
 	return true, admissionctl.Allowed("Allowed to create ClusterLogging")
 }
@@ -134,9 +135,7 @@ func (r *retentionPolicyValidator) isAllowed(retentionPolicy *cl.RetentionPolicy
 
 // Authorized implements Webhook interface
 func (s *ClusterloggingWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
-	r := s.authorized(request)
-	r.UID = request.AdmissionRequest.UID
-	return r
+	return utils.WithUID(request, s.authorized(request))
 }
 
 func (s *ClusterloggingWebhook) authorized(request admissionctl.Request) admissionctl.Response {
@@ -187,7 +186,7 @@ func (s *ClusterloggingWebhook) authorized(request admissionctl.Request) admissi
 // If the request includes an OldObject (from an update or deletion), it will be
 // preferred, otherwise, the Object will be preferred.
 func (s *ClusterloggingWebhook) renderClusterLogging(request admissionctl.Request) (*cl.ClusterLogging, error) {
-	decoder, err := admissionctl.NewDecoder(&s.s)
+	decoder, err := s.s.Decoder()
 	if err != nil {
 		return nil, err
 	}
@@ -285,16 +284,12 @@ func (s *ClusterloggingWebhook) ClassicEnabled() bool { return true }
 
 func (s *ClusterloggingWebhook) HypershiftEnabled() bool { return false }
 
+// scheme registers the types the webhook decodes
+var scheme = utils.NewLazyScheme(cl.AddToScheme)
+
 // NewWebhook creates a new webhook
 func NewWebhook() *ClusterloggingWebhook {
-	scheme := runtime.NewScheme()
-	err := cl.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding cluster-logging scheme to ClusterloggingWebhook")
-		os.Exit(1)
-	}
-
 	return &ClusterloggingWebhook{
-		s: *scheme,
+		s: scheme,
 	}
 }
@@ -3,18 +3,16 @@ package clusterrolebinding
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"regexp"
 	"slices"
 	"strings"
 
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
-	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -51,34 +49,18 @@ var (
 		"openshift-gitops",
 	}
 
-	allowedUsers = []string{
-		"backplane-cluster-admin",
-	}
-	allowedGroups = []string{
-		"system:serviceaccounts:openshift-backplane-srep",
-	}
+	allowedUsers  = hookconfig.SREAdminUsers
+	allowedGroups = hookconfig.SREAdminGroups
 )
 
 type ClusterRoleBindingWebHook struct {
-	s runtime.Scheme
+	s *utils.LazyScheme
 }
 
 // NewWebhook creates the new webhook
 func NewWebhook() *ClusterRoleBindingWebHook {
-	scheme := runtime.NewScheme()
-	err := admissionv1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding admissionsv1 scheme to ClusterRoleBindingWebHook")
-		os.Exit(1)
-	}
-	err = corev1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding corev1 scheme to ClusterRoleBindingWebHook")
-		os.Exit(1)
-	}
-
 	return &ClusterRoleBindingWebHook{
-		s: *scheme,
+		s: utils.CoreScheme,
 	}
 }
 
@@ -88,23 +70,15 @@ func (s *ClusterRoleBindingWebHook) Authorized(request admissionctl.Request) adm
 }
 
 func (s *ClusterRoleBindingWebHook) authorized(request admissionctl.Request) admissionctl.Response {
-	var ret admissionctl.Response
-
 	if request.AdmissionRequest.UserInfo.Username == "system:unauthenticated" {
-		log.Info("system:unauthenticated made a webhook request. Check RBAC rules", "request", request.AdmissionRequest)
-		ret = admissionctl.Denied("Unauthenticated")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		log.Info("system:unauthenticated made a webhook request. Check RBAC rules", "request", utils.RedactRequest(request.AdmissionRequest))
+		return utils.Deny(request, utils.ReasonCRBUnauthenticated, "Unauthenticated")
 	}
 	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "system:") {
-		ret = admissionctl.Allowed("authenticated system: users are allowed")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "authenticated system: users are allowed")
 	}
 	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "kube:") {
-		ret = admissionctl.Allowed("kube: users are allowed")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "kube: users are allowed")
 	}
 
 	clusterRoleBinding, err := s.renderClusterRoleBinding(request)
@@ -122,25 +96,19 @@ func (s *ClusterRoleBindingWebHook) authorized(request admissionctl.Request) adm
 
 			annotations := clusterRoleBinding.GetObjectMeta().GetAnnotations()
 			if annotations["oc.openshift.io/command"] == "oc adm must-gather" && request.AdmissionRequest.UserInfo.Username == "cluster-admin" {
-				ret = admissionctl.Allowed("cluster-admin: cluster-admin may manage must-gather resources")
-				ret.UID = request.AdmissionRequest.UID
-				return ret
+				return utils.Allow(request, "cluster-admin: cluster-admin may manage must-gather resources")
 			}
 
-			ret = admissionctl.Denied(fmt.Sprintf("Deleting ClusterRoleBinding %v is not allowed", clusterRoleBinding.Name))
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Deny(request, utils.ReasonCRBProtectedDelete, fmt.Sprintf("Deleting ClusterRoleBinding %v is not allowed", clusterRoleBinding.Name))
 		}
 	}
 
-	ret = admissionctl.Allowed("Request is allowed")
-	ret.UID = request.AdmissionRequest.UID
-	return ret
+	return utils.Allow(request, "Request is allowed")
 }
 
 // renderSCC render the SCC object from the requests
 func (s *ClusterRoleBindingWebHook) renderClusterRoleBinding(request admissionctl.Request) (*rbacv1.ClusterRoleBinding, error) {
-	decoder, err := admissionctl.NewDecoder(&s.s)
+	decoder, err := s.s.Decoder()
 	if err != nil {
 		return nil, err
 	}
@@ -170,11 +138,13 @@ func isAllowedUserGroup(request admissionctl.Request) bool {
 }
 
 // isProtectedNamespace returns true if clusterRoleBinding subject link
-// to ServiceAccount and openshift-*|kube-system ns
+// to ServiceAccount and openshift-*|kube-system ns, or a namespace added with
+// hookconfig.ProtectedNamespacesEnvVar
 func isProtectedNamespace(clusterRoleBinding *rbacv1.ClusterRoleBinding) bool {
 	for _, subject := range clusterRoleBinding.Subjects {
 		if subject.Kind == "ServiceAccount" {
-			if protectedNamespaces.Match([]byte(subject.Namespace)) && !slices.Contains(exceptionNamespaces, subject.Namespace) {
+			protected := protectedNamespaces.Match([]byte(subject.Namespace)) || hookconfig.IsAdditionalProtectedNamespace(subject.Namespace)
+			if protected && !slices.Contains(exceptionNamespaces, subject.Namespace) {
 				return true
 			}
 		}
@@ -222,6 +192,11 @@ func (s *ClusterRoleBindingWebHook) ObjectSelector() *metav1.LabelSelector {
 	return nil
 }
 
+// NamespaceSelector implements Webhook interface
+func (s *ClusterRoleBindingWebHook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
 // SideEffects implements Webhook interface
 func (s *ClusterRoleBindingWebHook) SideEffects() admissionregv1.SideEffectClass {
 	return admissionregv1.SideEffectClassNone
@@ -0,0 +1,51 @@
+package webhooks
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+// ComplianceFilter is implemented by webhooks which are only enabled under
+// some compliance profiles
+type ComplianceFilter interface {
+	// EnabledForCompliance returns whether the webhook applies under
+	// compliance
+	EnabledForCompliance(compliance utils.Compliance) bool
+}
+
+// ComplianceSelector is implemented by webhooks which apply to more clusters
+// under some compliance profiles
+type ComplianceSelector interface {
+	// SyncSetLabelSelectorForCompliance returns the SyncSet label selector
+	// of the webhook under compliance
+	SyncSetLabelSelectorForCompliance(compliance utils.Compliance) metav1.LabelSelector
+}
+
+// EnabledForCompliance returns whether hook applies under compliance
+func EnabledForCompliance(hook Webhook, compliance utils.Compliance) bool {
+	if filter, ok := hook.(ComplianceFilter); ok {
+		return filter.EnabledForCompliance(compliance)
+	}
+	return true
+}
+
+// SyncSetLabelSelector returns the SyncSet label selector of hook under
+// compliance
+func SyncSetLabelSelector(hook Webhook, compliance utils.Compliance) metav1.LabelSelector {
+	if variant, ok := hook.(ComplianceSelector); ok {
+		return variant.SyncSetLabelSelectorForCompliance(compliance)
+	}
+	return hook.SyncSetLabelSelector()
+}
+
+// ForCompliance returns the webhooks of r which apply under compliance
+func (r RegisteredWebhooks) ForCompliance(compliance utils.Compliance) RegisteredWebhooks {
+	hooks := RegisteredWebhooks{}
+	for name, factory := range r {
+		if EnabledForCompliance(factory(), compliance) {
+			hooks[name] = factory
+		}
+	}
+	return hooks
+}
@@ -7,12 +7,11 @@ import (
 	"slices"
 	"strings"
 
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
-	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -24,9 +23,9 @@ const (
 
 var (
 	timeout                          int32 = 2
-	allowedUsers                           = []string{"system:admin", "backplane-cluster-admin"}
-	sreAdminGroups                         = []string{"system:serviceaccounts:openshift-backplane-srep"}
-	privilegedServiceAccountGroupsRe       = regexp.MustCompile(utils.PrivilegedServiceAccountGroups)
+	allowedUsers                           = hookconfig.Identities(hookconfig.PlatformAdminUsersFor(WebhookName), hookconfig.SREAdminUsers)
+	sreAdminGroups                         = hookconfig.SREAdminGroups
+	privilegedServiceAccountGroupsRe       = regexp.MustCompile(hookconfig.PrivilegedServiceAccountGroups)
 	scope                                  = admissionregv1.ClusterScope
 	rules                                  = []admissionregv1.RuleWithOperations{
 		{
@@ -47,16 +46,11 @@ var (
 )
 
 // customresourcedefinitionsruleWebhook validates a customresourcedefinition change
-type customresourcedefinitionsruleWebhook struct {
-	s runtime.Scheme
-}
+type customresourcedefinitionsruleWebhook struct{}
 
 // NewWebhook creates the new webhook
 func NewWebhook() *customresourcedefinitionsruleWebhook {
-	scheme := runtime.NewScheme()
-	return &customresourcedefinitionsruleWebhook{
-		s: *scheme,
-	}
+	return &customresourcedefinitionsruleWebhook{}
 }
 
 // Authorized implements Webhook interface
@@ -66,38 +60,34 @@ func (s *customresourcedefinitionsruleWebhook) Authorized(request admissionctl.R
 }
 
 func (s *customresourcedefinitionsruleWebhook) authorized(request admissionctl.Request) admissionctl.Response {
-	var ret admissionctl.Response
-
-	crd, err := s.renderCustomResourceDefinition(request)
+	// The name is all the webhook needs of the CustomResourceDefinition, whose
+	// schemas make it costly to decode
+	raw := request.Object.Raw
+	if len(request.OldObject.Raw) > 0 {
+		raw = request.OldObject.Raw
+	}
+	name, err := utils.StringField(raw, "metadata", "name")
 	if err != nil {
-		log.Error(err, "Could not render a CustomResourceDefinition from the incoming request")
+		log.Error(err, "Could not read the name of the CustomResourceDefinition of the incoming request")
 		return admissionctl.Errored(http.StatusBadRequest, err)
 	}
 
-	if utils.IsProtectedByResourceName(crd.GetName()) {
-		log.Info(fmt.Sprintf("%s operation detected on protected CustomResourceDefinition: %s", request.Operation, crd.Name))
+	if utils.IsProtectedByResourceName(name) {
+		log.Info(fmt.Sprintf("%s operation detected on protected CustomResourceDefinition: %s", request.Operation, name))
 		if isAllowedUser(request) {
-			ret = admissionctl.Allowed(fmt.Sprintf("User '%s' in group(s) '%s' can operate on CustomResourceDefinitions", request.UserInfo.Username, strings.Join(request.UserInfo.Groups, ", ")))
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Allow(request, fmt.Sprintf("User '%s' in group(s) '%s' can operate on CustomResourceDefinitions", request.UserInfo.Username, strings.Join(request.UserInfo.Groups, ", ")))
 		}
 		for _, group := range request.UserInfo.Groups {
 			if privilegedServiceAccountGroupsRe.Match([]byte(group)) {
-				ret = admissionctl.Allowed(fmt.Sprintf("Privileged service accounts in group(s) '%s' can operate on CustomResourceDefinitions", strings.Join(request.UserInfo.Groups, ", ")))
-				ret.UID = request.AdmissionRequest.UID
-				return ret
+				return utils.Allow(request, fmt.Sprintf("Privileged service accounts in group(s) '%s' can operate on CustomResourceDefinitions", strings.Join(request.UserInfo.Groups, ", ")))
 			}
 		}
 
-		ret = admissionctl.Denied(fmt.Sprintf("User '%s' prevented from accessing Red Mat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support", request.UserInfo.Username))
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Deny(request, utils.ReasonCRDManagedResource, fmt.Sprintf("User '%s' prevented from accessing Red Mat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support", request.UserInfo.Username))
 	}
 
-	log.Info("Allowing access", "request", request.AdmissionRequest)
-	ret = admissionctl.Allowed("Non managed CustomResourceDefinition")
-	ret.UID = request.AdmissionRequest.UID
-	return ret
+	log.Info("Allowing access", "request", utils.RedactRequest(request.AdmissionRequest))
+	return utils.Allow(request, "Non managed CustomResourceDefinition")
 }
 
 // isAllowedUser checks if the user or group is allowed to perform the action
@@ -115,24 +105,6 @@ func isAllowedUser(request admissionctl.Request) bool {
 	return false
 }
 
-func (s *customresourcedefinitionsruleWebhook) renderCustomResourceDefinition(req admissionctl.Request) (*apiextensionsv1.CustomResourceDefinition, error) {
-	decoder, err := admissionctl.NewDecoder(&s.s)
-	if err != nil {
-		return nil, err
-	}
-	customResourceDefinition := &apiextensionsv1.CustomResourceDefinition{}
-
-	if len(req.OldObject.Raw) > 0 {
-		err = decoder.DecodeRaw(req.OldObject, customResourceDefinition)
-	} else {
-		err = decoder.Decode(req, customResourceDefinition)
-	}
-	if err != nil {
-		return nil, err
-	}
-	return customResourceDefinition, nil
-}
-
 // GetURI implements Webhook interface
 func (s *customresourcedefinitionsruleWebhook) GetURI() string {
 	return "/" + WebhookName
@@ -172,6 +144,11 @@ func (s *customresourcedefinitionsruleWebhook) ObjectSelector() *metav1.LabelSel
 	return nil
 }
 
+// NamespaceSelector implements Webhook interface
+func (s *customresourcedefinitionsruleWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
 // SideEffects implements Webhook interface
 func (s *customresourcedefinitionsruleWebhook) SideEffects() admissionregv1.SideEffectClass {
 	return admissionregv1.SideEffectClassNone
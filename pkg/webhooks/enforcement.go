@@ -0,0 +1,32 @@
+package webhooks
+
+import (
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+)
+
+// EnforcementModeAnnotation records the EnforcementMode of a webhook on its
+// generated webhook configuration, so the mode shipped to the fleet can be
+// read off the cluster
+const EnforcementModeAnnotation = "managed.openshift.io/enforcement-mode"
+
+// EnforcementModer is implemented by webhooks which don't enforce by default,
+// e.g. new webhooks rolled out in hookconfig.EnforcementAudit to find what
+// they would deny before they do
+type EnforcementModer interface {
+	// EnforcementMode returns whether the webhook denies requests or only
+	// reports them
+	EnforcementMode() hookconfig.EnforcementMode
+}
+
+// EnforcementMode returns the EnforcementMode of hook. The
+// hookconfig.WebhookEnforcement configured for hook takes precedence over its
+// EnforcementModer, and webhooks enforce by default.
+func EnforcementMode(hook Webhook) hookconfig.EnforcementMode {
+	if mode, configured := hookconfig.WebhookEnforcementMode(hook.Name()); configured {
+		return mode
+	}
+	if moder, ok := hook.(EnforcementModer); ok {
+		return moder.EnforcementMode()
+	}
+	return hookconfig.EnforcementEnforce
+}
@@ -1,15 +1,14 @@
 package hiveownership
 
 import (
-	"os"
 	"slices"
 	"sync"
 
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	admissionv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -24,12 +23,12 @@ const (
 // if it made by a customer to manage hive-labeled resources
 type HiveOwnershipWebhook struct {
 	mu sync.Mutex
-	s  runtime.Scheme
+	s  *utils.LazyScheme
 }
 
 var (
-	privilegedUsers = []string{"kube:admin", "system:admin", "system:serviceaccount:kube-system:generic-garbage-collector", "backplane-cluster-admin"}
-	adminGroups     = []string{"system:serviceaccounts:openshift-backplane-srep"}
+	privilegedUsers = hookconfig.Identities(hookconfig.KubeAdminUsersFor(WebhookName), hookconfig.PlatformAdminUsersFor(WebhookName), []string{"system:serviceaccount:kube-system:generic-garbage-collector"}, hookconfig.SREAdminUsers)
+	adminGroups     = hookconfig.SREAdminGroups
 
 	log = logf.Log.WithName(WebhookName)
 
@@ -97,27 +96,24 @@ func (s *HiveOwnershipWebhook) ObjectSelector() *metav1.LabelSelector {
 	}
 }
 
-func (s *HiveOwnershipWebhook) authorized(request admissionctl.Request) admissionctl.Response {
-	var ret admissionctl.Response
+// NamespaceSelector implements Webhook interface
+func (s *HiveOwnershipWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
 
+func (s *HiveOwnershipWebhook) authorized(request admissionctl.Request) admissionctl.Response {
 	// Admin users
 	if slices.Contains(privilegedUsers, request.AdmissionRequest.UserInfo.Username) {
-		ret = admissionctl.Allowed("Admin users may edit managed resources")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "Admin users may edit managed resources")
 	}
 	// Users in admin groups
 	for _, group := range request.AdmissionRequest.UserInfo.Groups {
 		if slices.Contains(adminGroups, group) {
-			ret = admissionctl.Allowed("Members of admin group may edit managed resources")
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Allow(request, "Members of admin group may edit managed resources")
 		}
 	}
 
-	ret = admissionctl.Denied("Prevented from accessing Red Hat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
-	ret.UID = request.AdmissionRequest.UID
-	return ret
+	return utils.Deny(request, utils.ReasonHiveManagedResource, "Prevented from accessing Red Hat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
 }
 
 // Authorized implements Webhook interface
@@ -134,16 +130,12 @@ func (s *HiveOwnershipWebhook) ClassicEnabled() bool { return true }
 
 func (s *HiveOwnershipWebhook) HypershiftEnabled() bool { return false }
 
+// scheme registers the types the webhook decodes
+var scheme = utils.NewLazyScheme(admissionv1.AddToScheme)
+
 // NewWebhook creates a new webhook
 func NewWebhook() *HiveOwnershipWebhook {
-	scheme := runtime.NewScheme()
-	err := admissionv1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding admissionsv1 scheme to HiveOwnershipWebhook")
-		os.Exit(1)
-	}
-
 	return &HiveOwnershipWebhook{
-		s: *scheme,
+		s: scheme,
 	}
 }
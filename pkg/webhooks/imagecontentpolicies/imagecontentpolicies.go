@@ -10,7 +10,6 @@ import (
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -24,20 +23,24 @@ const (
 	unauthorizedRepositoryMirrors = `(^registry\.redhat\.io$|^quay\.io(/.*)?$|^registry\.access\.redhat\.com(/.*)?)`
 )
 
+// unauthorizedRepositoryMirrorsRe is unauthorizedRepositoryMirrors, compiled
+// once rather than for every request
+var unauthorizedRepositoryMirrorsRe = regexp.MustCompile(unauthorizedRepositoryMirrors)
+
 type ImageContentPoliciesWebhook struct {
-	scheme *runtime.Scheme
+	scheme *utils.LazyScheme
 	log    logr.Logger
 }
 
 func NewWebhook() *ImageContentPoliciesWebhook {
 	return &ImageContentPoliciesWebhook{
-		scheme: runtime.NewScheme(),
+		scheme: utils.EmptyScheme,
 		log:    logf.Log.WithName(WebhookName),
 	}
 }
 
 func (w *ImageContentPoliciesWebhook) Authorized(request admission.Request) admission.Response {
-	decoder, err := admission.NewDecoder(w.scheme)
+	decoder, err := w.scheme.Decoder()
 	if err != nil {
 		return admission.Errored(http.StatusBadRequest, err)
 	}
@@ -149,6 +152,10 @@ func (w *ImageContentPoliciesWebhook) ObjectSelector() *metav1.LabelSelector {
 	return nil
 }
 
+func (w *ImageContentPoliciesWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
 func (w *ImageContentPoliciesWebhook) SideEffects() admissionregv1.SideEffectClass {
 	return admissionregv1.SideEffectClassNone
 }
@@ -175,7 +182,6 @@ func (w *ImageContentPoliciesWebhook) HypershiftEnabled() bool {
 
 // authorizeImageDigestMirrorSet should reject an ImageDigestMirrorSet that matches an unauthorized mirror list
 func authorizeImageDigestMirrorSet(idms configv1.ImageDigestMirrorSet) bool {
-	unauthorizedRepositoryMirrorsRe := regexp.MustCompile(unauthorizedRepositoryMirrors)
 	for _, mirror := range idms.Spec.ImageDigestMirrors {
 		if unauthorizedRepositoryMirrorsRe.Match([]byte(mirror.Source)) {
 			return false
@@ -187,7 +193,6 @@ func authorizeImageDigestMirrorSet(idms configv1.ImageDigestMirrorSet) bool {
 
 // authorizeImageTagMirrorSet should reject an ImageTagMirrorSet that matches an unauthorized mirror list
 func authorizeImageTagMirrorSet(itms configv1.ImageTagMirrorSet) bool {
-	unauthorizedRepositoryMirrorsRe := regexp.MustCompile(unauthorizedRepositoryMirrors)
 	for _, mirror := range itms.Spec.ImageTagMirrors {
 		if unauthorizedRepositoryMirrorsRe.Match([]byte(mirror.Source)) {
 			return false
@@ -199,7 +204,6 @@ func authorizeImageTagMirrorSet(itms configv1.ImageTagMirrorSet) bool {
 
 // authorizeImageContentSourcePolicy should reject an ImageContentSourcePolicy that matches an unauthorized mirror list
 func authorizeImageContentSourcePolicy(icsp operatorv1alpha1.ImageContentSourcePolicy) bool {
-	unauthorizedRepositoryMirrorsRe := regexp.MustCompile(unauthorizedRepositoryMirrors)
 	for _, mirror := range icsp.Spec.RepositoryDigestMirrors {
 		if unauthorizedRepositoryMirrorsRe.Match([]byte(mirror.Source)) {
 			return false
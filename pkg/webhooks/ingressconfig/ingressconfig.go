@@ -1,31 +1,27 @@
 package ingressconfig
 
 import (
-	"os"
 	"regexp"
+	"slices"
 	"sync"
 
-	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
-	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 )
 
 const (
-	WebhookName     string = "ingress-config-validation"
-	privilegedUsers string = `system:admin`
-	docString       string = `Managed OpenShift customers may not modify ingress config resources because it can can degrade cluster operators and can interfere with OpenShift SRE monitoring.`
+	WebhookName string = "ingress-config-validation"
+	docString   string = `Managed OpenShift customers may not modify ingress config resources because it can can degrade cluster operators and can interfere with OpenShift SRE monitoring.`
 )
 
 var (
 	log                         = logf.Log.WithName(WebhookName)
-	privilegedServiceAccountsRe = regexp.MustCompile(utils.PrivilegedServiceAccountGroups)
-	privilegedUsersRe           = regexp.MustCompile(privilegedUsers)
+	privilegedServiceAccountsRe = regexp.MustCompile(hookconfig.PrivilegedServiceAccountGroups)
 
 	scope = admissionregv1.ClusterScope
 	rules = []admissionregv1.RuleWithOperations{
@@ -43,26 +39,23 @@ var (
 
 type IngressConfigWebhook struct {
 	mu sync.Mutex
-	s  runtime.Scheme
+	s  *utils.LazyScheme
 }
 
 // Authorized will determine if the request is allowed
 func (w *IngressConfigWebhook) Authorized(request admissionctl.Request) (ret admissionctl.Response) {
-	ret = admissionctl.Denied("Only privileged service accounts may access")
-	ret.UID = request.AdmissionRequest.UID
+	ret = utils.Deny(request, utils.ReasonIngressConfigUnprivileged, "Only privileged service accounts may access")
 
 	// allow if modified by an allowlist-ed service account
 	for _, group := range request.UserInfo.Groups {
 		if privilegedServiceAccountsRe.Match([]byte(group)) {
-			ret = admissionctl.Allowed("Privileged service accounts may access")
-			ret.UID = request.AdmissionRequest.UID
+			ret = utils.Allow(request, "Privileged service accounts may access")
 		}
 	}
 
 	// allow if modified by an allowliste-ed user
-	if privilegedUsersRe.Match([]byte(request.UserInfo.Username)) {
-		ret = admissionctl.Allowed("Privileged service accounts may access")
-		ret.UID = request.AdmissionRequest.UID
+	if slices.Contains(hookconfig.PlatformAdminUsersFor(WebhookName), request.UserInfo.Username) {
+		ret = utils.Allow(request, "Privileged service accounts may access")
 	}
 
 	return
@@ -103,6 +96,8 @@ func (w *IngressConfigWebhook) Rules() []admissionregv1.RuleWithOperations { ret
 // LabelSelector.
 func (w *IngressConfigWebhook) ObjectSelector() *metav1.LabelSelector { return nil }
 
+func (w *IngressConfigWebhook) NamespaceSelector() *metav1.LabelSelector { return nil }
+
 // SideEffects are what side effects, if any, this hook has. Refer to
 // https://kubernetes.io/docs/reference/access-authn-authz/extensible-admission-controllers/#side-effects
 func (w *IngressConfigWebhook) SideEffects() admissionregv1.SideEffectClass {
@@ -128,20 +123,7 @@ func (w *IngressConfigWebhook) HypershiftEnabled() bool { return true }
 
 // NewWebhook creates a new webhook
 func NewWebhook() *IngressConfigWebhook {
-	scheme := runtime.NewScheme()
-	err := admissionv1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding admissionsv1 scheme to IngressConfigWebhook")
-		os.Exit(1)
-	}
-
-	err = corev1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding corev1 scheme to IngressConfigWebhook")
-		os.Exit(1)
-	}
-
 	return &IngressConfigWebhook{
-		s: *scheme,
+		s: utils.CoreScheme,
 	}
 }
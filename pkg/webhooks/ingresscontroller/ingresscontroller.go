@@ -7,10 +7,10 @@ import (
 	"strings"
 
 	operatorv1 "github.com/openshift/api/operator/v1"
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -36,18 +36,19 @@ var (
 			},
 		},
 	}
-	allowedUsers = []string{
-		"backplane-cluster-admin",
-	}
+	allowedUsers = hookconfig.SREAdminUsers
 )
 
 type IngressControllerWebhook struct {
-	s runtime.Scheme
+	s *utils.LazyScheme
 }
 
 // ObjectSelector implements Webhook interface
 func (wh *IngressControllerWebhook) ObjectSelector() *metav1.LabelSelector { return nil }
 
+// NamespaceSelector implements Webhook interface
+func (wh *IngressControllerWebhook) NamespaceSelector() *metav1.LabelSelector { return nil }
+
 func (wh *IngressControllerWebhook) Doc() string {
 	return fmt.Sprintf(docString)
 }
@@ -91,7 +92,7 @@ func (wh *IngressControllerWebhook) Validate(req admissionctl.Request) bool {
 }
 
 func (wh *IngressControllerWebhook) renderIngressController(req admissionctl.Request) (*operatorv1.IngressController, error) {
-	decoder, err := admissionctl.NewDecoder(&wh.s)
+	decoder, err := wh.s.Decoder()
 	if err != nil {
 		return nil, err
 	}
@@ -106,7 +107,6 @@ func (wh *IngressControllerWebhook) renderIngressController(req admissionctl.Req
 }
 
 func (wh *IngressControllerWebhook) authorized(request admissionctl.Request) admissionctl.Response {
-	var ret admissionctl.Response
 	ic, err := wh.renderIngressController(request)
 	if err != nil {
 		log.Error(err, "Couldn't render an IngressController from the incoming request")
@@ -117,42 +117,30 @@ func (wh *IngressControllerWebhook) authorized(request admissionctl.Request) adm
 	if request.AdmissionRequest.UserInfo.Username == "system:unauthenticated" {
 		// This could highlight a significant problem with RBAC since an
 		// unauthenticated user should have no permissions.
-		log.Info("system:unauthenticated made a webhook request. Check RBAC rules", "request", request.AdmissionRequest)
-		ret = admissionctl.Denied("Unauthenticated")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		log.Info("system:unauthenticated made a webhook request. Check RBAC rules", "request", utils.RedactRequest(request.AdmissionRequest))
+		return utils.Deny(request, utils.ReasonIngressControllerUnauthenticated, "Unauthenticated")
 	}
 
 	log.Info("Checking if user is authenticated system: user")
 	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "system:") {
-		ret = admissionctl.Allowed("authenticated system: users are allowed")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "authenticated system: users are allowed")
 	}
 
 	log.Info("Checking if user is kube: user")
 	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "kube:") {
-		ret = admissionctl.Allowed("kube: users are allowed")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "kube: users are allowed")
 	}
 
 	// Check if the group does not have exceptions
 	if !isAllowedUser(request) {
 		for _, toleration := range ic.Spec.NodePlacement.Tolerations {
 			if strings.Contains(toleration.Key, "node-role.kubernetes.io/master") {
-				ret = admissionctl.Denied("Not allowed to provision ingress controller pods with toleration for master nodes.")
-				ret.UID = request.AdmissionRequest.UID
-
-				return ret
+				return utils.Deny(request, utils.ReasonIngressControllerMasterToleration, "Not allowed to provision ingress controller pods with toleration for master nodes.")
 			}
 		}
 	}
 
-	ret = admissionctl.Allowed("IngressController operation is allowed")
-	ret.UID = request.AdmissionRequest.UID
-
-	return ret
+	return utils.Allow(request, "IngressController operation is allowed")
 }
 
 // isAllowedUser checks if the user is allowed to perform the action
@@ -198,8 +186,7 @@ func (s *IngressControllerWebhook) HypershiftEnabled() bool { return false }
 
 // NewWebhook creates a new webhook
 func NewWebhook() *IngressControllerWebhook {
-	scheme := runtime.NewScheme()
 	return &IngressControllerWebhook{
-		s: *scheme,
+		s: utils.EmptyScheme,
 	}
 }
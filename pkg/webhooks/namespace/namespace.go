@@ -3,7 +3,6 @@ package namespace
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"regexp"
 	"slices"
 	"sync"
@@ -14,18 +13,15 @@ import (
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 const (
-	WebhookName                  string = "namespace-validation"
-	badNamespace                 string = `(^com$|^io$|^in$)`
-	layeredProductNamespace      string = `^redhat-.*`
-	layeredProductAdminGroupName string = "layered-sre-cluster-admins"
-	docString                    string = `Managed OpenShift Customers may not modify namespaces specified in the %v ConfigMaps because customer workloads should be placed in customer-created namespaces. Customers may not create namespaces identified by this regular expression %s because it could interfere with critical DNS resolution. Additionally, customers may not set or change the values of these Namespace labels %s.`
-	clusterAdminGroup            string = "cluster-admins"
+	WebhookName             string = "namespace-validation"
+	badNamespace            string = `(^com$|^io$|^in$)`
+	layeredProductNamespace string = `^redhat-.*`
+	docString               string = `Managed OpenShift Customers may not modify namespaces specified in the %v ConfigMaps because customer workloads should be placed in customer-created namespaces. Customers may not create namespaces identified by this regular expression %s because it could interfere with critical DNS resolution. Additionally, customers may not set or change the values of these Namespace labels %s.`
 )
 
 // exported vars to be used across packages
@@ -34,9 +30,9 @@ var (
 )
 
 var (
-	clusterAdminUsers           = []string{"kube:admin", "system:admin", "backplane-cluster-admin"}
-	sreAdminGroups              = []string{"system:serviceaccounts:openshift-backplane-srep"}
-	privilegedServiceAccountsRe = regexp.MustCompile(utils.PrivilegedServiceAccountGroups)
+	clusterAdminUsers           = hookconfig.Identities(hookconfig.KubeAdminUsersFor(WebhookName), hookconfig.PlatformAdminUsersFor(WebhookName), hookconfig.SREAdminUsers)
+	sreAdminGroups              = hookconfig.SREAdminGroups
+	privilegedServiceAccountsRe = regexp.MustCompile(hookconfig.PrivilegedServiceAccountGroups)
 	layeredProductNamespaceRe   = regexp.MustCompile(layeredProductNamespace)
 	// protectedLabels are labels which managed customers should not be allowed
 	// change by dedicated-admins.
@@ -44,6 +40,8 @@ var (
 		// https://github.com/openshift/managed-cluster-config/tree/master/deploy/resource-quotas
 		"managed.openshift.io/storage-pv-quota-exempt",
 		"managed.openshift.io/service-lb-quota-exempt",
+		// exemption.ExemptionLabel exempts add-on namespaces from webhooks
+		"managed.openshift.io/webhook-exempt",
 	}
 
 	log = logf.Log.WithName(WebhookName)
@@ -65,12 +63,15 @@ var (
 // NamespaceWebhook validates a Namespace change
 type NamespaceWebhook struct {
 	mu sync.Mutex
-	s  runtime.Scheme
+	s  *utils.LazyScheme
 }
 
 // ObjectSelector implements Webhook interface
 func (s *NamespaceWebhook) ObjectSelector() *metav1.LabelSelector { return nil }
 
+// NamespaceSelector implements Webhook interface
+func (s *NamespaceWebhook) NamespaceSelector() *metav1.LabelSelector { return nil }
+
 func (s *NamespaceWebhook) Doc() string {
 	return fmt.Sprintf(docString, hookconfig.ConfigMapSources, badNamespace, protectedLabels)
 }
@@ -126,7 +127,7 @@ func (s *NamespaceWebhook) Validate(req admissionctl.Request) bool {
 // (request.OldObject) objects returned. See the renderOldAndNewNamespaces
 // documentation for more.
 func (s *NamespaceWebhook) renderNamespace(req admissionctl.Request) (*corev1.Namespace, error) {
-	decoder, err := admissionctl.NewDecoder(&s.s)
+	decoder, err := s.s.Decoder()
 	if err != nil {
 		return nil, err
 	}
@@ -151,7 +152,7 @@ func (s *NamespaceWebhook) renderNamespace(req admissionctl.Request) (*corev1.Na
 // If there is no corresponding namespace, this method will return nil in the
 // appropriate position.
 func (s *NamespaceWebhook) renderOldAndNewNamespaces(req admissionctl.Request) (*corev1.Namespace, *corev1.Namespace, error) {
-	decoder, err := admissionctl.NewDecoder(&s.s)
+	decoder, err := s.s.Decoder()
 	if err != nil {
 		return nil, nil, err
 	}
@@ -185,8 +186,6 @@ func (s *NamespaceWebhook) Authorized(request admissionctl.Request) admissionctl
 
 // Is the request authorized?
 func (s *NamespaceWebhook) authorized(request admissionctl.Request) admissionctl.Response {
-	var ret admissionctl.Response
-
 	// Picking OldObject or Object will suffice for most validation concerns
 	ns, err := s.renderNamespace(request)
 	if err != nil {
@@ -196,55 +195,39 @@ func (s *NamespaceWebhook) authorized(request admissionctl.Request) admissionctl
 	// service accounts making requests will include their name in the group
 	for _, group := range request.UserInfo.Groups {
 		if privilegedServiceAccountsRe.Match([]byte(group)) {
-			ret = admissionctl.Allowed("Privileged service accounts may access")
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Allow(request, "Privileged service accounts may access")
 		}
 	}
 	// This must be prior to privileged namespace check
-	if slices.Contains(request.UserInfo.Groups, layeredProductAdminGroupName) &&
+	if hookconfig.IsMember(request.UserInfo.Groups, hookconfig.LayeredProductAdminGroups) &&
 		layeredProductNamespaceRe.Match([]byte(ns.GetName())) {
-		ret = admissionctl.Allowed("Layered product admins may access")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "Layered product admins may access")
 	}
 
 	// L64-73
 	if hookconfig.IsPrivilegedNamespace(ns.GetName()) {
 
 		if amIAdmin(request) {
-			ret = admissionctl.Allowed("Cluster and SRE admins may access")
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Allow(request, "Cluster and SRE admins may access")
 		}
-		log.Info("Non-admin attempted to access a privileged namespace matching a regex from this list", "list", hookconfig.PrivilegedNamespaces, "request", request.AdmissionRequest)
-		ret = admissionctl.Denied(fmt.Sprintf("Prevented from accessing Red Hat managed namespaces. Customer workloads should be placed in customer namespaces, and should not match an entry in this list of regular expressions: %v", hookconfig.PrivilegedNamespaces))
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		log.Info("Non-admin attempted to access a privileged namespace matching a regex from this list", "list", hookconfig.PrivilegedNamespaces, "request", utils.RedactRequest(request.AdmissionRequest))
+		return utils.Deny(request, utils.ReasonNamespaceManaged, fmt.Sprintf("Prevented from accessing Red Hat managed namespaces. Customer workloads should be placed in customer namespaces, and should not match an entry in this list of regular expressions: %v", hookconfig.PrivilegedNamespaces))
 	}
 	if BadNamespaceRe.Match([]byte(ns.GetName())) {
 
 		if amIAdmin(request) {
-			ret = admissionctl.Allowed("Cluster and SRE admins may access")
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Allow(request, "Cluster and SRE admins may access")
 		}
-		log.Info("Non-admin attempted to access a potentially harmful namespace (eg matching this regex)", "regex", badNamespace, "request", request.AdmissionRequest)
-		ret = admissionctl.Denied(fmt.Sprintf("Prevented from creating a potentially harmful namespace. Customer namespaces should not match this regular expression, as this would impact DNS resolution: %s", badNamespace))
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		log.Info("Non-admin attempted to access a potentially harmful namespace (eg matching this regex)", "regex", badNamespace, "request", utils.RedactRequest(request.AdmissionRequest))
+		return utils.Deny(request, utils.ReasonNamespaceHarmfulName, fmt.Sprintf("Prevented from creating a potentially harmful namespace. Customer namespaces should not match this regular expression, as this would impact DNS resolution: %s", badNamespace))
 	}
 	// Check labels.
 	unauthorized, err := s.unauthorizedLabelChanges(request)
 	if !amIAdmin(request) && unauthorized {
-		ret = admissionctl.Denied(fmt.Sprintf("Denied. Err %+v", err))
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Deny(request, utils.ReasonNamespaceProtectedLabel, fmt.Sprintf("Denied. Err %+v", err))
 	}
 	// L75-L77
-	ret = admissionctl.Allowed("RBAC allowed")
-	ret.UID = request.AdmissionRequest.UID
-	return ret
+	return utils.Allow(request, "RBAC allowed")
 }
 
 // unauthorizedLabelChanges returns true if the request should be denied because of a label violation. The error is the reason for denial.
@@ -323,26 +306,13 @@ func (s *NamespaceWebhook) HypershiftEnabled() bool { return true }
 
 // NewWebhook creates a new webhook
 func NewWebhook() *NamespaceWebhook {
-	scheme := runtime.NewScheme()
-	err := admissionv1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding admissionsv1 scheme to NamespaceWebhook")
-		os.Exit(1)
-	}
-
-	err = corev1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding corev1 scheme to NamespaceWebhook")
-		os.Exit(1)
-	}
-
 	return &NamespaceWebhook{
-		s: *scheme,
+		s: utils.CoreScheme,
 	}
 }
 
 func amIAdmin(request admissionctl.Request) bool {
-	if slices.Contains(clusterAdminUsers, request.UserInfo.Username) || slices.Contains(request.UserInfo.Groups, clusterAdminGroup) {
+	if slices.Contains(clusterAdminUsers, request.UserInfo.Username) || hookconfig.IsMember(request.UserInfo.Groups, hookconfig.CustomerClusterAdminGroups) {
 		return true
 	}
 
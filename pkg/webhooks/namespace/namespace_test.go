@@ -1072,3 +1072,34 @@ func TestGetURI(t *testing.T) {
 		t.Fatalf("Hook URI does not begin with a /")
 	}
 }
+
+func BenchmarkAuthorized(b *testing.B) {
+	gvk := metav1.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+	gvr := metav1.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	benchmarks := []struct {
+		name      string
+		namespace string
+		operation admissionv1.Operation
+		allowed   bool
+	}{
+		{name: "customer-namespace-create-allowed", namespace: "my-project", operation: admissionv1.Create, allowed: true},
+		{name: "privileged-namespace-update-denied", namespace: privilegedNamespace, operation: admissionv1.Update, allowed: false},
+	}
+	hook := NewWebhook()
+	for _, bm := range benchmarks {
+		obj := testutils.RepresentativeObject(testutils.RepresentativeNamespace, bm.namespace, "")
+		request, err := testutils.CreateRequest(bm.name, gvk, gvr, bm.operation, "alice", []string{"dedicated-admins", "system:authenticated", "system:authenticated:oauth"}, "", obj, obj)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if resp := hook.Authorized(request); resp.Allowed != bm.allowed {
+			b.Fatalf("%s: expected allowed=%v, got %v", bm.name, bm.allowed, resp.Allowed)
+		}
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				hook.Authorized(request)
+			}
+		})
+	}
+}
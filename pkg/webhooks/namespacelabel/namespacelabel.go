@@ -0,0 +1,185 @@
+package namespacelabel
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "namespacelabel-mutation"
+	docString   string = `Namespaces created by Managed OpenShift Customers are labeled with %v so that platform selectors for SyncSets, network policy and monitoring apply to them.`
+	// legalEntityLabelKey carries the cluster owner's legal entity ID, from
+	// hookconfig.LegalEntityIDEnvVar. It is only stamped if that is set.
+	legalEntityLabelKey string = "api.openshift.com/legal-entity-id"
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.ClusterScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"namespaces"},
+				Scope:       &scope,
+			},
+		},
+	}
+	// managedLabels are stamped onto every customer namespace at creation
+	managedLabels = map[string]string{
+		"managed.openshift.io/tier":    "customer",
+		"openshift.io/user-monitoring": "true",
+	}
+)
+
+// NamespaceLabelWebhook mutates customer Namespaces to carry the managed labels
+type NamespaceLabelWebhook struct {
+	s      *utils.LazyScheme
+	labels map[string]string
+}
+
+// NewWebhook creates the new webhook
+func NewWebhook() *NamespaceLabelWebhook {
+	labels := make(map[string]string, len(managedLabels)+1)
+	for k, v := range managedLabels {
+		labels[k] = v
+	}
+	if legalEntity := os.Getenv(hookconfig.LegalEntityIDEnvVar); legalEntity != "" {
+		labels[legalEntityLabelKey] = legalEntity
+	}
+
+	return &NamespaceLabelWebhook{
+		s:      utils.CoreScheme,
+		labels: labels,
+	}
+}
+
+// Authorized implements Webhook interface
+func (s *NamespaceLabelWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate adds any missing managed labels to customer Namespaces
+func (s *NamespaceLabelWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	ns, err := s.renderNamespace(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a Namespace from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	if hookconfig.IsPrivilegedNamespace(ns.GetName()) {
+		return utils.Allow(request, "Privileged namespaces are not labeled as customer namespaces")
+	}
+
+	patches := utils.AddLabelPatches(ns.GetLabels(), s.labels)
+	if len(patches) == 0 {
+		return utils.Allow(request, fmt.Sprintf("Namespace '%s' already carries the managed labels", ns.GetName()))
+	}
+
+	log.Info(fmt.Sprintf("Adding managed labels to namespace %s", ns.GetName()))
+	return utils.WithUID(request, admissionctl.Patched(fmt.Sprintf("Added managed labels to namespace '%s'", ns.GetName()), patches...))
+}
+
+// renderNamespace renders the Namespace in the admission Request
+func (s *NamespaceLabelWebhook) renderNamespace(request admissionctl.Request) (*corev1.Namespace, error) {
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return nil, err
+	}
+	ns := &corev1.Namespace{}
+	err = decoder.Decode(request, ns)
+	if err != nil {
+		return nil, err
+	}
+	return ns, nil
+}
+
+// GetURI implements Webhook interface
+func (s *NamespaceLabelWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *NamespaceLabelWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "Namespace")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *NamespaceLabelWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *NamespaceLabelWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *NamespaceLabelWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *NamespaceLabelWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *NamespaceLabelWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *NamespaceLabelWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *NamespaceLabelWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *NamespaceLabelWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *NamespaceLabelWebhook) Doc() string {
+	return fmt.Sprintf(docString, managedLabels)
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Return utils.DefaultLabelSelector() to stick with the default
+func (s *NamespaceLabelWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	return utils.DefaultLabelSelector()
+}
+
+func (s *NamespaceLabelWebhook) ClassicEnabled() bool { return true }
+
+func (s *NamespaceLabelWebhook) HypershiftEnabled() bool { return true }
@@ -0,0 +1,111 @@
+package namespacelabel
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+)
+
+const testNamespaceJSONString string = `
+{
+	"apiVersion": "v1",
+	"kind": "Namespace",
+	"metadata": {
+		"name": "%s",
+		"uid": "1234"%s
+	}
+}`
+
+func createRawNamespaceJSON(name string, labels map[string]string) []byte {
+	if labels == nil {
+		return []byte(fmt.Sprintf(testNamespaceJSONString, name, ""))
+	}
+	labelsMarshaled, _ := json.Marshal(labels)
+	return []byte(fmt.Sprintf(testNamespaceJSONString, name, ",\n\t\t\"labels\": "+string(labelsMarshaled)))
+}
+
+type namespaceLabelTestSuites struct {
+	testID         string
+	name           string
+	legalEntity    string
+	labels         map[string]string
+	expectedLabels map[string]string
+}
+
+func runNamespaceLabelTests(t *testing.T, tests []namespaceLabelTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "Namespace",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "namespaces",
+	}
+
+	for _, test := range tests {
+		t.Setenv(hookconfig.LegalEntityIDEnvVar, test.legalEntity)
+
+		rawNamespace := createRawNamespaceJSON(test.name, test.labels)
+		mutatedNamespace := corev1.Namespace{}
+		testutils.SendMutation(t, NewWebhook(), testutils.MutationRequest{
+			TestID: test.testID,
+			GVK:    gvk,
+			GVR:    gvr,
+			Object: rawNamespace,
+		}, &mutatedNamespace)
+		if !reflect.DeepEqual(mutatedNamespace.Labels, test.expectedLabels) {
+			t.Fatalf("%s: Expected labels %v, got %v", test.testID, test.expectedLabels, mutatedNamespace.Labels)
+		}
+	}
+}
+
+func TestNamespaceLabels(t *testing.T) {
+	tests := []namespaceLabelTestSuites{
+		{
+			testID: "unlabeled-customer-namespace",
+			name:   "my-namespace",
+			labels: nil,
+			expectedLabels: map[string]string{
+				"managed.openshift.io/tier":    "customer",
+				"openshift.io/user-monitoring": "true",
+			},
+		},
+		{
+			testID:      "customer-namespace-with-legal-entity",
+			name:        "my-namespace",
+			legalEntity: "abc123",
+			labels:      map[string]string{"team": "a"},
+			expectedLabels: map[string]string{
+				"team":                              "a",
+				"managed.openshift.io/tier":         "customer",
+				"openshift.io/user-monitoring":      "true",
+				"api.openshift.com/legal-entity-id": "abc123",
+			},
+		},
+		{
+			testID: "customer-namespace-keeps-existing-values",
+			name:   "my-namespace",
+			labels: map[string]string{"openshift.io/user-monitoring": "false"},
+			expectedLabels: map[string]string{
+				"managed.openshift.io/tier":    "customer",
+				"openshift.io/user-monitoring": "false",
+			},
+		},
+		{
+			testID:         "privileged-namespace-untouched",
+			name:           "openshift-monitoring",
+			labels:         map[string]string{"openshift.io/cluster-monitoring": "true"},
+			expectedLabels: map[string]string{"openshift.io/cluster-monitoring": "true"},
+		},
+	}
+	runNamespaceLabelTests(t, tests)
+}
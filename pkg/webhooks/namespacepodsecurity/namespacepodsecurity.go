@@ -0,0 +1,210 @@
+package namespacepodsecurity
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "namespacepodsecurity-mutation"
+	docString   string = `Namespaces created by Managed OpenShift Customers are labeled with the managed pod security profile (enforce=%s, warn=%s, audit=%s) rather than relying on cluster Pod Security Admission defaults, which vary between versions. Pod security labels set by the customer are left untouched.`
+	// enforceLevelEnvVar and auditLevelEnvVar name the environment variables
+	// overriding the managed pod security profile
+	enforceLevelEnvVar string = "POD_SECURITY_ENFORCE_LEVEL"
+	auditLevelEnvVar   string = "POD_SECURITY_AUDIT_LEVEL"
+	// defaultEnforceLevel and defaultAuditLevel make up the managed pod
+	// security profile. warn follows the audit level so users see what
+	// audit records.
+	defaultEnforceLevel string = "baseline"
+	defaultAuditLevel   string = "restricted"
+
+	enforceLabelKey string = "pod-security.kubernetes.io/enforce"
+	warnLabelKey    string = "pod-security.kubernetes.io/warn"
+	auditLabelKey   string = "pod-security.kubernetes.io/audit"
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.ClusterScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"namespaces"},
+				Scope:       &scope,
+			},
+		},
+	}
+	// validLevels are the Pod Security Standards levels
+	validLevels = map[string]bool{
+		"privileged": true,
+		"baseline":   true,
+		"restricted": true,
+	}
+)
+
+// NamespacePodSecurityWebhook mutates customer Namespaces to carry the managed
+// pod security labels
+type NamespacePodSecurityWebhook struct {
+	s      *utils.LazyScheme
+	labels map[string]string
+}
+
+// NewWebhook creates the new webhook
+func NewWebhook() *NamespacePodSecurityWebhook {
+	enforce := levelFromEnv(enforceLevelEnvVar, defaultEnforceLevel)
+	audit := levelFromEnv(auditLevelEnvVar, defaultAuditLevel)
+
+	return &NamespacePodSecurityWebhook{
+		s: utils.CoreScheme,
+		labels: map[string]string{
+			enforceLabelKey: enforce,
+			warnLabelKey:    audit,
+			auditLabelKey:   audit,
+		},
+	}
+}
+
+// levelFromEnv returns the pod security level named by the environment
+// variable, falling back to def when it is unset or not a valid level
+func levelFromEnv(envVar, def string) string {
+	level := os.Getenv(envVar)
+	if level == "" {
+		return def
+	}
+	if !validLevels[level] {
+		log.Info(fmt.Sprintf("Ignoring invalid pod security level %q in %s, using %s", level, envVar, def))
+		return def
+	}
+	return level
+}
+
+// Authorized implements Webhook interface
+func (s *NamespacePodSecurityWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate adds any missing pod security labels to customer Namespaces
+func (s *NamespacePodSecurityWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	ns, err := s.renderNamespace(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a Namespace from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	if hookconfig.IsPrivilegedNamespace(ns.GetName()) {
+		return utils.Allow(request, "Privileged namespaces keep the platform pod security configuration")
+	}
+
+	patches := utils.AddLabelPatches(ns.GetLabels(), s.labels)
+	if len(patches) == 0 {
+		return utils.Allow(request, fmt.Sprintf("Namespace '%s' already carries pod security labels", ns.GetName()))
+	}
+
+	log.Info(fmt.Sprintf("Adding pod security labels to namespace %s", ns.GetName()))
+	return utils.WithUID(request, admissionctl.Patched(fmt.Sprintf("Added pod security labels to namespace '%s'", ns.GetName()), patches...))
+}
+
+// renderNamespace renders the Namespace in the admission Request
+func (s *NamespacePodSecurityWebhook) renderNamespace(request admissionctl.Request) (*corev1.Namespace, error) {
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return nil, err
+	}
+	ns := &corev1.Namespace{}
+	err = decoder.Decode(request, ns)
+	if err != nil {
+		return nil, err
+	}
+	return ns, nil
+}
+
+// GetURI implements Webhook interface
+func (s *NamespacePodSecurityWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *NamespacePodSecurityWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "Namespace")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *NamespacePodSecurityWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *NamespacePodSecurityWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *NamespacePodSecurityWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *NamespacePodSecurityWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *NamespacePodSecurityWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *NamespacePodSecurityWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *NamespacePodSecurityWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *NamespacePodSecurityWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *NamespacePodSecurityWebhook) Doc() string {
+	return fmt.Sprintf(docString, defaultEnforceLevel, defaultAuditLevel, defaultAuditLevel)
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Return utils.DefaultLabelSelector() to stick with the default
+func (s *NamespacePodSecurityWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	return utils.DefaultLabelSelector()
+}
+
+func (s *NamespacePodSecurityWebhook) ClassicEnabled() bool { return true }
+
+func (s *NamespacePodSecurityWebhook) HypershiftEnabled() bool { return true }
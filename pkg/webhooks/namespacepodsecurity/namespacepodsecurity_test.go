@@ -0,0 +1,120 @@
+package namespacepodsecurity
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+)
+
+const testNamespaceJSONString string = `
+{
+	"apiVersion": "v1",
+	"kind": "Namespace",
+	"metadata": {
+		"name": "%s",
+		"uid": "1234"%s
+	}
+}`
+
+func createRawNamespaceJSON(name string, labels map[string]string) []byte {
+	if labels == nil {
+		return []byte(fmt.Sprintf(testNamespaceJSONString, name, ""))
+	}
+	labelsMarshaled, _ := json.Marshal(labels)
+	return []byte(fmt.Sprintf(testNamespaceJSONString, name, ",\n\t\t\"labels\": "+string(labelsMarshaled)))
+}
+
+type namespacePodSecurityTestSuites struct {
+	testID         string
+	name           string
+	enforceLevel   string
+	labels         map[string]string
+	expectedLabels map[string]string
+}
+
+func runNamespacePodSecurityTests(t *testing.T, tests []namespacePodSecurityTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "Namespace",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "namespaces",
+	}
+
+	for _, test := range tests {
+		t.Setenv(enforceLevelEnvVar, test.enforceLevel)
+
+		rawNamespace := createRawNamespaceJSON(test.name, test.labels)
+		mutatedNamespace := corev1.Namespace{}
+		testutils.SendMutation(t, NewWebhook(), testutils.MutationRequest{
+			TestID: test.testID,
+			GVK:    gvk,
+			GVR:    gvr,
+			Object: rawNamespace,
+		}, &mutatedNamespace)
+		if !reflect.DeepEqual(mutatedNamespace.Labels, test.expectedLabels) {
+			t.Fatalf("%s: Expected labels %v, got %v", test.testID, test.expectedLabels, mutatedNamespace.Labels)
+		}
+	}
+}
+
+func TestNamespacePodSecurityLabels(t *testing.T) {
+	tests := []namespacePodSecurityTestSuites{
+		{
+			testID: "unlabeled-customer-namespace",
+			name:   "my-namespace",
+			expectedLabels: map[string]string{
+				enforceLabelKey: "baseline",
+				warnLabelKey:    "restricted",
+				auditLabelKey:   "restricted",
+			},
+		},
+		{
+			testID: "customer-namespace-keeps-own-enforce-level",
+			name:   "my-namespace",
+			labels: map[string]string{"team": "a", enforceLabelKey: "privileged"},
+			expectedLabels: map[string]string{
+				"team":          "a",
+				enforceLabelKey: "privileged",
+				warnLabelKey:    "restricted",
+				auditLabelKey:   "restricted",
+			},
+		},
+		{
+			testID:       "enforce-level-from-environment",
+			name:         "my-namespace",
+			enforceLevel: "restricted",
+			expectedLabels: map[string]string{
+				enforceLabelKey: "restricted",
+				warnLabelKey:    "restricted",
+				auditLabelKey:   "restricted",
+			},
+		},
+		{
+			testID:       "invalid-enforce-level-falls-back",
+			name:         "my-namespace",
+			enforceLevel: "lenient",
+			expectedLabels: map[string]string{
+				enforceLabelKey: "baseline",
+				warnLabelKey:    "restricted",
+				auditLabelKey:   "restricted",
+			},
+		},
+		{
+			testID:         "privileged-namespace-untouched",
+			name:           "openshift-monitoring",
+			labels:         map[string]string{"openshift.io/cluster-monitoring": "true"},
+			expectedLabels: map[string]string{"openshift.io/cluster-monitoring": "true"},
+		},
+	}
+	runNamespacePodSecurityTests(t, tests)
+}
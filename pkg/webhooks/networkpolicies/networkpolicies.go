@@ -13,7 +13,6 @@ import (
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -25,9 +24,9 @@ const (
 
 var (
 	timeout                          int32 = 2
-	allowedUsers                           = []string{"system:admin", "backplane-cluster-admin"}
-	sreAdminGroups                         = []string{"system:serviceaccounts:openshift-backplane-srep"}
-	privilegedServiceAccountGroupsRe       = regexp.MustCompile(utils.PrivilegedServiceAccountGroups)
+	allowedUsers                           = hookconfig.Identities(hookconfig.PlatformAdminUsersFor(WebhookName), hookconfig.SREAdminUsers)
+	sreAdminGroups                         = hookconfig.SREAdminGroups
+	privilegedServiceAccountGroupsRe       = regexp.MustCompile(hookconfig.PrivilegedServiceAccountGroups)
 	scope                                  = admissionregv1.NamespacedScope
 	rules                                  = []admissionregv1.RuleWithOperations{
 		{
@@ -49,14 +48,13 @@ var (
 
 // networkpoliciesruleWebhook validates a networkpolicy change
 type networkpoliciesruleWebhook struct {
-	s runtime.Scheme
+	s *utils.LazyScheme
 }
 
 // NewWebhook creates the new webhook
 func NewWebhook() *networkpoliciesruleWebhook {
-	scheme := runtime.NewScheme()
 	return &networkpoliciesruleWebhook{
-		s: *scheme,
+		s: utils.EmptyScheme,
 	}
 }
 
@@ -67,8 +65,6 @@ func (s *networkpoliciesruleWebhook) Authorized(request admissionctl.Request) ad
 }
 
 func (s *networkpoliciesruleWebhook) authorized(request admissionctl.Request) admissionctl.Response {
-	var ret admissionctl.Response
-
 	np, err := s.renderNetworkPolicy(request)
 	if err != nil {
 		log.Error(err, "Could not render a NetworkPolicy from the incoming request")
@@ -78,36 +74,26 @@ func (s *networkpoliciesruleWebhook) authorized(request admissionctl.Request) ad
 	if !isAllowedNamespace(np.GetNamespace()) {
 		log.Info(fmt.Sprintf("%s operation detected on managed namespace: %s", request.Operation, np.GetNamespace()))
 		if isAllowedUser(request) {
-			ret = admissionctl.Allowed(fmt.Sprintf("User '%s' in group(s) '%s' can operate on NetworkPolicies", request.UserInfo.Username, strings.Join(request.UserInfo.Groups, ", ")))
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Allow(request, fmt.Sprintf("User '%s' in group(s) '%s' can operate on NetworkPolicies", request.UserInfo.Username, strings.Join(request.UserInfo.Groups, ", ")))
 		}
 		for _, group := range request.UserInfo.Groups {
 			if privilegedServiceAccountGroupsRe.Match([]byte(group)) {
-				ret = admissionctl.Allowed(fmt.Sprintf("Privileged service accounts in group(s) '%s' can operate on NetworkPolicies", strings.Join(request.UserInfo.Groups, ", ")))
-				ret.UID = request.AdmissionRequest.UID
-				return ret
+				return utils.Allow(request, fmt.Sprintf("Privileged service accounts in group(s) '%s' can operate on NetworkPolicies", strings.Join(request.UserInfo.Groups, ", ")))
 			}
 		}
 
-		ret = admissionctl.Denied(fmt.Sprintf("User '%s' prevented from accessing Red Mat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support", request.UserInfo.Username))
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Deny(request, utils.ReasonNetworkPolicyManagedNamespace, fmt.Sprintf("User '%s' prevented from accessing Red Mat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support", request.UserInfo.Username))
 	}
 
 	if np.GetNamespace() == "openshift-ingress" {
 		ingressName, labelFound := np.Spec.PodSelector.MatchLabels["ingresscontroller.operator.openshift.io/deployment-ingresscontroller"]
 		if !labelFound || ingressName == "default" {
-			ret = admissionctl.Denied(fmt.Sprintf("User '%s' prevented from creating network policy that may impact default ingress, which is managed by Red Hat. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support", request.UserInfo.Username))
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Deny(request, utils.ReasonNetworkPolicyDefaultIngress, fmt.Sprintf("User '%s' prevented from creating network policy that may impact default ingress, which is managed by Red Hat. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support", request.UserInfo.Username))
 		}
 	}
 
-	log.Info("Allowing access", "request", request.AdmissionRequest)
-	ret = admissionctl.Allowed("Non managed namespace")
-	ret.UID = request.AdmissionRequest.UID
-	return ret
+	log.Info("Allowing access", "request", utils.RedactRequest(request.AdmissionRequest))
+	return utils.Allow(request, "Non managed namespace")
 }
 
 // isAllowedNamespace checks if the namespace is excluded from this webhook
@@ -131,7 +117,7 @@ func isAllowedUser(request admissionctl.Request) bool {
 }
 
 func (s *networkpoliciesruleWebhook) renderNetworkPolicy(req admissionctl.Request) (*networkingv1.NetworkPolicy, error) {
-	decoder, err := admissionctl.NewDecoder(&s.s)
+	decoder, err := s.s.Decoder()
 	if err != nil {
 		return nil, err
 	}
@@ -184,6 +170,11 @@ func (s *networkpoliciesruleWebhook) ObjectSelector() *metav1.LabelSelector {
 	return nil
 }
 
+// NamespaceSelector implements Webhook interface
+func (s *networkpoliciesruleWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
 // SideEffects implements Webhook interface
 func (s *networkpoliciesruleWebhook) SideEffects() admissionregv1.SideEffectClass {
 	return admissionregv1.SideEffectClassNone
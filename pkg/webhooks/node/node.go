@@ -5,13 +5,13 @@ import (
 	"slices"
 	"strings"
 
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/localmetrics"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -26,8 +26,8 @@ const (
 )
 
 var (
-	adminGroups = []string{"system:serviceaccounts:openshift-backplane-srep"}
-	adminUsers  = []string{"backplane-cluster-admin"}
+	adminGroups = hookconfig.SREAdminGroups
+	adminUsers  = hookconfig.SREAdminUsers
 	scope       = admissionregv1.AllScopes
 	rules       = []admissionregv1.RuleWithOperations{
 		{
@@ -49,7 +49,7 @@ var (
 
 // NodeWebhook protects various objects from unauthorized manipulation
 type NodeWebhook struct {
-	scheme *runtime.Scheme
+	scheme *utils.LazyScheme
 }
 
 func (s *NodeWebhook) Doc() string {
@@ -59,6 +59,9 @@ func (s *NodeWebhook) Doc() string {
 // ObjectSelector implements Webhook interface
 func (s *NodeWebhook) ObjectSelector() *metav1.LabelSelector { return nil }
 
+// NamespaceSelector implements Webhook interface
+func (s *NodeWebhook) NamespaceSelector() *metav1.LabelSelector { return nil }
+
 // TimeoutSeconds implements Webhook interface
 func (s *NodeWebhook) TimeoutSeconds() int32 { return 2 }
 
@@ -100,43 +103,31 @@ func (s *NodeWebhook) Authorized(request admissionctl.Request) admissionctl.Resp
 }
 
 func (s *NodeWebhook) authorized(request admissionctl.Request) admissionctl.Response {
-	var ret admissionctl.Response
-
 	if request.AdmissionRequest.UserInfo.Username == "system:unauthenticated" {
 		// This could highlight a significant problem with RBAC since an
 		// unauthenticated user should have no permissions.
-		log.Info("system:unauthenticated made a webhook request. Check RBAC rules", "request", request.AdmissionRequest)
-		ret = admissionctl.Denied("Unauthenticated")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		log.Info("system:unauthenticated made a webhook request. Check RBAC rules", "request", utils.RedactRequest(request.AdmissionRequest))
+		return utils.Deny(request, utils.ReasonNodeUnauthenticated, "Unauthenticated")
 	}
 	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "system:") {
-		ret = admissionctl.Allowed("authenticated system: users are allowed")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "authenticated system: users are allowed")
 	}
 	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "kube:") {
-		ret = admissionctl.Allowed("kube: users are allowed")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "kube: users are allowed")
 	}
 	if slices.Contains(adminUsers, request.AdmissionRequest.UserInfo.Username) {
-		ret = admissionctl.Allowed("Specified admin users are allowed")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "Specified admin users are allowed")
 	}
 	for _, userGroup := range request.UserInfo.Groups {
 		if slices.Contains(adminGroups, userGroup) {
-			ret = admissionctl.Allowed("Members of admin groups are allowed")
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Allow(request, "Members of admin groups are allowed")
 		}
 	}
 
 	//Checks for non-adminGroups non-ceeGroup non-adminGroups users
 	if request.Kind.Kind == "Node" {
 		node := corev1.Node{}
-		decoder, err := admission.NewDecoder(s.scheme)
+		decoder, err := s.scheme.Decoder()
 		if err != nil {
 			return admission.Errored(http.StatusBadRequest, err)
 		}
@@ -159,45 +150,33 @@ func (s *NodeWebhook) authorized(request admissionctl.Request) admissionctl.Resp
 
 		if request.Operation == admissionv1.Delete {
 			localmetrics.IncrementNodeWebhookBlockedRequest(request.UserInfo.Username)
-			ret = admissionctl.Denied("Prevented from deleting nodes. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Deny(request, utils.ReasonNodeDelete, "Prevented from deleting nodes. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
 		}
 
 		if _, ok := node.Labels["node-role.kubernetes.io/infra"]; ok {
 			localmetrics.IncrementNodeWebhookBlockedRequest(request.UserInfo.Username)
 			log.Info("Denying access to infra node")
-			ret = admissionctl.Denied("Prevented from modifying Red Hat managed infra nodes. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Deny(request, utils.ReasonNodeInfraModify, "Prevented from modifying Red Hat managed infra nodes. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
 		}
 
 		if _, ok := node.Labels["node-role.kubernetes.io/control-plane"]; ok {
 			localmetrics.IncrementNodeWebhookBlockedRequest(request.UserInfo.Username)
 			log.Info("Denying access to control plane node")
-			ret = admissionctl.Denied("Prevented from modifying Red Hat managed control plane nodes. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Deny(request, utils.ReasonNodeControlPlaneModify, "Prevented from modifying Red Hat managed control plane nodes. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
 		}
 
 		if _, ok := node.Labels["node-role.kubernetes.io/master"]; ok {
 			localmetrics.IncrementNodeWebhookBlockedRequest(request.UserInfo.Username)
 			log.Info("Denying access to control plane node")
-			ret = admissionctl.Denied("Prevented from modifying Red Hat managed master nodes. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Deny(request, utils.ReasonNodeMasterModify, "Prevented from modifying Red Hat managed master nodes. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
 		}
 
-		ret = admissionctl.Allowed("Allowed to modify worker nodes")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "Allowed to modify worker nodes")
 	}
 
 	// Should never get here
-	log.Info("Unexpectedly denying access", "request", request.AdmissionRequest)
-	ret = admissionctl.Denied("Prevented from accessing Red Hat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
-	ret.UID = request.AdmissionRequest.UID
-	return ret
+	log.Info("Unexpectedly denying access", "request", utils.RedactRequest(request.AdmissionRequest))
+	return utils.Deny(request, utils.ReasonNodeManagedResource, "Prevented from accessing Red Hat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
 }
 
 // SyncSetLabelSelector returns the label selector to use in the SyncSet.
@@ -212,6 +191,6 @@ func (s *NodeWebhook) HypershiftEnabled() bool { return false }
 // NewWebhook creates a new webhook
 func NewWebhook() *NodeWebhook {
 	return &NodeWebhook{
-		scheme: runtime.NewScheme(),
+		scheme: utils.EmptyScheme,
 	}
 }
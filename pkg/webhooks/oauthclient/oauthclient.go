@@ -0,0 +1,223 @@
+package oauthclient
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+
+	oauthv1 "github.com/openshift/api/oauth/v1"
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	WebhookName = "oauthclient-validation"
+	docString   = `Managed OpenShift Customers may not delete or rotate the secrets of the following platform OAuthClients: %s, or of any OAuthClient matching this regular expression: %s`
+	// backplaneClients matches the OAuthClients SRE uses to reach the cluster
+	// through backplane
+	backplaneClients = `^backplane-.*`
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.ClusterScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{"UPDATE", "DELETE"},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{"oauth.openshift.io"},
+				APIVersions: []string{"*"},
+				Resources:   []string{"oauthclients"},
+				Scope:       &scope,
+			},
+		},
+	}
+	allowedUsers                = hookconfig.Identities(hookconfig.KubeAdminUsersFor(WebhookName), hookconfig.PlatformAdminUsersFor(WebhookName), hookconfig.SREAdminUsers)
+	allowedGroups               = hookconfig.SREAdminGroups
+	privilegedServiceAccountsRe = regexp.MustCompile(hookconfig.PrivilegedServiceAccountGroups)
+	backplaneClientsRe          = regexp.MustCompile(backplaneClients)
+	protectedOAuthClients       = []string{
+		"console",
+		"openshift-browser-client",
+		"openshift-challenging-client",
+	}
+)
+
+type OAuthClientWebhook struct {
+	scheme *utils.LazyScheme
+}
+
+// NewWebhook creates the new webhook
+func NewWebhook() *OAuthClientWebhook {
+	return &OAuthClientWebhook{
+		scheme: utils.EmptyScheme,
+	}
+}
+
+// Authorized implements Webhook interface
+func (s *OAuthClientWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	return s.authorized(request)
+}
+
+func (s *OAuthClientWebhook) authorized(request admissionctl.Request) admissionctl.Response {
+	oldClient, newClient, err := s.renderOAuthClients(request)
+	if err != nil {
+		log.Error(err, "Couldn't render an OAuthClient from the incoming request")
+		return admissionctl.Errored(http.StatusBadRequest, err)
+	}
+
+	if isProtectedOAuthClient(oldClient) && !isAllowedUserGroup(request) {
+		switch request.Operation {
+		case admissionv1.Delete:
+			log.Info(fmt.Sprintf("Deleting operation detected on protected OAuthClient: %v", oldClient.Name))
+			return utils.Deny(request, utils.ReasonOAuthClientPlatformDelete, fmt.Sprintf("Deleting the platform OAuthClient %v is not allowed", oldClient.Name))
+		case admissionv1.Update:
+			if isSecretChanged(oldClient, newClient) {
+				log.Info(fmt.Sprintf("Secret rotation detected on protected OAuthClient: %v", oldClient.Name))
+				return utils.Deny(request, utils.ReasonOAuthClientPlatformSecretModify, fmt.Sprintf("Changing the secrets of the platform OAuthClient %v is not allowed", oldClient.Name))
+			}
+		}
+	}
+
+	return utils.Allow(request, "Request is allowed")
+}
+
+// renderOAuthClients renders the old and, for UPDATEs, the new OAuthClient
+// from the request. Return order is: old, new, error.
+func (s *OAuthClientWebhook) renderOAuthClients(request admissionctl.Request) (*oauthv1.OAuthClient, *oauthv1.OAuthClient, error) {
+	decoder, err := s.scheme.Decoder()
+	if err != nil {
+		return nil, nil, err
+	}
+	oldClient := &oauthv1.OAuthClient{}
+	newClient := &oauthv1.OAuthClient{}
+
+	if len(request.OldObject.Raw) > 0 {
+		err = decoder.DecodeRaw(request.OldObject, oldClient)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(request.Object.Raw) > 0 {
+		err = decoder.DecodeRaw(request.Object, newClient)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return oldClient, newClient, nil
+}
+
+// isAllowedUserGroup checks if the user or group is allowed to perform the action
+func isAllowedUserGroup(request admissionctl.Request) bool {
+	if slices.Contains(allowedUsers, request.UserInfo.Username) {
+		return true
+	}
+
+	for _, group := range request.UserInfo.Groups {
+		// The console and authentication operators own these clients and
+		// legitimately rotate their secrets
+		if privilegedServiceAccountsRe.Match([]byte(group)) {
+			return true
+		}
+		if slices.Contains(allowedGroups, group) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isProtectedOAuthClient checks if the OAuthClient is one the platform or
+// SRE depends on
+func isProtectedOAuthClient(client *oauthv1.OAuthClient) bool {
+	if slices.Contains(protectedOAuthClients, client.Name) {
+		return true
+	}
+	return backplaneClientsRe.Match([]byte(client.Name))
+}
+
+// isSecretChanged checks if an UPDATE modifies the secret material of the
+// OAuthClient
+func isSecretChanged(oldClient, newClient *oauthv1.OAuthClient) bool {
+	if oldClient.Secret != newClient.Secret {
+		return true
+	}
+	return !slices.Equal(oldClient.AdditionalSecrets, newClient.AdditionalSecrets)
+}
+
+// GetURI implements Webhook interface
+func (s *OAuthClientWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *OAuthClientWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "OAuthClient")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *OAuthClientWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *OAuthClientWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *OAuthClientWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *OAuthClientWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *OAuthClientWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *OAuthClientWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *OAuthClientWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *OAuthClientWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *OAuthClientWebhook) Doc() string {
+	return fmt.Sprintf(docString, protectedOAuthClients, backplaneClients)
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Return utils.DefaultLabelSelector() to stick with the default
+func (s *OAuthClientWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	return utils.DefaultLabelSelector()
+}
+
+func (s *OAuthClientWebhook) ClassicEnabled() bool { return true }
+
+func (s *OAuthClientWebhook) HypershiftEnabled() bool { return true }
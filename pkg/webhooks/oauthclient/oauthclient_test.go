@@ -0,0 +1,200 @@
+package oauthclient
+
+import (
+	"fmt"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type oauthClientTestSuites struct {
+	testID          string
+	targetClient    string
+	oldSecret       string
+	newSecret       string
+	username        string
+	operation       admissionv1.Operation
+	userGroups      []string
+	shouldBeAllowed bool
+}
+
+const testObjectRaw string = `
+{
+	"apiVersion": "oauth.openshift.io/v1",
+	"kind": "OAuthClient",
+	"metadata": {
+		"name": "%s",
+		"uid": "1234"
+	},
+	"secret": "%s",
+	"grantMethod": "auto"
+}`
+
+func createRawJSONString(name, secret string) string {
+	s := fmt.Sprintf(testObjectRaw, name, secret)
+	return s
+}
+
+func runOAuthClientTests(t *testing.T, tests []oauthClientTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "oauth.openshift.io",
+		Version: "v1",
+		Kind:    "OAuthClient",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "oauth.openshift.io",
+		Version:  "v1",
+		Resource: "oauthclients",
+	}
+
+	for _, test := range tests {
+		newSecret := test.newSecret
+		if newSecret == "" {
+			newSecret = test.oldSecret
+		}
+
+		obj := runtime.RawExtension{
+			Raw: []byte(createRawJSONString(test.targetClient, newSecret)),
+		}
+
+		oldObj := runtime.RawExtension{
+			Raw: []byte(createRawJSONString(test.targetClient, test.oldSecret)),
+		}
+
+		// DELETE operations only carry the OldObject
+		if test.operation == admissionv1.Delete {
+			obj = oldObj
+		}
+
+		hook := NewWebhook()
+		httprequest, err := testutils.CreateHTTPRequest(hook.GetURI(),
+			test.testID, gvk, gvr, test.operation, test.username, test.userGroups, "", &obj, &oldObj)
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err.Error())
+		}
+
+		response, err := testutils.SendHTTPRequest(httprequest, hook)
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err.Error())
+		}
+		if response.UID == "" {
+			t.Fatalf("No tracking UID associated with the response.")
+		}
+
+		if response.Allowed != test.shouldBeAllowed {
+			t.Fatalf("Mismatch in %s: %s (groups=%s) %s %s the oauthclient. Test's expectation is that the user %s", test.testID, test.username, test.userGroups, testutils.CanCanNot(response.Allowed), test.operation, testutils.CanCanNot(test.shouldBeAllowed))
+		}
+	}
+}
+
+func TestUser(t *testing.T) {
+	tests := []oauthClientTestSuites{
+		{
+			targetClient:    "console",
+			testID:          "user-cant-delete-console",
+			username:        "user1",
+			oldSecret:       "abc",
+			operation:       admissionv1.Delete,
+			userGroups:      []string{"system:authenticated", "system:authenticated:oauth"},
+			shouldBeAllowed: false,
+		},
+		{
+			targetClient:    "openshift-browser-client",
+			testID:          "user-cant-rotate-browser-client",
+			username:        "user1",
+			oldSecret:       "abc",
+			newSecret:       "def",
+			operation:       admissionv1.Update,
+			userGroups:      []string{"system:authenticated", "system:authenticated:oauth"},
+			shouldBeAllowed: false,
+		},
+		{
+			targetClient:    "openshift-challenging-client",
+			testID:          "user-can-update-challenging-client-without-secret-change",
+			username:        "user1",
+			oldSecret:       "abc",
+			operation:       admissionv1.Update,
+			userGroups:      []string{"system:authenticated", "system:authenticated:oauth"},
+			shouldBeAllowed: true,
+		},
+		{
+			targetClient:    "backplane-srep",
+			testID:          "user-cant-delete-backplane-client",
+			username:        "user1",
+			oldSecret:       "abc",
+			operation:       admissionv1.Delete,
+			userGroups:      []string{"system:authenticated", "system:authenticated:oauth"},
+			shouldBeAllowed: false,
+		},
+		{
+			targetClient:    "my-app",
+			testID:          "user-can-delete-own-client",
+			username:        "user1",
+			oldSecret:       "abc",
+			operation:       admissionv1.Delete,
+			userGroups:      []string{"system:authenticated", "system:authenticated:oauth"},
+			shouldBeAllowed: true,
+		},
+		{
+			targetClient:    "my-app",
+			testID:          "user-can-rotate-own-client",
+			username:        "user1",
+			oldSecret:       "abc",
+			newSecret:       "def",
+			operation:       admissionv1.Update,
+			userGroups:      []string{"system:authenticated", "system:authenticated:oauth"},
+			shouldBeAllowed: true,
+		},
+	}
+	runOAuthClientTests(t, tests)
+}
+
+func TestPrivilegedUsers(t *testing.T) {
+	tests := []oauthClientTestSuites{
+		{
+			targetClient:    "console",
+			testID:          "console-operator-can-rotate-console",
+			username:        "system:serviceaccount:openshift-console-operator:console-operator",
+			oldSecret:       "abc",
+			newSecret:       "def",
+			operation:       admissionv1.Update,
+			userGroups:      []string{"system:serviceaccounts", "system:serviceaccounts:openshift-console-operator"},
+			shouldBeAllowed: true,
+		},
+		{
+			targetClient:    "console",
+			testID:          "backplane-cluster-admin-can-delete-console",
+			username:        "backplane-cluster-admin",
+			oldSecret:       "abc",
+			operation:       admissionv1.Delete,
+			userGroups:      []string{"system:authenticated"},
+			shouldBeAllowed: true,
+		},
+		{
+			targetClient:    "openshift-browser-client",
+			testID:          "srep-can-rotate-browser-client",
+			username:        "system:serviceaccount:openshift-backplane-srep:1234",
+			oldSecret:       "abc",
+			newSecret:       "def",
+			operation:       admissionv1.Update,
+			userGroups:      []string{"system:serviceaccounts:openshift-backplane-srep"},
+			shouldBeAllowed: true,
+		},
+		{
+			targetClient:    "console",
+			testID:          "customer-serviceaccount-cant-rotate-console",
+			username:        "system:serviceaccount:my-ns:my-sa",
+			oldSecret:       "abc",
+			newSecret:       "def",
+			operation:       admissionv1.Update,
+			userGroups:      []string{"system:serviceaccounts", "system:serviceaccounts:my-ns"},
+			shouldBeAllowed: false,
+		},
+	}
+	runOAuthClientTests(t, tests)
+}
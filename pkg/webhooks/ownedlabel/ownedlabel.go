@@ -0,0 +1,163 @@
+package ownedlabel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/ownershiplabel"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "ownedlabel-validation"
+	docString   string = `Managed OpenShift customers may not set, change or remove the "%s" label, which marks the resources created by Hive or SRE.`
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	// rules match the resources labeled by the ownershiplabel-mutation
+	// webhook. The ObjectSelector limits them to the requests whose new or
+	// existing object carries the label.
+	rules = ownershiplabel.LabeledRules(admissionregv1.Create, admissionregv1.Update)
+)
+
+// OwnedLabelWebhook protects the label of platform-created resources, so
+// label-based protection can trust it
+type OwnedLabelWebhook struct{}
+
+// NewWebhook creates the new webhook
+func NewWebhook() *OwnedLabelWebhook {
+	return &OwnedLabelWebhook{}
+}
+
+// Authorized implements Webhook interface
+func (s *OwnedLabelWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	return s.authorized(request)
+}
+
+func (s *OwnedLabelWebhook) authorized(request admissionctl.Request) admissionctl.Response {
+	if ownershiplabel.IsPlatformRequest(request) {
+		return utils.Allow(request, "The platform may label the resources it owns")
+	}
+
+	value, labeled, err := ownedLabel(request.Object.Raw)
+	if err != nil {
+		log.Error(err, "Couldn't render the object metadata from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+	if request.Operation == admissionv1.Create {
+		if labeled {
+			return utils.Deny(request, utils.ReasonOwnedLabelModify, fmt.Sprintf("Managed OpenShift customers may not create resources labeled with %s, which marks resources created by the platform", ownershiplabel.OwnedLabel))
+		}
+		return utils.Allow(request, "The resource isn't labeled as owned")
+	}
+
+	oldValue, oldLabeled, err := ownedLabel(request.OldObject.Raw)
+	if err != nil {
+		log.Error(err, "Couldn't render the old object metadata from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+	if labeled != oldLabeled || value != oldValue {
+		return utils.Deny(request, utils.ReasonOwnedLabelModify, fmt.Sprintf("Managed OpenShift customers may not set, change or remove the %s label, which marks resources created by the platform", ownershiplabel.OwnedLabel))
+	}
+	return utils.Allow(request, "The owned label is unchanged")
+}
+
+// ownedLabel returns the value of the OwnedLabel of the object in raw, and
+// whether it is set
+func ownedLabel(raw []byte) (string, bool, error) {
+	// Only the metadata is needed, so any kind can be decoded
+	obj := &metav1.PartialObjectMetadata{}
+	if err := json.Unmarshal(raw, obj); err != nil {
+		return "", false, err
+	}
+	value, labeled := obj.GetLabels()[ownershiplabel.OwnedLabel]
+	return value, labeled, nil
+}
+
+// GetURI implements Webhook interface
+func (s *OwnedLabelWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *OwnedLabelWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (len(request.Object.Raw) > 0)
+	valid = valid && (request.Operation == admissionv1.Create || len(request.OldObject.Raw) > 0)
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *OwnedLabelWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *OwnedLabelWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *OwnedLabelWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *OwnedLabelWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector intercepts the requests whose new or existing object has
+// the OwnedLabel, which the API server matches against both on updates, so
+// setting, changing and removing it are all intercepted
+func (s *OwnedLabelWebhook) ObjectSelector() *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{
+				Key:      ownershiplabel.OwnedLabel,
+				Operator: metav1.LabelSelectorOpExists,
+			},
+		},
+	}
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *OwnedLabelWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *OwnedLabelWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *OwnedLabelWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *OwnedLabelWebhook) Doc() string {
+	return fmt.Sprintf(docString, ownershiplabel.OwnedLabel)
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Return utils.DefaultLabelSelector() to stick with the default
+func (s *OwnedLabelWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	return utils.DefaultLabelSelector()
+}
+
+func (s *OwnedLabelWebhook) ClassicEnabled() bool { return true }
+
+func (s *OwnedLabelWebhook) HypershiftEnabled() bool { return false }
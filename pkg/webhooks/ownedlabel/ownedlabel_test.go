@@ -0,0 +1,140 @@
+package ownedlabel
+
+import (
+	"encoding/json"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/ownershiplabel"
+)
+
+type ownedLabelTestSuites struct {
+	testID          string
+	username        string
+	userGroups      []string
+	operation       admissionv1.Operation
+	oldLabels       map[string]string
+	labels          map[string]string
+	shouldBeAllowed bool
+}
+
+func createConfigMap(t *testing.T, name string, labels map[string]string) *runtime.RawExtension {
+	raw, err := json.Marshal(corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "openshift-config", UID: "1234", Labels: labels},
+	})
+	if err != nil {
+		t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
+	}
+	return &runtime.RawExtension{Raw: raw}
+}
+
+func runOwnedLabelTests(t *testing.T, tests []ownedLabelTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "ConfigMap",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "configmaps",
+	}
+
+	for _, test := range tests {
+		hook := NewWebhook()
+		httprequest, err := testutils.CreateHTTPRequest(hook.GetURI(),
+			test.testID,
+			gvk, gvr, test.operation, test.username, test.userGroups, "openshift-config",
+			createConfigMap(t, test.testID, test.labels), createConfigMap(t, test.testID, test.oldLabels))
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err.Error())
+		}
+
+		response, err := testutils.SendHTTPRequest(httprequest, hook)
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err.Error())
+		}
+		if response.UID == "" {
+			t.Fatalf("No tracking UID associated with the response: %+v", response)
+		}
+
+		if response.Allowed != test.shouldBeAllowed {
+			t.Fatalf("%s: %s (groups=%s) %s %s. Test's expectation is that the user %s",
+				test.testID, test.username, test.userGroups,
+				testutils.CanCanNot(response.Allowed), string(test.operation),
+				testutils.CanCanNot(test.shouldBeAllowed))
+		}
+	}
+}
+
+func TestOwnedLabel(t *testing.T) {
+	owned := map[string]string{ownershiplabel.OwnedLabel: "true"}
+	tests := []ownedLabelTestSuites{
+		{
+			testID:          "customer-creates-owned",
+			username:        "my_user",
+			userGroups:      []string{"system:authenticated"},
+			operation:       admissionv1.Create,
+			labels:          owned,
+			shouldBeAllowed: false,
+		},
+		{
+			testID:          "customer-adds-label",
+			username:        "my_user",
+			userGroups:      []string{"system:authenticated"},
+			operation:       admissionv1.Update,
+			labels:          owned,
+			shouldBeAllowed: false,
+		},
+		{
+			testID:          "dedicated-admin-removes-label",
+			username:        "dedicated-admin-user",
+			userGroups:      []string{"dedicated-admins", "system:authenticated"},
+			operation:       admissionv1.Update,
+			oldLabels:       owned,
+			labels:          map[string]string{"app": "mine"},
+			shouldBeAllowed: false,
+		},
+		{
+			testID:          "customer-changes-label",
+			username:        "my_user",
+			userGroups:      []string{"system:authenticated"},
+			operation:       admissionv1.Update,
+			oldLabels:       owned,
+			labels:          map[string]string{ownershiplabel.OwnedLabel: "false"},
+			shouldBeAllowed: false,
+		},
+		{
+			testID:          "customer-updates-owned-keeping-label",
+			username:        "my_user",
+			userGroups:      []string{"system:authenticated"},
+			operation:       admissionv1.Update,
+			oldLabels:       owned,
+			labels:          map[string]string{ownershiplabel.OwnedLabel: "true", "app": "mine"},
+			shouldBeAllowed: true,
+		},
+		{
+			testID:          "hive-creates-owned",
+			username:        "system:admin",
+			userGroups:      []string{"system:masters", "system:authenticated"},
+			operation:       admissionv1.Create,
+			labels:          owned,
+			shouldBeAllowed: true,
+		},
+		{
+			testID:          "srep-removes-label",
+			username:        "system:serviceaccount:openshift-backplane-srep:1234",
+			userGroups:      []string{"system:serviceaccounts:openshift-backplane-srep", "system:authenticated"},
+			operation:       admissionv1.Update,
+			oldLabels:       owned,
+			shouldBeAllowed: true,
+		},
+	}
+	runOwnedLabelTests(t, tests)
+}
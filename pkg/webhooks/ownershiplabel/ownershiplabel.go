@@ -0,0 +1,233 @@
+package ownershiplabel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"gomodules.xyz/jsonpatch/v2"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "ownershiplabel-mutation"
+	docString   string = `Resources created on Managed OpenShift clusters by Hive or SRE are labeled with "%s": "true" at admission time, so label-based protection can tell platform-applied resources apart from customer resources.`
+	// OwnedLabel marks a resource as created by the platform
+	OwnedLabel string = "managed.openshift.io/owned"
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.AllScopes
+	// LabeledResources are the resources labeled with OwnedLabel. The
+	// ownedlabel-validation webhook protects the label on the same resources.
+	LabeledResources = []admissionregv1.Rule{
+		{
+			APIGroups:   []string{""},
+			APIVersions: []string{"v1"},
+			Resources: []string{
+				"configmaps",
+				"limitranges",
+				"namespaces",
+				"resourcequotas",
+				"secrets",
+				"serviceaccounts",
+				"services",
+			},
+			Scope: &scope,
+		},
+		{
+			APIGroups:   []string{"rbac.authorization.k8s.io"},
+			APIVersions: []string{"v1"},
+			Resources: []string{
+				"clusterrolebindings",
+				"clusterroles",
+				"rolebindings",
+				"roles",
+			},
+			Scope: &scope,
+		},
+		{
+			APIGroups:   []string{"apps"},
+			APIVersions: []string{"v1"},
+			Resources: []string{
+				"daemonsets",
+				"deployments",
+			},
+			Scope: &scope,
+		},
+		{
+			APIGroups:   []string{"networking.k8s.io"},
+			APIVersions: []string{"v1"},
+			Resources:   []string{"networkpolicies"},
+			Scope:       &scope,
+		},
+		{
+			APIGroups:   []string{"monitoring.coreos.com"},
+			APIVersions: []string{"*"},
+			Resources: []string{
+				"prometheusrules",
+				"servicemonitors",
+			},
+			Scope: &scope,
+		},
+		{
+			APIGroups:   []string{"quota.openshift.io"},
+			APIVersions: []string{"*"},
+			Resources:   []string{"clusterresourcequotas"},
+			Scope:       &scope,
+		},
+	}
+	rules = LabeledRules(admissionregv1.Create)
+	// platformUsers apply resources on behalf of the platform. Hive applies
+	// SyncSets with the admin kubeconfig, which authenticates as system:admin.
+	platformUsers  = hookconfig.Identities(hookconfig.PlatformAdminUsersFor(WebhookName), hookconfig.SREAdminUsers)
+	platformGroups = hookconfig.SREAdminGroups
+)
+
+// LabeledRules returns the rules matching operations on the LabeledResources
+func LabeledRules(operations ...admissionregv1.OperationType) []admissionregv1.RuleWithOperations {
+	rules := make([]admissionregv1.RuleWithOperations, 0, len(LabeledResources))
+	for _, rule := range LabeledResources {
+		rules = append(rules, admissionregv1.RuleWithOperations{Operations: operations, Rule: rule})
+	}
+	return rules
+}
+
+// OwnershipLabelWebhook mutates platform-created resources to carry OwnedLabel
+type OwnershipLabelWebhook struct{}
+
+// NewWebhook creates the new webhook
+func NewWebhook() *OwnershipLabelWebhook {
+	return &OwnershipLabelWebhook{}
+}
+
+// Authorized implements Webhook interface
+func (s *OwnershipLabelWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate adds OwnedLabel to resources created by platform identities
+func (s *OwnershipLabelWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	if !IsPlatformRequest(request) {
+		return utils.Allow(request, "Only resources created by the platform are labeled as owned")
+	}
+
+	// Only the metadata is needed, so any kind can be decoded
+	obj := &metav1.PartialObjectMetadata{}
+	if err := json.Unmarshal(request.Object.Raw, obj); err != nil {
+		log.Error(err, "Couldn't render the object metadata from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	if _, found := obj.GetLabels()[OwnedLabel]; found {
+		return utils.Allow(request, fmt.Sprintf("%s '%s' is already labeled as owned", request.Kind.Kind, obj.GetName()))
+	}
+
+	var op jsonpatch.JsonPatchOperation
+	if obj.GetLabels() == nil {
+		op = jsonpatch.NewOperation("add", "/metadata/labels", map[string]string{OwnedLabel: "true"})
+	} else {
+		op = jsonpatch.NewOperation("add", "/metadata/labels/"+utils.EscapeJSONPointer(OwnedLabel), "true")
+	}
+
+	log.Info(fmt.Sprintf("Labeling %s %s created by %s as owned", request.Kind.Kind, obj.GetName(), request.UserInfo.Username))
+	// obj.GetName() is empty for objects created with generateName
+	return utils.WithUID(request, admissionctl.Patched(fmt.Sprintf("Labeled %s '%s' as owned", request.Kind.Kind, obj.GetName()), op))
+}
+
+// IsPlatformRequest returns true if the request was made by Hive or SRE
+func IsPlatformRequest(request admissionctl.Request) bool {
+	if slices.Contains(platformUsers, request.UserInfo.Username) {
+		return true
+	}
+	for _, group := range request.UserInfo.Groups {
+		if slices.Contains(platformGroups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetURI implements Webhook interface
+func (s *OwnershipLabelWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *OwnershipLabelWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (len(request.Object.Raw) > 0)
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *OwnershipLabelWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *OwnershipLabelWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *OwnershipLabelWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *OwnershipLabelWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *OwnershipLabelWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *OwnershipLabelWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *OwnershipLabelWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *OwnershipLabelWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *OwnershipLabelWebhook) Doc() string {
+	return fmt.Sprintf(docString, OwnedLabel)
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Return utils.DefaultLabelSelector() to stick with the default
+func (s *OwnershipLabelWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	return utils.DefaultLabelSelector()
+}
+
+func (s *OwnershipLabelWebhook) ClassicEnabled() bool { return true }
+
+func (s *OwnershipLabelWebhook) HypershiftEnabled() bool { return false }
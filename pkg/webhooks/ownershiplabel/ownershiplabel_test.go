@@ -0,0 +1,101 @@
+package ownershiplabel
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+)
+
+type ownershipLabelTestSuites struct {
+	testID         string
+	username       string
+	userGroups     []string
+	labels         map[string]string
+	expectedLabels map[string]string
+}
+
+func runOwnershipLabelTests(t *testing.T, tests []ownershipLabelTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "ConfigMap",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "configmaps",
+	}
+
+	for _, test := range tests {
+		rawConfigMap, err := json.Marshal(corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: test.testID, Namespace: "openshift-config", UID: "1234", Labels: test.labels},
+			Data:       map[string]string{"key": "value"},
+		})
+		if err != nil {
+			t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
+		}
+		mutatedConfigMap := corev1.ConfigMap{}
+		testutils.SendMutation(t, NewWebhook(), testutils.MutationRequest{
+			TestID:     test.testID,
+			GVK:        gvk,
+			GVR:        gvr,
+			Username:   test.username,
+			UserGroups: test.userGroups,
+			Namespace:  "openshift-config",
+			Object:     rawConfigMap,
+		}, &mutatedConfigMap)
+		if !reflect.DeepEqual(mutatedConfigMap.Labels, test.expectedLabels) {
+			t.Fatalf("%s: Expected labels %v, got %v", test.testID, test.expectedLabels, mutatedConfigMap.Labels)
+		}
+	}
+}
+
+func TestOwnershipLabel(t *testing.T) {
+	tests := []ownershipLabelTestSuites{
+		{
+			testID:         "hive-created-resource-labeled",
+			username:       "system:admin",
+			userGroups:     []string{"system:masters", "system:authenticated"},
+			expectedLabels: map[string]string{OwnedLabel: "true"},
+		},
+		{
+			testID:         "backplane-admin-keeps-existing-labels",
+			username:       "backplane-cluster-admin",
+			userGroups:     []string{"system:authenticated"},
+			labels:         map[string]string{"app": "sre"},
+			expectedLabels: map[string]string{"app": "sre", OwnedLabel: "true"},
+		},
+		{
+			testID:         "srep-serviceaccount-labeled",
+			username:       "system:serviceaccount:openshift-backplane-srep:1234",
+			userGroups:     []string{"system:serviceaccounts:openshift-backplane-srep", "system:authenticated"},
+			expectedLabels: map[string]string{OwnedLabel: "true"},
+		},
+		{
+			testID:         "already-owned-untouched",
+			username:       "system:admin",
+			userGroups:     []string{"system:authenticated"},
+			labels:         map[string]string{OwnedLabel: "true"},
+			expectedLabels: map[string]string{OwnedLabel: "true"},
+		},
+		{
+			testID:         "customer-resource-untouched",
+			username:       "my_user",
+			userGroups:     []string{"system:authenticated"},
+			expectedLabels: nil,
+		},
+		{
+			testID:         "dedicated-admin-resource-untouched",
+			username:       "dedicated-admin-user",
+			userGroups:     []string{"dedicated-admins", "system:authenticated"},
+			expectedLabels: nil,
+		},
+	}
+	runOwnershipLabelTests(t, tests)
+}
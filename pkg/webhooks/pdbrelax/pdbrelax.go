@@ -0,0 +1,223 @@
+package pdbrelax
+
+import (
+	"fmt"
+	"net/http"
+
+	"gomodules.xyz/jsonpatch/v2"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/policy"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "pdbrelax-mutation"
+	docString   string = `PodDisruptionBudgets in customer namespaces on Managed OpenShift clusters which allow no disruptions (maxUnavailable of 0 or minAvailable of 100%%) block node drains during upgrades. They are rewritten to maxUnavailable=%s and a warning is returned, or only warned about if the pdbPolicyMode of the ValidatingWebhookPolicy is Warn.`
+	// relaxPDBFeatureFlag is the ClusterDeployment label which opts a
+	// cluster in to PodDisruptionBudget relaxing
+	relaxPDBFeatureFlag string = "ext-managed.openshift.io/relax-pod-disruption-budgets"
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.NamespacedScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+				admissionregv1.Update,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{"policy"},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"poddisruptionbudgets"},
+				Scope:       &scope,
+			},
+		},
+	}
+	// relaxedMaxUnavailable is the smallest budget which still lets a drain progress
+	relaxedMaxUnavailable = intstr.FromInt(1)
+)
+
+// PDBRelaxWebhook mutates customer PodDisruptionBudgets to allow a disruption
+type PDBRelaxWebhook struct {
+	s *utils.LazyScheme
+}
+
+// scheme registers the types the webhook decodes
+var scheme = utils.NewLazyScheme(admissionv1.AddToScheme, policyv1.AddToScheme)
+
+// NewWebhook creates the new webhook
+func NewWebhook() *PDBRelaxWebhook {
+	return &PDBRelaxWebhook{
+		s: scheme,
+	}
+}
+
+// Authorized implements Webhook interface
+func (s *PDBRelaxWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate rewrites customer PodDisruptionBudgets which allow no
+// disruptions so that drains can evict at least one Pod
+func (s *PDBRelaxWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	if hookconfig.IsPrivilegedNamespace(request.Namespace) {
+		return utils.Allow(request, "PodDisruptionBudgets in privileged namespaces are not relaxed")
+	}
+
+	pdb, err := s.renderPDB(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a PodDisruptionBudget from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	var patches []jsonpatch.JsonPatchOperation
+	switch {
+	case isZero(pdb.Spec.MaxUnavailable):
+		patches = []jsonpatch.JsonPatchOperation{
+			jsonpatch.NewOperation("replace", "/spec/maxUnavailable", relaxedMaxUnavailable),
+		}
+	case isFullPercentage(pdb.Spec.MinAvailable):
+		// minAvailable and maxUnavailable are mutually exclusive
+		patches = []jsonpatch.JsonPatchOperation{
+			jsonpatch.NewOperation("remove", "/spec/minAvailable", nil),
+			jsonpatch.NewOperation("add", "/spec/maxUnavailable", relaxedMaxUnavailable),
+		}
+	default:
+		return utils.Allow(request, "PodDisruptionBudget allows disruptions")
+	}
+
+	if policy.Current().PDBMode() == policy.PDBPolicyWarn {
+		warning := fmt.Sprintf("PodDisruptionBudget %s allows no disruptions, which blocks node drains during cluster upgrades. Allow at least one disruption, e.g. maxUnavailable=%s.", pdb.GetName(), relaxedMaxUnavailable.String())
+		return utils.AllowWithWarning(request, "PodDisruptionBudget allows no disruptions", warning)
+	}
+
+	log.Info(fmt.Sprintf("Relaxing PodDisruptionBudget %s/%s", request.Namespace, pdb.GetName()))
+	warning := fmt.Sprintf("PodDisruptionBudget %s allows no disruptions, which blocks node drains during cluster upgrades. It has been changed to maxUnavailable=%s.", pdb.GetName(), relaxedMaxUnavailable.String())
+	return utils.WithUID(request, admissionctl.Patched(fmt.Sprintf("Relaxed PodDisruptionBudget '%s'", pdb.GetName()), patches...).WithWarnings(warning))
+}
+
+// isZero returns true if the budget is 0 or 0%
+func isZero(v *intstr.IntOrString) bool {
+	if v == nil {
+		return false
+	}
+	if v.Type == intstr.Int {
+		return v.IntVal == 0
+	}
+	return v.StrVal == "0%"
+}
+
+// isFullPercentage returns true if the budget is 100%
+func isFullPercentage(v *intstr.IntOrString) bool {
+	return v != nil && v.Type == intstr.String && v.StrVal == "100%"
+}
+
+// renderPDB renders the PodDisruptionBudget in the admission Request
+func (s *PDBRelaxWebhook) renderPDB(request admissionctl.Request) (*policyv1.PodDisruptionBudget, error) {
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return nil, err
+	}
+	pdb := &policyv1.PodDisruptionBudget{}
+	err = decoder.Decode(request, pdb)
+	if err != nil {
+		return nil, err
+	}
+	return pdb, nil
+}
+
+// GetURI implements Webhook interface
+func (s *PDBRelaxWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *PDBRelaxWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "PodDisruptionBudget")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *PDBRelaxWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *PDBRelaxWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *PDBRelaxWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *PDBRelaxWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *PDBRelaxWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *PDBRelaxWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *PDBRelaxWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *PDBRelaxWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *PDBRelaxWebhook) Doc() string {
+	return fmt.Sprintf(docString, relaxedMaxUnavailable.String())
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// PodDisruptionBudget relaxing is opted in to per cluster by setting the
+// relaxPDBFeatureFlag label to 'true' on the ClusterDeployment.
+func (s *PDBRelaxWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	customLabelSelector := utils.DefaultLabelSelector()
+	customLabelSelector.MatchExpressions = append(customLabelSelector.MatchExpressions,
+		metav1.LabelSelectorRequirement{
+			Key:      relaxPDBFeatureFlag,
+			Operator: metav1.LabelSelectorOpIn,
+			Values: []string{
+				"true",
+			},
+		})
+	return customLabelSelector
+}
+
+func (s *PDBRelaxWebhook) ClassicEnabled() bool { return true }
+
+func (s *PDBRelaxWebhook) HypershiftEnabled() bool { return true }
@@ -0,0 +1,137 @@
+package pdbrelax
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/policy"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+)
+
+type pdbRelaxTestSuites struct {
+	testID                 string
+	namespace              string
+	spec                   policyv1.PodDisruptionBudgetSpec
+	expectedMaxUnavailable *intstr.IntOrString
+	expectedMinAvailable   *intstr.IntOrString
+	expectWarning          bool
+}
+
+func intOrStringPtr(v intstr.IntOrString) *intstr.IntOrString {
+	return &v
+}
+
+func runPDBRelaxTests(t *testing.T, tests []pdbRelaxTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "policy",
+		Version: "v1",
+		Kind:    "PodDisruptionBudget",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "policy",
+		Version:  "v1",
+		Resource: "poddisruptionbudgets",
+	}
+
+	for _, test := range tests {
+		rawPDB, err := json.Marshal(policyv1.PodDisruptionBudget{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "policy/v1", Kind: "PodDisruptionBudget"},
+			ObjectMeta: metav1.ObjectMeta{Name: test.testID, Namespace: test.namespace, UID: "1234"},
+			Spec:       test.spec,
+		})
+		if err != nil {
+			t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
+		}
+		mutatedPDB := policyv1.PodDisruptionBudget{}
+		response := testutils.SendMutation(t, NewWebhook(), testutils.MutationRequest{
+			TestID:    test.testID,
+			GVK:       gvk,
+			GVR:       gvr,
+			Namespace: test.namespace,
+			Object:    rawPDB,
+		}, &mutatedPDB)
+		if (len(response.Warnings) > 0) != test.expectWarning {
+			t.Fatalf("%s: Expected warnings %t, got %v", test.testID, test.expectWarning, response.Warnings)
+		}
+		if !reflect.DeepEqual(mutatedPDB.Spec.MaxUnavailable, test.expectedMaxUnavailable) {
+			t.Fatalf("%s: Expected maxUnavailable %v, got %v", test.testID, test.expectedMaxUnavailable, mutatedPDB.Spec.MaxUnavailable)
+		}
+		if !reflect.DeepEqual(mutatedPDB.Spec.MinAvailable, test.expectedMinAvailable) {
+			t.Fatalf("%s: Expected minAvailable %v, got %v", test.testID, test.expectedMinAvailable, mutatedPDB.Spec.MinAvailable)
+		}
+	}
+}
+
+func TestRelaxPDB(t *testing.T) {
+	one := intOrStringPtr(intstr.FromInt(1))
+	tests := []pdbRelaxTestSuites{
+		{
+			testID:                 "max-unavailable-zero",
+			namespace:              "my-namespace",
+			spec:                   policyv1.PodDisruptionBudgetSpec{MaxUnavailable: intOrStringPtr(intstr.FromInt(0))},
+			expectedMaxUnavailable: one,
+			expectWarning:          true,
+		},
+		{
+			testID:                 "max-unavailable-zero-percent",
+			namespace:              "my-namespace",
+			spec:                   policyv1.PodDisruptionBudgetSpec{MaxUnavailable: intOrStringPtr(intstr.FromString("0%"))},
+			expectedMaxUnavailable: one,
+			expectWarning:          true,
+		},
+		{
+			testID:                 "min-available-full-percent",
+			namespace:              "my-namespace",
+			spec:                   policyv1.PodDisruptionBudgetSpec{MinAvailable: intOrStringPtr(intstr.FromString("100%"))},
+			expectedMaxUnavailable: one,
+			expectWarning:          true,
+		},
+		{
+			testID:                 "max-unavailable-two-untouched",
+			namespace:              "my-namespace",
+			spec:                   policyv1.PodDisruptionBudgetSpec{MaxUnavailable: intOrStringPtr(intstr.FromInt(2))},
+			expectedMaxUnavailable: intOrStringPtr(intstr.FromInt(2)),
+		},
+		{
+			testID:               "min-available-half-untouched",
+			namespace:            "my-namespace",
+			spec:                 policyv1.PodDisruptionBudgetSpec{MinAvailable: intOrStringPtr(intstr.FromString("50%"))},
+			expectedMinAvailable: intOrStringPtr(intstr.FromString("50%")),
+		},
+		{
+			testID:                 "privileged-namespace-untouched",
+			namespace:              "openshift-monitoring",
+			spec:                   policyv1.PodDisruptionBudgetSpec{MaxUnavailable: intOrStringPtr(intstr.FromInt(0))},
+			expectedMaxUnavailable: intOrStringPtr(intstr.FromInt(0)),
+		},
+	}
+	runPDBRelaxTests(t, tests)
+}
+
+func TestWarnPDBPolicy(t *testing.T) {
+	policy.SetCurrent(policy.Spec{PDBPolicyMode: policy.PDBPolicyWarn})
+	t.Cleanup(func() { policy.SetCurrent(policy.Spec{}) })
+
+	tests := []pdbRelaxTestSuites{
+		{
+			testID:                 "max-unavailable-zero-only-warned",
+			namespace:              "my-namespace",
+			spec:                   policyv1.PodDisruptionBudgetSpec{MaxUnavailable: intOrStringPtr(intstr.FromInt(0))},
+			expectedMaxUnavailable: intOrStringPtr(intstr.FromInt(0)),
+			expectWarning:          true,
+		},
+		{
+			testID:               "min-available-full-percent-only-warned",
+			namespace:            "my-namespace",
+			spec:                 policyv1.PodDisruptionBudgetSpec{MinAvailable: intOrStringPtr(intstr.FromString("100%"))},
+			expectedMinAvailable: intOrStringPtr(intstr.FromString("100%")),
+			expectWarning:        true,
+		},
+	}
+	runPDBRelaxTests(t, tests)
+}
@@ -0,0 +1,21 @@
+package webhooks
+
+import (
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// PermissionRequirer is implemented by webhooks which read the cluster, e.g.
+// to look up the objects a request refers to, so that the service account of
+// the webhook server is only granted what the deployed webhooks need
+type PermissionRequirer interface {
+	// RequiredPermissions returns the cluster-wide rules the webhook needs
+	RequiredPermissions() []rbacv1.PolicyRule
+}
+
+// RequiredPermissions returns the rules hook needs, if any
+func RequiredPermissions(hook Webhook) []rbacv1.PolicyRule {
+	if requirer, ok := hook.(PermissionRequirer); ok {
+		return requirer.RequiredPermissions()
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package webhooks
+
+import (
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+// PlatformFilter is implemented by webhooks which only apply on some clouds
+// or regions
+type PlatformFilter interface {
+	// EnabledOnPlatform returns whether the webhook applies on platform in
+	// region. region is empty if the platform reports none.
+	EnabledOnPlatform(platform configv1.PlatformType, region string) bool
+}
+
+// EnabledOnPlatform returns whether hook applies on platform in region. The
+// hookconfig.WebhookPlatforms configured for hook take precedence over its
+// PlatformFilter.
+func EnabledOnPlatform(hook Webhook, platform configv1.PlatformType, region string) bool {
+	if enabled, configured := hookconfig.EnabledOnPlatform(hook.Name(), string(platform), region); configured {
+		return enabled
+	}
+	if filter, ok := hook.(PlatformFilter); ok {
+		return filter.EnabledOnPlatform(platform, region)
+	}
+	return true
+}
+
+// ForPlatform returns the webhooks of r with those which don't apply on
+// platform in region allowing every request. They keep serving their URI,
+// since the SelectorSyncSet deploys their configuration on every cloud.
+func (r RegisteredWebhooks) ForPlatform(platform configv1.PlatformType, region string) RegisteredWebhooks {
+	hooks := RegisteredWebhooks{}
+	for name, factory := range r {
+		if EnabledOnPlatform(factory(), platform, region) {
+			hooks[name] = factory
+			continue
+		}
+		factory := factory
+		hooks[name] = func() Webhook {
+			return notApplicable{Webhook: factory(), platform: platform, region: region}
+		}
+	}
+	return hooks
+}
+
+// notApplicable is a webhook which doesn't apply on the cluster's platform
+type notApplicable struct {
+	Webhook
+	platform configv1.PlatformType
+	region   string
+}
+
+// Authorized implements Webhook interface
+func (n notApplicable) Authorized(request admissionctl.Request) admissionctl.Response {
+	location := string(n.platform)
+	if n.region != "" {
+		location += "/" + n.region
+	}
+	return utils.Allow(request, fmt.Sprintf("%s does not apply on %s", n.Name(), location))
+}
@@ -3,15 +3,12 @@ package pod
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"regexp"
 	"sync"
 
-	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
@@ -45,12 +42,15 @@ var (
 
 type PodWebhook struct {
 	mu sync.Mutex
-	s  runtime.Scheme
+	s  *utils.LazyScheme
 }
 
 // ObjectSelector implements Webhook interface
 func (s *PodWebhook) ObjectSelector() *metav1.LabelSelector { return nil }
 
+// NamespaceSelector implements Webhook interface
+func (s *PodWebhook) NamespaceSelector() *metav1.LabelSelector { return nil }
+
 func (s *PodWebhook) Doc() string {
 	return fmt.Sprintf(docString)
 }
@@ -94,7 +94,7 @@ func (s *PodWebhook) Validate(req admissionctl.Request) bool {
 }
 
 func (s *PodWebhook) renderPod(req admissionctl.Request) (*corev1.Pod, error) {
-	decoder, err := admissionctl.NewDecoder(&s.s)
+	decoder, err := s.s.Decoder()
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +110,10 @@ func (s *PodWebhook) renderPod(req admissionctl.Request) (*corev1.Pod, error) {
 	return pod, nil
 }
 
-func isRequestPrivileged(namespace string) bool {
+// IsRequestPrivileged returns true if the namespace is a privileged namespace
+// other than the ones customers are expected to schedule workloads into
+// (unprivilegedNamespace). Exported to be used across packages.
+func IsRequestPrivileged(namespace string) bool {
 	if hookconfig.IsPrivilegedNamespace(namespace) {
 		if unprivilegedNamespaceRe.Match([]byte(namespace)) {
 			return false
@@ -126,7 +129,6 @@ func (s *PodWebhook) Authorized(request admissionctl.Request) admissionctl.Respo
 }
 
 func (s *PodWebhook) authorized(request admissionctl.Request) admissionctl.Response {
-	var ret admissionctl.Response
 	pod, err := s.renderPod(request)
 	if err != nil {
 		log.Error(err, "Couldn't render a Pod from the incoming request")
@@ -135,35 +137,25 @@ func (s *PodWebhook) authorized(request admissionctl.Request) admissionctl.Respo
 
 	// If the incoming Pod is aimed at a privileged namespace except for unprivilegedNamespace, allow it to do whatever it wants.
 	// However, if the pod is targeting a customer's namespace (aka non-privileged), then it may not tolerate certain master/infra node taints.
-	if !isRequestPrivileged(pod.ObjectMeta.GetNamespace()) {
+	if !IsRequestPrivileged(pod.ObjectMeta.GetNamespace()) {
 		for _, toleration := range pod.Spec.Tolerations {
 			if toleration.Key == "node-role.kubernetes.io/infra" && toleration.Effect == corev1.TaintEffectNoSchedule {
-				ret = admissionctl.Denied("Not allowed to schedule a pod with NoSchedule taint on infra node")
-				ret.UID = request.AdmissionRequest.UID
-				return ret
+				return utils.Deny(request, utils.ReasonPodInfraNoScheduleToleration, "Not allowed to schedule a pod with NoSchedule taint on infra node")
 			}
 			if toleration.Key == "node-role.kubernetes.io/infra" && toleration.Effect == corev1.TaintEffectPreferNoSchedule {
-				ret = admissionctl.Denied("Not allowed to schedule a pod with PreferNoSchedule taint on infra node")
-				ret.UID = request.AdmissionRequest.UID
-				return ret
+				return utils.Deny(request, utils.ReasonPodInfraPreferNoScheduleToleration, "Not allowed to schedule a pod with PreferNoSchedule taint on infra node")
 			}
 			if toleration.Key == "node-role.kubernetes.io/master" && toleration.Effect == corev1.TaintEffectNoSchedule {
-				ret = admissionctl.Denied("Not allowed to schedule a pod with NoSchedule taint on master node")
-				ret.UID = request.AdmissionRequest.UID
-				return ret
+				return utils.Deny(request, utils.ReasonPodMasterNoScheduleToleration, "Not allowed to schedule a pod with NoSchedule taint on master node")
 			}
 			if toleration.Key == "node-role.kubernetes.io/master" && toleration.Effect == corev1.TaintEffectPreferNoSchedule {
-				ret = admissionctl.Denied("Not allowed to schedule a pod with PreferNoSchedule taint on master node")
-				ret.UID = request.AdmissionRequest.UID
-				return ret
+				return utils.Deny(request, utils.ReasonPodMasterPreferNoScheduleToleration, "Not allowed to schedule a pod with PreferNoSchedule taint on master node")
 			}
 		}
 	}
 
 	// Hereafter, all requests are controlled by RBAC
-	ret = admissionctl.Allowed("Allowed to create Pod because of RBAC")
-	ret.UID = request.AdmissionRequest.UID
-	return ret
+	return utils.Allow(request, "Allowed to create Pod because of RBAC")
 }
 
 // SyncSetLabelSelector returns the label selector to use in the SyncSet.
@@ -177,20 +169,7 @@ func (s *PodWebhook) HypershiftEnabled() bool { return false }
 
 // NewWebhook creates a new webhook
 func NewWebhook() *PodWebhook {
-	scheme := runtime.NewScheme()
-	err := admissionv1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding admissionsv1 scheme to PodWebhook")
-		os.Exit(1)
-	}
-
-	err = corev1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding corev1 scheme to PodWebhook")
-		os.Exit(1)
-	}
-
 	return &PodWebhook{
-		s: *scheme,
+		s: utils.CoreScheme,
 	}
 }
@@ -583,3 +583,34 @@ func TestUserPositive(t *testing.T) {
 	}
 	runPodTests(t, tests)
 }
+
+func BenchmarkAuthorized(b *testing.B) {
+	gvk := metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	gvr := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+	benchmarks := []struct {
+		name      string
+		namespace string
+		username  string
+		groups    []string
+	}{
+		{name: "customer-pod-create", namespace: "my-project", username: "system:serviceaccount:kube-system:replicaset-controller", groups: []string{"system:serviceaccounts", "system:serviceaccounts:kube-system", "system:authenticated"}},
+		{name: "dedicated-admin-pod-create", namespace: "my-project", username: "alice", groups: []string{"dedicated-admins", "system:authenticated"}},
+	}
+	hook := NewWebhook()
+	for _, bm := range benchmarks {
+		obj := testutils.RepresentativeObject(testutils.RepresentativePod, "frontend-7c9d8b6f4d-x2x9z", bm.namespace)
+		request, err := testutils.CreateRequest(bm.name, gvk, gvr, admissionv1.Create, bm.username, bm.groups, bm.namespace, obj, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if resp := hook.Authorized(request); !resp.Allowed {
+			b.Fatalf("%s: expected the pod to be allowed, got %v", bm.name, resp.Result)
+		}
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				hook.Authorized(request)
+			}
+		})
+	}
+}
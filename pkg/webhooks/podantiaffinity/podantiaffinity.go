@@ -0,0 +1,237 @@
+package podantiaffinity
+
+import (
+	"fmt"
+	"net/http"
+
+	"gomodules.xyz/jsonpatch/v2"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "podantiaffinity-mutation"
+	docString   string = `Deployments in customer namespaces on Managed OpenShift clusters with more than one replica and no affinity are given a preferred pod anti-affinity on their %v label, so replicas are scheduled onto different nodes where possible.`
+	// podAntiAffinityFeatureFlag is the ClusterDeployment label which opts a
+	// cluster in to defaulting pod anti-affinity
+	podAntiAffinityFeatureFlag string = "ext-managed.openshift.io/default-pod-anti-affinity"
+	// antiAffinityWeight is the highest preference weight, since this is the
+	// only scheduling preference on the Deployment
+	antiAffinityWeight int32 = 100
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.NamespacedScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+				admissionregv1.Update,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{"apps"},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"deployments"},
+				Scope:       &scope,
+			},
+		},
+	}
+	// appLabelKeys are the pod labels identifying an app, in order of preference
+	appLabelKeys = []string{
+		"app",
+		"app.kubernetes.io/name",
+	}
+)
+
+// PodAntiAffinityWebhook mutates customer Deployments to spread their replicas
+// across nodes
+type PodAntiAffinityWebhook struct {
+	s *utils.LazyScheme
+}
+
+// scheme registers the types the webhook decodes
+var scheme = utils.NewLazyScheme(admissionv1.AddToScheme, appsv1.AddToScheme)
+
+// NewWebhook creates the new webhook
+func NewWebhook() *PodAntiAffinityWebhook {
+	return &PodAntiAffinityWebhook{
+		s: scheme,
+	}
+}
+
+// Authorized implements Webhook interface
+func (s *PodAntiAffinityWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate adds a preferred pod anti-affinity to multi-replica
+// customer Deployments which define no affinity
+func (s *PodAntiAffinityWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	if hookconfig.IsPrivilegedNamespace(request.Namespace) {
+		return utils.Allow(request, "Deployments in privileged namespaces are exempt from anti-affinity defaulting")
+	}
+
+	deployment, err := s.renderDeployment(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a Deployment from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	// A nil replicas count defaults to 1
+	if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas <= 1 {
+		return utils.Allow(request, "Single-replica Deployments are not given anti-affinity")
+	}
+	if deployment.Spec.Template.Spec.Affinity != nil {
+		return utils.Allow(request, fmt.Sprintf("Deployment '%s' already defines affinity", deployment.GetName()))
+	}
+
+	key, value, found := appLabel(deployment.Spec.Template.GetLabels())
+	if !found {
+		return utils.Allow(request, fmt.Sprintf("Deployment '%s' has no app label to spread its pods by", deployment.GetName()))
+	}
+
+	log.Info(fmt.Sprintf("Adding pod anti-affinity to deployment %s/%s", request.Namespace, deployment.GetName()))
+	return utils.WithUID(request, admissionctl.Patched(
+		fmt.Sprintf("Added pod anti-affinity to deployment '%s'", deployment.GetName()),
+		jsonpatch.NewOperation("add", "/spec/template/spec/affinity", defaultAffinity(key, value)),
+	))
+}
+
+// appLabel returns the first of appLabelKeys set in the pod labels
+func appLabel(labels map[string]string) (string, string, bool) {
+	for _, key := range appLabelKeys {
+		if value, found := labels[key]; found {
+			return key, value, true
+		}
+	}
+	return "", "", false
+}
+
+// defaultAffinity returns a preferred anti-affinity against pods with the same
+// app label on the same node
+func defaultAffinity(key, value string) corev1.Affinity {
+	return corev1.Affinity{
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+				{
+					Weight: antiAffinityWeight,
+					PodAffinityTerm: corev1.PodAffinityTerm{
+						LabelSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{key: value},
+						},
+						TopologyKey: corev1.LabelHostname,
+					},
+				},
+			},
+		},
+	}
+}
+
+// renderDeployment renders the Deployment in the admission Request
+func (s *PodAntiAffinityWebhook) renderDeployment(request admissionctl.Request) (*appsv1.Deployment, error) {
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return nil, err
+	}
+	deployment := &appsv1.Deployment{}
+	err = decoder.Decode(request, deployment)
+	if err != nil {
+		return nil, err
+	}
+	return deployment, nil
+}
+
+// GetURI implements Webhook interface
+func (s *PodAntiAffinityWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *PodAntiAffinityWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "Deployment")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *PodAntiAffinityWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *PodAntiAffinityWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *PodAntiAffinityWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *PodAntiAffinityWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *PodAntiAffinityWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *PodAntiAffinityWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *PodAntiAffinityWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *PodAntiAffinityWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *PodAntiAffinityWebhook) Doc() string {
+	return fmt.Sprintf(docString, appLabelKeys)
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Anti-affinity defaulting is opted in to per cluster by setting the
+// podAntiAffinityFeatureFlag label to 'true' on the ClusterDeployment.
+func (s *PodAntiAffinityWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	customLabelSelector := utils.DefaultLabelSelector()
+	customLabelSelector.MatchExpressions = append(customLabelSelector.MatchExpressions,
+		metav1.LabelSelectorRequirement{
+			Key:      podAntiAffinityFeatureFlag,
+			Operator: metav1.LabelSelectorOpIn,
+			Values: []string{
+				"true",
+			},
+		})
+	return customLabelSelector
+}
+
+func (s *PodAntiAffinityWebhook) ClassicEnabled() bool { return true }
+
+func (s *PodAntiAffinityWebhook) HypershiftEnabled() bool { return true }
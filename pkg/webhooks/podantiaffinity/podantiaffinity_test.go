@@ -0,0 +1,136 @@
+package podantiaffinity
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+)
+
+type podAntiAffinityTestSuites struct {
+	testID           string
+	namespace        string
+	replicas         *int32
+	labels           map[string]string
+	affinity         *corev1.Affinity
+	expectedAffinity *corev1.Affinity
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func runPodAntiAffinityTests(t *testing.T, tests []podAntiAffinityTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "apps",
+		Version: "v1",
+		Kind:    "Deployment",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "apps",
+		Version:  "v1",
+		Resource: "deployments",
+	}
+
+	for _, test := range tests {
+		rawDeployment, err := json.Marshal(appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+			ObjectMeta: metav1.ObjectMeta{Name: test.testID, Namespace: test.namespace, UID: "1234"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: test.replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: test.labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: test.labels},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "web", Image: "quay.io/example/web:v1"}},
+						Affinity:   test.affinity,
+					},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
+		}
+		mutatedDeployment := appsv1.Deployment{}
+		testutils.SendMutation(t, NewWebhook(), testutils.MutationRequest{
+			TestID:    test.testID,
+			GVK:       gvk,
+			GVR:       gvr,
+			Namespace: test.namespace,
+			Object:    rawDeployment,
+		}, &mutatedDeployment)
+		if !reflect.DeepEqual(mutatedDeployment.Spec.Template.Spec.Affinity, test.expectedAffinity) {
+			t.Fatalf("%s: Expected affinity %v, got %v", test.testID, test.expectedAffinity, mutatedDeployment.Spec.Template.Spec.Affinity)
+		}
+	}
+}
+
+func TestDefaultPodAntiAffinity(t *testing.T) {
+	webAffinity := defaultAffinity("app", "web")
+	nameAffinity := defaultAffinity("app.kubernetes.io/name", "api")
+	own := &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{
+				{
+					Weight: 1,
+					Preference: corev1.NodeSelectorTerm{
+						MatchExpressions: []corev1.NodeSelectorRequirement{
+							{Key: "node-role.kubernetes.io/worker", Operator: corev1.NodeSelectorOpExists},
+						},
+					},
+				},
+			},
+		},
+	}
+	tests := []podAntiAffinityTestSuites{
+		{
+			testID:           "multi-replica-app-label",
+			namespace:        "my-namespace",
+			replicas:         int32Ptr(3),
+			labels:           map[string]string{"app": "web"},
+			expectedAffinity: &webAffinity,
+		},
+		{
+			testID:           "multi-replica-recommended-name-label",
+			namespace:        "my-namespace",
+			replicas:         int32Ptr(2),
+			labels:           map[string]string{"app.kubernetes.io/name": "api"},
+			expectedAffinity: &nameAffinity,
+		},
+		{
+			testID:           "single-replica-untouched",
+			namespace:        "my-namespace",
+			replicas:         int32Ptr(1),
+			labels:           map[string]string{"app": "web"},
+			expectedAffinity: nil,
+		},
+		{
+			testID:           "own-affinity-untouched",
+			namespace:        "my-namespace",
+			replicas:         int32Ptr(3),
+			labels:           map[string]string{"app": "web"},
+			affinity:         own,
+			expectedAffinity: own,
+		},
+		{
+			testID:           "no-app-label-untouched",
+			namespace:        "my-namespace",
+			replicas:         int32Ptr(3),
+			labels:           map[string]string{"tier": "frontend"},
+			expectedAffinity: nil,
+		},
+		{
+			testID:           "privileged-namespace-untouched",
+			namespace:        "openshift-monitoring",
+			replicas:         int32Ptr(3),
+			labels:           map[string]string{"app": "web"},
+			expectedAffinity: nil,
+		},
+	}
+	runPodAntiAffinityTests(t, tests)
+}
@@ -0,0 +1,254 @@
+package podcostlabels
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/ttlcache"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/pod"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "podcostlabels-mutation"
+	docString   string = `Pods created in customer namespaces on Managed OpenShift clusters are given the cost allocation labels %v of their namespace, so fleet-level chargeback reporting can attribute them. Labels already set on the Pod are left untouched.`
+	// costLabelsEnvVar names the environment variable overriding
+	// defaultCostLabels, as a comma separated list of label keys
+	costLabelsEnvVar string = "COST_ALLOCATION_LABELS"
+	// namespaceCacheTTL is how long the labels of a namespace are cached, and
+	// so how long a change to them takes to apply to new Pods
+	namespaceCacheTTL  = 30 * time.Second
+	namespaceCacheSize = 4096
+	// lookupTimeout bounds the lookup of a namespace, within the timeout of
+	// the webhook
+	lookupTimeout = time.Second
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.NamespacedScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"pods"},
+				Scope:       &scope,
+			},
+		},
+	}
+	// defaultCostLabels are the namespace labels copied onto pods
+	defaultCostLabels = []string{
+		"api.openshift.com/legal-entity-id",
+		"cost-center",
+		"team",
+	}
+	// namespaceLabels caches the labels of namespaces, so that a burst of
+	// Pods in a namespace, e.g. of a scaled up Deployment, makes one lookup
+	namespaceLabels = ttlcache.New[map[string]string](namespaceCacheTTL, namespaceCacheSize)
+)
+
+// PodCostLabelsWebhook mutates customer Pods to carry their namespace's cost
+// allocation labels
+type PodCostLabelsWebhook struct {
+	s               *utils.LazyScheme
+	kubeClient      *utils.LazyClient
+	namespaceLabels *ttlcache.Cache[map[string]string]
+	labelKeys       []string
+}
+
+// NewWebhook creates the new webhook
+func NewWebhook() *PodCostLabelsWebhook {
+	labelKeys := defaultCostLabels
+	if v := os.Getenv(costLabelsEnvVar); v != "" {
+		labelKeys = []string{}
+		for _, key := range strings.Split(v, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				labelKeys = append(labelKeys, key)
+			}
+		}
+	}
+
+	return &PodCostLabelsWebhook{
+		s:               utils.CoreScheme,
+		kubeClient:      utils.CoreClient,
+		namespaceLabels: namespaceLabels,
+		labelKeys:       labelKeys,
+	}
+}
+
+// Authorized implements Webhook interface
+func (s *PodCostLabelsWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate copies the cost allocation labels of the namespace onto
+// customer Pods
+func (s *PodCostLabelsWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	if pod.IsRequestPrivileged(request.Namespace) {
+		return utils.Allow(request, "Pods in privileged namespaces are not labeled for cost allocation")
+	}
+
+	p, err := s.renderPod(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a Pod from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	labels, err := s.namespaceLabels.Get(request.Namespace, func() (map[string]string, error) {
+		return s.getNamespaceLabels(request.Namespace)
+	})
+	if err != nil {
+		log.Error(err, fmt.Sprintf("Failed to get namespace %s", request.Namespace))
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+
+	wanted := map[string]string{}
+	for _, key := range s.labelKeys {
+		if value, found := labels[key]; found {
+			wanted[key] = value
+		}
+	}
+
+	patches := utils.AddLabelPatches(p.GetLabels(), wanted)
+	if len(patches) == 0 {
+		return utils.Allow(request, "Pod already carries its namespace's cost allocation labels")
+	}
+
+	log.Info(fmt.Sprintf("Adding cost allocation labels to pod %s/%s", request.Namespace, p.GetName()))
+	return utils.WithUID(request, admissionctl.Patched(fmt.Sprintf("Added cost allocation labels to pod '%s'", p.GetName()), patches...))
+}
+
+// getNamespaceLabels gets the labels of the namespace from the API server
+func (s *PodCostLabelsWebhook) getNamespaceLabels(name string) (map[string]string, error) {
+	kubeClient, err := s.kubeClient.Client()
+	if err != nil {
+		return nil, fmt.Errorf("fail creating KubeClient for PodCostLabelsWebhook: %v", err)
+	}
+	// Concurrent requests in the namespace share the lookup of the first, so
+	// it must not be canceled with it
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	ns := &corev1.Namespace{}
+	if err := kubeClient.Get(ctx, client.ObjectKey{Name: name}, ns); err != nil {
+		return nil, err
+	}
+	return ns.GetLabels(), nil
+}
+
+// renderPod renders the Pod in the admission Request
+func (s *PodCostLabelsWebhook) renderPod(request admissionctl.Request) (*corev1.Pod, error) {
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return nil, err
+	}
+	p := &corev1.Pod{}
+	err = decoder.Decode(request, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// RequiredPermissions implements webhooks.PermissionRequirer, the webhook
+// copies the cost labels from the namespace of the Pod
+func (s *PodCostLabelsWebhook) RequiredPermissions() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{corev1.GroupName},
+			Resources: []string{"namespaces"},
+			Verbs:     []string{"get"},
+		},
+	}
+}
+
+// GetURI implements Webhook interface
+func (s *PodCostLabelsWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *PodCostLabelsWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "Pod")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *PodCostLabelsWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *PodCostLabelsWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *PodCostLabelsWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *PodCostLabelsWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *PodCostLabelsWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *PodCostLabelsWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *PodCostLabelsWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *PodCostLabelsWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *PodCostLabelsWebhook) Doc() string {
+	return fmt.Sprintf(docString, defaultCostLabels)
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Return utils.DefaultLabelSelector() to stick with the default
+func (s *PodCostLabelsWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	return utils.DefaultLabelSelector()
+}
+
+func (s *PodCostLabelsWebhook) ClassicEnabled() bool { return true }
+
+func (s *PodCostLabelsWebhook) HypershiftEnabled() bool { return true }
@@ -0,0 +1,146 @@
+package podcostlabels
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/ttlcache"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+func newMockNamespace(name string, labels map[string]string) client.Client {
+	s := runtime.NewScheme()
+	_ = corev1.AddToScheme(s)
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	return fake.NewClientBuilder().WithScheme(s).WithObjects(ns).Build()
+}
+
+type podCostLabelsTestSuites struct {
+	testID          string
+	namespace       string
+	namespaceLabels map[string]string
+	podLabels       map[string]string
+	expectedLabels  map[string]string
+}
+
+func runPodCostLabelsTests(t *testing.T, tests []podCostLabelsTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "Pod",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "pods",
+	}
+
+	for _, test := range tests {
+		rawPod, err := json.Marshal(corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: test.testID, Namespace: test.namespace, UID: "1234", Labels: test.podLabels},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: "quay.io/example/app:v1"}},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
+		}
+		mutatedPod := corev1.Pod{}
+		hook := NewWebhook()
+		hook.kubeClient = utils.StaticClient(newMockNamespace(test.namespace, test.namespaceLabels))
+		hook.namespaceLabels = ttlcache.New[map[string]string](namespaceCacheTTL, namespaceCacheSize)
+		testutils.SendMutation(t, hook, testutils.MutationRequest{
+			TestID:    test.testID,
+			GVK:       gvk,
+			GVR:       gvr,
+			Namespace: test.namespace,
+			Object:    rawPod,
+		}, &mutatedPod)
+		if !reflect.DeepEqual(mutatedPod.Labels, test.expectedLabels) {
+			t.Fatalf("%s: Expected labels %v, got %v", test.testID, test.expectedLabels, mutatedPod.Labels)
+		}
+	}
+}
+
+func TestPodCostLabels(t *testing.T) {
+	tests := []podCostLabelsTestSuites{
+		{
+			testID:          "unlabeled-pod-gets-namespace-cost-labels",
+			namespace:       "my-namespace",
+			namespaceLabels: map[string]string{"cost-center": "cc-42", "team": "payments", "kubernetes.io/metadata.name": "my-namespace"},
+			expectedLabels:  map[string]string{"cost-center": "cc-42", "team": "payments"},
+		},
+		{
+			testID:          "pod-keeps-own-labels",
+			namespace:       "my-namespace",
+			namespaceLabels: map[string]string{"cost-center": "cc-42", "api.openshift.com/legal-entity-id": "abc123"},
+			podLabels:       map[string]string{"app": "web", "cost-center": "cc-7"},
+			expectedLabels:  map[string]string{"app": "web", "cost-center": "cc-7", "api.openshift.com/legal-entity-id": "abc123"},
+		},
+		{
+			testID:          "namespace-without-cost-labels",
+			namespace:       "my-namespace",
+			namespaceLabels: map[string]string{"kubernetes.io/metadata.name": "my-namespace"},
+			podLabels:       map[string]string{"app": "web"},
+			expectedLabels:  map[string]string{"app": "web"},
+		},
+		{
+			testID:          "privileged-namespace-untouched",
+			namespace:       "openshift-monitoring",
+			namespaceLabels: map[string]string{"team": "sre"},
+			expectedLabels:  nil,
+		},
+	}
+	runPodCostLabelsTests(t, tests)
+}
+
+func TestCostLabelsFromEnv(t *testing.T) {
+	t.Setenv(costLabelsEnvVar, "billing/account, ,project")
+	hook := NewWebhook()
+	if !reflect.DeepEqual(hook.labelKeys, []string{"billing/account", "project"}) {
+		t.Fatalf("Expected cost labels from the environment, got %v", hook.labelKeys)
+	}
+}
+
+func TestNamespaceLabelsCached(t *testing.T) {
+	kubeClient := newMockNamespace("my-namespace", map[string]string{"team": "web"})
+	hook := NewWebhook()
+	hook.kubeClient = utils.StaticClient(kubeClient)
+	hook.namespaceLabels = ttlcache.New[map[string]string](namespaceCacheTTL, namespaceCacheSize)
+	if labels, err := hook.namespaceLabels.Get("my-namespace", func() (map[string]string, error) {
+		return hook.getNamespaceLabels("my-namespace")
+	}); err != nil || labels["team"] != "web" {
+		t.Fatalf("Expected the labels of the namespace, got %v, %v", labels, err)
+	}
+
+	// Later Pods of the namespace use the cached labels rather than getting
+	// the namespace again
+	if err := kubeClient.Delete(context.Background(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "my-namespace"}}); err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	rawPod, _ := json.Marshal(corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "cached", Namespace: "my-namespace", UID: "1234"},
+	})
+	mutatedPod := corev1.Pod{}
+	testutils.SendMutation(t, hook, testutils.MutationRequest{
+		TestID:    "cached-namespace-labels",
+		GVK:       metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		GVR:       metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+		Namespace: "my-namespace",
+		Object:    rawPod,
+	}, &mutatedPod)
+	if mutatedPod.Labels["team"] != "web" {
+		t.Fatalf("Expected the cached labels of the namespace, got %v", mutatedPod.Labels)
+	}
+}
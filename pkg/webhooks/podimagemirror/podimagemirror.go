@@ -0,0 +1,339 @@
+package podimagemirror
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"gomodules.xyz/jsonpatch/v2"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/ttlcache"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/pod"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "podimagemirror-mutation"
+	docString   string = `Image references in Pods created in customer namespaces on disconnected Managed OpenShift clusters are rewritten to the first mirror configured for their repository by ImageDigestMirrorSets, ImageTagMirrorSets or ImageContentSourcePolicies.`
+	// rewriteImageMirrorsFeatureFlag is the ClusterDeployment label which opts
+	// a cluster in to image reference rewriting
+	rewriteImageMirrorsFeatureFlag string = "ext-managed.openshift.io/rewrite-image-mirrors"
+	// mirrorsCacheTTL is how long the mirror configuration is cached, and so
+	// how long a change to it takes to apply to new Pods
+	mirrorsCacheTTL = 30 * time.Second
+	// lookupTimeout bounds the lookup of the mirror configuration, within the
+	// timeout of the webhook
+	lookupTimeout = time.Second
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.NamespacedScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"pods"},
+				Scope:       &scope,
+			},
+		},
+	}
+)
+
+// mirrorRule maps a source repository to the mirror which replaces it
+type mirrorRule struct {
+	source string
+	mirror string
+}
+
+// clusterMirrors are the mirror rules of the cluster's mirror configuration
+type clusterMirrors struct {
+	digestRules []mirrorRule
+	tagRules    []mirrorRule
+}
+
+// PodImageMirrorWebhook mutates Pod image references to use configured mirrors
+type PodImageMirrorWebhook struct {
+	s          *utils.LazyScheme
+	kubeClient *utils.LazyClient
+	mirrors    *ttlcache.Cache[clusterMirrors]
+}
+
+var (
+	// scheme registers the types the webhook decodes
+	scheme     = utils.NewLazyScheme(admissionv1.AddToScheme, corev1.AddToScheme, configv1.AddToScheme, operatorv1alpha1.AddToScheme)
+	kubeClient = utils.NewLazyClient(scheme)
+	// mirrors caches the mirror configuration, which is the same for every Pod
+	mirrors = ttlcache.New[clusterMirrors](mirrorsCacheTTL, 1)
+)
+
+// NewWebhook creates the new webhook
+func NewWebhook() *PodImageMirrorWebhook {
+	return &PodImageMirrorWebhook{
+		s:          scheme,
+		kubeClient: kubeClient,
+		mirrors:    mirrors,
+	}
+}
+
+// Authorized implements Webhook interface
+func (s *PodImageMirrorWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate rewrites customer Pod images which match a mirror rule
+func (s *PodImageMirrorWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	if pod.IsRequestPrivileged(request.Namespace) {
+		return utils.Allow(request, "Pods in privileged namespaces are exempt from image mirror rewriting")
+	}
+
+	p, err := s.renderPod(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a Pod from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	m, err := s.mirrors.Get("cluster", s.mirrorRules)
+	if err != nil {
+		log.Error(err, "Failed to read the cluster image mirror configuration")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+
+	// Each image is replaced by its own operation rather than by diffing a
+	// re-encoded Pod, which would drop the fields the vendored API types don't
+	// know.
+	patches := []jsonpatch.JsonPatchOperation{}
+	for i, c := range p.Spec.InitContainers {
+		if image, ok := rewriteImage(c.Image, m.digestRules, m.tagRules); ok {
+			patches = append(patches, jsonpatch.NewOperation("replace", fmt.Sprintf("/spec/initContainers/%d/image", i), image))
+		}
+	}
+	for i, c := range p.Spec.Containers {
+		if image, ok := rewriteImage(c.Image, m.digestRules, m.tagRules); ok {
+			patches = append(patches, jsonpatch.NewOperation("replace", fmt.Sprintf("/spec/containers/%d/image", i), image))
+		}
+	}
+	if len(patches) == 0 {
+		return utils.Allow(request, "No Pod images match a configured mirror")
+	}
+
+	log.Info(fmt.Sprintf("Rewriting images to mirrors for pod %s/%s", request.Namespace, p.GetName()))
+	return utils.WithUID(request, admissionctl.Patched(fmt.Sprintf("Rewrote images of pod '%s' to their mirrors", p.GetName()), patches...))
+}
+
+// mirrorRules collects the first mirror for each source in the cluster's
+// mirror configuration
+func (s *PodImageMirrorWebhook) mirrorRules() (clusterMirrors, error) {
+	digestRules := []mirrorRule{}
+	tagRules := []mirrorRule{}
+
+	kubeClient, err := s.kubeClient.Client()
+	if err != nil {
+		return clusterMirrors{}, fmt.Errorf("fail creating KubeClient for PodImageMirrorWebhook: %v", err)
+	}
+	// Concurrent requests share the lookup of the first, so it must not be
+	// canceled with it
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+
+	idmsList := &configv1.ImageDigestMirrorSetList{}
+	if err := kubeClient.List(ctx, idmsList); err != nil {
+		return clusterMirrors{}, fmt.Errorf("failed to list ImageDigestMirrorSets: %v", err)
+	}
+	for _, idms := range idmsList.Items {
+		for _, m := range idms.Spec.ImageDigestMirrors {
+			if len(m.Mirrors) > 0 {
+				digestRules = append(digestRules, mirrorRule{source: m.Source, mirror: string(m.Mirrors[0])})
+			}
+		}
+	}
+
+	icspList := &operatorv1alpha1.ImageContentSourcePolicyList{}
+	if err := kubeClient.List(ctx, icspList); err != nil {
+		return clusterMirrors{}, fmt.Errorf("failed to list ImageContentSourcePolicies: %v", err)
+	}
+	for _, icsp := range icspList.Items {
+		for _, m := range icsp.Spec.RepositoryDigestMirrors {
+			if len(m.Mirrors) > 0 {
+				digestRules = append(digestRules, mirrorRule{source: m.Source, mirror: m.Mirrors[0]})
+			}
+		}
+	}
+
+	itmsList := &configv1.ImageTagMirrorSetList{}
+	if err := kubeClient.List(ctx, itmsList); err != nil {
+		return clusterMirrors{}, fmt.Errorf("failed to list ImageTagMirrorSets: %v", err)
+	}
+	for _, itms := range itmsList.Items {
+		for _, m := range itms.Spec.ImageTagMirrors {
+			if len(m.Mirrors) > 0 {
+				tagRules = append(tagRules, mirrorRule{source: m.Source, mirror: string(m.Mirrors[0])})
+			}
+		}
+	}
+
+	return clusterMirrors{digestRules: digestRules, tagRules: tagRules}, nil
+}
+
+// rewriteImage returns the image with its repository replaced by the mirror of
+// the most specific matching rule. Digest references use digestRules and all
+// other references use tagRules. It returns false if no rule matched.
+func rewriteImage(image string, digestRules, tagRules []mirrorRule) (string, bool) {
+	rules := tagRules
+	if strings.Contains(image, "@") {
+		rules = digestRules
+	}
+
+	best := mirrorRule{}
+	for _, r := range rules {
+		if repositoryMatches(image, r.source) && len(r.source) > len(best.source) {
+			best = r
+		}
+	}
+	if best.source == "" {
+		return image, false
+	}
+	return best.mirror + strings.TrimPrefix(image, best.source), true
+}
+
+// repositoryMatches returns true if image lives in source, which is either the
+// exact repository or a parent registry/namespace of it
+func repositoryMatches(image, source string) bool {
+	if !strings.HasPrefix(image, source) {
+		return false
+	}
+	rest := image[len(source):]
+	return rest == "" || strings.HasPrefix(rest, "/") || strings.HasPrefix(rest, ":") || strings.HasPrefix(rest, "@")
+}
+
+// renderPod renders the Pod in the admission Request
+func (s *PodImageMirrorWebhook) renderPod(request admissionctl.Request) (*corev1.Pod, error) {
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return nil, err
+	}
+	p := &corev1.Pod{}
+	err = decoder.Decode(request, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// RequiredPermissions implements webhooks.PermissionRequirer, the webhook
+// looks up the image mirrors of the cluster
+func (s *PodImageMirrorWebhook) RequiredPermissions() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{configv1.GroupName},
+			Resources: []string{"imagedigestmirrorsets", "imagetagmirrorsets"},
+			Verbs:     []string{"list"},
+		},
+		{
+			APIGroups: []string{operatorv1alpha1.GroupName},
+			Resources: []string{"imagecontentsourcepolicies"},
+			Verbs:     []string{"list"},
+		},
+	}
+}
+
+// GetURI implements Webhook interface
+func (s *PodImageMirrorWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *PodImageMirrorWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "Pod")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *PodImageMirrorWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *PodImageMirrorWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *PodImageMirrorWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *PodImageMirrorWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *PodImageMirrorWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *PodImageMirrorWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *PodImageMirrorWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *PodImageMirrorWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *PodImageMirrorWebhook) Doc() string {
+	return docString
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Image rewriting is opted in to per cluster by setting the
+// rewriteImageMirrorsFeatureFlag label to 'true' on the ClusterDeployment.
+func (s *PodImageMirrorWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	customLabelSelector := utils.DefaultLabelSelector()
+	customLabelSelector.MatchExpressions = append(customLabelSelector.MatchExpressions,
+		metav1.LabelSelectorRequirement{
+			Key:      rewriteImageMirrorsFeatureFlag,
+			Operator: metav1.LabelSelectorOpIn,
+			Values: []string{
+				"true",
+			},
+		})
+	return customLabelSelector
+}
+
+func (s *PodImageMirrorWebhook) ClassicEnabled() bool { return true }
+
+func (s *PodImageMirrorWebhook) HypershiftEnabled() bool { return false }
@@ -0,0 +1,193 @@
+package podimagemirror
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/ttlcache"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const testDigest string = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func newMockMirrors(obs ...client.Object) client.Client {
+	s := runtime.NewScheme()
+	_ = configv1.Install(s)
+	_ = operatorv1alpha1.Install(s)
+	return fake.NewClientBuilder().WithScheme(s).WithObjects(obs...).Build()
+}
+
+func createRawPodJSON(name string, spec corev1.PodSpec, namespace string) ([]byte, error) {
+	str := `{
+		"apiVersion": "v1",
+		"kind": "Pod",
+		"metadata": {
+			"name": "%s",
+			"namespace": "%s",
+			"uid": "1234"
+		},
+		"spec": %s
+	}`
+
+	partial, err := json.Marshal(spec)
+	return []byte(fmt.Sprintf(str, name, namespace, string(partial))), err
+}
+
+type podImageMirrorTestSuites struct {
+	testID        string
+	namespace     string
+	image         string
+	expectedImage string
+}
+
+func runPodImageMirrorTests(t *testing.T, tests []podImageMirrorTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "Pod",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "pods",
+	}
+	mirrors := []client.Object{
+		&configv1.ImageDigestMirrorSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "digest-mirrors"},
+			Spec: configv1.ImageDigestMirrorSetSpec{
+				ImageDigestMirrors: []configv1.ImageDigestMirrors{
+					{Source: "quay.io/acme", Mirrors: []configv1.ImageMirror{"mirror.example.com/acme", "backup.example.com/acme"}},
+					{Source: "quay.io/acme/special", Mirrors: []configv1.ImageMirror{"special.example.com/acme"}},
+				},
+			},
+		},
+		&operatorv1alpha1.ImageContentSourcePolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy-mirrors"},
+			Spec: operatorv1alpha1.ImageContentSourcePolicySpec{
+				RepositoryDigestMirrors: []operatorv1alpha1.RepositoryDigestMirrors{
+					{Source: "docker.io/library/busybox", Mirrors: []string{"mirror.example.com/busybox"}},
+				},
+			},
+		},
+		&configv1.ImageTagMirrorSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "tag-mirrors"},
+			Spec: configv1.ImageTagMirrorSetSpec{
+				ImageTagMirrors: []configv1.ImageTagMirrors{
+					{Source: "registry.example.com", Mirrors: []configv1.ImageMirror{"mirror.example.com/registry"}},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		spec := corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: test.image}},
+		}
+		rawPod, err := createRawPodJSON(test.testID, spec, test.namespace)
+		if err != nil {
+			t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
+		}
+		mutatedPod := corev1.Pod{}
+		hook := NewWebhook()
+		hook.kubeClient = utils.StaticClient(newMockMirrors(mirrors...))
+		hook.mirrors = ttlcache.New[clusterMirrors](mirrorsCacheTTL, 1)
+		testutils.SendMutation(t, hook, testutils.MutationRequest{
+			TestID:    test.testID,
+			GVK:       gvk,
+			GVR:       gvr,
+			Namespace: test.namespace,
+			Object:    rawPod,
+		}, &mutatedPod)
+		if mutatedPod.Spec.Containers[0].Image != test.expectedImage {
+			t.Fatalf("%s: Expected image %s, got %s", test.testID, test.expectedImage, mutatedPod.Spec.Containers[0].Image)
+		}
+	}
+}
+
+func TestRewriteImages(t *testing.T) {
+	tests := []podImageMirrorTestSuites{
+		{
+			testID:        "digest-image-uses-first-mirror",
+			namespace:     "my-namespace",
+			image:         "quay.io/acme/app@" + testDigest,
+			expectedImage: "mirror.example.com/acme/app@" + testDigest,
+		},
+		{
+			testID:        "most-specific-source-wins",
+			namespace:     "my-namespace",
+			image:         "quay.io/acme/special@" + testDigest,
+			expectedImage: "special.example.com/acme@" + testDigest,
+		},
+		{
+			testID:        "icsp-digest-image-rewritten",
+			namespace:     "my-namespace",
+			image:         "docker.io/library/busybox@" + testDigest,
+			expectedImage: "mirror.example.com/busybox@" + testDigest,
+		},
+		{
+			testID:        "tag-image-uses-tag-mirrors",
+			namespace:     "my-namespace",
+			image:         "registry.example.com/team/app:v1",
+			expectedImage: "mirror.example.com/registry/team/app:v1",
+		},
+		{
+			testID:        "tag-image-ignores-digest-mirrors",
+			namespace:     "my-namespace",
+			image:         "quay.io/acme/app:latest",
+			expectedImage: "quay.io/acme/app:latest",
+		},
+		{
+			testID:        "partial-path-segment-not-matched",
+			namespace:     "my-namespace",
+			image:         "quay.io/acmecorp/app@" + testDigest,
+			expectedImage: "quay.io/acmecorp/app@" + testDigest,
+		},
+		{
+			testID:        "privileged-namespace-untouched",
+			namespace:     "openshift-monitoring",
+			image:         "quay.io/acme/app@" + testDigest,
+			expectedImage: "quay.io/acme/app@" + testDigest,
+		},
+	}
+	runPodImageMirrorTests(t, tests)
+}
+
+func TestRewriteImagesKeepsNewerFields(t *testing.T) {
+	mutatedPod := map[string]interface{}{}
+	hook := NewWebhook()
+	hook.kubeClient = utils.StaticClient(newMockMirrors(&configv1.ImageTagMirrorSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "tag-mirrors"},
+		Spec: configv1.ImageTagMirrorSetSpec{
+			ImageTagMirrors: []configv1.ImageTagMirrors{
+				{Source: "quay.io/example", Mirrors: []configv1.ImageMirror{"mirror.example.com/example"}},
+			},
+		},
+	}))
+	hook.mirrors = ttlcache.New[clusterMirrors](mirrorsCacheTTL, 1)
+	testutils.SendMutation(t, hook, testutils.MutationRequest{
+		TestID:    "pod-with-newer-fields",
+		GVK:       metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		GVR:       metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+		Namespace: "my-namespace",
+		Object:    []byte(fmt.Sprintf(testutils.PodWithNewerFields, "pod-with-newer-fields", "my-namespace")),
+	}, &mutatedPod)
+	testutils.KeepsNewerPodFields(t, mutatedPod)
+	spec := mutatedPod["spec"].(map[string]interface{})
+	for _, containers := range []string{"initContainers", "containers"} {
+		c := spec[containers].([]interface{})[0].(map[string]interface{})
+		if image := c["image"].(string); !strings.HasPrefix(image, "mirror.example.com/example/") {
+			t.Fatalf("Expected the image of the %s to be rewritten to the mirror, got %s", containers, image)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package podimageregistry
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "podimageregistry-validation"
+	docString   string = `Under the FedRAMP compliance profile, Pods in customer namespaces may only pull images from the allowed registries.`
+	// defaultRegistry is the registry of images named without one
+	defaultRegistry string = "docker.io"
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.NamespacedScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+				admissionregv1.Update,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"pods"},
+				Scope:       &scope,
+			},
+		},
+	}
+)
+
+// PodImageRegistryWebhook denies customer Pods pulling images from registries
+// outside the allowed list
+type PodImageRegistryWebhook struct {
+	s *utils.LazyScheme
+}
+
+// NewWebhook creates the new webhook
+func NewWebhook() *PodImageRegistryWebhook {
+	return &PodImageRegistryWebhook{
+		s: utils.CoreScheme,
+	}
+}
+
+// Authorized implements Webhook interface
+func (s *PodImageRegistryWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	return s.authorized(request)
+}
+
+func (s *PodImageRegistryWebhook) authorized(request admissionctl.Request) admissionctl.Response {
+	if hookconfig.IsPrivilegedNamespace(request.Namespace) {
+		return utils.Allow(request, "Pods in privileged namespaces may pull images from any registry")
+	}
+
+	pod, err := s.renderPod(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a Pod from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	for _, image := range podImages(pod) {
+		registry := imageRegistry(image)
+		if !hookconfig.IsAllowedImageRegistry(registry) {
+			log.Info(fmt.Sprintf("Denying image %s from registry %s for pod %s/%s", image, registry, request.Namespace, pod.GetName()))
+			return utils.Deny(request, utils.ReasonImageRegistryNotAllowed, fmt.Sprintf("Image %s is pulled from registry %s, images may only be pulled from %s", image, registry, strings.Join(hookconfig.AllowedImageRegistries, ", ")))
+		}
+	}
+
+	return utils.Allow(request, "All images are pulled from allowed registries")
+}
+
+// podImages returns the images of every container of pod
+func podImages(pod *corev1.Pod) []string {
+	images := []string{}
+	for _, c := range pod.Spec.InitContainers {
+		images = append(images, c.Image)
+	}
+	for _, c := range pod.Spec.Containers {
+		images = append(images, c.Image)
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		images = append(images, c.Image)
+	}
+	return images
+}
+
+// imageRegistry returns the registry host of image. As with the container
+// runtime, the first path component is only a registry if it looks like a
+// host, otherwise the image comes from defaultRegistry.
+func imageRegistry(image string) string {
+	host, _, found := strings.Cut(image, "/")
+	if !found || !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return defaultRegistry
+	}
+	return host
+}
+
+// renderPod renders the Pod in the admission Request
+func (s *PodImageRegistryWebhook) renderPod(request admissionctl.Request) (*corev1.Pod, error) {
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return nil, err
+	}
+	pod := &corev1.Pod{}
+	err = decoder.Decode(request, pod)
+	if err != nil {
+		return nil, err
+	}
+	return pod, nil
+}
+
+// GetURI implements Webhook interface
+func (s *PodImageRegistryWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *PodImageRegistryWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "Pod")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *PodImageRegistryWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *PodImageRegistryWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *PodImageRegistryWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *PodImageRegistryWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *PodImageRegistryWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *PodImageRegistryWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *PodImageRegistryWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *PodImageRegistryWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *PodImageRegistryWebhook) Doc() string {
+	return docString
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+func (s *PodImageRegistryWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	return utils.DefaultLabelSelector()
+}
+
+// EnabledForCompliance implements webhooks.ComplianceFilter
+func (s *PodImageRegistryWebhook) EnabledForCompliance(compliance utils.Compliance) bool {
+	return compliance == utils.ComplianceFedRAMP
+}
+
+func (s *PodImageRegistryWebhook) ClassicEnabled() bool { return true }
+
+func (s *PodImageRegistryWebhook) HypershiftEnabled() bool { return true }
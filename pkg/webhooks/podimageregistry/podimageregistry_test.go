@@ -0,0 +1,104 @@
+package podimageregistry
+
+import (
+	"encoding/json"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+func runRegistryTest(t *testing.T, testID, namespace string, spec corev1.PodSpec, shouldBeAllowed bool) {
+	t.Helper()
+	gvk := metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	gvr := metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+	raw, err := json.Marshal(corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-pod", Namespace: namespace},
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatalf("Couldn't marshal the pod: %v", err)
+	}
+
+	hook := NewWebhook()
+	httprequest, err := testutils.CreateHTTPRequest(hook.GetURI(),
+		testID, gvk, gvr, admissionv1.Create, "dedicated-admin", []string{"system:authenticated", "dedicated-admins"}, namespace, &runtime.RawExtension{Raw: raw}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	response, err := testutils.SendHTTPRequest(httprequest, hook)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err.Error())
+	}
+	if response.UID == "" {
+		t.Fatalf("No tracking UID associated with the response.")
+	}
+	if response.Allowed != shouldBeAllowed {
+		t.Fatalf("%s: expected allowed %v, got %v: %v", testID, shouldBeAllowed, response.Allowed, response.Result)
+	}
+}
+
+func TestAllowedRegistries(t *testing.T) {
+	spec := corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "init", Image: "registry.redhat.io/ubi9/ubi:latest"}},
+		Containers: []corev1.Container{
+			{Name: "app", Image: "quay.io/example/app@sha256:0123456789abcdef"},
+			{Name: "internal", Image: "image-registry.openshift-image-registry.svc:5000/my-project/app:v1"},
+		},
+	}
+	runRegistryTest(t, "allowed-registries", "my-project", spec, true)
+}
+
+func TestExternalRegistry(t *testing.T) {
+	for testID, image := range map[string]string{
+		"external-registry": "ghcr.io/example/app:v1",
+		"implicit-docker":   "nginx:latest",
+		"docker-namespace":  "library/nginx",
+		"localhost":         "localhost/app",
+	} {
+		spec := corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}}
+		runRegistryTest(t, testID, "my-project", spec, false)
+	}
+}
+
+func TestExternalInitContainer(t *testing.T) {
+	spec := corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "init", Image: "docker.io/busybox"}},
+		Containers:     []corev1.Container{{Name: "app", Image: "quay.io/example/app"}},
+	}
+	runRegistryTest(t, "external-init-container", "my-project", spec, false)
+}
+
+func TestPrivilegedNamespace(t *testing.T) {
+	spec := corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "ghcr.io/example/app:v1"}}}
+	runRegistryTest(t, "privileged-namespace", "openshift-monitoring", spec, true)
+}
+
+func TestImageRegistry(t *testing.T) {
+	for image, expected := range map[string]string{
+		"nginx":                       "docker.io",
+		"library/nginx:1.25":          "docker.io",
+		"quay.io/example/app":         "quay.io",
+		"registry.example.com:5000/a": "registry.example.com:5000",
+		"localhost/app":               "localhost",
+	} {
+		if registry := imageRegistry(image); registry != expected {
+			t.Errorf("Expected registry %s for %s, got %s", expected, image, registry)
+		}
+	}
+}
+
+func TestEnabledForCompliance(t *testing.T) {
+	hook := NewWebhook()
+	if hook.EnabledForCompliance(utils.ComplianceNone) {
+		t.Error("Expected the webhook to be disabled without a compliance profile")
+	}
+	if !hook.EnabledForCompliance(utils.ComplianceFedRAMP) {
+		t.Error("Expected the webhook to be enabled under fedramp")
+	}
+}
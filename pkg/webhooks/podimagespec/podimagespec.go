@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"os"
 	"regexp"
 
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/k8sutil"
@@ -16,8 +15,8 @@ import (
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/json"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -51,40 +50,15 @@ var (
 
 // PodImageSpecWebhook mutates an image spec in a pod
 type PodImageSpecWebhook struct {
-	s          *runtime.Scheme
+	s          *utils.LazyScheme
 	kubeClient client.Client
 }
 
+// scheme registers the types the webhook decodes
+var scheme = utils.NewLazyScheme(admissionv1.AddToScheme, admissionregv1.AddToScheme, corev1.AddToScheme, imagestreamv1.AddToScheme, registryv1.AddToScheme)
+
 // NewWebhook creates the new webhook
 func NewWebhook() *PodImageSpecWebhook {
-	scheme := runtime.NewScheme()
-
-	err := admissionv1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding admissionv1 scheme to PodImageSpecWebhook")
-		os.Exit(1)
-	}
-	err = admissionregv1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding admissionregv1 scheme to PodImageSpecWebhook")
-		os.Exit(1)
-	}
-	err = corev1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding corev1 scheme to PodImageSpecWebhook")
-		os.Exit(1)
-	}
-	err = imagestreamv1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding imagestreamv1 scheme to PodImageSpecWebhook")
-		os.Exit(1)
-	}
-	err = registryv1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding registryv1 scheme to PodImageSpecWebhook")
-		os.Exit(1)
-	}
-
 	return &PodImageSpecWebhook{
 		s: scheme,
 	}
@@ -95,73 +69,59 @@ func (s *PodImageSpecWebhook) Authorized(request admissionctl.Request) admission
 	ret := s.authorized(request)
 	if err := ret.Complete(request); err != nil {
 		log.Error(err, "Failed to complete the request")
-		ret = admissionctl.Errored(http.StatusInternalServerError, err)
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
 	}
 	return ret
 }
 
 func (s *PodImageSpecWebhook) authorized(request admissionctl.Request) admissionctl.Response {
 	var err error
-	var ret admissionctl.Response
 	ctx := context.Background()
 
 	if s.kubeClient == nil {
-		s.kubeClient, err = k8sutil.KubeClient(s.s)
+		kubeScheme, err := s.s.Scheme()
+		if err == nil {
+			s.kubeClient, err = k8sutil.KubeClient(kubeScheme)
+		}
 		if err != nil {
 			log.Error(err, "Fail creating KubeClient for PodImageSpecWebhook")
-			ret = admissionctl.Errored(http.StatusBadRequest, err)
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
 		}
 	}
 
 	pod, err := s.renderPod(request)
 	if err != nil {
 		log.Error(err, "couldn't render a Pod from the incoming request")
-		ret = admissionctl.Errored(http.StatusBadRequest, err)
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
 	}
 
 	if !podContainsContainerRegexMatch(pod) {
-		ret = admissionctl.Allowed("Pod image spec is valid")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "Pod image spec is valid")
 	}
 
 	registryAvailable, err := s.checkImageRegistryStatus(ctx)
 	if err != nil {
 		log.Error(err, "failed to check image registry status")
-		ret = admissionctl.Errored(http.StatusInternalServerError, err)
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
 	}
 
 	if registryAvailable {
-		ret = admissionctl.Allowed("Image registry is available, no mutation required")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "Image registry is available, no mutation required")
 	}
 
 	mutatedPod, err := s.mutatePod(ctx, pod)
 	if err != nil {
 		log.Error(err, "Unable mutate pod")
-		ret = admissionctl.Errored(http.StatusInternalServerError, err)
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
 	}
 
-	ret = admissionctl.PatchResponseFromRaw(request.Object.Raw, mutatedPod)
-	ret.UID = request.AdmissionRequest.UID
-	return ret
+	return utils.WithUID(request, admissionctl.PatchResponseFromRaw(request.Object.Raw, mutatedPod))
 
 }
 
 // renderPod renders the Pod in the admission Request
 func (s *PodImageSpecWebhook) renderPod(request admissionctl.Request) (*corev1.Pod, error) {
-	decoder, err := admissionctl.NewDecoder(s.s)
+	decoder, err := s.s.Decoder()
 	if err != nil {
 		return nil, err
 	}
@@ -266,6 +226,23 @@ func (s *PodImageSpecWebhook) lookupImageStreamTagSpec(ctx context.Context, imag
 	return imageStreamTag.Tag.From.Name, nil
 }
 
+// RequiredPermissions implements webhooks.PermissionRequirer, the webhook
+// reads the image registry configuration and the ImageStreamTags Pods refer to
+func (s *PodImageSpecWebhook) RequiredPermissions() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{registryv1.GroupVersion.Group},
+			Resources: []string{"configs"},
+			Verbs:     []string{"get"},
+		},
+		{
+			APIGroups: []string{imagestreamv1.GroupName},
+			Resources: []string{"imagestreamtags"},
+			Verbs:     []string{"get"},
+		},
+	}
+}
+
 // GetURI implements Webhook interface
 func (s *PodImageSpecWebhook) GetURI() string {
 	return "/" + WebhookName
@@ -305,6 +282,11 @@ func (s *PodImageSpecWebhook) ObjectSelector() *metav1.LabelSelector {
 	return nil
 }
 
+// NamespaceSelector implements Webhook interface
+func (s *PodImageSpecWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
 // SideEffects implements Webhook interface
 func (s *PodImageSpecWebhook) SideEffects() admissionregv1.SideEffectClass {
 	return admissionregv1.SideEffectClassNone
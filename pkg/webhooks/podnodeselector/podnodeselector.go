@@ -0,0 +1,181 @@
+package podnodeselector
+
+import (
+	"fmt"
+	"net/http"
+
+	"gomodules.xyz/jsonpatch/v2"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/pod"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "podnodeselector-mutation"
+	docString   string = `Pods created in customer namespaces on Managed OpenShift clusters without any placement constraints are given a nodeSelector of %s so that they are scheduled on worker nodes.`
+	// workerNodeLabel is the node label carried by all worker nodes
+	workerNodeLabel string = "node-role.kubernetes.io/worker"
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.NamespacedScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"pods"},
+				Scope:       &scope,
+			},
+		},
+	}
+)
+
+// PodNodeSelectorWebhook mutates customer Pods to target worker nodes
+type PodNodeSelectorWebhook struct {
+	s *utils.LazyScheme
+}
+
+// NewWebhook creates the new webhook
+func NewWebhook() *PodNodeSelectorWebhook {
+	return &PodNodeSelectorWebhook{
+		s: utils.CoreScheme,
+	}
+}
+
+// Authorized implements Webhook interface
+func (s *PodNodeSelectorWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate adds a worker nodeSelector to customer Pods which have
+// no placement constraints of their own
+func (s *PodNodeSelectorWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	if pod.IsRequestPrivileged(request.Namespace) {
+		return utils.Allow(request, "Pods in privileged namespaces are exempt from default placement")
+	}
+
+	p, err := s.renderPod(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a Pod from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	if hasPlacementConstraints(p) {
+		return utils.Allow(request, "Pod already defines placement constraints")
+	}
+
+	log.Info(fmt.Sprintf("Adding default worker nodeSelector to pod %s/%s", request.Namespace, p.GetName()))
+	return utils.WithUID(request, admissionctl.Patched(
+		fmt.Sprintf("Added default worker nodeSelector to pod '%s'", p.GetName()),
+		jsonpatch.NewOperation("add", "/spec/nodeSelector", map[string]string{workerNodeLabel: ""}),
+	))
+}
+
+// hasPlacementConstraints returns true if the Pod already says where it
+// should (or should not) be scheduled
+func hasPlacementConstraints(p *corev1.Pod) bool {
+	if p.Spec.NodeName != "" || len(p.Spec.NodeSelector) > 0 {
+		return true
+	}
+	return p.Spec.Affinity != nil && p.Spec.Affinity.NodeAffinity != nil
+}
+
+// renderPod renders the Pod in the admission Request
+func (s *PodNodeSelectorWebhook) renderPod(request admissionctl.Request) (*corev1.Pod, error) {
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return nil, err
+	}
+	p := &corev1.Pod{}
+	err = decoder.Decode(request, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetURI implements Webhook interface
+func (s *PodNodeSelectorWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *PodNodeSelectorWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "Pod")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *PodNodeSelectorWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *PodNodeSelectorWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *PodNodeSelectorWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *PodNodeSelectorWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *PodNodeSelectorWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *PodNodeSelectorWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *PodNodeSelectorWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *PodNodeSelectorWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *PodNodeSelectorWebhook) Doc() string {
+	return fmt.Sprintf(docString, workerNodeLabel)
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Return utils.DefaultLabelSelector() to stick with the default
+func (s *PodNodeSelectorWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	return utils.DefaultLabelSelector()
+}
+
+func (s *PodNodeSelectorWebhook) ClassicEnabled() bool { return true }
+
+func (s *PodNodeSelectorWebhook) HypershiftEnabled() bool { return false }
@@ -0,0 +1,125 @@
+package podnodeselector
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+)
+
+func createRawPodJSON(name string, spec corev1.PodSpec, namespace string) ([]byte, error) {
+	str := `{
+		"apiVersion": "v1",
+		"kind": "Pod",
+		"metadata": {
+			"name": "%s",
+			"namespace": "%s",
+			"uid": "1234"
+		},
+		"spec": %s
+	}`
+
+	partial, err := json.Marshal(spec)
+	return []byte(fmt.Sprintf(str, name, namespace, string(partial))), err
+}
+
+type podNodeSelectorTestSuites struct {
+	testID               string
+	namespace            string
+	spec                 corev1.PodSpec
+	expectedNodeSelector map[string]string
+}
+
+func runPodNodeSelectorTests(t *testing.T, tests []podNodeSelectorTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "Pod",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "pods",
+	}
+
+	for _, test := range tests {
+		rawPod, err := createRawPodJSON(test.testID, test.spec, test.namespace)
+		if err != nil {
+			t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
+		}
+		mutatedPod := corev1.Pod{}
+		testutils.SendMutation(t, NewWebhook(), testutils.MutationRequest{
+			TestID:    test.testID,
+			GVK:       gvk,
+			GVR:       gvr,
+			Namespace: test.namespace,
+			Object:    rawPod,
+		}, &mutatedPod)
+		if !reflect.DeepEqual(mutatedPod.Spec.NodeSelector, test.expectedNodeSelector) {
+			t.Fatalf("%s: Expected nodeSelector %v, got %v", test.testID, test.expectedNodeSelector, mutatedPod.Spec.NodeSelector)
+		}
+	}
+}
+
+func TestDefaultNodeSelector(t *testing.T) {
+	containers := []corev1.Container{{Name: "app", Image: "quay.io/app:latest"}}
+	tests := []podNodeSelectorTestSuites{
+		{
+			testID:               "customer-pod-without-placement-gets-worker-selector",
+			namespace:            "my-namespace",
+			spec:                 corev1.PodSpec{Containers: containers},
+			expectedNodeSelector: map[string]string{workerNodeLabel: ""},
+		},
+		{
+			testID:    "customer-pod-with-node-selector-untouched",
+			namespace: "my-namespace",
+			spec: corev1.PodSpec{
+				Containers:   containers,
+				NodeSelector: map[string]string{"disktype": "ssd"},
+			},
+			expectedNodeSelector: map[string]string{"disktype": "ssd"},
+		},
+		{
+			testID:    "customer-pod-with-node-affinity-untouched",
+			namespace: "my-namespace",
+			spec: corev1.PodSpec{
+				Containers: containers,
+				Affinity: &corev1.Affinity{
+					NodeAffinity: &corev1.NodeAffinity{
+						RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+							NodeSelectorTerms: []corev1.NodeSelectorTerm{
+								{
+									MatchExpressions: []corev1.NodeSelectorRequirement{
+										{Key: "disktype", Operator: corev1.NodeSelectorOpIn, Values: []string{"ssd"}},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedNodeSelector: nil,
+		},
+		{
+			testID:    "customer-pod-with-node-name-untouched",
+			namespace: "my-namespace",
+			spec: corev1.PodSpec{
+				Containers: containers,
+				NodeName:   "worker-1",
+			},
+			expectedNodeSelector: nil,
+		},
+		{
+			testID:               "privileged-namespace-pod-untouched",
+			namespace:            "openshift-monitoring",
+			spec:                 corev1.PodSpec{Containers: containers},
+			expectedNodeSelector: nil,
+		},
+	}
+	runPodNodeSelectorTests(t, tests)
+}
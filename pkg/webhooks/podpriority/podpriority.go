@@ -0,0 +1,185 @@
+package podpriority
+
+import (
+	"fmt"
+	"net/http"
+
+	"gomodules.xyz/jsonpatch/v2"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/pod"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "podpriority-mutation"
+	docString   string = `Pods created in customer namespaces on Managed OpenShift clusters without a priorityClassName are assigned the %s PriorityClass.`
+	// PriorityClassName is the platform-defined PriorityClass for customer
+	// workloads. It is shipped alongside the webhook.
+	PriorityClassName string = "managed-customer-workload"
+	// PriorityClassValue is the priority of PriorityClassName. It matches the
+	// priority of Pods without a class so that platform components, which use
+	// the system and openshift-user-critical classes, always preempt first.
+	PriorityClassValue int32 = 0
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.NamespacedScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"pods"},
+				Scope:       &scope,
+			},
+		},
+	}
+)
+
+// PodPriorityWebhook mutates customer Pods to use the customer PriorityClass
+type PodPriorityWebhook struct {
+	s *utils.LazyScheme
+}
+
+// NewWebhook creates the new webhook
+func NewWebhook() *PodPriorityWebhook {
+	return &PodPriorityWebhook{
+		s: utils.CoreScheme,
+	}
+}
+
+// Authorized implements Webhook interface
+func (s *PodPriorityWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate assigns the customer PriorityClass to customer Pods
+// without one
+func (s *PodPriorityWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	if pod.IsRequestPrivileged(request.Namespace) {
+		return utils.Allow(request, "Pods in privileged namespaces are exempt from the default PriorityClass")
+	}
+
+	p, err := s.renderPod(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a Pod from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	if p.Spec.PriorityClassName != "" {
+		return utils.Allow(request, "Pod already defines a PriorityClass")
+	}
+
+	// The Priority admission plugin has already resolved spec.priority by the
+	// time webhooks are called, so it must be kept consistent with the class.
+	// The fields are patched one by one rather than by diffing a re-encoded
+	// Pod, which would drop the fields the vendored API types don't know.
+	preemptionPolicy := corev1.PreemptLowerPriority
+	patches := []jsonpatch.JsonPatchOperation{
+		jsonpatch.NewOperation("add", "/spec/priorityClassName", PriorityClassName),
+		jsonpatch.NewOperation("add", "/spec/priority", PriorityClassValue),
+		jsonpatch.NewOperation("add", "/spec/preemptionPolicy", preemptionPolicy),
+	}
+
+	log.Info(fmt.Sprintf("Assigning PriorityClass %s to pod %s/%s", PriorityClassName, request.Namespace, p.GetName()))
+	return utils.WithUID(request, admissionctl.Patched(fmt.Sprintf("Assigned PriorityClass %s to pod '%s'", PriorityClassName, p.GetName()), patches...))
+}
+
+// renderPod renders the Pod in the admission Request
+func (s *PodPriorityWebhook) renderPod(request admissionctl.Request) (*corev1.Pod, error) {
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return nil, err
+	}
+	p := &corev1.Pod{}
+	err = decoder.Decode(request, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetURI implements Webhook interface
+func (s *PodPriorityWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *PodPriorityWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "Pod")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *PodPriorityWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *PodPriorityWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *PodPriorityWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *PodPriorityWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *PodPriorityWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *PodPriorityWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *PodPriorityWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *PodPriorityWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *PodPriorityWebhook) Doc() string {
+	return fmt.Sprintf(docString, PriorityClassName)
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Return utils.DefaultLabelSelector() to stick with the default
+func (s *PodPriorityWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	return utils.DefaultLabelSelector()
+}
+
+func (s *PodPriorityWebhook) ClassicEnabled() bool { return true }
+
+func (s *PodPriorityWebhook) HypershiftEnabled() bool { return false }
@@ -0,0 +1,123 @@
+package podpriority
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+)
+
+func createRawPodJSON(name string, spec corev1.PodSpec, namespace string) ([]byte, error) {
+	str := `{
+		"apiVersion": "v1",
+		"kind": "Pod",
+		"metadata": {
+			"name": "%s",
+			"namespace": "%s",
+			"uid": "1234"
+		},
+		"spec": %s
+	}`
+
+	partial, err := json.Marshal(spec)
+	return []byte(fmt.Sprintf(str, name, namespace, string(partial))), err
+}
+
+type podPriorityTestSuites struct {
+	testID                string
+	namespace             string
+	spec                  corev1.PodSpec
+	expectedPriorityClass string
+}
+
+func runPodPriorityTests(t *testing.T, tests []podPriorityTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "Pod",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "pods",
+	}
+
+	for _, test := range tests {
+		rawPod, err := createRawPodJSON(test.testID, test.spec, test.namespace)
+		if err != nil {
+			t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
+		}
+		mutatedPod := corev1.Pod{}
+		testutils.SendMutation(t, NewWebhook(), testutils.MutationRequest{
+			TestID:    test.testID,
+			GVK:       gvk,
+			GVR:       gvr,
+			Namespace: test.namespace,
+			Object:    rawPod,
+		}, &mutatedPod)
+		if mutatedPod.Spec.PriorityClassName != test.expectedPriorityClass {
+			t.Fatalf("%s: Expected priorityClassName %q, got %q", test.testID, test.expectedPriorityClass, mutatedPod.Spec.PriorityClassName)
+		}
+		if test.expectedPriorityClass == PriorityClassName &&
+			(mutatedPod.Spec.Priority == nil || *mutatedPod.Spec.Priority != PriorityClassValue) {
+			t.Fatalf("%s: Expected priority %d, got %v", test.testID, PriorityClassValue, mutatedPod.Spec.Priority)
+		}
+	}
+}
+
+func TestDefaultPriorityClass(t *testing.T) {
+	containers := []corev1.Container{{Name: "app", Image: "quay.io/app:latest"}}
+	resolvedPriority := int32(0)
+	tests := []podPriorityTestSuites{
+		{
+			testID:                "customer-pod-without-class",
+			namespace:             "my-namespace",
+			spec:                  corev1.PodSpec{Containers: containers},
+			expectedPriorityClass: PriorityClassName,
+		},
+		{
+			testID:    "customer-pod-with-resolved-priority",
+			namespace: "my-namespace",
+			spec: corev1.PodSpec{
+				Containers: containers,
+				Priority:   &resolvedPriority,
+			},
+			expectedPriorityClass: PriorityClassName,
+		},
+		{
+			testID:    "customer-pod-with-class-untouched",
+			namespace: "my-namespace",
+			spec: corev1.PodSpec{
+				Containers:        containers,
+				PriorityClassName: "my-batch-class",
+			},
+			expectedPriorityClass: "my-batch-class",
+		},
+		{
+			testID:                "privileged-namespace-pod-untouched",
+			namespace:             "openshift-monitoring",
+			spec:                  corev1.PodSpec{Containers: containers},
+			expectedPriorityClass: "",
+		},
+	}
+	runPodPriorityTests(t, tests)
+}
+
+func TestDefaultPriorityClassKeepsNewerFields(t *testing.T) {
+	mutatedPod := map[string]interface{}{}
+	testutils.SendMutation(t, NewWebhook(), testutils.MutationRequest{
+		TestID:    "pod-with-newer-fields",
+		GVK:       metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		GVR:       metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+		Namespace: "my-namespace",
+		Object:    []byte(fmt.Sprintf(testutils.PodWithNewerFields, "pod-with-newer-fields", "my-namespace")),
+	}, &mutatedPod)
+	testutils.KeepsNewerPodFields(t, mutatedPod)
+	if spec := mutatedPod["spec"].(map[string]interface{}); spec["priorityClassName"] != PriorityClassName {
+		t.Fatalf("Expected priorityClassName %q, got %v", PriorityClassName, spec["priorityClassName"])
+	}
+}
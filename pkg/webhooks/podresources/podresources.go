@@ -0,0 +1,323 @@
+package podresources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"gomodules.xyz/jsonpatch/v2"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/ttlcache"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/pod"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "podresources-mutation"
+	docString   string = `Containers created in customer namespaces without a LimitRange on Managed OpenShift clusters which don't request CPU or memory are given default requests of %s CPU and %s memory.`
+	// cpuRequestEnvVar and memoryRequestEnvVar name the environment variables
+	// which override the default requests
+	cpuRequestEnvVar    string = "DEFAULT_CPU_REQUEST"
+	memoryRequestEnvVar string = "DEFAULT_MEMORY_REQUEST"
+	defaultCPURequest   string = "10m"
+	defaultMemRequest   string = "64Mi"
+	// limitRangeCacheTTL is how long whether a namespace has a LimitRange is
+	// cached, and so how long a new or deleted LimitRange takes to apply
+	limitRangeCacheTTL  = 30 * time.Second
+	limitRangeCacheSize = 4096
+	// lookupTimeout bounds the lookup of the LimitRanges of a namespace,
+	// within the timeout of the webhook
+	lookupTimeout = time.Second
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.NamespacedScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"pods"},
+				Scope:       &scope,
+			},
+		},
+	}
+	// hasLimitRange caches whether namespaces have a LimitRange, so that a
+	// burst of Pods in a namespace, e.g. of a scaled up Deployment, makes one
+	// lookup
+	hasLimitRange = ttlcache.New[bool](limitRangeCacheTTL, limitRangeCacheSize)
+)
+
+// PodResourcesWebhook mutates customer Pods to carry default resource requests
+type PodResourcesWebhook struct {
+	kubeClient    *utils.LazyClient
+	hasLimitRange *ttlcache.Cache[bool]
+	requests      corev1.ResourceList
+}
+
+// NewWebhook creates the new webhook
+func NewWebhook() *PodResourcesWebhook {
+	return &PodResourcesWebhook{
+		kubeClient:    utils.CoreClient,
+		hasLimitRange: hasLimitRange,
+		requests: corev1.ResourceList{
+			corev1.ResourceCPU:    quantityFromEnv(cpuRequestEnvVar, defaultCPURequest),
+			corev1.ResourceMemory: quantityFromEnv(memoryRequestEnvVar, defaultMemRequest),
+		},
+	}
+}
+
+// quantityFromEnv parses the quantity in the environment variable envVar,
+// falling back to fallback if it is unset or can't be parsed
+func quantityFromEnv(envVar, fallback string) resource.Quantity {
+	if v := os.Getenv(envVar); v != "" {
+		q, err := resource.ParseQuantity(v)
+		if err == nil {
+			return q
+		}
+		log.Error(err, fmt.Sprintf("Invalid quantity in %s, using the default %s", envVar, fallback))
+	}
+	return resource.MustParse(fallback)
+}
+
+// Authorized implements Webhook interface
+func (s *PodResourcesWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate adds the default requests to customer containers which
+// neither request nor limit CPU or memory
+func (s *PodResourcesWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	if pod.IsRequestPrivileged(request.Namespace) {
+		return utils.Allow(request, "Pods in privileged namespaces are exempt from default resource requests")
+	}
+
+	p, err := renderPod(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a Pod from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	limited, err := s.hasLimitRange.Get(request.Namespace, func() (bool, error) {
+		return s.namespaceHasLimitRange(request.Namespace)
+	})
+	if err != nil {
+		log.Error(err, "Failed to list LimitRanges")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	if limited {
+		return utils.Allow(request, "Namespace has a LimitRange which provides default requests")
+	}
+
+	patches := []jsonpatch.JsonPatchOperation{}
+	for i, c := range p.Spec.InitContainers {
+		patches = append(patches, s.requestPatches(fmt.Sprintf("/spec/initContainers/%d", i), c)...)
+	}
+	for i, c := range p.Spec.Containers {
+		patches = append(patches, s.requestPatches(fmt.Sprintf("/spec/containers/%d", i), c)...)
+	}
+	if len(patches) == 0 {
+		return utils.Allow(request, "All containers already define resource requests")
+	}
+
+	log.Info(fmt.Sprintf("Adding default resource requests to pod %s/%s", request.Namespace, p.Metadata.Name))
+	return utils.WithUID(request, admissionctl.Patched(fmt.Sprintf("Added default resource requests to pod '%s'", p.Metadata.Name), patches...))
+}
+
+// requestPatches constructs the JSONPatch operations adding each default
+// request which the container at path neither requests nor limits.
+// Kubernetes already defaults a missing request to the limit, so limited
+// resources are left alone.
+func (s *PodResourcesWebhook) requestPatches(path string, c container) []jsonpatch.JsonPatchOperation {
+	missing := corev1.ResourceList{}
+	for name, quantity := range s.requests {
+		if c.Resources != nil {
+			if _, found := c.Resources.Requests[name]; found {
+				continue
+			}
+			if _, found := c.Resources.Limits[name]; found {
+				continue
+			}
+		}
+		missing[name] = quantity
+	}
+
+	switch {
+	case len(missing) == 0:
+		return nil
+	case c.Resources == nil:
+		return []jsonpatch.JsonPatchOperation{
+			jsonpatch.NewOperation("add", path+"/resources", corev1.ResourceRequirements{Requests: missing}),
+		}
+	case c.Resources.Requests == nil:
+		return []jsonpatch.JsonPatchOperation{
+			jsonpatch.NewOperation("add", path+"/resources/requests", missing),
+		}
+	}
+
+	names := make([]string, 0, len(missing))
+	for name := range missing {
+		names = append(names, string(name))
+	}
+	// Keep the patch stable for the same input
+	sort.Strings(names)
+	patches := make([]jsonpatch.JsonPatchOperation, 0, len(names))
+	for _, name := range names {
+		patches = append(patches, jsonpatch.NewOperation("add", path+"/resources/requests/"+utils.EscapeJSONPointer(name), missing[corev1.ResourceName(name)]))
+	}
+	return patches
+}
+
+// namespaceHasLimitRange returns true if there is any LimitRange in the namespace
+func (s *PodResourcesWebhook) namespaceHasLimitRange(namespace string) (bool, error) {
+	kubeClient, err := s.kubeClient.Client()
+	if err != nil {
+		return false, fmt.Errorf("fail creating KubeClient for PodResourcesWebhook: %v", err)
+	}
+	// Concurrent requests in the namespace share the lookup of the first, so
+	// it must not be canceled with it
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	limitRanges := &corev1.LimitRangeList{}
+	err = kubeClient.List(ctx, limitRanges, client.InNamespace(namespace), client.Limit(1))
+	if err != nil {
+		return false, fmt.Errorf("failed to list LimitRanges in namespace %s: %v", namespace, err)
+	}
+	return len(limitRanges.Items) > 0, nil
+}
+
+// container is the part of a container the webhook reads. Only the names of
+// its requests and limits are decoded, so that nothing of the Pod is
+// re-encoded and the fields the vendored API types don't know are kept.
+type container struct {
+	Resources *struct {
+		Requests map[corev1.ResourceName]json.RawMessage `json:"requests"`
+		Limits   map[corev1.ResourceName]json.RawMessage `json:"limits"`
+	} `json:"resources"`
+}
+
+// podContainers is the part of a Pod the webhook reads
+type podContainers struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		InitContainers []container `json:"initContainers"`
+		Containers     []container `json:"containers"`
+	} `json:"spec"`
+}
+
+// renderPod renders the containers of the Pod in the admission Request
+func renderPod(request admissionctl.Request) (*podContainers, error) {
+	p := &podContainers{}
+	if err := json.Unmarshal(request.Object.Raw, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// RequiredPermissions implements webhooks.PermissionRequirer, the webhook
+// leaves namespaces with a LimitRange alone
+func (s *PodResourcesWebhook) RequiredPermissions() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{corev1.GroupName},
+			Resources: []string{"limitranges"},
+			Verbs:     []string{"list"},
+		},
+	}
+}
+
+// GetURI implements Webhook interface
+func (s *PodResourcesWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *PodResourcesWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "Pod")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *PodResourcesWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *PodResourcesWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *PodResourcesWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *PodResourcesWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *PodResourcesWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *PodResourcesWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *PodResourcesWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *PodResourcesWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *PodResourcesWebhook) Doc() string {
+	cpu := s.requests[corev1.ResourceCPU]
+	mem := s.requests[corev1.ResourceMemory]
+	return fmt.Sprintf(docString, cpu.String(), mem.String())
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Return utils.DefaultLabelSelector() to stick with the default
+func (s *PodResourcesWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	return utils.DefaultLabelSelector()
+}
+
+func (s *PodResourcesWebhook) ClassicEnabled() bool { return true }
+
+func (s *PodResourcesWebhook) HypershiftEnabled() bool { return true }
@@ -0,0 +1,180 @@
+package podresources
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/ttlcache"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+func newMockLimitRanges(obs ...client.Object) client.Client {
+	s := runtime.NewScheme()
+	_ = corev1.AddToScheme(s)
+	return fake.NewClientBuilder().WithScheme(s).WithObjects(obs...).Build()
+}
+
+func createRawPodJSON(name string, spec corev1.PodSpec, namespace string) ([]byte, error) {
+	str := `{
+		"apiVersion": "v1",
+		"kind": "Pod",
+		"metadata": {
+			"name": "%s",
+			"namespace": "%s",
+			"uid": "1234"
+		},
+		"spec": %s
+	}`
+
+	partial, err := json.Marshal(spec)
+	return []byte(fmt.Sprintf(str, name, namespace, string(partial))), err
+}
+
+type podResourcesTestSuites struct {
+	testID           string
+	namespace        string
+	limitRanges      []client.Object
+	resources        corev1.ResourceRequirements
+	expectedRequests corev1.ResourceList
+}
+
+func runPodResourcesTests(t *testing.T, tests []podResourcesTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "Pod",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "pods",
+	}
+
+	for _, test := range tests {
+		spec := corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "quay.io/app:latest", Resources: test.resources}},
+		}
+		rawPod, err := createRawPodJSON(test.testID, spec, test.namespace)
+		if err != nil {
+			t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
+		}
+		mutatedPod := corev1.Pod{}
+		hook := NewWebhook()
+		hook.kubeClient = utils.StaticClient(newMockLimitRanges(test.limitRanges...))
+		hook.hasLimitRange = ttlcache.New[bool](limitRangeCacheTTL, limitRangeCacheSize)
+		testutils.SendMutation(t, hook, testutils.MutationRequest{
+			TestID:    test.testID,
+			GVK:       gvk,
+			GVR:       gvr,
+			Namespace: test.namespace,
+			Object:    rawPod,
+		}, &mutatedPod)
+		requests := mutatedPod.Spec.Containers[0].Resources.Requests
+		if len(requests) != len(test.expectedRequests) {
+			t.Fatalf("%s: Expected requests %v, got %v", test.testID, test.expectedRequests, requests)
+		}
+		for name, expected := range test.expectedRequests {
+			if actual, found := requests[name]; !found || actual.Cmp(expected) != 0 {
+				t.Fatalf("%s: Expected requests %v, got %v", test.testID, test.expectedRequests, requests)
+			}
+		}
+	}
+}
+
+func TestDefaultRequests(t *testing.T) {
+	tests := []podResourcesTestSuites{
+		{
+			testID:    "container-without-requests-gets-defaults",
+			namespace: "my-namespace",
+			expectedRequests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(defaultCPURequest),
+				corev1.ResourceMemory: resource.MustParse(defaultMemRequest),
+			},
+		},
+		{
+			testID:    "container-with-cpu-request-gets-memory-default",
+			namespace: "my-namespace",
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+			},
+			expectedRequests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1"),
+				corev1.ResourceMemory: resource.MustParse(defaultMemRequest),
+			},
+		},
+		{
+			testID:    "container-with-memory-limit-gets-cpu-default-only",
+			namespace: "my-namespace",
+			resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+			},
+			expectedRequests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse(defaultCPURequest),
+			},
+		},
+		{
+			testID:    "namespace-with-limitrange-untouched",
+			namespace: "my-namespace",
+			limitRanges: []client.Object{
+				&corev1.LimitRange{ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: "my-namespace"}},
+			},
+			expectedRequests: nil,
+		},
+		{
+			testID:           "privileged-namespace-untouched",
+			namespace:        "openshift-monitoring",
+			expectedRequests: nil,
+		},
+	}
+	runPodResourcesTests(t, tests)
+}
+
+func TestRequestsFromEnv(t *testing.T) {
+	t.Setenv(cpuRequestEnvVar, "250m")
+	t.Setenv(memoryRequestEnvVar, "not-a-quantity")
+	hook := NewWebhook()
+
+	cpu := hook.requests[corev1.ResourceCPU]
+	if cpu.Cmp(resource.MustParse("250m")) != 0 {
+		t.Fatalf("Expected CPU request 250m from the environment, got %s", cpu.String())
+	}
+	mem := hook.requests[corev1.ResourceMemory]
+	if mem.Cmp(resource.MustParse(defaultMemRequest)) != 0 {
+		t.Fatalf("Expected invalid memory request to fall back to %s, got %s", defaultMemRequest, mem.String())
+	}
+}
+
+func TestDefaultRequestsKeepsNewerFields(t *testing.T) {
+	mutatedPod := map[string]interface{}{}
+	hook := NewWebhook()
+	hook.kubeClient = utils.StaticClient(newMockLimitRanges())
+	hook.hasLimitRange = ttlcache.New[bool](limitRangeCacheTTL, limitRangeCacheSize)
+	testutils.SendMutation(t, hook, testutils.MutationRequest{
+		TestID:    "pod-with-newer-fields",
+		GVK:       metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		GVR:       metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+		Namespace: "my-namespace",
+		Object:    []byte(fmt.Sprintf(testutils.PodWithNewerFields, "pod-with-newer-fields", "my-namespace")),
+	}, &mutatedPod)
+	testutils.KeepsNewerPodFields(t, mutatedPod)
+
+	raw, _ := json.Marshal(mutatedPod)
+	p := corev1.Pod{}
+	if err := json.Unmarshal(raw, &p); err != nil {
+		t.Fatalf("Expected no error, got %s while decoding the mutated Pod", err.Error())
+	}
+	for _, c := range append(p.Spec.InitContainers, p.Spec.Containers...) {
+		if len(c.Resources.Requests) != 2 {
+			t.Fatalf("Expected the default requests on container %s, got %v", c.Name, c.Resources.Requests)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package podseccomp
+
+import (
+	"fmt"
+	"net/http"
+
+	"gomodules.xyz/jsonpatch/v2"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/pod"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "podseccomp-mutation"
+	docString   string = `Pods created in customer namespaces on Managed OpenShift clusters which do not specify a seccomp profile are given the %s seccomp profile.`
+	// defaultSeccompFeatureFlag is the ClusterDeployment label which opts a
+	// cluster in to seccomp defaulting. It is opt-in since SCCs which don't
+	// list any seccompProfiles reject Pods that set one.
+	defaultSeccompFeatureFlag string = "ext-managed.openshift.io/default-pod-seccomp"
+	// podSeccompAnnotation is the deprecated Pod annotation for the pod-level
+	// seccomp profile. It is still honored by the kubelet.
+	podSeccompAnnotation string = "seccomp.security.alpha.kubernetes.io/pod"
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.NamespacedScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"pods"},
+				Scope:       &scope,
+			},
+		},
+	}
+	runtimeDefault = corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}
+)
+
+// PodSeccompWebhook mutates customer Pods to use the RuntimeDefault seccomp profile
+type PodSeccompWebhook struct {
+	s *utils.LazyScheme
+}
+
+// NewWebhook creates the new webhook
+func NewWebhook() *PodSeccompWebhook {
+	return &PodSeccompWebhook{
+		s: utils.CoreScheme,
+	}
+}
+
+// Authorized implements Webhook interface
+func (s *PodSeccompWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate sets the pod-level seccomp profile to RuntimeDefault on
+// customer Pods which have not chosen one. Containers inherit the pod-level
+// profile, and any profile set on a container still takes precedence.
+func (s *PodSeccompWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	if pod.IsRequestPrivileged(request.Namespace) {
+		return utils.Allow(request, "Pods in privileged namespaces are exempt from seccomp defaulting")
+	}
+
+	p, err := s.renderPod(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a Pod from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	if hasPodSeccompProfile(p) || isWindowsPod(p) {
+		return utils.Allow(request, "Pod already defines a seccomp profile")
+	}
+
+	var op jsonpatch.JsonPatchOperation
+	if p.Spec.SecurityContext == nil {
+		op = jsonpatch.NewOperation("add", "/spec/securityContext", corev1.PodSecurityContext{SeccompProfile: &runtimeDefault})
+	} else {
+		op = jsonpatch.NewOperation("add", "/spec/securityContext/seccompProfile", runtimeDefault)
+	}
+
+	log.Info(fmt.Sprintf("Adding default seccomp profile to pod %s/%s", request.Namespace, p.GetName()))
+	return utils.WithUID(request, admissionctl.Patched(fmt.Sprintf("Added %s seccomp profile to pod '%s'", runtimeDefault.Type, p.GetName()), op))
+}
+
+// hasPodSeccompProfile returns true if the Pod sets a pod-level seccomp
+// profile, either in its securityContext or with the deprecated annotation
+func hasPodSeccompProfile(p *corev1.Pod) bool {
+	if _, found := p.GetAnnotations()[podSeccompAnnotation]; found {
+		return true
+	}
+	return p.Spec.SecurityContext != nil && p.Spec.SecurityContext.SeccompProfile != nil
+}
+
+// isWindowsPod returns true for Pods targeting Windows nodes, which do not
+// support seccomp
+func isWindowsPod(p *corev1.Pod) bool {
+	return p.Spec.OS != nil && p.Spec.OS.Name == corev1.Windows
+}
+
+// renderPod renders the Pod in the admission Request
+func (s *PodSeccompWebhook) renderPod(request admissionctl.Request) (*corev1.Pod, error) {
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return nil, err
+	}
+	p := &corev1.Pod{}
+	err = decoder.Decode(request, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetURI implements Webhook interface
+func (s *PodSeccompWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *PodSeccompWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "Pod")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *PodSeccompWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *PodSeccompWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *PodSeccompWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *PodSeccompWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *PodSeccompWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *PodSeccompWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *PodSeccompWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *PodSeccompWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *PodSeccompWebhook) Doc() string {
+	return fmt.Sprintf(docString, runtimeDefault.Type)
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Seccomp defaulting is opted in to per cluster by setting the
+// defaultSeccompFeatureFlag label to 'true' on the ClusterDeployment.
+func (s *PodSeccompWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	customLabelSelector := utils.DefaultLabelSelector()
+	customLabelSelector.MatchExpressions = append(customLabelSelector.MatchExpressions,
+		metav1.LabelSelectorRequirement{
+			Key:      defaultSeccompFeatureFlag,
+			Operator: metav1.LabelSelectorOpIn,
+			Values: []string{
+				"true",
+			},
+		})
+	return customLabelSelector
+}
+
+func (s *PodSeccompWebhook) ClassicEnabled() bool { return true }
+
+func (s *PodSeccompWebhook) HypershiftEnabled() bool { return true }
@@ -0,0 +1,119 @@
+package podseccomp
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+)
+
+func createRawPodJSON(name string, spec corev1.PodSpec, namespace string) ([]byte, error) {
+	str := `{
+		"apiVersion": "v1",
+		"kind": "Pod",
+		"metadata": {
+			"name": "%s",
+			"namespace": "%s",
+			"uid": "1234"
+		},
+		"spec": %s
+	}`
+
+	partial, err := json.Marshal(spec)
+	return []byte(fmt.Sprintf(str, name, namespace, string(partial))), err
+}
+
+type podSeccompTestSuites struct {
+	testID          string
+	namespace       string
+	spec            corev1.PodSpec
+	expectedProfile *corev1.SeccompProfile
+}
+
+func runPodSeccompTests(t *testing.T, tests []podSeccompTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "Pod",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "pods",
+	}
+
+	for _, test := range tests {
+		rawPod, err := createRawPodJSON(test.testID, test.spec, test.namespace)
+		if err != nil {
+			t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
+		}
+		mutatedPod := corev1.Pod{}
+		testutils.SendMutation(t, NewWebhook(), testutils.MutationRequest{
+			TestID:    test.testID,
+			GVK:       gvk,
+			GVR:       gvr,
+			Namespace: test.namespace,
+			Object:    rawPod,
+		}, &mutatedPod)
+		var profile *corev1.SeccompProfile
+		if mutatedPod.Spec.SecurityContext != nil {
+			profile = mutatedPod.Spec.SecurityContext.SeccompProfile
+		}
+		if !reflect.DeepEqual(profile, test.expectedProfile) {
+			t.Fatalf("%s: Expected seccompProfile %v, got %v", test.testID, test.expectedProfile, profile)
+		}
+	}
+}
+
+func TestDefaultSeccompProfile(t *testing.T) {
+	containers := []corev1.Container{{Name: "app", Image: "quay.io/app:latest"}}
+	nonRoot := true
+	unconfined := &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined}
+	tests := []podSeccompTestSuites{
+		{
+			testID:          "customer-pod-without-security-context",
+			namespace:       "my-namespace",
+			spec:            corev1.PodSpec{Containers: containers},
+			expectedProfile: &runtimeDefault,
+		},
+		{
+			testID:    "customer-pod-with-security-context",
+			namespace: "my-namespace",
+			spec: corev1.PodSpec{
+				Containers:      containers,
+				SecurityContext: &corev1.PodSecurityContext{RunAsNonRoot: &nonRoot},
+			},
+			expectedProfile: &runtimeDefault,
+		},
+		{
+			testID:    "customer-pod-with-explicit-profile-untouched",
+			namespace: "my-namespace",
+			spec: corev1.PodSpec{
+				Containers:      containers,
+				SecurityContext: &corev1.PodSecurityContext{SeccompProfile: unconfined},
+			},
+			expectedProfile: unconfined,
+		},
+		{
+			testID:    "windows-pod-untouched",
+			namespace: "my-namespace",
+			spec: corev1.PodSpec{
+				Containers: containers,
+				OS:         &corev1.PodOS{Name: corev1.Windows},
+			},
+			expectedProfile: nil,
+		},
+		{
+			testID:          "privileged-namespace-pod-untouched",
+			namespace:       "openshift-monitoring",
+			spec:            corev1.PodSpec{Containers: containers},
+			expectedProfile: nil,
+		},
+	}
+	runPodSeccompTests(t, tests)
+}
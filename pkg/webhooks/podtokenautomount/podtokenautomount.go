@@ -0,0 +1,180 @@
+package podtokenautomount
+
+import (
+	"fmt"
+	"net/http"
+
+	"gomodules.xyz/jsonpatch/v2"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/pod"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "podtokenautomount-mutation"
+	docString   string = `Pods created in namespaces labeled with %s=true have automountServiceAccountToken set to false, unless the Pod explicitly sets it to true.`
+	// hardenedNamespaceLabel is the Namespace label which opts a namespace in
+	// to hardened mode
+	hardenedNamespaceLabel string = "managed.openshift.io/hardened"
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.NamespacedScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"pods"},
+				Scope:       &scope,
+			},
+		},
+	}
+)
+
+// PodTokenAutomountWebhook mutates Pods in hardened namespaces to not mount a
+// service account token
+type PodTokenAutomountWebhook struct {
+	s *utils.LazyScheme
+}
+
+// NewWebhook creates the new webhook
+func NewWebhook() *PodTokenAutomountWebhook {
+	return &PodTokenAutomountWebhook{
+		s: utils.CoreScheme,
+	}
+}
+
+// Authorized implements Webhook interface
+func (s *PodTokenAutomountWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate disables service account token automounting on Pods which
+// have not chosen. The Pod field takes precedence over the ServiceAccount's, so
+// this applies whatever the ServiceAccount sets.
+func (s *PodTokenAutomountWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	if pod.IsRequestPrivileged(request.Namespace) {
+		return utils.Allow(request, "Pods in privileged namespaces are exempt from hardened mode")
+	}
+
+	p, err := s.renderPod(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a Pod from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	if p.Spec.AutomountServiceAccountToken != nil {
+		return utils.Allow(request, fmt.Sprintf("Pod explicitly sets automountServiceAccountToken to %t", *p.Spec.AutomountServiceAccountToken))
+	}
+
+	log.Info(fmt.Sprintf("Disabling service account token automount on pod %s/%s", request.Namespace, p.GetName()))
+	return utils.WithUID(request, admissionctl.Patched(
+		fmt.Sprintf("Disabled service account token automount on pod '%s'", p.GetName()),
+		jsonpatch.NewOperation("add", "/spec/automountServiceAccountToken", false),
+	))
+}
+
+// renderPod renders the Pod in the admission Request
+func (s *PodTokenAutomountWebhook) renderPod(request admissionctl.Request) (*corev1.Pod, error) {
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return nil, err
+	}
+	p := &corev1.Pod{}
+	err = decoder.Decode(request, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetURI implements Webhook interface
+func (s *PodTokenAutomountWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *PodTokenAutomountWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "Pod")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *PodTokenAutomountWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *PodTokenAutomountWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *PodTokenAutomountWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *PodTokenAutomountWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *PodTokenAutomountWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface. Only namespaces which have
+// opted in to hardened mode are sent to this webhook.
+func (s *PodTokenAutomountWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			hardenedNamespaceLabel: "true",
+		},
+	}
+}
+
+// SideEffects implements Webhook interface
+func (s *PodTokenAutomountWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *PodTokenAutomountWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *PodTokenAutomountWebhook) Doc() string {
+	return fmt.Sprintf(docString, hardenedNamespaceLabel)
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Return utils.DefaultLabelSelector() to stick with the default
+func (s *PodTokenAutomountWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	return utils.DefaultLabelSelector()
+}
+
+func (s *PodTokenAutomountWebhook) ClassicEnabled() bool { return true }
+
+func (s *PodTokenAutomountWebhook) HypershiftEnabled() bool { return true }
@@ -0,0 +1,89 @@
+package podtokenautomount
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+)
+
+type podTokenAutomountTestSuites struct {
+	testID            string
+	namespace         string
+	automount         *bool
+	expectedAutomount *bool
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func runPodTokenAutomountTests(t *testing.T, tests []podTokenAutomountTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "Pod",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "pods",
+	}
+
+	for _, test := range tests {
+		rawPod, err := json.Marshal(corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: test.testID, Namespace: test.namespace, UID: "1234"},
+			Spec: corev1.PodSpec{
+				Containers:                   []corev1.Container{{Name: "app", Image: "quay.io/example/app:v1"}},
+				AutomountServiceAccountToken: test.automount,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
+		}
+		mutatedPod := corev1.Pod{}
+		testutils.SendMutation(t, NewWebhook(), testutils.MutationRequest{
+			TestID:    test.testID,
+			GVK:       gvk,
+			GVR:       gvr,
+			Namespace: test.namespace,
+			Object:    rawPod,
+		}, &mutatedPod)
+		if !reflect.DeepEqual(mutatedPod.Spec.AutomountServiceAccountToken, test.expectedAutomount) {
+			t.Fatalf("%s: Expected automountServiceAccountToken %v, got %v", test.testID, test.expectedAutomount, mutatedPod.Spec.AutomountServiceAccountToken)
+		}
+	}
+}
+
+func TestDisableTokenAutomount(t *testing.T) {
+	tests := []podTokenAutomountTestSuites{
+		{
+			testID:            "unset-automount-disabled",
+			namespace:         "my-namespace",
+			expectedAutomount: boolPtr(false),
+		},
+		{
+			testID:            "explicit-opt-in-untouched",
+			namespace:         "my-namespace",
+			automount:         boolPtr(true),
+			expectedAutomount: boolPtr(true),
+		},
+		{
+			testID:            "explicit-opt-out-untouched",
+			namespace:         "my-namespace",
+			automount:         boolPtr(false),
+			expectedAutomount: boolPtr(false),
+		},
+		{
+			testID:            "privileged-namespace-untouched",
+			namespace:         "openshift-monitoring",
+			expectedAutomount: nil,
+		},
+	}
+	runPodTokenAutomountTests(t, tests)
+}
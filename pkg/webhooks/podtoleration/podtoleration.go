@@ -0,0 +1,237 @@
+package podtoleration
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"gomodules.xyz/jsonpatch/v2"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/pod"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "podtoleration-mutation"
+	docString   string = `Managed OpenShift Customers may not schedule Pods on infra or master nodes. Tolerations for infra or master node taints, including tolerations of all taints, are removed from Pods created in customer namespaces.`
+	// stripTolerationsFeatureFlag is the ClusterDeployment label which opts a
+	// cluster in to toleration stripping instead of only denying.
+	stripTolerationsFeatureFlag string = "ext-managed.openshift.io/strip-pod-tolerations"
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.NamespacedScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"pods"},
+				Scope:       &scope,
+			},
+		},
+	}
+	// restrictedTaintKeys are the node taints customer Pods may not tolerate
+	restrictedTaintKeys = []string{
+		"node-role.kubernetes.io/infra",
+		"node-role.kubernetes.io/master",
+		"node-role.kubernetes.io/control-plane",
+	}
+	// restrictedTaintEffects are the taint effects which keep customer Pods
+	// off of the restricted nodes. An empty effect tolerates all effects.
+	restrictedTaintEffects = []corev1.TaintEffect{
+		"",
+		corev1.TaintEffectNoSchedule,
+		corev1.TaintEffectPreferNoSchedule,
+	}
+)
+
+// PodTolerationWebhook removes infra and master tolerations from customer Pods
+type PodTolerationWebhook struct {
+	s *utils.LazyScheme
+}
+
+// NewWebhook creates the new webhook
+func NewWebhook() *PodTolerationWebhook {
+	return &PodTolerationWebhook{
+		s: utils.CoreScheme,
+	}
+}
+
+// Authorized implements Webhook interface
+func (s *PodTolerationWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate removes any toleration of a restricted node taint from
+// Pods created in customer namespaces
+func (s *PodTolerationWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	if pod.IsRequestPrivileged(request.Namespace) {
+		return utils.Allow(request, "Pods in privileged namespaces may tolerate any taint")
+	}
+
+	p, err := s.renderPod(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a Pod from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	removed := restrictedTolerations(p.Spec.Tolerations)
+	if len(removed) == 0 {
+		return utils.Allow(request, "Pod does not tolerate infra or master node taints")
+	}
+
+	// Each toleration is removed by its own operation rather than by diffing a
+	// re-encoded Pod, which would drop the fields the vendored API types don't
+	// know. The indices are in descending order, so removing one doesn't shift
+	// the ones still to remove.
+	patches := make([]jsonpatch.JsonPatchOperation, 0, len(removed))
+	warnings := make([]string, 0, len(removed))
+	for _, i := range removed {
+		patches = append(patches, jsonpatch.NewOperation("remove", fmt.Sprintf("/spec/tolerations/%d", i), nil))
+		warnings = append(warnings, removalWarning(p.Spec.Tolerations[i]))
+	}
+
+	log.Info(fmt.Sprintf("Removed %d restricted tolerations from pod %s/%s", len(removed), request.Namespace, p.GetName()))
+	return utils.WithUID(request, admissionctl.Patched(fmt.Sprintf("Removed restricted tolerations from pod '%s'", p.GetName()), patches...).WithWarnings(warnings...))
+}
+
+// isRestrictedToleration returns true if the toleration would allow a Pod to
+// be scheduled on an infra or master node. A toleration with an empty key and
+// the Exists operator tolerates every taint, the restricted ones included.
+func isRestrictedToleration(toleration corev1.Toleration) bool {
+	wildcard := toleration.Key == "" && toleration.Operator == corev1.TolerationOpExists
+	return (wildcard || slices.Contains(restrictedTaintKeys, toleration.Key)) &&
+		slices.Contains(restrictedTaintEffects, toleration.Effect)
+}
+
+// restrictedTolerations returns the indices of the restricted tolerations, in
+// descending order
+func restrictedTolerations(tolerations []corev1.Toleration) []int {
+	removed := []int{}
+	for i := len(tolerations) - 1; i >= 0; i-- {
+		if isRestrictedToleration(tolerations[i]) {
+			removed = append(removed, i)
+		}
+	}
+	return removed
+}
+
+// removalWarning returns the warning telling the user that the toleration
+// was removed
+func removalWarning(toleration corev1.Toleration) string {
+	taint := "taint " + toleration.Key
+	if toleration.Key == "" {
+		taint = "all taints"
+	}
+	return fmt.Sprintf("Removed toleration for %s with effect %q: customer Pods may not be scheduled on infra or master nodes", taint, toleration.Effect)
+}
+
+// renderPod renders the Pod in the admission Request
+func (s *PodTolerationWebhook) renderPod(request admissionctl.Request) (*corev1.Pod, error) {
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return nil, err
+	}
+	p := &corev1.Pod{}
+	err = decoder.Decode(request, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetURI implements Webhook interface
+func (s *PodTolerationWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *PodTolerationWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "Pod")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *PodTolerationWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *PodTolerationWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *PodTolerationWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *PodTolerationWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *PodTolerationWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *PodTolerationWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *PodTolerationWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *PodTolerationWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *PodTolerationWebhook) Doc() string {
+	return docString
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Toleration stripping is opted in to per cluster by setting the
+// stripTolerationsFeatureFlag label to 'true' on the ClusterDeployment.
+func (s *PodTolerationWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	customLabelSelector := utils.DefaultLabelSelector()
+	customLabelSelector.MatchExpressions = append(customLabelSelector.MatchExpressions,
+		metav1.LabelSelectorRequirement{
+			Key:      stripTolerationsFeatureFlag,
+			Operator: metav1.LabelSelectorOpIn,
+			Values: []string{
+				"true",
+			},
+		})
+	return customLabelSelector
+}
+
+func (s *PodTolerationWebhook) ClassicEnabled() bool { return true }
+
+func (s *PodTolerationWebhook) HypershiftEnabled() bool { return false }
@@ -0,0 +1,177 @@
+package podtoleration
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+)
+
+func createRawPodJSON(name string, tolerations []corev1.Toleration, namespace string) ([]byte, error) {
+	str := `{
+		"apiVersion": "v1",
+		"kind": "Pod",
+		"metadata": {
+			"name": "%s",
+			"namespace": "%s",
+			"uid": "1234"
+		},
+		"spec": {
+			"containers": [{"name": "app", "image": "quay.io/app:latest"}],
+			"tolerations": %s
+		}
+	}`
+
+	partial, err := json.Marshal(tolerations)
+	return []byte(fmt.Sprintf(str, name, namespace, string(partial))), err
+}
+
+type podTolerationTestSuites struct {
+	testID              string
+	namespace           string
+	tolerations         []corev1.Toleration
+	expectedTolerations []corev1.Toleration
+	expectedWarnings    int
+}
+
+func runPodTolerationTests(t *testing.T, tests []podTolerationTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "Pod",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "pods",
+	}
+
+	for _, test := range tests {
+		rawPod, err := createRawPodJSON(test.testID, test.tolerations, test.namespace)
+		if err != nil {
+			t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
+		}
+		mutatedPod := corev1.Pod{}
+		response := testutils.SendMutation(t, NewWebhook(), testutils.MutationRequest{
+			TestID:    test.testID,
+			GVK:       gvk,
+			GVR:       gvr,
+			Namespace: test.namespace,
+			Object:    rawPod,
+		}, &mutatedPod)
+		if len(response.Warnings) != test.expectedWarnings {
+			t.Fatalf("%s: Expected %d warnings, got %v", test.testID, test.expectedWarnings, response.Warnings)
+		}
+		if !reflect.DeepEqual(mutatedPod.Spec.Tolerations, test.expectedTolerations) {
+			t.Fatalf("%s: Expected tolerations %v, got %v", test.testID, test.expectedTolerations, mutatedPod.Spec.Tolerations)
+		}
+	}
+}
+
+func TestStripTolerations(t *testing.T) {
+	infraNoSchedule := corev1.Toleration{
+		Key:      "node-role.kubernetes.io/infra",
+		Operator: corev1.TolerationOpExists,
+		Effect:   corev1.TaintEffectNoSchedule,
+	}
+	masterPreferNoSchedule := corev1.Toleration{
+		Key:      "node-role.kubernetes.io/master",
+		Operator: corev1.TolerationOpExists,
+		Effect:   corev1.TaintEffectPreferNoSchedule,
+	}
+	controlPlaneAllEffects := corev1.Toleration{
+		Key:      "node-role.kubernetes.io/control-plane",
+		Operator: corev1.TolerationOpExists,
+	}
+	masterNoExecute := corev1.Toleration{
+		Key:      "node-role.kubernetes.io/master",
+		Operator: corev1.TolerationOpExists,
+		Effect:   corev1.TaintEffectNoExecute,
+	}
+	tolerateEverything := corev1.Toleration{
+		Operator: corev1.TolerationOpExists,
+	}
+	wildcardNoExecute := corev1.Toleration{
+		Operator: corev1.TolerationOpExists,
+		Effect:   corev1.TaintEffectNoExecute,
+	}
+	customToleration := corev1.Toleration{
+		Key:      "my-taint",
+		Operator: corev1.TolerationOpEqual,
+		Value:    "true",
+		Effect:   corev1.TaintEffectNoSchedule,
+	}
+
+	tests := []podTolerationTestSuites{
+		{
+			testID:              "customer-pod-infra-toleration-stripped",
+			namespace:           "my-namespace",
+			tolerations:         []corev1.Toleration{infraNoSchedule, customToleration},
+			expectedTolerations: []corev1.Toleration{customToleration},
+			expectedWarnings:    1,
+		},
+		{
+			testID:              "customer-pod-all-restricted-tolerations-stripped",
+			namespace:           "my-namespace",
+			tolerations:         []corev1.Toleration{infraNoSchedule, masterPreferNoSchedule, controlPlaneAllEffects},
+			expectedTolerations: []corev1.Toleration{},
+			expectedWarnings:    3,
+		},
+		{
+			testID:              "customer-pod-wildcard-toleration-stripped",
+			namespace:           "my-namespace",
+			tolerations:         []corev1.Toleration{customToleration, tolerateEverything, wildcardNoExecute, infraNoSchedule},
+			expectedTolerations: []corev1.Toleration{customToleration, wildcardNoExecute},
+			expectedWarnings:    2,
+		},
+		{
+			testID:              "customer-pod-noexecute-toleration-kept",
+			namespace:           "my-namespace",
+			tolerations:         []corev1.Toleration{masterNoExecute},
+			expectedTolerations: []corev1.Toleration{masterNoExecute},
+			expectedWarnings:    0,
+		},
+		{
+			testID:              "customer-pod-custom-toleration-kept",
+			namespace:           "my-namespace",
+			tolerations:         []corev1.Toleration{customToleration},
+			expectedTolerations: []corev1.Toleration{customToleration},
+			expectedWarnings:    0,
+		},
+		{
+			testID:              "privileged-pod-tolerations-kept",
+			namespace:           "openshift-monitoring",
+			tolerations:         []corev1.Toleration{infraNoSchedule},
+			expectedTolerations: []corev1.Toleration{infraNoSchedule},
+			expectedWarnings:    0,
+		},
+		{
+			testID:              "openshift-operators-pod-tolerations-stripped",
+			namespace:           "openshift-operators",
+			tolerations:         []corev1.Toleration{masterPreferNoSchedule},
+			expectedTolerations: []corev1.Toleration{},
+			expectedWarnings:    1,
+		},
+	}
+	runPodTolerationTests(t, tests)
+}
+
+func TestStripTolerationsKeepsNewerFields(t *testing.T) {
+	mutatedPod := map[string]interface{}{}
+	testutils.SendMutation(t, NewWebhook(), testutils.MutationRequest{
+		TestID:    "pod-with-newer-fields",
+		GVK:       metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		GVR:       metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+		Namespace: "my-namespace",
+		Object:    []byte(fmt.Sprintf(testutils.PodWithNewerFields, "pod-with-newer-fields", "my-namespace")),
+	}, &mutatedPod)
+	testutils.KeepsNewerPodFields(t, mutatedPod)
+	if tolerations := mutatedPod["spec"].(map[string]interface{})["tolerations"].([]interface{}); len(tolerations) != 0 {
+		t.Fatalf("Expected the infra toleration to be removed, got %v", tolerations)
+	}
+}
@@ -0,0 +1,223 @@
+package podtolerationseconds
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"gomodules.xyz/jsonpatch/v2"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/pod"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "podtolerationseconds-mutation"
+	docString   string = `Pods created in customer namespaces on Managed OpenShift clusters which tolerate the %v NoExecute taints for longer than %d seconds, or indefinitely, have their tolerationSeconds capped at %d seconds so workloads on failed nodes are rescheduled.`
+	// maxTolerationSecondsEnvVar names the environment variable overriding
+	// defaultMaxTolerationSeconds
+	maxTolerationSecondsEnvVar string = "MAX_TOLERATION_SECONDS"
+	// defaultMaxTolerationSeconds matches the Kubernetes default for these taints
+	defaultMaxTolerationSeconds int64 = 300
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.NamespacedScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"pods"},
+				Scope:       &scope,
+			},
+		},
+	}
+	// cappedTaintKeys are the node failure taints whose tolerations are capped
+	cappedTaintKeys = []string{
+		corev1.TaintNodeNotReady,
+		corev1.TaintNodeUnreachable,
+	}
+)
+
+// PodTolerationSecondsWebhook mutates customer Pods to cap how long they stay
+// bound to failed nodes
+type PodTolerationSecondsWebhook struct {
+	s          *utils.LazyScheme
+	maxSeconds int64
+}
+
+// NewWebhook creates the new webhook
+func NewWebhook() *PodTolerationSecondsWebhook {
+	return &PodTolerationSecondsWebhook{
+		s:          utils.CoreScheme,
+		maxSeconds: maxSecondsFromEnv(),
+	}
+}
+
+// maxSecondsFromEnv returns the tolerationSeconds cap from the environment,
+// falling back to defaultMaxTolerationSeconds when unset or invalid
+func maxSecondsFromEnv() int64 {
+	if v := os.Getenv(maxTolerationSecondsEnvVar); v != "" {
+		seconds, err := strconv.ParseInt(v, 10, 64)
+		if err == nil && seconds >= 0 {
+			return seconds
+		}
+		log.Info(fmt.Sprintf("Invalid tolerationSeconds in %s, using the default %d", maxTolerationSecondsEnvVar, defaultMaxTolerationSeconds))
+	}
+	return defaultMaxTolerationSeconds
+}
+
+// Authorized implements Webhook interface
+func (s *PodTolerationSecondsWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate caps the tolerationSeconds of customer Pod tolerations for
+// the node failure taints
+func (s *PodTolerationSecondsWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	if pod.IsRequestPrivileged(request.Namespace) {
+		return utils.Allow(request, "Pods in privileged namespaces are exempt from tolerationSeconds capping")
+	}
+
+	p, err := s.renderPod(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a Pod from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	patches := []jsonpatch.JsonPatchOperation{}
+	for i, toleration := range p.Spec.Tolerations {
+		if !isCappedToleration(toleration) {
+			continue
+		}
+		if toleration.TolerationSeconds != nil && *toleration.TolerationSeconds <= s.maxSeconds {
+			continue
+		}
+		// add replaces tolerationSeconds when it is already set
+		patches = append(patches, jsonpatch.NewOperation("add", fmt.Sprintf("/spec/tolerations/%d/tolerationSeconds", i), s.maxSeconds))
+	}
+
+	if len(patches) == 0 {
+		return utils.Allow(request, "Pod tolerations are within the tolerationSeconds cap")
+	}
+
+	log.Info(fmt.Sprintf("Capping tolerationSeconds on pod %s/%s", request.Namespace, p.GetName()))
+	warning := fmt.Sprintf("tolerationSeconds for the %v taints has been capped at %d so the pod is rescheduled off failed nodes", cappedTaintKeys, s.maxSeconds)
+	return utils.WithUID(request, admissionctl.Patched(fmt.Sprintf("Capped tolerationSeconds on pod '%s'", p.GetName()), patches...).WithWarnings(warning))
+}
+
+// isCappedToleration returns true if the toleration is a NoExecute toleration
+// for one of the cappedTaintKeys. Tolerations with an empty key tolerate every
+// taint and are left alone.
+func isCappedToleration(toleration corev1.Toleration) bool {
+	if toleration.Effect != corev1.TaintEffectNoExecute {
+		return false
+	}
+	for _, key := range cappedTaintKeys {
+		if toleration.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// renderPod renders the Pod in the admission Request
+func (s *PodTolerationSecondsWebhook) renderPod(request admissionctl.Request) (*corev1.Pod, error) {
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return nil, err
+	}
+	p := &corev1.Pod{}
+	err = decoder.Decode(request, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetURI implements Webhook interface
+func (s *PodTolerationSecondsWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *PodTolerationSecondsWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "Pod")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *PodTolerationSecondsWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *PodTolerationSecondsWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *PodTolerationSecondsWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *PodTolerationSecondsWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *PodTolerationSecondsWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *PodTolerationSecondsWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *PodTolerationSecondsWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *PodTolerationSecondsWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *PodTolerationSecondsWebhook) Doc() string {
+	return fmt.Sprintf(docString, cappedTaintKeys, defaultMaxTolerationSeconds, defaultMaxTolerationSeconds)
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Return utils.DefaultLabelSelector() to stick with the default
+func (s *PodTolerationSecondsWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	return utils.DefaultLabelSelector()
+}
+
+func (s *PodTolerationSecondsWebhook) ClassicEnabled() bool { return true }
+
+func (s *PodTolerationSecondsWebhook) HypershiftEnabled() bool { return true }
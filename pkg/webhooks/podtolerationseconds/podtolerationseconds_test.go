@@ -0,0 +1,121 @@
+package podtolerationseconds
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+)
+
+type podTolerationSecondsTestSuites struct {
+	testID              string
+	namespace           string
+	tolerations         []corev1.Toleration
+	expectedTolerations []corev1.Toleration
+	expectWarning       bool
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func runPodTolerationSecondsTests(t *testing.T, tests []podTolerationSecondsTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "Pod",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "pods",
+	}
+
+	for _, test := range tests {
+		rawPod, err := json.Marshal(corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: test.testID, Namespace: test.namespace, UID: "1234"},
+			Spec: corev1.PodSpec{
+				Containers:  []corev1.Container{{Name: "app", Image: "quay.io/example/app:v1"}},
+				Tolerations: test.tolerations,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
+		}
+		mutatedPod := corev1.Pod{}
+		response := testutils.SendMutation(t, NewWebhook(), testutils.MutationRequest{
+			TestID:    test.testID,
+			GVK:       gvk,
+			GVR:       gvr,
+			Namespace: test.namespace,
+			Object:    rawPod,
+		}, &mutatedPod)
+		if (len(response.Warnings) > 0) != test.expectWarning {
+			t.Fatalf("%s: Expected warnings %t, got %v", test.testID, test.expectWarning, response.Warnings)
+		}
+		if !reflect.DeepEqual(mutatedPod.Spec.Tolerations, test.expectedTolerations) {
+			t.Fatalf("%s: Expected tolerations %v, got %v", test.testID, test.expectedTolerations, mutatedPod.Spec.Tolerations)
+		}
+	}
+}
+
+func TestCapTolerationSeconds(t *testing.T) {
+	notReady := func(seconds *int64) corev1.Toleration {
+		return corev1.Toleration{Key: corev1.TaintNodeNotReady, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute, TolerationSeconds: seconds}
+	}
+	unreachable := func(seconds *int64) corev1.Toleration {
+		return corev1.Toleration{Key: corev1.TaintNodeUnreachable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute, TolerationSeconds: seconds}
+	}
+	tolerateAll := corev1.Toleration{Operator: corev1.TolerationOpExists}
+	tests := []podTolerationSecondsTestSuites{
+		{
+			testID:              "long-toleration-capped",
+			namespace:           "my-namespace",
+			tolerations:         []corev1.Toleration{notReady(int64Ptr(3600)), unreachable(int64Ptr(60))},
+			expectedTolerations: []corev1.Toleration{notReady(int64Ptr(300)), unreachable(int64Ptr(60))},
+			expectWarning:       true,
+		},
+		{
+			testID:              "indefinite-toleration-capped",
+			namespace:           "my-namespace",
+			tolerations:         []corev1.Toleration{unreachable(nil)},
+			expectedTolerations: []corev1.Toleration{unreachable(int64Ptr(300))},
+			expectWarning:       true,
+		},
+		{
+			testID:              "short-tolerations-untouched",
+			namespace:           "my-namespace",
+			tolerations:         []corev1.Toleration{notReady(int64Ptr(300)), unreachable(int64Ptr(30))},
+			expectedTolerations: []corev1.Toleration{notReady(int64Ptr(300)), unreachable(int64Ptr(30))},
+		},
+		{
+			testID:              "tolerate-everything-untouched",
+			namespace:           "my-namespace",
+			tolerations:         []corev1.Toleration{tolerateAll},
+			expectedTolerations: []corev1.Toleration{tolerateAll},
+		},
+		{
+			testID:              "privileged-namespace-untouched",
+			namespace:           "openshift-monitoring",
+			tolerations:         []corev1.Toleration{notReady(nil)},
+			expectedTolerations: []corev1.Toleration{notReady(nil)},
+		},
+	}
+	runPodTolerationSecondsTests(t, tests)
+}
+
+func TestMaxTolerationSecondsFromEnv(t *testing.T) {
+	t.Setenv(maxTolerationSecondsEnvVar, "120")
+	if hook := NewWebhook(); hook.maxSeconds != 120 {
+		t.Fatalf("Expected a cap of 120 from the environment, got %d", hook.maxSeconds)
+	}
+	t.Setenv(maxTolerationSecondsEnvVar, "forever")
+	if hook := NewWebhook(); hook.maxSeconds != defaultMaxTolerationSeconds {
+		t.Fatalf("Expected the default cap for an invalid value, got %d", hook.maxSeconds)
+	}
+}
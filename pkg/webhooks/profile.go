@@ -0,0 +1,54 @@
+package webhooks
+
+import (
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+// ProfileFilter is implemented by webhooks which are only enabled on some
+// product profiles, beyond ClassicEnabled and HypershiftEnabled
+type ProfileFilter interface {
+	// EnabledForProfile returns whether the webhook applies to profile
+	EnabledForProfile(profile utils.Profile) bool
+}
+
+// ProfileRules is implemented by webhooks whose rules differ by product
+// profile
+type ProfileRules interface {
+	// RulesForProfile returns the rules of the webhook on profile
+	RulesForProfile(profile utils.Profile) []admissionregv1.RuleWithOperations
+}
+
+// Enabled returns whether hook applies to profile
+func Enabled(hook Webhook, profile utils.Profile) bool {
+	if profile == utils.ProfileAll {
+		return true
+	}
+	if profile.Hypershift() && !hook.HypershiftEnabled() || !profile.Hypershift() && !hook.ClassicEnabled() {
+		return false
+	}
+	if filter, ok := hook.(ProfileFilter); ok {
+		return filter.EnabledForProfile(profile)
+	}
+	return true
+}
+
+// Rules returns the rules of hook on profile
+func Rules(hook Webhook, profile utils.Profile) []admissionregv1.RuleWithOperations {
+	if variant, ok := hook.(ProfileRules); ok && profile != utils.ProfileAll {
+		return variant.RulesForProfile(profile)
+	}
+	return hook.Rules()
+}
+
+// ForProfile returns the webhooks of r which apply to profile
+func (r RegisteredWebhooks) ForProfile(profile utils.Profile) RegisteredWebhooks {
+	hooks := RegisteredWebhooks{}
+	for name, factory := range r {
+		if Enabled(factory(), profile) {
+			hooks[name] = factory
+		}
+	}
+	return hooks
+}
@@ -24,9 +24,9 @@ const (
 
 var (
 	timeout                          int32 = 2
-	allowedUsers                           = []string{"kube:admin", "system:admin", "backplane-cluster-admin"}
-	sreAdminGroups                         = []string{"system:serviceaccounts:openshift-backplane-srep"}
-	privilegedServiceAccountGroupsRe       = regexp.MustCompile(utils.PrivilegedServiceAccountGroups)
+	allowedUsers                           = hookconfig.Identities(hookconfig.KubeAdminUsersFor(WebhookName), hookconfig.PlatformAdminUsersFor(WebhookName), hookconfig.SREAdminUsers)
+	sreAdminGroups                         = hookconfig.SREAdminGroups
+	privilegedServiceAccountGroupsRe       = regexp.MustCompile(hookconfig.PrivilegedServiceAccountGroups)
 	privilegedLabels                       = map[string]string{"app.kubernetes.io/name": "stackrox"}
 	scope                                  = admissionregv1.NamespacedScope
 	rules                                  = []admissionregv1.RuleWithOperations{
@@ -49,7 +49,7 @@ var (
 
 // prometheusruleWebhook validates a prometheusRule change
 type prometheusruleWebhook struct {
-	s runtime.Scheme
+	s *utils.LazyScheme
 }
 
 // We just need a runtime object to get the namespace
@@ -60,9 +60,8 @@ type prometheusRule struct {
 
 // NewWebhook creates the new webhook
 func NewWebhook() *prometheusruleWebhook {
-	scheme := runtime.NewScheme()
 	return &prometheusruleWebhook{
-		s: *scheme,
+		s: utils.EmptyScheme,
 	}
 }
 
@@ -72,8 +71,6 @@ func (s *prometheusruleWebhook) Authorized(request admissionctl.Request) admissi
 }
 
 func (s *prometheusruleWebhook) authorized(request admissionctl.Request) admissionctl.Response {
-	var ret admissionctl.Response
-
 	pr, err := s.renderPrometheusRule(request)
 	if err != nil {
 		log.Error(err, "Couldn't render a PrometheusRule from the incoming request")
@@ -86,34 +83,24 @@ func (s *prometheusruleWebhook) authorized(request admissionctl.Request) admissi
 		pr.GetNamespace() != "openshift-user-workload-monitoring" {
 		log.Info(fmt.Sprintf("%s operation detected on managed namespace: %s", request.Operation, pr.GetNamespace()))
 		if isAllowedUser(request) {
-			ret = admissionctl.Allowed(fmt.Sprintf("User can do operations on PrometheusRules"))
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Allow(request, fmt.Sprintf("User can do operations on PrometheusRules"))
 		}
 		for _, group := range request.UserInfo.Groups {
 			if privilegedServiceAccountGroupsRe.Match([]byte(group)) {
-				ret = admissionctl.Allowed("Privileged service accounts do operations on PrometheusRules")
-				ret.UID = request.AdmissionRequest.UID
-				return ret
+				return utils.Allow(request, "Privileged service accounts do operations on PrometheusRules")
 			}
 		}
 
 		// TODO: [OSD-20025] Remove this exception after MON-3518 is completed
 		if hasPrivilegedLabel(pr) {
-			ret = admissionctl.Allowed("PrometheusRules with privileged labels can be modified")
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Allow(request, "PrometheusRules with privileged labels can be modified")
 		}
 
-		ret = admissionctl.Denied(fmt.Sprintf("Prevented from accessing Red Hat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support"))
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Deny(request, utils.ReasonPrometheusRuleManagedNamespace, fmt.Sprintf("Prevented from accessing Red Hat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support"))
 	}
 
 	log.Info("Allowing access")
-	ret = admissionctl.Allowed("Non managed namespace")
-	ret.UID = request.AdmissionRequest.UID
-	return ret
+	return utils.Allow(request, "Non managed namespace")
 }
 
 // isAllowedUser checks if the user or group is allowed to perform the action
@@ -155,7 +142,7 @@ func (s *prometheusruleWebhook) Validate(request admissionctl.Request) bool {
 	return valid
 }
 func (s *prometheusruleWebhook) renderPrometheusRule(req admissionctl.Request) (*prometheusRule, error) {
-	decoder, err := admissionctl.NewDecoder(&s.s)
+	decoder, err := s.s.Decoder()
 	if err != nil {
 		return nil, err
 	}
@@ -197,6 +184,11 @@ func (s *prometheusruleWebhook) ObjectSelector() *metav1.LabelSelector {
 	return nil
 }
 
+// NamespaceSelector implements Webhook interface
+func (s *prometheusruleWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
 // SideEffects implements Webhook interface
 func (s *prometheusruleWebhook) SideEffects() admissionregv1.SideEffectClass {
 	return admissionregv1.SideEffectClassNone
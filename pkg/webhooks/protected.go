@@ -0,0 +1,22 @@
+package webhooks
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ObjectProtector is implemented by webhooks which keep objects in place,
+// e.g. against deletion, so that policy engines checking cluster state
+// rather than requests can check the objects exist
+type ObjectProtector interface {
+	// ProtectedObjects returns the objects the webhook protects, with the
+	// fields which identify them
+	ProtectedObjects() []*unstructured.Unstructured
+}
+
+// ProtectedObjects returns the objects hook protects, if any
+func ProtectedObjects(hook Webhook) []*unstructured.Unstructured {
+	if protector, ok := hook.(ObjectProtector); ok {
+		return protector.ProtectedObjects()
+	}
+	return nil
+}
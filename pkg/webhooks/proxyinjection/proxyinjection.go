@@ -0,0 +1,295 @@
+package proxyinjection
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"gomodules.xyz/jsonpatch/v2"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/ttlcache"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "proxyinjection-mutation"
+	docString   string = `Pods created in namespaces labeled with %s=true are given HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables matching the cluster-wide proxy. Variables already set on a container are left untouched.`
+	// injectProxyLabel is the Namespace label which opts a namespace in to
+	// proxy environment injection
+	injectProxyLabel string = "managed.openshift.io/inject-proxy"
+	// proxyCacheTTL is how long the cluster-wide proxy configuration is
+	// cached, and so how long a change to it takes to apply to new Pods
+	proxyCacheTTL = 30 * time.Second
+	// lookupTimeout bounds the lookup of the cluster-wide proxy, within the
+	// timeout of the webhook
+	lookupTimeout = time.Second
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.NamespacedScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"pods"},
+				Scope:       &scope,
+			},
+		},
+	}
+)
+
+// ProxyInjectionWebhook mutates Pods to carry the cluster-wide proxy settings
+type ProxyInjectionWebhook struct {
+	s          *utils.LazyScheme
+	kubeClient *utils.LazyClient
+	proxyEnv   *ttlcache.Cache[[]corev1.EnvVar]
+}
+
+var (
+	// scheme registers the types the webhook decodes
+	scheme     = utils.NewLazyScheme(admissionv1.AddToScheme, corev1.AddToScheme, configv1.AddToScheme)
+	kubeClient = utils.NewLazyClient(scheme)
+	// proxyEnv caches the proxy environment of the cluster-wide proxy, which
+	// is the same for every Pod
+	proxyEnv = ttlcache.New[[]corev1.EnvVar](proxyCacheTTL, 1)
+)
+
+// NewWebhook creates the new webhook
+func NewWebhook() *ProxyInjectionWebhook {
+	return &ProxyInjectionWebhook{
+		s:          scheme,
+		kubeClient: kubeClient,
+		proxyEnv:   proxyEnv,
+	}
+}
+
+// Authorized implements Webhook interface
+func (s *ProxyInjectionWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate adds the cluster-wide proxy environment to every container
+// in the Pod
+func (s *ProxyInjectionWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	pod, err := s.renderPod(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a Pod from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	proxyEnv, err := s.proxyEnv.Get("cluster", s.clusterProxyEnv)
+	if err != nil {
+		log.Error(err, "Failed to read the cluster-wide proxy configuration")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+
+	if len(proxyEnv) == 0 {
+		return utils.Allow(request, "Cluster has no proxy configured, no mutation required")
+	}
+
+	// The variables are added by patch operations on each container rather
+	// than by diffing a re-encoded Pod, which would drop the fields the
+	// vendored API types don't know.
+	patches := []jsonpatch.JsonPatchOperation{}
+	for i, c := range pod.Spec.InitContainers {
+		patches = append(patches, envPatches(fmt.Sprintf("/spec/initContainers/%d", i), c.Env, proxyEnv)...)
+	}
+	for i, c := range pod.Spec.Containers {
+		patches = append(patches, envPatches(fmt.Sprintf("/spec/containers/%d", i), c.Env, proxyEnv)...)
+	}
+	if len(patches) == 0 {
+		return utils.Allow(request, "All containers already define the proxy environment")
+	}
+
+	log.Info(fmt.Sprintf("Injecting proxy environment into pod %s/%s", request.Namespace, pod.GetName()))
+	return utils.WithUID(request, admissionctl.Patched(fmt.Sprintf("Injected the proxy environment into pod '%s'", pod.GetName()), patches...))
+}
+
+// clusterProxyEnv returns the proxy environment variables for the effective
+// cluster-wide proxy configuration. Unset values are omitted.
+func (s *ProxyInjectionWebhook) clusterProxyEnv() ([]corev1.EnvVar, error) {
+	kubeClient, err := s.kubeClient.Client()
+	if err != nil {
+		return nil, fmt.Errorf("fail creating KubeClient for ProxyInjectionWebhook: %v", err)
+	}
+	// Concurrent requests share the lookup of the first, so it must not be
+	// canceled with it
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	proxy := &configv1.Proxy{}
+	err = kubeClient.Get(ctx, client.ObjectKey{Name: "cluster"}, proxy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster proxy config: %v", err)
+	}
+
+	env := []corev1.EnvVar{}
+	for _, v := range []corev1.EnvVar{
+		{Name: "HTTP_PROXY", Value: proxy.Status.HTTPProxy},
+		{Name: "HTTPS_PROXY", Value: proxy.Status.HTTPSProxy},
+		{Name: "NO_PROXY", Value: proxy.Status.NoProxy},
+	} {
+		if v.Value != "" {
+			env = append(env, v)
+		}
+	}
+	// NO_PROXY alone does nothing
+	if len(env) == 1 && env[0].Name == "NO_PROXY" {
+		return nil, nil
+	}
+	return env, nil
+}
+
+// envPatches constructs the JSONPatch operations appending each of proxyEnv
+// which env, the environment of the container at path, doesn't already define
+func envPatches(path string, env []corev1.EnvVar, proxyEnv []corev1.EnvVar) []jsonpatch.JsonPatchOperation {
+	missing := []corev1.EnvVar{}
+	for _, p := range proxyEnv {
+		found := false
+		for _, e := range env {
+			if e.Name == p.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, p)
+		}
+	}
+
+	switch {
+	case len(missing) == 0:
+		return nil
+	case env == nil:
+		// No env key at all, so add them all at once
+		return []jsonpatch.JsonPatchOperation{
+			jsonpatch.NewOperation("add", path+"/env", missing),
+		}
+	}
+	patches := make([]jsonpatch.JsonPatchOperation, 0, len(missing))
+	for _, v := range missing {
+		patches = append(patches, jsonpatch.NewOperation("add", path+"/env/-", v))
+	}
+	return patches
+}
+
+// renderPod renders the Pod in the admission Request
+func (s *ProxyInjectionWebhook) renderPod(request admissionctl.Request) (*corev1.Pod, error) {
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return nil, err
+	}
+	pod := &corev1.Pod{}
+	err = decoder.Decode(request, pod)
+	if err != nil {
+		return nil, err
+	}
+	return pod, nil
+}
+
+// RequiredPermissions implements webhooks.PermissionRequirer, the webhook
+// injects the cluster proxy
+func (s *ProxyInjectionWebhook) RequiredPermissions() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{configv1.GroupName},
+			Resources: []string{"proxies"},
+			Verbs:     []string{"get"},
+		},
+	}
+}
+
+// GetURI implements Webhook interface
+func (s *ProxyInjectionWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *ProxyInjectionWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "Pod")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *ProxyInjectionWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *ProxyInjectionWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *ProxyInjectionWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *ProxyInjectionWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *ProxyInjectionWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface. Only namespaces which have
+// opted in to proxy injection are sent to this webhook.
+func (s *ProxyInjectionWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			injectProxyLabel: "true",
+		},
+	}
+}
+
+// SideEffects implements Webhook interface
+func (s *ProxyInjectionWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *ProxyInjectionWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *ProxyInjectionWebhook) Doc() string {
+	return fmt.Sprintf(docString, injectProxyLabel)
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Return utils.DefaultLabelSelector() to stick with the default
+func (s *ProxyInjectionWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	return utils.DefaultLabelSelector()
+}
+
+func (s *ProxyInjectionWebhook) ClassicEnabled() bool { return true }
+
+func (s *ProxyInjectionWebhook) HypershiftEnabled() bool { return true }
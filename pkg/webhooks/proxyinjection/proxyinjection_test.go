@@ -0,0 +1,168 @@
+package proxyinjection
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/ttlcache"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+func newMockProxy(obs ...client.Object) client.Client {
+	s := runtime.NewScheme()
+	_ = configv1.Install(s)
+	return fake.NewClientBuilder().WithScheme(s).WithObjects(obs...).Build()
+}
+
+func createRawPodJSON(name string, spec corev1.PodSpec, namespace string) ([]byte, error) {
+	str := `{
+		"apiVersion": "v1",
+		"kind": "Pod",
+		"metadata": {
+			"name": "%s",
+			"namespace": "%s",
+			"uid": "1234"
+		},
+		"spec": %s
+	}`
+
+	partial, err := json.Marshal(spec)
+	return []byte(fmt.Sprintf(str, name, namespace, string(partial))), err
+}
+
+type proxyInjectionTestSuites struct {
+	testID      string
+	proxyStatus configv1.ProxyStatus
+	spec        corev1.PodSpec
+	expectedEnv [][]corev1.EnvVar
+}
+
+func runProxyInjectionTests(t *testing.T, tests []proxyInjectionTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "Pod",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "pods",
+	}
+
+	for _, test := range tests {
+		rawPod, err := createRawPodJSON(test.testID, test.spec, "my-namespace")
+		if err != nil {
+			t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
+		}
+		mutatedPod := corev1.Pod{}
+		hook := NewWebhook()
+		hook.kubeClient = utils.StaticClient(newMockProxy(&configv1.Proxy{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+			Status:     test.proxyStatus,
+		}))
+		hook.proxyEnv = ttlcache.New[[]corev1.EnvVar](proxyCacheTTL, 1)
+		testutils.SendMutation(t, hook, testutils.MutationRequest{
+			TestID:    test.testID,
+			GVK:       gvk,
+			GVR:       gvr,
+			Namespace: "my-namespace",
+			Object:    rawPod,
+		}, &mutatedPod)
+		for i, c := range mutatedPod.Spec.Containers {
+			if !reflect.DeepEqual(c.Env, test.expectedEnv[i]) {
+				t.Fatalf("%s: Expected container %s env %v, got %v", test.testID, c.Name, test.expectedEnv[i], c.Env)
+			}
+		}
+	}
+}
+
+func TestProxyInjection(t *testing.T) {
+	proxied := configv1.ProxyStatus{
+		HTTPProxy:  "http://proxy.example.com:3128",
+		HTTPSProxy: "http://proxy.example.com:3128",
+		NoProxy:    ".cluster.local,.svc",
+	}
+	proxyEnv := []corev1.EnvVar{
+		{Name: "HTTP_PROXY", Value: proxied.HTTPProxy},
+		{Name: "HTTPS_PROXY", Value: proxied.HTTPSProxy},
+		{Name: "NO_PROXY", Value: proxied.NoProxy},
+	}
+	tests := []proxyInjectionTestSuites{
+		{
+			testID:      "proxy-env-injected",
+			proxyStatus: proxied,
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: "quay.io/app:latest"}},
+			},
+			expectedEnv: [][]corev1.EnvVar{proxyEnv},
+		},
+		{
+			testID:      "existing-env-preserved",
+			proxyStatus: proxied,
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:  "app",
+						Image: "quay.io/app:latest",
+						Env: []corev1.EnvVar{
+							{Name: "FOO", Value: "bar"},
+							{Name: "NO_PROXY", Value: "*"},
+						},
+					},
+				},
+			},
+			expectedEnv: [][]corev1.EnvVar{
+				{
+					{Name: "FOO", Value: "bar"},
+					{Name: "NO_PROXY", Value: "*"},
+					{Name: "HTTP_PROXY", Value: proxied.HTTPProxy},
+					{Name: "HTTPS_PROXY", Value: proxied.HTTPSProxy},
+				},
+			},
+		},
+		{
+			testID:      "no-cluster-proxy-untouched",
+			proxyStatus: configv1.ProxyStatus{},
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: "quay.io/app:latest"}},
+			},
+			expectedEnv: [][]corev1.EnvVar{nil},
+		},
+	}
+	runProxyInjectionTests(t, tests)
+}
+
+func TestProxyInjectionKeepsNewerFields(t *testing.T) {
+	mutatedPod := map[string]interface{}{}
+	hook := NewWebhook()
+	hook.kubeClient = utils.StaticClient(newMockProxy(&configv1.Proxy{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Status:     configv1.ProxyStatus{HTTPSProxy: "http://proxy.example.com:3128"},
+	}))
+	hook.proxyEnv = ttlcache.New[[]corev1.EnvVar](proxyCacheTTL, 1)
+	testutils.SendMutation(t, hook, testutils.MutationRequest{
+		TestID:    "pod-with-newer-fields",
+		GVK:       metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		GVR:       metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+		Namespace: "my-namespace",
+		Object:    []byte(fmt.Sprintf(testutils.PodWithNewerFields, "pod-with-newer-fields", "my-namespace")),
+	}, &mutatedPod)
+	testutils.KeepsNewerPodFields(t, mutatedPod)
+	spec := mutatedPod["spec"].(map[string]interface{})
+	for _, containers := range []string{"initContainers", "containers"} {
+		c := spec[containers].([]interface{})[0].(map[string]interface{})
+		if env, _ := c["env"].([]interface{}); len(env) != 1 {
+			t.Fatalf("Expected HTTPS_PROXY in the env of the %s, got %v", containers, c["env"])
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package pullsecretinjection
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"gomodules.xyz/jsonpatch/v2"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "pullsecretinjection-mutation"
+	docString   string = `Pods and ServiceAccounts created in namespaces labeled with %s=true are given the %s imagePullSecret, which is provided in those namespaces by managed add-ons.`
+	// injectPullSecretLabel is the Namespace label which opts a namespace in
+	// to imagePullSecret injection
+	injectPullSecretLabel string = "managed.openshift.io/inject-pull-secret"
+	// pullSecretEnvVar names the environment variable which overrides the name
+	// of the injected imagePullSecret
+	pullSecretEnvVar      string = "MANAGED_PULL_SECRET_NAME"
+	defaultPullSecretName string = "managed-pull-secret"
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.NamespacedScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"pods", "serviceaccounts"},
+				Scope:       &scope,
+			},
+		},
+	}
+)
+
+// PullSecretInjectionWebhook mutates Pods and ServiceAccounts to reference the
+// managed imagePullSecret
+type PullSecretInjectionWebhook struct {
+	s          *utils.LazyScheme
+	secretName string
+}
+
+// NewWebhook creates the new webhook
+func NewWebhook() *PullSecretInjectionWebhook {
+	secretName := os.Getenv(pullSecretEnvVar)
+	if secretName == "" {
+		secretName = defaultPullSecretName
+	}
+
+	return &PullSecretInjectionWebhook{
+		s:          utils.CoreScheme,
+		secretName: secretName,
+	}
+}
+
+// Authorized implements Webhook interface
+func (s *PullSecretInjectionWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate appends the managed imagePullSecret to the Pod or
+// ServiceAccount unless it is already referenced
+func (s *PullSecretInjectionWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	var existing []corev1.LocalObjectReference
+	var path string
+	switch request.Kind.Kind {
+	case "Pod":
+		p := &corev1.Pod{}
+		if err := decoder.Decode(request, p); err != nil {
+			log.Error(err, "Couldn't render a Pod from the incoming request")
+			return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+		}
+		existing = p.Spec.ImagePullSecrets
+		path = "/spec/imagePullSecrets"
+	case "ServiceAccount":
+		sa := &corev1.ServiceAccount{}
+		if err := decoder.Decode(request, sa); err != nil {
+			log.Error(err, "Couldn't render a ServiceAccount from the incoming request")
+			return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+		}
+		existing = sa.ImagePullSecrets
+		path = "/imagePullSecrets"
+	}
+
+	for _, ref := range existing {
+		if ref.Name == s.secretName {
+			return utils.Allow(request, "Managed imagePullSecret is already referenced")
+		}
+	}
+
+	ref := corev1.LocalObjectReference{Name: s.secretName}
+	var op jsonpatch.JsonPatchOperation
+	if len(existing) == 0 {
+		op = jsonpatch.NewOperation("add", path, []corev1.LocalObjectReference{ref})
+	} else {
+		op = jsonpatch.NewOperation("add", path+"/-", ref)
+	}
+
+	log.Info(fmt.Sprintf("Adding imagePullSecret %s to %s %s/%s", s.secretName, request.Kind.Kind, request.Namespace, request.Name))
+	return utils.WithUID(request, admissionctl.Patched(fmt.Sprintf("Added imagePullSecret '%s'", s.secretName), op))
+}
+
+// GetURI implements Webhook interface
+func (s *PullSecretInjectionWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *PullSecretInjectionWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "Pod" || request.Kind.Kind == "ServiceAccount")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *PullSecretInjectionWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *PullSecretInjectionWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *PullSecretInjectionWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *PullSecretInjectionWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *PullSecretInjectionWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface. Only namespaces which have
+// opted in to imagePullSecret injection are sent to this webhook.
+func (s *PullSecretInjectionWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			injectPullSecretLabel: "true",
+		},
+	}
+}
+
+// SideEffects implements Webhook interface
+func (s *PullSecretInjectionWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *PullSecretInjectionWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *PullSecretInjectionWebhook) Doc() string {
+	return fmt.Sprintf(docString, injectPullSecretLabel, s.secretName)
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Return utils.DefaultLabelSelector() to stick with the default
+func (s *PullSecretInjectionWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	return utils.DefaultLabelSelector()
+}
+
+func (s *PullSecretInjectionWebhook) ClassicEnabled() bool { return true }
+
+func (s *PullSecretInjectionWebhook) HypershiftEnabled() bool { return true }
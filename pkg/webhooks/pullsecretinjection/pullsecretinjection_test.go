@@ -0,0 +1,126 @@
+package pullsecretinjection
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+)
+
+type pullSecretTestSuites struct {
+	testID          string
+	kind            string
+	existing        []corev1.LocalObjectReference
+	expectedSecrets []corev1.LocalObjectReference
+}
+
+func createRawObject(kind string, existing []corev1.LocalObjectReference) ([]byte, error) {
+	meta := metav1.ObjectMeta{Name: "test", Namespace: "addon-namespace", UID: "1234"}
+	switch kind {
+	case "ServiceAccount":
+		return json.Marshal(corev1.ServiceAccount{
+			TypeMeta:         metav1.TypeMeta{APIVersion: "v1", Kind: kind},
+			ObjectMeta:       meta,
+			ImagePullSecrets: existing,
+		})
+	default:
+		return json.Marshal(corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: kind},
+			ObjectMeta: meta,
+			Spec: corev1.PodSpec{
+				Containers:       []corev1.Container{{Name: "app", Image: "partner.example.com/app:v1"}},
+				ImagePullSecrets: existing,
+			},
+		})
+	}
+}
+
+// mutatedObject holds the imagePullSecrets of a mutated Pod or ServiceAccount
+type mutatedObject struct {
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets"`
+	Spec             struct {
+		ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets"`
+	} `json:"spec"`
+}
+
+func (o mutatedObject) imagePullSecrets(kind string) []corev1.LocalObjectReference {
+	if kind == "ServiceAccount" {
+		return o.ImagePullSecrets
+	}
+	return o.Spec.ImagePullSecrets
+}
+
+func runPullSecretTests(t *testing.T, tests []pullSecretTestSuites) {
+	for _, test := range tests {
+		gvk := metav1.GroupVersionKind{Group: "", Version: "v1", Kind: test.kind}
+		gvr := metav1.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}
+		if test.kind == "ServiceAccount" {
+			gvr.Resource = "serviceaccounts"
+		}
+
+		raw, err := createRawObject(test.kind, test.existing)
+		if err != nil {
+			t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
+		}
+		mutated := mutatedObject{}
+		testutils.SendMutation(t, NewWebhook(), testutils.MutationRequest{
+			TestID:    test.testID,
+			GVK:       gvk,
+			GVR:       gvr,
+			Namespace: "addon-namespace",
+			Object:    raw,
+		}, &mutated)
+		secrets := mutated.imagePullSecrets(test.kind)
+		if !reflect.DeepEqual(secrets, test.expectedSecrets) {
+			t.Fatalf("%s: Expected imagePullSecrets %v, got %v", test.testID, test.expectedSecrets, secrets)
+		}
+	}
+}
+
+func TestInjectPullSecret(t *testing.T) {
+	managed := corev1.LocalObjectReference{Name: defaultPullSecretName}
+	own := corev1.LocalObjectReference{Name: "my-registry"}
+	tests := []pullSecretTestSuites{
+		{
+			testID:          "pod-without-pull-secrets",
+			kind:            "Pod",
+			expectedSecrets: []corev1.LocalObjectReference{managed},
+		},
+		{
+			testID:          "pod-with-own-pull-secret",
+			kind:            "Pod",
+			existing:        []corev1.LocalObjectReference{own},
+			expectedSecrets: []corev1.LocalObjectReference{own, managed},
+		},
+		{
+			testID:          "pod-already-referencing-managed-secret",
+			kind:            "Pod",
+			existing:        []corev1.LocalObjectReference{managed},
+			expectedSecrets: []corev1.LocalObjectReference{managed},
+		},
+		{
+			testID:          "serviceaccount-without-pull-secrets",
+			kind:            "ServiceAccount",
+			expectedSecrets: []corev1.LocalObjectReference{managed},
+		},
+		{
+			testID:          "serviceaccount-with-own-pull-secret",
+			kind:            "ServiceAccount",
+			existing:        []corev1.LocalObjectReference{own},
+			expectedSecrets: []corev1.LocalObjectReference{own, managed},
+		},
+	}
+	runPullSecretTests(t, tests)
+}
+
+func TestPullSecretNameFromEnv(t *testing.T) {
+	t.Setenv(pullSecretEnvVar, "partner-registry")
+	hook := NewWebhook()
+	if hook.secretName != "partner-registry" {
+		t.Fatalf("Expected secret name partner-registry from the environment, got %s", hook.secretName)
+	}
+}
@@ -35,6 +35,10 @@ type Webhook interface {
 	// Rules() to match only on incoming requests which match the specific
 	// LabelSelector.
 	ObjectSelector() *metav1.LabelSelector
+	// NamespaceSelector uses a *metav1.LabelSelector to augment the webhook's
+	// Rules() to match only on incoming requests for objects in namespaces
+	// which match the specific LabelSelector.
+	NamespaceSelector() *metav1.LabelSelector
 	// SideEffects are what side effects, if any, this hook has. Refer to
 	// https://kubernetes.io/docs/reference/access-authn-authz/extensible-admission-controllers/#side-effects
 	SideEffects() admissionregv1.SideEffectClass
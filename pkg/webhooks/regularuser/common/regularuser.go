@@ -2,7 +2,6 @@ package common
 
 import (
 	"fmt"
-	"os"
 	"slices"
 	"strings"
 
@@ -14,7 +13,6 @@ import (
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -40,13 +38,12 @@ const (
 )
 
 var (
-	adminGroups         = []string{"system:serviceaccounts:openshift-backplane-srep"}
-	adminUsers          = []string{"backplane-cluster-admin"}
+	adminGroups         = hookconfig.SREAdminGroups
+	adminUsers          = hookconfig.SREAdminUsers
 	clusterVersionUsers = []string{
 		"system:serviceaccount:openshift-managed-upgrade-operator:managed-upgrade-operator",
 		"system:serviceaccount:openshift-cluster-version:default",
 	}
-	ceeGroup = "system:serviceaccounts:openshift-backplane-cee"
 
 	scope = admissionregv1.AllScopes
 	rules = []admissionregv1.RuleWithOperations{
@@ -140,7 +137,7 @@ var (
 
 // RegularuserWebhook protects various objects from unauthorized manipulation
 type RegularuserWebhook struct {
-	s runtime.Scheme
+	s *utils.LazyScheme
 }
 
 func (s *RegularuserWebhook) Doc() string {
@@ -158,6 +155,8 @@ func (s *RegularuserWebhook) Doc() string {
 	for k := range hist {
 		allGroups = append(allGroups, k)
 	}
+	// Sorted so the generated documentation is stable
+	slices.Sort(allGroups)
 
 	return fmt.Sprintf(docString, allGroups)
 }
@@ -165,6 +164,9 @@ func (s *RegularuserWebhook) Doc() string {
 // ObjectSelector implements Webhook interface
 func (s *RegularuserWebhook) ObjectSelector() *metav1.LabelSelector { return nil }
 
+// NamespaceSelector implements Webhook interface
+func (s *RegularuserWebhook) NamespaceSelector() *metav1.LabelSelector { return nil }
+
 // TimeoutSeconds implements Webhook interface
 func (s *RegularuserWebhook) TimeoutSeconds() int32 { return 2 }
 
@@ -184,6 +186,38 @@ func (s *RegularuserWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
 // Rules implements Webhook interface
 func (s *RegularuserWebhook) Rules() []admissionregv1.RuleWithOperations { return rules }
 
+// hostedControlPlaneGroups are the APIGroups of machine and node management,
+// which a hosted control plane runs outside the cluster
+var hostedControlPlaneGroups = []string{
+	"machine.openshift.io",
+	"autoscaling.openshift.io",
+	"machineconfiguration.openshift.io",
+}
+
+// RulesForProfile implements webhooks.ProfileRules. Hosted control planes
+// have no control-plane machines or machine configuration in the cluster,
+// so those rules are dropped.
+func (s *RegularuserWebhook) RulesForProfile(profile utils.Profile) []admissionregv1.RuleWithOperations {
+	if !profile.Hypershift() {
+		return rules
+	}
+	hcpRules := []admissionregv1.RuleWithOperations{}
+	for _, rule := range rules {
+		groups := []string{}
+		for _, group := range rule.APIGroups {
+			if !slices.Contains(hostedControlPlaneGroups, group) {
+				groups = append(groups, group)
+			}
+		}
+		if len(groups) == 0 {
+			continue
+		}
+		rule.APIGroups = groups
+		hcpRules = append(hcpRules, rule)
+	}
+	return hcpRules
+}
+
 // GetURI implements Webhook interface
 func (s *RegularuserWebhook) GetURI() string { return "/regularuser-validation" }
 
@@ -206,100 +240,78 @@ func (s *RegularuserWebhook) Authorized(request admissionctl.Request) admissionc
 }
 
 func (s *RegularuserWebhook) authorized(request admissionctl.Request) admissionctl.Response {
-	var ret admissionctl.Response
-
 	if request.AdmissionRequest.UserInfo.Username == "system:unauthenticated" {
 		// This could highlight a significant problem with RBAC since an
 		// unauthenticated user should have no permissions.
-		log.Info("system:unauthenticated made a webhook request. Check RBAC rules", "request", request.AdmissionRequest)
-		ret = admissionctl.Denied("Unauthenticated")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		log.Info("system:unauthenticated made a webhook request. Check RBAC rules", "request", utils.RedactRequest(request.AdmissionRequest))
+		return utils.Deny(request, utils.ReasonUserUnauthenticated, "Unauthenticated")
 	}
 
 	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "kube:") {
-		ret = admissionctl.Allowed("kube: users are allowed")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "kube: users are allowed")
 	}
 
 	switch {
 	case utils.RequestMatchesGroupKind(request, mustGatherKind, mustGatherGroup):
 		if isMustGatherAuthorized(request) {
-			ret = admissionctl.Allowed("Management of MustGather CR is authorized")
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Allow(request, "Management of MustGather CR is authorized")
 		}
 	case utils.RequestMatchesGroupKind(request, customDomainKind, customDomainGroup):
 		if isCustomDomainAuthorized(request) {
-			ret = admissionctl.Allowed("Management of CustomDomain CR is authorized")
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Allow(request, "Management of CustomDomain CR is authorized")
 		}
 	case utils.RequestMatchesGroupKind(request, clusterVersionKind, clusterVersionGroup):
 		if isClusterVersionAuthorized(request) {
 			return utils.WebhookResponse(request, true, "")
 		} else {
-			log.Info("Denying access", "request", request.AdmissionRequest)
+			log.Info("Denying access", "request", utils.RedactRequest(request.AdmissionRequest))
 			return utils.WebhookResponse(request, false, "Prevented from accessing Red Hat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
 		}
 	case utils.RequestMatchesGroupKind(request, netNamespaceKind, netNamespaceGroup):
 		if isNetNamespaceAuthorized(s, request) {
-			ret = admissionctl.Allowed("Management of NetNamespace CR is authorized")
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Allow(request, "Management of NetNamespace CR is authorized")
 		}
 	}
 
 	// TODO: Do not allow all system:serviceaccount:* users or belong to system:serviceaccounts:* groups
 	// https://kubernetes.io/docs/reference/access-authn-authz/rbac/
 	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "system:") {
-		ret = admissionctl.Allowed("authenticated system: users are allowed")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "authenticated system: users are allowed")
 	}
 
 	if slices.Contains(adminUsers, request.AdmissionRequest.UserInfo.Username) {
-		ret = admissionctl.Allowed("Specified admin users are allowed")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "Specified admin users are allowed")
 	}
 
 	for _, userGroup := range request.UserInfo.Groups {
 		if slices.Contains(adminGroups, userGroup) {
-			ret = admissionctl.Allowed("Members of admin groups are allowed")
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.Allow(request, "Members of admin groups are allowed")
 		}
 	}
 
 	if request.Kind.Kind == "ConfigMap" && shouldAllowConfigMapChange(s, request) {
-		ret = admissionctl.Allowed("Modification of Config Maps that are not user-ca-bundle are allowed")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "Modification of Config Maps that are not user-ca-bundle are allowed")
 	}
 
-	log.Info("Denying access", "request", request.AdmissionRequest)
-	ret = admissionctl.Denied("Prevented from accessing Red Hat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
-	ret.UID = request.AdmissionRequest.UID
-	return ret
+	log.Info("Denying access", "request", utils.RedactRequest(request.AdmissionRequest))
+	return utils.Deny(request, utils.ReasonUserManagedResource, "Prevented from accessing Red Hat managed resources. This is in an effort to prevent harmful actions that may cause unintended consequences or affect the stability of the cluster. If you have any questions about this, please reach out to Red Hat support at https://access.redhat.com/support")
 }
 
 // isMustGatherAuthorized check if request is authorized for MustGather CR
 func isMustGatherAuthorized(request admissionctl.Request) bool {
-	return slices.Contains(request.UserInfo.Groups, ceeGroup)
+	return hookconfig.IsMember(request.UserInfo.Groups, hookconfig.CEEGroups)
 }
 
 // isCustomDomainAuthorized check if request is authorized for CustomDomain CR
 func isCustomDomainAuthorized(request admissionctl.Request) bool {
-	return slices.Contains(request.UserInfo.Groups, "cluster-admins") ||
-		slices.Contains(request.UserInfo.Groups, "dedicated-admins")
+	return hookconfig.IsMember(request.UserInfo.Groups, hookconfig.CustomerClusterAdminGroups) ||
+		hookconfig.IsDedicatedAdminAllowed(WebhookName, request)
 }
 
 // isNetNamespaceAuthorized check if request is authorized for NetNamespace CR
 func isNetNamespaceAuthorized(s *RegularuserWebhook, request admissionctl.Request) bool {
-	return (slices.Contains(request.UserInfo.Groups, "cluster-admins") ||
-		slices.Contains(request.UserInfo.Groups, "dedicated-admins")) &&
+	return (hookconfig.IsMember(request.UserInfo.Groups, hookconfig.CustomerClusterAdminGroups) ||
+		hookconfig.IsDedicatedAdminAllowed(WebhookName, request)) &&
 		isNetNamespaceValid(s, request)
 }
 
@@ -329,7 +341,7 @@ func isClusterVersionAuthorized(request admissionctl.Request) bool {
 // isNetNamespaceValid check if the NetNamespace is valid
 func isNetNamespaceValid(s *RegularuserWebhook, request admissionctl.Request) bool {
 	// Decode object into a NetNamespace object
-	decoder, err := admissionctl.NewDecoder(&s.s)
+	decoder, err := s.s.Decoder()
 	if err != nil {
 		return false
 	}
@@ -351,7 +363,7 @@ func isNetNamespaceValid(s *RegularuserWebhook, request admissionctl.Request) bo
 
 // allow if a ConfigMap is being updated that does not live under openshift-config or is not called user-ca-bundle under openshift-config
 func shouldAllowConfigMapChange(s *RegularuserWebhook, request admissionctl.Request) bool {
-	decoder, err := admissionctl.NewDecoder(&s.s)
+	decoder, err := s.s.Decoder()
 	if err != nil {
 		return false
 	}
@@ -381,29 +393,13 @@ func (s *RegularuserWebhook) ClassicEnabled() bool { return true }
 
 func (s *RegularuserWebhook) HypershiftEnabled() bool { return true }
 
+// scheme registers the types the webhook decodes
+var scheme = utils.NewLazyScheme(admissionv1.AddToScheme, corev1.AddToScheme, networkv1.Install)
+
 // NewWebhook creates a new webhook
 func NewWebhook() *RegularuserWebhook {
 
-	scheme := runtime.NewScheme()
-	err := admissionv1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding admissionv1 scheme to RegularuserWebhook")
-		os.Exit(1)
-	}
-
-	err = corev1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding corev1 scheme to RegularuserWebhook")
-		os.Exit(1)
-	}
-
-	err = networkv1.Install(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding networkv1 scheme to RegularuserWebhook")
-		os.Exit(1)
-	}
-
 	return &RegularuserWebhook{
-		s: *scheme,
+		s: scheme,
 	}
 }
@@ -2,9 +2,11 @@ package common
 
 import (
 	"fmt"
+	"slices"
 	"testing"
 
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -951,3 +953,24 @@ func TestGetURI(t *testing.T) {
 		t.Fatalf("Hook URI does not begin with a /")
 	}
 }
+
+func TestRulesForProfile(t *testing.T) {
+	hook := NewWebhook()
+	if got := hook.RulesForProfile(utils.ProfileOSD); len(got) != len(rules) {
+		t.Fatalf("Expected the OSD profile to have every rule, got %d of %d", len(got), len(rules))
+	}
+	hcpRules := hook.RulesForProfile(utils.ProfileROSAHCP)
+	if len(hcpRules) != len(rules)-2 {
+		t.Fatalf("Expected the autoscaling and machineconfiguration rules to be dropped on HCP, got %d of %d rules", len(hcpRules), len(rules))
+	}
+	for _, rule := range hcpRules {
+		for _, group := range hostedControlPlaneGroups {
+			if slices.Contains(rule.APIGroups, group) {
+				t.Errorf("Expected no HCP rule to match %s, got %v", group, rule.APIGroups)
+			}
+		}
+	}
+	if !slices.Contains(rules[0].APIGroups, "machine.openshift.io") {
+		t.Error("Expected the HCP rules not to modify the default rules")
+	}
+}
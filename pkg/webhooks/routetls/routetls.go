@@ -0,0 +1,234 @@
+package routetls
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	routev1 "github.com/openshift/api/route/v1"
+	"gomodules.xyz/jsonpatch/v2"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "routetls-mutation"
+	docString   string = `Routes in customer namespaces on Managed OpenShift clusters which target a TLS port (%v) without TLS termination are upgraded to passthrough termination, and routes which allow insecure traffic have insecureEdgeTerminationPolicy set to %s. A warning is returned for each change.`
+	// routeTLSFeatureFlag is the ClusterDeployment label which opts a
+	// cluster in to Route TLS enforcement
+	routeTLSFeatureFlag string = "ext-managed.openshift.io/enforce-route-tls"
+	// tlsPortsEnvVar names the environment variable overriding
+	// defaultTLSPorts, as a comma separated list of port numbers or names
+	tlsPortsEnvVar string = "ROUTE_TLS_PORTS"
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.NamespacedScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+				admissionregv1.Update,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{"route.openshift.io"},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"routes"},
+				Scope:       &scope,
+			},
+		},
+	}
+	// defaultTLSPorts are target ports which serve TLS, so a plain Route to
+	// them would send HTTP to a TLS backend
+	defaultTLSPorts = []string{"443", "8443", "https"}
+	// minimumInsecurePolicy is the managed minimum for insecure traffic on
+	// TLS Routes
+	minimumInsecurePolicy = routev1.InsecureEdgeTerminationPolicyRedirect
+)
+
+// RouteTLSWebhook mutates customer Routes to meet the managed TLS minimum
+type RouteTLSWebhook struct {
+	s        *utils.LazyScheme
+	tlsPorts []string
+}
+
+// scheme registers the types the webhook decodes
+var scheme = utils.NewLazyScheme(admissionv1.AddToScheme, routev1.AddToScheme)
+
+// NewWebhook creates the new webhook
+func NewWebhook() *RouteTLSWebhook {
+	tlsPorts := defaultTLSPorts
+	if v := os.Getenv(tlsPortsEnvVar); v != "" {
+		tlsPorts = strings.Split(v, ",")
+		for i := range tlsPorts {
+			tlsPorts[i] = strings.TrimSpace(tlsPorts[i])
+		}
+	}
+
+	return &RouteTLSWebhook{
+		s:        scheme,
+		tlsPorts: tlsPorts,
+	}
+}
+
+// Authorized implements Webhook interface
+func (s *RouteTLSWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate upgrades plain customer Routes to TLS backends and raises
+// insecureEdgeTerminationPolicy to the managed minimum
+func (s *RouteTLSWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	if hookconfig.IsPrivilegedNamespace(request.Namespace) {
+		return utils.Allow(request, "Routes in privileged namespaces are exempt from TLS enforcement")
+	}
+
+	route, err := s.renderRoute(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a Route from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	var patches []jsonpatch.JsonPatchOperation
+	var warnings []string
+	switch {
+	case route.Spec.TLS == nil && s.targetsTLSPort(route):
+		patches = append(patches, jsonpatch.NewOperation("add", "/spec/tls", routev1.TLSConfig{
+			Termination:                   routev1.TLSTerminationPassthrough,
+			InsecureEdgeTerminationPolicy: minimumInsecurePolicy,
+		}))
+		warnings = append(warnings, fmt.Sprintf("Route %s targets TLS port %s without TLS termination. It has been changed to %s termination.", route.GetName(), route.Spec.Port.TargetPort.String(), routev1.TLSTerminationPassthrough))
+	case route.Spec.TLS != nil && route.Spec.TLS.InsecureEdgeTerminationPolicy == routev1.InsecureEdgeTerminationPolicyAllow:
+		patches = append(patches, jsonpatch.NewOperation("replace", "/spec/tls/insecureEdgeTerminationPolicy", minimumInsecurePolicy))
+		warnings = append(warnings, fmt.Sprintf("Route %s allowed insecure traffic. insecureEdgeTerminationPolicy has been changed to %s.", route.GetName(), minimumInsecurePolicy))
+	}
+
+	if len(patches) == 0 {
+		return utils.Allow(request, "Route meets the managed TLS minimum")
+	}
+
+	log.Info(fmt.Sprintf("Enforcing TLS minimum on route %s/%s", request.Namespace, route.GetName()))
+	return utils.WithUID(request, admissionctl.Patched(fmt.Sprintf("Enforced TLS minimum on route '%s'", route.GetName()), patches...).WithWarnings(warnings...))
+}
+
+// targetsTLSPort returns true if the Route's target port is one of tlsPorts
+func (s *RouteTLSWebhook) targetsTLSPort(route *routev1.Route) bool {
+	if route.Spec.Port == nil {
+		return false
+	}
+	target := route.Spec.Port.TargetPort.String()
+	for _, port := range s.tlsPorts {
+		if target == port {
+			return true
+		}
+	}
+	return false
+}
+
+// renderRoute renders the Route in the admission Request
+func (s *RouteTLSWebhook) renderRoute(request admissionctl.Request) (*routev1.Route, error) {
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return nil, err
+	}
+	route := &routev1.Route{}
+	err = decoder.Decode(request, route)
+	if err != nil {
+		return nil, err
+	}
+	return route, nil
+}
+
+// GetURI implements Webhook interface
+func (s *RouteTLSWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *RouteTLSWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "Route")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *RouteTLSWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *RouteTLSWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *RouteTLSWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *RouteTLSWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *RouteTLSWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *RouteTLSWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *RouteTLSWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *RouteTLSWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *RouteTLSWebhook) Doc() string {
+	return fmt.Sprintf(docString, defaultTLSPorts, minimumInsecurePolicy)
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Route TLS enforcement is opted in to per cluster by setting the
+// routeTLSFeatureFlag label to 'true' on the ClusterDeployment.
+func (s *RouteTLSWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	customLabelSelector := utils.DefaultLabelSelector()
+	customLabelSelector.MatchExpressions = append(customLabelSelector.MatchExpressions,
+		metav1.LabelSelectorRequirement{
+			Key:      routeTLSFeatureFlag,
+			Operator: metav1.LabelSelectorOpIn,
+			Values: []string{
+				"true",
+			},
+		})
+	return customLabelSelector
+}
+
+func (s *RouteTLSWebhook) ClassicEnabled() bool { return true }
+
+func (s *RouteTLSWebhook) HypershiftEnabled() bool { return true }
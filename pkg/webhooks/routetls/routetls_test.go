@@ -0,0 +1,119 @@
+package routetls
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	routev1 "github.com/openshift/api/route/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+)
+
+type routeTLSTestSuites struct {
+	testID        string
+	namespace     string
+	targetPort    intstr.IntOrString
+	tls           *routev1.TLSConfig
+	expectedTLS   *routev1.TLSConfig
+	expectWarning bool
+}
+
+func runRouteTLSTests(t *testing.T, tests []routeTLSTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "route.openshift.io",
+		Version: "v1",
+		Kind:    "Route",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "route.openshift.io",
+		Version:  "v1",
+		Resource: "routes",
+	}
+
+	for _, test := range tests {
+		rawRoute, err := json.Marshal(routev1.Route{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "route.openshift.io/v1", Kind: "Route"},
+			ObjectMeta: metav1.ObjectMeta{Name: test.testID, Namespace: test.namespace, UID: "1234"},
+			Spec: routev1.RouteSpec{
+				To:   routev1.RouteTargetReference{Kind: "Service", Name: "web"},
+				Port: &routev1.RoutePort{TargetPort: test.targetPort},
+				TLS:  test.tls,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
+		}
+		mutatedRoute := routev1.Route{}
+		response := testutils.SendMutation(t, NewWebhook(), testutils.MutationRequest{
+			TestID:    test.testID,
+			GVK:       gvk,
+			GVR:       gvr,
+			Namespace: test.namespace,
+			Object:    rawRoute,
+		}, &mutatedRoute)
+		if (len(response.Warnings) > 0) != test.expectWarning {
+			t.Fatalf("%s: Expected warnings %t, got %v", test.testID, test.expectWarning, response.Warnings)
+		}
+		if !reflect.DeepEqual(mutatedRoute.Spec.TLS, test.expectedTLS) {
+			t.Fatalf("%s: Expected TLS %v, got %v", test.testID, test.expectedTLS, mutatedRoute.Spec.TLS)
+		}
+	}
+}
+
+func TestRouteTLS(t *testing.T) {
+	tests := []routeTLSTestSuites{
+		{
+			testID:        "plain-route-to-https-port-upgraded",
+			namespace:     "my-namespace",
+			targetPort:    intstr.FromString("https"),
+			expectedTLS:   &routev1.TLSConfig{Termination: routev1.TLSTerminationPassthrough, InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyRedirect},
+			expectWarning: true,
+		},
+		{
+			testID:        "plain-route-to-8443-upgraded",
+			namespace:     "my-namespace",
+			targetPort:    intstr.FromInt(8443),
+			expectedTLS:   &routev1.TLSConfig{Termination: routev1.TLSTerminationPassthrough, InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyRedirect},
+			expectWarning: true,
+		},
+		{
+			testID:        "edge-route-allowing-insecure-normalized",
+			namespace:     "my-namespace",
+			targetPort:    intstr.FromInt(8080),
+			tls:           &routev1.TLSConfig{Termination: routev1.TLSTerminationEdge, InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyAllow},
+			expectedTLS:   &routev1.TLSConfig{Termination: routev1.TLSTerminationEdge, InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyRedirect},
+			expectWarning: true,
+		},
+		{
+			testID:      "edge-route-redirecting-untouched",
+			namespace:   "my-namespace",
+			targetPort:  intstr.FromInt(8080),
+			tls:         &routev1.TLSConfig{Termination: routev1.TLSTerminationEdge, InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyNone},
+			expectedTLS: &routev1.TLSConfig{Termination: routev1.TLSTerminationEdge, InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyNone},
+		},
+		{
+			testID:      "plain-route-to-http-port-untouched",
+			namespace:   "my-namespace",
+			targetPort:  intstr.FromString("http"),
+			expectedTLS: nil,
+		},
+		{
+			testID:      "privileged-namespace-untouched",
+			namespace:   "openshift-console",
+			targetPort:  intstr.FromString("https"),
+			expectedTLS: nil,
+		},
+	}
+	runRouteTLSTests(t, tests)
+}
+
+func TestTLSPortsFromEnv(t *testing.T) {
+	t.Setenv(tlsPortsEnvVar, "9443, grpc-tls")
+	hook := NewWebhook()
+	if !reflect.DeepEqual(hook.tlsPorts, []string{"9443", "grpc-tls"}) {
+		t.Fatalf("Expected TLS ports from the environment, got %v", hook.tlsPorts)
+	}
+}
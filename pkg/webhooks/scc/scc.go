@@ -6,11 +6,12 @@ import (
 	"slices"
 
 	securityv1 "github.com/openshift/api/security/v1"
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -35,11 +36,10 @@ var (
 			},
 		},
 	}
-	allowedUsers = []string{
+	allowedUsers = hookconfig.Identities([]string{
 		"system:serviceaccount:openshift-monitoring:cluster-monitoring-operator",
 		"system:serviceaccount:openshift-cluster-version:default",
-		"system:admin",
-	}
+	}, hookconfig.PlatformAdminUsersFor(WebhookName))
 	allowedGroups = []string{}
 	defaultSCCs   = []string{
 		"anyuid",
@@ -54,17 +54,14 @@ var (
 		"restricted",
 		"restricted-v2",
 	}
+	defaultSCCSet = utils.NewStringSet(defaultSCCs...)
 )
 
-type SCCWebHook struct {
-	scheme *runtime.Scheme
-}
+type SCCWebHook struct{}
 
 // NewWebhook creates the new webhook
 func NewWebhook() *SCCWebHook {
-	return &SCCWebHook{
-		scheme: runtime.NewScheme(),
-	}
+	return &SCCWebHook{}
 }
 
 // Authorized implements Webhook interface
@@ -73,50 +70,25 @@ func (s *SCCWebHook) Authorized(request admissionctl.Request) admissionctl.Respo
 }
 
 func (s *SCCWebHook) authorized(request admissionctl.Request) admissionctl.Response {
-	var ret admissionctl.Response
-
-	scc, err := s.renderSCC(request)
+	// The name is all the webhook needs of the SCC
+	name, err := utils.StringField(request.OldObject.Raw, "metadata", "name")
 	if err != nil {
-		log.Error(err, "Couldn't render a SCC from the incoming request")
+		log.Error(err, "Couldn't read the name of the SCC of the incoming request")
 		return admissionctl.Errored(http.StatusBadRequest, err)
 	}
 
-	if isDefaultSCC(scc) && !isAllowedUserGroup(request) {
+	if isDefaultSCC(name) && !isAllowedUserGroup(request) {
 		switch request.Operation {
 		case admissionv1.Delete:
-			log.Info(fmt.Sprintf("Deleting operation detected on default SCC: %v", scc.Name))
-			ret = admissionctl.Denied(fmt.Sprintf("Deleting default SCCs %v is not allowed", defaultSCCs))
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			log.Info(fmt.Sprintf("Deleting operation detected on default SCC: %v", name))
+			return utils.Deny(request, utils.ReasonSCCDefaultDelete, fmt.Sprintf("Deleting default SCCs %v is not allowed", defaultSCCs))
 		case admissionv1.Update:
-			log.Info(fmt.Sprintf("Updating operation detected on default SCC: %v", scc.Name))
-			ret = admissionctl.Denied(fmt.Sprintf("Modifying default SCCs %v is not allowed", defaultSCCs))
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			log.Info(fmt.Sprintf("Updating operation detected on default SCC: %v", name))
+			return utils.Deny(request, utils.ReasonSCCDefaultModify, fmt.Sprintf("Modifying default SCCs %v is not allowed", defaultSCCs))
 		}
 	}
 
-	ret = admissionctl.Allowed("Request is allowed")
-	ret.UID = request.AdmissionRequest.UID
-	return ret
-}
-
-// renderSCC render the SCC object from the requests
-func (s *SCCWebHook) renderSCC(request admissionctl.Request) (*securityv1.SecurityContextConstraints, error) {
-	decoder, err := admissionctl.NewDecoder(s.scheme)
-	if err != nil {
-		return nil, err
-	}
-	scc := &securityv1.SecurityContextConstraints{}
-
-	if len(request.OldObject.Raw) > 0 {
-		err = decoder.DecodeRaw(request.OldObject, scc)
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	return scc, nil
+	return utils.Allow(request, "Request is allowed")
 }
 
 // isAllowedUserGroup checks if the user or group is allowed to perform the action
@@ -136,13 +108,8 @@ func isAllowedUserGroup(request admissionctl.Request) bool {
 
 // isDefaultSCC checks if the request is going to operate on the SCC in the
 // default list
-func isDefaultSCC(scc *securityv1.SecurityContextConstraints) bool {
-	for _, s := range defaultSCCs {
-		if scc.Name == s {
-			return true
-		}
-	}
-	return false
+func isDefaultSCC(name string) bool {
+	return defaultSCCSet.Has(name)
 }
 
 // GetURI implements Webhook interface
@@ -184,6 +151,11 @@ func (s *SCCWebHook) ObjectSelector() *metav1.LabelSelector {
 	return nil
 }
 
+// NamespaceSelector implements Webhook interface
+func (s *SCCWebHook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
 // SideEffects implements Webhook interface
 func (s *SCCWebHook) SideEffects() admissionregv1.SideEffectClass {
 	return admissionregv1.SideEffectClassNone
@@ -208,3 +180,16 @@ func (s *SCCWebHook) SyncSetLabelSelector() metav1.LabelSelector {
 func (s *SCCWebHook) ClassicEnabled() bool { return true }
 
 func (s *SCCWebHook) HypershiftEnabled() bool { return true }
+
+// ProtectedObjects implements webhooks.ObjectProtector, the default SCCs
+// must not be deleted
+func (s *SCCWebHook) ProtectedObjects() []*unstructured.Unstructured {
+	objects := make([]*unstructured.Unstructured, 0, len(defaultSCCs))
+	for _, name := range defaultSCCs {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(securityv1.GroupVersion.WithKind("SecurityContextConstraints"))
+		obj.SetName(name)
+		objects = append(objects, obj)
+	}
+	return objects
+}
@@ -2,12 +2,14 @@ package scc
 
 import (
 	"fmt"
+	"path/filepath"
 	"testing"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -77,6 +79,15 @@ func runSCCTests(t *testing.T, tests []sccTestSuites) {
 		if response.Allowed != test.shouldBeAllowed {
 			t.Fatalf("Mismatch: %s (groups=%s) %s %s the scc. Test's expectation is that the user %s", test.username, test.userGroups, testutils.CanCanNot(response.Allowed), test.operation, testutils.CanCanNot(test.shouldBeAllowed))
 		}
+		if !response.Allowed {
+			expectedCode := utils.ReasonSCCDefaultModify
+			if test.operation == admissionv1.Delete {
+				expectedCode = utils.ReasonSCCDefaultDelete
+			}
+			if string(response.Result.Reason) != string(expectedCode) || response.AuditAnnotations[utils.ReasonCodeAuditAnnotation] != string(expectedCode) {
+				t.Fatalf("%s: Expected reason code %s in the status and audit annotations, got %s and %v", test.testID, expectedCode, response.Result.Reason, response.AuditAnnotations)
+			}
+		}
 	}
 }
 func TestUser(t *testing.T) {
@@ -172,3 +183,54 @@ func TestUser(t *testing.T) {
 	}
 	runSCCTests(t, tests)
 }
+
+// TestCapturedFixtures replays the requests captured from clusters in
+// testdata, which must get the decision they got when captured
+func TestCapturedFixtures(t *testing.T) {
+	fixtures, err := filepath.Glob("testdata/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range fixtures {
+		response, fixture, err := testutils.ReplayFixture(path, NewWebhook())
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if response.Allowed != fixture.Allowed {
+			t.Errorf("%s: expected allowed to be %v, got %v", path, fixture.Allowed, response.Allowed)
+		}
+		if !response.Allowed && fixture.Code != "" && response.Result.Reason != metav1.StatusReason(fixture.Code) {
+			t.Errorf("%s: expected the reason code %s, got %s", path, fixture.Code, response.Result.Reason)
+		}
+	}
+}
+
+func BenchmarkAuthorized(b *testing.B) {
+	gvk := metav1.GroupVersionKind{Group: "security.openshift.io", Version: "v1", Kind: "SecurityContextConstraints"}
+	gvr := metav1.GroupVersionResource{Group: "security.openshift.io", Version: "v1", Resource: "securitycontextconstraints"}
+	benchmarks := []struct {
+		name    string
+		scc     string
+		allowed bool
+	}{
+		{name: "default-scc-update-denied", scc: "anyuid", allowed: false},
+		{name: "custom-scc-update-allowed", scc: "my-scc", allowed: true},
+	}
+	hook := NewWebhook()
+	for _, bm := range benchmarks {
+		obj := testutils.RepresentativeObject(testutils.RepresentativeSCC, bm.scc, "")
+		request, err := testutils.CreateRequest(bm.name, gvk, gvr, admissionv1.Update, "alice", []string{"dedicated-admins", "system:authenticated"}, "", obj, obj)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if resp := hook.Authorized(request); resp.Allowed != bm.allowed {
+			b.Fatalf("%s: expected allowed=%v, got %v", bm.name, bm.allowed, resp.Allowed)
+		}
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				hook.Authorized(request)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package sccpriority
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+
+	securityv1 "github.com/openshift/api/security/v1"
+	"gomodules.xyz/jsonpatch/v2"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/policy"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "sccpriority-mutation"
+	docString   string = `Managed OpenShift Customers may not create SCCs with a priority above %d. Higher priorities are lowered to %d and a warning is returned.`
+	// maxPriority is the highest priority a customer SCC may carry. It is kept
+	// below the default anyuid SCC (priority 10) so customer SCCs never outrank
+	// the platform defaults during SCC admission.
+	maxPriority int32 = policy.MaxSCCPriorityCeiling
+	// clampPriorityFeatureFlag is the ClusterDeployment label which opts a
+	// cluster in to SCC priority clamping.
+	clampPriorityFeatureFlag string = "ext-managed.openshift.io/clamp-scc-priority"
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.ClusterScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{"CREATE", "UPDATE"},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{"security.openshift.io"},
+				APIVersions: []string{"*"},
+				Resources:   []string{"securitycontextconstraints"},
+				Scope:       &scope,
+			},
+		},
+	}
+	allowedUsers                = hookconfig.Identities(hookconfig.KubeAdminUsersFor(WebhookName), hookconfig.PlatformAdminUsersFor(WebhookName), hookconfig.SREAdminUsers)
+	privilegedServiceAccountsRe = regexp.MustCompile(hookconfig.PrivilegedServiceAccountGroups)
+)
+
+// SCCPriorityWebhook mutates customer SCCs to stay under the priority ceiling
+type SCCPriorityWebhook struct {
+	scheme *utils.LazyScheme
+}
+
+// NewWebhook creates the new webhook
+func NewWebhook() *SCCPriorityWebhook {
+	return &SCCPriorityWebhook{
+		scheme: utils.EmptyScheme,
+	}
+}
+
+// Authorized implements Webhook interface
+func (s *SCCPriorityWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate lowers the priority of customer SCCs which exceed the
+// priority ceiling, maxPriority unless the ValidatingWebhookPolicy lowers it
+func (s *SCCPriorityWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	if isAllowedUserGroup(request) {
+		return utils.Allow(request, "Privileged users may set any SCC priority")
+	}
+
+	scc, err := s.renderSCC(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a SCC from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	// The ValidatingWebhookPolicy may lower the ceiling
+	ceiling := policy.Current().SCCPriorityCeilingOr(maxPriority)
+	if scc.Priority == nil || *scc.Priority <= ceiling {
+		return utils.Allow(request, "SCC priority is within the allowed range")
+	}
+
+	log.Info(fmt.Sprintf("Clamping priority of SCC %s from %d to %d", scc.Name, *scc.Priority, ceiling))
+	warning := fmt.Sprintf("SCC %s priority lowered from %d to the maximum allowed priority %d", scc.Name, *scc.Priority, ceiling)
+	return utils.WithUID(request, admissionctl.Patched(
+		fmt.Sprintf("Clamped priority of SCC '%s'", scc.Name),
+		jsonpatch.NewOperation("replace", "/priority", ceiling),
+	).WithWarnings(warning))
+}
+
+// renderSCC renders the SCC being created or updated in the admission Request
+func (s *SCCPriorityWebhook) renderSCC(request admissionctl.Request) (*securityv1.SecurityContextConstraints, error) {
+	decoder, err := s.scheme.Decoder()
+	if err != nil {
+		return nil, err
+	}
+	scc := &securityv1.SecurityContextConstraints{}
+	err = decoder.DecodeRaw(request.Object, scc)
+	if err != nil {
+		return nil, err
+	}
+	return scc, nil
+}
+
+// isAllowedUserGroup checks if the user or group may set any priority
+func isAllowedUserGroup(request admissionctl.Request) bool {
+	if slices.Contains(allowedUsers, request.UserInfo.Username) {
+		return true
+	}
+
+	for _, group := range request.UserInfo.Groups {
+		if privilegedServiceAccountsRe.Match([]byte(group)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetURI implements Webhook interface
+func (s *SCCPriorityWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *SCCPriorityWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "SecurityContextConstraints")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *SCCPriorityWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *SCCPriorityWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *SCCPriorityWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *SCCPriorityWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *SCCPriorityWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *SCCPriorityWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *SCCPriorityWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *SCCPriorityWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *SCCPriorityWebhook) Doc() string {
+	return fmt.Sprintf(docString, maxPriority, maxPriority)
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Priority clamping is opted in to per cluster by setting the
+// clampPriorityFeatureFlag label to 'true' on the ClusterDeployment.
+func (s *SCCPriorityWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	customLabelSelector := utils.DefaultLabelSelector()
+	customLabelSelector.MatchExpressions = append(customLabelSelector.MatchExpressions,
+		metav1.LabelSelectorRequirement{
+			Key:      clampPriorityFeatureFlag,
+			Operator: metav1.LabelSelectorOpIn,
+			Values: []string{
+				"true",
+			},
+		})
+	return customLabelSelector
+}
+
+func (s *SCCPriorityWebhook) ClassicEnabled() bool { return true }
+
+func (s *SCCPriorityWebhook) HypershiftEnabled() bool { return false }
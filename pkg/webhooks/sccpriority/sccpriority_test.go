@@ -0,0 +1,162 @@
+package sccpriority
+
+import (
+	"fmt"
+	"testing"
+
+	securityv1 "github.com/openshift/api/security/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/policy"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+)
+
+const testObjectRaw string = `
+{
+	"apiVersion": "security.openshift.io/v1",
+	"kind": "SecurityContextConstraints",
+	"metadata": {
+		"name": "%s",
+		"uid": "1234"
+	},
+	"priority": %s
+}`
+
+type sccPriorityTestSuites struct {
+	testID           string
+	username         string
+	userGroups       []string
+	operation        admissionv1.Operation
+	priority         *int32
+	expectedPriority *int32
+	expectWarning    bool
+}
+
+func createRawJSONString(name string, priority *int32) string {
+	p := "null"
+	if priority != nil {
+		p = fmt.Sprintf("%d", *priority)
+	}
+	return fmt.Sprintf(testObjectRaw, name, p)
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func runSCCPriorityTests(t *testing.T, tests []sccPriorityTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "security.openshift.io",
+		Version: "v1",
+		Kind:    "SecurityContextConstraints",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "security.openshift.io",
+		Version:  "v1",
+		Resource: "securitycontextconstraints",
+	}
+
+	for _, test := range tests {
+		rawSCC := []byte(createRawJSONString("isv-operator-scc", test.priority))
+		mutatedSCC := securityv1.SecurityContextConstraints{}
+		response := testutils.SendMutation(t, NewWebhook(), testutils.MutationRequest{
+			TestID:     test.testID,
+			GVK:        gvk,
+			GVR:        gvr,
+			Operation:  test.operation,
+			Username:   test.username,
+			UserGroups: test.userGroups,
+			Object:     rawSCC,
+		}, &mutatedSCC)
+		if (len(response.Warnings) > 0) != test.expectWarning {
+			t.Fatalf("%s: Expected warnings %t, got %v", test.testID, test.expectWarning, response.Warnings)
+		}
+		if (mutatedSCC.Priority == nil) != (test.expectedPriority == nil) ||
+			(mutatedSCC.Priority != nil && *mutatedSCC.Priority != *test.expectedPriority) {
+			t.Fatalf("%s: Expected priority %v, got %v", test.testID, test.expectedPriority, mutatedSCC.Priority)
+		}
+	}
+}
+
+func TestClampPriority(t *testing.T) {
+	tests := []sccPriorityTestSuites{
+		{
+			testID:           "customer-scc-above-ceiling-is-clamped",
+			username:         "my_user",
+			userGroups:       []string{"system:authenticated"},
+			operation:        admissionv1.Create,
+			priority:         int32Ptr(100),
+			expectedPriority: int32Ptr(maxPriority),
+			expectWarning:    true,
+		},
+		{
+			testID:           "customer-scc-update-above-ceiling-is-clamped",
+			username:         "my_user",
+			userGroups:       []string{"system:authenticated"},
+			operation:        admissionv1.Update,
+			priority:         int32Ptr(maxPriority + 1),
+			expectedPriority: int32Ptr(maxPriority),
+			expectWarning:    true,
+		},
+		{
+			testID:           "customer-scc-at-ceiling-untouched",
+			username:         "my_user",
+			userGroups:       []string{"system:authenticated"},
+			operation:        admissionv1.Create,
+			priority:         int32Ptr(maxPriority),
+			expectedPriority: int32Ptr(maxPriority),
+		},
+		{
+			testID:           "customer-scc-without-priority-untouched",
+			username:         "my_user",
+			userGroups:       []string{"system:authenticated"},
+			operation:        admissionv1.Create,
+			priority:         nil,
+			expectedPriority: nil,
+		},
+		{
+			testID:           "platform-serviceaccount-untouched",
+			username:         "system:serviceaccount:openshift-cluster-version:default",
+			userGroups:       []string{"system:serviceaccounts:openshift-cluster-version"},
+			operation:        admissionv1.Create,
+			priority:         int32Ptr(100),
+			expectedPriority: int32Ptr(100),
+		},
+		{
+			testID:           "backplane-admin-untouched",
+			username:         "backplane-cluster-admin",
+			userGroups:       []string{"system:authenticated"},
+			operation:        admissionv1.Update,
+			priority:         int32Ptr(100),
+			expectedPriority: int32Ptr(100),
+		},
+	}
+	runSCCPriorityTests(t, tests)
+}
+
+func TestPolicyLowersCeiling(t *testing.T) {
+	policy.SetCurrent(policy.Spec{SCCPriorityCeiling: int32Ptr(5)})
+	t.Cleanup(func() { policy.SetCurrent(policy.Spec{}) })
+
+	tests := []sccPriorityTestSuites{
+		{
+			testID:           "customer-scc-above-policy-ceiling-is-clamped",
+			username:         "my_user",
+			userGroups:       []string{"system:authenticated"},
+			operation:        admissionv1.Create,
+			priority:         int32Ptr(maxPriority),
+			expectedPriority: int32Ptr(5),
+			expectWarning:    true,
+		},
+		{
+			testID:           "customer-scc-at-policy-ceiling-untouched",
+			username:         "my_user",
+			userGroups:       []string{"system:authenticated"},
+			operation:        admissionv1.Create,
+			priority:         int32Ptr(5),
+			expectedPriority: int32Ptr(5),
+		},
+	}
+	runSCCPriorityTests(t, tests)
+}
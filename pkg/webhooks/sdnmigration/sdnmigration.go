@@ -8,10 +8,10 @@ import (
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 )
 
@@ -23,7 +23,7 @@ const (
 
 var (
 	log                         = logf.Log.WithName(WebhookName)
-	privilegedServiceAccountsRe = regexp.MustCompile(utils.PrivilegedServiceAccountGroups)
+	privilegedServiceAccountsRe = regexp.MustCompile(hookconfig.PrivilegedServiceAccountGroups)
 
 	scope = admissionregv1.ClusterScope
 	rules = []admissionregv1.RuleWithOperations{
@@ -40,7 +40,7 @@ var (
 )
 
 type NetworkConfigWebhook struct {
-	s runtime.Scheme
+	s *utils.LazyScheme
 }
 
 // Authorized will determine if the request is allowed
@@ -53,12 +53,10 @@ func (w *NetworkConfigWebhook) Authorized(request admissionctl.Request) admissio
 	}
 
 	if request.Operation == admissionv1.Update {
-		decoder, err := admissionctl.NewDecoder(&w.s)
+		decoder, err := w.s.Decoder()
 		if err != nil {
 			log.Error(err, "failed to initialize decoder")
-			ret := admissionctl.Errored(http.StatusBadRequest, err)
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
 		}
 
 		object := &configv1.Network{}
@@ -66,15 +64,11 @@ func (w *NetworkConfigWebhook) Authorized(request admissionctl.Request) admissio
 
 		if err := decoder.Decode(request, object); err != nil {
 			log.Error(err, "failed to render a Network from request.Object")
-			ret := admissionctl.Errored(http.StatusBadRequest, err)
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
 		}
 		if err := decoder.DecodeRaw(request.OldObject, oldObject); err != nil {
 			log.Error(err, "failed to render a Network from request.OldObject")
-			ret := admissionctl.Errored(http.StatusBadRequest, err)
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+			return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
 		}
 
 		if v, ok := oldObject.Annotations[overrideAnnotation]; ok && v == "true" {
@@ -126,6 +120,8 @@ func (w *NetworkConfigWebhook) Rules() []admissionregv1.RuleWithOperations { ret
 // LabelSelector.
 func (w *NetworkConfigWebhook) ObjectSelector() *metav1.LabelSelector { return nil }
 
+func (w *NetworkConfigWebhook) NamespaceSelector() *metav1.LabelSelector { return nil }
+
 // SideEffects are what side effects, if any, this hook has. Refer to
 // https://kubernetes.io/docs/reference/access-authn-authz/extensible-admission-controllers/#side-effects
 func (w *NetworkConfigWebhook) SideEffects() admissionregv1.SideEffectClass {
@@ -151,9 +147,7 @@ func (w *NetworkConfigWebhook) HypershiftEnabled() bool { return false }
 
 // NewWebhook creates a new webhook
 func NewWebhook() *NetworkConfigWebhook {
-	scheme := runtime.NewScheme()
-
 	return &NetworkConfigWebhook{
-		s: *scheme,
+		s: utils.EmptyScheme,
 	}
 }
@@ -1,25 +1,30 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"slices"
 	"strings"
 
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/policy"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 	"gomodules.xyz/jsonpatch/v2"
 
+	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 const (
 	WebhookName           string = "service-mutation"
-	docString             string = `LoadBalancer-type services on Managed OpenShift clusters must contain an additional annotation for managed policy compliance.`
+	docString             string = `LoadBalancer-type services on Managed OpenShift clusters must contain an additional annotation for managed policy compliance. Customer namespaces may be limited to a number of them by the loadBalancerQuota of the ValidatingWebhookPolicy.`
 	annotationKey         string = "service.beta.kubernetes.io/aws-load-balancer-additional-resource-tags"
 	annotationValuePrefix string = "red-hat-managed="
 	annotationValueSuffix string = "true"
@@ -47,14 +52,15 @@ var (
 
 // ServiceWebhook mutates a Service change
 type ServiceWebhook struct {
-	s runtime.Scheme
+	s          *utils.LazyScheme
+	kubeClient *utils.LazyClient
 }
 
 // NewWebhook creates the new webhook
 func NewWebhook() *ServiceWebhook {
-	scheme := runtime.NewScheme()
 	return &ServiceWebhook{
-		s: *scheme,
+		s:          utils.CoreScheme,
+		kubeClient: utils.CoreClient,
 	}
 }
 
@@ -77,15 +83,29 @@ func (s *ServiceWebhook) authorizeOrMutate(request admissionctl.Request) admissi
 	}
 
 	if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
-		ret = admissionctl.Allowed("Non-LoadBalancer Services are exempt from compliance annotation requirements")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "Non-LoadBalancer Services are exempt from compliance annotation requirements")
+	}
+
+	if quota := policy.Current().LoadBalancerQuota; quota != nil && !hookconfig.IsPrivilegedNamespace(request.Namespace) {
+		added, err := s.addsLoadBalancer(request)
+		if err != nil {
+			log.Error(err, "Could not render the old Service from the incoming request")
+			return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+		}
+		if added {
+			count, err := s.countLoadBalancers(context.Background(), request.Namespace, service.GetName())
+			if err != nil {
+				log.Error(err, "Couldn't count the LoadBalancer Services of the namespace")
+				return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+			}
+			if count >= int(*quota) {
+				return utils.Deny(request, utils.ReasonServiceLoadBalancerQuota, fmt.Sprintf("Namespace %s already has %d LoadBalancer Services, the most allowed on this cluster", request.Namespace, count))
+			}
+		}
 	}
 
 	if hasRedHatManagedTag(service.GetAnnotations()) {
-		ret = admissionctl.Allowed(fmt.Sprintf("Service '%s' contains the proper compliance annotation", service.GetName()))
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, fmt.Sprintf("Service '%s' contains the proper compliance annotation", service.GetName()))
 	}
 
 	// If we've gotten this far, then mutation is necessary
@@ -99,6 +119,45 @@ func (s *ServiceWebhook) authorizeOrMutate(request admissionctl.Request) admissi
 	return ret
 }
 
+// addsLoadBalancer returns whether the request adds a LoadBalancer Service to
+// the namespace, by creating one or by changing the type of an existing one.
+// Other updates, e.g. of the finalizers by the service controller, must go
+// on even when the namespace is over a lowered quota
+func (s *ServiceWebhook) addsLoadBalancer(request admissionctl.Request) (bool, error) {
+	if request.Operation != admissionv1.Update {
+		return true, nil
+	}
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return false, err
+	}
+	old := &corev1.Service{}
+	if err := decoder.DecodeRaw(request.OldObject, old); err != nil {
+		return false, err
+	}
+	return old.Spec.Type != corev1.ServiceTypeLoadBalancer, nil
+}
+
+// countLoadBalancers returns how many LoadBalancer Services namespace has
+// other than the one named name, which the request creates or updates
+func (s *ServiceWebhook) countLoadBalancers(ctx context.Context, namespace, name string) (int, error) {
+	kubeClient, err := s.kubeClient.Client()
+	if err != nil {
+		return 0, fmt.Errorf("fail creating KubeClient for ServiceWebhook: %v", err)
+	}
+	services := &corev1.ServiceList{}
+	if err := kubeClient.List(ctx, services, client.InNamespace(namespace)); err != nil {
+		return 0, fmt.Errorf("failed to list Services in namespace %s: %v", namespace, err)
+	}
+	count := 0
+	for _, svc := range services.Items {
+		if svc.Name != name && svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
+			count++
+		}
+	}
+	return count, nil
+}
+
 // hasRedHatManagedTag checks if a Service's "aws-load-balancer-additional-resource-tags"
 // annotation contains the necessary value for compliance with managed policies.
 // Set serviceAnnotations param to output of service.GetAnnotations()
@@ -115,8 +174,7 @@ func hasRedHatManagedTag(serviceAnnotations map[string]string) bool {
 func buildPatch(serviceAnnotations map[string]string) jsonpatch.JsonPatchOperation {
 	patchPath := "/metadata/annotations"
 	if serviceAnnotations != nil {
-		rfc6901Encoder := strings.NewReplacer("~", "~0", "/", "~1")
-		patchPath += "/" + rfc6901Encoder.Replace(annotationKey)
+		patchPath += "/" + utils.EscapeJSONPointer(annotationKey)
 	}
 
 	existingAnnotationValue, hasAnnotation := serviceAnnotations[annotationKey]
@@ -145,7 +203,7 @@ func buildPatch(serviceAnnotations map[string]string) jsonpatch.JsonPatchOperati
 
 // renderService extracts the Service from the incoming request
 func (s *ServiceWebhook) renderService(req admissionctl.Request) (*corev1.Service, error) {
-	decoder, err := admissionctl.NewDecoder(&s.s)
+	decoder, err := s.s.Decoder()
 	if err != nil {
 		return nil, err
 	}
@@ -158,6 +216,18 @@ func (s *ServiceWebhook) renderService(req admissionctl.Request) (*corev1.Servic
 	return service, nil
 }
 
+// RequiredPermissions implements webhooks.PermissionRequirer, the webhook
+// looks up the Services of the namespace
+func (s *ServiceWebhook) RequiredPermissions() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{corev1.GroupName},
+			Resources: []string{"services"},
+			Verbs:     []string{"list"},
+		},
+	}
+}
+
 // GetURI implements Webhook interface
 func (s *ServiceWebhook) GetURI() string {
 	return "/" + WebhookName
@@ -197,6 +267,11 @@ func (s *ServiceWebhook) ObjectSelector() *metav1.LabelSelector {
 	return nil
 }
 
+// NamespaceSelector implements Webhook interface
+func (s *ServiceWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
 // SideEffects implements Webhook interface
 func (s *ServiceWebhook) SideEffects() admissionregv1.SideEffectClass {
 	return admissionregv1.SideEffectClassNone
@@ -12,8 +12,12 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/policy"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
 )
 
 const patchPath string = "/metadata/annotations/service.beta.kubernetes.io~1aws-load-balancer-additional-resource-tags"
@@ -476,3 +480,112 @@ func Test_hasRedHatManagedTag(t *testing.T) {
 		})
 	}
 }
+
+func loadBalancerService(namespace, name string, serviceType corev1.ServiceType) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       corev1.ServiceSpec{Type: serviceType},
+	}
+}
+
+func TestLoadBalancerQuota(t *testing.T) {
+	quota := int32(2)
+	policy.SetCurrent(policy.Spec{LoadBalancerQuota: &quota})
+	t.Cleanup(func() { policy.SetCurrent(policy.Spec{}) })
+
+	gvk := metav1.GroupVersionKind{Version: "v1", Kind: "Service"}
+	gvr := metav1.GroupVersionResource{Version: "v1", Resource: "services"}
+	tests := []struct {
+		testID          string
+		namespace       string
+		operation       admissionv1.Operation
+		oldType         corev1.ServiceType
+		existing        []client.Object
+		shouldBeAllowed bool
+	}{
+		{
+			testID:          "below-quota",
+			namespace:       "my-ns",
+			existing:        []client.Object{loadBalancerService("my-ns", "lb1", corev1.ServiceTypeLoadBalancer), loadBalancerService("my-ns", "web", corev1.ServiceTypeClusterIP)},
+			shouldBeAllowed: true,
+		},
+		{
+			testID:          "at-quota",
+			namespace:       "my-ns",
+			existing:        []client.Object{loadBalancerService("my-ns", "lb1", corev1.ServiceTypeLoadBalancer), loadBalancerService("my-ns", "lb2", corev1.ServiceTypeLoadBalancer)},
+			shouldBeAllowed: false,
+		},
+		{
+			testID:          "update-over-lowered-quota",
+			namespace:       "my-ns",
+			operation:       admissionv1.Update,
+			oldType:         corev1.ServiceTypeLoadBalancer,
+			existing:        []client.Object{loadBalancerService("my-ns", "lb1", corev1.ServiceTypeLoadBalancer), loadBalancerService("my-ns", "lb2", corev1.ServiceTypeLoadBalancer), loadBalancerService("my-ns", "testservice", corev1.ServiceTypeLoadBalancer)},
+			shouldBeAllowed: true,
+		},
+		{
+			testID:          "update-to-loadbalancer-at-quota",
+			namespace:       "my-ns",
+			operation:       admissionv1.Update,
+			oldType:         corev1.ServiceTypeClusterIP,
+			existing:        []client.Object{loadBalancerService("my-ns", "lb1", corev1.ServiceTypeLoadBalancer), loadBalancerService("my-ns", "lb2", corev1.ServiceTypeLoadBalancer), loadBalancerService("my-ns", "testservice", corev1.ServiceTypeClusterIP)},
+			shouldBeAllowed: false,
+		},
+		{
+			testID:          "update-of-counted-service",
+			namespace:       "my-ns",
+			existing:        []client.Object{loadBalancerService("my-ns", "lb1", corev1.ServiceTypeLoadBalancer), loadBalancerService("my-ns", "testservice", corev1.ServiceTypeLoadBalancer)},
+			shouldBeAllowed: true,
+		},
+		{
+			testID:          "other-namespaces-not-counted",
+			namespace:       "my-ns",
+			existing:        []client.Object{loadBalancerService("other-ns", "lb1", corev1.ServiceTypeLoadBalancer), loadBalancerService("other-ns", "lb2", corev1.ServiceTypeLoadBalancer)},
+			shouldBeAllowed: true,
+		},
+		{
+			testID:          "privileged-namespace-unlimited",
+			namespace:       "openshift-ingress",
+			existing:        []client.Object{loadBalancerService("openshift-ingress", "lb1", corev1.ServiceTypeLoadBalancer), loadBalancerService("openshift-ingress", "lb2", corev1.ServiceTypeLoadBalancer)},
+			shouldBeAllowed: true,
+		},
+	}
+	for _, test := range tests {
+		hook := NewWebhook()
+		scheme, err := hook.s.Scheme()
+		if err != nil {
+			t.Fatal(err)
+		}
+		hook.kubeClient = utils.StaticClient(fake.NewClientBuilder().WithScheme(scheme).WithObjects(test.existing...).Build())
+		operation := test.operation
+		if operation == "" {
+			operation = admissionv1.Create
+		}
+		obj := &runtime.RawExtension{Raw: createJSONByteArrayService(nil)}
+		var oldObj *runtime.RawExtension
+		if test.oldType != "" {
+			old := loadBalancerService(test.namespace, "testservice", test.oldType)
+			old.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Service"}
+			raw, err := json.Marshal(old)
+			if err != nil {
+				t.Fatal(err)
+			}
+			oldObj = &runtime.RawExtension{Raw: raw}
+		}
+		httprequest, err := testutils.CreateHTTPRequest(hook.GetURI(),
+			test.testID, gvk, gvr, operation, "my_user", []string{"my_group"}, test.namespace, obj, oldObj)
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err.Error())
+		}
+		response, err := testutils.SendHTTPRequest(httprequest, hook)
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err.Error())
+		}
+		if response.Allowed != test.shouldBeAllowed {
+			t.Errorf("%s: Expected allowed %t, got %t", test.testID, test.shouldBeAllowed, response.Allowed)
+		}
+		if !response.Allowed && response.Result.Reason != metav1.StatusReason(utils.ReasonServiceLoadBalancerQuota) {
+			t.Errorf("%s: Expected reason %s, got %s", test.testID, utils.ReasonServiceLoadBalancerQuota, response.Result.Reason)
+		}
+	}
+}
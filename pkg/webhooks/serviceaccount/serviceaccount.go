@@ -3,15 +3,12 @@ package serviceaccount
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"slices"
 	"strings"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
-	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
@@ -39,12 +36,8 @@ var (
 			},
 		},
 	}
-	allowedUsers = []string{
-		"backplane-cluster-admin",
-	}
-	allowedGroups = []string{
-		"system:serviceaccounts:openshift-backplane-srep",
-	}
+	allowedUsers           = config.SREAdminUsers
+	allowedGroups          = config.SREAdminGroups
 	allowedServiceAccounts = []string{
 		"builder",
 		"default",
@@ -57,27 +50,11 @@ var (
 	}
 )
 
-type serviceAccountWebhook struct {
-	s runtime.Scheme
-}
+type serviceAccountWebhook struct{}
 
 // NewWebhook creates the new webhook
 func NewWebhook() *serviceAccountWebhook {
-	scheme := runtime.NewScheme()
-	err := admissionv1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding admissionsv1 scheme to serviceAccountWebhook")
-		os.Exit(1)
-	}
-	err = corev1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding corev1 scheme to serviceAccountWebhook")
-		os.Exit(1)
-	}
-
-	return &serviceAccountWebhook{
-		s: *scheme,
-	}
+	return &serviceAccountWebhook{}
 }
 
 // Authorized implements Webhook interface
@@ -86,63 +63,34 @@ func (s *serviceAccountWebhook) Authorized(request admissionctl.Request) admissi
 }
 
 func (s *serviceAccountWebhook) authorized(request admissionctl.Request) admissionctl.Response {
-	var ret admissionctl.Response
-
 	if request.AdmissionRequest.UserInfo.Username == "system:unauthenticated" {
 		// This could highlight a significant problem with RBAC since an
 		// unauthenticated user should have no permissions.
-		log.Info("system:unauthenticated made a webhook request. Check RBAC rules", "request", request.AdmissionRequest)
-		ret = admissionctl.Denied("Unauthenticated")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		log.Info("system:unauthenticated made a webhook request. Check RBAC rules", "request", utils.RedactRequest(request.AdmissionRequest))
+		return utils.Deny(request, utils.ReasonServiceAccountUnauthenticated, "Unauthenticated")
 	}
 	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "system:") {
-		ret = admissionctl.Allowed("authenticated system: users are allowed")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "authenticated system: users are allowed")
 	}
 	if strings.HasPrefix(request.AdmissionRequest.UserInfo.Username, "kube:") {
-		ret = admissionctl.Allowed("kube: users are allowed")
-		ret.UID = request.AdmissionRequest.UID
-		return ret
+		return utils.Allow(request, "kube: users are allowed")
 	}
 
-	sa, err := s.renderServiceAccount(request)
+	// The name is all the webhook needs of the service account
+	name, err := utils.StringField(request.OldObject.Raw, "metadata", "name")
 	if err != nil {
-		log.Error(err, "Couldn't render a service account from the incoming request")
+		log.Error(err, "Couldn't read the name of the service account of the incoming request")
 		return admissionctl.Errored(http.StatusBadRequest, err)
 	}
 
 	if isProtectedNamespace(request) && !isAllowedUserGroup(request) {
-		if request.Operation == admissionv1.Delete && !isAllowedServiceAccount(sa) {
-			log.Info(fmt.Sprintf("Deleting operation detected on proteced serviceaccount: %v", sa.Name))
-			ret = admissionctl.Denied(fmt.Sprintf("Deleting protected service account under namespace %v is not allowed", request.Namespace))
-			ret.UID = request.AdmissionRequest.UID
-			return ret
+		if request.Operation == admissionv1.Delete && !isAllowedServiceAccount(name) {
+			log.Info(fmt.Sprintf("Deleting operation detected on proteced serviceaccount: %v", name))
+			return utils.Deny(request, utils.ReasonServiceAccountProtectedDelete, fmt.Sprintf("Deleting protected service account under namespace %v is not allowed", request.Namespace))
 		}
 	}
 
-	ret = admissionctl.Allowed("Request is allowed")
-	ret.UID = request.AdmissionRequest.UID
-	return ret
-}
-
-// renderServiceAccount render the serviceaccount object from the requests
-func (s *serviceAccountWebhook) renderServiceAccount(request admissionctl.Request) (*corev1.ServiceAccount, error) {
-	decoder, err := admissionctl.NewDecoder(&s.s)
-	if err != nil {
-		return nil, err
-	}
-	sa := &corev1.ServiceAccount{}
-
-	if len(request.OldObject.Raw) > 0 {
-		err = decoder.DecodeRaw(request.OldObject, sa)
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	return sa, nil
+	return utils.Allow(request, "Request is allowed")
 }
 
 // isAllowedUserGroup checks if the user or group is allowed to perform the action
@@ -171,9 +119,9 @@ func isProtectedNamespace(request admissionctl.Request) bool {
 	return false
 }
 
-func isAllowedServiceAccount(sa *corev1.ServiceAccount) bool {
+func isAllowedServiceAccount(name string) bool {
 	for _, s := range allowedServiceAccounts {
-		if sa.Name == s {
+		if name == s {
 			return true
 		}
 	}
@@ -220,6 +168,11 @@ func (s *serviceAccountWebhook) ObjectSelector() *metav1.LabelSelector {
 	return nil
 }
 
+// NamespaceSelector implements Webhook interface
+func (s *serviceAccountWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
 // SideEffects implements Webhook interface
 func (s *serviceAccountWebhook) SideEffects() admissionregv1.SideEffectClass {
 	return admissionregv1.SideEffectClassNone
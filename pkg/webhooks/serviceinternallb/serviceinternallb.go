@@ -0,0 +1,327 @@
+package serviceinternallb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"gomodules.xyz/jsonpatch/v2"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/k8sutil"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/ttlcache"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "serviceinternallb-mutation"
+	docString   string = `LoadBalancer-type services in customer namespaces on private Managed OpenShift clusters are annotated to use an internal load balancer. Services which explicitly request a public load balancer are denied.`
+	// privateClusterFeatureFlag is the ClusterDeployment label which marks a
+	// cluster as private and opts it in to internal load balancer enforcement
+	privateClusterFeatureFlag string = "ext-managed.openshift.io/private-cluster-internal-lb"
+	// platformCacheTTL is how long the platform of the cluster is cached,
+	// which is long since it never changes
+	platformCacheTTL = time.Hour
+	// lookupTimeout bounds the lookup of the cluster infrastructure, within
+	// the timeout of the webhook
+	lookupTimeout = time.Second
+)
+
+// lbAnnotation is a cloud-specific annotation which selects the kind of load
+// balancer of a Service
+type lbAnnotation struct {
+	key   string
+	value string
+}
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.NamespacedScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+				admissionregv1.Update,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"services"},
+				Scope:       &scope,
+			},
+		},
+	}
+	// internalLBAnnotations request an internal load balancer
+	internalLBAnnotations = map[configv1.PlatformType]lbAnnotation{
+		configv1.AWSPlatformType:   {key: "service.beta.kubernetes.io/aws-load-balancer-internal", value: "true"},
+		configv1.AzurePlatformType: {key: "service.beta.kubernetes.io/azure-load-balancer-internal", value: "true"},
+		configv1.GCPPlatformType:   {key: "networking.gke.io/load-balancer-type", value: "Internal"},
+	}
+	// publicLBAnnotations request a public load balancer whatever the
+	// internal load balancer annotation, e.g. with the AWS Load Balancer
+	// Controller
+	publicLBAnnotations = map[configv1.PlatformType][]lbAnnotation{
+		configv1.AWSPlatformType: {{key: "service.beta.kubernetes.io/aws-load-balancer-scheme", value: "internet-facing"}},
+	}
+)
+
+// ServiceInternalLBWebhook mutates customer LoadBalancer Services to be internal
+type ServiceInternalLBWebhook struct {
+	s          *utils.LazyScheme
+	kubeClient *utils.LazyClient
+	platform   *ttlcache.Cache[configv1.PlatformType]
+}
+
+var (
+	// scheme registers the types the webhook decodes
+	scheme     = utils.NewLazyScheme(admissionv1.AddToScheme, corev1.AddToScheme, configv1.AddToScheme)
+	kubeClient = utils.NewLazyClient(scheme)
+	// platform caches the platform of the cluster when the cluster
+	// capabilities aren't loaded. The webhook is constructed for every
+	// request, so it is kept here rather than on the webhook.
+	platform = ttlcache.New[configv1.PlatformType](platformCacheTTL, 1)
+)
+
+// NewWebhook creates the new webhook
+func NewWebhook() *ServiceInternalLBWebhook {
+	return &ServiceInternalLBWebhook{
+		s:          scheme,
+		kubeClient: kubeClient,
+		platform:   platform,
+	}
+}
+
+// Authorized implements Webhook interface
+func (s *ServiceInternalLBWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate ensures customer LoadBalancer Services carry the internal
+// load balancer annotation for the cluster's cloud, denying public overrides
+func (s *ServiceInternalLBWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	if hookconfig.IsPrivilegedNamespace(request.Namespace) {
+		return utils.Allow(request, "Services in privileged namespaces are exempt from internal load balancer enforcement")
+	}
+
+	service, err := s.renderService(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a Service from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		return utils.Allow(request, "Non-LoadBalancer Services are exempt from internal load balancer enforcement")
+	}
+
+	platform, err := s.clusterPlatform()
+	if err != nil {
+		log.Error(err, "Failed to determine the cluster platform")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+
+	annotation, supported := internalLBAnnotations[platform]
+	if !supported {
+		return utils.Allow(request, fmt.Sprintf("Platform %s has no internal load balancer annotation", platform))
+	}
+
+	for _, public := range publicLBAnnotations[platform] {
+		if value, found := service.GetAnnotations()[public.key]; found && strings.EqualFold(value, public.value) {
+			log.Info(fmt.Sprintf("Denying public load balancer for service %s/%s", request.Namespace, service.GetName()))
+			return utils.Deny(request, utils.ReasonILBPublicLoadBalancer, fmt.Sprintf("Services on private clusters must use an internal load balancer, remove %s: %s", public.key, public.value))
+		}
+	}
+
+	value, found := service.GetAnnotations()[annotation.key]
+	if found && strings.EqualFold(value, annotation.value) {
+		return utils.Allow(request, fmt.Sprintf("Service '%s' already uses an internal load balancer", service.GetName()))
+	}
+	if found {
+		log.Info(fmt.Sprintf("Denying public load balancer for service %s/%s", request.Namespace, service.GetName()))
+		return utils.Deny(request, utils.ReasonILBPublicLoadBalancer, fmt.Sprintf("Services on private clusters must use an internal load balancer, set %s to %s", annotation.key, annotation.value))
+	}
+
+	log.Info(fmt.Sprintf("%s operation on service %s/%s mutated to use an internal load balancer", request.Operation, request.Namespace, service.GetName()))
+	return utils.WithUID(request, admissionctl.Patched(
+		fmt.Sprintf("Added internal load balancer annotation to service '%s'", service.GetName()),
+		buildPatch(service.GetAnnotations(), annotation),
+	))
+}
+
+// buildPatch constructs a JSONPatch adding the internal load balancer annotation
+func buildPatch(serviceAnnotations map[string]string, annotation lbAnnotation) jsonpatch.JsonPatchOperation {
+	if serviceAnnotations == nil {
+		// No annotation key at all
+		return jsonpatch.NewOperation("add", "/metadata/annotations", map[string]string{annotation.key: annotation.value})
+	}
+	return jsonpatch.NewOperation("add", "/metadata/annotations/"+utils.EscapeJSONPointer(annotation.key), annotation.value)
+}
+
+// clusterPlatform returns the cloud platform the cluster runs on
+func (s *ServiceInternalLBWebhook) clusterPlatform() (configv1.PlatformType, error) {
+	if caps, loaded := k8sutil.ClusterCapabilities(); loaded {
+		return caps.Platform, nil
+	}
+	return s.platform.Get("cluster", s.infrastructurePlatform)
+}
+
+// infrastructurePlatform looks up the platform in the status of the cluster
+// Infrastructure
+func (s *ServiceInternalLBWebhook) infrastructurePlatform() (configv1.PlatformType, error) {
+	kubeClient, err := s.kubeClient.Client()
+	if err != nil {
+		return "", fmt.Errorf("fail creating KubeClient for ServiceInternalLBWebhook: %v", err)
+	}
+	// Concurrent requests share the lookup of the first, so it must not be
+	// canceled with it
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	infra := &configv1.Infrastructure{}
+	err = kubeClient.Get(ctx, client.ObjectKey{Name: "cluster"}, infra)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster infrastructure config: %v", err)
+	}
+	if infra.Status.PlatformStatus == nil {
+		return "", nil
+	}
+	return infra.Status.PlatformStatus.Type, nil
+}
+
+// renderService renders the Service in the admission Request
+func (s *ServiceInternalLBWebhook) renderService(request admissionctl.Request) (*corev1.Service, error) {
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return nil, err
+	}
+	service := &corev1.Service{}
+	err = decoder.Decode(request, service)
+	if err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// RequiredPermissions implements webhooks.PermissionRequirer, the webhook
+// annotates Services for the platform of the cluster
+func (s *ServiceInternalLBWebhook) RequiredPermissions() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{configv1.GroupName},
+			Resources: []string{"infrastructures"},
+			Verbs:     []string{"get"},
+		},
+	}
+}
+
+// GetURI implements Webhook interface
+func (s *ServiceInternalLBWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *ServiceInternalLBWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "Service")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *ServiceInternalLBWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *ServiceInternalLBWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *ServiceInternalLBWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *ServiceInternalLBWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *ServiceInternalLBWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *ServiceInternalLBWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *ServiceInternalLBWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *ServiceInternalLBWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *ServiceInternalLBWebhook) Doc() string {
+	return docString
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Enforcement is opted in to per cluster by setting the
+// privateClusterFeatureFlag label to 'true' on private ClusterDeployments.
+func (s *ServiceInternalLBWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	customLabelSelector := utils.DefaultLabelSelector()
+	customLabelSelector.MatchExpressions = append(customLabelSelector.MatchExpressions,
+		metav1.LabelSelectorRequirement{
+			Key:      privateClusterFeatureFlag,
+			Operator: metav1.LabelSelectorOpIn,
+			Values: []string{
+				"true",
+			},
+		})
+	return customLabelSelector
+}
+
+// SyncSetLabelSelectorForCompliance implements webhooks.ComplianceSelector.
+// Under FedRAMP no cluster may expose a public load balancer, so enforcement
+// applies to every cluster rather than those opted in.
+func (s *ServiceInternalLBWebhook) SyncSetLabelSelectorForCompliance(compliance utils.Compliance) metav1.LabelSelector {
+	if compliance == utils.ComplianceFedRAMP {
+		return utils.DefaultLabelSelector()
+	}
+	return s.SyncSetLabelSelector()
+}
+
+// EnabledOnPlatform implements webhooks.PlatformFilter. Only the platforms
+// with an internal load balancer annotation are enforced.
+func (s *ServiceInternalLBWebhook) EnabledOnPlatform(platform configv1.PlatformType, region string) bool {
+	_, supported := internalLBAnnotations[platform]
+	return supported
+}
+
+func (s *ServiceInternalLBWebhook) ClassicEnabled() bool { return true }
+
+func (s *ServiceInternalLBWebhook) HypershiftEnabled() bool { return false }
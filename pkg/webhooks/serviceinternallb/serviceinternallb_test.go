@@ -0,0 +1,180 @@
+package serviceinternallb
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/ttlcache"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+func newMockInfrastructure(platform configv1.PlatformType) client.Client {
+	s := runtime.NewScheme()
+	_ = configv1.Install(s)
+	infra := &configv1.Infrastructure{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Status: configv1.InfrastructureStatus{
+			PlatformStatus: &configv1.PlatformStatus{Type: platform},
+		},
+	}
+	return fake.NewClientBuilder().WithScheme(s).WithObjects(infra).Build()
+}
+
+type serviceInternalLBTestSuites struct {
+	testID              string
+	platform            configv1.PlatformType
+	namespace           string
+	serviceType         corev1.ServiceType
+	annotations         map[string]string
+	shouldBeAllowed     bool
+	expectedAnnotations map[string]string
+}
+
+func runServiceInternalLBTests(t *testing.T, tests []serviceInternalLBTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "Service",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "services",
+	}
+
+	for _, test := range tests {
+		rawService, err := json.Marshal(corev1.Service{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+			ObjectMeta: metav1.ObjectMeta{Name: test.testID, Namespace: test.namespace, UID: "1234", Annotations: test.annotations},
+			Spec:       corev1.ServiceSpec{Type: test.serviceType},
+		})
+		if err != nil {
+			t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
+		}
+		mutatedService := corev1.Service{}
+		hook := NewWebhook()
+		hook.kubeClient = utils.StaticClient(newMockInfrastructure(test.platform))
+		hook.platform = ttlcache.New[configv1.PlatformType](platformCacheTTL, 1)
+		response := testutils.SendMutation(t, hook, testutils.MutationRequest{
+			TestID:       test.testID,
+			GVK:          gvk,
+			GVR:          gvr,
+			Namespace:    test.namespace,
+			Object:       rawService,
+			ExpectDenied: !test.shouldBeAllowed,
+		}, &mutatedService)
+		if !response.Allowed {
+			continue
+		}
+		if !reflect.DeepEqual(mutatedService.Annotations, test.expectedAnnotations) {
+			t.Fatalf("%s: Expected annotations %v, got %v", test.testID, test.expectedAnnotations, mutatedService.Annotations)
+		}
+	}
+}
+
+func TestInternalLoadBalancer(t *testing.T) {
+	awsInternal := "service.beta.kubernetes.io/aws-load-balancer-internal"
+	tests := []serviceInternalLBTestSuites{
+		{
+			testID:              "aws-loadbalancer-gets-internal-annotation",
+			platform:            configv1.AWSPlatformType,
+			namespace:           "my-namespace",
+			serviceType:         corev1.ServiceTypeLoadBalancer,
+			shouldBeAllowed:     true,
+			expectedAnnotations: map[string]string{awsInternal: "true"},
+		},
+		{
+			testID:              "gcp-loadbalancer-keeps-other-annotations",
+			platform:            configv1.GCPPlatformType,
+			namespace:           "my-namespace",
+			serviceType:         corev1.ServiceTypeLoadBalancer,
+			annotations:         map[string]string{"team": "a"},
+			shouldBeAllowed:     true,
+			expectedAnnotations: map[string]string{"team": "a", "networking.gke.io/load-balancer-type": "Internal"},
+		},
+		{
+			testID:              "already-internal-untouched",
+			platform:            configv1.AWSPlatformType,
+			namespace:           "my-namespace",
+			serviceType:         corev1.ServiceTypeLoadBalancer,
+			annotations:         map[string]string{awsInternal: "true"},
+			shouldBeAllowed:     true,
+			expectedAnnotations: map[string]string{awsInternal: "true"},
+		},
+		{
+			testID:          "explicit-public-override-denied",
+			platform:        configv1.AWSPlatformType,
+			namespace:       "my-namespace",
+			serviceType:     corev1.ServiceTypeLoadBalancer,
+			annotations:     map[string]string{awsInternal: "false"},
+			shouldBeAllowed: false,
+		},
+		{
+			testID:          "aws-internet-facing-scheme-denied",
+			platform:        configv1.AWSPlatformType,
+			namespace:       "my-namespace",
+			serviceType:     corev1.ServiceTypeLoadBalancer,
+			annotations:     map[string]string{awsInternal: "true", "service.beta.kubernetes.io/aws-load-balancer-scheme": "internet-facing"},
+			shouldBeAllowed: false,
+		},
+		{
+			testID:              "aws-internal-scheme-gets-internal-annotation",
+			platform:            configv1.AWSPlatformType,
+			namespace:           "my-namespace",
+			serviceType:         corev1.ServiceTypeLoadBalancer,
+			annotations:         map[string]string{"service.beta.kubernetes.io/aws-load-balancer-scheme": "internal"},
+			shouldBeAllowed:     true,
+			expectedAnnotations: map[string]string{awsInternal: "true", "service.beta.kubernetes.io/aws-load-balancer-scheme": "internal"},
+		},
+		{
+			testID:              "clusterip-untouched",
+			platform:            configv1.AWSPlatformType,
+			namespace:           "my-namespace",
+			serviceType:         corev1.ServiceTypeClusterIP,
+			shouldBeAllowed:     true,
+			expectedAnnotations: nil,
+		},
+		{
+			testID:              "privileged-namespace-untouched",
+			platform:            configv1.AWSPlatformType,
+			namespace:           "openshift-ingress",
+			serviceType:         corev1.ServiceTypeLoadBalancer,
+			shouldBeAllowed:     true,
+			expectedAnnotations: nil,
+		},
+	}
+	runServiceInternalLBTests(t, tests)
+}
+
+func TestSyncSetLabelSelectorForCompliance(t *testing.T) {
+	hook := NewWebhook()
+	if !reflect.DeepEqual(hook.SyncSetLabelSelectorForCompliance(utils.ComplianceNone), hook.SyncSetLabelSelector()) {
+		t.Error("Expected the opt-in selector without a compliance profile")
+	}
+	if !reflect.DeepEqual(hook.SyncSetLabelSelectorForCompliance(utils.ComplianceFedRAMP), utils.DefaultLabelSelector()) {
+		t.Error("Expected every cluster to be selected under fedramp")
+	}
+}
+
+func TestEnabledOnPlatform(t *testing.T) {
+	hook := NewWebhook()
+	for platform, expected := range map[configv1.PlatformType]bool{
+		configv1.AWSPlatformType:       true,
+		configv1.GCPPlatformType:       true,
+		configv1.AzurePlatformType:     true,
+		configv1.BareMetalPlatformType: false,
+	} {
+		if enabled := hook.EnabledOnPlatform(platform, ""); enabled != expected {
+			t.Errorf("Expected enabled on %s to be %v, got %v", platform, expected, enabled)
+		}
+	}
+}
@@ -3,15 +3,11 @@ package techpreviewnoupgrade
 import (
 	"fmt"
 	"net/http"
-	"os"
 
 	configv1 "github.com/openshift/api/config/v1"
 	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
-	admissionv1 "k8s.io/api/admission/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
-	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -39,11 +35,13 @@ var (
 )
 
 type TechPreviewNoUpgradeWebhook struct {
-	s runtime.Scheme
+	s *utils.LazyScheme
 }
 
 func (s *TechPreviewNoUpgradeWebhook) ObjectSelector() *metav1.LabelSelector { return nil }
 
+func (s *TechPreviewNoUpgradeWebhook) NamespaceSelector() *metav1.LabelSelector { return nil }
+
 func (s *TechPreviewNoUpgradeWebhook) Doc() string {
 	return fmt.Sprintf(docString)
 }
@@ -89,7 +87,7 @@ func (s *TechPreviewNoUpgradeWebhook) ClassicEnabled() bool { return true }
 func (s *TechPreviewNoUpgradeWebhook) HypershiftEnabled() bool { return true }
 
 func (s *TechPreviewNoUpgradeWebhook) renderFeatureGate(request admissionctl.Request) (*configv1.FeatureGate, error) {
-	decoder, err := admissionctl.NewDecoder(&s.s)
+	decoder, err := s.s.Decoder()
 	if err != nil {
 		return nil, err
 	}
@@ -105,51 +103,27 @@ func (s *TechPreviewNoUpgradeWebhook) renderFeatureGate(request admissionctl.Req
 }
 
 func (s *TechPreviewNoUpgradeWebhook) authorized(request admissionctl.Request) admissionctl.Response {
-	var ret admissionctl.Response
-
 	featureGate, err := s.renderFeatureGate(request)
 
 	if err != nil {
 		log.Error(err, "Couldn't render a FeatureGate from the incoming request")
 
-		ret = admissionctl.Errored(http.StatusBadRequest, err)
-		ret.UID = request.AdmissionRequest.UID
-
-		return ret
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
 	}
 
 	if featureGate != nil && featureGate.Spec.FeatureSet == "TechPreviewNoUpgrade" {
-		log.Info("Not allowing access because of TechPreviewNoUpgrade Feature Gate", "request", request.AdmissionRequest)
+		log.Info("Not allowing access because of TechPreviewNoUpgrade Feature Gate", "request", utils.RedactRequest(request.AdmissionRequest))
 
-		ret = admissionctl.Denied("The TechPreviewNoUpgrade Feature Gate is not allowed")
-		ret.UID = request.AdmissionRequest.UID
-
-		return ret
+		return utils.Deny(request, utils.ReasonTechPreviewNoUpgradeFeatureGate, "The TechPreviewNoUpgrade Feature Gate is not allowed")
 	}
 
-	log.Info("Allowing access", "request", request.AdmissionRequest)
-
-	ret = admissionctl.Allowed("FeatureGate operation is allowed")
-	ret.UID = request.AdmissionRequest.UID
+	log.Info("Allowing access", "request", utils.RedactRequest(request.AdmissionRequest))
 
-	return ret
+	return utils.Allow(request, "FeatureGate operation is allowed")
 }
 
 func NewWebhook() *TechPreviewNoUpgradeWebhook {
-	scheme := runtime.NewScheme()
-
-	err := admissionv1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding admissionsv1 scheme to TechPreviewNoUpgradeWebhook")
-		os.Exit(1)
-	}
-	err = corev1.AddToScheme(scheme)
-	if err != nil {
-		log.Error(err, "Fail adding corev1 scheme to TechPreviewNoUpgradeWebhook")
-		os.Exit(1)
-	}
-
 	return &TechPreviewNoUpgradeWebhook{
-		s: *scheme,
+		s: utils.CoreScheme,
 	}
 }
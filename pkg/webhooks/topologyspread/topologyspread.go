@@ -0,0 +1,217 @@
+package topologyspread
+
+import (
+	"fmt"
+	"net/http"
+
+	"gomodules.xyz/jsonpatch/v2"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	hookconfig "github.com/openshift/managed-cluster-validating-webhooks/pkg/config"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+const (
+	WebhookName string = "topologyspread-mutation"
+	docString   string = `Deployments in customer namespaces on Managed OpenShift clusters with more than one replica and no topologySpreadConstraints have their pods spread across %v on a best-effort basis, so a single zone or node failure doesn't take out every replica.`
+	// topologySpreadFeatureFlag is the ClusterDeployment label which opts a
+	// cluster in to defaulting topology spread constraints
+	topologySpreadFeatureFlag string = "ext-managed.openshift.io/default-topology-spread"
+)
+
+var (
+	timeout int32 = 2
+	log           = logf.Log.WithName(WebhookName)
+	scope         = admissionregv1.NamespacedScope
+	rules         = []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{
+				admissionregv1.Create,
+				admissionregv1.Update,
+			},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{"apps"},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"deployments"},
+				Scope:       &scope,
+			},
+		},
+	}
+	// topologyKeys are the failure domains replicas are spread across
+	topologyKeys = []string{
+		corev1.LabelTopologyZone,
+		corev1.LabelHostname,
+	}
+)
+
+// TopologySpreadWebhook mutates customer Deployments to spread their replicas
+type TopologySpreadWebhook struct {
+	s *utils.LazyScheme
+}
+
+// scheme registers the types the webhook decodes
+var scheme = utils.NewLazyScheme(admissionv1.AddToScheme, appsv1.AddToScheme)
+
+// NewWebhook creates the new webhook
+func NewWebhook() *TopologySpreadWebhook {
+	return &TopologySpreadWebhook{
+		s: scheme,
+	}
+}
+
+// Authorized implements Webhook interface
+func (s *TopologySpreadWebhook) Authorized(request admissionctl.Request) admissionctl.Response {
+	ret := s.authorizeOrMutate(request)
+	// ret.Complete() sets the UID and finalizes the patch
+	if err := ret.Complete(request); err != nil {
+		log.Error(err, "Failed to complete the request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusInternalServerError, err))
+	}
+	return ret
+}
+
+// authorizeOrMutate adds default topology spread constraints to multi-replica
+// customer Deployments which define none
+func (s *TopologySpreadWebhook) authorizeOrMutate(request admissionctl.Request) admissionctl.Response {
+	if hookconfig.IsPrivilegedNamespace(request.Namespace) {
+		return utils.Allow(request, "Deployments in privileged namespaces are exempt from topology spread defaulting")
+	}
+
+	deployment, err := s.renderDeployment(request)
+	if err != nil {
+		log.Error(err, "Couldn't render a Deployment from the incoming request")
+		return utils.WithUID(request, admissionctl.Errored(http.StatusBadRequest, err))
+	}
+
+	// A nil replicas count defaults to 1
+	if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas <= 1 {
+		return utils.Allow(request, "Single-replica Deployments are not spread")
+	}
+	if len(deployment.Spec.Template.Spec.TopologySpreadConstraints) > 0 {
+		return utils.Allow(request, fmt.Sprintf("Deployment '%s' already defines topology spread constraints", deployment.GetName()))
+	}
+	if deployment.Spec.Selector == nil {
+		return utils.Allow(request, fmt.Sprintf("Deployment '%s' has no selector to spread its pods by", deployment.GetName()))
+	}
+
+	log.Info(fmt.Sprintf("Adding topology spread constraints to deployment %s/%s", request.Namespace, deployment.GetName()))
+	return utils.WithUID(request, admissionctl.Patched(
+		fmt.Sprintf("Added topology spread constraints to deployment '%s'", deployment.GetName()),
+		jsonpatch.NewOperation("add", "/spec/template/spec/topologySpreadConstraints", defaultConstraints(deployment.Spec.Selector)),
+	))
+}
+
+// defaultConstraints returns a best-effort spread constraint for each
+// topologyKey, selecting the Deployment's own pods
+func defaultConstraints(selector *metav1.LabelSelector) []corev1.TopologySpreadConstraint {
+	constraints := make([]corev1.TopologySpreadConstraint, 0, len(topologyKeys))
+	for _, key := range topologyKeys {
+		constraints = append(constraints, corev1.TopologySpreadConstraint{
+			MaxSkew:     1,
+			TopologyKey: key,
+			// ScheduleAnyway never leaves a pod pending because of spreading
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector:     selector.DeepCopy(),
+		})
+	}
+	return constraints
+}
+
+// renderDeployment renders the Deployment in the admission Request
+func (s *TopologySpreadWebhook) renderDeployment(request admissionctl.Request) (*appsv1.Deployment, error) {
+	decoder, err := s.s.Decoder()
+	if err != nil {
+		return nil, err
+	}
+	deployment := &appsv1.Deployment{}
+	err = decoder.Decode(request, deployment)
+	if err != nil {
+		return nil, err
+	}
+	return deployment, nil
+}
+
+// GetURI implements Webhook interface
+func (s *TopologySpreadWebhook) GetURI() string {
+	return "/" + WebhookName
+}
+
+// Validate implements Webhook interface
+func (s *TopologySpreadWebhook) Validate(request admissionctl.Request) bool {
+	valid := true
+	valid = valid && (request.UserInfo.Username != "")
+	valid = valid && (request.Kind.Kind == "Deployment")
+
+	return valid
+}
+
+// Name implements Webhook interface
+func (s *TopologySpreadWebhook) Name() string {
+	return WebhookName
+}
+
+// FailurePolicy implements Webhook interface
+func (s *TopologySpreadWebhook) FailurePolicy() admissionregv1.FailurePolicyType {
+	return admissionregv1.Ignore
+}
+
+// MatchPolicy implements Webhook interface
+func (s *TopologySpreadWebhook) MatchPolicy() admissionregv1.MatchPolicyType {
+	return admissionregv1.Equivalent
+}
+
+// Rules implements Webhook interface
+func (s *TopologySpreadWebhook) Rules() []admissionregv1.RuleWithOperations {
+	return rules
+}
+
+// ObjectSelector implements Webhook interface
+func (s *TopologySpreadWebhook) ObjectSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// NamespaceSelector implements Webhook interface
+func (s *TopologySpreadWebhook) NamespaceSelector() *metav1.LabelSelector {
+	return nil
+}
+
+// SideEffects implements Webhook interface
+func (s *TopologySpreadWebhook) SideEffects() admissionregv1.SideEffectClass {
+	return admissionregv1.SideEffectClassNone
+}
+
+// TimeoutSeconds implements Webhook interface
+func (s *TopologySpreadWebhook) TimeoutSeconds() int32 {
+	return timeout
+}
+
+// Doc implements Webhook interface
+func (s *TopologySpreadWebhook) Doc() string {
+	return fmt.Sprintf(docString, topologyKeys)
+}
+
+// SyncSetLabelSelector returns the label selector to use in the SyncSet.
+// Topology spread defaulting is opted in to per cluster by setting the
+// topologySpreadFeatureFlag label to 'true' on the ClusterDeployment.
+func (s *TopologySpreadWebhook) SyncSetLabelSelector() metav1.LabelSelector {
+	customLabelSelector := utils.DefaultLabelSelector()
+	customLabelSelector.MatchExpressions = append(customLabelSelector.MatchExpressions,
+		metav1.LabelSelectorRequirement{
+			Key:      topologySpreadFeatureFlag,
+			Operator: metav1.LabelSelectorOpIn,
+			Values: []string{
+				"true",
+			},
+		})
+	return customLabelSelector
+}
+
+func (s *TopologySpreadWebhook) ClassicEnabled() bool { return true }
+
+func (s *TopologySpreadWebhook) HypershiftEnabled() bool { return true }
@@ -0,0 +1,124 @@
+package topologyspread
+
+import (
+	"encoding/json"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/testutils"
+)
+
+type topologySpreadTestSuites struct {
+	testID              string
+	namespace           string
+	replicas            *int32
+	constraints         []corev1.TopologySpreadConstraint
+	expectedConstraints int
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func runTopologySpreadTests(t *testing.T, tests []topologySpreadTestSuites) {
+	gvk := metav1.GroupVersionKind{
+		Group:   "apps",
+		Version: "v1",
+		Kind:    "Deployment",
+	}
+	gvr := metav1.GroupVersionResource{
+		Group:    "apps",
+		Version:  "v1",
+		Resource: "deployments",
+	}
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+	for _, test := range tests {
+		rawDeployment, err := json.Marshal(appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+			ObjectMeta: metav1.ObjectMeta{Name: test.testID, Namespace: test.namespace, UID: "1234"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: test.replicas,
+				Selector: selector,
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: selector.MatchLabels},
+					Spec: corev1.PodSpec{
+						Containers:                []corev1.Container{{Name: "web", Image: "quay.io/example/web:v1"}},
+						TopologySpreadConstraints: test.constraints,
+					},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Couldn't create a JSON fragment %s", err.Error())
+		}
+
+		mutatedDeployment := appsv1.Deployment{}
+		testutils.SendMutation(t, NewWebhook(), testutils.MutationRequest{
+			TestID:    test.testID,
+			GVK:       gvk,
+			GVR:       gvr,
+			Namespace: test.namespace,
+			Object:    rawDeployment,
+		}, &mutatedDeployment)
+		constraints := mutatedDeployment.Spec.Template.Spec.TopologySpreadConstraints
+		if len(constraints) != test.expectedConstraints {
+			t.Fatalf("%s: Expected %d topology spread constraints, got %v", test.testID, test.expectedConstraints, constraints)
+		}
+		for _, c := range constraints {
+			if test.constraints == nil && c.WhenUnsatisfiable != corev1.ScheduleAnyway {
+				t.Fatalf("%s: Expected defaulted constraint %s to be best-effort, got %s", test.testID, c.TopologyKey, c.WhenUnsatisfiable)
+			}
+			if c.LabelSelector == nil || c.LabelSelector.MatchLabels["app"] != "web" {
+				t.Fatalf("%s: Expected constraint %s to select the Deployment's pods, got %v", test.testID, c.TopologyKey, c.LabelSelector)
+			}
+		}
+	}
+}
+
+func TestDefaultTopologySpread(t *testing.T) {
+	own := []corev1.TopologySpreadConstraint{
+		{
+			MaxSkew:           2,
+			TopologyKey:       corev1.LabelTopologyZone,
+			WhenUnsatisfiable: corev1.DoNotSchedule,
+			LabelSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		},
+	}
+	tests := []topologySpreadTestSuites{
+		{
+			testID:              "multi-replica-deployment-spread",
+			namespace:           "my-namespace",
+			replicas:            int32Ptr(3),
+			expectedConstraints: 2,
+		},
+		{
+			testID:              "single-replica-deployment-untouched",
+			namespace:           "my-namespace",
+			replicas:            int32Ptr(1),
+			expectedConstraints: 0,
+		},
+		{
+			testID:              "defaulted-replicas-untouched",
+			namespace:           "my-namespace",
+			expectedConstraints: 0,
+		},
+		{
+			testID:              "own-constraints-untouched",
+			namespace:           "my-namespace",
+			replicas:            int32Ptr(3),
+			constraints:         own,
+			expectedConstraints: 1,
+		},
+		{
+			testID:              "privileged-namespace-untouched",
+			namespace:           "openshift-monitoring",
+			replicas:            int32Ptr(3),
+			expectedConstraints: 0,
+		},
+	}
+	runTopologySpreadTests(t, tests)
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"sync"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/k8sutil"
+)
+
+// CoreClient is the client of the webhooks reading core types, shared by
+// all of them
+var CoreClient = NewLazyClient(CoreScheme)
+
+// LazyClient is a client of the API server for the types of a LazyScheme,
+// built on its first use. Building a client runs API discovery, and the
+// webhooks are constructed for every request, so they hold a package
+// LazyClient rather than building their own.
+type LazyClient struct {
+	scheme *LazyScheme
+	mu     sync.Mutex
+	client client.Client
+}
+
+// NewLazyClient returns a LazyClient for the types of scheme
+func NewLazyClient(scheme *LazyScheme) *LazyClient {
+	return &LazyClient{scheme: scheme}
+}
+
+// StaticClient returns a LazyClient of kubeClient, for tests to give webhooks
+// a fake client
+func StaticClient(kubeClient client.Client) *LazyClient {
+	return &LazyClient{client: kubeClient}
+}
+
+// Client returns the client, building it on the first call. Errors aren't
+// kept, so a client which failed to build, e.g. since the API server was
+// unavailable, is built again by the next call.
+func (l *LazyClient) Client() (client.Client, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.client != nil {
+		return l.client, nil
+	}
+	kubeScheme, err := l.scheme.Scheme()
+	if err != nil {
+		return nil, err
+	}
+	kubeClient, err := k8sutil.KubeClient(kubeScheme)
+	if err != nil {
+		return nil, err
+	}
+	l.client = kubeClient
+	return kubeClient, nil
+}
@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var errUnexpectedEnd = errors.New("unexpected end of the JSON document")
+
+// Field returns the raw JSON value at path, a list of object keys, in the
+// JSON document raw, or nil if raw is empty or has no such field. It scans raw without
+// decoding it and doesn't allocate, so hooks which only need a few fields of
+// a large object, e.g. its name, don't pay for decoding all of it. The scan
+// stops at the field, raw is only checked to be valid JSON up to it. The
+// first one of duplicate keys is found, which the API server never sends.
+func Field(raw []byte, path ...string) ([]byte, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	i := skipSpace(raw, 0)
+	for _, key := range path {
+		if i >= len(raw) {
+			return nil, errUnexpectedEnd
+		}
+		if raw[i] != '{' {
+			if bytes.HasPrefix(raw[i:], []byte("null")) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("expected an object at offset %d of the JSON document above field %q", i, key)
+		}
+		var err error
+		var found bool
+		if i, found, err = findKey(raw, i, key); err != nil || !found {
+			return nil, err
+		}
+	}
+	end, err := skipValue(raw, i)
+	if err != nil {
+		return nil, err
+	}
+	return raw[i:end], nil
+}
+
+// StringField returns the string at path in the JSON document raw, or the
+// empty string if raw is empty or has no such field. Like Field it doesn't decode raw,
+// the returned string is its only allocation.
+func StringField(raw []byte, path ...string) (string, error) {
+	value, err := Field(raw, path...)
+	if err != nil || value == nil || bytes.Equal(value, []byte("null")) {
+		return "", err
+	}
+	if value[0] != '"' {
+		return "", fmt.Errorf("expected a string at field %v of the JSON document", path)
+	}
+	if bytes.IndexByte(value, '\\') < 0 {
+		return string(value[1 : len(value)-1]), nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+// findKey returns the offset of the value of key in the object starting at
+// offset i of raw, and whether the object has key
+func findKey(raw []byte, i int, key string) (int, bool, error) {
+	i = skipSpace(raw, i+1)
+	if i < len(raw) && raw[i] == '}' {
+		return i, false, nil
+	}
+	for {
+		if i >= len(raw) || raw[i] != '"' {
+			return i, false, fmt.Errorf("expected an object key at offset %d of the JSON document", i)
+		}
+		end, escaped, err := skipString(raw, i)
+		if err != nil {
+			return i, false, err
+		}
+		match := keyEquals(raw[i:end], escaped, key)
+		i = skipSpace(raw, end)
+		if i >= len(raw) || raw[i] != ':' {
+			return i, false, fmt.Errorf("expected a colon at offset %d of the JSON document", i)
+		}
+		i = skipSpace(raw, i+1)
+		if match {
+			return i, true, nil
+		}
+		if i, err = skipValue(raw, i); err != nil {
+			return i, false, err
+		}
+		i = skipSpace(raw, i)
+		if i >= len(raw) {
+			return i, false, errUnexpectedEnd
+		}
+		switch raw[i] {
+		case ',':
+			i = skipSpace(raw, i+1)
+		case '}':
+			return i, false, nil
+		default:
+			return i, false, fmt.Errorf("expected a comma or the end of an object at offset %d of the JSON document", i)
+		}
+	}
+}
+
+// keyEquals returns whether the quoted object key quoted is key
+func keyEquals(quoted []byte, escaped bool, key string) bool {
+	if !escaped {
+		// The conversion is optimized out of the comparison
+		return string(quoted[1:len(quoted)-1]) == key
+	}
+	var s string
+	return json.Unmarshal(quoted, &s) == nil && s == key
+}
+
+// skipValue returns the offset following the JSON value starting at offset i
+// of raw
+func skipValue(raw []byte, i int) (int, error) {
+	if i >= len(raw) {
+		return i, errUnexpectedEnd
+	}
+	switch c := raw[i]; {
+	case c == '"':
+		end, _, err := skipString(raw, i)
+		return end, err
+	case c == '{' || c == '[':
+		return skipNested(raw, i)
+	case c == 't':
+		return skipLiteral(raw, i, "true")
+	case c == 'f':
+		return skipLiteral(raw, i, "false")
+	case c == 'n':
+		return skipLiteral(raw, i, "null")
+	case c == '-' || (c >= '0' && c <= '9'):
+		end := i + 1
+		for end < len(raw) && isNumberByte(raw[end]) {
+			end++
+		}
+		return end, nil
+	default:
+		return i, fmt.Errorf("invalid character %q at offset %d of the JSON document", c, i)
+	}
+}
+
+// skipString returns the offset following the string starting at offset i
+// of raw, and whether the string has escape sequences
+func skipString(raw []byte, i int) (int, bool, error) {
+	escaped := false
+	for j := i + 1; j < len(raw); j++ {
+		switch raw[j] {
+		case '\\':
+			escaped = true
+			j++
+		case '"':
+			return j + 1, escaped, nil
+		}
+	}
+	return len(raw), escaped, errUnexpectedEnd
+}
+
+// skipNested returns the offset following the object or array starting at
+// offset i of raw. Its content is only checked to be balanced.
+func skipNested(raw []byte, i int) (int, error) {
+	depth := 0
+	for j := i; j < len(raw); j++ {
+		switch raw[j] {
+		case '"':
+			end, _, err := skipString(raw, j)
+			if err != nil {
+				return end, err
+			}
+			j = end - 1
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return j + 1, nil
+			}
+		}
+	}
+	return len(raw), errUnexpectedEnd
+}
+
+func skipLiteral(raw []byte, i int, literal string) (int, error) {
+	if !bytes.HasPrefix(raw[i:], []byte(literal)) {
+		return i, fmt.Errorf("invalid literal at offset %d of the JSON document", i)
+	}
+	return i + len(literal), nil
+}
+
+func skipSpace(raw []byte, i int) int {
+	for i < len(raw) && (raw[i] == ' ' || raw[i] == '\t' || raw[i] == '\n' || raw[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+func isNumberByte(c byte) bool {
+	return (c >= '0' && c <= '9') || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E'
+}
@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+const (
+	// MaxBodyBytesEnvVar, MaxDepthEnvVar and MaxFieldsEnvVar override the
+	// DefaultDecodeLimits, 0 disabling a limit
+	MaxBodyBytesEnvVar string = "DECODE_MAX_BODY_BYTES"
+	MaxDepthEnvVar     string = "DECODE_MAX_DEPTH"
+	MaxFieldsEnvVar    string = "DECODE_MAX_FIELDS"
+)
+
+// DefaultDecodeLimits are the limits of the AdmissionReviews the webhook pods
+// decode. An AdmissionReview carries the object and old object of a request,
+// each at most the 1.5MiB etcd allows by default, and the deepest objects on
+// clusters are the schemas of CustomResourceDefinitions.
+var DefaultDecodeLimits = DecodeLimits{
+	MaxBodyBytes: 6 << 20,
+	MaxDepth:     256,
+	MaxFields:    200000,
+}
+
+// DecodeLimits bound the memory decoding an AdmissionReview takes, so that a
+// pathological object, e.g. a multi-megabyte ConfigMap sent to a webhook
+// matching every kind, can't exhaust the process the webhooks share. The
+// zero value of a limit disables it.
+type DecodeLimits struct {
+	// MaxBodyBytes is the size of the largest request body read
+	MaxBodyBytes int64
+	// MaxDepth is the deepest nesting of JSON objects and arrays
+	MaxDepth int
+	// MaxFields is the number of JSON object fields of the whole review
+	MaxFields int
+}
+
+// DecodeLimitError is a request exceeding a DecodeLimits
+type DecodeLimitError struct {
+	Limit string
+	Value int64
+	Max   int64
+}
+
+func (e *DecodeLimitError) Error() string {
+	return fmt.Sprintf("the AdmissionReview exceeds the decode limits of the webhook: %s of at least %d, the maximum is %d", e.Limit, e.Value, e.Max)
+}
+
+// DecodeLimitsFromEnv returns the DefaultDecodeLimits overridden by
+// MaxBodyBytesEnvVar, MaxDepthEnvVar and MaxFieldsEnvVar
+func DecodeLimitsFromEnv() (DecodeLimits, error) {
+	body, err := parseLimit(MaxBodyBytesEnvVar, DefaultDecodeLimits.MaxBodyBytes)
+	if err != nil {
+		return DefaultDecodeLimits, err
+	}
+	depth, err := parseLimit(MaxDepthEnvVar, int64(DefaultDecodeLimits.MaxDepth))
+	if err != nil {
+		return DefaultDecodeLimits, err
+	}
+	fields, err := parseLimit(MaxFieldsEnvVar, int64(DefaultDecodeLimits.MaxFields))
+	if err != nil {
+		return DefaultDecodeLimits, err
+	}
+	return DecodeLimits{MaxBodyBytes: body, MaxDepth: int(depth), MaxFields: int(fields)}, nil
+}
+
+// parseLimit returns the limit envVar sets, or def if it's unset
+func parseLimit(envVar string, def int64) (int64, error) {
+	value := os.Getenv(envVar)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || n < 0 {
+		return def, fmt.Errorf("%s must be a non-negative number, got %q", envVar, value)
+	}
+	return n, nil
+}
+
+// limitReader reads at most max bytes of r, failing with a DecodeLimitError
+// rather than reading on
+type limitReader struct {
+	r    io.Reader
+	read int64
+	max  int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.read >= l.max {
+		// Tell a body of exactly max bytes from a longer one
+		var b [1]byte
+		if n, _ := l.r.Read(b[:]); n > 0 {
+			return 0, &DecodeLimitError{Limit: "body bytes", Value: l.read + 1, Max: l.max}
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.max-l.read {
+		p = p[:l.max-l.read]
+	}
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	return n, err
+}
+
+// reader returns r, bounded by MaxBodyBytes and failing as soon as what it
+// read exceeds MaxDepth or MaxFields, so that no more of an oversized body is
+// read or decoded
+func (l DecodeLimits) reader(r io.Reader) io.Reader {
+	if l.MaxBodyBytes > 0 {
+		r = &limitReader{r: r, max: l.MaxBodyBytes}
+	}
+	if l.MaxDepth <= 0 && l.MaxFields <= 0 {
+		return r
+	}
+	return &scanReader{r: r, scan: &structureScanner{limits: l}}
+}
+
+// scanReader scans what it reads of r with scan
+type scanReader struct {
+	r    io.Reader
+	scan *structureScanner
+}
+
+func (s *scanReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if scanErr := s.scan.scan(p[:n]); scanErr != nil {
+		// Hold back the chunk, which the decoder would decode before
+		// seeing the error
+		return 0, scanErr
+	}
+	return n, err
+}
+
+// structureScanner counts the nesting depth and fields of a JSON document
+// read in chunks, keeping whether a chunk ended within a string
+type structureScanner struct {
+	limits   DecodeLimits
+	depth    int
+	fields   int
+	inString bool
+	escaped  bool
+}
+
+// scan counts the next chunk of the document, returning a DecodeLimitError
+// once it exceeds the limits
+func (s *structureScanner) scan(chunk []byte) error {
+	for _, c := range chunk {
+		if s.inString {
+			switch {
+			case s.escaped:
+				s.escaped = false
+			case c == '\\':
+				s.escaped = true
+			case c == '"':
+				s.inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			s.inString = true
+		case '{', '[':
+			s.depth++
+			if s.limits.MaxDepth > 0 && s.depth > s.limits.MaxDepth {
+				return &DecodeLimitError{Limit: "nesting depth", Value: int64(s.depth), Max: int64(s.limits.MaxDepth)}
+			}
+		case '}', ']':
+			s.depth--
+		case ':':
+			s.fields++
+			if s.limits.MaxFields > 0 && s.fields > s.limits.MaxFields {
+				return &DecodeLimitError{Limit: "fields", Value: int64(s.fields), Max: int64(s.limits.MaxFields)}
+			}
+		}
+	}
+	return nil
+}
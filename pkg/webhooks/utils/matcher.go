@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// StringSet is a set of names, e.g. of protected objects, checked in constant
+// time rather than by scanning a list on every request
+type StringSet map[string]struct{}
+
+// NewStringSet returns the set of names
+func NewStringSet(names ...string) StringSet {
+	set := make(StringSet, len(names))
+	for _, name := range names {
+		set[name] = struct{}{}
+	}
+	return set
+}
+
+// Has returns whether name is in the set
+func (s StringSet) Has(name string) bool {
+	_, ok := s[name]
+	return ok
+}
+
+// PatternMatcher matches names against a list of regular expressions, e.g.
+// the protected namespaces, built once from the configuration rather than
+// compiling the expressions on every request. Most expressions are exact
+// names like ^default$ or prefixes like ^kube-.*, which it matches with a set
+// and string prefixes, matching the others with a single regular expression.
+type PatternMatcher struct {
+	patterns []string
+	exact    StringSet
+	prefixes []string
+	re       *regexp.Regexp
+}
+
+// NewPatternMatcher compiles patterns. Invalid patterns are skipped, the
+// PatternMatcher matching the valid ones, and returned joined in the error,
+// so configured patterns can be reported without dropping the valid ones.
+func NewPatternMatcher(patterns []string) (*PatternMatcher, error) {
+	m := &PatternMatcher{patterns: []string{}, exact: StringSet{}}
+	others := []string{}
+	var errs []error
+	for _, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("invalid pattern %q: %w", pattern, err))
+			continue
+		}
+		m.patterns = append(m.patterns, pattern)
+		if name, ok := literalPattern(pattern, "$"); ok {
+			m.exact[name] = struct{}{}
+		} else if prefix, ok := literalPattern(pattern, ".*"); ok {
+			m.prefixes = append(m.prefixes, prefix)
+		} else {
+			others = append(others, "(?:"+pattern+")")
+		}
+	}
+	if len(others) > 0 {
+		m.re = regexp.MustCompile(strings.Join(others, "|"))
+	}
+	return m, errors.Join(errs...)
+}
+
+// literalPattern returns the literal of a pattern made of ^, the literal and
+// suffix
+func literalPattern(pattern, suffix string) (string, bool) {
+	if !strings.HasPrefix(pattern, "^") || !strings.HasSuffix(pattern, suffix) {
+		return "", false
+	}
+	literal := strings.TrimSuffix(pattern[1:], suffix)
+	if literal == "" || regexp.QuoteMeta(literal) != literal {
+		return "", false
+	}
+	return literal, true
+}
+
+// Matches returns whether name matches one of the patterns. A nil
+// PatternMatcher matches nothing.
+func (m *PatternMatcher) Matches(name string) bool {
+	if m == nil {
+		return false
+	}
+	if m.exact.Has(name) {
+		return true
+	}
+	for _, prefix := range m.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return m.re != nil && m.re.MatchString(name)
+}
+
+// Patterns returns the valid patterns the PatternMatcher was built from
+func (m *PatternMatcher) Patterns() []string {
+	if m == nil {
+		return nil
+	}
+	return m.patterns
+}
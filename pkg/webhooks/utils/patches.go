@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+
+	"gomodules.xyz/jsonpatch/v2"
+)
+
+// rfc6901Encoder escapes the reference tokens of JSON pointers, see RFC 6901
+var rfc6901Encoder = strings.NewReplacer("~", "~0", "/", "~1")
+
+// EscapeJSONPointer escapes a map key, such as a label or annotation key, for
+// use as a reference token of a JSONPatch path
+func EscapeJSONPointer(token string) string {
+	return rfc6901Encoder.Replace(token)
+}
+
+// AddLabelPatches constructs the JSONPatch operations adding each wanted label
+// that is not already set. Labels the user already set are left untouched.
+func AddLabelPatches(existing, wanted map[string]string) []jsonpatch.JsonPatchOperation {
+	if len(wanted) == 0 {
+		return nil
+	}
+	if existing == nil {
+		// No labels key at all, so add them all at once
+		return []jsonpatch.JsonPatchOperation{
+			jsonpatch.NewOperation("add", "/metadata/labels", wanted),
+		}
+	}
+
+	keys := make([]string, 0, len(wanted))
+	for k := range wanted {
+		if _, found := existing[k]; !found {
+			keys = append(keys, k)
+		}
+	}
+	// Keep the patch stable for the same input
+	sort.Strings(keys)
+
+	patches := make([]jsonpatch.JsonPatchOperation, 0, len(keys))
+	for _, k := range keys {
+		patches = append(patches, jsonpatch.NewOperation("add", "/metadata/labels/"+EscapeJSONPointer(k), wanted[k]))
+	}
+	return patches
+}
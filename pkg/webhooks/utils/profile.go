@@ -0,0 +1,72 @@
+package utils
+
+import "fmt"
+
+// Profile is a managed offering the webhooks are deployed to. It selects
+// which webhooks are served and which variant of their rules applies.
+type Profile string
+
+const (
+	// ProfileAll serves every registered webhook with its default rules
+	ProfileAll         Profile = ""
+	ProfileOSD         Profile = "osd"
+	ProfileROSAClassic Profile = "rosa-classic"
+	ProfileROSAHCP     Profile = "rosa-hcp"
+)
+
+// Profiles are the supported product profiles
+var Profiles = []Profile{ProfileOSD, ProfileROSAClassic, ProfileROSAHCP}
+
+// ParseProfile returns the Profile named name, or ProfileAll if it's empty
+func ParseProfile(name string) (Profile, error) {
+	if name == "" {
+		return ProfileAll, nil
+	}
+	for _, profile := range Profiles {
+		if string(profile) == name {
+			return profile, nil
+		}
+	}
+	return ProfileAll, fmt.Errorf("unknown product profile %q, it must be one of %v", name, Profiles)
+}
+
+// Hypershift returns whether profile runs hosted control planes
+func (p Profile) Hypershift() bool {
+	return p == ProfileROSAHCP
+}
+
+// Compliance is a compliance regime the webhooks are deployed under. It
+// enables additional webhooks and tightens the checks of others.
+type Compliance string
+
+const (
+	// ComplianceNone applies the default guardrails
+	ComplianceNone Compliance = ""
+	// ComplianceFedRAMP applies the guardrails of government regions: images
+	// only come from the allowed registries, load balancers are internal on
+	// every cluster and denials must be shipped to an audit sink
+	ComplianceFedRAMP Compliance = "fedramp"
+)
+
+// Compliances are the supported compliance profiles
+var Compliances = []Compliance{ComplianceFedRAMP}
+
+// ParseCompliance returns the Compliance named name, or ComplianceNone if it's
+// empty
+func ParseCompliance(name string) (Compliance, error) {
+	if name == "" {
+		return ComplianceNone, nil
+	}
+	for _, compliance := range Compliances {
+		if string(compliance) == name {
+			return compliance, nil
+		}
+	}
+	return ComplianceNone, fmt.Errorf("unknown compliance profile %q, it must be one of %v", name, Compliances)
+}
+
+// RequiresAuditSink returns whether denials must be shipped to an audit sink
+// under c
+func (c Compliance) RequiresAuditSink() bool {
+	return c == ComplianceFedRAMP
+}
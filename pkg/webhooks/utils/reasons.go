@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ReasonCode is a stable, machine-readable identifier for why a request was
+// denied. Codes are never reused or renamed once released, so tooling, service
+// logs and docs can key off them instead of the human-readable message.
+type ReasonCode string
+
+// Audit annotation keys attached to admission responses. The API server
+// prefixes them with the webhook name.
+const (
+	// ReasonCodeAuditAnnotation carries the ReasonCode of a denial
+	ReasonCodeAuditAnnotation string = "reason-code"
+	// WebhookAuditAnnotation carries the name of the webhook which answered
+	WebhookAuditAnnotation string = "webhook"
+	// DecisionAuditAnnotation carries one of the Decision* values
+	DecisionAuditAnnotation string = "decision"
+	// CorrelationIDAuditAnnotation carries the support correlation ID of a
+	// denial
+	CorrelationIDAuditAnnotation string = "correlation-id"
+	// ExemptionAuditAnnotation carries the name of the WebhookExemption a
+	// request matched
+	ExemptionAuditAnnotation string = "exemption"
+	// ServiceAccountExemptionAuditAnnotation carries the service account
+	// exempted from a webhook by configuration
+	ServiceAccountExemptionAuditAnnotation string = "service-account-exemption"
+	// LabelExemptionAuditAnnotation carries the namespace or service account
+	// whose exemption label exempted a request
+	LabelExemptionAuditAnnotation string = "label-exemption"
+	// DeclarativeManagerAuditAnnotation carries the declarative manager whose
+	// unchanged re-apply a webhook would have denied
+	DeclarativeManagerAuditAnnotation string = "declarative-manager"
+	// BreakGlassAuditAnnotation carries the expiry of the break-glass which
+	// allowed a request that would have been denied
+	BreakGlassAuditAnnotation string = "break-glass"
+	// AuditModeAuditAnnotation carries what set the webhook of a request that
+	// would have been denied to audit mode: the ValidatingWebhookPolicy, its
+	// maintenance window or the webhook's enforcement mode
+	AuditModeAuditAnnotation string = "audit-mode"
+	// OverrideAuditAnnotation carries the nonce of the override token which
+	// allowed a request that would have been denied
+	OverrideAuditAnnotation string = "override"
+	// LoadShedAuditAnnotation marks a request allowed without being evaluated
+	// while the webhooks were overloaded
+	LoadShedAuditAnnotation string = "load-shed"
+)
+
+// Values of DecisionAuditAnnotation
+const (
+	DecisionAllowed             string = "allowed"
+	DecisionAllowedWithWarnings string = "allowed-with-warnings"
+	DecisionDenied              string = "denied"
+	DecisionErrored             string = "errored"
+)
+
+const (
+	ReasonCLORetentionPolicy ReasonCode = "CLO001_RETENTION_POLICY"
+
+	ReasonCRBUnauthenticated  ReasonCode = "CRB001_UNAUTHENTICATED"
+	ReasonCRBProtectedDelete  ReasonCode = "CRB002_PROTECTED_DELETE"
+	ReasonCRDManagedResource  ReasonCode = "CRD001_MANAGED_RESOURCE"
+	ReasonHiveManagedResource ReasonCode = "HIVE001_MANAGED_RESOURCE"
+
+	ReasonILBPublicLoadBalancer ReasonCode = "ILB001_PUBLIC_LOAD_BALANCER"
+
+	ReasonImageRegistryNotAllowed ReasonCode = "IMGREG001_REGISTRY_NOT_ALLOWED"
+
+	ReasonIngressConfigUnprivileged ReasonCode = "INGCFG001_UNPRIVILEGED_ACCESS"
+
+	ReasonIngressControllerUnauthenticated  ReasonCode = "INGCTL001_UNAUTHENTICATED"
+	ReasonIngressControllerMasterToleration ReasonCode = "INGCTL002_MASTER_TOLERATION"
+
+	ReasonNetworkPolicyManagedNamespace ReasonCode = "NETPOL001_MANAGED_NAMESPACE"
+	ReasonNetworkPolicyDefaultIngress   ReasonCode = "NETPOL002_DEFAULT_INGRESS"
+
+	ReasonNodeUnauthenticated    ReasonCode = "NODE001_UNAUTHENTICATED"
+	ReasonNodeDelete             ReasonCode = "NODE002_DELETE"
+	ReasonNodeInfraModify        ReasonCode = "NODE003_INFRA_MODIFY"
+	ReasonNodeControlPlaneModify ReasonCode = "NODE004_CONTROL_PLANE_MODIFY"
+	ReasonNodeMasterModify       ReasonCode = "NODE005_MASTER_MODIFY"
+	ReasonNodeManagedResource    ReasonCode = "NODE006_MANAGED_RESOURCE"
+
+	ReasonNamespaceManaged        ReasonCode = "NS001_MANAGED_NAMESPACE"
+	ReasonNamespaceHarmfulName    ReasonCode = "NS002_HARMFUL_NAME"
+	ReasonNamespaceProtectedLabel ReasonCode = "NS003_PROTECTED_LABEL"
+
+	ReasonOAuthClientPlatformDelete       ReasonCode = "OAUTH001_PLATFORM_CLIENT_DELETE"
+	ReasonOAuthClientPlatformSecretModify ReasonCode = "OAUTH002_PLATFORM_CLIENT_SECRET_MODIFY"
+
+	ReasonOwnedLabelModify ReasonCode = "OWN001_OWNED_LABEL_MODIFY"
+
+	ReasonPodInfraNoScheduleToleration        ReasonCode = "POD001_INFRA_NOSCHEDULE_TOLERATION"
+	ReasonPodInfraPreferNoScheduleToleration  ReasonCode = "POD002_INFRA_PREFERNOSCHEDULE_TOLERATION"
+	ReasonPodMasterNoScheduleToleration       ReasonCode = "POD003_MASTER_NOSCHEDULE_TOLERATION"
+	ReasonPodMasterPreferNoScheduleToleration ReasonCode = "POD004_MASTER_PREFERNOSCHEDULE_TOLERATION"
+
+	ReasonPrometheusRuleManagedNamespace ReasonCode = "PROMRULE001_MANAGED_NAMESPACE"
+
+	ReasonServiceAccountUnauthenticated   ReasonCode = "SA001_UNAUTHENTICATED"
+	ReasonServiceAccountProtectedDelete   ReasonCode = "SA002_PROTECTED_DELETE"
+	ReasonSCCDefaultModify                ReasonCode = "SCC001_DEFAULT_SCC_MODIFY"
+	ReasonSCCDefaultDelete                ReasonCode = "SCC002_DEFAULT_SCC_DELETE"
+	ReasonServiceLoadBalancerQuota        ReasonCode = "SVC001_LOAD_BALANCER_QUOTA"
+	ReasonTechPreviewNoUpgradeFeatureGate ReasonCode = "TPNU001_FEATURE_GATE"
+
+	ReasonUserUnauthenticated ReasonCode = "USER001_UNAUTHENTICATED"
+	ReasonUserManagedResource ReasonCode = "USER002_MANAGED_RESOURCE"
+)
+
+// Denied returns a response denying the request with the given ReasonCode.
+// The code is set as the status reason and as an audit annotation, and the
+// message as the status message shown to the user.
+func Denied(code ReasonCode, message string) admissionctl.Response {
+	ret := admissionctl.Denied(string(code))
+	ret.Result.Message = message
+	ret.AuditAnnotations = map[string]string{
+		ReasonCodeAuditAnnotation: string(code),
+	}
+	return ret
+}
+
+// DenialReason returns the ReasonCode and message of a denial response. The
+// code is empty for denials which were not created with Denied.
+func DenialReason(resp admissionctl.Response) (ReasonCode, string) {
+	if resp.Result == nil {
+		return "", ""
+	}
+	code, coded := resp.AuditAnnotations[ReasonCodeAuditAnnotation]
+	if !coded {
+		// admissionctl.Denied carries the message in the reason
+		message := resp.Result.Message
+		if message == "" {
+			message = string(resp.Result.Reason)
+		}
+		return "", message
+	}
+	return ReasonCode(code), resp.Result.Message
+}
+
+// WithCorrelationID returns the denial resp with id appended to its message
+// and set as an audit annotation, so a customer-pasted error leads support to
+// the logs and records of the request
+func WithCorrelationID(resp admissionctl.Response, id string) admissionctl.Response {
+	if resp.Result == nil {
+		return resp
+	}
+	_, message := DenialReason(resp)
+	result := *resp.Result
+	result.Message = fmt.Sprintf("%s (support correlation ID: %s)", message, id)
+	resp.Result = &result
+	annotations := map[string]string{
+		CorrelationIDAuditAnnotation: id,
+	}
+	for k, v := range resp.AuditAnnotations {
+		annotations[k] = v
+	}
+	resp.AuditAnnotations = annotations
+	return resp
+}
+
+// CorrelationID returns the correlation ID set by WithCorrelationID, or an
+// empty string
+func CorrelationID(resp admissionctl.Response) string {
+	return resp.AuditAnnotations[CorrelationIDAuditAnnotation]
+}
+
+// WithAuditSource returns the denial resp marked as audited by source, the
+// policy, maintenance window or enforcement mode setting its webhook to audit
+// mode. Recorders use it to tell the requests which would have been denied
+// from the denials.
+func WithAuditSource(resp admissionctl.Response, source string) admissionctl.Response {
+	annotations := map[string]string{
+		AuditModeAuditAnnotation: source,
+	}
+	for k, v := range resp.AuditAnnotations {
+		annotations[k] = v
+	}
+	resp.AuditAnnotations = annotations
+	return resp
+}
+
+// AuditSource returns the source set by WithAuditSource, or an empty string
+// for a denial
+func AuditSource(resp admissionctl.Response) string {
+	return resp.AuditAnnotations[AuditModeAuditAnnotation]
+}
+
+// AllowDenied returns a response allowing the request resp denied, e.g. as it
+// is exempted, with message. It keeps the warnings of resp, and carries the
+// reason code of the denial and value under annotationKey as audit
+// annotations, so the audit log tells why the denial was lifted.
+func AllowDenied(resp admissionctl.Response, message, annotationKey, value string) admissionctl.Response {
+	allowed := admissionctl.Allowed(message)
+	allowed.Warnings = slices.Clone(resp.Warnings)
+	allowed.AuditAnnotations = map[string]string{
+		annotationKey: value,
+	}
+	if code, _ := DenialReason(resp); code != "" {
+		allowed.AuditAnnotations[ReasonCodeAuditAnnotation] = string(code)
+	}
+	return allowed
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"encoding/json"
+	"regexp"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// RedactedValue replaces credential material in logged and audited objects
+const RedactedValue string = "REDACTED"
+
+// lastAppliedAnnotation holds a full copy of the applied object, including
+// any data which is redacted from the object itself
+const lastAppliedAnnotation string = "kubectl.kubernetes.io/last-applied-configuration"
+
+// credentialKey matches ConfigMap keys which commonly hold credentials
+var credentialKey = regexp.MustCompile(`(?i)(passw(or)?d|secret|token|credential|auth|api[-_.]?key|private[-_.]?key|\.dockerconfigjson|\.dockercfg|\.pem$|\.key$)`)
+
+// RedactRequest returns a copy of request which is safe to log or audit. The
+// Object and OldObject of Secrets, including pull secrets, have their data
+// and stringData values redacted. ConfigMaps have the values of keys which look
+// like credentials redacted, and OAuthClients their secrets.
+func RedactRequest(request admissionv1.AdmissionRequest) admissionv1.AdmissionRequest {
+	request.Object = redactRawExtension(request.Object)
+	request.OldObject = redactRawExtension(request.OldObject)
+	return request
+}
+
+func redactRawExtension(ext runtime.RawExtension) runtime.RawExtension {
+	if len(ext.Raw) == 0 {
+		return ext
+	}
+	return runtime.RawExtension{Raw: RedactObject(ext.Raw)}
+}
+
+// RedactObject returns the JSON encoded object raw with any credential
+// material redacted. Objects which can't be decoded, and so can't be checked,
+// are dropped entirely.
+func RedactObject(raw []byte) []byte {
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil
+	}
+	kind, _ := obj["kind"].(string)
+	apiVersion, _ := obj["apiVersion"].(string)
+
+	redacted := true
+	switch {
+	case kind == "Secret" && apiVersion == "v1":
+		redactValues(obj, "data", nil)
+		redactValues(obj, "stringData", nil)
+	case kind == "ConfigMap" && apiVersion == "v1":
+		redactValues(obj, "data", credentialKey)
+		redactValues(obj, "binaryData", credentialKey)
+	case kind == "OAuthClient" && apiVersion == "oauth.openshift.io/v1":
+		if _, ok := obj["secret"]; ok {
+			obj["secret"] = RedactedValue
+		}
+		if secrets, ok := obj["additionalSecrets"].([]interface{}); ok {
+			for i := range secrets {
+				secrets[i] = RedactedValue
+			}
+		}
+	default:
+		redacted = false
+	}
+	if !redacted {
+		return raw
+	}
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			if _, ok := annotations[lastAppliedAnnotation]; ok {
+				annotations[lastAppliedAnnotation] = RedactedValue
+			}
+		}
+	}
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+// redactValues redacts the values of the map at obj[field], or only those
+// whose key matches keys when it is set
+func redactValues(obj map[string]interface{}, field string, keys *regexp.Regexp) {
+	values, ok := obj[field].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for k := range values {
+		if keys == nil || keys.MatchString(k) {
+			values[k] = RedactedValue
+		}
+	}
+}
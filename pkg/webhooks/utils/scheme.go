@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"sync"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var (
+	// EmptyScheme is the scheme of the webhooks decoding types they don't
+	// register, which the decoder then decodes as plain JSON
+	EmptyScheme = NewLazyScheme()
+	// CoreScheme is the scheme of the webhooks decoding core types, shared
+	// by most webhooks
+	CoreScheme = NewLazyScheme(admissionv1.AddToScheme, corev1.AddToScheme)
+)
+
+// LazyScheme is a scheme and its decoder, built on their first use. The
+// webhooks are constructed at startup and for every request, so they hold a
+// package LazyScheme rather than building their schemes, and webhooks
+// decoding the same types share one.
+type LazyScheme struct {
+	addToScheme []func(*runtime.Scheme) error
+	once        sync.Once
+	scheme      *runtime.Scheme
+	decoder     *admissionctl.Decoder
+	err         error
+}
+
+// NewLazyScheme returns a LazyScheme of the types the addToScheme functions
+// register
+func NewLazyScheme(addToScheme ...func(*runtime.Scheme) error) *LazyScheme {
+	return &LazyScheme{addToScheme: addToScheme}
+}
+
+func (l *LazyScheme) build() {
+	scheme := runtime.NewScheme()
+	for _, add := range l.addToScheme {
+		if err := add(scheme); err != nil {
+			l.err = err
+			return
+		}
+	}
+	l.scheme = scheme
+	l.decoder, l.err = admissionctl.NewDecoder(scheme)
+}
+
+// Scheme returns the scheme, building it on the first call. It returns the
+// same error on every call if a type can't be registered.
+func (l *LazyScheme) Scheme() (*runtime.Scheme, error) {
+	l.once.Do(l.build)
+	return l.scheme, l.err
+}
+
+// Decoder returns the admission decoder of the scheme, building it on the
+// first call
+func (l *LazyScheme) Decoder() (*admissionctl.Decoder, error) {
+	l.once.Do(l.build)
+	return l.decoder, l.err
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// serverSetMetadata are the metadata fields the API server sets, which differ
+// between an object and its unchanged re-apply
+var serverSetMetadata = []string{"resourceVersion", "generation", "managedFields", "creationTimestamp", "uid"}
+
+// IsUnchangedUpdate returns whether request updates an object without
+// changing it, e.g. a GitOps controller re-applying the same manifest. The
+// metadata set by the API server, the status, and the last applied
+// configuration annotation kubectl and Argo CD set are ignored.
+func IsUnchangedUpdate(request admissionv1.AdmissionRequest) bool {
+	if request.Operation != admissionv1.Update || len(request.Object.Raw) == 0 || len(request.OldObject.Raw) == 0 {
+		return false
+	}
+	object, err := comparableObject(request.Object.Raw)
+	if err != nil {
+		return false
+	}
+	old, err := comparableObject(request.OldObject.Raw)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(object, old)
+}
+
+// comparableObject decodes raw without the fields IsUnchangedUpdate ignores
+func comparableObject(raw []byte) (map[string]interface{}, error) {
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	delete(obj, "status")
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		for _, field := range serverSetMetadata {
+			delete(metadata, field)
+		}
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			delete(annotations, lastAppliedAnnotation)
+			if len(annotations) == 0 {
+				delete(metadata, "annotations")
+			}
+		}
+	}
+	return obj, nil
+}
@@ -10,24 +10,12 @@ import (
 
 	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/serializer"
-	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	kjson "sigs.k8s.io/json"
 )
 
 const (
 	validContentType string = "application/json"
-	// PrivilegedServiceAccountGroups is a regex string of serviceaccounts that our webhooks should commonly allow to
-	// perform restricted actions.
-	// Centralized osde2e tests have a serviceaccount like "system:serviceaccounts:osde2e-abcde"
-	// Decentralized osde2e tests have a serviceaccount like "system:serviceaccounts:osde2e-h-abcde"
-	PrivilegedServiceAccountGroups string = `^system:serviceaccounts:(kube-.*|openshift|openshift-.*|default|redhat-.*|osde2e-(h-)?[a-z0-9]{5})`
-)
-
-var (
-	admissionScheme = runtime.NewScheme()
-	admissionCodecs = serializer.NewCodecFactory(admissionScheme)
 )
 
 func RequestMatchesGroupKind(req admissionctl.Request, kind, group string) bool {
@@ -58,6 +46,9 @@ func IsProtectedByResourceName(name string) bool {
 	return slices.Contains(protectedNames, name)
 }
 
+// RegexSliceContains returns whether needle matches one of the regular
+// expressions of haystack, compiling them on every call. Checks made for
+// every request use a PatternMatcher built once instead.
 func RegexSliceContains(needle string, haystack []string) bool {
 	for _, check := range haystack {
 		checkRe := regexp.MustCompile(check)
@@ -68,26 +59,27 @@ func RegexSliceContains(needle string, haystack []string) bool {
 	return false
 }
 
+// ParseHTTPRequest decodes the AdmissionReview of r within the
+// DefaultDecodeLimits
 func ParseHTTPRequest(r *http.Request) (admissionctl.Request, admissionctl.Response, error) {
+	return ParseHTTPRequestWithLimits(r, DefaultDecodeLimits)
+}
+
+// ParseHTTPRequestWithLimits decodes the AdmissionReview of r, failing with a
+// DecodeLimitError and a 413 response if it exceeds limits. The review is
+// decoded from the body as it is read rather than from a copy of it, and
+// reading stops at MaxBodyBytes or as soon as the other limits are exceeded.
+// The depth and fields are only counted as the body is read, so it is
+// scanned once.
+func ParseHTTPRequestWithLimits(r *http.Request, limits DecodeLimits) (admissionctl.Request, admissionctl.Response, error) {
 	var resp admissionctl.Response
 	var req admissionctl.Request
 	var err error
-	var body []byte
-	if r.Body != nil {
-		if body, err = io.ReadAll(r.Body); err != nil {
-			resp = admissionctl.Errored(http.StatusBadRequest, err)
-			return req, resp, err
-		}
-	} else {
+	if r.Body == nil {
 		err := errors.New("request body is nil")
 		resp = admissionctl.Errored(http.StatusBadRequest, err)
 		return req, resp, err
 	}
-	if len(body) == 0 {
-		err := errors.New("request body is empty")
-		resp = admissionctl.Errored(http.StatusBadRequest, err)
-		return req, resp, err
-	}
 	contentType := r.Header.Get("Content-Type")
 	if contentType != validContentType {
 		err := fmt.Errorf("contentType=%s, expected application/json", contentType)
@@ -95,8 +87,8 @@ func ParseHTTPRequest(r *http.Request) (admissionctl.Request, admissionctl.Respo
 		return req, resp, err
 	}
 	ar := admissionv1.AdmissionReview{}
-	if _, _, err := admissionCodecs.UniversalDeserializer().Decode(body, nil, &ar); err != nil {
-		resp = admissionctl.Errored(http.StatusBadRequest, err)
+	if err := decodeAdmissionReview(limits.reader(r.Body), &ar); err != nil {
+		resp = admissionctl.Errored(decodeErrorCode(err), err)
 		return req, resp, err
 	}
 
@@ -113,14 +105,60 @@ func ParseHTTPRequest(r *http.Request) (admissionctl.Request, admissionctl.Respo
 	return req, resp, nil
 }
 
+// decodeAdmissionReview decodes the single AdmissionReview body streams into ar
+func decodeAdmissionReview(body io.Reader, ar *admissionv1.AdmissionReview) error {
+	dec := kjson.NewDecoderCaseSensitivePreserveInts(body)
+	if err := dec.Decode(ar); err != nil {
+		if err == io.EOF {
+			return errors.New("request body is empty")
+		}
+		return err
+	}
+	// Read on to the end of the body, which holds nothing but the review
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("unexpected data after the AdmissionReview")
+		}
+		return err
+	}
+	return nil
+}
+
+// decodeErrorCode returns the HTTP status code of failing to read a body
+func decodeErrorCode(err error) int32 {
+	var limitErr *DecodeLimitError
+	if errors.As(err, &limitErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
 // WebhookResponse assembles an allowed or denied admission response with the same UID as the provided request.
 // The reason for allowed admission responses is not shown to the end user and is commonly empty string: ""
 func WebhookResponse(request admissionctl.Request, allowed bool, reason string) admissionctl.Response {
-	resp := admissionctl.ValidationResponse(allowed, reason)
+	return WithUID(request, admissionctl.ValidationResponse(allowed, reason))
+}
+
+// WithUID returns resp answering request, i.e. with its UID, which the API
+// server requires of every response
+func WithUID(request admissionctl.Request, resp admissionctl.Response) admissionctl.Response {
 	resp.UID = request.UID
 	return resp
 }
 
-func init() {
-	utilruntime.Must(admissionv1.AddToScheme(admissionScheme))
+// Allow returns the response allowing request. The reason isn't shown to the
+// user.
+func Allow(request admissionctl.Request, reason string) admissionctl.Response {
+	return WithUID(request, admissionctl.Allowed(reason))
+}
+
+// AllowWithWarning returns the response allowing request with warnings, which
+// kubectl and oc show to the user
+func AllowWithWarning(request admissionctl.Request, reason string, warnings ...string) admissionctl.Response {
+	return WithUID(request, admissionctl.Allowed(reason).WithWarnings(warnings...))
+}
+
+// Deny returns the response denying request with code, as Denied does
+func Deny(request admissionctl.Request, code ReasonCode, message string) admissionctl.Response {
+	return WithUID(request, Denied(code, message))
 }
@@ -1,10 +1,19 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	admissionctl "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
@@ -55,3 +64,528 @@ func TestRequestMatchesGroupKind(t *testing.T) {
 		})
 	}
 }
+
+func TestDenied(t *testing.T) {
+	resp := Denied(ReasonSCCDefaultModify, "Modifying default SCCs is not allowed")
+	if resp.Allowed || resp.Result.Code != 403 {
+		t.Fatalf("Expected a 403 denial, got %+v", resp)
+	}
+	if resp.Result.Reason != metav1.StatusReason(ReasonSCCDefaultModify) || resp.AuditAnnotations[ReasonCodeAuditAnnotation] != string(ReasonSCCDefaultModify) {
+		t.Fatalf("Expected reason code %s in the status and audit annotations, got %s and %v", ReasonSCCDefaultModify, resp.Result.Reason, resp.AuditAnnotations)
+	}
+
+	code, message := DenialReason(resp)
+	if code != ReasonSCCDefaultModify || message != "Modifying default SCCs is not allowed" {
+		t.Fatalf("Expected %s and the denial message, got %s and %q", ReasonSCCDefaultModify, code, message)
+	}
+
+	code, message = DenialReason(admissionctl.Denied("Not allowed"))
+	if code != "" || message != "Not allowed" {
+		t.Fatalf("Expected no code and the reason as message for an uncoded denial, got %s and %q", code, message)
+	}
+}
+
+func TestWithCorrelationID(t *testing.T) {
+	resp := WithCorrelationID(Denied(ReasonSCCDefaultModify, "Modifying default SCCs is not allowed"), "1a2b3c4d")
+	code, message := DenialReason(resp)
+	if code != ReasonSCCDefaultModify || message != "Modifying default SCCs is not allowed (support correlation ID: 1a2b3c4d)" {
+		t.Fatalf("Expected the correlation ID in the message, got %s and %q", code, message)
+	}
+	if CorrelationID(resp) != "1a2b3c4d" {
+		t.Fatalf("Expected the correlation ID audit annotation, got %v", resp.AuditAnnotations)
+	}
+
+	_, message = DenialReason(WithCorrelationID(admissionctl.Denied("Not allowed"), "1a2b3c4d"))
+	if message != "Not allowed (support correlation ID: 1a2b3c4d)" {
+		t.Fatalf("Expected the correlation ID in an uncoded denial's message, got %q", message)
+	}
+}
+
+func TestWithAuditSource(t *testing.T) {
+	denied := Denied(ReasonSCCDefaultModify, "Modifying default SCCs is not allowed")
+	if AuditSource(denied) != "" {
+		t.Fatalf("Expected no audit source for a denial, got %v", denied.AuditAnnotations)
+	}
+	resp := WithAuditSource(denied, "maintenance/upgrades")
+	if AuditSource(resp) != "maintenance/upgrades" {
+		t.Fatalf("Expected the audit source audit annotation, got %v", resp.AuditAnnotations)
+	}
+	if code, _ := DenialReason(resp); code != ReasonSCCDefaultModify {
+		t.Fatalf("Expected the reason code to be kept, got %q", code)
+	}
+	if AuditSource(denied) != "" {
+		t.Fatalf("Expected the original denial not to be modified, got %v", denied.AuditAnnotations)
+	}
+}
+
+func TestAllowDenied(t *testing.T) {
+	denied := Denied(ReasonSCCDefaultModify, "Modifying default SCCs is not allowed")
+	denied.Warnings = []string{"deprecated field"}
+	resp := AllowDenied(denied, "Exempted", ExemptionAuditAnnotation, "upgrade")
+	if !resp.Allowed || resp.Result.Reason != "Exempted" {
+		t.Fatalf("Expected the request to be allowed, got %+v", resp)
+	}
+	if resp.AuditAnnotations[ExemptionAuditAnnotation] != "upgrade" || resp.AuditAnnotations[ReasonCodeAuditAnnotation] != string(ReasonSCCDefaultModify) {
+		t.Fatalf("Expected the exemption and reason code audit annotations, got %v", resp.AuditAnnotations)
+	}
+	if !reflect.DeepEqual(resp.Warnings, denied.Warnings) {
+		t.Fatalf("Expected the warnings to be kept, got %v", resp.Warnings)
+	}
+	resp.Warnings[0] = "changed"
+	if denied.Warnings[0] != "deprecated field" {
+		t.Fatal("Expected the warnings of the denial not to be shared")
+	}
+
+	uncoded := AllowDenied(admissionctl.Denied("Not allowed"), "Exempted", ExemptionAuditAnnotation, "upgrade")
+	if _, coded := uncoded.AuditAnnotations[ReasonCodeAuditAnnotation]; coded {
+		t.Fatalf("Expected no reason code for an uncoded denial, got %v", uncoded.AuditAnnotations)
+	}
+}
+
+func TestRedactObject(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		leaked   []string
+		retained []string
+	}{
+		{
+			name: "secret",
+			raw: `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"creds","annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"data\":{\"password\":\"aHVudGVyMg==\"}}"}},` +
+				`"type":"Opaque","data":{"password":"aHVudGVyMg=="},"stringData":{"token":"sha256~abc"}}`,
+			leaked:   []string{"aHVudGVyMg==", "sha256~abc"},
+			retained: []string{`"name":"creds"`, `"password":"REDACTED"`, `"type":"Opaque"`},
+		},
+		{
+			name:     "pull-secret",
+			raw:      `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"pull-secret","namespace":"openshift-config"},"type":"kubernetes.io/dockerconfigjson","data":{".dockerconfigjson":"eyJhdXRocyI6e319"}}`,
+			leaked:   []string{"eyJhdXRocyI6e319"},
+			retained: []string{`"namespace":"openshift-config"`},
+		},
+		{
+			name:     "configmap",
+			raw:      `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"app"},"data":{"log-level":"debug","db_password":"hunter2","api-key":"k-123"}}`,
+			leaked:   []string{"hunter2", "k-123"},
+			retained: []string{`"log-level":"debug"`},
+		},
+		{
+			name:     "oauthclient",
+			raw:      `{"apiVersion":"oauth.openshift.io/v1","kind":"OAuthClient","metadata":{"name":"console"},"secret":"s3cr3t","additionalSecrets":["0ld"]}`,
+			leaked:   []string{"s3cr3t", "0ld"},
+			retained: []string{`"name":"console"`},
+		},
+		{
+			name:     "other-kinds-untouched",
+			raw:      `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"web"},"spec":{"containers":[{"name":"web","env":[{"name":"MODE","value":"prod"}]}]}}`,
+			retained: []string{`"value":"prod"`},
+		},
+		{
+			name:   "undecodable-dropped",
+			raw:    `{"kind":"Secret","data":{"password":"aHVudGVyMg=="`,
+			leaked: []string{"aHVudGVyMg=="},
+		},
+	}
+	for _, test := range tests {
+		request := RedactRequest(admissionv1.AdmissionRequest{
+			Object:    runtime.RawExtension{Raw: []byte(test.raw)},
+			OldObject: runtime.RawExtension{Raw: []byte(test.raw)},
+		})
+		for _, redacted := range [][]byte{request.Object.Raw, request.OldObject.Raw} {
+			for _, leaked := range test.leaked {
+				if strings.Contains(string(redacted), leaked) {
+					t.Fatalf("%s: Expected %q to be redacted, got %s", test.name, leaked, redacted)
+				}
+			}
+			for _, retained := range test.retained {
+				if !strings.Contains(string(redacted), retained) {
+					t.Fatalf("%s: Expected %q to be retained, got %s", test.name, retained, redacted)
+				}
+			}
+		}
+	}
+}
+
+func TestParseProfile(t *testing.T) {
+	for _, name := range []string{"", "osd", "rosa-classic", "rosa-hcp"} {
+		profile, err := ParseProfile(name)
+		if err != nil || string(profile) != name {
+			t.Errorf("Expected %q to parse, got %q, %v", name, profile, err)
+		}
+	}
+	if _, err := ParseProfile("rosa"); err == nil {
+		t.Error("Expected an unknown profile to fail to parse")
+	}
+	if !ProfileROSAHCP.Hypershift() || ProfileROSAClassic.Hypershift() {
+		t.Error("Expected only rosa-hcp to run hosted control planes")
+	}
+}
+
+func TestParseCompliance(t *testing.T) {
+	for _, name := range []string{"", "fedramp"} {
+		compliance, err := ParseCompliance(name)
+		if err != nil || string(compliance) != name {
+			t.Errorf("Expected %q to parse, got %q, %v", name, compliance, err)
+		}
+	}
+	if _, err := ParseCompliance("hipaa"); err == nil {
+		t.Error("Expected an unknown compliance profile to fail to parse")
+	}
+	if !ComplianceFedRAMP.RequiresAuditSink() || ComplianceNone.RequiresAuditSink() {
+		t.Error("Expected only fedramp to require an audit sink")
+	}
+}
+
+func TestIsUnchangedUpdate(t *testing.T) {
+	old := `{"kind": "SecurityContextConstraints", "metadata": {"name": "anyuid", "resourceVersion": "1", "generation": 1, "annotations": {"include.release.openshift.io/self-managed-high-availability": "true"}}, "priority": 10}`
+	tests := []struct {
+		name      string
+		operation admissionv1.Operation
+		object    string
+		expected  bool
+	}{
+		{
+			name:      "unchanged",
+			operation: admissionv1.Update,
+			object:    `{"kind": "SecurityContextConstraints", "metadata": {"name": "anyuid", "resourceVersion": "2", "generation": 2, "annotations": {"include.release.openshift.io/self-managed-high-availability": "true", "kubectl.kubernetes.io/last-applied-configuration": "{}"}}, "priority": 10}`,
+			expected:  true,
+		},
+		{
+			name:      "changed field",
+			operation: admissionv1.Update,
+			object:    `{"kind": "SecurityContextConstraints", "metadata": {"name": "anyuid", "annotations": {"include.release.openshift.io/self-managed-high-availability": "true"}}, "priority": 11}`,
+		},
+		{
+			name:      "changed annotation",
+			operation: admissionv1.Update,
+			object:    `{"kind": "SecurityContextConstraints", "metadata": {"name": "anyuid"}, "priority": 10}`,
+		},
+		{
+			name:      "not an update",
+			operation: admissionv1.Delete,
+			object:    old,
+		},
+		{
+			name:      "undecodable",
+			operation: admissionv1.Update,
+			object:    `not json`,
+		},
+	}
+	for _, test := range tests {
+		request := admissionv1.AdmissionRequest{
+			Operation: test.operation,
+			Object:    runtime.RawExtension{Raw: []byte(test.object)},
+			OldObject: runtime.RawExtension{Raw: []byte(old)},
+		}
+		if got := IsUnchangedUpdate(request); got != test.expected {
+			t.Errorf("%s: Expected %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestStringField(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		path     []string
+		expected string
+		err      bool
+	}{
+		{
+			name:     "name",
+			raw:      `{"kind": "SecurityContextConstraints", "metadata": {"annotations": {"a": "{\"name\": \"x\"}"}, "labels": {}, "name": "anyuid"}, "priority": 10}`,
+			path:     []string{"metadata", "name"},
+			expected: "anyuid",
+		},
+		{
+			name:     "after nested values",
+			raw:      `{"spec": {"versions": [{"schema": {"a": [1, -2.5e3, true, false, null, "]}"]}}]}, "metadata" : { "name" : "b" } }`,
+			path:     []string{"metadata", "name"},
+			expected: "b",
+		},
+		{
+			name:     "escaped",
+			raw:      `{"metadata": {"name": "a\"b"}}`,
+			path:     []string{"metadata", "name"},
+			expected: `a"b`,
+		},
+		{
+			name: "case sensitive",
+			raw:  `{"metadata": {"Name": "a"}}`,
+			path: []string{"metadata", "name"},
+		},
+		{
+			name: "missing",
+			raw:  `{"kind": "Pod", "metadata": {}}`,
+			path: []string{"metadata", "name"},
+		},
+		{
+			name: "null parent",
+			raw:  `{"metadata": null}`,
+			path: []string{"metadata", "name"},
+		},
+		{
+			name: "not a string",
+			raw:  `{"metadata": {"name": 1}}`,
+			path: []string{"metadata", "name"},
+			err:  true,
+		},
+		{
+			name: "not an object",
+			raw:  `{"metadata": []}`,
+			path: []string{"metadata", "name"},
+			err:  true,
+		},
+		{
+			name: "truncated",
+			raw:  `{"kind": "Pod", "metad`,
+			path: []string{"metadata", "name"},
+			err:  true,
+		},
+		{
+			name: "not json",
+			raw:  `not json`,
+			path: []string{"metadata", "name"},
+			err:  true,
+		},
+	}
+	for _, test := range tests {
+		got, err := StringField([]byte(test.raw), test.path...)
+		if (err != nil) != test.err {
+			t.Errorf("%s: Expected an error %v, got %v", test.name, test.err, err)
+		}
+		if got != test.expected {
+			t.Errorf("%s: Expected %q, got %q", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestFieldDoesNotAllocate(t *testing.T) {
+	raw := []byte(`{"kind": "CustomResourceDefinition", "spec": {"versions": [{"name": "v1", "schema": {}}]}, "metadata": {"name": "a"}}`)
+	allocs := testing.AllocsPerRun(100, func() {
+		if value, err := Field(raw, "metadata", "name"); err != nil || string(value) != `"a"` {
+			t.Fatalf("Expected the name, got %s, %v", value, err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations, got %v", allocs)
+	}
+}
+
+func TestParseHTTPRequestReusesBodies(t *testing.T) {
+	review := func(name string) *http.Request {
+		body := fmt.Sprintf(`{"kind": "AdmissionReview", "apiVersion": "admission.k8s.io/v1", "request": {"uid": "%[1]s", "operation": "CREATE", "object": {"metadata": {"name": "%[1]s"}}}}`, name)
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		return r
+	}
+	first, _, err := ParseHTTPRequest(review("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Decoding the second body doesn't change the objects of the first
+	if _, _, err := ParseHTTPRequest(review("second")); err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := StringField(first.Object.Raw, "metadata", "name"); name != "first" || first.UID != "first" {
+		t.Fatalf("Expected the first request to keep its object, got %s", first.Object.Raw)
+	}
+}
+
+func TestParseHTTPRequestWithLimits(t *testing.T) {
+	review := func(object string) *http.Request {
+		body := fmt.Sprintf(`{"kind": "AdmissionReview", "apiVersion": "admission.k8s.io/v1", "request": {"uid": "a", "operation": "CREATE", "object": %s}}`, object)
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		return r
+	}
+	configMap := fmt.Sprintf(`{"metadata": {"name": "a"}, "data": {"a": %q}}`, strings.Repeat("a", 4096))
+	nested := strings.Repeat(`{"a": `, 20) + "{}" + strings.Repeat("}", 20)
+	fields := `{"metadata": {"name": "a", "labels": {"a": "1", "b": "2", "c": "3"}}}`
+	tests := []struct {
+		name   string
+		object string
+		limits DecodeLimits
+		limit  string
+	}{
+		{name: "within the limits", object: configMap, limits: DecodeLimits{MaxBodyBytes: 8192, MaxDepth: 5, MaxFields: 10}},
+		{name: "unlimited", object: nested, limits: DecodeLimits{}},
+		{name: "body too large", object: configMap, limits: DecodeLimits{MaxBodyBytes: 4096}, limit: "body bytes"},
+		{name: "too deep", object: nested, limits: DecodeLimits{MaxDepth: 10}, limit: "nesting depth"},
+		{name: "too many fields", object: fields, limits: DecodeLimits{MaxFields: 6}, limit: "fields"},
+		{name: "colons in strings aren't fields", object: `{"data": {"a": "::::::::{{{{[[[["}}`, limits: DecodeLimits{MaxDepth: 4, MaxFields: 8}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, resp, err := ParseHTTPRequestWithLimits(review(test.object), test.limits)
+			var limitErr *DecodeLimitError
+			if test.limit == "" {
+				if err != nil {
+					t.Fatalf("Expected the request to be decoded, got %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &limitErr) || limitErr.Limit != test.limit {
+				t.Fatalf("Expected the %s limit to be exceeded, got %v", test.limit, err)
+			}
+			if resp.Result == nil || resp.Result.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("Expected a 413 response, got %v", resp.Result)
+			}
+		})
+	}
+}
+
+// unreadable fails the test it is read in
+type unreadable struct{ t *testing.T }
+
+func (u unreadable) Read([]byte) (int, error) {
+	u.t.Fatal("Expected the body to be read no further than the exceeded limit")
+	return 0, io.EOF
+}
+
+func TestParseHTTPRequestStreams(t *testing.T) {
+	request := func(body io.Reader) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/", body)
+		r.Header.Set("Content-Type", "application/json")
+		return r
+	}
+	head := `{"kind": "AdmissionReview", "apiVersion": "admission.k8s.io/v1", "request": {"uid": "a", "operation": "CREATE", "object": `
+	deep := head + strings.Repeat(`{"a": `, 20)
+	_, _, err := ParseHTTPRequestWithLimits(request(io.MultiReader(strings.NewReader(deep), unreadable{t})), DecodeLimits{MaxDepth: 10})
+	var limitErr *DecodeLimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != "nesting depth" {
+		t.Fatalf("Expected the nesting depth limit to be exceeded, got %v", err)
+	}
+
+	// Strings and their escapes span the chunks read one byte at a time
+	escaped := head + `{"metadata": {"name": "a"}, "data": {"a": "\\\":{{[["}}}}`
+	req, _, err := ParseHTTPRequestWithLimits(request(iotest.OneByteReader(strings.NewReader(escaped))), DecodeLimits{MaxDepth: 4, MaxFields: 10})
+	if err != nil || req.UID != "a" {
+		t.Fatalf("Expected the request to be decoded, got %v", err)
+	}
+
+	for name, body := range map[string]string{
+		"empty":         "",
+		"trailing data": head + `{}}} {}`,
+		"invalid JSON":  `{"kind": "AdmissionReview", "request": {"uid": a}}`,
+	} {
+		if _, resp, err := ParseHTTPRequestWithLimits(request(strings.NewReader(body)), DefaultDecodeLimits); err == nil || resp.Result == nil || resp.Result.Code != http.StatusBadRequest {
+			t.Fatalf("Expected a 400 response to the %s body, got %v", name, err)
+		}
+	}
+}
+
+func TestDecodeLimitsFromEnv(t *testing.T) {
+	t.Setenv(MaxDepthEnvVar, "0")
+	t.Setenv(MaxFieldsEnvVar, "10")
+	limits, err := DecodeLimitsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := DecodeLimits{MaxBodyBytes: DefaultDecodeLimits.MaxBodyBytes, MaxFields: 10}
+	if limits != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, limits)
+	}
+	t.Setenv(MaxBodyBytesEnvVar, "-1")
+	if limits, err := DecodeLimitsFromEnv(); err == nil || limits != DefaultDecodeLimits {
+		t.Fatalf("Expected an invalid limit to keep the defaults with an error, got %+v, %v", limits, err)
+	}
+}
+
+func TestLazyScheme(t *testing.T) {
+	builds := 0
+	lazy := NewLazyScheme(func(s *runtime.Scheme) error {
+		builds++
+		return admissionv1.AddToScheme(s)
+	})
+	if builds != 0 {
+		t.Fatalf("Expected the scheme to be built on its first use, got %d builds", builds)
+	}
+	first, err := lazy.Decoder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := lazy.Decoder()
+	scheme, _ := lazy.Scheme()
+	if builds != 1 || first != second {
+		t.Fatalf("Expected the scheme to be built once and its decoder shared, got %d builds", builds)
+	}
+	if !scheme.Recognizes(admissionv1.SchemeGroupVersion.WithKind("AdmissionReview")) {
+		t.Fatal("Expected the scheme to recognize the registered types")
+	}
+
+	failing := NewLazyScheme(func(*runtime.Scheme) error { return fmt.Errorf("conflict") })
+	for i := 0; i < 2; i++ {
+		if _, err := failing.Decoder(); err == nil {
+			t.Fatal("Expected the registration error on every use")
+		}
+	}
+}
+
+func TestPatternMatcher(t *testing.T) {
+	patterns := []string{"^default$", "^kube-.*", "^openshift-.*-operator$", "(?i)^Redhat$", "^a.b$"}
+	m, err := NewPatternMatcher(patterns)
+	if err != nil {
+		t.Fatalf("Expected the patterns to compile, got %v", err)
+	}
+	for name, expected := range map[string]bool{
+		"default":                true,
+		"defaults":               false,
+		"kube-system":            true,
+		"kube":                   false,
+		"openshift-foo-operator": true,
+		"openshift-foo":          false,
+		"REDHAT":                 true,
+		"axb":                    true,
+		"a":                      false,
+	} {
+		if got := m.Matches(name); got != expected || got != RegexSliceContains(name, patterns) {
+			t.Errorf("Expected %s to match %v, got %v", name, expected, got)
+		}
+	}
+	partial, err := NewPatternMatcher([]string{"^ok$", "(unclosed"})
+	if err == nil {
+		t.Fatal("Expected an invalid pattern to fail")
+	}
+	if !partial.Matches("ok") || !reflect.DeepEqual(partial.Patterns(), []string{"^ok$"}) {
+		t.Fatalf("Expected the valid patterns to be kept, got %v", partial.Patterns())
+	}
+	var unset *PatternMatcher
+	if unset.Matches("default") {
+		t.Fatal("Expected a nil matcher to match nothing")
+	}
+	if set := NewStringSet("anyuid", "privileged"); !set.Has("anyuid") || set.Has("restricted") {
+		t.Fatalf("Expected the set to hold its names only, got %v", set)
+	}
+}
+
+func TestResponseHelpers(t *testing.T) {
+	request := admissionctl.Request{AdmissionRequest: admissionv1.AdmissionRequest{UID: "a"}}
+	if resp := Allow(request, "allowed"); resp.UID != "a" || !resp.Allowed {
+		t.Fatalf("Expected an allowed response to the request, got %+v", resp)
+	}
+	resp := AllowWithWarning(request, "allowed", "deprecated", "ignored")
+	if resp.UID != "a" || !resp.Allowed || len(resp.Warnings) != 2 {
+		t.Fatalf("Expected an allowed response with two warnings, got %+v", resp)
+	}
+	resp = Deny(request, ReasonSCCDefaultModify, "Modifying default SCCs is not allowed")
+	if code, message := DenialReason(resp); resp.UID != "a" || resp.Allowed || code != ReasonSCCDefaultModify || message != "Modifying default SCCs is not allowed" {
+		t.Fatalf("Expected a denial with the reason code, got %+v", resp)
+	}
+	if resp := WithUID(request, admissionctl.Errored(http.StatusBadRequest, errors.New("invalid"))); resp.UID != "a" {
+		t.Fatalf("Expected the UID of the request, got %+v", resp)
+	}
+}
+
+func TestAddLabelPatches(t *testing.T) {
+	wanted := map[string]string{"team/owner": "web", "tier": "gold"}
+	if patches := AddLabelPatches(nil, wanted); len(patches) != 1 || patches[0].Path != "/metadata/labels" {
+		t.Fatalf("Expected one patch adding all the labels, got %v", patches)
+	}
+	patches := AddLabelPatches(map[string]string{"tier": "silver"}, wanted)
+	if len(patches) != 1 || patches[0].Path != "/metadata/labels/team~1owner" || patches[0].Value != "web" {
+		t.Fatalf("Expected one patch adding the escaped missing label, got %v", patches)
+	}
+	if patches := AddLabelPatches(nil, nil); patches != nil {
+		t.Fatalf("Expected no patches without wanted labels, got %v", patches)
+	}
+}
@@ -0,0 +1,140 @@
+//go:build e2e
+
+// Package e2e exercises the allow and deny paths of the webhooks deployed on
+// a live cluster, e.g.
+//
+//	make e2e
+//	build/_output/e2e -kubeconfig ~/.kube/staging -test.v
+//
+// The kubeconfig must be able to impersonate users and bind cluster-admin.
+// The requests are dry runs, so the cluster is left as it was; the only
+// object created is the ClusterRoleBinding of the throwaway regular user,
+// which is deleted when the suite ends.
+package e2e
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// runLabel labels the objects created by a run with its ID, so leftovers
+	// of an interrupted run can be found and deleted
+	runLabel = "managed.openshift.io/webhooks-e2e"
+	timeout  = 30 * time.Second
+)
+
+var (
+	kubeconfig = flag.String("kubeconfig", os.Getenv("KUBECONFIG"), "Path of the kubeconfig of the tested cluster, $KUBECONFIG by default")
+
+	// runID tells apart the names of concurrent runs against one cluster
+	runID string
+	// restConfig is the config of the kubeconfig identity, which sets up
+	// the suite
+	restConfig  *rest.Config
+	adminClient client.Client
+
+	// regularUser is a throwaway customer user bound to cluster-admin, so
+	// RBAC allows its requests and the webhooks decide them
+	regularUser identity
+	// privilegedUser is exempt from the webhooks guarding the platform
+	privilegedUser = identity{Username: "system:admin", Groups: []string{"system:masters", "system:authenticated"}}
+
+	clients   = map[string]client.Client{}
+	clientsMu sync.Mutex
+)
+
+// identity is the user the requests of a case are impersonated as
+type identity struct {
+	Username string
+	Groups   []string
+}
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if err := setUp(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to set up the e2e suite: %v\n", err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	if err := tearDown(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to clean up the e2e suite, delete the objects labelled %s=%s: %v\n", runLabel, runID, err)
+		if code == 0 {
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
+
+func setUp() error {
+	if *kubeconfig == "" {
+		return fmt.Errorf("-kubeconfig or $KUBECONFIG is required")
+	}
+	var err error
+	restConfig, err = clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		return err
+	}
+	adminClient, err = client.New(restConfig, client.Options{})
+	if err != nil {
+		return err
+	}
+	runID = utilrand.String(5)
+	regularUser = identity{Username: "e2e-user-" + runID, Groups: []string{"system:authenticated", "system:authenticated:oauth"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return adminClient.Create(ctx, regularUserBinding())
+}
+
+func tearDown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := adminClient.Delete(ctx, regularUserBinding()); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func regularUserBinding() *rbacv1.ClusterRoleBinding {
+	return &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "webhooks-e2e-" + runID,
+			Labels: map[string]string{runLabel: runID},
+		},
+		RoleRef: rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "cluster-admin"},
+		Subjects: []rbacv1.Subject{
+			{APIGroup: rbacv1.GroupName, Kind: rbacv1.UserKind, Name: regularUser.Username},
+		},
+	}
+}
+
+// clientFor returns a client impersonating id
+func clientFor(id identity) (client.Client, error) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	if c, ok := clients[id.Username]; ok {
+		return c, nil
+	}
+	cfg := rest.CopyConfig(restConfig)
+	cfg.Impersonate = rest.ImpersonationConfig{UserName: id.Username, Groups: id.Groups}
+	c, err := client.New(cfg, client.Options{})
+	if err != nil {
+		return nil, err
+	}
+	clients[id.Username] = c
+	return c, nil
+}
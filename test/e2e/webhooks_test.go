@@ -0,0 +1,217 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/namespace"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/namespacelabel"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/prometheusrule"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/regularuser/common"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/scc"
+	"github.com/openshift/managed-cluster-validating-webhooks/pkg/webhooks/utils"
+)
+
+var (
+	namespaceGVK      = schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+	sccGVK            = schema.GroupVersionKind{Group: "security.openshift.io", Version: "v1", Kind: "SecurityContextConstraints"}
+	clusterVersionGVK = schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "ClusterVersion"}
+	prometheusRuleGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "PrometheusRule"}
+)
+
+// webhookCase is one request to a webhook, and its expected answer
+type webhookCase struct {
+	name string
+	as   identity
+	// request sends the request as a dry run, returning the object the API
+	// server answered with
+	request func(ctx context.Context, c client.Client) (*unstructured.Unstructured, error)
+	denied  bool
+	// code is the reason code of the denial, if the webhook sets one
+	code utils.ReasonCode
+	// check checks the object answered to an allowed request, e.g. the
+	// patches of a mutating webhook
+	check func(obj *unstructured.Unstructured) error
+}
+
+// webhookCases returns the cases of each webhook. Webhooks without cases are
+// reported as skipped.
+func webhookCases() map[string][]webhookCase {
+	return map[string][]webhookCase{
+		scc.WebhookName: {
+			{name: "regular user deletes a default SCC", as: regularUser, request: deleteObject(sccGVK, "", "anyuid"), denied: true, code: utils.ReasonSCCDefaultDelete},
+			{name: "regular user modifies a default SCC", as: regularUser, request: labelObject(sccGVK, "", "anyuid"), denied: true, code: utils.ReasonSCCDefaultModify},
+			{name: "platform admin deletes a default SCC", as: privilegedUser, request: deleteObject(sccGVK, "", "anyuid")},
+			{name: "platform admin modifies a default SCC", as: privilegedUser, request: labelObject(sccGVK, "", "anyuid")},
+		},
+		namespace.WebhookName: {
+			{name: "regular user creates a managed namespace", as: regularUser, request: createNamespace("openshift-e2e-" + runID), denied: true, code: utils.ReasonNamespaceManaged},
+			{name: "regular user creates a customer namespace", as: regularUser, request: createNamespace("e2e-" + runID)},
+			{name: "platform admin creates a managed namespace", as: privilegedUser, request: createNamespace("openshift-e2e-" + runID)},
+		},
+		namespacelabel.WebhookName: {
+			{name: "customer namespaces are labelled", as: regularUser, request: createNamespace("e2e-" + runID), check: hasLabel("managed.openshift.io/tier", "customer")},
+			{name: "managed namespaces are not labelled", as: privilegedUser, request: createNamespace("openshift-e2e-" + runID), check: hasLabel("managed.openshift.io/tier", "")},
+		},
+		common.WebhookName: {
+			{name: "regular user modifies the ClusterVersion", as: regularUser, request: labelObject(clusterVersionGVK, "", "version"), denied: true},
+			{name: "platform admin modifies the ClusterVersion", as: privilegedUser, request: labelObject(clusterVersionGVK, "", "version")},
+		},
+		prometheusrule.WebhookName: {
+			{name: "regular user creates a PrometheusRule in a managed namespace", as: regularUser, request: createPrometheusRule("openshift-monitoring"), denied: true, code: utils.ReasonPrometheusRuleManagedNamespace},
+			{name: "platform admin creates a PrometheusRule in a managed namespace", as: privilegedUser, request: createPrometheusRule("openshift-monitoring")},
+		},
+	}
+}
+
+// TestWebhooks runs the cases of each registered webhook deployed on the
+// cluster, e.g. -test.run TestWebhooks/scc-validation for a single webhook
+func TestWebhooks(t *testing.T) {
+	cases := webhookCases()
+	names := make([]string, 0, len(webhooks.Webhooks))
+	for name := range webhooks.Webhooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			if len(cases[name]) == 0 {
+				t.Skip("no e2e cases")
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			deployed, err := isDeployed(ctx, name)
+			if err != nil {
+				t.Fatalf("failed to get the webhook configuration: %v", err)
+			}
+			if !deployed {
+				t.Skip("not deployed on this cluster")
+			}
+			for _, tc := range cases[name] {
+				tc := tc
+				t.Run(tc.name, func(t *testing.T) {
+					runCase(t, name, tc)
+				})
+			}
+		})
+	}
+}
+
+func runCase(t *testing.T, hook string, tc webhookCase) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c, err := clientFor(tc.as)
+	if err != nil {
+		t.Fatalf("failed to create the client of %s: %v", tc.as.Username, err)
+	}
+	obj, err := tc.request(ctx, c)
+	if !tc.denied {
+		if err != nil {
+			t.Fatalf("expected the request of %s to be allowed, got: %v", tc.as.Username, err)
+		}
+		if tc.check != nil {
+			if err := tc.check(obj); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return
+	}
+	if err == nil {
+		t.Fatalf("expected %s to deny the request of %s, it was allowed", hook, tc.as.Username)
+	}
+	deniedBy := fmt.Sprintf("admission webhook %q denied the request", hook+".managed.openshift.io")
+	if !strings.Contains(err.Error(), deniedBy) {
+		t.Fatalf("expected %s to deny the request of %s, got: %v", hook, tc.as.Username, err)
+	}
+	if tc.code != "" && apierrors.ReasonForError(err) != metav1.StatusReason(tc.code) {
+		t.Fatalf("expected the denial to have reason code %s, got %s: %v", tc.code, apierrors.ReasonForError(err), err)
+	}
+}
+
+// isDeployed returns whether the configuration of hook is on the cluster,
+// which it isn't for webhooks of other product profiles
+func isDeployed(ctx context.Context, hook string) (bool, error) {
+	var configuration client.Object = &admissionregv1.ValidatingWebhookConfiguration{}
+	if strings.HasSuffix(hook, "-mutation") {
+		configuration = &admissionregv1.MutatingWebhookConfiguration{}
+	}
+	err := adminClient.Get(ctx, client.ObjectKey{Name: "sre-" + hook}, configuration)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func newObject(gvk schema.GroupVersionKind, namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func deleteObject(gvk schema.GroupVersionKind, namespace, name string) func(context.Context, client.Client) (*unstructured.Unstructured, error) {
+	return func(ctx context.Context, c client.Client) (*unstructured.Unstructured, error) {
+		obj := newObject(gvk, namespace, name)
+		return obj, c.Delete(ctx, obj, client.DryRunAll)
+	}
+}
+
+// labelObject updates an existing object by adding the run label to it
+func labelObject(gvk schema.GroupVersionKind, namespace, name string) func(context.Context, client.Client) (*unstructured.Unstructured, error) {
+	return func(ctx context.Context, c client.Client) (*unstructured.Unstructured, error) {
+		obj := newObject(gvk, namespace, name)
+		patch := fmt.Sprintf(`{"metadata":{"labels":{%q:%q}}}`, runLabel, runID)
+		return obj, c.Patch(ctx, obj, client.RawPatch(types.MergePatchType, []byte(patch)), client.DryRunAll)
+	}
+}
+
+func createNamespace(name string) func(context.Context, client.Client) (*unstructured.Unstructured, error) {
+	return func(ctx context.Context, c client.Client) (*unstructured.Unstructured, error) {
+		obj := newObject(namespaceGVK, "", name)
+		obj.SetLabels(map[string]string{runLabel: runID})
+		return obj, c.Create(ctx, obj, client.DryRunAll)
+	}
+}
+
+func createPrometheusRule(namespace string) func(context.Context, client.Client) (*unstructured.Unstructured, error) {
+	return func(ctx context.Context, c client.Client) (*unstructured.Unstructured, error) {
+		obj := newObject(prometheusRuleGVK, namespace, "e2e-"+runID)
+		obj.SetLabels(map[string]string{runLabel: runID})
+		obj.Object["spec"] = map[string]interface{}{
+			"groups": []interface{}{
+				map[string]interface{}{
+					"name":  "e2e",
+					"rules": []interface{}{map[string]interface{}{"record": "e2e:up", "expr": "vector(1)"}},
+				},
+			},
+		}
+		return obj, c.Create(ctx, obj, client.DryRunAll)
+	}
+}
+
+// hasLabel checks the object has the label key with value, or not at all if
+// value is empty
+func hasLabel(key, value string) func(*unstructured.Unstructured) error {
+	return func(obj *unstructured.Unstructured) error {
+		if got := obj.GetLabels()[key]; got != value {
+			return fmt.Errorf("expected label %s of %s to be %q, got %q", key, obj.GetName(), value, got)
+		}
+		return nil
+	}
+}